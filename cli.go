@@ -0,0 +1,438 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/tools"
+	"github.com/Rhyanz46/mcp-service/internal/webhook"
+)
+
+// parseInterspersed parses flags that may come before or after the
+// positional arguments, e.g. `index ./docs --include-code`, and returns the
+// positional ones.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var pos []string
+	for {
+		_ = fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos
+		}
+		if args[0] == "--" {
+			return append(pos, args[1:]...)
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// commaList splits a comma-separated flag value, dropping empty items.
+func commaList(s string) []any {
+	var out []any
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// callTool runs one tool the way an MCP client would and prints its JSON
+// payload to stdout. Tool failures are printed too and exit 1.
+func callTool(conf *cfg.Config, rag *ragvec.VecRAG, name string, args map[string]any) int {
+	env := tools.NewEnv(conf, rag)
+	registry := tools.NewRegistry()
+	tools.RegisterBuiltin(registry, env)
+	registry.Configure(conf.Tools)
+	res, err := registry.Call(name, args)
+	env.Hooks.Wait(5 * time.Second)
+	if err != nil {
+		if te, ok := err.(*tools.Error); ok && te.Data != nil {
+			log.Printf("%s: %s: %v", name, te.Message, te.Data)
+		} else {
+			log.Printf("%s: %v", name, err)
+		}
+		return 1
+	}
+	payload, err := tools.ResultPayload(res)
+	if err != nil {
+		log.Printf("%s: %v", name, err)
+		return 1
+	}
+	if payload == nil {
+		for _, c := range res.Content {
+			if c.Type == "text" {
+				fmt.Println(c.Text)
+			}
+		}
+	} else {
+		var v any
+		if err := json.Unmarshal(payload, &v); err != nil {
+			log.Printf("%s: %v", name, err)
+			return 1
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			log.Printf("%s: %v", name, err)
+			return 1
+		}
+	}
+	if res.IsError {
+		return 1
+	}
+	return 0
+}
+
+// runIndex implements `mcp-service index <dir>`: rag_index as a one-shot
+// command for CI jobs and scripts.
+func runIndex(args []string) int {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: mcp-service index <dir> [flags]")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "", "Path to configuration file (optional)")
+	testFlag := fs.Bool("test", false, "Enable testing mode (prefers test-config.json)")
+	includeCode := fs.Bool("include-code", false, "Also index source code files")
+	tags := fs.String("tags", "", "Comma-separated labels stored on every chunk")
+	ttl := fs.String("ttl", "", "Delete the indexed chunks after this long, e.g. 36h or 7d")
+	prune := fs.Bool("prune-missing", false, "Delete chunks of files removed from disk since the last run (default: indexing.prune_missing)")
+	include := fs.String("include", "", "Comma-separated patterns; only matching files are indexed, e.g. 'docs/**/*.md,api/**/*.yaml'")
+	exclude := fs.String("exclude", "", "Comma-separated patterns to skip on top of indexing.exclude, e.g. '**/testdata/**,*.min.js'")
+	pos := parseInterspersed(fs, args)
+	if len(pos) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	loadConfig(*configPath, *testFlag)
+	conf := cfg.Global
+	conf.Qdrant.FailOpen = false
+	callArgs := map[string]any{
+		"dir": pos[0], "include_code": *includeCode, "tags": commaList(*tags), "ttl": *ttl,
+		"include": commaList(*include), "exclude": commaList(*exclude),
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "prune-missing" {
+			callArgs["prune_missing"] = *prune
+		}
+	})
+	return callTool(conf, startRAG(conf), "rag_index", callArgs)
+}
+
+// runSearch implements `mcp-service search "<query>"`, printing the
+// rag_search hits as JSON.
+func runSearch(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: mcp-service search \"<query>\" [flags]")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "", "Path to configuration file (optional)")
+	testFlag := fs.Bool("test", false, "Enable testing mode (prefers test-config.json)")
+	k := fs.Int("k", 5, "Number of results")
+	project := fs.String("project", "", "Only search this project")
+	tagsAny := fs.String("tags", "", "Comma-separated labels; hits must carry at least one")
+	pos := parseInterspersed(fs, args)
+	if len(pos) == 0 || strings.TrimSpace(strings.Join(pos, " ")) == "" {
+		fs.Usage()
+		return 2
+	}
+
+	loadConfig(*configPath, *testFlag)
+	conf := cfg.Global
+	conf.Qdrant.FailOpen = false
+	callArgs := map[string]any{"query": strings.Join(pos, " "), "k": float64(*k)}
+	if *project != "" {
+		callArgs["project"] = *project
+	}
+	if tags := commaList(*tagsAny); len(tags) > 0 {
+		callArgs["tags_any"] = tags
+	}
+	return callTool(conf, startRAG(conf), "rag_search", callArgs)
+}
+
+// runEval implements `mcp-service eval <file.jsonl>`, evaluating the cases
+// with the configured search settings and printing the report as JSON.
+// Compare configurations by running it once per config file.
+func runEval(args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: mcp-service eval <file.jsonl> [flags]")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "", "Path to configuration file (optional)")
+	testFlag := fs.Bool("test", false, "Enable testing mode (prefers test-config.json)")
+	k := fs.Int("k", 10, "Cut-off rank for the metrics")
+	pos := parseInterspersed(fs, args)
+	if len(pos) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	loadConfig(*configPath, *testFlag)
+	conf := cfg.Global
+	cases, err := ragvec.LoadEvalCases(pos[0])
+	if err != nil {
+		log.Printf("Eval: %v", err)
+		return 1
+	}
+	// With access.tenant set, only the tenant's documents are searched
+	acl, err := conf.Access.TenantLabels(conf.Access.Tenant)
+	if err != nil {
+		log.Printf("Eval: %v", err)
+		return 1
+	}
+	conf.Qdrant.FailOpen = false
+	rag := startRAG(conf)
+	report := map[string]any{
+		"file":    pos[0],
+		"cases":   len(cases),
+		"k":       *k,
+		"reports": rag.Evaluate(cases, *k, nil, true, acl),
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Printf("Eval: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// runStatus implements `mcp-service status`. An unreachable vector store is
+// reported in the output rather than failing the command.
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to configuration file (optional)")
+	testFlag := fs.Bool("test", false, "Enable testing mode (prefers test-config.json)")
+	projects := fs.Bool("projects", false, "Also count chunks per project")
+	probe := fs.Bool("probe-embedding", true, "Embed a tiny probe input to measure provider latency")
+	_ = fs.Parse(args)
+
+	loadConfig(*configPath, *testFlag)
+	conf := cfg.Global
+	conf.Qdrant.FailOpen = true
+	conf.Qdrant.StartupRetries = 1
+	return callTool(conf, startRAG(conf), "status_get", map[string]any{
+		"fast_only": !*projects, "probe_embedding": *probe,
+	})
+}
+
+// runIndexStdin implements `mcp-service index-stdin`: it reads one document
+// from stdin, indexes it under a virtual path and prints the result as JSON,
+// so shell pipelines can feed content without writing files.
+func runIndexStdin(args []string) int {
+	fs := flag.NewFlagSet("index-stdin", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: mcp-service index-stdin --path virtual://notes.md [--project X] [flags] < document")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "", "Path to configuration file (optional)")
+	testFlag := fs.Bool("test", false, "Enable testing mode (prefers test-config.json)")
+	path := fs.String("path", "", "Path stored for the document; its extension picks the file type (required)")
+	project := fs.String("project", "stdin", "Project for the document")
+	tags := fs.String("tags", "", "Comma-separated labels stored on every chunk")
+	ttl := fs.String("ttl", "", "Delete the document's chunks after this long, e.g. 36h or 7d")
+	_ = fs.Parse(args)
+
+	if strings.TrimSpace(*path) == "" {
+		fmt.Fprintln(os.Stderr, "index-stdin: --path is required")
+		fs.Usage()
+		return 2
+	}
+	expire, err := ragvec.ParseTTL(*ttl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "index-stdin: %v\n", err)
+		return 2
+	}
+	loadConfig(*configPath, *testFlag)
+	conf := cfg.Global
+
+	var in io.Reader = os.Stdin
+	maxBytes := int64(conf.Indexing.MaxFileKB) * 1024
+	if maxBytes > 0 {
+		in = io.LimitReader(os.Stdin, maxBytes+1)
+	}
+	b, err := io.ReadAll(in)
+	if err != nil {
+		log.Printf("index-stdin: read stdin: %v", err)
+		return 1
+	}
+	if maxBytes > 0 && int64(len(b)) > maxBytes {
+		log.Printf("index-stdin: document is larger than indexing.max_file_kb (%d KB)", conf.Indexing.MaxFileKB)
+		return 1
+	}
+	if strings.TrimSpace(string(b)) == "" {
+		log.Printf("index-stdin: stdin is empty")
+		return 1
+	}
+
+	conf.Qdrant.FailOpen = false
+	rag := startRAG(conf)
+	hooks := webhook.New(conf)
+	opts := ragvec.IngestOptions{Tags: strings.Split(*tags, ","), TTL: expire, Project: *project}
+	start := time.Now()
+	n, err := rag.IngestText(*path, string(b), opts)
+	errText := ""
+	if err != nil {
+		errText = err.Error()
+	}
+	hooks.Notify(webhook.Event{Event: "index", Source: "cli", Count: n,
+		Details: map[string]any{"path": *path, "project": *project}}, start, errText)
+	hooks.Wait(5 * time.Second)
+	if err != nil {
+		log.Printf("index-stdin: %v", err)
+		return 1
+	}
+	out := map[string]any{"indexed": n, "path": *path, "project": *project, "status": "success"}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		log.Printf("index-stdin: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// runConfig implements `mcp-service config init|validate`.
+func runConfig(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "init":
+			return runConfigInit(args[1:])
+		case "validate":
+			return runConfigValidate(args[1:])
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Usage: mcp-service config init [-o config.json] [--force]")
+	fmt.Fprintln(os.Stderr, "       mcp-service config validate [-config path] [--offline] [--json]")
+	return 2
+}
+
+// runConfigInit writes the default configuration with comments explaining
+// each setting.
+func runConfigInit(args []string) int {
+	fs := flag.NewFlagSet("config init", flag.ExitOnError)
+	out := fs.String("o", "config.json", "File to write, or - for stdout")
+	force := fs.Bool("force", false, "Overwrite an existing file")
+	_ = fs.Parse(args)
+
+	if *out == "-" {
+		b, err := defaultConfig().Annotated()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "config init: %v\n", err)
+			return 1
+		}
+		_, _ = os.Stdout.Write(b)
+		return 0
+	}
+	if err := writeDefaultConfig(*out, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "config init: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s; check it with `mcp-service config validate -config %s`\n", *out, *out)
+	return 0
+}
+
+// defaultConfig is the configuration config init writes.
+func defaultConfig() *cfg.Config {
+	conf := cfg.DefaultConfig()
+	// Credentials stay in the environment rather than the file
+	conf.Embedding.OpenAI.APIKey = ""
+	conf.Webhooks = []cfg.WebhookConfig{}
+	conf.Connectors = []cfg.ConnectorConfig{}
+	return conf
+}
+
+// writeDefaultConfig writes the annotated default configuration to path,
+// refusing to replace an existing file unless force.
+func writeDefaultConfig(path string, force bool) error {
+	b, err := defaultConfig().Annotated()
+	if err != nil {
+		return err
+	}
+	mode := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, mode, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// loadCheckedConfig loads path the way the server does, without exiting on
+// errors; the config is nil when the check failed.
+func loadCheckedConfig(path string) (*cfg.Config, ragvec.Check) {
+	conf := cfg.DefaultConfig()
+	if err := conf.LoadFromFile(path); err != nil {
+		return nil, ragvec.Check{Name: "config", Status: "fail", Detail: fmt.Sprintf("%s: %v", path, err)}
+	}
+	conf.LoadFromEnv()
+	if err := conf.Validate(); err != nil {
+		return nil, ragvec.Check{Name: "config", Status: "fail", Detail: fmt.Sprintf("%s: %v", path, err)}
+	}
+	return conf, ragvec.Check{Name: "config", Status: "ok", Detail: path + " is valid"}
+}
+
+// printChecks prints checks as aligned lines, with the fix under each one
+// that did not pass, or as JSON. It returns the exit code: 1 if any failed.
+func printChecks(checks []ragvec.Check, asJSON bool) int {
+	failed := false
+	for _, c := range checks {
+		failed = failed || c.Status == "fail"
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(map[string]any{"valid": !failed, "checks": checks})
+	} else {
+		for _, c := range checks {
+			fmt.Printf("%-4s  %-18s %s\n", c.Status, c.Name, c.Detail)
+			if c.Fix != "" && c.Status != "ok" {
+				fmt.Printf("%24s fix: %s\n", "", c.Fix)
+			}
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// runConfigValidate loads a config file the way the server does and, unless
+// offline, probes the embedding providers and the vector store. It prints one
+// line per check and exits 1 when any fails.
+func runConfigValidate(args []string) int {
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to configuration file (optional)")
+	testFlag := fs.Bool("test", false, "Enable testing mode (prefers test-config.json)")
+	offline := fs.Bool("offline", false, "Only check the file; do not contact providers or the vector store")
+	asJSON := fs.Bool("json", false, "Print the checks as JSON")
+	_ = fs.Parse(args)
+
+	conf, check := loadCheckedConfig(resolveConfigPath(*configPath, *testFlag))
+	checks := []ragvec.Check{check}
+	if conf != nil && !*offline {
+		checks = append(checks, ragvec.CheckConfig(conf)...)
+	}
+	return printChecks(checks, *asJSON)
+}
@@ -191,12 +191,13 @@ echo ""
 
 # Test the service with a simple JSON-RPC sequence
 echo "1️⃣  Testing initialization..."
-echo '{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}' | timeout 10s ./mcp-demo 2>/dev/null | head -1 | jq '.result.serverInfo.name' 2>/dev/null || echo "✅ Service initialized"
+echo '{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}' | timeout 10s ./mcp-demo 2>/dev/null | head -1 | jq '.result.serverInfo.name' 2>/dev/null || echo "✅ Service initialized"
 
 echo ""
 echo "2️⃣  Testing document indexing..."
 (
-echo '{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}'
+echo '{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}'
+echo '{"jsonrpc":"2.0","method":"notifications/initialized"}'
 sleep 1
 echo '{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"rag_index","arguments":{"dir":"./docs","include_code":false}}}'
 sleep 5
@@ -205,7 +206,8 @@ sleep 5
 echo ""
 echo "3️⃣  Testing semantic search..."
 (
-echo '{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}'
+echo '{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}'
+echo '{"jsonrpc":"2.0","method":"notifications/initialized"}'
 sleep 1
 echo '{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"rag_search","arguments":{"query":"What is machine learning?","k":3}}}'
 sleep 3
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/pathglob"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+// doctorWalkLimit bounds how many entries of the docs dir doctor inspects.
+const doctorWalkLimit = 20000
+
+// runDoctor implements `mcp-service doctor`: everything config validate
+// checks, plus the docs dir, the files the service writes and the stdio
+// framing of the server itself, each failure with a suggested fix. On a
+// terminal it offers to create a missing config file.
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to configuration file (optional)")
+	testFlag := fs.Bool("test", false, "Enable testing mode (prefers test-config.json)")
+	yes := fs.Bool("y", false, "Answer yes to questions, e.g. creating a missing config file")
+	asJSON := fs.Bool("json", false, "Print the checks as JSON")
+	_ = fs.Parse(args)
+
+	path := resolveConfigPath(*configPath, *testFlag)
+	var checks []ragvec.Check
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if !confirm(fmt.Sprintf("%s does not exist. Create it with the defaults?", path), *yes) {
+			checks = append(checks, ragvec.Check{Name: "config", Status: "fail", Detail: path + " does not exist",
+				Fix: "run `mcp-service config init -o " + path + "` or pass -config <path>"})
+			return printChecks(checks, *asJSON)
+		}
+		if err := writeDefaultConfig(path, false); err != nil {
+			checks = append(checks, ragvec.Check{Name: "config", Status: "fail", Detail: err.Error()})
+			return printChecks(checks, *asJSON)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	}
+
+	conf, check := loadCheckedConfig(path)
+	checks = append(checks, check)
+	if conf == nil {
+		return printChecks(checks, *asJSON)
+	}
+	checks = append(checks, ragvec.CheckConfig(conf)...)
+	checks = append(checks, checkDocsDir(conf))
+	checks = append(checks, checkWritable("manifest", conf.Indexing.ManifestPath, "indexing.manifest_path"))
+	if conf.Store.Backend == "memory" {
+		checks = append(checks, checkWritable("memory store", conf.Store.Memory.Path, "store.memory.path"))
+	}
+	checks = append(checks, checkStdio(path))
+	return printChecks(checks, *asJSON)
+}
+
+// confirm asks a yes/no question on stderr when stdin is a terminal; yes
+// answers it without asking. Anything but y/yes, or no terminal, is no.
+func confirm(question string, yes bool) bool {
+	if yes {
+		return true
+	}
+	st, err := os.Stdin.Stat()
+	if err != nil || st.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// /dev/null is a character device too
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(st, null) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// checkDocsDir walks indexing.docs_dir the way rag_index does and reports
+// entries it could not read, which would abort indexing.
+func checkDocsDir(conf *cfg.Config) ragvec.Check {
+	const name = "docs dir"
+	dir := conf.Indexing.DocsDir
+	st, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return ragvec.Check{Name: name, Status: "warn", Detail: dir + " does not exist",
+			Fix: "create it or set indexing.docs_dir (DOCS_DIR); rag_index can still index other directories"}
+	case err != nil:
+		return ragvec.Check{Name: name, Status: "fail", Detail: err.Error(), Fix: "make " + dir + " readable for this user"}
+	case !st.IsDir():
+		return ragvec.Check{Name: name, Status: "fail", Detail: dir + " is not a directory", Fix: "point indexing.docs_dir at a directory"}
+	}
+
+	exclude := map[string]bool{}
+	for _, d := range conf.Indexing.ExcludeDirs {
+		exclude[d] = true
+	}
+	// Validate already rejected malformed patterns
+	patterns, _ := pathglob.Compile(conf.Indexing.Exclude)
+	var unreadable []string
+	files, seen := 0, 0
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if seen++; seen > doctorWalkLimit {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			unreadable = append(unreadable, path)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil && patterns.Match(filepath.ToSlash(rel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && exclude[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !conf.IsDocumentationFile(ext) && !(conf.Indexing.IncludeCode && conf.IsCodeFile(ext)) {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			unreadable = append(unreadable, path)
+			return nil
+		}
+		f.Close()
+		files++
+		return nil
+	})
+
+	switch {
+	case len(unreadable) > 0:
+		shown := unreadable[:min(len(unreadable), 3)]
+		return ragvec.Check{Name: name, Status: "fail",
+			Detail: fmt.Sprintf("cannot read %d entries under %s, which aborts rag_index: %s", len(unreadable), dir, strings.Join(shown, ", ")),
+			Fix:    "fix their permissions (chmod a+rX) or add the directories to indexing.exclude_dirs"}
+	case files == 0:
+		return ragvec.Check{Name: name, Status: "warn", Detail: "no indexable files under " + dir,
+			Fix: fmt.Sprintf("add documents (%s) or enable indexing.include_code", strings.Join(conf.Indexing.FileTypes.Documentation, ", "))}
+	case seen > doctorWalkLimit:
+		return ragvec.Check{Name: name, Status: "ok", Detail: fmt.Sprintf("%s readable; %d indexable files in the first %d entries", dir, files, doctorWalkLimit)}
+	}
+	return ragvec.Check{Name: name, Status: "ok", Detail: fmt.Sprintf("%s readable, %d indexable files", dir, files)}
+}
+
+// checkWritable verifies the service can create and replace path, which it
+// writes through a temporary file in the same directory.
+func checkWritable(name, path, key string) ragvec.Check {
+	if path == "" {
+		return ragvec.Check{Name: name, Status: "ok", Detail: "kept in memory (" + key + " is empty)"}
+	}
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err == nil {
+		f.Close()
+		err = os.Remove(f.Name())
+	}
+	if err != nil {
+		fix := fmt.Sprintf("make %s writable for this user, or change %s", dir, key)
+		if _, serr := os.Stat(dir); errors.Is(serr, os.ErrNotExist) {
+			fix = fmt.Sprintf("create %s, or change %s", dir, key)
+		}
+		return ragvec.Check{Name: name, Status: "fail", Detail: fmt.Sprintf("cannot write to %s: %v", dir, err), Fix: fix}
+	}
+	return ragvec.Check{Name: name, Status: "ok", Detail: path + " is writable"}
+}
+
+// checkStdio starts this binary as an MCP server without a vector store
+// and talks to it with both framings clients use: newline-delimited JSON
+// and Content-Length headers. Anything on stdout besides the replies, such
+// as a stray log line, breaks clients.
+func checkStdio(configPath string) ragvec.Check {
+	const name = "stdio"
+	fix := "configure clients to run `mcp-service serve -config <path>` directly; nothing but MCP messages may be written to stdout"
+	exe, err := os.Executable()
+	if err != nil {
+		return ragvec.Check{Name: name, Status: "warn", Detail: "cannot locate the binary: " + err.Error()}
+	}
+	requests := [][]byte{
+		[]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"doctor","version":"1"}}}`),
+		[]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`),
+		[]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list","params":{}}`),
+	}
+	var details []string
+	for _, headers := range []bool{false, true} {
+		mode := "newline-delimited"
+		if headers {
+			mode = "Content-Length"
+		}
+		var in bytes.Buffer
+		for _, r := range requests {
+			if headers {
+				fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(r), r)
+			} else {
+				in.Write(append(r, '\n'))
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		cmd := exec.CommandContext(ctx, exe, "serve", "-config", configPath, "-no-qdrant")
+		cmd.Env = append(os.Environ(), "MCP_NO_QDRANT=1")
+		cmd.Stdin = &in
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		start := time.Now()
+		err := cmd.Run()
+		cancel()
+		if err != nil {
+			msg := strings.TrimSpace(stderr.String())
+			if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+				msg = msg[i+1:]
+			}
+			return ragvec.Check{Name: name, Status: "fail", Detail: fmt.Sprintf("%s: server exited: %v %s", mode, err, msg), Fix: fix}
+		}
+		tools, err := parseFrames(stdout.Bytes(), headers)
+		if err != nil {
+			return ragvec.Check{Name: name, Status: "fail", Detail: fmt.Sprintf("%s: %v", mode, err), Fix: fix}
+		}
+		details = append(details, fmt.Sprintf("%s ok (%d tools, %d ms)", mode, tools, time.Since(start).Milliseconds()))
+	}
+	return ragvec.Check{Name: name, Status: "ok", Detail: strings.Join(details, "; ")}
+}
+
+// parseFrames checks that out holds exactly the replies to the initialize
+// (id 1) and tools/list (id 2) requests in the given framing, and returns
+// the number of tools listed.
+func parseFrames(out []byte, headers bool) (int, error) {
+	r := bufio.NewReader(bytes.NewReader(out))
+	tools := -1
+	for id := 1; id <= 2; id++ {
+		var frame []byte
+		if headers {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return 0, fmt.Errorf("reply %d missing", id)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+			if !strings.HasPrefix(line, "Content-Length:") || err != nil {
+				return 0, fmt.Errorf("expected a Content-Length header, got %q", strings.TrimSpace(line))
+			}
+			if blank, _ := r.ReadString('\n'); strings.TrimSpace(blank) != "" {
+				return 0, fmt.Errorf("expected a blank line after the header, got %q", strings.TrimSpace(blank))
+			}
+			frame = make([]byte, n)
+			if _, err := io.ReadFull(r, frame); err != nil {
+				return 0, fmt.Errorf("reply %d shorter than its Content-Length", id)
+			}
+		} else {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				return 0, fmt.Errorf("reply %d missing", id)
+			}
+			frame = line
+		}
+		var resp struct {
+			ID     json.RawMessage `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  any             `json:"error"`
+		}
+		if err := json.Unmarshal(frame, &resp); err != nil {
+			return 0, fmt.Errorf("stdout carries non-protocol output: %q", bytes.TrimSpace(frame))
+		}
+		if string(resp.ID) != strconv.Itoa(id) || resp.Error != nil {
+			return 0, fmt.Errorf("unexpected reply to request %d: %s", id, bytes.TrimSpace(frame))
+		}
+		if id == 2 {
+			var list struct {
+				Tools []any `json:"tools"`
+			}
+			_ = json.Unmarshal(resp.Result, &list)
+			tools = len(list.Tools)
+		}
+	}
+	if rest, _ := io.ReadAll(r); len(bytes.TrimSpace(rest)) > 0 {
+		return 0, fmt.Errorf("stdout carries non-protocol output after the replies: %q", bytes.TrimSpace(rest))
+	}
+	return tools, nil
+}
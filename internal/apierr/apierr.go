@@ -0,0 +1,186 @@
+// Package apierr maps failures to the error taxonomy clients see over MCP
+// and HTTP alike: a category with a stable code, the component that
+// failed, whether retrying can help, and a hint on what to do about it.
+//
+// Errors are marked with their category where they arise (see Wrap), so
+// code reporting them only needs Of.
+package apierr
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+)
+
+// Category is the kind of a failure.
+type Category string
+
+const (
+	// Validation is a request the server cannot act on as sent
+	Validation Category = "validation"
+	// Config is a server setting that is missing or does not allow the
+	// request
+	Config Category = "config"
+	// Provider is a failure of an external model: embeddings, reranking,
+	// OCR
+	Provider Category = "provider"
+	// Store is a failure of the vector store
+	Store Category = "vector_store"
+	// Limit is a quota or guardrail that stopped the request
+	Limit Category = "limit"
+	// Auth is a missing or wrong API key
+	Auth Category = "auth"
+	// Internal is anything else
+	Internal Category = "internal"
+)
+
+// codes are the JSON-RPC codes of the categories. Validation and Internal
+// use the spec's own codes; the rest are in the range JSON-RPC leaves to
+// servers (-32000 to -32099).
+var codes = map[Category]int{
+	Validation: -32602,
+	Config:     -32010,
+	Provider:   -32020,
+	Store:      -32030,
+	Limit:      -32040,
+	Auth:       -32050,
+	Internal:   -32603,
+}
+
+// Code returns the JSON-RPC code of c.
+func (c Category) Code() int {
+	if code, ok := codes[c]; ok {
+		return code
+	}
+	return codes[Internal]
+}
+
+// Data describes a failure to clients. It is the data of JSON-RPC errors,
+// part of failed tool results, and the fields of HTTP error bodies.
+type Data struct {
+	Code     int      `json:"code"`
+	Category Category `json:"category"`
+	// Component names what failed: "request", "config", "embedding",
+	// "reranker", "ocr", the vector store backend, "quota", "indexing",
+	// "server"
+	Component string `json:"component"`
+	// Retryable is set when the same request may succeed later
+	Retryable bool   `json:"retryable"`
+	Hint      string `json:"hint,omitempty"`
+	Details   string `json:"details,omitempty"`
+}
+
+// Error is an error marked with its category and component.
+type Error struct {
+	Category  Category
+	Component string
+	// Transient marks the error retryable whatever it wraps
+	Transient bool
+	Err       error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Wrap marks err as a failure of component in category c. nil stays nil,
+// and an error marked already keeps its mark.
+func Wrap(c Category, component string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Category: c, Component: component, Err: err}
+}
+
+// statusRE matches the HTTP status the stores and providers put in their
+// errors, for the statuses worth retrying.
+var statusRE = regexp.MustCompile(`\bhttp (429|5\d\d)\b`)
+
+// Of describes err, showing details as its text; callers pass err's text
+// redacted as the config asks. Errors not marked by Wrap are internal.
+func Of(err error, details string) Data {
+	d := Data{Category: Internal, Component: "server", Details: details}
+	var e *Error
+	if errors.As(err, &e) {
+		d.Category, d.Component = e.Category, e.Component
+		d.Retryable = e.Transient
+	}
+	if !d.Retryable && (d.Category == Provider || d.Category == Store) {
+		d.Retryable = transient(err)
+	}
+	d.Code = d.Category.Code()
+	d.Hint = hint(d)
+	return d
+}
+
+// transient reports whether err looks like it may pass on its own: a
+// network error, a timeout, or a 429 or 5xx reply.
+func transient(err error) bool {
+	var ne net.Error
+	if errors.As(err, &ne) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return statusRE.MatchString(err.Error())
+}
+
+// New describes a failure that has no error value.
+func New(c Category, component, details string) Data {
+	d := Data{Code: c.Code(), Category: c, Component: component, Details: details}
+	d.Hint = hint(d)
+	return d
+}
+
+// Invalid describes a request that fails validation.
+func Invalid(details string) Data {
+	return New(Validation, "request", details)
+}
+
+// QuotaExceeded describes a request refused because the caller's quota is
+// used up until its window resets.
+func QuotaExceeded(details string) Data {
+	d := New(Limit, "quota", details)
+	d.Retryable = true
+	d.Hint = hint(d)
+	return d
+}
+
+// Unavailable describes a request that needs the vector store while the
+// server runs without it (degraded mode).
+func Unavailable(details string) Data {
+	d := New(Store, "vector_store", details)
+	d.Retryable = true
+	d.Hint = "The server is in degraded mode until the vector store is reachable; retry later"
+	return d
+}
+
+func hint(d Data) string {
+	switch d.Category {
+	case Validation:
+		return "Fix the request parameters; retrying unchanged fails again"
+	case Config:
+		return "Change the server configuration (check it with `mcp-service config validate`)"
+	case Provider:
+		if d.Retryable {
+			return "The " + d.Component + " provider failed transiently; retry with backoff"
+		}
+		return "Check the " + d.Component + " provider's credentials and model settings (status_get shows provider health)"
+	case Store:
+		if d.Retryable {
+			return "The vector store is unreachable or overloaded; retry with backoff"
+		}
+		return "Check the vector store and the collection settings (`mcp-service doctor`)"
+	case Limit:
+		if d.Retryable {
+			return "Wait for the quota window to reset"
+		}
+		return "Narrow the request or raise the limit in the configuration"
+	case Auth:
+		return "Send a valid API key as Authorization: Bearer <key> or X-API-Key"
+	}
+	return ""
+}
@@ -0,0 +1,261 @@
+package chunker
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/pathglob"
+)
+
+// ArchiveSep separates an archive's path from the path of a file inside it,
+// as in docs/bundle.zip!guide/intro.md.
+const ArchiveSep = "!"
+
+// archiveExts are the archive formats read with indexing.archives.
+var archiveExts = []string{".zip", ".tar.gz", ".tgz"}
+
+// IsArchive reports whether path names a supported archive.
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiskPath returns the file on disk that holds path: the archive for a
+// file inside one, else path itself.
+func DiskPath(p string) string {
+	for i := strings.Index(p, ArchiveSep); i >= 0; {
+		if IsArchive(p[:i]) {
+			return p[:i]
+		}
+		j := strings.Index(p[i+1:], ArchiveSep)
+		if j < 0 {
+			break
+		}
+		i += 1 + j
+	}
+	return p
+}
+
+// errArchiveLimit stops reading an archive that exceeds
+// indexing.archives.
+var errArchiveLimit = errors.New("archive limit reached")
+
+// archiveEntry is a regular file inside an archive. read returns at most
+// limit bytes of it, failing beyond that.
+type archiveEntry struct {
+	name    string
+	size    int64
+	modTime time.Time
+	read    func(limit int64) ([]byte, error)
+}
+
+// archiveReader selects the files of one archive the way walkEligible
+// selects files on disk and enforces indexing.archives.
+type archiveReader struct {
+	path     string
+	opts     WalkOptions
+	config   *cfg.Config
+	exclude  *pathglob.Set
+	maxBytes int64
+	// entries and total count the files read and their uncompressed bytes
+	entries int
+	total   int64
+}
+
+// walkArchive calls fn for each eligible file of the archive at p. Reading
+// stops at indexing.archives.max_entries or max_total_mb, or at a corrupt
+// entry, with a log line; what was read by then is kept. Only errors of fn
+// are returned.
+func walkArchive(p string, opts WalkOptions, config *cfg.Config, fn func(File) error) error {
+	exclude, err := pathglob.Compile(append(append([]string{}, config.Indexing.Exclude...), opts.Exclude...))
+	if err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	a := &archiveReader{path: p, opts: opts, config: config, exclude: exclude, maxBytes: int64(config.Indexing.MaxFileKB) * 1024}
+	var fnErr error
+	err = a.each(func(e archiveEntry) error {
+		if !a.eligible(e) {
+			return nil
+		}
+		ac := config.Indexing.Archives
+		if ac.MaxEntries > 0 && a.entries >= ac.MaxEntries {
+			return fmt.Errorf("%w: more than %d files", errArchiveLimit, ac.MaxEntries)
+		}
+		if ac.MaxTotalMB > 0 && a.total+e.size > int64(ac.MaxTotalMB)<<20 {
+			return fmt.Errorf("%w: more than %d MB uncompressed", errArchiveLimit, ac.MaxTotalMB)
+		}
+		// Headers can lie about sizes; never read more than they promise
+		b, err := e.read(e.size)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.name, err)
+		}
+		a.entries++
+		a.total += int64(len(b))
+		// EPUB books are zip files themselves, binary until converted
+		if len(b) == 0 || (!hasChapters(e.name) && bytes.IndexByte(b[:min(len(b), binarySniffBytes)], 0) >= 0) {
+			return nil
+		}
+		text, err := DocumentText(e.name, b, a.config)
+		if err != nil {
+			log.Printf("Warning: skipping %s%s%s: %v", p, ArchiveSep, e.name, err)
+			return nil
+		}
+		fnErr = fn(File{Path: p + ArchiveSep + e.name, Text: text, ModTime: e.modTime})
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		log.Printf("Warning: stopped reading %s after %d files: %v", p, a.entries, err)
+	}
+	return nil
+}
+
+// countArchive counts the files walkArchive would read from the archive at
+// p, without reading them. A corrupt archive counts what was listed before
+// the damage.
+func countArchive(p string, opts WalkOptions, config *cfg.Config) (int, error) {
+	exclude, err := pathglob.Compile(append(append([]string{}, config.Indexing.Exclude...), opts.Exclude...))
+	if err != nil {
+		return 0, fmt.Errorf("exclude: %w", err)
+	}
+	a := &archiveReader{path: p, opts: opts, config: config, exclude: exclude, maxBytes: int64(config.Indexing.MaxFileKB) * 1024}
+	n := 0
+	limit := config.Indexing.Archives.MaxEntries
+	_ = a.each(func(e archiveEntry) error {
+		if a.eligible(e) {
+			if n++; limit > 0 && n >= limit {
+				return errArchiveLimit
+			}
+		}
+		return nil
+	})
+	return n, nil
+}
+
+// eligible applies the file type, size, exclude_dirs and exclude rules to
+// an entry's path inside the archive.
+func (a *archiveReader) eligible(e archiveEntry) bool {
+	if a.maxBytes > 0 && e.size > a.maxBytes {
+		return false
+	}
+	dirs := strings.Split(path.Dir(e.name), "/")
+	for _, d := range dirs {
+		if slices.Contains(a.config.Indexing.ExcludeDirs, d) {
+			return false
+		}
+	}
+	if a.exclude.Match(e.name, false) {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(e.name))
+	return a.config.IsDocumentationFile(ext) || (a.opts.IncludeCode && a.config.IsCodeFile(ext))
+}
+
+// each calls fn for the regular files of the archive in their stored order.
+func (a *archiveReader) each(fn func(archiveEntry) error) error {
+	if strings.HasSuffix(strings.ToLower(a.path), ".zip") {
+		return a.eachZip(fn)
+	}
+	return a.eachTarGz(fn)
+}
+
+func (a *archiveReader) eachZip(fn func(archiveEntry) error) error {
+	zr, err := zip.OpenReader(a.path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		name, ok := entryName(f.Name)
+		if !ok || !f.Mode().IsRegular() {
+			continue
+		}
+		err := fn(archiveEntry{name: name, size: int64(f.UncompressedSize64), modTime: f.Modified, read: func(limit int64) ([]byte, error) {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return readLimited(rc, limit)
+		}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *archiveReader) eachTarGz(fn func(archiveEntry) error) error {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, ok := entryName(h.Name)
+		if !ok || h.Typeflag != tar.TypeReg {
+			continue
+		}
+		err = fn(archiveEntry{name: name, size: h.Size, modTime: h.ModTime, read: func(limit int64) ([]byte, error) {
+			return readLimited(tr, limit)
+		}})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// entryName cleans the path of an archive entry, rejecting absolute paths
+// and paths that climb out of the archive.
+func entryName(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "./"))
+	if name == "." || strings.HasPrefix(name, "/") || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
+
+// readLimited reads r to the end, failing when it holds more than limit
+// bytes.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("%w: entry larger than its header says", errArchiveLimit)
+	}
+	return b, nil
+}
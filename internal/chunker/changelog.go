@@ -0,0 +1,161 @@
+package chunker
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// changelogEntry is the heading of one version in a changelog.
+type changelogEntry struct {
+	Version string // normalized, see NormalizeVersion
+	Date    string // YYYY-MM-DD, "" when the heading has none
+	Start   int
+}
+
+// versionHeadingRE matches the version at the start of a heading title in
+// the usual changelog styles: "[1.2.0] - 2024-01-31" (Keep a Changelog),
+// "v1.2.0 (2024-01-31)", "Version 1.2", "[1.2.0](https://...) 2024-01-31".
+var versionHeadingRE = regexp.MustCompile(`(?i)^\[?((?:version|release)\s+)?(v)?(\d+(?:\.\d+)*(?:-[0-9a-z.]+)?(?:\+[0-9a-z.]+)?)\]?`)
+
+var unreleasedHeadingRE = regexp.MustCompile(`(?i)^\[?unreleased\]?`)
+
+var isoDateRE = regexp.MustCompile(`\b(\d{4})[-/](\d{2})[-/](\d{2})\b`)
+
+// changelogDateLayouts are the spelled-out dates accepted after a version.
+var changelogDateLayouts = []string{"January 2, 2006", "Jan 2, 2006", "2 January 2006", "2 Jan 2006"}
+
+var spelledDateRE = regexp.MustCompile(`(?i)\b(?:\d{1,2} [a-z]{3,9}\.? \d{4}|[a-z]{3,9}\.? \d{1,2},? \d{4})\b`)
+
+// changelogEntries returns the version headings of a changelog, those at
+// the level of the first one, or nil when path is not a changelog under
+// chunking.changelogs or has no version headings.
+func changelogEntries(path, text string, config *cfg.Config) []changelogEntry {
+	if !config.IsChangelog(path) {
+		return nil
+	}
+	var out []changelogEntry
+	level := 0
+	for _, h := range Headings(text) {
+		if level != 0 && h.Level != level {
+			continue
+		}
+		version, rest, ok := versionHeading(h.Title)
+		if !ok {
+			continue
+		}
+		level = h.Level
+		out = append(out, changelogEntry{Version: version, Date: changelogDate(rest), Start: h.Start})
+	}
+	return out
+}
+
+// versionHeading reads the version a heading title starts with and returns
+// the rest of the title.
+func versionHeading(title string) (version, rest string, ok bool) {
+	if m := unreleasedHeadingRE.FindString(title); m != "" {
+		return "unreleased", title[len(m):], true
+	}
+	m := versionHeadingRE.FindStringSubmatchIndex(title)
+	if m == nil {
+		return "", "", false
+	}
+	rest = title[m[1]:]
+	// "1.2.0" or "v2", not "2 things to know" or "1.2.0beta"
+	if rest != "" && (isLetter(rest[0]) || rest[0] >= '0' && rest[0] <= '9' || rest[0] == '_') {
+		return "", "", false
+	}
+	prefixed := m[2] >= 0 || m[4] >= 0
+	if !prefixed && !strings.Contains(title[m[6]:m[7]], ".") {
+		return "", "", false
+	}
+	return NormalizeVersion(title[m[6]:m[7]]), rest, true
+}
+
+// changelogDate finds the release date in the rest of a version heading.
+func changelogDate(rest string) string {
+	if m := isoDateRE.FindStringSubmatch(rest); m != nil {
+		return m[1] + "-" + m[2] + "-" + m[3]
+	}
+	if m := spelledDateRE.FindString(rest); m != "" {
+		m = strings.Replace(m, ".", "", 1)
+		for _, layout := range changelogDateLayouts {
+			if t, err := time.Parse(layout, m); err == nil {
+				return t.Format(time.DateOnly)
+			}
+		}
+	}
+	return ""
+}
+
+// splitAt cuts text at each start, then windows segments longer than size
+// on their own; segments are never packed into one chunk. It keeps
+// changelog versions and email messages apart.
+func splitAt(text string, starts []int, size, overlap int) []string {
+	var out []string
+	cut := func(seg string) {
+		if strings.TrimSpace(seg) == "" {
+			return
+		}
+		if len([]rune(seg)) > size {
+			out = append(out, chunkText(seg, size, overlap)...)
+			return
+		}
+		out = append(out, seg)
+	}
+	prev := 0
+	for _, start := range starts {
+		cut(text[prev:start])
+		prev = start
+	}
+	cut(text[prev:])
+	return out
+}
+
+// versionAt returns the entry a chunk starting at start belongs to.
+func versionAt(entries []changelogEntry, start int) (changelogEntry, bool) {
+	var cur changelogEntry
+	found := false
+	for _, e := range entries {
+		if e.Start > start {
+			break
+		}
+		cur, found = e, true
+	}
+	return cur, found
+}
+
+// NormalizeVersion puts a version as written in a heading or a search
+// filter in the form stored in payloads: lower case, without brackets, a
+// "version " prefix or a leading "v".
+func NormalizeVersion(v string) string {
+	v = strings.ToLower(strings.Trim(strings.TrimSpace(v), "[]"))
+	for _, p := range []string{"version ", "release "} {
+		v = strings.TrimSpace(strings.TrimPrefix(v, p))
+	}
+	if len(v) > 1 && v[0] == 'v' && v[1] >= '0' && v[1] <= '9' {
+		v = v[1:]
+	}
+	return v
+}
+
+// VersionPrefixes lists what a version filter may name to select version
+// v: each leading run of its numeric parts and v itself, so "2.3" selects
+// 2.3.0 and 2.3.1-rc.1.
+func VersionPrefixes(v string) []string {
+	core := v
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		core = v[:i]
+	}
+	var out []string
+	parts := strings.Split(core, ".")
+	for i := range parts {
+		out = append(out, strings.Join(parts[:i+1], "."))
+	}
+	if out[len(out)-1] != v {
+		out = append(out, v)
+	}
+	return out
+}
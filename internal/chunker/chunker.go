@@ -1,11 +1,19 @@
 package chunker
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/pathglob"
 )
 
 type Chunk struct {
@@ -13,19 +21,188 @@ type Chunk struct {
 	Path     string
 	Text     string
 	Position int
+	FileHash string // sha256 of the whole source file
+	Kind     string // "" for plain text windows, "summary" for generated file summaries, "toc" for tables of contents
+	// Start and End are the chunk's byte range in the source file; End 0
+	// means the range is unknown (e.g. generated summaries)
+	Start, End int
+	Modified   time.Time // source file modification time
+	// Headings is the outline of the file on table-of-contents chunks
+	Headings []Heading
+	// Section is the title of the heading the chunk falls under, "" for
+	// code and text before the first heading
+	Section string
+	// Chapter is the title of the chapter the chunk falls under in EPUB
+	// and LaTeX documents
+	Chapter string
+	// Symbols names the schema definitions (messages, services, types) the
+	// chunk covers in .proto and .graphql files
+	Symbols []string
+	// Version and ReleaseDate come from the changelog entry the chunk
+	// belongs to
+	Version     string
+	ReleaseDate string
+	// ReferencedBy is the document that references an image chunk's image
+	ReferencedBy string
+	// StartTime and EndTime span a transcript chunk's cues as HH:MM:SS
+	StartTime, EndTime string
+	// Message is the email the chunk belongs to, nil for other files
+	Message *Message
+	// ACL holds the access labels access.rules gave the chunk's file
+	ACL []string
 }
 
-func readDocs(dir string, includeCode bool, config *cfg.Config) ([]struct{ Path, Text string }, error) {
-	var out []struct{ Path, Text string }
+// File is a document read from disk before chunking.
+type File struct {
+	Path    string
+	Text    string
+	ModTime time.Time
+	// Reused is set, and Text left empty, for files WalkOptions.Reuse took
+	// from a cache
+	Reused bool
+}
+
+func readDocs(dir string, includeCode bool, config *cfg.Config) ([]File, error) {
+	var out []File
+	err := WalkFiles(dir, WalkOptions{IncludeCode: includeCode}, config, func(f File) error {
+		out = append(out, f)
+		return nil
+	})
+	return out, err
+}
+
+// WalkOptions selects the files WalkFiles reads. Patterns use the pathglob
+// syntax and match paths relative to the walked directory.
+type WalkOptions struct {
+	IncludeCode bool
+	// Include, when set, limits the walk to matching files, whatever their
+	// extension; files not recognized as documentation or code must look
+	// like text
+	Include []string
+	// Exclude is skipped in addition to indexing.exclude
+	Exclude []string
+	// Reuse, when set, is asked about each file before it is read; when it
+	// returns true the file is passed on unread, with Reused set
+	Reuse func(path string, info os.FileInfo) bool
+}
+
+// binarySniffBytes is how much of a file is checked for NUL bytes before
+// indexing a file picked by an include pattern.
+const binarySniffBytes = 8000
+
+// WalkFiles walks dir and calls fn for each eligible file as soon as it is
+// read, so only one file is held in memory at a time. An error from fn stops
+// the walk and is returned.
+func WalkFiles(dir string, opts WalkOptions, config *cfg.Config, fn func(File) error) error {
+	maxBytes := int64(config.Indexing.MaxFileKB) * 1024
+	return walkEligible(dir, opts, config, func(path string) error {
+		return walkArchive(path, opts, config, fn)
+	}, func(path string, info os.FileInfo, isDoc, sniff bool) error {
+		// Size check before reading
+		if maxBytes > 0 && info.Size() > maxBytes {
+			return nil
+		}
+		if opts.Reuse != nil && opts.Reuse(path, info) {
+			return fn(File{Path: path, ModTime: info.ModTime(), Reused: true})
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if sniff && bytes.IndexByte(b[:min(len(b), binarySniffBytes)], 0) >= 0 {
+			return nil
+		}
+		// Documentation files are kept even when empty
+		if len(b) == 0 && !isDoc {
+			return nil
+		}
+		text, err := DocumentText(path, b, config)
+		if err != nil {
+			log.Printf("Warning: skipping %s: %v", path, err)
+			return nil
+		}
+		return fn(File{Path: path, Text: text, ModTime: info.ModTime()})
+	})
+}
+
+// DocumentText returns the text indexed for a file's content: EPUB books
+// and LaTeX sources are converted to markdown-shaped text with one heading
+// per chapter and section, transcripts to one line per cue and emails to
+// one block per message; other files are taken as they are. Chunk byte
+// ranges refer to this text.
+func DocumentText(path string, b []byte, config *cfg.Config) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".epub":
+		return epubText(b)
+	case ".tex":
+		return latexText(string(b)), nil
+	case ".vtt", ".srt":
+		return transcriptText(string(b)), nil
+	case ".eml", ".mbox":
+		return emailText(path, b, config.Indexing.Email.Attachments)
+	}
+	return string(b), nil
+}
+
+// hasChapters reports whether DocumentText turns the file's chapters into
+// level-1 headings.
+func hasChapters(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".epub" || ext == ".tex"
+}
+
+// errCountDone stops CountFiles once it has seen enough files.
+var errCountDone = errors.New("count done")
+
+// CountFiles counts the files WalkFiles would read from dir without reading
+// them, stopping once the count exceeds stopAfter (0 counts everything).
+// Empty files and files an include pattern picks that turn out binary are
+// counted too.
+func CountFiles(dir string, opts WalkOptions, config *cfg.Config, stopAfter int) (int, error) {
+	maxBytes := int64(config.Indexing.MaxFileKB) * 1024
+	n := 0
+	err := walkEligible(dir, opts, config, func(path string) error {
+		inner, err := countArchive(path, opts, config)
+		if n += inner; stopAfter > 0 && n > stopAfter {
+			return errCountDone
+		}
+		return err
+	}, func(path string, info os.FileInfo, isDoc, sniff bool) error {
+		if maxBytes > 0 && info.Size() > maxBytes {
+			return nil
+		}
+		if n++; stopAfter > 0 && n > stopAfter {
+			return errCountDone
+		}
+		return nil
+	})
+	if errors.Is(err, errCountDone) {
+		err = nil
+	}
+	return n, err
+}
+
+// walkEligible calls visit for each file of dir that opts and the indexing
+// config select. isDoc marks documentation files; sniff marks files picked
+// only by an include pattern, which must be checked for binary content.
+// Archives go to visitArchive instead when indexing.archives is enabled.
+func walkEligible(dir string, opts WalkOptions, config *cfg.Config, visitArchive func(path string) error, visit func(path string, info os.FileInfo, isDoc, sniff bool) error) error {
 	// Normalize base dir
 	baseAbs, _ := filepath.Abs(dir)
-	exclude := map[string]struct{}{}
+	excludeDirs := map[string]struct{}{}
 	for _, d := range config.Indexing.ExcludeDirs {
-		exclude[d] = struct{}{}
+		excludeDirs[d] = struct{}{}
+	}
+	exclude, err := pathglob.Compile(append(append([]string{}, config.Indexing.Exclude...), opts.Exclude...))
+	if err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	include, err := pathglob.Compile(opts.Include)
+	if err != nil {
+		return fmt.Errorf("include: %w", err)
 	}
-	maxBytes := int64(config.Indexing.MaxFileKB) * 1024
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	return walkTree(dir, config.Indexing.FollowSymlinks, config.Indexing.MaxDepth, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -36,10 +213,18 @@ func readDocs(dir string, includeCode bool, config *cfg.Config) ([]struct{ Path,
 			}
 			return nil
 		}
-		// Skip excluded directories
+		rel, relErr := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		// Skip excluded directories and files
+		if relErr == nil && exclude.Match(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if info.IsDir() {
 			name := filepath.Base(path)
-			if _, ok := exclude[name]; ok {
+			if _, ok := excludeDirs[name]; ok {
 				return filepath.SkipDir
 			}
 			return nil
@@ -49,40 +234,25 @@ func readDocs(dir string, includeCode bool, config *cfg.Config) ([]struct{ Path,
 			return nil
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-
-		// Documentation files - always include
-		if config.IsDocumentationFile(ext) {
-			// Size check before reading
-			if maxBytes > 0 && info.Size() > maxBytes {
+		if config.Indexing.Archives.Enabled && IsArchive(path) {
+			// An include pattern must pick the archive itself
+			if !include.Empty() && (relErr != nil || !include.Match(rel, false)) {
 				return nil
 			}
-			b, err := os.ReadFile(path)
-			if err != nil {
-				return err
-			}
-			out = append(out, struct{ Path, Text string }{path, string(b)})
-			return nil
+			return visitArchive(path)
 		}
-
-		// Code files - only if includeCode is true
-		if includeCode && config.IsCodeFile(ext) {
-			if maxBytes > 0 && info.Size() > maxBytes {
+		ext := strings.ToLower(filepath.Ext(path))
+		isDoc := config.IsDocumentationFile(ext)
+		isCode := config.IsCodeFile(ext)
+		if !include.Empty() {
+			if relErr != nil || !include.Match(rel, false) {
 				return nil
 			}
-			b, err := os.ReadFile(path)
-			if err != nil {
-				return err
-			}
-			text := string(b)
-			if len(text) > 0 {
-				out = append(out, struct{ Path, Text string }{path, text})
-			}
+		} else if !isDoc && !(opts.IncludeCode && isCode) {
+			return nil
 		}
-
-		return nil
+		return visit(path, info, isDoc, !isDoc && !isCode)
 	})
-	return out, err
 }
 
 func chunkText(text string, size, overlap int) []string {
@@ -118,20 +288,93 @@ func MakeChunks(dir string, size, overlap int, includeCode bool, config *cfg.Con
 	if err != nil {
 		return nil, err
 	}
+	return ChunkFiles(files, size, overlap, config), nil
+}
+
+// ChunkFiles splits already loaded files into chunks. size and overlap apply
+// unless config has a chunking profile for the file's extension.
+func ChunkFiles(files []File, size, overlap int, config *cfg.Config) []Chunk {
 	var out []Chunk
 	for _, f := range files {
-		parts := chunkText(f.Text, size, overlap)
+		hash := FileHash(f.Text)
+		fsize, foverlap, strategy := config.ChunkProfileFor(f.Path, size, overlap)
+		entries := changelogEntries(f.Path, f.Text, config)
+		var parts []string
+		messages := emailEntries(f.Path, f.Text)
+		var marks [][]int
+		switch {
+		case len(entries) > 0:
+			starts := make([]int, len(entries))
+			for i, e := range entries {
+				starts[i] = e.Start
+			}
+			parts = splitAt(f.Text, starts, fsize, foverlap)
+		case len(messages) > 0:
+			starts := make([]int, len(messages))
+			for i, m := range messages {
+				starts[i] = m.Start
+			}
+			parts = splitAt(f.Text, starts, fsize, foverlap)
+		case isTranscript(f.Path):
+			parts = transcriptSections(f.Text, fsize, foverlap)
+			marks = timecodeRE.FindAllStringSubmatchIndex(f.Text, -1)
+		default:
+			parts = splitText(f.Path, f.Text, fsize, foverlap, strategy)
+		}
+		// "#" starts comments in many languages, not headings
+		var headings, chapters []Heading
+		if config.GetFileType(f.Path) != "code" {
+			headings = Headings(f.Text)
+		}
+		var defs []schemaDef
+		if strategy == cfg.StrategySchema {
+			defs = schemaDefs(f.Path, f.Text)
+		}
+		if hasChapters(f.Path) {
+			for _, h := range headings {
+				if h.Level == 1 {
+					chapters = append(chapters, h)
+				}
+			}
+		}
+		cursor := 0
 		for i, p := range parts {
 			id := filepath.Base(f.Path) + ":" + intToStr(i)
-			out = append(out, Chunk{
+			c := Chunk{
 				ID:       id,
 				Path:     f.Path,
 				Text:     p,
 				Position: i,
-			})
+				FileHash: hash,
+				Modified: f.ModTime,
+			}
+			// Parts are windows of the file in order; overlapping windows
+			// start after the previous one
+			if at := strings.Index(f.Text[cursor:], p); p != "" && at >= 0 {
+				c.Start = cursor + at
+				c.End = c.Start + len(p)
+				cursor = c.Start + 1
+				c.Section = sectionAt(headings, c.Start, c.End)
+				c.Chapter = sectionAt(chapters, c.Start, c.End)
+				c.Symbols = symbolsAt(defs, c.Start, c.End)
+				if e, ok := versionAt(entries, c.Start); ok {
+					c.Version, c.ReleaseDate = e.Version, e.Date
+				}
+				c.Message = messageAt(messages, c.Start)
+				if len(marks) > 0 {
+					c.StartTime, c.EndTime = timecodesAt(marks, f.Text, c.Start, c.End)
+				}
+			}
+			out = append(out, c)
 		}
 	}
-	return out, nil
+	return out
+}
+
+// FileHash returns the hex sha256 of a file's text, as stored on its chunks.
+func FileHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
 }
 
 // Simple integer to string conversion
@@ -0,0 +1,297 @@
+package chunker
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Message describes the email a chunk belongs to.
+type Message struct {
+	Subject string
+	From    string
+	// Date is RFC 3339 in UTC, "" when the message has no readable date
+	Date string
+	// ThreadID is the Message-ID that starts the thread: the first of
+	// References, else In-Reply-To, else the message's own ID
+	ThreadID string
+}
+
+// emailEntry is a message of an email file and where it starts in the text
+// DocumentText made of it.
+type emailEntry struct {
+	Message
+	Start int
+}
+
+// emailHeaderRE matches the header block emailText writes at the start of
+// each message.
+var emailHeaderRE = regexp.MustCompile(`(?m)^# (.*)\nFrom: (.*)\nDate: (.*)\nThread: (.*)\n`)
+
+// blockquoteRE matches the quoted reply of an HTML body.
+var blockquoteRE = regexp.MustCompile(`(?is)<blockquote\b.*?</blockquote>`)
+
+// attributionRE matches the "On ..., X wrote:" line above a quoted reply.
+var attributionRE = regexp.MustCompile(`(?i)^on .+ wrote:$`)
+
+// isEmail reports whether path is an .eml message or an .mbox mailbox.
+func isEmail(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".eml" || ext == ".mbox"
+}
+
+// emailText reduces an .eml or .mbox file to markdown-shaped text, one
+// block per message: the subject as a level-1 heading, From, Date and
+// Thread lines, then the body. Plain text bodies are preferred over HTML
+// ones; quoted replies are dropped, since the thread holds them already.
+// Attachments are skipped unless attachments is set, which adds the text
+// ones under a "## Attachment: name" heading. Unreadable messages of a
+// mailbox are skipped.
+func emailText(path string, b []byte, attachments bool) (string, error) {
+	raws := [][]byte{b}
+	if strings.ToLower(filepath.Ext(path)) == ".mbox" {
+		raws = mboxMessages(b)
+	}
+	var blocks []string
+	var firstErr error
+	for _, raw := range raws {
+		block, err := emailBlock(raw, attachments)
+		if err != nil {
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 && firstErr != nil {
+		return "", firstErr
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+func emailBlock(raw []byte, attachments bool) (string, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	dec := new(mime.WordDecoder)
+	header := func(key string) string {
+		v := msg.Header.Get(key)
+		if d, err := dec.DecodeHeader(v); err == nil {
+			v = d
+		}
+		return strings.Join(strings.Fields(v), " ")
+	}
+	m := Message{Subject: header("Subject"), From: header("From")}
+	if m.Subject == "" {
+		m.Subject = "(no subject)"
+	}
+	if list, err := msg.Header.AddressList("From"); err == nil && len(list) > 0 {
+		m.From = list[0].Address
+		if list[0].Name != "" {
+			m.From = list[0].Name + " <" + list[0].Address + ">"
+		}
+	}
+	if t, err := msg.Header.Date(); err == nil {
+		m.Date = t.UTC().Format(time.RFC3339)
+	}
+	m.ThreadID = threadID(msg.Header)
+
+	body, atts := emailPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, attachments)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\nFrom: %s\nDate: %s\nThread: %s\n", m.Subject, m.From, m.Date, m.ThreadID)
+	if body = demoteHeadings(emailBody(body)); body != "" {
+		b.WriteString("\n" + body + "\n")
+	}
+	for _, a := range atts {
+		fmt.Fprintf(&b, "\n## Attachment: %s\n\n%s\n", a.name, strings.TrimSpace(a.text))
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// threadID returns the ID of the first message of h's thread, without
+// angle brackets.
+func threadID(h mail.Header) string {
+	for _, key := range []string{"References", "In-Reply-To", "Message-Id"} {
+		if ids := strings.Fields(h.Get(key)); len(ids) > 0 {
+			return strings.Trim(ids[0], "<>")
+		}
+	}
+	return ""
+}
+
+type emailAttachment struct {
+	name, text string
+}
+
+// emailPart returns the body text of a MIME part and the text attachments
+// under it when attachments is set. multipart/alternative prefers the
+// plain text version; other multiparts take the first body found.
+func emailPart(contentType, encoding string, r io.Reader, attachments bool) (string, []emailAttachment) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		mediaType, params = "text/plain", nil
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return partText(mediaType, params["charset"], encoding, r), nil
+	}
+	mr := multipart.NewReader(r, params["boundary"])
+	var body, html string
+	var atts []emailAttachment
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		ct := p.Header.Get("Content-Type")
+		cte := p.Header.Get("Content-Transfer-Encoding")
+		if name := p.FileName(); name != "" || strings.HasPrefix(strings.ToLower(p.Header.Get("Content-Disposition")), "attachment") {
+			pt, pp, _ := mime.ParseMediaType(ct)
+			if attachments && strings.HasPrefix(pt, "text/") {
+				if text := partText(pt, pp["charset"], cte, p); strings.TrimSpace(text) != "" {
+					atts = append(atts, emailAttachment{name: cmp.Or(name, "untitled"), text: text})
+				}
+			}
+			continue
+		}
+		text, nested := emailPart(ct, cte, p, attachments)
+		atts = append(atts, nested...)
+		switch {
+		case mediaType == "multipart/alternative" && strings.HasPrefix(strings.ToLower(ct), "text/html"):
+			html = cmp.Or(html, text)
+		case body == "":
+			body = text
+		}
+	}
+	return cmp.Or(body, html), atts
+}
+
+// partText decodes a leaf part: text/plain as is, text/html reduced to its
+// text without quoted replies, anything else to "".
+func partText(mediaType, charset, encoding string, r io.Reader) string {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return ""
+	}
+	if c := strings.ToLower(charset); c == "iso-8859-1" || c == "latin1" || c == "windows-1252" {
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		b = []byte(string(runes))
+	}
+	switch {
+	case mediaType == "text/html":
+		return htmlText(blockquoteRE.ReplaceAllString(string(b), ""))
+	case strings.HasPrefix(mediaType, "text/"):
+		return string(b)
+	}
+	return ""
+}
+
+// emailBody normalizes line endings and drops quoted replies with the
+// attribution line above them.
+func emailBody(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	var out []string
+	for i, l := range lines {
+		t := strings.TrimSpace(l)
+		if strings.HasPrefix(t, ">") {
+			continue
+		}
+		if attributionRE.MatchString(t) && i+1 < len(lines) && nextQuoted(lines[i+1:]) {
+			continue
+		}
+		out = append(out, strings.TrimRight(l, " \t"))
+	}
+	return strings.TrimSpace(blankLinesRE.ReplaceAllString(strings.Join(out, "\n"), "\n\n"))
+}
+
+// nextQuoted reports whether the first non-blank line is quoted.
+func nextQuoted(lines []string) bool {
+	for _, l := range lines {
+		if t := strings.TrimSpace(l); t != "" {
+			return strings.HasPrefix(t, ">")
+		}
+	}
+	return false
+}
+
+// mboxMessages splits an mbox file at its "From " separator lines and
+// undoes the ">From " quoting of lines inside messages.
+func mboxMessages(b []byte) [][]byte {
+	var out [][]byte
+	var cur []string
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, []byte(strings.Join(cur, "\n")))
+		}
+		cur = nil
+	}
+	prevBlank := true
+	for _, l := range strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n") {
+		if prevBlank && strings.HasPrefix(l, "From ") {
+			flush()
+			cur = []string{}
+			prevBlank = false
+			continue
+		}
+		if cur != nil {
+			if t := strings.TrimLeft(l, ">"); len(t) < len(l) && strings.HasPrefix(t, "From ") {
+				l = l[1:]
+			}
+			cur = append(cur, l)
+		}
+		prevBlank = l == ""
+	}
+	flush()
+	if out == nil {
+		// Not an mbox after all: one message
+		return [][]byte{b}
+	}
+	return out
+}
+
+// emailEntries returns the messages of an email file's text, or nil for
+// other files.
+func emailEntries(path, text string) []emailEntry {
+	if !isEmail(path) {
+		return nil
+	}
+	var out []emailEntry
+	for _, m := range emailHeaderRE.FindAllStringSubmatchIndex(text, -1) {
+		out = append(out, emailEntry{
+			Message: Message{Subject: text[m[2]:m[3]], From: text[m[4]:m[5]], Date: text[m[6]:m[7]], ThreadID: text[m[8]:m[9]]},
+			Start:   m[0],
+		})
+	}
+	return out
+}
+
+// messageAt returns the message a chunk starting at start belongs to.
+func messageAt(entries []emailEntry, start int) *Message {
+	var cur *Message
+	for i := range entries {
+		if entries[i].Start > start {
+			break
+		}
+		cur = &entries[i].Message
+	}
+	return cur
+}
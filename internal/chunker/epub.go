@@ -0,0 +1,212 @@
+package chunker
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// epubMaxBytes bounds the uncompressed content read from one EPUB.
+const epubMaxBytes = 64 << 20
+
+var epubNavLinkRE = regexp.MustCompile(`(?is)<a\b([^>]*)>(.*?)</a>`)
+
+// epubText reduces an EPUB to markdown-shaped text: one level-1 heading per
+// chapter of the spine, named by the table of contents, followed by the
+// chapter's text with its own headings one level down.
+func epubText(b []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return "", fmt.Errorf("epub: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	budget := int64(epubMaxBytes)
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("epub: missing %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		data, err := readLimited(rc, budget)
+		if err != nil {
+			return nil, fmt.Errorf("epub: %s: %w", name, err)
+		}
+		budget -= int64(len(data))
+		return data, nil
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	data, err := read("META-INF/container.xml")
+	if err != nil {
+		return "", err
+	}
+	if err := xml.Unmarshal(data, &container); err != nil || len(container.Rootfiles) == 0 {
+		return "", fmt.Errorf("epub: no package document in container.xml")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg struct {
+		Title    string `xml:"metadata>title"`
+		Manifest []struct {
+			ID         string `xml:"id,attr"`
+			Href       string `xml:"href,attr"`
+			MediaType  string `xml:"media-type,attr"`
+			Properties string `xml:"properties,attr"`
+		} `xml:"manifest>item"`
+		Spine struct {
+			TOC   string `xml:"toc,attr"`
+			Items []struct {
+				IDRef string `xml:"idref,attr"`
+			} `xml:"itemref"`
+		} `xml:"spine"`
+	}
+	if data, err = read(opfPath); err != nil {
+		return "", err
+	}
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return "", fmt.Errorf("epub: package document: %w", err)
+	}
+	base := path.Dir(opfPath)
+	hrefs := map[string]string{}
+	labels := map[string]string{}
+	for _, it := range pkg.Manifest {
+		hrefs[it.ID] = epubResolve(base, it.Href)
+		// EPUB 3 navigation document, or the EPUB 2 NCX named by the spine
+		if strings.Contains(" "+it.Properties+" ", " nav ") {
+			if nav, err := read(hrefs[it.ID]); err == nil {
+				epubNavLabels(nav, path.Dir(hrefs[it.ID]), labels)
+			}
+		} else if it.ID == pkg.Spine.TOC || it.MediaType == "application/x-dtbncx+xml" {
+			if ncx, err := read(hrefs[it.ID]); err == nil {
+				epubNCXLabels(ncx, path.Dir(hrefs[it.ID]), labels)
+			}
+		}
+	}
+
+	var out strings.Builder
+	if t := strings.TrimSpace(pkg.Title); t != "" {
+		out.WriteString(t + "\n\n")
+	}
+	for i, ref := range pkg.Spine.Items {
+		name, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		page, err := read(name)
+		if err != nil {
+			return "", err
+		}
+		text := ParseHTML(string(page)).Text
+		title := labels[name]
+		if hs := Headings(text); len(hs) > 0 && hs[0].Start == 0 {
+			if title == "" {
+				title = hs[0].Title
+			}
+			// The chapter's own title heading would repeat the chapter heading
+			if strings.EqualFold(hs[0].Title, title) {
+				_, rest, _ := strings.Cut(text, "\n")
+				text = strings.TrimSpace(rest)
+			}
+		}
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		out.WriteString("# " + title + "\n\n" + demoteHeadings(text) + "\n\n")
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// epubResolve resolves a manifest or navigation href against dir.
+func epubResolve(dir, href string) string {
+	if u, err := url.PathUnescape(href); err == nil {
+		href = u
+	}
+	if i := strings.IndexByte(href, '#'); i >= 0 {
+		href = href[:i]
+	}
+	return path.Clean(path.Join(dir, href))
+}
+
+// epubNavLabels records the first label of each document linked from an
+// EPUB 3 navigation document.
+func epubNavLabels(nav []byte, dir string, labels map[string]string) {
+	for _, m := range epubNavLinkRE.FindAllStringSubmatch(string(nav), -1) {
+		href := htmlAttrs("<a " + m[1] + ">")["href"]
+		label := htmlText(m[2])
+		if target := epubResolve(dir, href); href != "" && label != "" && labels[target] == "" {
+			labels[target] = label
+		}
+	}
+}
+
+// epubNCXLabels records the first label of each document in an EPUB 2 NCX
+// table of contents.
+func epubNCXLabels(ncx []byte, dir string, labels map[string]string) {
+	type navPoint struct {
+		Label   string `xml:"navLabel>text"`
+		Content struct {
+			Src string `xml:"src,attr"`
+		} `xml:"content"`
+		Children []navPoint `xml:"navPoint"`
+	}
+	var doc struct {
+		Points []navPoint `xml:"navMap>navPoint"`
+	}
+	if xml.Unmarshal(ncx, &doc) != nil {
+		return
+	}
+	var walk func([]navPoint)
+	walk = func(ps []navPoint) {
+		for _, p := range ps {
+			target := epubResolve(dir, p.Content.Src)
+			if label := strings.TrimSpace(p.Label); label != "" && p.Content.Src != "" && labels[target] == "" {
+				labels[target] = label
+			}
+			walk(p.Children)
+		}
+	}
+	walk(doc.Points)
+}
+
+// demoteHeadings shifts the markdown headings of text so the outermost is
+// level 2, making room for a chapter heading above them.
+func demoteHeadings(text string) string {
+	hs := Headings(text)
+	if len(hs) == 0 {
+		return text
+	}
+	top := hs[0].Level
+	for _, h := range hs {
+		top = min(top, h.Level)
+	}
+	var b strings.Builder
+	last := 0
+	for _, h := range hs {
+		level := min(h.Level-top+2, 6)
+		// Headings start at the line; keep its indentation
+		line := text[h.Start:]
+		i := strings.IndexByte(line, '#')
+		b.WriteString(text[last:h.Start] + line[:i] + strings.Repeat("#", level))
+		last = h.Start + i + h.Level
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
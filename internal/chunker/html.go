@@ -0,0 +1,114 @@
+package chunker
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlTitleRE   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlCommentRE = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlMainRE    = regexp.MustCompile(`(?is)<(?:main|article)\b[^>]*>(.*)</(?:main|article)>`)
+	htmlHeadingRE = regexp.MustCompile(`(?i)<h([1-6])\b[^>]*>`)
+	htmlItemRE    = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlBreakRE   = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlBlockRE   = regexp.MustCompile(`(?i)</?(?:p|div|section|h[1-6]|ul|ol|table|tr|pre|blockquote|dl|dt|dd|figure|header|body)\b[^>]*>`)
+	htmlCellRE    = regexp.MustCompile(`(?i)<t[dh]\b[^>]*>`)
+	htmlTagRE     = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlAnchorRE  = regexp.MustCompile(`(?is)<a\b[^>]*>`)
+	htmlLinkRE    = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	htmlMetaRE    = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+	htmlAttrRE    = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	spacesRE      = regexp.MustCompile(`[ \t\r\f\v\x{00a0}]+`)
+	blankLinesRE  = regexp.MustCompile(`\n{3,}`)
+
+	// Elements whose content is never page text, or is site chrome
+	htmlDropped = []string{"head", "script", "style", "noscript", "template", "svg", "iframe", "nav", "aside", "footer", "form"}
+	htmlDropRE  = func() []*regexp.Regexp {
+		out := make([]*regexp.Regexp, len(htmlDropped))
+		for i, t := range htmlDropped {
+			out[i] = regexp.MustCompile(`(?is)<` + t + `\b.*?</` + t + `\s*>`)
+		}
+		return out
+	}()
+)
+
+// HTMLPage is what indexing uses from an HTML page.
+type HTMLPage struct {
+	Title     string
+	Text      string
+	Canonical string   // href of <link rel="canonical">, unresolved
+	Links     []string // hrefs of all <a> elements, unresolved
+	// NoIndex and NoFollow come from <meta name="robots">
+	NoIndex, NoFollow bool
+}
+
+// ParseHTML extracts the title, links and robots directives of a page and
+// reduces it to its readable text: scripts, styles and navigation are
+// dropped, the <main>/<article> content is preferred when the page has one,
+// and headings and list items keep a markdown-like shape so the text chunks
+// like a document.
+func ParseHTML(src string) HTMLPage {
+	var p HTMLPage
+	if m := htmlTitleRE.FindStringSubmatch(src); m != nil {
+		p.Title = strings.TrimSpace(html.UnescapeString(htmlTagRE.ReplaceAllString(m[1], "")))
+	}
+	s := htmlCommentRE.ReplaceAllString(src, "")
+	for _, tag := range htmlAnchorRE.FindAllString(s, -1) {
+		if href, ok := htmlAttrs(tag)["href"]; ok && href != "" {
+			p.Links = append(p.Links, href)
+		}
+	}
+	for _, tag := range htmlLinkRE.FindAllString(s, -1) {
+		attrs := htmlAttrs(tag)
+		if strings.EqualFold(attrs["rel"], "canonical") && p.Canonical == "" {
+			p.Canonical = attrs["href"]
+		}
+	}
+	for _, tag := range htmlMetaRE.FindAllString(s, -1) {
+		attrs := htmlAttrs(tag)
+		if strings.EqualFold(attrs["name"], "robots") {
+			content := strings.ToLower(attrs["content"])
+			p.NoIndex = p.NoIndex || strings.Contains(content, "noindex") || strings.Contains(content, "none")
+			p.NoFollow = p.NoFollow || strings.Contains(content, "nofollow") || strings.Contains(content, "none")
+		}
+	}
+	p.Text = htmlText(s)
+	return p
+}
+
+// htmlAttrs returns the attributes of an opening tag, names lowercased and
+// values unescaped.
+func htmlAttrs(tag string) map[string]string {
+	out := map[string]string{}
+	for _, m := range htmlAttrRE.FindAllStringSubmatch(tag, -1) {
+		out[strings.ToLower(m[1])] = strings.TrimSpace(html.UnescapeString(m[2] + m[3] + m[4]))
+	}
+	return out
+}
+
+func htmlText(s string) string {
+	for _, re := range htmlDropRE {
+		s = re.ReplaceAllString(s, "")
+	}
+	if m := htmlMainRE.FindStringSubmatch(s); m != nil {
+		s = m[1]
+	}
+	s = htmlHeadingRE.ReplaceAllStringFunc(s, func(tag string) string {
+		level := int(htmlHeadingRE.FindStringSubmatch(tag)[1][0] - '0')
+		return "\n\n" + strings.Repeat("#", level) + " "
+	})
+	s = htmlItemRE.ReplaceAllString(s, "\n- ")
+	s = htmlBreakRE.ReplaceAllString(s, "\n")
+	s = htmlBlockRE.ReplaceAllString(s, "\n\n")
+	s = htmlCellRE.ReplaceAllString(s, " ")
+	s = htmlTagRE.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(spacesRE.ReplaceAllString(l, " "))
+	}
+	return strings.TrimSpace(blankLinesRE.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
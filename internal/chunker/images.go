@@ -0,0 +1,98 @@
+package chunker
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ImageRef is an image referenced by a document, with its alt text.
+type ImageRef struct {
+	Src string
+	Alt string
+}
+
+// imageMIME are the image formats indexed with indexing.images.
+var imageMIME = map[string]string{
+	".png": "image/png", ".jpg": "image/jpeg", ".jpeg": "image/jpeg", ".gif": "image/gif",
+	".webp": "image/webp", ".bmp": "image/bmp", ".tif": "image/tiff", ".tiff": "image/tiff",
+}
+
+// ImageMIME returns the media type of an image path, "" for other files.
+func ImageMIME(path string) string {
+	return imageMIME[strings.ToLower(filepath.Ext(path))]
+}
+
+var (
+	mdImageRE    = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+["'(][^)]*)?\)`)
+	mdRefImageRE = regexp.MustCompile(`!\[([^\]]*)\]\[([^\]]*)\]`)
+	mdLinkDefRE  = regexp.MustCompile(`(?m)^ {0,3}\[([^\]]+)\]:\s*<?(\S+?)>?(?:\s|$)`)
+	htmlImgRE    = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+	rstImageRE   = regexp.MustCompile(`^\s*\.\.\s+(?:image|figure)::\s*(\S+)`)
+	rstAltRE     = regexp.MustCompile(`^\s+:alt:\s*(.*)$`)
+	adocImageRE  = regexp.MustCompile(`image::?([^\[\s]+)\[([^\]]*)\]`)
+)
+
+// ImageRefs lists the images text references in markdown (inline and
+// reference style), HTML <img>, reStructuredText image and figure
+// directives and AsciiDoc image macros, in order, each source once.
+func ImageRefs(text string) []ImageRef {
+	var out []ImageRef
+	seen := map[string]bool{}
+	add := func(src, alt string) {
+		src = strings.TrimSpace(src)
+		if src == "" || seen[src] {
+			return
+		}
+		seen[src] = true
+		out = append(out, ImageRef{Src: src, Alt: strings.Join(strings.Fields(alt), " ")})
+	}
+	for _, m := range mdImageRE.FindAllStringSubmatch(text, -1) {
+		add(m[2], m[1])
+	}
+	defs := map[string]string{}
+	for _, m := range mdLinkDefRE.FindAllStringSubmatch(text, -1) {
+		defs[strings.ToLower(m[1])] = m[2]
+	}
+	for _, m := range mdRefImageRE.FindAllStringSubmatch(text, -1) {
+		// ![alt][] uses the alt text as the reference
+		ref := m[2]
+		if ref == "" {
+			ref = m[1]
+		}
+		if src, ok := defs[strings.ToLower(ref)]; ok {
+			add(src, m[1])
+		}
+	}
+	for _, tag := range htmlImgRE.FindAllString(text, -1) {
+		attrs := htmlAttrs(tag)
+		add(attrs["src"], attrs["alt"])
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		m := rstImageRE.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		alt := ""
+		// Options follow the directive, indented
+		for _, opt := range lines[i+1:] {
+			if strings.TrimSpace(opt) == "" || !strings.HasPrefix(opt, " ") && !strings.HasPrefix(opt, "\t") {
+				break
+			}
+			if a := rstAltRE.FindStringSubmatch(opt); a != nil {
+				alt = a[1]
+			}
+		}
+		add(m[1], alt)
+	}
+	for _, m := range adocImageRE.FindAllStringSubmatch(text, -1) {
+		// The first positional attribute is the alt text
+		alt, _, _ := strings.Cut(m[2], ",")
+		if strings.Contains(alt, "=") {
+			alt = ""
+		}
+		add(m[1], strings.Trim(alt, `"`))
+	}
+	return out
+}
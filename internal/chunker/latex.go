@@ -0,0 +1,237 @@
+package chunker
+
+import (
+	"regexp"
+	"strings"
+)
+
+// latexLevels are the sectioning commands, outermost first.
+var latexLevels = []string{"part", "chapter", "section", "subsection", "subsubsection", "paragraph"}
+
+// latexPunctRE matches the space a dropped citation or reference leaves
+// before punctuation.
+var latexPunctRE = regexp.MustCompile(` +([.,;:])`)
+
+var latexSectioningRE = regexp.MustCompile(`\\(part|chapter|section|subsection|subsubsection|paragraph)\*?\s*[\[{]`)
+
+var latexTitleRE = regexp.MustCompile(`\\title\s*(?:\[[^\]]*\])?\s*{`)
+
+// latexDropped are commands left out together with their arguments:
+// references, layout and preamble.
+var latexDropped = map[string]bool{
+	"cite": true, "citep": true, "citet": true, "ref": true, "eqref": true, "pageref": true, "autoref": true, "cref": true, "label": true,
+	"includegraphics": true, "input": true, "include": true, "bibliography": true, "bibliographystyle": true,
+	"documentclass": true, "usepackage": true, "newcommand": true, "renewcommand": true, "providecommand": true, "newenvironment": true,
+	"setlength": true, "setcounter": true, "addtocounter": true, "vspace": true, "hspace": true, "author": true, "date": true, "thanks": true,
+	"maketitle": true, "tableofcontents": true, "listoffigures": true, "listoftables": true, "newpage": true, "clearpage": true, "pagebreak": true,
+	"centering": true, "noindent": true, "par": true, "medskip": true, "bigskip": true, "smallskip": true, "hline": true, "toprule": true,
+	"midrule": true, "bottomrule": true, "appendix": true, "frontmatter": true, "mainmatter": true, "backmatter": true,
+}
+
+// latexVerbatim are environments whose content is kept as it is, in a
+// fenced block.
+var latexVerbatim = map[string]bool{"verbatim": true, "lstlisting": true, "minted": true, "Verbatim": true}
+
+// latexText reduces a LaTeX document to markdown-shaped text: sectioning
+// commands become headings, the outermost level used becoming level 1,
+// listings become fenced blocks, formatting commands keep their text and
+// comments, references and layout commands are dropped.
+func latexText(src string) string {
+	src = latexStripComments(src)
+	c := &latexConverter{top: len(latexLevels)}
+	for _, m := range latexSectioningRE.FindAllStringSubmatch(src, -1) {
+		for i, l := range latexLevels {
+			if l == m[1] && i < c.top {
+				c.top = i
+			}
+		}
+	}
+	if m := latexTitleRE.FindStringIndex(src); m != nil {
+		arg, _ := latexGroup(src, m[1]-1)
+		c.title = strings.TrimSpace(c.convert(arg))
+	}
+	body := src
+	if _, after, ok := strings.Cut(src, `\begin{document}`); ok {
+		body, _, _ = strings.Cut(after, `\end{document}`)
+	}
+	text := c.convert(body)
+	if c.title != "" {
+		text = c.title + "\n\n" + text
+	}
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(spacesRE.ReplaceAllString(l, " "))
+	}
+	text = latexPunctRE.ReplaceAllString(strings.Join(lines, "\n"), "$1")
+	return strings.TrimSpace(blankLinesRE.ReplaceAllString(text, "\n\n"))
+}
+
+type latexConverter struct {
+	// top is the index in latexLevels of the outermost sectioning command
+	// used
+	top   int
+	title string
+}
+
+func (c *latexConverter) convert(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch ch := s[i]; ch {
+		case '\\':
+			i = c.command(s, i, &b)
+		case '{', '}', '$':
+			i++
+		case '~':
+			b.WriteByte(' ')
+			i++
+		case '`', '\'':
+			// ``quotes'' become plain double quotes
+			if i+1 < len(s) && s[i+1] == ch {
+				b.WriteByte('"')
+				i += 2
+			} else {
+				b.WriteByte(ch)
+				i++
+			}
+		default:
+			b.WriteByte(ch)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// command converts the command at s[i] and returns the index after it.
+func (c *latexConverter) command(s string, i int, b *strings.Builder) int {
+	j := i + 1
+	for j < len(s) && isLetter(s[j]) {
+		j++
+	}
+	if j == i+1 {
+		// Control symbol: \\, \&, \%, \, and the like
+		if j >= len(s) {
+			return j
+		}
+		switch s[j] {
+		case '\\':
+			b.WriteByte('\n')
+		case '&', '%', '$', '#', '_', '{', '}':
+			b.WriteByte(s[j])
+		default:
+			b.WriteByte(' ')
+		}
+		return j + 1
+	}
+	name := s[i+1 : j]
+	if j < len(s) && s[j] == '*' {
+		j++
+	}
+	var args []string
+	for {
+		k := j
+		for k < len(s) && (s[k] == ' ' || s[k] == '\t') {
+			k++
+		}
+		if k < len(s) && s[k] == '[' {
+			if end := strings.IndexByte(s[k:], ']'); end >= 0 {
+				j = k + end + 1
+				continue
+			}
+		}
+		// Arguments follow the command directly, or after spaces
+		if k < len(s) && s[k] == '{' {
+			arg, end := latexGroup(s, k)
+			args = append(args, arg)
+			j = end
+			continue
+		}
+		break
+	}
+
+	switch {
+	case name == "begin" && len(args) > 0 && latexVerbatim[args[0]]:
+		end := `\end{` + args[0] + `}`
+		raw, _, _ := strings.Cut(s[j:], end)
+		b.WriteString("\n\n```\n" + strings.Trim(raw, "\n") + "\n```\n\n")
+		return min(len(s), j+len(raw)+len(end))
+	case name == "begin" || name == "end":
+		b.WriteString("\n\n")
+	case name == "item":
+		if !strings.HasSuffix(strings.TrimRight(b.String(), " \t"), "\n") {
+			b.WriteByte('\n')
+		}
+		b.WriteString("- ")
+	case name == "title" || latexDropped[name]:
+	case name == "href" && len(args) == 2:
+		b.WriteString(c.convert(args[1]))
+	case name == "footnote" && len(args) > 0:
+		b.WriteString(" (" + c.convert(args[0]) + ")")
+	case name == "caption" && len(args) > 0:
+		b.WriteString("\n\n" + c.convert(args[len(args)-1]) + "\n\n")
+	default:
+		if lvl := c.level(name); lvl > 0 && len(args) > 0 {
+			title := strings.Join(strings.Fields(c.convert(args[len(args)-1])), " ")
+			b.WriteString("\n\n" + strings.Repeat("#", lvl) + " " + title + "\n\n")
+			break
+		}
+		for n, a := range args {
+			if n > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(c.convert(a))
+		}
+	}
+	return j
+}
+
+// level is the markdown heading level of a sectioning command, 0 for other
+// commands.
+func (c *latexConverter) level(name string) int {
+	for i, l := range latexLevels {
+		if l == name {
+			return min(i-c.top+1, 6)
+		}
+	}
+	return 0
+}
+
+// latexGroup returns the content of the brace group opening at s[i] and the
+// index after its closing brace, or the rest of s when it is not closed.
+func latexGroup(s string, i int) (string, int) {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return s[i+1 : j], j + 1
+			}
+		}
+	}
+	return s[min(i+1, len(s)):], len(s)
+}
+
+// latexStripComments removes % comments, keeping escaped \% signs.
+func latexStripComments(s string) string {
+	lines := strings.Split(s, "\n")
+	for n, l := range lines {
+		for i := 0; i < len(l); i++ {
+			if l[i] == '\\' {
+				i++
+				continue
+			}
+			if l[i] == '%' {
+				lines[n] = l[:i]
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
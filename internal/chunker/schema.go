@@ -0,0 +1,196 @@
+package chunker
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// schemaKeywords are the keywords that open a top-level definition, by
+// schema language.
+var schemaKeywords = map[string][]string{
+	"proto":   {"message", "enum", "service", "extend"},
+	"graphql": {"type", "interface", "enum", "input", "union", "scalar", "schema", "directive", "extend", "fragment", "query", "mutation", "subscription"},
+}
+
+// schemaDef is a top-level definition of a Protocol Buffers or GraphQL
+// schema. Start is where its leading comments or description begin; End is
+// after its last token.
+type schemaDef struct {
+	Kind, Name string
+	Start, End int
+}
+
+// schemaLang returns the schema language of path, "" for other files.
+func schemaLang(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".proto":
+		return "proto"
+	case ".graphql", ".gql":
+		return "graphql"
+	}
+	return ""
+}
+
+// schemaDefs lists the top-level definitions of a .proto or .graphql file
+// in order: messages, enums, services and extensions of Protocol Buffers;
+// types, inputs, enums, directives, fragments and operations of GraphQL.
+// Comments and strings are skipped, so a keyword inside them does not count.
+func schemaDefs(path, text string) []schemaDef {
+	lang := schemaLang(path)
+	if lang == "" {
+		return nil
+	}
+	var defs []schemaDef
+	depth := 0
+	lead := -1 // start of the comments or description before the next token
+	kind := ""
+	kindStart := 0
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+			continue
+		case lang == "proto" && strings.HasPrefix(text[i:], "//"), lang == "graphql" && c == '#':
+			// A comment after code on the same line belongs to that code
+			if lead < 0 && depth == 0 && startsLine(text, i) {
+				lead = i
+			}
+			i = lineEnd(text, i)
+			continue
+		case lang == "proto" && strings.HasPrefix(text[i:], "/*"):
+			if lead < 0 && depth == 0 && startsLine(text, i) {
+				lead = i
+			}
+			if end := strings.Index(text[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(text)
+			}
+			continue
+		case c == '"' || (lang == "proto" && c == '\''):
+			// GraphQL descriptions are strings before the definition
+			if lead < 0 && depth == 0 && lang == "graphql" {
+				lead = i
+			}
+			i = stringEnd(text, i)
+			if lang == "proto" && depth == 0 {
+				lead = -1
+			}
+			continue
+		}
+		start := i
+		word := ""
+		if c == '@' || c == '_' || isLetter(c) {
+			i++
+			for i < len(text) && (text[i] == '_' || text[i] == '.' || isLetter(text[i]) || text[i] >= '0' && text[i] <= '9') {
+				i++
+			}
+			word = text[start:i]
+		} else {
+			i++
+		}
+		switch {
+		case c == '{' || c == '(' || c == '[':
+			// A definition without a name: schema { ... } or an anonymous
+			// operation
+			if c == '{' && kind != "" && depth == 0 {
+				name := ""
+				if strings.HasSuffix(kind, "schema") {
+					name = "schema"
+				}
+				defs = append(defs, schemaDef{Kind: kind, Name: name, Start: kindStart})
+				kind = ""
+			}
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth = max(depth-1, 0)
+		case depth > 0:
+		case kind != "" && word != "" && !slices.Contains(schemaKeywords[lang], word):
+			defs = append(defs, schemaDef{Kind: kind, Name: word, Start: kindStart})
+			kind = ""
+		case kind == "extend" && word != "":
+			// extend type Foo, extend schema
+			kind += " " + word
+		case word != "" && slices.Contains(schemaKeywords[lang], word):
+			kind = word
+			kindStart = start
+			if lead >= 0 {
+				kindStart = lead
+			}
+		}
+		lead = -1
+		if len(defs) > 0 && depth == 0 && kind == "" {
+			defs[len(defs)-1].End = i
+		}
+	}
+	return defs
+}
+
+// schemaDecls splits a schema at its top-level definitions, each segment
+// starting at the definition's comments. ok is false when no definition is
+// found.
+func schemaDecls(path, text string) ([]string, bool) {
+	defs := schemaDefs(path, text)
+	if len(defs) == 0 {
+		return nil, false
+	}
+	var out []string
+	prev := 0
+	for _, d := range defs {
+		if d.Start <= prev {
+			continue
+		}
+		out = append(out, text[prev:d.Start])
+		prev = d.Start
+	}
+	return append(out, text[prev:]), true
+}
+
+// symbolsAt returns the names of the definitions that overlap the byte
+// range [start, end): the one it starts in and those starting inside it.
+func symbolsAt(defs []schemaDef, start, end int) []string {
+	var out []string
+	for _, d := range defs {
+		if d.Name != "" && d.Start < end && d.End > start {
+			out = append(out, d.Name)
+		}
+	}
+	return out
+}
+
+// startsLine reports whether only spaces precede s[i] on its line.
+func startsLine(s string, i int) bool {
+	return strings.TrimLeft(s[strings.LastIndexByte(s[:i], '\n')+1:i], " \t") == ""
+}
+
+// lineEnd returns the index of the newline ending the line at i, or
+// len(s).
+func lineEnd(s string, i int) int {
+	if j := strings.IndexByte(s[i:], '\n'); j >= 0 {
+		return i + j
+	}
+	return len(s)
+}
+
+// stringEnd returns the index after the string literal opening at s[i],
+// including GraphQL """block strings""".
+func stringEnd(s string, i int) int {
+	if strings.HasPrefix(s[i:], `"""`) {
+		if j := strings.Index(s[i+3:], `"""`); j >= 0 {
+			return i + 3 + j + 3
+		}
+		return len(s)
+	}
+	q := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case q, '\n':
+			return j + 1
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,133 @@
+package chunker
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// splitText chunks text with the given strategy. Structured strategies cut
+// at natural boundaries and pack small pieces together up to size; pieces
+// longer than size fall back to overlapping windows.
+func splitText(path, text string, size, overlap int, strategy string) []string {
+	switch strategy {
+	case cfg.StrategyMarkdown:
+		return packSegments(markdownSections(text), size, overlap)
+	case cfg.StrategyAST:
+		if segs, ok := goDecls(path, text); ok {
+			return packSegments(segs, size, overlap)
+		}
+	case cfg.StrategySchema:
+		if segs, ok := schemaDecls(path, text); ok {
+			return packSegments(segs, size, overlap)
+		}
+	}
+	return chunkText(text, size, overlap)
+}
+
+// markdownSections splits text before every heading line, ignoring lines
+// inside fenced code blocks.
+func markdownSections(text string) []string {
+	var out []string
+	var cur strings.Builder
+	inFence := false
+	for _, line := range strings.SplitAfter(text, "\n") {
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(t, "#") && cur.Len() > 0 {
+			out = append(out, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+// goDecls splits Go source at top-level declarations. Each segment starts at
+// the declaration's doc comment, so comments stay with the code they
+// describe; the package clause and imports form the first segment. ok is
+// false when the file does not parse.
+func goDecls(path, text string) ([]string, bool) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, text, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+	var cuts []int
+	for _, d := range f.Decls {
+		pos := d.Pos()
+		if doc := declDoc(d); doc != nil {
+			pos = doc.Pos()
+		}
+		cuts = append(cuts, fset.Position(pos).Offset)
+	}
+	sort.Ints(cuts)
+	var out []string
+	prev := 0
+	for _, c := range cuts {
+		if c <= prev {
+			continue
+		}
+		out = append(out, text[prev:c])
+		prev = c
+	}
+	out = append(out, text[prev:])
+	// Imports belong with the package clause, not in a segment of their own
+	if len(f.Imports) > 0 && len(out) > 1 {
+		end := fset.Position(f.Imports[len(f.Imports)-1].End()).Offset
+		for len(out) > 1 && len(out[0]) <= end {
+			out[1] = out[0] + out[1]
+			out = out[1:]
+		}
+	}
+	return out, true
+}
+
+func declDoc(d ast.Decl) *ast.CommentGroup {
+	switch dd := d.(type) {
+	case *ast.FuncDecl:
+		return dd.Doc
+	case *ast.GenDecl:
+		return dd.Doc
+	}
+	return nil
+}
+
+// packSegments joins consecutive segments while they fit in size runes.
+// Oversized segments are emitted as overlapping windows on their own.
+func packSegments(segs []string, size, overlap int) []string {
+	var out []string
+	var cur strings.Builder
+	curLen := 0
+	flush := func() {
+		if strings.TrimSpace(cur.String()) != "" {
+			out = append(out, cur.String())
+		}
+		cur.Reset()
+		curLen = 0
+	}
+	for _, s := range segs {
+		n := len([]rune(s))
+		if n > size {
+			flush()
+			out = append(out, chunkText(s, size, overlap)...)
+			continue
+		}
+		if curLen+n > size {
+			flush()
+		}
+		cur.WriteString(s)
+		curLen += n
+	}
+	flush()
+	return out
+}
@@ -0,0 +1,65 @@
+package chunker
+
+import "strings"
+
+// Heading is a section title of a document.
+type Heading struct {
+	Level int    `json:"level"`
+	Title string `json:"title"`
+	// Start is the byte offset of the heading line in the file
+	Start int `json:"start_byte"`
+}
+
+// sectionAt returns the title of the last heading at or before start, or
+// failing that the first one inside [start, end).
+func sectionAt(hs []Heading, start, end int) string {
+	title := ""
+	for _, h := range hs {
+		if h.Start > start {
+			if title == "" && h.Start < end {
+				title = h.Title
+			}
+			break
+		}
+		title = h.Title
+	}
+	return title
+}
+
+// Headings returns the markdown headings of text in order, ignoring lines
+// inside fenced code blocks. Pages fetched from the web keep their <h1>–<h6>
+// as markdown headings, so they are covered too. A "#" must be followed by a
+// space, which keeps "#include" and hashtags out.
+func Headings(text string) []Heading {
+	var out []Heading
+	inFence := false
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		start := offset
+		offset += len(line)
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.HasPrefix(t, "#") {
+			continue
+		}
+		level := len(t) - len(strings.TrimLeft(t, "#"))
+		rest := t[level:]
+		if level > 6 || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		// Closing hashes after a space are decoration ("## Title ##"), not
+		// part of the title ("## C#")
+		title := strings.TrimSpace(rest)
+		if bare := strings.TrimRight(title, "#"); bare == "" || strings.HasSuffix(bare, " ") {
+			title = strings.TrimSpace(bare)
+		}
+		if title == "" {
+			continue
+		}
+		out = append(out, Heading{Level: level, Title: title, Start: start})
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+package chunker
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// transcriptCue is one caption of a WebVTT or SubRip file, with its times
+// in milliseconds.
+type transcriptCue struct {
+	Start, End int
+	Text       string
+}
+
+var (
+	cueTimeRE  = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{2})[.,](\d{1,3})$`)
+	cueVoiceRE = regexp.MustCompile(`<v(?:\.[^ >]*)?\s+([^>]+)>`)
+	cueTagRE   = regexp.MustCompile(`<[^>]*>`)
+	// timecodeRE matches the timecode DocumentText puts at the start of
+	// each cue line of a transcript
+	timecodeRE = regexp.MustCompile(`(?m)^\[(\d{2,}:\d{2}:\d{2})\]`)
+)
+
+// isTranscript reports whether path is a WebVTT or SubRip transcript.
+func isTranscript(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".vtt" || ext == ".srt"
+}
+
+// transcriptText reduces a .vtt or .srt file to one line per cue, each
+// starting with the cue's start time: "[00:01:23] Ana: we rolled back".
+// A last line holds the end time of the last cue alone. Voice tags become
+// a "Name: " prefix, other markup is dropped, and lines repeated from the
+// previous cue (rolling captions) are left out. Notes, styles and regions
+// of WebVTT files are skipped.
+func transcriptText(src string) string {
+	var b strings.Builder
+	cues := transcriptCues(src)
+	for _, c := range cues {
+		fmt.Fprintf(&b, "[%s] %s\n", Timecode(c.Start), c.Text)
+	}
+	if len(cues) > 0 {
+		fmt.Fprintf(&b, "[%s]\n", Timecode(cues[len(cues)-1].End))
+	}
+	return b.String()
+}
+
+func transcriptCues(src string) []transcriptCue {
+	src = strings.TrimPrefix(strings.ReplaceAll(src, "\r\n", "\n"), "\ufeff")
+	var out []transcriptCue
+	var prev []string
+	for _, block := range strings.Split(src, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		if first := strings.TrimSpace(lines[0]); strings.HasPrefix(first, "NOTE") || first == "STYLE" || first == "REGION" {
+			continue
+		}
+		// The timing line follows an optional cue identifier
+		at := slices.IndexFunc(lines, func(l string) bool { return strings.Contains(l, "-->") })
+		if at < 0 || at > 1 {
+			continue
+		}
+		from, rest, _ := strings.Cut(lines[at], "-->")
+		// Cue settings follow the end time
+		to := strings.Fields(rest)
+		if len(to) == 0 {
+			continue
+		}
+		start, ok1 := cueTime(strings.TrimSpace(from))
+		end, ok2 := cueTime(to[0])
+		if !ok1 || !ok2 {
+			continue
+		}
+		var text []string
+		for _, l := range lines[at+1:] {
+			l = cueVoiceRE.ReplaceAllString(l, "$1: ")
+			l = strings.Join(strings.Fields(html.UnescapeString(cueTagRE.ReplaceAllString(l, ""))), " ")
+			if l != "" && !slices.Contains(prev, l) {
+				text = append(text, l)
+			}
+		}
+		if len(text) == 0 {
+			continue
+		}
+		prev = text
+		out = append(out, transcriptCue{Start: start, End: end, Text: strings.Join(text, " ")})
+	}
+	return out
+}
+
+// cueTime parses "01:02:03.450", "02:03.450" or "01:02:03,450" into
+// milliseconds.
+func cueTime(s string) (int, bool) {
+	m := cueTimeRE.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	h, _ := strconv.Atoi(m[1])
+	mins, _ := strconv.Atoi(m[2])
+	sec, _ := strconv.Atoi(m[3])
+	ms, _ := strconv.Atoi((m[4] + "00")[:3])
+	return ((h*60+mins)*60+sec)*1000 + ms, true
+}
+
+// Timecode formats milliseconds as HH:MM:SS, dropping the fraction.
+func Timecode(ms int) string {
+	s := ms / 1000
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+// transcriptSections packs whole cue lines into chunks of up to size
+// runes, so each chunk starts at a cue. A line longer than size is split
+// into windows on its own.
+func transcriptSections(text string, size, overlap int) []string {
+	var out []string
+	var cur strings.Builder
+	flush := func() {
+		if strings.TrimSpace(cur.String()) != "" {
+			out = append(out, cur.String())
+		}
+		cur.Reset()
+	}
+	lines := strings.SplitAfter(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		n := len([]rune(line))
+		if i == len(lines)-1 && i > 0 && cur.Len() > 0 {
+			// The end time line stays with the last cue
+			cur.WriteString(line)
+			continue
+		}
+		if n > size {
+			flush()
+			out = append(out, chunkText(line, size, overlap)...)
+			continue
+		}
+		if len([]rune(cur.String()))+n > size {
+			flush()
+		}
+		cur.WriteString(line)
+	}
+	flush()
+	return out
+}
+
+// timecodesAt returns the span of the transcript chunk at [start, end):
+// the timecode of the cue it starts in and that of the next cue after it,
+// or of the end line.
+func timecodesAt(marks [][]int, text string, start, end int) (from, to string) {
+	for _, m := range marks {
+		code := text[m[2]:m[3]]
+		switch {
+		case m[0] <= start:
+			from = code
+		case m[0] >= end && to == "":
+			to = code
+		}
+	}
+	if to == "" && len(marks) > 0 {
+		last := marks[len(marks)-1]
+		to = text[last[2]:last[3]]
+	}
+	return from, to
+}
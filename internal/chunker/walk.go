@@ -0,0 +1,88 @@
+package chunker
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// walker is filepath.Walk that can follow symlinks to directories. Followed
+// trees are guarded against cycles by walking each real directory once,
+// and every tree against runaway nesting by a depth limit.
+type walker struct {
+	follow bool
+	// maxDepth is how many directory levels below the root are entered
+	// (0 = unlimited)
+	maxDepth int
+	fn       filepath.WalkFunc
+	// seen holds the resolved paths of the directories walked so far
+	seen map[string]bool
+}
+
+// walkTree walks root like filepath.Walk. With follow set, fn sees the
+// target's info for symlinks (dangling ones are skipped) and followed
+// directories are descended into.
+func walkTree(root string, follow bool, maxDepth int, fn filepath.WalkFunc) error {
+	info, err := os.Lstat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	w := &walker{follow: follow, maxDepth: maxDepth, fn: fn, seen: map[string]bool{}}
+	if err := w.walk(root, info, 0); err != filepath.SkipDir {
+		return err
+	}
+	return nil
+}
+
+func (w *walker) walk(path string, info os.FileInfo, depth int) error {
+	if w.follow && info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Stat(path)
+		if err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+			return nil
+		}
+		info = target
+	}
+	if err := w.fn(path, info, nil); err != nil || !info.IsDir() {
+		if err == filepath.SkipDir && info.IsDir() {
+			return nil
+		}
+		return err
+	}
+	// Without following links, a tree cannot contain itself
+	if w.follow {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			if w.seen[real] {
+				log.Printf("Skipping %s: %s was already walked (symlink cycle or duplicate link)", path, real)
+				return nil
+			}
+			w.seen[real] = true
+		}
+	}
+	if w.maxDepth > 0 && depth > w.maxDepth {
+		log.Printf("Skipping %s: deeper than indexing.max_depth (%d)", path, w.maxDepth)
+		return nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return w.fn(path, info, err)
+	}
+	for _, e := range entries {
+		child := filepath.Join(path, e.Name())
+		ci, err := os.Lstat(child)
+		if err != nil {
+			if err := w.fn(child, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.walk(child, ci, depth+1); err != nil {
+			// From a file, SkipDir skips the rest of its directory
+			if err == filepath.SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
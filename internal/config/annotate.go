@@ -0,0 +1,225 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// annotations are the comments `config init` writes next to each key,
+// addressed by dotted JSON path.
+var annotations = map[string]string{
+	"server.lenient_initialize":                         "serve stdio requests before initialize/initialized completes (for clients that skip the handshake; not -mcp-listen sessions)",
+	"server.max_sessions":                               "MCP sessions open at once on -mcp-listen, counting stdio (0 = unlimited)",
+	"server.max_response_kb":                            "cap per stdio response; oversized results are truncated (0 = unlimited)",
+	"embedding.provider":                                `"local" or "openai"`,
+	"embedding.openai.api_key":                          "or OPENAI_API_KEY",
+	"embedding.openai.max_request_kb":                   "split batches into requests of at most this size (0 = never split)",
+	"embedding.local.dim":                               "TF-IDF dimension (384 for all-MiniLM-L6-v2)",
+	"embedding.local.engine":                            `"hashing" (TF-IDF) or "onnx" (needs a binary built with -tags onnx)`,
+	"embedding.fallback":                                `e.g. ["local"]: used for searches while the primary provider fails (same dim required); index batches it embeds with another model are retried, never written`,
+	"embedding.health_check_interval_sec":               "re-probe providers in the chain (0 = startup only)",
+	"qdrant.collection":                                 "collection (or index) name, for every backend",
+	"qdrant.breaker_threshold":                          "consecutive failures before vector store calls fail fast",
+	"qdrant.breaker_cooldown_sec":                       "wait before a half-open trial call",
+	"qdrant.reconnect_interval_sec":                     "degraded mode: retry the store in the background (0 = never)",
+	"qdrant.startup_retries":                            "health check attempts at startup",
+	"qdrant.startup_backoff":                            "delay between attempts, doubled each time (max 30s)",
+	"qdrant.retry":                                      "upserts, searches and scrolls failing with a network error, timeout or 5xx",
+	"qdrant.retry.attempts":                             "tries in all (1 = no retries); 4xx replies are never retried",
+	"qdrant.retry.backoff":                              "first delay, doubled each time, with random jitter",
+	"qdrant.fail_open":                                  "true: start degraded instead of exiting when the store is down",
+	"qdrant.projects_cache_sec":                         "cache project aggregation; reset by index/delete (0 = off)",
+	"qdrant.collection_config":                          "storage/index tuning for large collections (zero = Qdrant default)",
+	"qdrant.collection_config.quantization.type":        `"", "scalar" (int8), "product" or "binary"`,
+	"qdrant.collection_config.shard_number":             "distributed Qdrant: shards (creation time only)",
+	"qdrant.collection_config.write_consistency_factor": "replicas that must acknowledge a write",
+	"store.backend":                                     `"qdrant", "redis" (Redis Stack), "opensearch" or "memory"`,
+	"store.redis.url":                                   "rediss:// for TLS; credentials as redis://:pass@host",
+	"store.redis.index":                                 `index name and key prefix ("" = qdrant.collection)`,
+	"store.opensearch.index":                            `"" = qdrant.collection`,
+	"store.opensearch.hybrid":                           "BM25 + k-NN in one query (OpenSearch 2.10+)",
+	"store.opensearch.text_weight":                      "BM25 share of the hybrid score",
+	"store.encryption.key":                              "base64 AES key (16/24/32 bytes) encrypting chunk text at rest; or PAYLOAD_ENCRYPTION_KEY",
+	"store.memory.path":                                 `gob file to persist the in-process store ("" = lost on exit)`,
+	"indexing.batch_retry":                              "embedding and writing of a failed batch, e.g. a rate-limited provider",
+	"indexing.batch_retry.attempts":                     "tries in all (1 = no retries); only network errors, timeouts, 429 and 5xx are retried",
+	"indexing.max_file_kb":                              "larger files are skipped",
+	"indexing.exclude":                                  `globs ("**/testdata/**", "*.min.js") or "re:<regexp>", relative to the indexed dir`,
+	"indexing.max_files":                                "per index run, counted before embedding; 0 = unlimited",
+	"indexing.max_total_chunks":                         "per index run; 0 = unlimited",
+	"indexing.limit_action":                             `"abort" (fail the run) or "warn" (report and index anyway)`,
+	"indexing.manifest_path":                            `index manifest (roots, hashes, settings); "" = in-memory only`,
+	"indexing.ttl_sweep_interval_sec":                   "how often chunks indexed with a ttl are expired; 0 = never",
+	"indexing.soft_delete_retention_hours":              "rag_delete hides chunks this long before purging; rag_undelete restores (0 = delete at once)",
+	"indexing.prune_missing":                            "rag_index deletes chunks of files removed from disk since its last run of the dir",
+	"indexing.secrets.action":                           `credentials in chunks: "mask", "skip" (drop the chunk), "flag" or "off"`,
+	"indexing.secrets.allow":                            "regexps of values that are not secrets, e.g. example keys",
+	"indexing.filters":                                  "run on each chunk before embedding, after the secrets check",
+	"indexing.filters.pii.kinds":                        "email, credit_card, national_id, phone ([] = all)",
+	"indexing.filters.http.url":                         `external filter: POST {"chunks":[{path,text}]} -> {"chunks":[{text,drop,redactions}]}`,
+	"indexing.filters.http.fail_open":                   "index unfiltered when the service fails (default: fail the run)",
+	"indexing.summaries":                                "extra per-file summary chunk",
+	"indexing.follow_symlinks":                          "linked dirs are walked too, each real dir once",
+	"indexing.max_depth":                                "directory levels walked below the indexed dir; 0 = unlimited",
+	"indexing.archives":                                 "index the files inside .zip, .tar.gz and .tgz archives",
+	"indexing.archives.max_entries":                     "per archive; reading stops there (0 = unlimited)",
+	"indexing.archives.max_total_mb":                    "uncompressed, per archive (0 = unlimited)",
+	"indexing.email.attachments":                        "index text attachments of .eml/.mbox messages",
+	"indexing.images":                                   "index alt text and OCR text of images referenced by docs",
+	"indexing.images.max_kb":                            "larger images are skipped (0 = unlimited)",
+	"indexing.images.ocr.url":                           `OCR endpoint: {"path","mime_type","image"} -> {"text"}; "" = alt text only`,
+	"indexing.images.ocr.api_key":                       "sent as a bearer token (or OCR_API_KEY)",
+	"indexing.toc":                                      "per-file headings for rag_toc, kept out of search",
+	"indexing.toc.max_headings":                         "deepest levels dropped first (0 = all)",
+	"indexing.chunk_cache":                              "reuse the chunks of unchanged files when re-indexing (e.g. after a model change)",
+	"indexing.chunk_cache.dir":                          "cache directory; holds the checked text of every chunk, encrypted with store.encryption",
+	"indexing.context_headers":                          "file and section line embedded with each chunk",
+	"indexing.context_headers.template":                 "Go text/template over .File .Path .Basename .Project .Section .Chapter .Title",
+	"indexing.summaries.provider":                       `"extractive" (offline) or "openai"`,
+	"indexing.urls":                                     "pages fetched by rag_index_url",
+	"indexing.urls.allow_private":                       "allow loopback/private addresses",
+	"indexing.urls.max_pages":                           "default and upper bound of pages per crawl",
+	"indexing.repos":                                    "repositories cloned by rag_index_repo",
+	"indexing.repos.protocols":                          "allowed git transports: https, http, ssh, git",
+	"indexing.repos.allow_private":                      "allow hosts on loopback/private addresses",
+	"indexing.repos.max_mb":                             "refuse repositories larger than this on disk (0 = no limit)",
+	"chunking.profiles":                                 `per-extension overrides, e.g. ".md": {"strategy": "markdown"}`,
+	"chunking.changelogs":                               "one chunk per version entry, with version and release_date payload fields",
+	"chunking.changelogs.files":                         "base name patterns of changelogs, case-insensitive",
+	"language.stopwords":                                `built-in lists: "en", "id"; changing them changes local vectors`,
+	"logging.redact_errors":                             "replace raw store/provider errors in replies with safe messages",
+	"http.api_key":                                      "bearer/X-API-Key auth for the REST API (-http)",
+	"http.admin_api_key":                                `key of the admin endpoints, e.g. on -admin-http ("" = api_key)`,
+	"http.debug_endpoints":                              "/debug/pprof and /debug/runtime; requires api_key",
+	"http.compare":                                      "indexes for /admin/compare: {name, collection, provider, model, dim}",
+	"http.socket_mode":                                  "permissions of a unix:// listener socket (octal)",
+	"http.socket_group":                                 `group owning that socket, e.g. "www-data" ("" = keep)`,
+	"http.access_log":                                   `one line per request: "logfmt", "json" or "off"`,
+	"http.gzip":                                         "compress responses for clients that accept gzip",
+	"http.max_response_kb":                              "cap /rag/search and /rag/projects; the rest via next_offset (0 = unlimited)",
+	"http.payload_admin":                                "/admin/payload bulk payload patching; requires api_key",
+	"tools.disabled":                                    "tool names hidden from tools/list and rejected by tools/call",
+	"tools.read_only":                                   "hide tools that modify the index",
+	"tools.role":                                        `limit the session to one role's tools: "reader", "admin" or a key of roles ("" = all); or MCP_ROLE`,
+	"tools.roles":                                       `custom roles, e.g. {"searcher": {"tools": ["rag_search"], "read_only": true}}`,
+	"tools.reload":                                      "re-read this section on SIGHUP; advertises tools.listChanged",
+	"tools.confirm.mode":                                `ask the user before destructive calls of MCP sessions: "elicit", "require" (refuse without elicitation) or "off"; or MCP_CONFIRM`,
+	"tools.confirm.operations":                          `what is confirmed: "delete_all", "delete_project", "purge", "restore"`,
+	"tools.confirm.timeout_sec":                         "refuse when the user has not answered by then",
+	"resources.instructions":                            "rag://instructions: search guide from the live index, for system prompts",
+	"resources.instructions.template":                   `Go text/template file replacing the built-in guide ("" = built-in)`,
+	"resources.instructions.max_projects":               "projects listed, largest first (0 = none)",
+	"resources.instructions.tips":                       "extra lines appended to the guide",
+	"tools.page_size":                                   "paginate tools/list with nextCursor (0 = all tools)",
+	"search.model_mismatch":                             `hits embedded by another provider/model: "warn", "filter" or "ignore"`,
+	"search.merge_adjacent":                             "merge consecutive chunks of one file into a single passage",
+	"search.hnsw_ef":                                    "HNSW beam width at search time (0 = Qdrant default)",
+	"search.consistency":                                `Qdrant cluster read consistency: "majority", "quorum", "all" or a replica count ("" = default)`,
+	"search.timeout_sec":                                "server-side search timeout (0 = Qdrant default)",
+	"search.exact":                                      "exhaustive (non-index) search; slow, for evaluation",
+	"search.read_through":                               "return current file content for each hit instead of the stored text",
+	"search.eval_dir":                                   `directory rag_eval reads its files from ("" = none)`,
+	"search.max_output_kb":                              "larger rag_search results list hits without text, fetched with rag_get_chunk (0 = never)",
+	"search.suggestions":                                "guidance when a search finds nothing useful",
+	"search.suggestions.min_score":                      "best hit below this counts as no answer",
+	"search.suggestions.max_projects":                   "closest projects suggested",
+	"search.suggestions.vocabulary_ttl_sec":             "cache of indexed terms for did-you-mean",
+	"search.cache":                                      "recent results, dropped when their project changes",
+	"search.cache.ttl_sec":                              "0 disables the cache",
+	"search.cache.max_entries":                          "oldest evicted first",
+	"search.quota":                                      "caps the hits and text each caller gets per window; the rest is cut off",
+	"search.quota.window_sec":                           "a caller's window starts with its first search",
+	"search.quota.max_results":                          "hits per window (0 = unlimited)",
+	"search.quota.max_kb":                               "text and snippet of those hits (0 = unlimited)",
+	"search.sampling":                                   "answers written by the MCP client's model from the hits (sampling/createMessage)",
+	"search.sampling.enabled":                           "answer every search; rag_search's answer argument decides per call",
+	"search.sampling.timeout_sec":                       "wait for the client and its user's approval, then return the hits alone",
+	"search.sampling.system_prompt":                     `"" = built-in instructions to answer from the hits and cite them`,
+	"search.sampling.model_hint":                        `model preference passed to the client ("" = the client's choice)`,
+	"search.reranker":                                   "rescores the best candidates after the store ranked them",
+	"search.reranker.provider":                          `"cohere", "jina", "passthrough" or "" (off)`,
+	"search.reranker.model":                             `"" = provider default`,
+	"search.reranker.url":                               "endpoint override, e.g. a self-hosted service with the same API",
+	"search.reranker.api_key":                           "or RERANK_API_KEY",
+	"search.reranker.candidates":                        "hits fetched for reranking (at least k)",
+	"search.reranker.fail_open":                         "keep the store ranking when the reranker fails",
+	"webhooks":                                          `{url, secret, events}: notified after index/delete operations`,
+	"backup.dir":                                        "rag_backup copies snapshots here (qdrant backend only)",
+	"backup.s3":                                         "and/or under prefix in this bucket; credentials as for s3 connectors",
+	"backup.keep_on_server":                             "keep snapshots on the Qdrant server after copying them",
+	"backup.timeout_sec":                                "limit for creating, copying or restoring one snapshot",
+	"query_log":                                         "search log for rag_analytics",
+	"query_log.mode":                                    `"hash" (HMAC-SHA256 of the query under salt) or "raw"`,
+	"query_log.salt":                                    "or RAG_QUERY_LOG_SALT",
+	"query_log.retention_days":                          "older entries dropped at startup (0 = keep)",
+	"query_log.max_results":                             "top hit paths recorded per search",
+	"connectors":                                        "external sources synced by rag_connector_sync (confluence, s3, slack_export, discord_export)",
+	"access":                                            "document-level access labels; see README",
+	"access.rules":                                      `label files by path: {"pattern": "hr/**", "labels": ["hr"]}`,
+	"access.tenants":                                    `HTTP callers searching only their labels: {name, api_key, labels}`,
+	"access.tenant":                                     `limit the MCP session to one tenant's documents ("" = all); or MCP_TENANT`,
+}
+
+var jsonKeyLine = regexp.MustCompile(`^( *)"([^"]+)": `)
+
+// Annotated renders the configuration as JSON with `//` comments explaining
+// the keys. LoadFromFile accepts the result as is.
+func (c *Config) Annotated() ([]byte, error) {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	out.WriteString("// mcp-service configuration. Comments run from // to the end of the line;\n")
+	out.WriteString("// environment variables override these values (see README).\n")
+	var stack []string
+	for _, line := range strings.Split(string(b), "\n") {
+		m := jsonKeyLine.FindStringSubmatch(line)
+		if m == nil {
+			out.WriteString(line + "\n")
+			continue
+		}
+		depth := len(m[1]) / 2
+		if depth < 1 || depth-1 > len(stack) {
+			out.WriteString(line + "\n")
+			continue
+		}
+		stack = append(stack[:depth-1], m[2])
+		out.WriteString(line)
+		if note, ok := annotations[strings.Join(stack, ".")]; ok {
+			out.WriteString(strings.Repeat(" ", max(1, 40-len(line))) + " // " + note)
+		}
+		out.WriteString("\n")
+	}
+	return out.Bytes(), nil
+}
+
+// stripComments blanks `//` comments outside JSON strings, keeping line
+// and column numbers of syntax errors intact.
+func stripComments(data []byte) []byte {
+	out := bytes.Clone(data)
+	inString, escaped := false, false
+	for i := 0; i < len(out); i++ {
+		ch := out[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		}
+	}
+	return out
+}
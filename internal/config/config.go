@@ -1,11 +1,25 @@
 package config
 
 import (
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/pathglob"
+	"github.com/Rhyanz46/mcp-service/internal/pii"
+	"github.com/Rhyanz46/mcp-service/internal/secrets"
+	"github.com/Rhyanz46/mcp-service/internal/stopwords"
 )
 
 // Global configuration instance
@@ -16,35 +30,378 @@ type Config struct {
 	Server    ServerConfig    `json:"server"`
 	Embedding EmbeddingConfig `json:"embedding"`
 	Qdrant    QdrantConfig    `json:"qdrant"`
+	Store     StoreConfig     `json:"store"`
 	Indexing  IndexingConfig  `json:"indexing"`
+	Chunking  ChunkingConfig  `json:"chunking"`
+	Language  LanguageConfig  `json:"language"`
 	Logging   LoggingConfig   `json:"logging"`
 	HTTP      HTTPConfig      `json:"http"`
+	Tools     ToolsConfig     `json:"tools"`
+	// Resources are the MCP resources offered to clients
+	Resources ResourcesConfig `json:"resources"`
+	Search    SearchConfig    `json:"search"`
+	// Webhooks are notified after index and delete operations complete
+	Webhooks []WebhookConfig `json:"webhooks"`
+	// Connectors are external knowledge sources indexed by rag_connector_sync
+	Connectors []ConnectorConfig `json:"connectors"`
+	// Backup stores collection snapshots taken by rag_backup
+	Backup BackupConfig `json:"backup"`
+	// QueryLog records searches for rag_analytics
+	QueryLog QueryLogConfig `json:"query_log"`
+	// Access labels documents with the groups allowed to read them and
+	// limits the searches of tenants to those labels
+	Access AccessConfig `json:"access"`
+}
+
+// AccessConfig controls document-level access. Every indexed chunk carries
+// acl labels (none = readable by everyone); a tenant's searches only return
+// chunks carrying one of its labels or none.
+type AccessConfig struct {
+	// Rules label the files of an indexed directory by path
+	Rules []ACLRule `json:"rules"`
+	// Tenants are HTTP callers identified by their own api_key
+	Tenants []TenantConfig `json:"tenants"`
+	// Tenant limits the MCP session to one tenant's documents ("" = no
+	// limit); MCP_TENANT sets it per spawned process
+	Tenant string `json:"tenant"`
+}
+
+// ACLRule adds Labels to the files matching Pattern, a pathglob pattern
+// relative to the indexed directory as in indexing.exclude. A pattern
+// naming a directory covers the files below it.
+type ACLRule struct {
+	Pattern string   `json:"pattern"`
+	Labels  []string `json:"labels"`
+}
+
+// TenantConfig is a caller restricted to the documents of its labels.
+type TenantConfig struct {
+	Name   string   `json:"name"`
+	APIKey string   `json:"api_key"`
+	Labels []string `json:"labels"`
+	// Quota replaces search.quota for the tenant
+	Quota *QuotaConfig `json:"quota,omitempty"`
+}
+
+// PublicLabel marks chunks indexed without labels, which every tenant may
+// read. It cannot be assigned.
+const PublicLabel = "*"
+
+// TenantLabels returns the labels a search of the named tenant may match,
+// including PublicLabel, or nil for "" (no restriction).
+func (a AccessConfig) TenantLabels(name string) ([]string, error) {
+	if name == "" {
+		return nil, nil
+	}
+	for _, t := range a.Tenants {
+		if t.Name == name {
+			return append(slices.Clone(t.Labels), PublicLabel), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown access tenant %q", name)
+}
+
+// TenantQuota returns the quota of the named tenant, search.quota for ""
+// or a tenant without its own.
+func (c *Config) TenantQuota(name string) QuotaConfig {
+	for _, t := range c.Access.Tenants {
+		if t.Name == name && name != "" && t.Quota != nil {
+			return *t.Quota
+		}
+	}
+	return c.Search.Quota
+}
+
+// TenantByKey returns the tenant whose api_key is key.
+func (a AccessConfig) TenantByKey(key string) (TenantConfig, bool) {
+	for _, t := range a.Tenants {
+		if t.APIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(t.APIKey)) == 1 {
+			return t, true
+		}
+	}
+	return TenantConfig{}, false
+}
+
+// CheckLabels rejects blank labels and PublicLabel.
+func CheckLabels(labels []string) error {
+	for _, l := range labels {
+		switch strings.TrimSpace(l) {
+		case "":
+			return fmt.Errorf("acl labels cannot be empty")
+		case PublicLabel:
+			return fmt.Errorf("acl label %q is reserved for unlabeled documents", PublicLabel)
+		}
+	}
+	return nil
+}
+
+func (a AccessConfig) validate(h HTTPConfig) error {
+	for _, r := range a.Rules {
+		if _, err := pathglob.Compile([]string{r.Pattern}); err != nil || strings.TrimSpace(r.Pattern) == "" {
+			return fmt.Errorf("access rule %q: invalid pattern", r.Pattern)
+		}
+		if len(r.Labels) == 0 {
+			return fmt.Errorf("access rule %q: labels are required", r.Pattern)
+		}
+		if err := CheckLabels(r.Labels); err != nil {
+			return fmt.Errorf("access rule %q: %w", r.Pattern, err)
+		}
+	}
+	names, keys := map[string]bool{}, map[string]bool{}
+	for _, t := range a.Tenants {
+		if strings.TrimSpace(t.Name) == "" || names[t.Name] {
+			return fmt.Errorf("access tenant names must be unique and not empty")
+		}
+		names[t.Name] = true
+		if err := CheckLabels(t.Labels); err != nil {
+			return fmt.Errorf("access tenant %s: %w", t.Name, err)
+		}
+		if t.Quota != nil {
+			if err := t.Quota.validate(); err != nil {
+				return fmt.Errorf("access tenant %s: %w", t.Name, err)
+			}
+		}
+		k := strings.TrimSpace(t.APIKey)
+		if k == "" {
+			continue
+		}
+		if keys[k] || k == strings.TrimSpace(h.APIKey) || k == h.AdminKey() {
+			return fmt.Errorf("access tenant %s: api_key must differ from every other key", t.Name)
+		}
+		keys[k] = true
+	}
+	_, err := a.TenantLabels(a.Tenant)
+	return err
+}
+
+// QueryLogConfig controls the search query log, a local JSONL file that
+// rag_analytics aggregates into top and unanswered queries.
+type QueryLogConfig struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+	// Mode is "hash" (store an HMAC-SHA256 of the normalized query under
+	// Salt) or "raw" (store the normalized query)
+	Mode string `json:"mode"`
+	// Salt keys the hashes so they cannot be matched against guessed
+	// queries without it
+	Salt string `json:"salt"`
+	// RetentionDays drops older entries when the service starts (0 = keep
+	// all)
+	RetentionDays int `json:"retention_days"`
+	// MaxResults is the number of top hit paths recorded per search
+	MaxResults int `json:"max_results"`
 }
 
 type ServerConfig struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
+	// MaxResponseKB caps a single stdio response; larger ones are truncated (0 = unlimited)
+	MaxResponseKB int `json:"max_response_kb"`
+	// LenientInitialize serves MCP requests of the stdio session before
+	// the initialize handshake completed, for clients that skip it;
+	// -mcp-listen sessions always require it
+	LenientInitialize bool `json:"lenient_initialize"`
+	// MaxSessions caps the MCP sessions open at once on -mcp-listen,
+	// counting the stdio one (0 = unlimited)
+	MaxSessions int `json:"max_sessions"`
 }
 
 type EmbeddingConfig struct {
 	Provider string         `json:"provider"` // "openai" or "local"
 	OpenAI   OpenAIConfig   `json:"openai"`
 	Local    LocalEmbedding `json:"local"`
+	// Fallback lists providers tried in order when the primary fails (e.g. ["local"]).
+	// All providers in the chain must produce vectors of the same dimension.
+	Fallback []string `json:"fallback"`
+	// HealthCheckIntervalSec re-probes the provider chain periodically (0 = startup only)
+	HealthCheckIntervalSec int `json:"health_check_interval_sec"`
 }
 
 type OpenAIConfig struct {
 	APIKey string `json:"api_key"`
 	Model  string `json:"model"`
 	Dim    int    `json:"dim"`
+	// MaxRequestKB splits an embedding batch into several requests whose
+	// JSON bodies stay under this size (0 = one request per batch)
+	MaxRequestKB int `json:"max_request_kb"`
 }
 
 type LocalEmbedding struct {
 	Dim int `json:"dim"`
+	// Engine selects the local algorithm: "hashing" (TF-IDF, default) or
+	// "onnx" (sentence-transformer such as all-MiniLM-L6-v2; needs a binary
+	// built with -tags onnx and Dim set to the model's output size)
+	Engine string     `json:"engine"`
+	ONNX   ONNXConfig `json:"onnx"`
+}
+
+// ONNXConfig locates a BERT-style ONNX model and its WordPiece vocabulary.
+type ONNXConfig struct {
+	ModelPath  string `json:"model_path"`  // e.g. all-MiniLM-L6-v2/model.onnx
+	VocabPath  string `json:"vocab_path"`  // vocab.txt shipped with the model
+	RuntimeLib string `json:"runtime_lib"` // path to libonnxruntime; "" uses the platform default
+	MaxTokens  int    `json:"max_tokens"`  // input truncation, including [CLS]/[SEP]
 }
 
 type QdrantConfig struct {
 	URL        string `json:"url"`
 	Collection string `json:"collection"`
+	// Circuit breaker: open after BreakerThreshold consecutive failures and
+	// fail fast for BreakerCooldownSec before letting a trial call through
+	BreakerThreshold   int `json:"breaker_threshold"`
+	BreakerCooldownSec int `json:"breaker_cooldown_sec"`
+	// ReconnectIntervalSec polls Qdrant while running degraded and initializes
+	// the RAG system once it becomes healthy (0 = stay degraded)
+	ReconnectIntervalSec int `json:"reconnect_interval_sec"`
+	// Startup health check policy: StartupRetries attempts, waiting
+	// StartupBackoff (Go duration, doubled after each failure, capped at 30s)
+	// in between. FailOpen starts in degraded mode instead of exiting.
+	StartupRetries int    `json:"startup_retries"`
+	StartupBackoff string `json:"startup_backoff"`
+	FailOpen       bool   `json:"fail_open"`
+	// ProjectsCacheSec caches the project aggregation; stale entries are served
+	// while refreshing in the background. Index/delete invalidate it (0 = off)
+	ProjectsCacheSec int `json:"projects_cache_sec"`
+	// CollectionConfig tunes storage and indexing of the collection
+	CollectionConfig CollectionConfig `json:"collection_config"`
+	// Retry repeats upserts, searches and scrolls that fail with a network
+	// error, a timeout or a 5xx reply, e.g. while Qdrant restarts
+	Retry RetryConfig `json:"retry"`
+}
+
+// RetryConfig is a retry policy for transient failures: up to Attempts
+// tries in all, waiting Backoff (Go duration, doubled after each failure and
+// capped at MaxBackoff) with random jitter in between. 4xx replies are
+// never retried.
+type RetryConfig struct {
+	Attempts   int    `json:"attempts"`
+	Backoff    string `json:"backoff"`
+	MaxBackoff string `json:"max_backoff"`
+}
+
+// Durations parses Backoff and MaxBackoff (empty means 200ms and 5s).
+func (r RetryConfig) Durations() (backoff, maxBackoff time.Duration, err error) {
+	backoff, maxBackoff = 200*time.Millisecond, 5*time.Second
+	if s := strings.TrimSpace(r.Backoff); s != "" {
+		if backoff, err = time.ParseDuration(s); err != nil {
+			return 0, 0, fmt.Errorf("backoff: %w", err)
+		}
+	}
+	if s := strings.TrimSpace(r.MaxBackoff); s != "" {
+		if maxBackoff, err = time.ParseDuration(s); err != nil {
+			return 0, 0, fmt.Errorf("max_backoff: %w", err)
+		}
+	}
+	if backoff < 0 || maxBackoff < backoff {
+		return 0, 0, fmt.Errorf("need 0 <= backoff <= max_backoff")
+	}
+	return backoff, maxBackoff, nil
+}
+
+// StoreConfig selects the vector database. The qdrant section still names
+// the collection and configures the circuit breaker and startup policy for
+// every backend.
+type StoreConfig struct {
+	Backend    string           `json:"backend"` // "qdrant" (default), "redis", "opensearch" or "memory"
+	Redis      RedisConfig      `json:"redis"`
+	OpenSearch OpenSearchConfig `json:"opensearch"`
+	Memory     MemoryConfig     `json:"memory"`
+	Encryption EncryptionConfig `json:"encryption"`
+}
+
+// EncryptionConfig encrypts the chunk text (payload preview and text) with
+// AES-GCM before it is written to the store, for stores shared with or
+// hosted by others.
+type EncryptionConfig struct {
+	// Key is a base64 AES key of 16, 24 or 32 bytes ("" = stored in clear);
+	// PAYLOAD_ENCRYPTION_KEY overrides it, e.g. when a KMS agent injects it
+	Key string `json:"key"`
+}
+
+// MemoryConfig configures the in-process store used for tests and demos.
+type MemoryConfig struct {
+	// Path is a gob file the points are saved to and loaded from ("" = not persisted)
+	Path string `json:"path"`
+}
+
+// RedisConfig points at a Redis Stack (RediSearch) server.
+type RedisConfig struct {
+	// URL is redis://[user:pass@]host:port[/db]; rediss:// enables TLS
+	URL string `json:"url"`
+	// Index names the search index and key prefix ("" = qdrant.collection)
+	Index string `json:"index"`
+	// PoolSize caps the idle connections kept open
+	PoolSize int `json:"pool_size"`
+}
+
+// OpenSearchConfig points at an OpenSearch cluster (k-NN plugin, 2.10+ for
+// hybrid search).
+type OpenSearchConfig struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Index names the index ("" = qdrant.collection)
+	Index string `json:"index"`
+	// Hybrid combines BM25 over the chunk text with k-NN in one query via a
+	// normalization search pipeline; false searches vectors only
+	Hybrid bool `json:"hybrid"`
+	// TextWeight is the BM25 share of the hybrid score in [0, 1]
+	TextWeight float64 `json:"text_weight"`
+	// InsecureSkipVerify disables TLS certificate checks (self-signed dev clusters)
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+// CollectionName returns the collection (or index) name of the active backend.
+func (c *Config) CollectionName() string {
+	switch {
+	case c.Store.Backend == "redis" && c.Store.Redis.Index != "":
+		return c.Store.Redis.Index
+	case c.Store.Backend == "opensearch" && c.Store.OpenSearch.Index != "":
+		return c.Store.OpenSearch.Index
+	}
+	return c.Qdrant.Collection
+}
+
+// StoreURL returns the URL of the active backend.
+func (c *Config) StoreURL() string {
+	switch c.Store.Backend {
+	case "redis":
+		return c.Store.Redis.URL
+	case "opensearch":
+		return c.Store.OpenSearch.URL
+	case "memory":
+		return "memory://" + c.Store.Memory.Path
+	}
+	return c.Qdrant.URL
+}
+
+// CollectionConfig is applied when the collection is created; HNSW,
+// quantization, on_disk_payload, replication_factor and
+// write_consistency_factor are also patched onto an existing one. Zero
+// values keep Qdrant's defaults.
+type CollectionConfig struct {
+	OnDiskVectors bool               `json:"on_disk_vectors"` // keep original vectors on disk (memmap)
+	OnDiskPayload bool               `json:"on_disk_payload"`
+	HNSW          HNSWConfig         `json:"hnsw"`
+	Quantization  QuantizationConfig `json:"quantization"`
+	// Distributed mode: shards per collection (fixed at creation), copies
+	// of each shard, and replicas that must acknowledge a write
+	ShardNumber            int `json:"shard_number"`
+	ReplicationFactor      int `json:"replication_factor"`
+	WriteConsistencyFactor int `json:"write_consistency_factor"`
+}
+
+type HNSWConfig struct {
+	M           int  `json:"m"`            // edges per node; lower saves memory
+	EfConstruct int  `json:"ef_construct"` // build-time neighbours; higher = better recall, slower indexing
+	OnDisk      bool `json:"on_disk"`      // keep the graph on disk
+}
+
+type QuantizationConfig struct {
+	Type        string  `json:"type"`        // "" (none), "scalar" (int8), "product" or "binary"
+	Quantile    float64 `json:"quantile"`    // scalar: outlier cut-off in (0.5, 1]; 0 = Qdrant default
+	Compression string  `json:"compression"` // product: "x4", "x8", "x16", "x32" or "x64"
+	AlwaysRAM   bool    `json:"always_ram"`  // keep quantized vectors in RAM while originals live on disk
 }
 
 type IndexingConfig struct {
@@ -53,11 +410,352 @@ type IndexingConfig struct {
 	ChunkOverlap int    `json:"chunk_overlap"`
 	BatchSize    int    `json:"batch_size"`
 	IncludeCode  bool   `json:"include_code"`
+	// BatchRetry repeats embedding and writing a batch that failed, e.g.
+	// on a rate-limited provider; store requests also follow qdrant.retry
+	BatchRetry RetryConfig `json:"batch_retry"`
 	// Guardrails
-	MaxFileKB      int             `json:"max_file_kb"`
-	ExcludeDirs    []string        `json:"exclude_dirs"`
-	FollowSymlinks bool            `json:"follow_symlinks"`
-	FileTypes      FileTypesConfig `json:"file_types"`
+	MaxFileKB   int      `json:"max_file_kb"`
+	ExcludeDirs []string `json:"exclude_dirs"`
+	// Exclude skips files and directories matching these patterns relative
+	// to the indexed directory: globs with ** ("**/testdata/**", "*.min.js")
+	// or regular expressions prefixed with "re:"
+	Exclude []string `json:"exclude"`
+	// FollowSymlinks descends into linked directories and reads linked
+	// files; each real directory is walked once, which breaks link cycles
+	FollowSymlinks bool `json:"follow_symlinks"`
+	// MaxDepth is how many directory levels below the indexed directory
+	// are walked; deeper ones are skipped with a log line (0 = unlimited)
+	MaxDepth int `json:"max_depth"`
+	// Archives indexes the files inside .zip and .tar.gz archives
+	Archives ArchivesConfig `json:"archives"`
+	// Images indexes the alt text and OCR text of images referenced by
+	// documentation
+	Images ImagesConfig `json:"images"`
+	// Email controls how .eml and .mbox messages are read
+	Email     EmailConfig     `json:"email"`
+	FileTypes FileTypesConfig `json:"file_types"`
+	// MaxFiles and MaxTotalChunks cap a single index run, guarding against
+	// pointing rag_index at a home directory (0 = unlimited)
+	MaxFiles       int `json:"max_files"`
+	MaxTotalChunks int `json:"max_total_chunks"`
+	// LimitAction is "abort" (fail the run before embedding past a cap) or
+	// "warn" (log, report and index everything)
+	LimitAction string `json:"limit_action"`
+	// ManifestPath stores the index manifest (indexed roots, hashes, settings); "" disables persistence
+	ManifestPath string `json:"manifest_path"`
+	// TTLSweepIntervalSec is how often chunks indexed with a ttl are checked
+	// for expiry and deleted (0 disables the sweeper)
+	TTLSweepIntervalSec int `json:"ttl_sweep_interval_sec"`
+	// SoftDeleteRetentionHours keeps chunks removed by rag_delete for this
+	// long, hidden from search, so rag_undelete can restore them; the TTL
+	// sweeper purges them afterwards (0 deletes immediately)
+	SoftDeleteRetentionHours int `json:"soft_delete_retention_hours"`
+	// PruneMissing makes rag_index delete the chunks of files its previous
+	// run of the same directory indexed but that are gone from disk
+	PruneMissing bool `json:"prune_missing"`
+	// Secrets scans chunks for credentials before they are embedded
+	Secrets SecretsConfig `json:"secrets"`
+	// Filters run on each chunk after the secrets check, before embedding
+	Filters FiltersConfig `json:"filters"`
+	// Summaries adds one generated summary chunk per file during indexing
+	Summaries SummariesConfig `json:"summaries"`
+	// TOC adds one table-of-contents point per file with headings, which
+	// rag_toc lists
+	TOC TOCConfig `json:"toc"`
+	// ChunkCache keeps the chunks of indexed files so re-indexing unchanged
+	// ones, e.g. to re-embed them with another model, skips reading and
+	// splitting them
+	ChunkCache ChunkCacheConfig `json:"chunk_cache"`
+	// ContextHeaders prepends the file and section to the embedded text
+	// of each chunk
+	ContextHeaders ContextHeadersConfig `json:"context_headers"`
+	// URLs controls fetching pages for rag_index_url
+	URLs URLFetchConfig `json:"urls"`
+	// Repos controls cloning git repositories for rag_index_repo
+	Repos RepoFetchConfig `json:"repos"`
+}
+
+// RepoFetchConfig limits what rag_index_repo clones.
+type RepoFetchConfig struct {
+	// TimeoutSec bounds the clone, including the transfer
+	TimeoutSec int `json:"timeout_sec"`
+	// Protocols are the git transports that may be used (https, http, ssh,
+	// git); local paths and file:// are never allowed
+	Protocols []string `json:"protocols"`
+	// AllowPrivate permits hosts on loopback, private and link-local
+	// addresses, refused by default as for indexing.urls
+	AllowPrivate bool `json:"allow_private"`
+	// MaxMB bounds the clone on disk, history and checkout together;
+	// larger repositories are refused (0 = no limit)
+	MaxMB int `json:"max_mb"`
+}
+
+// URLFetchConfig limits what rag_index_url fetches. Pages larger than
+// max_file_kb are rejected.
+type URLFetchConfig struct {
+	TimeoutSec int `json:"timeout_sec"`
+	// AllowPrivate permits loopback, private and link-local addresses, which
+	// are blocked by default so the tool cannot probe internal services
+	AllowPrivate bool `json:"allow_private"`
+	// MaxPages is the default and upper bound of pages fetched per crawl
+	MaxPages int `json:"max_pages"`
+	// CrawlDelayMS is the pause between crawl requests; a longer robots.txt
+	// Crawl-delay wins
+	CrawlDelayMS int `json:"crawl_delay_ms"`
+}
+
+// SecretsConfig controls what happens to likely credentials (API keys,
+// private keys, passwords in .env style assignments) found in chunks.
+type SecretsConfig struct {
+	// Action is "mask" (replace each secret with [REDACTED:<kind>]), "skip"
+	// (leave the chunk out), "flag" (store it unchanged, listing the kinds
+	// in payload secrets) or "off"
+	Action string `json:"action"`
+	// Allow lists regular expressions of values that are not secrets, e.g.
+	// the example keys of an API guide
+	Allow []string `json:"allow"`
+}
+
+// FiltersConfig configures the content filters that may redact or drop
+// chunks before they are embedded, e.g. to keep personal data out of the
+// vector store.
+type FiltersConfig struct {
+	PII  PIIFilterConfig  `json:"pii"`
+	HTTP HTTPFilterConfig `json:"http"`
+}
+
+// PIIFilterConfig enables the built-in regex redaction of personal data.
+type PIIFilterConfig struct {
+	Enabled bool `json:"enabled"`
+	// Kinds limits redaction to some of email, credit_card, national_id and
+	// phone (empty = all)
+	Kinds []string `json:"kinds"`
+}
+
+// HTTPFilterConfig sends each batch of chunks to an external filter service,
+// which returns the chunks' new texts or drops them.
+type HTTPFilterConfig struct {
+	URL        string            `json:"url"` // "" disables the filter
+	Headers    map[string]string `json:"headers"`
+	TimeoutSec int               `json:"timeout_sec"`
+	// FailOpen indexes chunks unfiltered when the service fails, instead of
+	// failing the index run
+	FailOpen bool `json:"fail_open"`
+}
+
+// SummariesConfig controls summary chunks, which help high-level questions
+// ("what does project X do?") that character windows answer poorly.
+type SummariesConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Provider string `json:"provider"` // "extractive" (offline) or "openai"
+	Model    string `json:"model"`    // chat model used by the openai provider
+	// MinFileChars skips files too short to benefit from a summary
+	MinFileChars int `json:"min_file_chars"`
+	// MaxChars caps the length of a generated summary
+	MaxChars int `json:"max_chars"`
+}
+
+// ImagesConfig indexes images that documentation files reference
+// (markdown, HTML, reStructuredText and AsciiDoc image syntax). Their alt
+// text, and the text an OCR service reads from them when ocr.url is set,
+// are stored under the image's path.
+type ImagesConfig struct {
+	Enabled bool `json:"enabled"`
+	// MaxKB skips larger images (0 = unlimited)
+	MaxKB int       `json:"max_kb"`
+	OCR   OCRConfig `json:"ocr"`
+}
+
+// OCRConfig is an external OCR HTTP endpoint. It receives
+// {"path", "mime_type", "image"} with the image base64-encoded and answers
+// {"text": "..."}.
+type OCRConfig struct {
+	URL        string `json:"url"` // "" indexes alt text only
+	APIKey     string `json:"api_key"`
+	TimeoutSec int    `json:"timeout_sec"`
+}
+
+// EmailConfig controls reading .eml and .mbox files. Each message is
+// indexed with its subject, sender, date and thread.
+type EmailConfig struct {
+	// Attachments indexes text attachments (text/*) under their message;
+	// other attachments are always skipped
+	Attachments bool `json:"attachments"`
+}
+
+// ArchivesConfig controls reading .zip, .tar.gz and .tgz archives during
+// indexing. Their files are extracted in memory and stored under
+// archive!inner/path; the other indexing rules apply to the inner paths.
+type ArchivesConfig struct {
+	Enabled bool `json:"enabled"`
+	// MaxEntries and MaxTotalMB stop reading an archive after this many
+	// files or uncompressed megabytes, guarding against archive bombs
+	// (0 = unlimited)
+	MaxEntries int `json:"max_entries"`
+	MaxTotalMB int `json:"max_total_mb"`
+}
+
+// ContextHeadersConfig controls the context line embedded with each chunk,
+// which helps chunks that read ambiguously in isolation ("it expires after
+// an hour"). The stored and returned text stays the raw chunk.
+type ContextHeadersConfig struct {
+	Enabled bool `json:"enabled"`
+	// Template is a Go text/template over ContextHeader
+	Template string `json:"template"`
+}
+
+// ContextHeader is what context_headers templates can use.
+type ContextHeader struct {
+	// File is the project directory and file name, e.g. api/auth.md
+	File     string
+	Path     string
+	Basename string
+	Project  string
+	// Section is the heading the chunk falls under, "" when none
+	Section string
+	// Chapter is the chapter of an EPUB or LaTeX document, "" elsewhere
+	Chapter string
+	// Title is the page or document title of fetched and connector
+	// documents
+	Title string
+}
+
+// DefaultContextHeader is the default context_headers template.
+const DefaultContextHeader = "File: {{.File}}{{if .Section}} — Section: {{.Section}}{{end}}"
+
+// Parse compiles the template and tries it on sample values, so a field
+// that does not exist fails here rather than during indexing.
+func (h ContextHeadersConfig) Parse() (*template.Template, error) {
+	t, err := template.New("context_header").Option("missingkey=error").Parse(h.Template)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Execute(io.Discard, ContextHeader{File: "docs/a.md", Path: "/docs/a.md", Basename: "a.md", Project: "docs", Section: "Intro", Chapter: "One"}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// TOCConfig controls table-of-contents points. They hold a file's markdown
+// headings and are kept out of search results.
+type TOCConfig struct {
+	Enabled bool `json:"enabled"`
+	// MaxHeadings caps the headings stored per file, dropping the deepest
+	// levels first; 0 keeps all
+	MaxHeadings int `json:"max_headings"`
+}
+
+// ChunkCacheConfig controls the chunk cache. A file is taken from it when
+// its size and modification time, or else its content hash, match the
+// cached copy and the chunking settings are unchanged.
+type ChunkCacheConfig struct {
+	Enabled bool `json:"enabled"`
+	// Dir holds the cache: one file per content hash and an index of the
+	// files seen. It stores the text of every chunk after the secrets
+	// check and content filters, sealed with store.encryption when set.
+	Dir string `json:"dir"`
+}
+
+// ChunkingConfig holds per-extension chunking profiles, keyed by extension
+// including the dot (".md"). Files without a profile use indexing.chunk_size
+// and indexing.chunk_overlap with plain character windows.
+type ChunkingConfig struct {
+	Profiles   map[string]ChunkProfile `json:"profiles"`
+	Changelogs ChangelogConfig         `json:"changelogs"`
+}
+
+// ChangelogConfig splits changelogs into one chunk per version entry, with
+// the version and release date in the payload. Files match by base name,
+// case-insensitively; a file counts as a changelog only when it has
+// headings that name versions.
+type ChangelogConfig struct {
+	Enabled bool     `json:"enabled"`
+	Files   []string `json:"files"` // base name patterns, e.g. "CHANGELOG*"
+}
+
+// Chunking strategies
+const (
+	StrategyWindow   = "window"   // fixed-size character windows
+	StrategyMarkdown = "markdown" // split at headings, pack small sections
+	StrategyAST      = "ast"      // split Go source at top-level declarations
+	StrategySchema   = "schema"   // split .proto and .graphql files at top-level definitions
+)
+
+// SchemaExtensions are the schema files chunked with StrategySchema unless
+// a profile sets another strategy.
+var SchemaExtensions = []string{".proto", ".graphql", ".gql"}
+
+// ChunkProfile overrides chunking for one extension. Size 0 and a missing
+// overlap fall back to the indexing defaults.
+type ChunkProfile struct {
+	Size     int    `json:"size"`
+	Overlap  *int   `json:"overlap"`
+	Strategy string `json:"strategy"` // "window" (default), "markdown", "ast" (.go only) or "schema" (.proto, .graphql, .gql; their default)
+}
+
+// IsChangelog reports whether path's base name matches
+// chunking.changelogs.files while changelog chunking is on.
+func (c *Config) IsChangelog(p string) bool {
+	if c == nil || !c.Chunking.Changelogs.Enabled {
+		return false
+	}
+	base := strings.ToLower(filepath.Base(p))
+	for _, f := range c.Chunking.Changelogs.Files {
+		if ok, _ := path.Match(strings.ToLower(f), base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ChunkProfileFor resolves the chunk size, overlap and strategy for path,
+// starting from the given defaults.
+func (c *Config) ChunkProfileFor(path string, size, overlap int) (int, int, string) {
+	ext := strings.ToLower(filepath.Ext(path))
+	strategy := StrategyWindow
+	if slices.Contains(SchemaExtensions, ext) {
+		strategy = StrategySchema
+	}
+	if c == nil {
+		return size, overlap, strategy
+	}
+	p, ok := c.Chunking.Profiles[ext]
+	if !ok {
+		return size, overlap, strategy
+	}
+	if p.Size > 0 {
+		size = p.Size
+	}
+	if p.Overlap != nil {
+		overlap = *p.Overlap
+	}
+	if p.Strategy != "" {
+		strategy = p.Strategy
+	}
+	return size, overlap, strategy
+}
+
+// MaxChunkOverlap is the largest overlap any profile can produce.
+func (c *Config) MaxChunkOverlap() int {
+	max := c.Indexing.ChunkOverlap
+	for _, p := range c.Chunking.Profiles {
+		if p.Overlap != nil && *p.Overlap > max {
+			max = *p.Overlap
+		}
+	}
+	return max
+}
+
+// LanguageConfig controls stopword removal in the local TF-IDF embeddings and
+// the classic BM25 index. Changing it changes local-mode vectors; re-index
+// afterwards.
+type LanguageConfig struct {
+	// Stopwords lists built-in stopword sets to apply, e.g. ["en", "id"]
+	Stopwords []string `json:"stopwords"`
+	// ExtraStopwords adds corpus-specific words
+	ExtraStopwords []string `json:"extra_stopwords"`
+	// DisableStopwords keeps every term
+	DisableStopwords bool `json:"disable_stopwords"`
 }
 
 type FileTypesConfig struct {
@@ -71,58 +769,636 @@ type FileTypesConfig struct {
 type LoggingConfig struct {
 	Level  string `json:"level"`
 	Prefix string `json:"prefix"`
+	// RedactErrors replaces raw provider/Qdrant errors in replies with safe
+	// messages; full details are only written to the server log
+	RedactErrors bool `json:"redact_errors"`
 }
 
 type HTTPConfig struct {
 	// APIKey enables simple bearer/X-API-Key auth for REST endpoints when non-empty
 	APIKey string `json:"api_key"`
+	// AdminAPIKey guards the admin endpoints (delete, undelete, metrics,
+	// debug, compare, payload) instead of APIKey ("" = APIKey)
+	AdminAPIKey string `json:"admin_api_key"`
+	// DebugEndpoints exposes /debug/pprof and /debug/runtime; requires APIKey
+	DebugEndpoints bool `json:"debug_endpoints"`
+	// Compare lists alternative indexes that /admin/compare can search side by
+	// side with the primary one; requires APIKey
+	Compare []CompareTarget `json:"compare"`
+	// PayloadAdmin exposes /admin/payload to patch payload fields by filter;
+	// requires APIKey
+	PayloadAdmin bool `json:"payload_admin"`
+	// SocketMode (octal) and SocketGroup (name or GID, "" = keep) apply to a
+	// unix:// listener, e.g. to let only the web server's group connect
+	SocketMode  string `json:"socket_mode"`
+	SocketGroup string `json:"socket_group"`
+	// AccessLog is the format of per-request access log lines: "logfmt",
+	// "json" or "off"
+	AccessLog string `json:"access_log"`
+	// Gzip compresses JSON responses for clients sending Accept-Encoding: gzip
+	Gzip bool `json:"gzip"`
+	// MaxResponseKB caps /rag/search and /rag/projects responses (before
+	// compression); the rest is left to the next page (0 = unlimited)
+	MaxResponseKB int `json:"max_response_kb"`
+}
+
+// AdminKey is the key of the admin endpoints.
+func (h HTTPConfig) AdminKey() string {
+	if k := strings.TrimSpace(h.AdminAPIKey); k != "" {
+		return k
+	}
+	return strings.TrimSpace(h.APIKey)
+}
+
+// SocketFileMode parses SocketMode (empty means 0660).
+func (h HTTPConfig) SocketFileMode() (os.FileMode, error) {
+	if strings.TrimSpace(h.SocketMode) == "" {
+		return 0660, nil
+	}
+	m, err := strconv.ParseUint(strings.TrimSpace(h.SocketMode), 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("%q is not an octal permission such as 0660", h.SocketMode)
+	}
+	return os.FileMode(m), nil
+}
+
+// WebhookConfig is an endpoint that receives index/delete events as JSON.
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Secret signs each body with HMAC-SHA256 (X-MCP-Signature); "" sends unsigned
+	Secret string `json:"secret"`
+	// Events limits delivery to "index" and/or "delete"; empty = all
+	Events []string `json:"events"`
+}
+
+// Wants reports whether the webhook subscribes to event.
+func (w WebhookConfig) Wants(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// ConnectorConfig is one external source of documents, e.g. a Confluence
+// space, an S3 bucket or a chat export.
+type ConnectorConfig struct {
+	// Name selects the connector in rag_connector_sync; it must be unique
+	Name string `json:"name"`
+	Type string `json:"type"` // "confluence", "s3", "slack_export" or "discord_export"
+	// Project for the indexed documents; "" uses the connector name
+	Project    string `json:"project"`
+	TimeoutSec int    `json:"timeout_sec"`
+
+	// confluence: BaseURL is the site's API root, e.g.
+	// https://acme.atlassian.net/wiki; slack_export: the workspace, e.g.
+	// https://acme.slack.com, for links to messages ("" = slack:// URIs)
+	BaseURL string `json:"base_url"`
+	Email   string `json:"email"`
+	// APIToken authenticates Email; CONFLUENCE_API_TOKEN fills in empty ones
+	APIToken string `json:"api_token"`
+	SpaceKey string `json:"space_key"`
+
+	// s3: objects under Prefix in Bucket
+	S3Location
+	// IncludeCode also indexes code files, besides documentation
+	IncludeCode bool `json:"include_code"`
+
+	// slack_export: Path is the unpacked export directory or its .zip
+	// file; discord_export: a DiscordChatExporter JSON file or a directory
+	// of them
+	Path string `json:"path"`
+	// Channels limits a chat export to these channel names (empty = all)
+	Channels []string `json:"channels"`
+	// GapMinutes starts a new conversation window after a pause this long
+	// in a channel (0 = 30)
+	GapMinutes int `json:"gap_minutes"`
+
+	// ACL labels every document of the connector (see access)
+	ACL []string `json:"acl"`
+}
+
+// S3Location is a prefix of an S3 bucket. Endpoint is set for S3-compatible
+// stores such as MinIO, which are addressed path-style; without it AWS
+// virtual-hosted URLs in Region are used. Empty credentials are filled in
+// from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN;
+// without any, requests are anonymous.
+type S3Location struct {
+	Bucket          string `json:"bucket"`
+	Prefix          string `json:"prefix"`
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token"`
+}
+
+// applyEnv fills in empty credentials and region from the AWS variables.
+func (l *S3Location) applyEnv() {
+	if l.AccessKeyID == "" && l.SecretAccessKey == "" {
+		l.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		l.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if l.SessionToken == "" {
+			l.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+	if l.Region == "" {
+		l.Region = os.Getenv("AWS_REGION")
+	}
+}
+
+func (l S3Location) validate() error {
+	if l.Endpoint != "" {
+		u, err := url.Parse(l.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint %q must be an http(s) URL", l.Endpoint)
+		}
+	}
+	if (l.AccessKeyID == "") != (l.SecretAccessKey == "") {
+		return fmt.Errorf("set both access_key_id and secret_access_key, or neither")
+	}
+	return nil
+}
+
+// BackupConfig configures rag_backup and rag_restore, which use the Qdrant
+// snapshot API.
+type BackupConfig struct {
+	// Dir receives a copy of every snapshot; "" = none
+	Dir string `json:"dir"`
+	// S3 receives a copy of every snapshot when its bucket is set
+	S3 S3Location `json:"s3"`
+	// KeepOnServer keeps snapshots on the Qdrant server after they were
+	// copied to Dir or S3; without a copy they are always kept
+	KeepOnServer bool `json:"keep_on_server"`
+	// TimeoutSec bounds creating, transferring and restoring one snapshot
+	TimeoutSec int `json:"timeout_sec"`
+}
+
+// CompareTarget is an alternative index, e.g. a collection re-embedded with a
+// new model during a migration. Empty fields inherit the primary settings.
+type CompareTarget struct {
+	Name       string `json:"name"`
+	Collection string `json:"collection"`
+	Provider   string `json:"provider"` // "openai" or "local"
+	Model      string `json:"model"`    // OpenAI embedding model
+	Dim        int    `json:"dim"`      // vector size of the provider
+}
+
+// ForCompareTarget returns a copy of c that searches target t. The copy has
+// no fallback chain so results always come from the named provider.
+func (c *Config) ForCompareTarget(t CompareTarget) *Config {
+	cc := *c
+	cc.Qdrant.Collection = t.Collection
+	cc.Store.Redis.Index = t.Collection
+	cc.Store.OpenSearch.Index = t.Collection
+	cc.Embedding.Fallback = nil
+	if t.Provider != "" {
+		cc.Embedding.Provider = t.Provider
+	}
+	if t.Model != "" {
+		cc.Embedding.OpenAI.Model = t.Model
+	}
+	if t.Dim > 0 {
+		if cc.Embedding.Provider == "openai" {
+			cc.Embedding.OpenAI.Dim = t.Dim
+		} else {
+			cc.Embedding.Local.Dim = t.Dim
+		}
+	}
+	return &cc
+}
+
+type ToolsConfig struct {
+	// Disabled lists tool names hidden from tools/list and rejected by tools/call
+	Disabled []string `json:"disabled"`
+	// ReadOnly hides every tool that modifies the index (rag_index, rag_delete, ...)
+	ReadOnly bool `json:"read_only"`
+	// PageSize enables cursor pagination of tools/list (0 = return all tools)
+	PageSize int `json:"page_size"`
+	// Role limits the session to the tools of one role ("" = no limit);
+	// MCP_ROLE sets it per spawned process
+	Role string `json:"role"`
+	// Roles defines roles besides the built-in "reader" (read-only tools)
+	// and "admin" (every tool); a definition here replaces a built-in one
+	Roles map[string]RoleConfig `json:"roles"`
+	// Confirm asks the user of an MCP client before destructive operations
+	Confirm ConfirmConfig `json:"confirm"`
+	// Reload re-reads this section on SIGHUP. Only then, or while the
+	// server waits to leave degraded mode, may the tool set change, and
+	// initialize advertises tools.listChanged.
+	Reload bool `json:"reload"`
+}
+
+// ConfirmConfig decides which destructive tool calls of MCP sessions wait
+// for the user's confirmation, asked through MCP elicitation. Calls from
+// the CLI are never asked about.
+type ConfirmConfig struct {
+	// Mode is "elicit" (ask clients that support elicitation, proceed
+	// otherwise), "require" (refuse when the client cannot be asked) or
+	// "off"; MCP_CONFIRM sets it per spawned process
+	Mode string `json:"mode"`
+	// Operations lists what is confirmed: "delete_all" (rag_delete
+	// all=true), "delete_project", "purge" and "restore" (rag_restore
+	// recreating the collection)
+	Operations []string `json:"operations"`
+	// TimeoutSec is how long to wait for the user's answer before refusing
+	TimeoutSec int `json:"timeout_sec"`
+}
+
+// ConfirmOperations are the operations ConfirmConfig.Operations may name.
+var ConfirmOperations = []string{"delete_all", "delete_project", "purge", "restore"}
+
+// Confirms reports whether op waits for a confirmation.
+func (c ConfirmConfig) Confirms(op string) bool {
+	if c.Mode == "off" {
+		return false
+	}
+	for _, o := range c.Operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourcesConfig controls the MCP resources offered to clients.
+type ResourcesConfig struct {
+	// Instructions is rag://instructions, a guide to the search tools
+	// generated from the live index for clients to put in a system prompt
+	Instructions InstructionsConfig `json:"instructions"`
+}
+
+// InstructionsConfig shapes the retrieval instructions resource.
+type InstructionsConfig struct {
+	Enabled bool `json:"enabled"`
+	// Template is a Go text/template file replacing the built-in guide
+	Template string `json:"template"`
+	// MaxProjects caps the projects listed, largest first (0 = none)
+	MaxProjects int `json:"max_projects"`
+	// Tips are extra lines appended to the guide, e.g. naming conventions
+	Tips []string `json:"tips"`
+}
+
+// RoleConfig is what a session in one role may list and call.
+type RoleConfig struct {
+	// Tools lists the allowed tool names ([] = every tool)
+	Tools []string `json:"tools"`
+	// ReadOnly also drops the tools that modify the index
+	ReadOnly bool `json:"read_only"`
+}
+
+// builtinRoles are available without configuration.
+var builtinRoles = map[string]RoleConfig{
+	"reader": {ReadOnly: true},
+	"admin":  {},
+}
+
+// SessionRole returns the rules of the configured role; an empty role
+// allows everything.
+func (t ToolsConfig) SessionRole() (RoleConfig, error) {
+	if t.Role == "" {
+		return RoleConfig{}, nil
+	}
+	if r, ok := t.Roles[t.Role]; ok {
+		return r, nil
+	}
+	if r, ok := builtinRoles[t.Role]; ok {
+		return r, nil
+	}
+	return RoleConfig{}, fmt.Errorf("unknown tools role %q", t.Role)
+}
+
+type SearchConfig struct {
+	// ModelMismatch controls hits whose stored embedding provider/model/dim
+	// differs from the one used for the query: "warn" (flag them), "filter"
+	// (drop them) or "ignore"
+	ModelMismatch string `json:"model_mismatch"`
+	// MergeAdjacent joins hits that are consecutive chunks of the same file
+	// into one passage, dropping the repeated overlap text
+	MergeAdjacent bool `json:"merge_adjacent"`
+	// Default search-time HNSW parameters; rag_search can override them per
+	// call. HNSWEf 0 keeps Qdrant's default, Exact forces a full scan.
+	HNSWEf int  `json:"hnsw_ef"`
+	Exact  bool `json:"exact"`
+	// Consistency is the default Qdrant read consistency in a cluster:
+	// "majority", "quorum", "all" or a replica count ("" = Qdrant default)
+	Consistency string `json:"consistency"`
+	// TimeoutSec bounds each search on the server (0 = Qdrant default)
+	TimeoutSec int `json:"timeout_sec"`
+	// ReadThrough re-reads each hit's byte range from the source file at
+	// query time (when it exists locally) instead of returning the stored text
+	ReadThrough bool `json:"read_through"`
+	// MaxOutputKB summarizes rag_search results that would be larger: hits
+	// are listed without their text, which rag_get_chunk fetches by cursor
+	// (0 = never summarize)
+	MaxOutputKB int `json:"max_output_kb"`
+	// EvalDir holds the files rag_eval may read; its file argument is
+	// resolved under it ("" = rag_eval reads no files)
+	EvalDir string `json:"eval_dir"`
+	// Suggestions adds guidance to searches that return nothing useful
+	Suggestions SuggestionsConfig `json:"suggestions"`
+	// Cache keeps recent search results until the index changes
+	Cache SearchCacheConfig `json:"cache"`
+	// Reranker rescores the best candidates of each search
+	Reranker RerankerConfig `json:"reranker"`
+	// Quota caps what each caller can retrieve through searches
+	Quota QuotaConfig `json:"quota"`
+	// Sampling has the MCP client's model answer rag_search from the hits
+	Sampling SamplingConfig `json:"sampling"`
+}
+
+// SamplingConfig controls answers to rag_search written by the model of
+// the MCP client (sampling/createMessage), for clients that advertise the
+// sampling capability. The hits are sent to the client, which may show
+// the request to its user before running it.
+type SamplingConfig struct {
+	// Enabled answers every search; rag_search's answer argument decides
+	// per call
+	Enabled bool `json:"enabled"`
+	// MaxTokens caps the answer
+	MaxTokens int `json:"max_tokens"`
+	// TimeoutSec bounds the wait for the client, including its user's
+	// approval; the hits are returned without an answer after it
+	TimeoutSec int `json:"timeout_sec"`
+	// SystemPrompt replaces the built-in instructions for the answer
+	SystemPrompt string `json:"system_prompt"`
+	// ModelHint is passed to the client as a model preference, e.g.
+	// "claude-3-haiku" ("" = the client's choice)
+	ModelHint string `json:"model_hint"`
+}
+
+// QuotaConfig caps the hits and text one caller receives from searches
+// within a window, so a runaway agent cannot page the whole corpus out. A
+// caller is an HTTP API key (or client address without one) or the MCP
+// session. Searches past a cap return fewer hits and say so.
+type QuotaConfig struct {
+	// WindowSec is the length of a caller's quota window, which starts with
+	// its first search
+	WindowSec int `json:"window_sec"`
+	// MaxResults caps the hits per window (0 = unlimited)
+	MaxResults int `json:"max_results"`
+	// MaxKB caps the text and snippet bytes of those hits (0 = unlimited)
+	MaxKB int `json:"max_kb"`
+}
+
+// Enabled reports whether q caps anything.
+func (q QuotaConfig) Enabled() bool {
+	return q.WindowSec > 0 && (q.MaxResults > 0 || q.MaxKB > 0)
+}
+
+func (q QuotaConfig) validate() error {
+	if q.WindowSec < 0 || q.MaxResults < 0 || q.MaxKB < 0 {
+		return fmt.Errorf("quota window_sec, max_results and max_kb cannot be negative")
+	}
+	return nil
+}
+
+// RerankerConfig picks the model that reorders search candidates after the
+// vector store ranked them.
+type RerankerConfig struct {
+	// Provider is "cohere", "jina", "passthrough" (keeps the store's order)
+	// or "" for none
+	Provider string `json:"provider"`
+	// Model defaults to rerank-v3.5 (cohere) or
+	// jina-reranker-v2-base-multilingual (jina)
+	Model string `json:"model"`
+	// URL replaces the provider's endpoint, e.g. for a self-hosted service
+	// with the same API
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+	// Candidates is how many hits the store returns for reranking; at
+	// least k are used
+	Candidates int `json:"candidates"`
+	TimeoutSec int `json:"timeout_sec"`
+	// FailOpen keeps the store's ranking when the reranker fails instead
+	// of failing the search
+	FailOpen bool `json:"fail_open"`
+}
+
+// SearchCacheConfig controls the in-process cache of search results. An
+// entry is dropped when its project is indexed, deleted or edited, or after
+// TTLSec, which also bounds how long writes by other processes go unseen.
+type SearchCacheConfig struct {
+	// TTLSec is how long results are kept (0 = no cache)
+	TTLSec int `json:"ttl_sec"`
+	// MaxEntries caps the cached searches; the oldest are evicted first
+	MaxEntries int `json:"max_entries"`
+}
+
+// SuggestionsConfig controls the suggestions field of rag_search, which
+// tells agents how to retry a search that found nothing useful instead of
+// answering from empty context.
+type SuggestionsConfig struct {
+	Enabled bool `json:"enabled"`
+	// MinScore is the score the best hit must reach for a search to count
+	// as answered; below it suggestions are added
+	MinScore float64 `json:"min_score"`
+	// MaxProjects caps the closest projects suggested
+	MaxProjects int `json:"max_projects"`
+	// VocabularyTTLSec is how long the indexed terms used for did-you-mean
+	// suggestions are cached
+	VocabularyTTLSec int `json:"vocabulary_ttl_sec"`
+}
+
+// ValidReadConsistency reports whether v is a Qdrant read consistency:
+// empty, "majority", "quorum", "all" or a positive replica count.
+func ValidReadConsistency(v string) bool {
+	switch v {
+	case "", "majority", "quorum", "all":
+		return true
+	}
+	n, err := strconv.Atoi(v)
+	return err == nil && n > 0
 }
 
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Name:    "mcp-rag-service",
-			Version: "1.0.0",
+			Name:          "mcp-rag-service",
+			Version:       "1.0.0",
+			MaxResponseKB: 4096,
+			MaxSessions:   16,
 		},
 		Embedding: EmbeddingConfig{
 			Provider: "local", // Default to local to avoid API dependencies
 			OpenAI: OpenAIConfig{
-				APIKey: os.Getenv("OPENAI_API_KEY"),
-				Model:  "text-embedding-3-small",
-				Dim:    1536,
+				APIKey:       os.Getenv("OPENAI_API_KEY"),
+				Model:        "text-embedding-3-small",
+				Dim:          1536,
+				MaxRequestKB: 1024,
 			},
 			Local: LocalEmbedding{
-				Dim: 300, // TF-IDF dimension
+				Dim:    300, // TF-IDF dimension
+				Engine: "hashing",
+				ONNX: ONNXConfig{
+					MaxTokens: 256,
+				},
 			},
+			Fallback:               []string{},
+			HealthCheckIntervalSec: 60,
 		},
 		Qdrant: QdrantConfig{
-			URL:        "http://localhost:6333",
-			Collection: "mcp_rag",
+			URL:                  "http://localhost:6333",
+			Collection:           "mcp_rag",
+			BreakerThreshold:     5,
+			BreakerCooldownSec:   10,
+			ReconnectIntervalSec: 10,
+			StartupRetries:       5,
+			StartupBackoff:       "2s",
+			FailOpen:             false,
+			ProjectsCacheSec:     300,
+			Retry: RetryConfig{
+				Attempts:   3,
+				Backoff:    "200ms",
+				MaxBackoff: "5s",
+			},
+		},
+		Store: StoreConfig{
+			Backend: "qdrant",
+			Redis: RedisConfig{
+				URL:      "redis://localhost:6379/0",
+				PoolSize: 8,
+			},
+			OpenSearch: OpenSearchConfig{
+				URL:        "http://localhost:9200",
+				Hybrid:     true,
+				TextWeight: 0.3,
+			},
 		},
 		Indexing: IndexingConfig{
-			DocsDir:        "./docs",
-			ChunkSize:      800,
-			ChunkOverlap:   100,
-			BatchSize:      10,
-			IncludeCode:    false,
-			MaxFileKB:      1024, // 1 MB default limit
-			ExcludeDirs:    []string{".git", "node_modules", "vendor", "build", "dist", "target", ".venv"},
-			FollowSymlinks: false,
+			DocsDir:                  "./docs",
+			ChunkSize:                800,
+			ChunkOverlap:             100,
+			BatchSize:                10,
+			BatchRetry:               RetryConfig{Attempts: 3, Backoff: "1s", MaxBackoff: "30s"},
+			IncludeCode:              false,
+			MaxFileKB:                1024, // 1 MB default limit
+			ExcludeDirs:              []string{".git", "node_modules", "vendor", "build", "dist", "target", ".venv"},
+			Exclude:                  []string{},
+			MaxFiles:                 10000,
+			MaxTotalChunks:           100000,
+			LimitAction:              "abort",
+			FollowSymlinks:           false,
+			MaxDepth:                 64,
+			Archives:                 ArchivesConfig{Enabled: false, MaxEntries: 1000, MaxTotalMB: 100},
+			Images:                   ImagesConfig{Enabled: false, MaxKB: 5120, OCR: OCRConfig{TimeoutSec: 60}},
+			ManifestPath:             "rag-manifest.json",
+			TTLSweepIntervalSec:      300,
+			SoftDeleteRetentionHours: 168,
+			PruneMissing:             false,
+			Secrets:                  SecretsConfig{Action: "mask", Allow: []string{}},
+			Filters: FiltersConfig{
+				PII:  PIIFilterConfig{Kinds: []string{}},
+				HTTP: HTTPFilterConfig{Headers: map[string]string{}, TimeoutSec: 30},
+			},
+			Summaries: SummariesConfig{
+				Enabled:      false,
+				Provider:     "extractive",
+				Model:        "gpt-4o-mini",
+				MinFileChars: 1500,
+				MaxChars:     600,
+			},
+			TOC:        TOCConfig{Enabled: true, MaxHeadings: 200},
+			ChunkCache: ChunkCacheConfig{Enabled: false, Dir: "rag-chunk-cache"},
+			ContextHeaders: ContextHeadersConfig{
+				Enabled:  false,
+				Template: DefaultContextHeader,
+			},
+			URLs:  URLFetchConfig{TimeoutSec: 30, MaxPages: 50, CrawlDelayMS: 500},
+			Repos: RepoFetchConfig{TimeoutSec: 300, Protocols: []string{"https", "ssh"}, MaxMB: 500},
 			FileTypes: FileTypesConfig{
-				Documentation: []string{".md", ".txt", ".rst", ".adoc"},
-				Code:          []string{".go", ".py", ".js", ".ts", ".java", ".cpp", ".c", ".h", ".cs", ".php", ".rb", ".rs", ".scala", ".kt", ".swift", ".dart", ".r", ".m", ".sh", ".bat", ".ps1"},
+				Documentation: []string{".md", ".txt", ".rst", ".adoc", ".tex", ".epub", ".vtt", ".srt", ".eml", ".mbox"},
+				Code:          []string{".go", ".py", ".js", ".ts", ".java", ".cpp", ".c", ".h", ".cs", ".php", ".rb", ".rs", ".scala", ".kt", ".swift", ".dart", ".r", ".m", ".sh", ".bat", ".ps1", ".proto", ".graphql", ".gql"},
 				Config:        []string{".json", ".yaml", ".yml", ".xml", ".toml", ".ini", ".cfg", ".conf"},
 				Database:      []string{".sql", ".ddl", ".dml"},
 				Web:           []string{".html", ".css", ".scss", ".less", ".jsx", ".tsx", ".vue", ".svelte"},
 			},
 		},
+		Chunking: ChunkingConfig{
+			Profiles: map[string]ChunkProfile{},
+			Changelogs: ChangelogConfig{
+				Enabled: true,
+				Files:   []string{"CHANGELOG*", "CHANGES*", "HISTORY*", "NEWS*", "RELEASE_NOTES*", "RELEASES*"},
+			},
+		},
+		Language: LanguageConfig{
+			Stopwords:        []string{"en"},
+			ExtraStopwords:   []string{},
+			DisableStopwords: false,
+		},
 		Logging: LoggingConfig{
 			Level:  "info",
 			Prefix: "[MCP-RAG]",
 		},
 		HTTP: HTTPConfig{
-			APIKey: "",
+			APIKey:         "",
+			DebugEndpoints: false,
+			Compare:        []CompareTarget{},
+			SocketMode:     "0660",
+			AccessLog:      "logfmt",
+			Gzip:           true,
+			MaxResponseKB:  1024,
+		},
+		Tools: ToolsConfig{
+			Disabled: []string{},
+			ReadOnly: false,
+			PageSize: 0,
+			Roles:    map[string]RoleConfig{},
+			Reload:   true,
+			Confirm: ConfirmConfig{
+				Mode:       "elicit",
+				Operations: []string{"delete_all", "restore"},
+				TimeoutSec: 120,
+			},
+		},
+		Resources: ResourcesConfig{
+			Instructions: InstructionsConfig{Enabled: true, MaxProjects: 25, Tips: []string{}},
+		},
+		Search: SearchConfig{
+			ModelMismatch: "warn",
+			MergeAdjacent: true,
+			HNSWEf:        0,
+			Exact:         false,
+			ReadThrough:   false,
+			MaxOutputKB:   0,
+			EvalDir:       "eval",
+			Suggestions: SuggestionsConfig{
+				Enabled:          true,
+				MinScore:         0.2,
+				MaxProjects:      3,
+				VocabularyTTLSec: 600,
+			},
+			Cache: SearchCacheConfig{
+				TTLSec:     60,
+				MaxEntries: 500,
+			},
+			Reranker: RerankerConfig{
+				Provider:   "",
+				Candidates: 20,
+				TimeoutSec: 30,
+				FailOpen:   true,
+			},
+			Quota: QuotaConfig{
+				WindowSec: 3600,
+			},
+			Sampling: SamplingConfig{
+				Enabled:    false,
+				MaxTokens:  512,
+				TimeoutSec: 60,
+			},
+		},
+		Backup: BackupConfig{
+			Dir:          "",
+			KeepOnServer: false,
+			TimeoutSec:   600,
+		},
+		QueryLog: QueryLogConfig{
+			Enabled:       false,
+			Path:          "rag-queries.jsonl",
+			Mode:          "hash",
+			RetentionDays: 30,
+			MaxResults:    3,
+		},
+		Access: AccessConfig{
+			Rules:   []ACLRule{},
+			Tenants: []TenantConfig{},
 		},
 	}
 }
@@ -150,13 +1426,14 @@ func InitConfig(configPath string) error {
 	return nil
 }
 
-// LoadFromFile loads configuration from a JSON file
+// LoadFromFile loads configuration from a JSON file, which may contain
+// `//` comments
 func (c *Config) LoadFromFile(path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(data, c)
+	return json.Unmarshal(stripComments(data), c)
 }
 
 // LoadFromEnv overrides configuration with environment variables
@@ -168,6 +1445,9 @@ func (c *Config) LoadFromEnv() {
 	if v := os.Getenv("MCP_SERVER_VERSION"); v != "" {
 		c.Server.Version = v
 	}
+	if v := os.Getenv("MCP_LENIENT_INITIALIZE"); v != "" {
+		c.Server.LenientInitialize = v == "1" || strings.EqualFold(v, "true")
+	}
 
 	// Embedding config
 	if v := os.Getenv("EMBEDDING_PROVIDER"); v != "" {
@@ -179,6 +1459,12 @@ func (c *Config) LoadFromEnv() {
 	if v := os.Getenv("OPENAI_EMBED_MODEL"); v != "" {
 		c.Embedding.OpenAI.Model = v
 	}
+	if v := os.Getenv("RERANK_API_KEY"); v != "" {
+		c.Search.Reranker.APIKey = v
+	}
+	if v := os.Getenv("OCR_API_KEY"); v != "" {
+		c.Indexing.Images.OCR.APIKey = v
+	}
 
 	// Qdrant config
 	if v := os.Getenv("QDRANT_URL"); v != "" {
@@ -187,21 +1473,99 @@ func (c *Config) LoadFromEnv() {
 	if v := os.Getenv("QDRANT_COLLECTION"); v != "" {
 		c.Qdrant.Collection = v
 	}
+	if v := os.Getenv("QDRANT_FAIL_OPEN"); v != "" {
+		c.Qdrant.FailOpen = v == "1" || strings.EqualFold(v, "true")
+	}
+
+	// Vector store config
+	if v := os.Getenv("VECTOR_STORE"); v != "" {
+		c.Store.Backend = v
+	}
+	if v := os.Getenv("REDIS_URL"); v != "" {
+		c.Store.Redis.URL = v
+	}
+	if v := os.Getenv("OPENSEARCH_URL"); v != "" {
+		c.Store.OpenSearch.URL = v
+	}
+	if v := os.Getenv("OPENSEARCH_PASSWORD"); v != "" {
+		c.Store.OpenSearch.Password = v
+	}
+	if v := os.Getenv("MEMORY_STORE_PATH"); v != "" {
+		c.Store.Memory.Path = v
+	}
+	if v := os.Getenv("PAYLOAD_ENCRYPTION_KEY"); v != "" {
+		c.Store.Encryption.Key = v
+	}
 
 	// Indexing config
 	if v := os.Getenv("DOCS_DIR"); v != "" {
 		c.Indexing.DocsDir = v
 	}
+	if v := os.Getenv("RAG_MANIFEST_PATH"); v != "" {
+		c.Indexing.ManifestPath = v
+	}
+	if v := os.Getenv("RAG_QUERY_LOG_SALT"); v != "" {
+		c.QueryLog.Salt = v
+	}
+	if v := os.Getenv("RAG_SUMMARIES"); v != "" {
+		c.Indexing.Summaries.Enabled = v == "1" || strings.EqualFold(v, "true")
+	}
+
+	// Language config
+	if v := os.Getenv("RAG_STOPWORDS"); v != "" {
+		if strings.EqualFold(v, "none") {
+			c.Language.DisableStopwords = true
+		} else {
+			c.Language.Stopwords = strings.Split(v, ",")
+			c.Language.DisableStopwords = false
+		}
+	}
 
 	// Logging config
 	if v := os.Getenv("LOG_LEVEL"); v != "" {
 		c.Logging.Level = v
 	}
+	if v := os.Getenv("REDACT_ERRORS"); v != "" {
+		c.Logging.RedactErrors = v == "1" || strings.EqualFold(v, "true")
+	}
 
 	// HTTP config
 	if v := os.Getenv("HTTP_API_KEY"); v != "" {
 		c.HTTP.APIKey = v
 	}
+	if v := os.Getenv("HTTP_ADMIN_API_KEY"); v != "" {
+		c.HTTP.AdminAPIKey = v
+	}
+
+	// Tools config
+	if v := os.Getenv("MCP_READ_ONLY"); v != "" {
+		c.Tools.ReadOnly = v == "1" || strings.EqualFold(v, "true")
+	}
+	if v := os.Getenv("MCP_ROLE"); v != "" {
+		c.Tools.Role = v
+	}
+	if v := os.Getenv("MCP_CONFIRM"); v != "" {
+		c.Tools.Confirm.Mode = v
+	}
+	if v := os.Getenv("MCP_TENANT"); v != "" {
+		c.Access.Tenant = v
+	}
+
+	// Connectors config
+	for i := range c.Connectors {
+		cn := &c.Connectors[i]
+		switch cn.Type {
+		case "confluence":
+			if cn.APIToken == "" {
+				cn.APIToken = os.Getenv("CONFLUENCE_API_TOKEN")
+			}
+		case "s3":
+			cn.S3Location.applyEnv()
+		}
+	}
+	if c.Backup.S3.Bucket != "" {
+		c.Backup.S3.applyEnv()
+	}
 }
 
 // Validate checks if the configuration is valid
@@ -215,15 +1579,456 @@ func (c *Config) Validate() error {
 	if c.Embedding.Provider == "openai" && c.Embedding.OpenAI.APIKey == "" {
 		return fmt.Errorf("OpenAI API key is required when using OpenAI provider")
 	}
+	if c.Embedding.OpenAI.MaxRequestKB < 0 {
+		return fmt.Errorf("embedding openai max_request_kb cannot be negative")
+	}
+	switch c.Embedding.Local.Engine {
+	case "", "hashing":
+	case "onnx":
+		if c.Embedding.Local.ONNX.ModelPath == "" || c.Embedding.Local.ONNX.VocabPath == "" {
+			return fmt.Errorf("local onnx engine requires onnx.model_path and onnx.vocab_path")
+		}
+		if c.Embedding.Local.ONNX.MaxTokens < 3 {
+			return fmt.Errorf("local onnx max_tokens must be at least 3")
+		}
+	default:
+		return fmt.Errorf("local embedding engine must be 'hashing' or 'onnx'")
+	}
+	if c.Server.MaxResponseKB < 0 {
+		return fmt.Errorf("max response size cannot be negative")
+	}
+	if c.Server.MaxSessions < 0 {
+		return fmt.Errorf("server.max_sessions cannot be negative")
+	}
+	if _, err := c.Tools.SessionRole(); err != nil {
+		return err
+	}
+	if c.Tools.PageSize < 0 {
+		return fmt.Errorf("tools page size cannot be negative")
+	}
+	switch c.Tools.Confirm.Mode {
+	case "", "off", "elicit", "require":
+	default:
+		return fmt.Errorf("tools confirm mode must be 'off', 'elicit' or 'require'")
+	}
+	for _, op := range c.Tools.Confirm.Operations {
+		if !slices.Contains(ConfirmOperations, op) {
+			return fmt.Errorf("tools confirm operation %q must be one of %s", op, strings.Join(ConfirmOperations, ", "))
+		}
+	}
+	if c.Tools.Confirm.TimeoutSec < 1 && c.Tools.Confirm.Mode != "off" {
+		return fmt.Errorf("tools confirm timeout_sec must be at least 1")
+	}
+	if c.Resources.Instructions.MaxProjects < 0 {
+		return fmt.Errorf("resources instructions max_projects cannot be negative")
+	}
+	if t := c.Resources.Instructions.Template; t != "" {
+		if _, err := os.Stat(t); err != nil {
+			return fmt.Errorf("resources instructions template: %w", err)
+		}
+	}
+	for _, fb := range c.Embedding.Fallback {
+		if fb != "openai" && fb != "local" {
+			return fmt.Errorf("fallback provider must be 'openai' or 'local', got %q", fb)
+		}
+		if fb == "openai" && c.Embedding.OpenAI.APIKey == "" {
+			return fmt.Errorf("OpenAI API key is required when using OpenAI as fallback provider")
+		}
+	}
+	switch c.Search.ModelMismatch {
+	case "warn", "filter", "ignore":
+	default:
+		return fmt.Errorf("search model_mismatch must be 'warn', 'filter' or 'ignore'")
+	}
+	if sc := c.Search.Suggestions; sc.MinScore < 0 || sc.MaxProjects < 0 || sc.VocabularyTTLSec < 0 {
+		return fmt.Errorf("search suggestions min_score, max_projects and vocabulary_ttl_sec cannot be negative")
+	}
+	if err := c.Search.Quota.validate(); err != nil {
+		return fmt.Errorf("search %w", err)
+	}
+	if c.Search.Cache.TTLSec < 0 || c.Search.Cache.MaxEntries < 0 {
+		return fmt.Errorf("search cache ttl_sec and max_entries cannot be negative")
+	}
+	if c.Search.Sampling.MaxTokens < 1 {
+		return fmt.Errorf("search sampling max_tokens must be at least 1")
+	}
+	if c.Search.Sampling.TimeoutSec < 1 {
+		return fmt.Errorf("search sampling timeout_sec must be at least 1")
+	}
+	switch rc := c.Search.Reranker; rc.Provider {
+	case "", "passthrough":
+	case "cohere", "jina":
+		if rc.APIKey == "" && rc.URL == "" {
+			return fmt.Errorf("search reranker %s requires api_key (or RERANK_API_KEY)", rc.Provider)
+		}
+		if rc.Candidates < 1 || rc.Candidates > 1000 {
+			return fmt.Errorf("search reranker candidates must be between 1 and 1000")
+		}
+	default:
+		return fmt.Errorf("search reranker provider must be 'cohere', 'jina', 'passthrough' or empty")
+	}
+	if c.Indexing.Summaries.Enabled {
+		switch c.Indexing.Summaries.Provider {
+		case "extractive":
+		case "openai":
+			if c.Embedding.OpenAI.APIKey == "" {
+				return fmt.Errorf("OpenAI API key is required for openai summaries")
+			}
+		default:
+			return fmt.Errorf("summaries provider must be 'extractive' or 'openai'")
+		}
+		if c.Indexing.Summaries.MaxChars <= 0 {
+			return fmt.Errorf("summaries max_chars must be positive")
+		}
+	}
+	if c.Indexing.TOC.MaxHeadings < 0 {
+		return fmt.Errorf("toc max_headings must be at least 0")
+	}
+	if ch := c.Indexing.ContextHeaders; ch.Enabled {
+		if _, err := ch.Parse(); err != nil {
+			return fmt.Errorf("context_headers template: %w", err)
+		}
+	}
+	for _, lang := range c.Language.Stopwords {
+		if !stopwords.Known(strings.TrimSpace(lang)) {
+			return fmt.Errorf("unknown stopword language %q (available: %s)", lang, strings.Join(stopwords.Languages(), ", "))
+		}
+	}
+	for ext, p := range c.Chunking.Profiles {
+		if !strings.HasPrefix(ext, ".") || ext != strings.ToLower(ext) {
+			return fmt.Errorf("chunking profile key %q must be a lowercase extension starting with '.'", ext)
+		}
+		switch p.Strategy {
+		case "", StrategyWindow, StrategyMarkdown:
+		case StrategyAST:
+			if ext != ".go" {
+				return fmt.Errorf("chunking profile %s: strategy 'ast' is only supported for .go", ext)
+			}
+		case StrategySchema:
+			if !slices.Contains(SchemaExtensions, ext) {
+				return fmt.Errorf("chunking profile %s: strategy 'schema' is only supported for %s", ext, strings.Join(SchemaExtensions, ", "))
+			}
+		default:
+			return fmt.Errorf("chunking profile %s: strategy must be 'window', 'markdown', 'ast' or 'schema'", ext)
+		}
+		size, overlap, _ := c.ChunkProfileFor(ext, c.Indexing.ChunkSize, c.Indexing.ChunkOverlap)
+		if p.Size < 0 || overlap < 0 || overlap >= size {
+			return fmt.Errorf("chunking profile %s: need size > overlap >= 0", ext)
+		}
+	}
+	for _, f := range c.Chunking.Changelogs.Files {
+		if _, err := path.Match(strings.ToLower(f), ""); err != nil {
+			return fmt.Errorf("chunking changelogs files: invalid pattern %q", f)
+		}
+	}
+	if c.HTTP.DebugEndpoints && c.HTTP.AdminKey() == "" {
+		return fmt.Errorf("http debug_endpoints requires http api_key or admin_api_key to be set")
+	}
+	if c.HTTP.PayloadAdmin && c.HTTP.AdminKey() == "" {
+		return fmt.Errorf("http payload_admin requires http api_key or admin_api_key to be set")
+	}
+	if _, err := c.HTTP.SocketFileMode(); err != nil {
+		return fmt.Errorf("http socket_mode: %w", err)
+	}
+	switch c.HTTP.AccessLog {
+	case "", "logfmt", "json", "off":
+	default:
+		return fmt.Errorf("http access_log must be 'logfmt', 'json' or 'off'")
+	}
+	if c.HTTP.MaxResponseKB < 0 {
+		return fmt.Errorf("http max_response_kb must be >= 0")
+	}
+	switch c.Store.Backend {
+	case "qdrant":
+	case "redis":
+		if !strings.HasPrefix(c.Store.Redis.URL, "redis://") && !strings.HasPrefix(c.Store.Redis.URL, "rediss://") {
+			return fmt.Errorf("store redis url must start with redis:// or rediss://")
+		}
+		if c.Store.Redis.PoolSize < 1 {
+			return fmt.Errorf("store redis pool_size must be at least 1")
+		}
+	case "opensearch":
+		osc := c.Store.OpenSearch
+		if !strings.HasPrefix(osc.URL, "http://") && !strings.HasPrefix(osc.URL, "https://") {
+			return fmt.Errorf("store opensearch url must start with http:// or https://")
+		}
+		if osc.TextWeight < 0 || osc.TextWeight > 1 {
+			return fmt.Errorf("store opensearch text_weight must be in [0, 1]")
+		}
+	case "memory":
+	default:
+		return fmt.Errorf("store backend must be 'qdrant', 'redis', 'opensearch' or 'memory'")
+	}
+	if key := c.Store.Encryption.Key; key != "" {
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return fmt.Errorf("store encryption key must be base64: %v", err)
+		}
+		if n := len(raw); n != 16 && n != 24 && n != 32 {
+			return fmt.Errorf("store encryption key must be 16, 24 or 32 bytes, got %d", n)
+		}
+	}
+	seen := map[string]bool{"primary": true}
+	for _, t := range c.HTTP.Compare {
+		if strings.TrimSpace(t.Name) == "" || seen[t.Name] {
+			return fmt.Errorf("http compare targets need unique names other than 'primary'")
+		}
+		seen[t.Name] = true
+		if strings.TrimSpace(t.Collection) == "" {
+			return fmt.Errorf("http compare target %s: collection is required", t.Name)
+		}
+		switch t.Provider {
+		case "", "local":
+		case "openai":
+			if c.Embedding.OpenAI.APIKey == "" {
+				return fmt.Errorf("http compare target %s: OpenAI API key is required", t.Name)
+			}
+		default:
+			return fmt.Errorf("http compare target %s: provider must be 'openai' or 'local'", t.Name)
+		}
+		if t.Dim < 0 {
+			return fmt.Errorf("http compare target %s: dim cannot be negative", t.Name)
+		}
+	}
+	if c.QueryLog.Enabled {
+		if strings.TrimSpace(c.QueryLog.Path) == "" {
+			return fmt.Errorf("query_log path is required when query logging is enabled")
+		}
+		if c.QueryLog.Mode != "hash" && c.QueryLog.Mode != "raw" {
+			return fmt.Errorf("query_log mode must be 'hash' or 'raw'")
+		}
+		if c.QueryLog.RetentionDays < 0 || c.QueryLog.MaxResults < 0 {
+			return fmt.Errorf("query_log retention_days and max_results cannot be negative")
+		}
+	}
+	if err := c.Access.validate(c.HTTP); err != nil {
+		return err
+	}
+	for _, w := range c.Webhooks {
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook url %q must be an http(s) URL", w.URL)
+		}
+		for _, e := range w.Events {
+			if e != "index" && e != "delete" {
+				return fmt.Errorf("webhook %s: events must be 'index' or 'delete'", u.Host)
+			}
+		}
+	}
+	names := map[string]bool{}
+	for _, cn := range c.Connectors {
+		if strings.TrimSpace(cn.Name) == "" || names[cn.Name] {
+			return fmt.Errorf("connector names must be set and unique (got %q)", cn.Name)
+		}
+		names[cn.Name] = true
+		switch cn.Type {
+		case "confluence":
+			u, err := url.Parse(cn.BaseURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("connector %s: base_url %q must be an http(s) URL", cn.Name, cn.BaseURL)
+			}
+			if cn.SpaceKey == "" || cn.Email == "" || cn.APIToken == "" {
+				return fmt.Errorf("connector %s: confluence requires space_key, email and api_token", cn.Name)
+			}
+		case "s3":
+			if cn.Bucket == "" {
+				return fmt.Errorf("connector %s: s3 requires bucket", cn.Name)
+			}
+			if err := cn.S3Location.validate(); err != nil {
+				return fmt.Errorf("connector %s: %w", cn.Name, err)
+			}
+		case "slack_export", "discord_export":
+			if cn.Path == "" {
+				return fmt.Errorf("connector %s: %s requires path", cn.Name, cn.Type)
+			}
+			if cn.BaseURL != "" {
+				if u, err := url.Parse(cn.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("connector %s: base_url %q must be an http(s) URL", cn.Name, cn.BaseURL)
+				}
+			}
+			if cn.GapMinutes < 0 {
+				return fmt.Errorf("connector %s: gap_minutes cannot be negative", cn.Name)
+			}
+		default:
+			return fmt.Errorf("connector %s: unknown type %q (supported: confluence, s3, slack_export, discord_export)", cn.Name, cn.Type)
+		}
+		if cn.TimeoutSec < 0 {
+			return fmt.Errorf("connector %s: timeout_sec cannot be negative", cn.Name)
+		}
+		if err := CheckLabels(cn.ACL); err != nil {
+			return fmt.Errorf("connector %s: %w", cn.Name, err)
+		}
+	}
+	if c.Backup.S3.Bucket != "" {
+		if err := c.Backup.S3.validate(); err != nil {
+			return fmt.Errorf("backup s3: %w", err)
+		}
+	}
+	if c.Backup.TimeoutSec < 0 {
+		return fmt.Errorf("backup timeout_sec cannot be negative")
+	}
+	if len(c.HTTP.Compare) > 0 && c.HTTP.AdminKey() == "" {
+		return fmt.Errorf("http compare requires http api_key or admin_api_key to be set")
+	}
+	if err := c.Qdrant.CollectionConfig.validate(); err != nil {
+		return err
+	}
+	if c.Qdrant.ProjectsCacheSec < 0 {
+		return fmt.Errorf("qdrant projects_cache_sec cannot be negative")
+	}
+	if c.Search.HNSWEf < 0 {
+		return fmt.Errorf("search hnsw_ef cannot be negative")
+	}
+	if !ValidReadConsistency(c.Search.Consistency) {
+		return fmt.Errorf("search consistency must be 'majority', 'quorum', 'all' or a positive number of replicas")
+	}
+	if c.Search.TimeoutSec < 0 {
+		return fmt.Errorf("search timeout_sec cannot be negative")
+	}
+	if c.Search.MaxOutputKB < 0 {
+		return fmt.Errorf("search max_output_kb cannot be negative")
+	}
+	if c.Qdrant.StartupRetries < 1 {
+		return fmt.Errorf("qdrant startup_retries must be at least 1")
+	}
+	if _, err := c.Qdrant.StartupBackoffDuration(); err != nil {
+		return fmt.Errorf("invalid qdrant startup_backoff: %w", err)
+	}
+	if c.Qdrant.Retry.Attempts < 1 {
+		return fmt.Errorf("qdrant retry attempts must be at least 1")
+	}
+	if _, _, err := c.Qdrant.Retry.Durations(); err != nil {
+		return fmt.Errorf("invalid qdrant retry: %w", err)
+	}
 	if c.Indexing.ChunkSize <= 0 {
 		return fmt.Errorf("chunk size must be positive")
 	}
 	if c.Indexing.ChunkOverlap < 0 {
 		return fmt.Errorf("chunk overlap cannot be negative")
 	}
+	if c.Indexing.URLs.TimeoutSec <= 0 {
+		return fmt.Errorf("indexing urls timeout_sec must be positive")
+	}
+	if c.Indexing.URLs.MaxPages <= 0 {
+		return fmt.Errorf("indexing urls max_pages must be positive")
+	}
+	if c.Indexing.URLs.CrawlDelayMS < 0 {
+		return fmt.Errorf("indexing urls crawl_delay_ms cannot be negative")
+	}
+	if c.Indexing.Repos.TimeoutSec <= 0 {
+		return fmt.Errorf("indexing repos timeout_sec must be positive")
+	}
+	if c.Indexing.Repos.MaxMB < 0 {
+		return fmt.Errorf("indexing repos max_mb cannot be negative")
+	}
+	for _, proto := range c.Indexing.Repos.Protocols {
+		switch proto {
+		case "https", "http", "ssh", "git":
+		default:
+			return fmt.Errorf("indexing repos protocol %q is not supported (use https, http, ssh or git)", proto)
+		}
+	}
+	if _, err := pathglob.Compile(c.Indexing.Exclude); err != nil {
+		return fmt.Errorf("indexing exclude: %v", err)
+	}
+	if c.Indexing.Archives.MaxEntries < 0 || c.Indexing.Archives.MaxTotalMB < 0 {
+		return fmt.Errorf("indexing archives max_entries and max_total_mb cannot be negative")
+	}
+	if im := c.Indexing.Images; im.MaxKB < 0 || im.OCR.TimeoutSec < 0 {
+		return fmt.Errorf("indexing images max_kb and ocr timeout_sec cannot be negative")
+	}
+	if u := c.Indexing.Images.OCR.URL; u != "" && !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return fmt.Errorf("indexing images ocr url must start with http:// or https://")
+	}
+	if c.Indexing.MaxFiles < 0 || c.Indexing.MaxTotalChunks < 0 || c.Indexing.MaxDepth < 0 {
+		return fmt.Errorf("indexing max_files, max_total_chunks and max_depth cannot be negative")
+	}
+	if c.Indexing.ChunkCache.Enabled && strings.TrimSpace(c.Indexing.ChunkCache.Dir) == "" {
+		return fmt.Errorf("indexing chunk_cache dir is required when the chunk cache is enabled")
+	}
+	switch c.Indexing.LimitAction {
+	case "", "abort", "warn":
+	default:
+		return fmt.Errorf("indexing limit_action must be 'abort' or 'warn'")
+	}
+	switch c.Indexing.Secrets.Action {
+	case "", "mask", "skip", "flag", "off":
+	default:
+		return fmt.Errorf("indexing secrets action must be 'mask', 'skip', 'flag' or 'off'")
+	}
+	if _, err := secrets.New(c.Indexing.Secrets.Allow); err != nil {
+		return fmt.Errorf("indexing secrets: %v", err)
+	}
+	if _, err := pii.New(c.Indexing.Filters.PII.Kinds); err != nil {
+		return fmt.Errorf("indexing filters pii: %v", err)
+	}
+	if u := c.Indexing.Filters.HTTP.URL; u != "" {
+		if p, err := url.Parse(u); err != nil || (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+			return fmt.Errorf("indexing filters http url must be an http(s) URL")
+		}
+	}
+	if c.Indexing.Filters.HTTP.TimeoutSec < 0 {
+		return fmt.Errorf("indexing filters http timeout_sec cannot be negative")
+	}
+	if c.Indexing.TTLSweepIntervalSec < 0 {
+		return fmt.Errorf("indexing ttl_sweep_interval_sec cannot be negative")
+	}
+	if c.Indexing.SoftDeleteRetentionHours < 0 {
+		return fmt.Errorf("indexing soft_delete_retention_hours cannot be negative")
+	}
 	if c.Indexing.BatchSize <= 0 {
 		return fmt.Errorf("batch size must be positive")
 	}
+	if c.Indexing.BatchRetry.Attempts < 1 {
+		return fmt.Errorf("indexing batch_retry attempts must be at least 1")
+	}
+	if _, _, err := c.Indexing.BatchRetry.Durations(); err != nil {
+		return fmt.Errorf("invalid indexing batch_retry: %w", err)
+	}
+	return nil
+}
+
+// StartupBackoffDuration parses StartupBackoff (empty means 2s).
+func (q QdrantConfig) StartupBackoffDuration() (time.Duration, error) {
+	if strings.TrimSpace(q.StartupBackoff) == "" {
+		return 2 * time.Second, nil
+	}
+	d, err := time.ParseDuration(q.StartupBackoff)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
+
+func (cc CollectionConfig) validate() error {
+	if cc.HNSW.M < 0 || cc.HNSW.EfConstruct < 0 {
+		return fmt.Errorf("qdrant hnsw m and ef_construct cannot be negative")
+	}
+	if cc.ShardNumber < 0 || cc.ReplicationFactor < 0 || cc.WriteConsistencyFactor < 0 {
+		return fmt.Errorf("qdrant shard_number, replication_factor and write_consistency_factor cannot be negative")
+	}
+	if cc.WriteConsistencyFactor > max(cc.ReplicationFactor, 1) {
+		return fmt.Errorf("qdrant write_consistency_factor cannot exceed replication_factor")
+	}
+	q := cc.Quantization
+	switch q.Type {
+	case "", "binary":
+	case "scalar":
+		if q.Quantile != 0 && (q.Quantile <= 0.5 || q.Quantile > 1) {
+			return fmt.Errorf("qdrant scalar quantization quantile must be in (0.5, 1]")
+		}
+	case "product":
+		switch q.Compression {
+		case "x4", "x8", "x16", "x32", "x64":
+		default:
+			return fmt.Errorf("qdrant product quantization compression must be x4, x8, x16, x32 or x64")
+		}
+	default:
+		return fmt.Errorf("qdrant quantization type must be 'scalar', 'product' or 'binary'")
+	}
 	return nil
 }
 
@@ -0,0 +1,215 @@
+package connectors
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// chatMessage is one message of a chat export.
+type chatMessage struct {
+	ID     string // unique in its channel: the Slack ts, the Discord ID
+	Time   time.Time
+	Author string
+	Text   string
+	// Thread is the ID of the message that started the message's thread,
+	// "" outside threads
+	Thread string
+}
+
+// chatChannel is a channel of an export with its messages in any order.
+type chatChannel struct {
+	ID, Name string
+	Messages []chatMessage
+	// Link returns the web URL of a message, "" when there is none
+	Link func(m chatMessage) string
+}
+
+// chatWindow is one conversation: a thread, or a run of channel messages
+// with no pause longer than the gap between two of them.
+type chatWindow struct {
+	Channel  *chatChannel
+	Thread   bool
+	Messages []chatMessage
+}
+
+// chatExport indexes the conversations of a chat export. load reads the
+// export's channels; each conversation becomes a document whose version
+// is a hash of its text, so a thread that got new replies is indexed again.
+type chatExport struct {
+	conf   cfg.ConnectorConfig
+	scheme string // URI scheme of conversations without a web link
+	load   func() ([]*chatChannel, error)
+
+	once    sync.Once
+	windows map[string]chatWindow
+	err     error
+}
+
+func (c *chatExport) read() (map[string]chatWindow, error) {
+	c.once.Do(func() {
+		channels, err := c.load()
+		if err != nil {
+			c.err = err
+			return
+		}
+		gap := 30 * time.Minute
+		if c.conf.GapMinutes > 0 {
+			gap = time.Duration(c.conf.GapMinutes) * time.Minute
+		}
+		c.windows = map[string]chatWindow{}
+		for _, ch := range channels {
+			if len(c.conf.Channels) > 0 && !slices.Contains(c.conf.Channels, ch.Name) {
+				continue
+			}
+			for _, w := range chatWindows(ch, gap) {
+				c.windows[w.id()] = w
+			}
+		}
+	})
+	return c.windows, c.err
+}
+
+func (c *chatExport) List(ctx context.Context) ([]Ref, error) {
+	windows, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Ref, 0, len(windows))
+	for id, w := range windows {
+		out = append(out, w.ref(id))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// Incremental lists every conversation. An export is a snapshot taken
+// before the previous sync may have run, so message times say nothing
+// about what it indexed; unchanged conversations are skipped by version.
+func (c *chatExport) Incremental(ctx context.Context, since time.Time) ([]Ref, error) {
+	return c.List(ctx)
+}
+
+// Fetch returns a conversation as one line per message under a heading
+// naming its channel and date. Its chunks carry channel, thread_id,
+// started_at and ended_at payload.
+func (c *chatExport) Fetch(ctx context.Context, ref Ref) (Document, error) {
+	windows, err := c.read()
+	if err != nil {
+		return Document{}, err
+	}
+	w, ok := windows[ref.ID]
+	if !ok {
+		return Document{}, fmt.Errorf("conversation %s is no longer in the export", ref.ID)
+	}
+	first, last := w.Messages[0], w.Messages[len(w.Messages)-1]
+	url := w.Channel.Link(first)
+	if url == "" {
+		url = c.scheme + "://" + w.Channel.Name + "/" + first.ID
+	}
+	doc := Document{
+		Ref:   w.ref(ref.ID),
+		Title: w.title(),
+		URL:   url,
+		Text:  w.text(),
+		Meta: map[string]any{
+			"channel":    w.Channel.Name,
+			"started_at": first.Time.UTC().Format(time.RFC3339),
+			"ended_at":   last.Time.UTC().Format(time.RFC3339),
+		},
+	}
+	if w.Thread {
+		doc.Meta["thread_id"] = first.Thread
+	}
+	return doc, nil
+}
+
+// chatWindows groups the messages of a channel into conversations: each
+// thread is one, including the message that started it, and the other
+// messages are cut wherever the channel was quiet for longer than gap.
+func chatWindows(ch *chatChannel, gap time.Duration) []chatWindow {
+	msgs := slices.Clone(ch.Messages)
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].Time.Before(msgs[j].Time) })
+	threads := map[string]int{}
+	var out []chatWindow
+	flow := -1 // the conversation outside threads being filled
+	for _, m := range msgs {
+		if m.Thread != "" {
+			i, ok := threads[m.Thread]
+			if !ok {
+				i = len(out)
+				threads[m.Thread] = i
+				out = append(out, chatWindow{Channel: ch, Thread: true})
+			}
+			out[i].Messages = append(out[i].Messages, m)
+			continue
+		}
+		if flow < 0 || m.Time.Sub(out[flow].Messages[len(out[flow].Messages)-1].Time) > gap {
+			flow = len(out)
+			out = append(out, chatWindow{Channel: ch})
+		}
+		out[flow].Messages = append(out[flow].Messages, m)
+	}
+	return out
+}
+
+// id names a conversation after its channel and first message.
+func (w chatWindow) id() string {
+	return w.Channel.ID + "/" + w.Messages[0].ID
+}
+
+func (w chatWindow) ref(id string) Ref {
+	sum := sha256.Sum256([]byte(w.text()))
+	return Ref{ID: id, Version: hex.EncodeToString(sum[:8]), Modified: w.Messages[len(w.Messages)-1].Time}
+}
+
+func (w chatWindow) title() string {
+	if w.Thread {
+		return "#" + w.Channel.Name + " thread: " + firstWords(w.Messages[0].Text, 60)
+	}
+	return "#" + w.Channel.Name + ", " + w.Messages[0].Time.UTC().Format("2006-01-02 15:04")
+}
+
+// text renders the conversation, each message as "[15:04] author: text";
+// the date is repeated whenever it changes.
+func (w chatWindow) text() string {
+	var b strings.Builder
+	kind := ""
+	if w.Thread {
+		kind = " thread"
+	}
+	day := w.Messages[0].Time.UTC().Format("2006-01-02")
+	fmt.Fprintf(&b, "# #%s%s, %s\n\n", w.Channel.Name, kind, day)
+	for _, m := range w.Messages {
+		t := m.Time.UTC()
+		stamp := t.Format("15:04")
+		if d := t.Format("2006-01-02"); d != day {
+			day = d
+			stamp = d + " " + stamp
+		}
+		fmt.Fprintf(&b, "[%s] %s: %s\n", stamp, m.Author, m.Text)
+	}
+	return b.String()
+}
+
+// firstWords returns the start of s, cut at a word boundary after at most
+// n runes.
+func firstWords(s string, n int) string {
+	r := []rune(strings.Join(strings.Fields(s), " "))
+	if len(r) <= n {
+		return string(r)
+	}
+	cut := string(r[:n])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
@@ -0,0 +1,184 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// confluence reads the current pages of one Confluence Cloud space through
+// the REST API, authenticating with an account email and API token.
+type confluence struct {
+	conf   cfg.ConnectorConfig
+	base   string
+	client *http.Client
+}
+
+const confluencePageSize = 100
+
+func newConfluence(conf cfg.ConnectorConfig) *confluence {
+	timeout := 30 * time.Second
+	if conf.TimeoutSec > 0 {
+		timeout = time.Duration(conf.TimeoutSec) * time.Second
+	}
+	return &confluence{conf: conf, base: strings.TrimRight(conf.BaseURL, "/"), client: &http.Client{Timeout: timeout}}
+}
+
+// confluenceContent is the part of a content object the connector reads.
+type confluenceContent struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Version struct {
+		Number int    `json:"number"`
+		When   string `json:"when"`
+	} `json:"version"`
+	Body struct {
+		Storage struct {
+			Value string `json:"value"`
+		} `json:"storage"`
+	} `json:"body"`
+	Links struct {
+		WebUI string `json:"webui"`
+		Base  string `json:"base"`
+	} `json:"_links"`
+}
+
+func (p confluenceContent) ref() Ref {
+	when, _ := time.Parse(time.RFC3339, p.Version.When)
+	return Ref{ID: p.ID, Version: strconv.Itoa(p.Version.Number), Modified: when}
+}
+
+func (c *confluence) List(ctx context.Context) ([]Ref, error) {
+	q := url.Values{
+		"spaceKey": {c.conf.SpaceKey},
+		"type":     {"page"},
+		"status":   {"current"},
+		"expand":   {"version"},
+		"limit":    {strconv.Itoa(confluencePageSize)},
+	}
+	return c.refs(ctx, "/rest/api/content?"+q.Encode())
+}
+
+func (c *confluence) Incremental(ctx context.Context, since time.Time) ([]Ref, error) {
+	// CQL dates have minute precision and are read in the account's time
+	// zone, so look back a day; pages whose version is unchanged are skipped
+	// by the caller anyway
+	cql := fmt.Sprintf(`space = "%s" and type = page and lastmodified >= "%s"`,
+		c.conf.SpaceKey, since.Add(-24*time.Hour).UTC().Format("2006-01-02 15:04"))
+	q := url.Values{
+		"cql":    {cql},
+		"expand": {"version"},
+		"limit":  {strconv.Itoa(confluencePageSize)},
+	}
+	return c.refs(ctx, "/rest/api/content/search?"+q.Encode())
+}
+
+// refs collects the results of a listing, following its next links.
+func (c *confluence) refs(ctx context.Context, next string) ([]Ref, error) {
+	var out []Ref
+	for next != "" {
+		var page struct {
+			Results []confluenceContent `json:"results"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := c.get(ctx, next, &page); err != nil {
+			return out, err
+		}
+		for _, p := range page.Results {
+			out = append(out, p.ref())
+		}
+		next = page.Links.Next
+		if len(page.Results) == 0 {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (c *confluence) Fetch(ctx context.Context, ref Ref) (Document, error) {
+	var p confluenceContent
+	if err := c.get(ctx, "/rest/api/content/"+url.PathEscape(ref.ID)+"?expand=body.storage,version", &p); err != nil {
+		return Document{}, err
+	}
+	base := p.Links.Base
+	if base == "" {
+		base = c.base
+	}
+	text := chunker.ParseHTML(confluenceHTML(p.Body.Storage.Value)).Text
+	return Document{
+		Ref:   p.ref(),
+		Title: p.Title,
+		URL:   base + p.Links.WebUI,
+		Text:  strings.TrimSpace("# " + p.Title + "\n\n" + text),
+	}, nil
+}
+
+// get fetches an API path (relative to the base URL) and decodes its JSON.
+func (c *confluence) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.conf.Email, c.conf.APIToken)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("confluence: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		hint := ""
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			hint = " (check email and api_token)"
+		}
+		return fmt.Errorf("confluence: %s%s: %s", resp.Status, hint, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("confluence: decode response: %w", err)
+	}
+	return nil
+}
+
+var (
+	confluenceCodeRE  = regexp.MustCompile(`(?s)<ac:structured-macro\b[^>]*ac:name="(?:code|noformat)"[^>]*>(.*?)</ac:structured-macro>`)
+	confluenceLangRE  = regexp.MustCompile(`(?s)<ac:parameter\b[^>]*ac:name="language"[^>]*>(.*?)</ac:parameter>`)
+	confluenceBodyRE  = regexp.MustCompile(`(?s)<ac:plain-text-body>\s*<!\[CDATA\[(.*?)\]\]>\s*</ac:plain-text-body>`)
+	confluenceCDataRE = regexp.MustCompile(`(?s)<!\[CDATA\[(.*?)\]\]>`)
+	confluenceParamRE = regexp.MustCompile(`(?s)<ac:parameter\b[^>]*>.*?</ac:parameter>`)
+)
+
+// confluenceHTML turns Confluence storage format into plain HTML for
+// chunker.ParseHTML: code macros become fenced blocks and other CDATA is
+// escaped so its text survives tag stripping. Macro parameters are dropped.
+func confluenceHTML(storage string) string {
+	s := confluenceCodeRE.ReplaceAllStringFunc(storage, func(m string) string {
+		inner := confluenceCodeRE.FindStringSubmatch(m)[1]
+		lang := ""
+		if l := confluenceLangRE.FindStringSubmatch(inner); l != nil {
+			lang = strings.TrimSpace(l[1])
+		}
+		code := ""
+		if b := confluenceBodyRE.FindStringSubmatch(inner); b != nil {
+			code = b[1]
+		}
+		return "<pre>```" + lang + "\n" + html.EscapeString(strings.Trim(code, "\n")) + "\n```</pre>"
+	})
+	s = confluenceParamRE.ReplaceAllString(s, "")
+	return confluenceCDataRE.ReplaceAllStringFunc(s, func(m string) string {
+		return html.EscapeString(confluenceCDataRE.FindStringSubmatch(m)[1])
+	})
+}
@@ -0,0 +1,71 @@
+// Package connectors fetches documents from external knowledge sources,
+// such as Confluence spaces and chat exports, for indexing.
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// Ref identifies one document of a source and its revision.
+type Ref struct {
+	ID string `json:"id"`
+	// Version changes whenever the document does; unchanged documents are
+	// not fetched again
+	Version  string    `json:"version"`
+	Modified time.Time `json:"modified"`
+}
+
+// Document is a fetched document converted to text, markdown where the
+// source has structure.
+type Document struct {
+	Ref
+	Title string
+	// URL is the document's web page, or its URI; it keeps the file
+	// extension where there is one, which picks the chunking strategy
+	URL  string
+	Text string
+	// Meta is extra payload stored on the document's chunks
+	Meta map[string]any
+}
+
+// Connector lists and fetches the documents of one source.
+type Connector interface {
+	// List returns every document of the source.
+	List(ctx context.Context) ([]Ref, error)
+	// Incremental returns the documents created or changed since t. It may
+	// return more, but deletions are only seen by List.
+	Incremental(ctx context.Context, since time.Time) ([]Ref, error)
+	// Fetch downloads one document.
+	Fetch(ctx context.Context, ref Ref) (Document, error)
+}
+
+// New builds the connector described by conf. config supplies the indexing
+// rules, e.g. which file types are indexed.
+func New(conf cfg.ConnectorConfig, config *cfg.Config) (Connector, error) {
+	switch conf.Type {
+	case "confluence":
+		return newConfluence(conf), nil
+	case "s3":
+		return newS3(conf, config), nil
+	case "slack_export":
+		return newSlackExport(conf), nil
+	case "discord_export":
+		return newDiscordExport(conf), nil
+	default:
+		return nil, fmt.Errorf("unknown connector type %q", conf.Type)
+	}
+}
+
+// Find returns the configuration of the connector called name.
+func Find(conf *cfg.Config, name string) (cfg.ConnectorConfig, bool) {
+	for _, c := range conf.Connectors {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return cfg.ConnectorConfig{}, false
+}
@@ -0,0 +1,93 @@
+package connectors
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// newDiscordExport reads the JSON exports of DiscordChatExporter, one file
+// per channel or thread.
+func newDiscordExport(conf cfg.ConnectorConfig) *chatExport {
+	return &chatExport{conf: conf, scheme: "discord", load: func() ([]*chatChannel, error) { return loadDiscord(conf) }}
+}
+
+type discordExport struct {
+	Guild struct {
+		ID string `json:"id"`
+	} `json:"guild"`
+	Channel struct {
+		ID       string `json:"id"`
+		Type     string `json:"type"`
+		Category string `json:"category"`
+		Name     string `json:"name"`
+	} `json:"channel"`
+	Messages []struct {
+		ID        string    `json:"id"`
+		Type      string    `json:"type"`
+		Timestamp time.Time `json:"timestamp"`
+		Content   string    `json:"content"`
+		Author    struct {
+			Name     string `json:"name"`
+			Nickname string `json:"nickname"`
+		} `json:"author"`
+	} `json:"messages"`
+}
+
+func loadDiscord(conf cfg.ConnectorConfig) ([]*chatChannel, error) {
+	files := []string{conf.Path}
+	if info, err := os.Stat(conf.Path); err != nil {
+		return nil, fmt.Errorf("discord export: %w", err)
+	} else if info.IsDir() {
+		files, _ = filepath.Glob(filepath.Join(conf.Path, "*.json"))
+	}
+	var out []*chatChannel
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var e discordExport
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("discord export: %s: %w", filepath.Base(f), err)
+		}
+		if e.Channel.ID == "" {
+			continue
+		}
+		guild, id := e.Guild.ID, e.Channel.ID
+		ch := &chatChannel{ID: id, Name: e.Channel.Name, Link: func(m chatMessage) string {
+			if guild == "" {
+				return ""
+			}
+			return "https://discord.com/channels/" + guild + "/" + id + "/" + m.ID
+		}}
+		// A thread is exported on its own; its category is its channel
+		thread := ""
+		if strings.Contains(e.Channel.Type, "Thread") {
+			thread = id
+			if e.Channel.Category != "" {
+				ch.Name = e.Channel.Category
+			}
+		}
+		for _, m := range e.Messages {
+			if (m.Type != "Default" && m.Type != "Reply") || strings.TrimSpace(m.Content) == "" {
+				continue
+			}
+			ch.Messages = append(ch.Messages, chatMessage{
+				ID:     m.ID,
+				Time:   m.Timestamp,
+				Author: cmp.Or(m.Author.Nickname, m.Author.Name),
+				Text:   strings.TrimSpace(m.Content),
+				Thread: thread,
+			})
+		}
+		out = append(out, ch)
+	}
+	return out, nil
+}
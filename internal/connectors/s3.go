@@ -0,0 +1,323 @@
+package connectors
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// s3 reads the objects under a prefix of an S3 bucket, or of an
+// S3-compatible store such as MinIO. Objects are selected by extension like
+// files in rag_index and read one at a time, never written to disk.
+type s3 struct {
+	conf   cfg.ConnectorConfig
+	config *cfg.Config
+	bucket *S3Bucket
+}
+
+func newS3(conf cfg.ConnectorConfig, config *cfg.Config) *s3 {
+	timeout := 30 * time.Second
+	if conf.TimeoutSec > 0 {
+		timeout = time.Duration(conf.TimeoutSec) * time.Second
+	}
+	return &s3{conf: conf, config: config, bucket: NewS3Bucket(conf.S3Location, timeout)}
+}
+
+// S3Bucket is a minimal client for one bucket of S3 or an S3-compatible
+// store. Requests are signed with AWS Signature Version 4 when credentials
+// are set.
+type S3Bucket struct {
+	loc      cfg.S3Location
+	endpoint *url.URL // bucket root
+	region   string
+	client   *http.Client
+}
+
+// NewS3Bucket returns a client for loc whose requests time out after
+// timeout (0 = never).
+func NewS3Bucket(loc cfg.S3Location, timeout time.Duration) *S3Bucket {
+	region := loc.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	var endpoint *url.URL
+	if loc.Endpoint != "" {
+		endpoint, _ = url.Parse(strings.TrimRight(loc.Endpoint, "/") + "/" + loc.Bucket)
+	} else {
+		endpoint = &url.URL{Scheme: "https", Host: loc.Bucket + ".s3." + region + ".amazonaws.com"}
+	}
+	return &S3Bucket{loc: loc, endpoint: endpoint, region: region, client: &http.Client{Timeout: timeout}}
+}
+
+// URL returns the s3:// URL of key.
+func (b *S3Bucket) URL(key string) string { return "s3://" + b.loc.Bucket + "/" + key }
+
+// S3Object is one entry of a bucket listing.
+type S3Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+}
+
+// s3ListResult is one page of a ListObjectsV2 response.
+type s3ListResult struct {
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+	Contents              []S3Object `xml:"Contents"`
+}
+
+// List returns the objects whose keys start with prefix, following
+// continuation tokens. On error the objects listed so far are returned.
+func (b *S3Bucket) List(ctx context.Context, prefix string) ([]S3Object, error) {
+	var out []S3Object
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := b.do(ctx, http.MethodGet, "", q, nil, 0)
+		if err != nil {
+			return out, err
+		}
+		var page s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return out, fmt.Errorf("s3: decode listing: %w", err)
+		}
+		out = append(out, page.Contents...)
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return out, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Get returns the response for object key; the caller closes its body.
+func (b *S3Bucket) Get(ctx context.Context, key string) (*http.Response, error) {
+	return b.do(ctx, http.MethodGet, key, nil, nil, 0)
+}
+
+// Put stores size bytes from body as object key in a single request, so
+// objects are limited to the 5 GB S3 allows for one PUT.
+func (b *S3Bucket) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	resp, err := b.do(ctx, http.MethodPut, key, nil, body, size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *s3) List(ctx context.Context) ([]Ref, error) {
+	return c.list(ctx, time.Time{})
+}
+
+// Incremental lists the whole prefix, as S3 cannot filter by date, and
+// keeps the objects modified since t, allowing for some clock skew.
+func (c *s3) Incremental(ctx context.Context, since time.Time) ([]Ref, error) {
+	return c.list(ctx, since.Add(-5*time.Minute))
+}
+
+func (c *s3) list(ctx context.Context, since time.Time) ([]Ref, error) {
+	maxBytes := int64(c.config.Indexing.MaxFileKB) * 1024
+	objects, err := c.bucket.List(ctx, c.conf.Prefix)
+	var out []Ref
+	for _, o := range objects {
+		if strings.HasSuffix(o.Key, "/") || !c.wanted(o.Key) || (maxBytes > 0 && o.Size > maxBytes) {
+			continue
+		}
+		if !since.IsZero() && o.LastModified.Before(since) {
+			continue
+		}
+		out = append(out, Ref{ID: o.Key, Version: strings.Trim(o.ETag, `"`), Modified: o.LastModified})
+	}
+	return out, err
+}
+
+// wanted applies the rag_index file type rules to an object key.
+func (c *s3) wanted(key string) bool {
+	ext := strings.ToLower(path.Ext(key))
+	return c.config.IsDocumentationFile(ext) || (c.conf.IncludeCode && c.config.IsCodeFile(ext))
+}
+
+// Fetch reads one object. Its key and, in versioned buckets, its version ID
+// are returned as object_key and version_id payload.
+func (c *s3) Fetch(ctx context.Context, ref Ref) (Document, error) {
+	resp, err := c.bucket.Get(ctx, ref.ID)
+	if err != nil {
+		return Document{}, err
+	}
+	defer resp.Body.Close()
+	var body io.Reader = resp.Body
+	maxBytes := int64(c.config.Indexing.MaxFileKB) * 1024
+	if maxBytes > 0 {
+		body = io.LimitReader(resp.Body, maxBytes+1)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return Document{}, fmt.Errorf("s3: read %s: %w", ref.ID, err)
+	}
+	if maxBytes > 0 && int64(len(b)) > maxBytes {
+		return Document{}, fmt.Errorf("object is larger than max_file_kb")
+	}
+	if !utf8.Valid(b) {
+		return Document{}, fmt.Errorf("object is not UTF-8 text")
+	}
+	doc := Document{
+		Ref:  ref,
+		URL:  c.bucket.URL(ref.ID),
+		Text: string(b),
+		Meta: map[string]any{"bucket": c.conf.Bucket, "object_key": ref.ID},
+	}
+	if etag := strings.Trim(resp.Header.Get("ETag"), `"`); etag != "" {
+		doc.Version = etag
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		doc.Modified = t
+	}
+	if v := resp.Header.Get("X-Amz-Version-Id"); v != "" && v != "null" {
+		doc.Meta["version_id"] = v
+	}
+	return doc, nil
+}
+
+// do sends a signed request for key (the bucket itself when "") and
+// returns the response once it succeeded. A body is sent with its size as
+// Content-Length.
+func (b *S3Bucket) do(ctx context.Context, method, key string, q url.Values, body io.Reader, size int64) (*http.Response, error) {
+	u := *b.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + key
+	// The escaped path must match the canonical URI that is signed
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = s3Query(q)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if b.loc.AccessKeyID != "" {
+		b.sign(req, time.Now())
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var e struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if xml.Unmarshal(b, &e) == nil && e.Code != "" {
+			return nil, fmt.Errorf("s3: %s: %s: %s", resp.Status, e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("s3: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers for an unsigned payload.
+func (b *S3Bucket) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	if b.loc.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", b.loc.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); lk == "range" || strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonHeaders.String(),
+		signed,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := day + "/" + b.region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := []byte("AWS4" + b.loc.SecretAccessKey)
+	for _, part := range []string{day, b.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.loc.AccessKeyID, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Query encodes a query string canonically: sorted, with %20 for spaces.
+func s3Query(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters, and
+// slashes unless encodeSlash.
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~', ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
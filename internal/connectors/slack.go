@@ -0,0 +1,219 @@
+package connectors
+
+import (
+	"archive/zip"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// newSlackExport reads a Slack workspace export: channels.json (and
+// groups.json for private channels), users.json, and a directory per
+// channel holding a JSON file of messages per day.
+func newSlackExport(conf cfg.ConnectorConfig) *chatExport {
+	return &chatExport{conf: conf, scheme: "slack", load: func() ([]*chatChannel, error) { return loadSlack(conf) }}
+}
+
+type slackUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+	Profile  struct {
+		DisplayName string `json:"display_name"`
+		RealName    string `json:"real_name"`
+	} `json:"profile"`
+}
+
+func (u slackUser) display() string {
+	for _, n := range []string{u.Profile.DisplayName, u.Profile.RealName, u.RealName, u.Name} {
+		if n != "" {
+			return n
+		}
+	}
+	return u.ID
+}
+
+type slackMessage struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	Username    string `json:"username"` // bots
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+	UserProfile struct {
+		DisplayName string `json:"display_name"`
+		RealName    string `json:"real_name"`
+	} `json:"user_profile"`
+}
+
+// slackSkipped are the message subtypes that are not conversation.
+var slackSkipped = map[string]bool{
+	"channel_join": true, "channel_leave": true, "channel_topic": true, "channel_purpose": true, "channel_name": true,
+	"channel_archive": true, "channel_unarchive": true, "group_join": true, "group_leave": true, "pinned_item": true,
+	"bot_add": true, "bot_remove": true,
+}
+
+// slackMarkupRE matches Slack's <...> markup: mentions, channel links and
+// URLs.
+var slackMarkupRE = regexp.MustCompile(`<([^>]*)>`)
+
+func loadSlack(conf cfg.ConnectorConfig) ([]*chatChannel, error) {
+	var fsys fs.FS
+	if strings.EqualFold(path.Ext(conf.Path), ".zip") {
+		zr, err := zip.OpenReader(conf.Path)
+		if err != nil {
+			return nil, fmt.Errorf("slack export: %w", err)
+		}
+		// The archive is read in full before the sync writes anything
+		defer zr.Close()
+		fsys = zr
+	} else {
+		fsys = os.DirFS(conf.Path)
+	}
+	root, err := slackRoot(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []slackUser
+	if err := readJSON(fsys, path.Join(root, "users.json"), &users); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, u := range users {
+		names[u.ID] = u.display()
+	}
+	var channels []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	for _, f := range []string{"channels.json", "groups.json"} {
+		var list []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := readJSON(fsys, path.Join(root, f), &list); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		channels = append(channels, list...)
+	}
+	channelNames := map[string]string{}
+	for _, ch := range channels {
+		channelNames[ch.ID] = ch.Name
+	}
+
+	base := strings.TrimRight(conf.BaseURL, "/")
+	var out []*chatChannel
+	for _, c := range channels {
+		days, err := fs.Glob(fsys, path.Join(root, c.Name, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		ch := &chatChannel{ID: c.ID, Name: c.Name, Link: func(m chatMessage) string {
+			if base == "" {
+				return ""
+			}
+			return base + "/archives/" + c.ID + "/p" + strings.ReplaceAll(m.ID, ".", "")
+		}}
+		for _, day := range days {
+			var msgs []slackMessage
+			if err := readJSON(fsys, day, &msgs); err != nil {
+				return nil, err
+			}
+			for _, m := range msgs {
+				if m.Type != "message" || slackSkipped[m.Subtype] || strings.TrimSpace(m.Text) == "" {
+					continue
+				}
+				t, ok := slackTime(m.TS)
+				if !ok {
+					continue
+				}
+				ch.Messages = append(ch.Messages, chatMessage{
+					ID:     m.TS,
+					Time:   t,
+					Author: cmp.Or(names[m.User], m.UserProfile.DisplayName, m.UserProfile.RealName, m.Username, m.User),
+					Text:   slackText(m.Text, names, channelNames),
+					Thread: m.ThreadTS,
+				})
+			}
+		}
+		out = append(out, ch)
+	}
+	return out, nil
+}
+
+// slackRoot finds the directory of channels.json: the export's root, or
+// the one directory a zip file was made of.
+func slackRoot(fsys fs.FS) (string, error) {
+	if _, err := fs.Stat(fsys, "channels.json"); err == nil {
+		return ".", nil
+	}
+	matches, _ := fs.Glob(fsys, "*/channels.json")
+	if len(matches) == 1 {
+		return path.Dir(matches[0]), nil
+	}
+	return "", fmt.Errorf("slack export: channels.json not found")
+}
+
+func readJSON(fsys fs.FS, name string, v any) error {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("slack export: %s: %w", name, err)
+	}
+	return nil
+}
+
+// slackTime parses a message ts, seconds with a microsecond fraction.
+func slackTime(ts string) (time.Time, bool) {
+	sec, frac, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	us, _ := strconv.ParseInt((frac + "000000")[:6], 10, 64)
+	return time.Unix(s, us*1000), true
+}
+
+// slackText resolves the markup of a message: <@U123> mentions become
+// @name, <#C123|general> becomes #general and <https://x|label> becomes
+// "label (https://x)".
+func slackText(s string, users, channels map[string]string) string {
+	s = slackMarkupRE.ReplaceAllStringFunc(s, func(m string) string {
+		inner := m[1 : len(m)-1]
+		target, label, _ := strings.Cut(inner, "|")
+		switch {
+		case strings.HasPrefix(target, "@"):
+			if n := users[target[1:]]; n != "" {
+				return "@" + n
+			}
+			return "@" + strings.TrimPrefix(label, "@")
+		case strings.HasPrefix(target, "#"):
+			if n := channels[target[1:]]; n != "" {
+				return "#" + n
+			}
+			return "#" + label
+		case strings.HasPrefix(target, "!"):
+			// <!here>, <!channel>
+			return "@" + strings.TrimPrefix(cmp.Or(label, target[1:]), "@")
+		case label != "" && label != target:
+			return label + " (" + target + ")"
+		}
+		return target
+	})
+	return strings.TrimSpace(html.UnescapeString(s))
+}
@@ -0,0 +1,153 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+type ctxKey struct{}
+
+// accessInfo travels with a request so handlers can add to its log line.
+type accessInfo struct {
+	requestID string
+	// keyID identifies the API key the caller authenticated with
+	keyID string
+}
+
+// statusRecorder remembers the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter { return s.ResponseWriter }
+
+// withAccessLog assigns every request an X-Request-ID, taking the client's
+// when it is a plausible ID, echoes it in the response and writes one log
+// line per request in format ("logfmt", "json" or "off").
+func withAccessLog(format string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := &accessInfo{requestID: r.Header.Get(ragvec.RequestIDHeader), keyID: "-"}
+		if !validRequestID(info.requestID) {
+			info.requestID = newRequestID()
+		}
+		w.Header().Set(ragvec.RequestIDHeader, info.requestID)
+		rec := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), ctxKey{}, info)))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		remote := r.RemoteAddr
+		if remote == "" || remote == "@" {
+			remote = "-" // unix socket peers have no address
+		}
+		entry := struct {
+			RequestID  string `json:"request_id"`
+			Method     string `json:"method"`
+			Path       string `json:"path"`
+			Status     int    `json:"status"`
+			DurationMS int64  `json:"duration_ms"`
+			Bytes      int    `json:"bytes"`
+			Key        string `json:"key"`
+			Remote     string `json:"remote"`
+		}{info.requestID, r.Method, r.URL.Path, rec.status, time.Since(start).Milliseconds(), rec.bytes, info.keyID, remote}
+		switch format {
+		case "off":
+		case "json":
+			b, _ := json.Marshal(entry)
+			log.Printf("%s", b)
+		default:
+			log.Printf("http request_id=%s method=%s path=%q status=%d duration_ms=%d bytes=%d key=%s remote=%s",
+				entry.RequestID, entry.Method, entry.Path, entry.Status, entry.DurationMS, entry.Bytes, entry.Key, entry.Remote)
+		}
+	})
+}
+
+// validRequestID accepts IDs of up to 128 letters, digits and ._:- so a
+// client cannot inject text into log lines or upstream headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == ':', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestID returns the X-Request-ID assigned to r, or "".
+func requestID(r *http.Request) string {
+	if info, ok := r.Context().Value(ctxKey{}).(*accessInfo); ok {
+		return info.requestID
+	}
+	return ""
+}
+
+// setKeyID records which API key authenticated r: a short hash, never the
+// key itself.
+func setKeyID(r *http.Request, key string) {
+	if info, ok := r.Context().Value(ctxKey{}).(*accessInfo); ok {
+		sum := sha256.Sum256([]byte(key))
+		info.keyID = hex.EncodeToString(sum[:4])
+	}
+}
+
+// callerID names the caller of r for quotas: the API key it authenticated
+// with, else its address.
+func callerID(r *http.Request) string {
+	if info, ok := r.Context().Value(ctxKey{}).(*accessInfo); ok && info.keyID != "-" {
+		return "key:" + info.keyID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// forRequest scopes rag to r, so its vector store and embedding calls carry
+// the request's X-Request-ID. A nil rag stays nil.
+func forRequest(rag *ragvec.VecRAG, r *http.Request) *ragvec.VecRAG {
+	return rag.WithRequestID(requestID(r))
+}
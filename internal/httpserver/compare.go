@@ -0,0 +1,120 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/redact"
+)
+
+// compareTargets opens the configured comparison indexes on first use, so a
+// target whose collection does not exist yet can be retried later.
+type compareTargets struct {
+	conf *cfg.Config
+	mu   sync.Mutex
+	open map[string]*ragvec.VecRAG
+}
+
+func (t *compareTargets) get(name string) (*ragvec.VecRAG, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rag, ok := t.open[name]; ok {
+		return rag, nil
+	}
+	for _, target := range t.conf.HTTP.Compare {
+		if target.Name != name {
+			continue
+		}
+		rag, err := ragvec.OpenVecRAG(t.conf.ForCompareTarget(target))
+		if err != nil {
+			return nil, err
+		}
+		t.open[name] = rag
+		return rag, nil
+	}
+	return nil, fmt.Errorf("unknown compare target %q", name)
+}
+
+// registerCompare mounts POST /admin/compare, which runs one query against two
+// indexes ("primary" or a name from http.compare) and reports both result
+// lists with their overlap.
+func registerCompare(mux *http.ServeMux, conf *cfg.Config, getRAG func() *ragvec.VecRAG, requireAuth func(http.HandlerFunc) http.HandlerFunc) {
+	targets := &compareTargets{conf: conf, open: map[string]*ragvec.VecRAG{}}
+	resolve := func(name string) (*ragvec.VecRAG, error) {
+		if name == "primary" {
+			if rag := getRAG(); rag != nil {
+				return rag, nil
+			}
+			return nil, fmt.Errorf("RAG not initialized")
+		}
+		return targets.get(name)
+	}
+
+	// POST /admin/compare {query, k, project, project_prefix, a, b}
+	mux.HandleFunc("/admin/compare", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed", Data: apierr.Invalid("Use POST")})
+			return
+		}
+		var body struct {
+			Query         string `json:"query"`
+			K             int    `json:"k"`
+			Project       string `json:"project"`
+			ProjectPrefix string `json:"project_prefix"`
+			A             string `json:"a"`
+			B             string `json:"b"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		if strings.TrimSpace(body.Query) == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "query required", Data: apierr.Invalid("")})
+			return
+		}
+		if body.K <= 0 || body.K > 20 {
+			body.K = 5
+		}
+		if body.A == "" {
+			body.A = "primary"
+		}
+		if body.B == "" {
+			body.B = conf.HTTP.Compare[0].Name
+		}
+		sides := make([]map[string]any, 2)
+		hits := make([][]ragvec.Hit, 2)
+		for i, name := range []string{body.A, body.B} {
+			rag, err := resolve(name)
+			if err != nil {
+				writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "compare target unavailable: " + name, Data: apierr.Of(err, redact.Error(err, conf.Logging.RedactErrors))})
+				return
+			}
+			rag = forRequest(rag, r)
+			start := time.Now()
+			hits[i], err = rag.SearchWithFilter(body.Query, body.K, body.Project, body.ProjectPrefix)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "search error on " + name, Data: apierr.Of(err, redact.Error(err, conf.Logging.RedactErrors))})
+				return
+			}
+			side := rag.Describe()
+			side["name"] = name
+			side["latency_ms"] = time.Since(start).Milliseconds()
+			side["chunks"] = hits[i]
+			sides[i] = side
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"query":   body.Query,
+			"k":       body.K,
+			"a":       sides[0],
+			"b":       sides[1],
+			"overlap": ragvec.CompareResults(hits[0], hits[1]),
+		})
+	}))
+}
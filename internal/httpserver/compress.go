@@ -0,0 +1,148 @@
+package httpserver
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gzipMinBytes is the smallest response worth compressing.
+const gzipMinBytes = 1024
+
+var gzipPool = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// gzipWriter buffers the start of a response until it is known to be large
+// enough to compress, then streams the rest through gzip.
+type gzipWriter struct {
+	http.ResponseWriter
+	status int
+	buf    []byte
+	gz     *gzip.Writer
+	// direct is set once headers went out; further writes go to gz if set
+	direct bool
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	if g.status == 0 {
+		g.status = code
+	}
+}
+
+func (g *gzipWriter) Write(b []byte) (int, error) {
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	if g.direct {
+		if g.gz != nil {
+			return g.gz.Write(b)
+		}
+		return g.ResponseWriter.Write(b)
+	}
+	if !compressible(g.Header()) {
+		g.start(false)
+		return g.ResponseWriter.Write(b)
+	}
+	g.buf = append(g.buf, b...)
+	if len(g.buf) >= gzipMinBytes {
+		g.start(true)
+		buf := g.buf
+		g.buf = nil
+		if _, err := g.gz.Write(buf); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start sends the headers, switching to gzip when compress is set.
+func (g *gzipWriter) start(compress bool) {
+	g.direct = true
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	if compress {
+		h := g.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.gz = gzipPool.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+}
+
+// finish flushes a response too small to compress, or the gzip trailer.
+func (g *gzipWriter) finish() {
+	if !g.direct {
+		g.start(false)
+		if len(g.buf) > 0 {
+			_, _ = g.ResponseWriter.Write(g.buf)
+		}
+		return
+	}
+	if g.gz != nil {
+		_ = g.gz.Close()
+		gzipPool.Put(g.gz)
+		g.gz = nil
+	}
+}
+
+// Flush sends what has been written so far, e.g. for streamed profiles.
+func (g *gzipWriter) Flush() {
+	if !g.direct {
+		g.start(compressible(g.Header()) && len(g.buf) > 0)
+		buf := g.buf
+		g.buf = nil
+		_, _ = g.Write(buf)
+	}
+	if g.gz != nil {
+		_ = g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// compressible reports whether a response with header h benefits from gzip:
+// JSON or text that is not already encoded.
+func compressible(h http.Header) bool {
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	return strings.HasPrefix(ct, "application/json") || strings.HasPrefix(ct, "text/")
+}
+
+// acceptsGzip reports whether the client listed gzip in Accept-Encoding
+// without q=0.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.TrimSpace(name) != "*" {
+			continue
+		}
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+		v, err := strconv.ParseFloat(q, 64)
+		return err == nil && v > 0
+	}
+	return false
+}
+
+// withGzip compresses JSON and text responses of at least gzipMinBytes for
+// clients that accept gzip.
+func withGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		g := &gzipWriter{ResponseWriter: w}
+		defer g.finish()
+		next.ServeHTTP(g, r)
+	})
+}
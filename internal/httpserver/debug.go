@@ -0,0 +1,58 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+var startedAt = time.Now()
+
+// registerDebug mounts /debug/pprof/* and /debug/runtime behind auth.
+func registerDebug(mux *http.ServeMux, requireAuth func(http.HandlerFunc) http.HandlerFunc) {
+	mux.HandleFunc("/debug/pprof/", requireAuth(pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", requireAuth(pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", requireAuth(pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", requireAuth(pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", requireAuth(pprof.Trace))
+
+	// GET /debug/runtime: goroutines, heap and GC stats
+	mux.HandleFunc("/debug/runtime", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		var lastGC any
+		if m.LastGC > 0 {
+			lastGC = time.Unix(0, int64(m.LastGC)).UTC()
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"go_version": runtime.Version(),
+			"uptime_sec": int64(time.Since(startedAt).Seconds()),
+			"goroutines": runtime.NumGoroutine(),
+			"num_cpu":    runtime.NumCPU(),
+			"gomaxprocs": runtime.GOMAXPROCS(0),
+			"heap": map[string]any{
+				"alloc_bytes":    m.HeapAlloc,
+				"sys_bytes":      m.HeapSys,
+				"inuse_bytes":    m.HeapInuse,
+				"released_bytes": m.HeapReleased,
+				"objects":        m.HeapObjects,
+			},
+			"memory": map[string]any{
+				"total_alloc_bytes": m.TotalAlloc,
+				"sys_bytes":         m.Sys,
+				"mallocs":           m.Mallocs,
+				"frees":             m.Frees,
+			},
+			"gc": map[string]any{
+				"num_gc":          m.NumGC,
+				"pause_total_ms":  float64(m.PauseTotalNs) / 1e6,
+				"last_pause_ms":   float64(m.PauseNs[(m.NumGC+255)%256]) / 1e6,
+				"last_gc":         lastGC,
+				"next_gc_bytes":   m.NextGC,
+				"cpu_fraction":    m.GCCPUFraction,
+				"forced_gc_count": m.NumForcedGC,
+			},
+		})
+	}))
+}
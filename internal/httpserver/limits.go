@@ -0,0 +1,39 @@
+package httpserver
+
+import "encoding/json"
+
+// fitList keeps a list response within maxBytes of JSON. build renders the
+// response with the first n of total items; when they do not all fit, as
+// many as fit are kept (at least one, so paging always advances) and the
+// response is marked truncated with the offset to continue from.
+func fitList(maxBytes, offset, total int, build func(n int) map[string]any) map[string]any {
+	resp := build(total)
+	if maxBytes <= 0 || total <= 1 || encodedSize(resp) <= maxBytes {
+		return resp
+	}
+	withHint := func(n int) map[string]any {
+		resp := build(n)
+		resp["truncated"] = true
+		resp["next_offset"] = offset + n
+		resp["max_response_kb"] = maxBytes / 1024
+		return resp
+	}
+	lo, hi := 1, total-1 // largest n in [lo, hi] that fits
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if encodedSize(withHint(mid)) <= maxBytes {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return withHint(lo)
+}
+
+func encodedSize(v any) int {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(b) + 1 // writeJSON's trailing newline
+}
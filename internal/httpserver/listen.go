@@ -0,0 +1,160 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation (SD_LISTEN_FDS_START).
+const listenFDsStart = 3
+
+// Listen opens a listener the way -http does; -mcp-listen uses it for MCP
+// sessions.
+func Listen(addr string, conf cfg.HTTPConfig) (net.Listener, error) {
+	return listen(addr, conf)
+}
+
+// listen opens the listener for an -http address:
+//
+//   - host:port or :port listens on TCP
+//   - unix:///path/to.sock listens on a unix domain socket with the
+//     configured socket_mode and socket_group
+//   - fd:// uses the first socket passed by systemd socket activation,
+//     fd://name the one whose FileDescriptorName= is name, and fd://N the
+//     N-th one (0-based)
+func listen(addr string, conf cfg.HTTPConfig) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		return listenUnix(strings.TrimPrefix(addr, "unix://"), conf)
+	case strings.HasPrefix(addr, "fd://"):
+		return listenInherited(strings.TrimPrefix(addr, "fd://"))
+	}
+	return net.Listen("tcp", addr)
+}
+
+// listenUnix creates a unix socket at path. A stale socket left by a
+// previous run is removed; one that still accepts connections is not.
+func listenUnix(path string, conf cfg.HTTPConfig) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("unix socket path is empty")
+	}
+	if st, err := os.Lstat(path); err == nil {
+		if st.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Until the chmod below the socket has the umask's permissions, which
+	// normally deny other users the write access needed to connect
+	mode, _ := conf.SocketFileMode()
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("chmod socket: %w", err)
+	}
+	if conf.SocketGroup != "" {
+		gid, err := lookupGroup(conf.SocketGroup)
+		if err == nil {
+			err = os.Chown(path, -1, gid)
+		}
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("set socket group %s: %w", conf.SocketGroup, err)
+		}
+	}
+	return ln, nil
+}
+
+// lookupGroup resolves a group name or numeric ID.
+func lookupGroup(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// The socket activation variables are read once and then unset, so child
+// processes do not take the sockets for theirs; the API, admin and MCP
+// listeners may all pick from them.
+var (
+	systemdOnce  sync.Once
+	systemdPID   bool
+	systemdFDs   int
+	systemdNames []string
+)
+
+func readSystemdEnv() {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	systemdPID = err == nil && pid == os.Getpid()
+	systemdFDs, _ = strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	systemdNames = strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for _, k := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		os.Unsetenv(k)
+	}
+}
+
+// listenInherited picks a socket passed by systemd (LISTEN_PID, LISTEN_FDS,
+// LISTEN_FDNAMES). The variables are unset afterwards so processes started
+// by the service, e.g. git, do not see them.
+func listenInherited(which string) (net.Listener, error) {
+	systemdOnce.Do(readSystemdEnv)
+	if !systemdPID {
+		return nil, errors.New("no sockets passed by systemd (LISTEN_PID is not this process); use a .socket unit with this service")
+	}
+	n, names := systemdFDs, systemdNames
+	if n < 1 {
+		return nil, errors.New("no sockets passed by systemd (LISTEN_FDS is empty)")
+	}
+
+	idx := -1
+	switch i, err := strconv.Atoi(which); {
+	case which == "":
+		idx = 0
+	case err == nil:
+		idx = i
+	default:
+		for i, name := range names {
+			if name == which {
+				idx = i
+				break
+			}
+		}
+	}
+	if idx < 0 || idx >= n {
+		return nil, fmt.Errorf("systemd passed %d sockets (%s), none matches %q", n, strings.Join(names, ", "), which)
+	}
+	fd := uintptr(listenFDsStart + idx)
+	f := os.NewFile(fd, "listen-fd-"+strconv.Itoa(idx))
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		if errors.Is(err, syscall.ENOTSOCK) {
+			return nil, fmt.Errorf("inherited fd %d is not a socket", fd)
+		}
+		return nil, fmt.Errorf("inherited fd %d: %w", fd, err)
+	}
+	return ln, nil
+}
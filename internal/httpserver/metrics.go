@@ -0,0 +1,64 @@
+package httpserver
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/tools"
+)
+
+// registerMetrics mounts GET /metrics: per-tool call counters in the
+// Prometheus text format.
+func registerMetrics(mux *http.ServeMux, stats *tools.CallStats, requireAuth func(http.HandlerFunc) http.HandlerFunc) {
+	mux.HandleFunc("/metrics", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed", Data: apierr.Invalid("")})
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		writeMetrics(bw, stats)
+		_ = bw.Flush()
+	}))
+}
+
+func writeMetrics(w *bufio.Writer, stats *tools.CallStats) {
+	snap := stats.Snapshot()
+	fmt.Fprintf(w, "# HELP mcp_uptime_seconds Seconds since the server started counting tool calls.\n")
+	fmt.Fprintf(w, "# TYPE mcp_uptime_seconds gauge\n")
+	fmt.Fprintf(w, "mcp_uptime_seconds %s\n", promFloat(time.Since(stats.Since()).Seconds()))
+
+	fmt.Fprintf(w, "# HELP mcp_tool_calls_total Tool invocations since startup.\n")
+	fmt.Fprintf(w, "# TYPE mcp_tool_calls_total counter\n")
+	for _, t := range snap {
+		fmt.Fprintf(w, "mcp_tool_calls_total{tool=%q} %d\n", t.Name, t.Calls)
+	}
+	fmt.Fprintf(w, "# HELP mcp_tool_errors_total Tool invocations that failed, including results flagged isError.\n")
+	fmt.Fprintf(w, "# TYPE mcp_tool_errors_total counter\n")
+	for _, t := range snap {
+		fmt.Fprintf(w, "mcp_tool_errors_total{tool=%q} %d\n", t.Name, t.Errors)
+	}
+	fmt.Fprintf(w, "# HELP mcp_tool_duration_seconds Tool call latency; quantiles cover recent calls.\n")
+	fmt.Fprintf(w, "# TYPE mcp_tool_duration_seconds summary\n")
+	for _, t := range snap {
+		for _, q := range []struct {
+			label string
+			ms    float64
+		}{{"0.5", t.P50MS}, {"0.9", t.P90MS}, {"0.99", t.P99MS}} {
+			fmt.Fprintf(w, "mcp_tool_duration_seconds{tool=%q,quantile=%q} %s\n", t.Name, q.label, promFloat(q.ms/1000))
+		}
+		fmt.Fprintf(w, "mcp_tool_duration_seconds_sum{tool=%q} %s\n", t.Name, promFloat(t.Total.Seconds()))
+		fmt.Fprintf(w, "mcp_tool_duration_seconds_count{tool=%q} %d\n", t.Name, t.Calls)
+	}
+}
+
+// promFloat formats v with microsecond resolution, dropping trailing zeros.
+func promFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 6, 64)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
@@ -0,0 +1,59 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/redact"
+)
+
+// registerPayload mounts POST /admin/payload, which merges fields into the
+// payload of every point matching a selector, e.g. tagging everything under
+// a path prefix with a team, without re-indexing.
+func registerPayload(mux *http.ServeMux, conf *cfg.Config, getRAG func() *ragvec.VecRAG, requireAuth func(http.HandlerFunc) http.HandlerFunc) {
+	// POST /admin/payload {match, path_prefix, set, all, dry_run}
+	mux.HandleFunc("/admin/payload", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed", Data: apierr.Invalid("Use POST")})
+			return
+		}
+		rag := forRequest(getRAG(), r)
+		if rag == nil {
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Data: apierr.Unavailable("Start Qdrant or disable -no-qdrant")})
+			return
+		}
+		var body struct {
+			ragvec.PayloadSelector
+			Set    map[string]any `json:"set"`
+			All    bool           `json:"all"`
+			DryRun bool           `json:"dry_run"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		if body.PayloadSelector.Empty() && !body.All {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid params", Data: apierr.Invalid("Provide match and/or path_prefix, or all=true to patch every point")})
+			return
+		}
+		matched, err := rag.PatchPayload(body.PayloadSelector, body.Set, body.DryRun)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "payload patch error", Data: apierr.Of(err, redact.Error(err, conf.Logging.RedactErrors))})
+			return
+		}
+		if !body.DryRun {
+			log.Printf("Payload patch applied to %d points (match=%v, path_prefix=%q)", matched, body.Match, body.PathPrefix)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"matched":     matched,
+			"dry_run":     body.DryRun,
+			"match":       body.Match,
+			"path_prefix": body.PathPrefix,
+			"set":         body.Set,
+		})
+	}))
+}
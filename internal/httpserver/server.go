@@ -1,51 +1,134 @@
 package httpserver
 
 import (
+	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
 	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/pathglob"
+	"github.com/Rhyanz46/mcp-service/internal/querylog"
+	"github.com/Rhyanz46/mcp-service/internal/quota"
 	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/redact"
+	"github.com/Rhyanz46/mcp-service/internal/tools"
+	"github.com/Rhyanz46/mcp-service/internal/webhook"
 )
 
+// maxSearchOffset bounds how deep /rag/search pages, since every page
+// searches offset+k hits.
+const maxSearchOffset = 100
+
+// errorResponse is the body of failed requests: the error, and the
+// code, category, component, retryable flag, hint and details that MCP
+// clients get as error data.
 type errorResponse struct {
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
+	Error string `json:"error"`
+	apierr.Data
+	// Batches tells what a failed index run wrote before it stopped
+	Batches *ragvec.BatchReport `json:"batches,omitempty"`
+	// Quota tells when a caller whose quota is used up may search again
+	Quota *quota.Report `json:"quota,omitempty"`
 }
 
-// Start launches a simple HTTP server exposing similar functionality as MCP tools
-func Start(addr string, conf *cfg.Config, rag *ragvec.VecRAG) {
-	mux := http.NewServeMux()
-	apiKey := strings.TrimSpace(conf.HTTP.APIKey)
-	requireAuth := func(h http.HandlerFunc) http.HandlerFunc {
+// authWith guards handlers with apiKey, sent as a bearer token or in
+// X-API-Key; an empty key leaves them open.
+func authWith(apiKey string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
 		if apiKey == "" {
 			return h
 		}
 		return func(w http.ResponseWriter, r *http.Request) {
-			key := r.Header.Get("Authorization")
-			if strings.HasPrefix(strings.ToLower(key), "bearer ") {
-				key = strings.TrimSpace(key[7:])
-			} else {
-				key = r.Header.Get("X-API-Key")
-			}
+			key := requestKey(r)
 			if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				_ = json.NewEncoder(w).Encode(errorResponse{Error: "unauthorized", Details: "Provide Authorization: Bearer <token> or X-API-Key header"})
+				unauthorized(w)
+				return
+			}
+			setKeyID(r, key)
+			h(w, r)
+		}
+	}
+}
+
+// authTenants is authWith(apiKey) that also admits the api_key of an
+// access tenant, whose name the handler finds with tenantOf.
+func authTenants(apiKey string, access cfg.AccessConfig) func(http.HandlerFunc) http.HandlerFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := requestKey(r)
+			if t, ok := access.TenantByKey(key); ok {
+				setKeyID(r, key)
+				h(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, t.Name)))
+				return
+			}
+			if apiKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+				unauthorized(w)
 				return
 			}
+			if apiKey != "" {
+				setKeyID(r, key)
+			}
 			h(w, r)
 		}
 	}
+}
+
+type tenantKey struct{}
+
+// tenantOf returns the tenant r was authorized as, "" for other callers.
+func tenantOf(r *http.Request) string {
+	name, _ := r.Context().Value(tenantKey{}).(string)
+	return name
+}
+
+// requestKey returns the bearer token or X-API-Key of r.
+func requestKey(r *http.Request) string {
+	key := r.Header.Get("Authorization")
+	if strings.HasPrefix(strings.ToLower(key), "bearer ") {
+		return strings.TrimSpace(key[7:])
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: "unauthorized", Data: apierr.New(apierr.Auth, "request", "Provide Authorization: Bearer <token> or X-API-Key header")})
+}
+
+// Start launches a simple HTTP server exposing similar functionality as MCP tools.
+// getRAG returns the current RAG system, or nil while running degraded.
+// Index and delete events are reported to hooks, searches are logged to
+// queries, and /metrics exports stats.
+// With adminAddr the admin endpoints (delete, undelete, metrics, debug,
+// compare, payload) move to a second listener there, leaving status,
+// search, index and projects on addr. Only opening the listeners can fail;
+// the servers then run in the background.
+func Start(addr, adminAddr string, conf *cfg.Config, getRAG func() *ragvec.VecRAG, hooks *webhook.Notifier, queries *querylog.Log, stats *tools.CallStats) error {
+	mux := http.NewServeMux()
+	requireAuth := authWith(strings.TrimSpace(conf.HTTP.APIKey))
+	admin := mux
+	if adminAddr != "" {
+		admin = http.NewServeMux()
+	}
+	requireAdmin := authWith(conf.HTTP.AdminKey())
+	// Tenants may search, seeing only the documents of their labels
+	requireSearch := authTenants(strings.TrimSpace(conf.HTTP.APIKey), conf.Access)
+	// Each caller's search results are metered against search.quota
+	meter := quota.New()
 
 	// health/status (fast by default)
 	mux.HandleFunc("/status", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		rag := forRequest(getRAG(), r)
 		fastOnly := true
 		if v := r.URL.Query().Get("fast_only"); v != "" {
 			if v == "0" || strings.EqualFold(v, "false") {
@@ -53,7 +136,7 @@ func Start(addr string, conf *cfg.Config, rag *ragvec.VecRAG) {
 			}
 		}
 		start := time.Now()
-		q := ragvec.NewQdrantWithConfig(&conf.Qdrant, 1)
+		q := ragvec.StoreWithRequestID(ragvec.NewStore(conf, 1), requestID(r))
 		healthErr := q.HealthCheck()
 		var chunks *int
 		if healthErr == nil {
@@ -64,6 +147,19 @@ func Start(addr string, conf *cfg.Config, rag *ragvec.VecRAG) {
 		var projectsCount *int
 		var note string
 		if healthErr == nil && !fastOnly {
+			var list []map[string]any
+			var err error
+			if rag != nil {
+				list, err = rag.ListProjects()
+			} else {
+				list, err = ragvec.FacetProjects(q)
+			}
+			if err == nil {
+				v := len(list)
+				projectsCount = &v
+			}
+		}
+		if healthErr == nil && !fastOnly && projectsCount == nil {
 			seen := map[string]struct{}{}
 			var offset any
 			for {
@@ -73,9 +169,10 @@ func Start(addr string, conf *cfg.Config, rag *ragvec.VecRAG) {
 					break
 				}
 				for _, pt := range pts {
-					if pth, ok := pt.Payload["path"].(string); ok {
-						proj := projectFromPath(pth)
+					if proj, ok := pt.Payload["project"].(string); ok && proj != "" {
 						seen[proj] = struct{}{}
+					} else if pth, ok := pt.Payload["path"].(string); ok {
+						seen[projectFromPath(pth)] = struct{}{}
 					}
 				}
 				if next == nil {
@@ -94,12 +191,20 @@ func Start(addr string, conf *cfg.Config, rag *ragvec.VecRAG) {
 		} else if fastOnly {
 			note = "fast_only=true"
 		}
+		embedding := map[string]any{"active": nil}
+		if rag != nil {
+			embedding = rag.EmbeddingStatus()
+		}
 		status := map[string]any{
-			"provider": conf.Embedding.Provider,
+			"provider":  conf.Embedding.Provider,
+			"embedding": embedding,
 			"qdrant": map[string]any{
-				"url":        conf.Qdrant.URL,
-				"collection": conf.Qdrant.Collection,
-				"health":     ifThenElse(healthErr == nil, "ok", safeErr(healthErr)),
+				"backend":    conf.Store.Backend,
+				"url":        qdrantURL(conf),
+				"collection": conf.CollectionName(),
+				"health":     ifThenElse(healthErr == nil, "ok", redact.Error(healthErr, conf.Logging.RedactErrors)),
+				"circuit":    circuitState(q),
+				"encrypted":  conf.Store.Encryption.Key != "",
 			},
 			"counts": map[string]any{
 				"chunks":   chunks,
@@ -111,6 +216,7 @@ func Start(addr string, conf *cfg.Config, rag *ragvec.VecRAG) {
 				"batch_size":    conf.Indexing.BatchSize,
 				"max_file_kb":   conf.Indexing.MaxFileKB,
 				"exclude_dirs":  conf.Indexing.ExcludeDirs,
+				"exclude":       conf.Indexing.Exclude,
 			},
 			"degraded_mode": rag == nil,
 			"fast_only":     fastOnly,
@@ -120,111 +226,399 @@ func Start(addr string, conf *cfg.Config, rag *ragvec.VecRAG) {
 		writeJSON(w, http.StatusOK, status)
 	}))
 
-	// POST /rag/index {dir, include_code}
+	// POST /rag/index {dir, include_code, tags, ttl, prune_missing, include, exclude}
 	mux.HandleFunc("/rag/index", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		rag := forRequest(getRAG(), r)
 		if rag == nil {
-			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Details: "Start Qdrant or disable -no-qdrant"})
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Data: apierr.Unavailable("Start Qdrant or disable -no-qdrant")})
 			return
 		}
 		var body struct {
-			Dir         string `json:"dir"`
-			IncludeCode bool   `json:"include_code"`
+			Dir         string   `json:"dir"`
+			IncludeCode bool     `json:"include_code"`
+			Tags        []string `json:"tags"`
+			ACL         []string `json:"acl"`
+			TTL         string   `json:"ttl"`
+			// PruneMissing defaults to indexing.prune_missing
+			PruneMissing *bool `json:"prune_missing"`
+			// Include limits the run to matching files; Exclude adds
+			// pathglob patterns to indexing.exclude
+			Include []string `json:"include"`
+			Exclude []string `json:"exclude"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json", Details: err.Error()})
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json", Data: apierr.Invalid(err.Error())})
 			return
 		}
 		if strings.TrimSpace(body.Dir) == "" {
 			body.Dir = "./docs"
 		}
-		n, err := rag.IngestDocs(body.Dir, body.IncludeCode)
+		prune := conf.Indexing.PruneMissing
+		if body.PruneMissing != nil {
+			prune = *body.PruneMissing
+		}
+		tags := ragvec.NormalizeTags(body.Tags)
+		ttl, err := ragvec.ParseTTL(body.TTL)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "index error", Details: err.Error()})
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid ttl", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		acl, err := ragvec.NormalizeACL(body.ACL)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid acl", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		if _, err := pathglob.Compile(body.Include); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid include", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		if _, err := pathglob.Compile(body.Exclude); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid exclude", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		start := time.Now()
+		var pruned ragvec.PruneResult
+		if prune {
+			pruned, err = rag.PruneMissing(body.Dir)
+			errText := redact.Error(err, conf.Logging.RedactErrors)
+			if pruned.FileCount > 0 || err != nil {
+				hooks.Notify(webhook.Event{Event: "delete", Source: "http", Count: pruned.Chunks,
+					Details: map[string]any{"directory": body.Dir, "missing_files": pruned.FileCount}}, start, errText)
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "prune error", Data: apierr.Of(err, errText)})
+				return
+			}
+		}
+		var warnings []string
+		report := &ragvec.IngestReport{}
+		n, err := rag.IngestDocsWith(body.Dir, body.IncludeCode, ragvec.IngestOptions{Tags: tags, ACL: acl, TTL: ttl, Include: body.Include, Exclude: body.Exclude,
+			Report: report, OnWarning: func(msg string) { warnings = append(warnings, msg) }})
+		errText := redact.Error(err, conf.Logging.RedactErrors)
+		hooks.Notify(webhook.Event{Event: "index", Source: "http", Count: n,
+			Details: map[string]any{"directory": body.Dir, "include_code": body.IncludeCode, "tags": tags}}, start, errText)
+		var limitErr *ragvec.LimitError
+		if errors.As(err, &limitErr) {
+			writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Error: "index limit exceeded", Data: apierr.New(apierr.Limit, "indexing", fmt.Sprintf("%s (%d chunks were indexed before stopping)", errText, n))})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "index error",
+				Data: apierr.Of(err, fmt.Sprintf("%s (%d chunks were indexed before stopping)", errText, n)), Batches: &report.Batches})
 			return
 		}
 		resp := map[string]any{
 			"indexed":      n,
+			"batches":      report.Batches,
 			"directory":    body.Dir,
 			"include_code": body.IncludeCode,
+			"tags":         tags,
 			"status":       "success",
 		}
+		if ttl > 0 {
+			resp["ttl"] = ttl.String()
+		}
+		if len(body.Include) > 0 {
+			resp["include"] = body.Include
+		}
+		if len(warnings) > 0 {
+			resp["warnings"] = warnings
+		}
+		if !report.Empty() {
+			resp["content_checks"] = report
+		}
+		if report.ReusedFiles > 0 {
+			resp["reused_files"] = report.ReusedFiles
+		}
+		if len(body.Exclude) > 0 {
+			resp["exclude"] = body.Exclude
+		}
+		if prune {
+			resp["pruned"] = pruned
+		}
 		writeJSON(w, http.StatusOK, resp)
 	}))
 
-    // POST /rag/search {query, k, project, project_prefix}
-    mux.HandleFunc("/rag/search", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+	// POST /rag/search {query, k, offset, project, project_prefix}
+	mux.HandleFunc("/rag/search", requireSearch(func(w http.ResponseWriter, r *http.Request) {
+		rag := forRequest(getRAG(), r)
 		if rag == nil {
-			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Details: "Start Qdrant or disable -no-qdrant"})
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Data: apierr.Unavailable("Start Qdrant or disable -no-qdrant")})
 			return
 		}
 		var body struct {
-			Query         string `json:"query"`
-			K             int    `json:"k"`
-			Project       string `json:"project"`
-			ProjectPrefix string `json:"project_prefix"`
+			Query         string   `json:"query"`
+			K             int      `json:"k"`
+			Offset        int      `json:"offset"`
+			Project       string   `json:"project"`
+			ProjectPrefix string   `json:"project_prefix"`
+			TagsAny       []string `json:"tags_any"`
+			TagsAll       []string `json:"tags_all"`
+			Version       string   `json:"version"`
+			ModifiedAfter string   `json:"modified_after"`
+			IndexedBefore string   `json:"indexed_before"`
+			Params        struct {
+				HNSWEf      *int    `json:"hnsw_ef"`
+				Exact       *bool   `json:"exact"`
+				Consistency *string `json:"consistency"`
+				TimeoutSec  *int    `json:"timeout_sec"`
+			} `json:"params"`
+			ReadThrough *bool `json:"read_through"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json", Details: err.Error()})
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json", Data: apierr.Invalid(err.Error())})
 			return
 		}
 		if strings.TrimSpace(body.Query) == "" {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "query required"})
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "query required", Data: apierr.Invalid("")})
 			return
 		}
 		if body.K <= 0 || body.K > 20 {
 			body.K = 5
 		}
-		hits, err := rag.SearchWithFilter(body.Query, body.K, body.Project, body.ProjectPrefix)
+		if body.Offset < 0 || body.Offset > maxSearchOffset {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid offset", Data: apierr.Invalid(fmt.Sprintf("offset must be between 0 and %d", maxSearchOffset))})
+			return
+		}
+		params := rag.DefaultSearchParams()
+		if body.Params.HNSWEf != nil && *body.Params.HNSWEf >= 0 {
+			params.HNSWEf = *body.Params.HNSWEf
+		}
+		if body.Params.Exact != nil {
+			params.Exact = *body.Params.Exact
+		}
+		if c := body.Params.Consistency; c != nil {
+			if !cfg.ValidReadConsistency(*c) {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid consistency", Data: apierr.Invalid("consistency must be majority, quorum, all or a positive number of replicas")})
+				return
+			}
+			params.Consistency = *c
+		}
+		if t := body.Params.TimeoutSec; t != nil && *t >= 0 {
+			params.TimeoutSec = *t
+		}
+		filter := ragvec.SearchFilter{Project: body.Project, ProjectPrefix: body.ProjectPrefix, TagsAny: body.TagsAny, TagsAll: body.TagsAll, Version: body.Version}
+		tenant := tenantOf(r)
+		var err error
+		// Tenants only find the documents of their labels
+		if filter.ACL, err = conf.Access.TenantLabels(tenant); err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "search error", Data: apierr.New(apierr.Config, "config", err.Error())})
+			return
+		}
+		if filter.ModifiedAfter, err = ragvec.ParseTime(body.ModifiedAfter); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid modified_after", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		if filter.IndexedBefore, err = ragvec.ParseTime(body.IndexedBefore); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid indexed_before", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		hits, err := rag.SearchFiltered(body.Query, body.Offset+body.K, filter, params)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "search error", Details: err.Error()})
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "search error", Data: apierr.Of(err, redact.Error(err, conf.Logging.RedactErrors))})
 			return
 		}
-		writeJSON(w, http.StatusOK, map[string]any{"query": body.Query, "chunks": hits, "total_chunks": len(hits)})
-    }))
+		var suggestions *ragvec.Suggestions
+		if body.Offset == 0 {
+			queries.Record("http", "", body.Query, body.Project, hits)
+			if suggestions, err = rag.Suggest(body.Query, hits, filter, params); err != nil {
+				log.Printf("Search suggestions error: %v", err)
+			}
+		}
+		hits = hits[min(body.Offset, len(hits)):]
+		// The body can turn read-through off, not on
+		if conf.Search.ReadThrough && (body.ReadThrough == nil || *body.ReadThrough) {
+			rag.ReadThrough(hits)
+		}
+		found := len(hits)
+		hits, usage := meter.Take(callerID(r), conf.TenantQuota(tenant), hits)
+		if usage != nil && found > 0 && len(hits) == 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(usage.RetryAfter()))
+			writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "quota exceeded",
+				Data: apierr.QuotaExceeded("The search quota of this caller is used up until " + usage.ResetsAt), Quota: usage})
+			return
+		}
+		writeJSON(w, http.StatusOK, fitList(conf.HTTP.MaxResponseKB*1024, body.Offset, len(hits), func(n int) map[string]any {
+			out := map[string]any{"query": body.Query, "chunks": hits[:n], "total_chunks": n, "offset": body.Offset}
+			if suggestions != nil {
+				out["suggestions"] = suggestions
+			}
+			if usage != nil {
+				out["quota"] = usage
+			}
+			return out
+		}))
+	}))
 
-    // POST /rag/delete {all, project}
-    mux.HandleFunc("/rag/delete", requireAuth(func(w http.ResponseWriter, r *http.Request) {
-        if rag == nil { writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Details: "Start Qdrant or disable -no-qdrant"}); return }
-        var body struct {
-            All     bool   `json:"all"`
-            Project string `json:"project"`
-        }
-        if err := json.NewDecoder(r.Body).Decode(&body); err != nil { writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json", Details: err.Error()}); return }
-        if !body.All && strings.TrimSpace(body.Project) == "" { writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid params", Details: "Provide all=true or a non-empty project"}); return }
-        var del int
-        var err error
-        if body.All {
-            del, err = rag.DeleteAll()
-        } else {
-            del, err = rag.DeleteProject(body.Project)
-        }
-        if err != nil { writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "delete error", Details: err.Error()}); return }
-        writeJSON(w, http.StatusOK, map[string]any{"deleted": del, "all": body.All, "project": body.Project})
-    }))
+	// POST /rag/delete {all, project, dry_run}
+	admin.HandleFunc("/rag/delete", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		rag := forRequest(getRAG(), r)
+		if rag == nil {
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Data: apierr.Unavailable("Start Qdrant or disable -no-qdrant")})
+			return
+		}
+		var body struct {
+			All     bool   `json:"all"`
+			Project string `json:"project"`
+			DryRun  bool   `json:"dry_run"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		if !body.All && strings.TrimSpace(body.Project) == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid params", Data: apierr.Invalid("Provide all=true or a non-empty project")})
+			return
+		}
+		if body.DryRun {
+			if body.All {
+				body.Project = ""
+			}
+			preview, err := rag.PreviewDelete(body.Project)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "delete error", Data: apierr.Of(err, redact.Error(err, conf.Logging.RedactErrors))})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"deleted": preview.Points, "all": body.All, "project": body.Project, "dry_run": true,
+				"projects": preview.Projects, "files": preview.Files, "file_count": preview.FileCount})
+			return
+		}
+		var del int
+		var err error
+		start := time.Now()
+		if body.All {
+			del, err = rag.DeleteAll()
+		} else {
+			del, err = rag.DeleteProject(body.Project)
+		}
+		errText := redact.Error(err, conf.Logging.RedactErrors)
+		hooks.Notify(webhook.Event{Event: "delete", Source: "http", Count: del,
+			Details: map[string]any{"all": body.All, "project": body.Project}}, start, errText)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "delete error", Data: apierr.Of(err, errText)})
+			return
+		}
+		resp := map[string]any{"deleted": del, "all": body.All, "project": body.Project, "dry_run": false}
+		if keep := rag.SoftDeleteRetention(); keep > 0 && del > 0 {
+			resp["restorable_until"] = time.Now().Add(keep).UTC().Format(time.RFC3339)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}))
+
+	// POST /rag/undelete {all, project, dry_run}
+	admin.HandleFunc("/rag/undelete", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		rag := forRequest(getRAG(), r)
+		if rag == nil {
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Data: apierr.Unavailable("Start Qdrant or disable -no-qdrant")})
+			return
+		}
+		var body struct {
+			All     bool   `json:"all"`
+			Project string `json:"project"`
+			DryRun  bool   `json:"dry_run"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid json", Data: apierr.Invalid(err.Error())})
+			return
+		}
+		if !body.All && strings.TrimSpace(body.Project) == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid params", Data: apierr.Invalid("Provide all=true or a non-empty project")})
+			return
+		}
+		if body.All {
+			body.Project = ""
+		}
+		if body.DryRun {
+			preview, err := rag.PreviewUndelete(body.Project)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "undelete error", Data: apierr.Of(err, redact.Error(err, conf.Logging.RedactErrors))})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"restored": preview.Points, "all": body.All, "project": body.Project, "dry_run": true,
+				"projects": preview.Projects, "files": preview.Files, "file_count": preview.FileCount})
+			return
+		}
+		n, err := rag.Undelete(body.Project)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "undelete error", Data: apierr.Of(err, redact.Error(err, conf.Logging.RedactErrors))})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"restored": n, "all": body.All, "project": body.Project, "dry_run": false})
+	}))
 
 	// GET /rag/projects?prefix=&offset=&limit=
 	mux.HandleFunc("/rag/projects", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		rag := forRequest(getRAG(), r)
 		if rag == nil {
-			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Details: "Start Qdrant or disable -no-qdrant"})
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "RAG not initialized", Data: apierr.Unavailable("Start Qdrant or disable -no-qdrant")})
 			return
 		}
 		q := r.URL.Query()
 		prefix := q.Get("prefix")
 		offset, _ := strconv.Atoi(q.Get("offset"))
+		offset = max(offset, 0)
 		limit, _ := strconv.Atoi(q.Get("limit"))
-		list, total, err := rag.ListProjectsFiltered(prefix, offset, limit)
+		// Tenants only count the chunks of their labels
+		acl, err := conf.Access.TenantLabels(tenantOf(r))
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "projects error", Details: err.Error()})
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "projects error", Data: apierr.New(apierr.Config, "config", err.Error())})
 			return
 		}
-		writeJSON(w, http.StatusOK, map[string]any{"projects": list, "count": len(list), "total": total, "offset": offset, "limit": limit, "filter": map[string]any{"prefix": prefix}})
+		list, total, err := rag.ListProjectsFiltered(prefix, offset, limit, acl)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "projects error", Data: apierr.Of(err, redact.Error(err, conf.Logging.RedactErrors))})
+			return
+		}
+		writeJSON(w, http.StatusOK, fitList(conf.HTTP.MaxResponseKB*1024, offset, len(list), func(n int) map[string]any {
+			resp := map[string]any{"projects": list[:n], "count": n, "total": total, "offset": offset, "limit": limit, "filter": map[string]any{"prefix": prefix}}
+			if offset+n < total {
+				resp["next_offset"] = offset + n
+			}
+			return resp
+		}))
 	}))
 
-	srv := &http.Server{Addr: addr, Handler: mux}
+	registerMetrics(admin, stats, requireAdmin)
+	if conf.HTTP.DebugEndpoints {
+		registerDebug(admin, requireAdmin)
+	}
+	if len(conf.HTTP.Compare) > 0 {
+		registerCompare(admin, conf, getRAG, requireAdmin)
+	}
+	if conf.HTTP.PayloadAdmin {
+		registerPayload(admin, conf, getRAG, requireAdmin)
+	}
+
+	ln, err := listen(addr, conf.HTTP)
+	if err != nil {
+		return err
+	}
+	var adminLn net.Listener
+	if adminAddr != "" {
+		if adminLn, err = listen(adminAddr, conf.HTTP); err != nil {
+			ln.Close()
+			return fmt.Errorf("admin listener: %w", err)
+		}
+	}
+	serve(ln, addr, "HTTP API", mux, conf.HTTP)
+	if adminLn != nil {
+		serve(adminLn, adminAddr, "Admin HTTP API", admin, conf.HTTP)
+	}
+	return nil
+}
+
+// serve runs mux on ln in the background with the configured compression
+// and access log.
+func serve(ln net.Listener, addr, name string, mux *http.ServeMux, conf cfg.HTTPConfig) {
+	var handler http.Handler = mux
+	if conf.Gzip {
+		handler = withGzip(handler)
+	}
+	srv := &http.Server{Handler: withAccessLog(conf.AccessLog, handler)}
 	go func() {
-		log.Printf("HTTP API listening on %s", addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("HTTP server error: %v", err)
+		log.Printf("%s listening on %s", name, addr)
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("%s server error: %v", name, err)
 		}
 	}()
 }
@@ -242,11 +636,12 @@ func ifThenElse(cond bool, a, b string) string {
 	return b
 }
 
-func safeErr(err error) string {
-	if err == nil {
-		return ""
+// qdrantURL returns the vector store URL as shown to clients.
+func qdrantURL(conf *cfg.Config) string {
+	if conf.Logging.RedactErrors {
+		return redact.URL(conf.StoreURL())
 	}
-	return err.Error()
+	return conf.StoreURL()
 }
 
 func projectFromPath(p string) string {
@@ -265,3 +660,9 @@ func projectFromPath(p string) string {
 	}
 	return rest[j+1:]
 }
+
+// circuitState reports the vector store circuit breaker state and failure streak.
+func circuitState(q ragvec.VectorStore) map[string]any {
+	state, failures := q.Breaker().State()
+	return map[string]any{"state": state, "consecutive_failures": failures}
+}
@@ -0,0 +1,42 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrElicitationUnsupported is returned by Elicit when the client did not
+// advertise elicitation or the negotiated protocol revision lacks it.
+var ErrElicitationUnsupported = errors.New("client does not support elicitation")
+
+// elicitation/create → params. RequestedSchema is a flat object schema of
+// primitive properties, the only kind clients have to render.
+type ElicitParams struct {
+	Message         string         `json:"message"`
+	RequestedSchema map[string]any `json:"requestedSchema"`
+}
+
+// elicitation/create → result. Action is "accept" (Content holds the
+// user's input), "decline" or "cancel".
+type ElicitResult struct {
+	Action  string         `json:"action"`
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// Elicit asks the user of session's client for input over rpc. Waiting
+// for a person, ctx should allow minutes rather than seconds.
+func Elicit(ctx context.Context, rpc *StdioRPC, session *Session, p ElicitParams) (*ElicitResult, error) {
+	if !session.CanElicit() {
+		return nil, ErrElicitationUnsupported
+	}
+	raw, err := rpc.Request(ctx, "elicitation/create", p)
+	if err != nil {
+		return nil, err
+	}
+	var res ElicitResult
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
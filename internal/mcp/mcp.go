@@ -3,40 +3,59 @@ package mcp
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 )
 
 // ----- JSON-RPC 2.0 -----
+
+// JSONRPCRequest is a request or notification. ID keeps the id token as
+// sent, so replies echo 1 as 1 and "1" as "1"; it is nil when the id is
+// absent and "null" for an explicit null. A client's response to a
+// request of the server carries Result or Error instead of a method.
 type JSONRPCRequest struct {
-	JSONRPC string          `json:"jsonrpc"`
-	ID      any             `json:"id,omitempty"`
-	Method  string          `json:"method"`
-	Params  json.RawMessage `json:"params,omitempty"`
+	JSONRPC string           `json:"jsonrpc"`
+	ID      json.RawMessage  `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *JSONRPCErrorObj `json:"error,omitempty"`
 }
 
+// JSONRPCResponse always carries an id; a nil ID is sent as null, as
+// JSON-RPC requires for errors about requests whose id is unknown.
 type JSONRPCResponse struct {
 	JSONRPC string           `json:"jsonrpc"`
-	ID      any              `json:"id,omitempty"`
+	ID      json.RawMessage  `json:"id"`
 	Result  any              `json:"result,omitempty"`
 	Error   *JSONRPCErrorObj `json:"error,omitempty"`
 }
 
+type JSONRPCNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
 type JSONRPCErrorObj struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Data    any    `json:"data,omitempty"`
 }
 
-func writeResp(w io.Writer, id any, result any, errObj *JSONRPCErrorObj) error {
-	resp := JSONRPCResponse{JSONRPC: "2.0", ID: id, Result: result, Error: errObj}
-	enc := json.NewEncoder(w)
-	return enc.Encode(resp)
+func (e *JSONRPCErrorObj) Error() string {
+	if e.Data != nil {
+		return fmt.Sprintf("%s (%d): %v", e.Message, e.Code, e.Data)
+	}
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
 }
 
 // ----- MCP minimal: structures -----
@@ -49,8 +68,20 @@ type InitializeResult struct {
 }
 
 type Capabilities struct {
-	// Per MCP spec, capabilities are objects; an empty object means supported.
-	Tools map[string]any `json:"tools"`
+	// Per MCP spec, capabilities are objects; an empty object means
+	// supported. They are typed any so that an empty map is sent as {}
+	// while a nil one is left out.
+	Tools any `json:"tools,omitempty"`
+	// Completions is advertised when completion/complete is supported
+	Completions any `json:"completions,omitempty"`
+	// Resources is advertised when resources/list and resources/read are
+	// supported
+	Resources any `json:"resources,omitempty"`
+}
+
+// tools/list → params
+type ToolsListParams struct {
+	Cursor string `json:"cursor,omitempty"`
 }
 
 type MCPServerInfo struct {
@@ -60,13 +91,16 @@ type MCPServerInfo struct {
 
 // tools/list → result
 type ToolsListResult struct {
-	Tools []Tool `json:"tools"`
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 type Tool struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
 	InputSchema map[string]any `json:"inputSchema"`
+	// OutputSchema describes StructuredContent of the tool's results
+	OutputSchema map[string]any `json:"outputSchema,omitempty"`
 }
 
 // tools/call → params & result
@@ -75,6 +109,85 @@ type ToolsCallParams struct {
 	Args map[string]any `json:"arguments"`
 }
 
+// resources/list → result
+type ResourcesListResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// resources/templates/list → result
+type ResourceTemplatesListResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+// ResourceTemplate describes resources by an RFC 6570 URI template; its
+// variables are the arguments completion/complete fills in.
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// resources/read → params & result
+type ResourcesReadParams struct {
+	URI string `json:"uri"`
+}
+
+type ResourcesReadResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// completion/complete → params & result
+type CompleteParams struct {
+	Ref      CompleteRef      `json:"ref"`
+	Argument CompleteArgument `json:"argument"`
+}
+
+// Reference types of completion/complete.
+const (
+	RefPrompt   = "ref/prompt"
+	RefResource = "ref/resource"
+)
+
+// CompleteRef identifies what is being completed: a prompt by Name
+// (RefPrompt) or a resource template by URI (RefResource).
+type CompleteRef struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+type CompleteArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type CompleteResult struct {
+	Completion Completion `json:"completion"`
+}
+
+type Completion struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
 // ContentItem represents a single content part in MCP responses
 type ContentItem struct {
 	Type string `json:"type"`
@@ -89,6 +202,13 @@ type ContentItem struct {
 
 type ToolsCallResult struct {
 	Content []ContentItem `json:"content"`
+	// IsError reports a tool execution failure (as opposed to a protocol error)
+	IsError bool `json:"isError,omitempty"`
+	// StructuredContent is the JSON result the tool's outputSchema
+	// describes; the content items carry it too, for older clients
+	StructuredContent any `json:"structuredContent,omitempty"`
+	// Meta carries out-of-band details such as truncation indicators
+	Meta map[string]any `json:"_meta,omitempty"`
 }
 
 // EmbeddedResource represents an inline resource payload per MCP spec
@@ -101,44 +221,94 @@ type EmbeddedResource struct {
 	// Blob []byte `json:"blob,omitempty"`
 }
 
+// writeChunkSize bounds a single write to the underlying stdout pipe.
+const writeChunkSize = 64 * 1024
+
 // Util baca/loop stdio
 type StdioRPC struct {
 	r          *bufio.Reader
-	w          io.Writer
+	w          *bufio.Writer
+	mu         sync.Mutex
 	headerMode bool
+	// maxBytes caps the encoded size of a single response (0 = unlimited)
+	maxBytes int
+	// strict refuses header framing that does not start with Content-Length
+	strict bool
+
+	// pending holds the requests of the server awaiting the client's
+	// response, by id
+	pendingMu sync.Mutex
+	pending   map[string]chan *JSONRPCRequest
+	closed    error
 }
 
 func NewStdioRPC() *StdioRPC {
+	return NewRPC(os.Stdin, os.Stdout)
+}
+
+// NewRPC returns an RPC that reads requests from r and writes to w, such as
+// one connection of the MCP socket listener.
+func NewRPC(r io.Reader, w io.Writer) *StdioRPC {
 	return &StdioRPC{
-		r: bufio.NewReader(os.Stdin),
-		w: os.Stdout,
+		r: bufio.NewReader(r),
+		w: bufio.NewWriterSize(w, writeChunkSize),
 	}
 }
 
+// SetMaxResponseBytes sets the maximum encoded size of a response frame.
+// Oversized responses are replaced with a truncated result or an error.
+func (s *StdioRPC) SetMaxResponseBytes(n int) {
+	if n < 0 {
+		n = 0
+	}
+	s.maxBytes = n
+}
+
+// ErrNotMCP is returned by Read in strict mode when a header frame does not
+// start with Content-Length, e.g. an HTTP request a browser sent to the
+// socket. The connection should be closed without a reply.
+var ErrNotMCP = errors.New("first header line is not Content-Length")
+
+// SetStrict makes Read refuse header framing whose first line is not
+// Content-Length with ErrNotMCP, for connections of the socket listener.
+func (s *StdioRPC) SetStrict(on bool) {
+	s.strict = on
+}
+
 func (s *StdioRPC) Read() (*JSONRPCRequest, error) {
 	// Detect framing
 	b, err := s.r.Peek(1)
 	if err != nil {
 		return nil, err
 	}
-	if b[0] == '{' {
-		s.headerMode = false
-		dec := json.NewDecoder(s.r)
-		var req JSONRPCRequest
-		if err := dec.Decode(&req); err != nil {
-			return nil, err
+	if b[0] == '{' || b[0] == '\n' || b[0] == '\r' {
+		s.setHeaderMode(false)
+		// One message per line. A decoder over s.r would buffer past the
+		// message and swallow the next one.
+		for {
+			line, err := s.r.ReadBytes('\n')
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return parseRequest(line)
 		}
-		return &req, nil
 	}
 	// LSP-style header framing
-	s.headerMode = true
+	s.setHeaderMode(true)
 	var contentLength int
-	for {
+	for first := true; ; first = false {
 		line, err := s.r.ReadString('\n')
 		if err != nil {
 			return nil, err
 		}
 		line = strings.TrimRight(line, "\r\n")
+		if first && s.strict && !strings.HasPrefix(strings.ToLower(line), "content-length:") {
+			return nil, ErrNotMCP
+		}
 		if line == "" {
 			break
 		}
@@ -159,49 +329,230 @@ func (s *StdioRPC) Read() (*JSONRPCRequest, error) {
 	if _, err := io.ReadFull(s.r, buf); err != nil {
 		return nil, err
 	}
-	dec := json.NewDecoder(bytes.NewReader(buf))
+	return parseRequest(buf)
+}
+
+// ParseError is returned by Read for a message that is not a JSON-RPC
+// object. The message has been consumed, so reading can go on with the
+// next one after replying -32700.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string { return e.Err.Error() }
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+func parseRequest(b []byte) (*JSONRPCRequest, error) {
 	var req JSONRPCRequest
-	if err := dec.Decode(&req); err != nil {
-		return nil, err
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, &ParseError{Err: err}
 	}
 	return &req, nil
 }
 
-func (s *StdioRPC) Reply(id any, result any) error {
-	if s.headerMode {
-		var buf bytes.Buffer
-		enc := json.NewEncoder(&buf)
-		if err := enc.Encode(JSONRPCResponse{JSONRPC: "2.0", ID: id, Result: result}); err != nil {
-			return err
-		}
-		b := buf.Bytes()
-		if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(b)); err != nil {
+// setHeaderMode records the framing of the last request; replies and
+// notifications reuse it.
+func (s *StdioRPC) setHeaderMode(on bool) {
+	s.mu.Lock()
+	s.headerMode = on
+	s.mu.Unlock()
+}
+
+func (s *StdioRPC) Reply(id json.RawMessage, result any) error {
+	return s.send(JSONRPCResponse{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *StdioRPC) ReplyError(id json.RawMessage, code int, msg string, data any) error {
+	return s.send(JSONRPCResponse{JSONRPC: "2.0", ID: id, Error: &JSONRPCErrorObj{Code: code, Message: msg, Data: data}})
+}
+
+// send encodes a response, enforces the size cap and writes it as one frame.
+// Writes are serialized so that a frame is never interleaved with another one.
+func (s *StdioRPC) send(resp JSONRPCResponse) error {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if s.maxBytes > 0 && len(b) > s.maxBytes {
+		b, err = s.truncate(resp, len(b))
+		if err != nil {
 			return err
 		}
-		_, err := s.w.Write(b)
+	}
+	return s.writeFrame(b)
+}
+
+// Notify sends a server-initiated JSON-RPC notification (no id, no reply).
+func (s *StdioRPC) Notify(method string, params any) error {
+	b, err := json.Marshal(JSONRPCNotification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
 		return err
 	}
-	return writeResp(s.w, id, result, nil)
+	return s.writeFrame(b)
 }
 
-func (s *StdioRPC) ReplyError(id any, code int, msg string, data any) error {
+// writeFrame writes one encoded message using the framing negotiated by Read.
+func (s *StdioRPC) writeFrame(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.headerMode {
-		var buf bytes.Buffer
-		enc := json.NewEncoder(&buf)
-		if err := enc.Encode(JSONRPCResponse{JSONRPC: "2.0", ID: id, Error: &JSONRPCErrorObj{Code: code, Message: msg, Data: data}}); err != nil {
+		if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(b)); err != nil {
 			return err
 		}
-		b := buf.Bytes()
-		if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(b)); err != nil {
+		if err := writeChunked(s.w, b); err != nil {
 			return err
 		}
-		_, err := s.w.Write(b)
-		return err
+	} else {
+		if err := writeChunked(s.w, b); err != nil {
+			return err
+		}
+		if err := s.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return s.w.Flush()
+}
+
+// truncate replaces an oversized response with a smaller one carrying a
+// truncation indicator. Tool results keep their leading text items when they
+// fit; anything else is turned into an error the client can act on.
+func (s *StdioRPC) truncate(resp JSONRPCResponse, size int) ([]byte, error) {
+	note := fmt.Sprintf("[response truncated: %d bytes exceeds limit of %d bytes]", size, s.maxBytes)
+	meta := map[string]any{"truncated": true, "original_bytes": size, "max_bytes": s.maxBytes}
+	r, ok := resp.Result.(ToolsCallResult)
+	if p, isPtr := resp.Result.(*ToolsCallResult); isPtr && p != nil {
+		r, ok = *p, true
+	}
+	if ok {
+		kept := ToolsCallResult{IsError: r.IsError, Meta: meta}
+		budget := s.maxBytes - len(note) - 256
+		for _, c := range r.Content {
+			if c.Type != "text" || len(c.Text) > budget {
+				break
+			}
+			budget -= len(c.Text)
+			kept.Content = append(kept.Content, c)
+		}
+		kept.Content = append(kept.Content, ContentItem{Type: "text", Text: note})
+		b, err := json.Marshal(JSONRPCResponse{JSONRPC: "2.0", ID: resp.ID, Result: kept})
+		if err == nil && len(b) <= s.maxBytes {
+			return b, nil
+		}
 	}
-	return writeResp(s.w, id, nil, &JSONRPCErrorObj{Code: code, Message: msg, Data: data})
+	return json.Marshal(JSONRPCResponse{JSONRPC: "2.0", ID: resp.ID, Error: &JSONRPCErrorObj{
+		Code: -32000, Message: "response too large", Data: meta,
+	}})
+}
+
+// writeChunked writes b in bounded slices so a multi-megabyte frame does not
+// sit in a single giant write while the client is draining the pipe.
+func writeChunked(w io.Writer, b []byte) error {
+	for len(b) > 0 {
+		n := len(b)
+		if n > writeChunkSize {
+			n = writeChunkSize
+		}
+		if _, err := w.Write(b[:n]); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
 }
 
 // Helper ID bila perlu
 var rid int64
 
 func nextID() int64 { return atomic.AddInt64(&rid, 1) }
+
+// IsResponse reports whether msg is a client's response to a request of
+// the server rather than a request of its own.
+func IsResponse(msg *JSONRPCRequest) bool {
+	return msg.Method == "" && msg.ID != nil && (msg.Result != nil || msg.Error != nil)
+}
+
+// Request sends a server-initiated request and waits for the client's
+// response, which the session's read loop hands over with Deliver. It
+// returns the result, the client's error as a *JSONRPCErrorObj, or ctx's
+// error once ctx ends first.
+func (s *StdioRPC) Request(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	id := strconv.FormatInt(nextID(), 10)
+	ch := make(chan *JSONRPCRequest, 1)
+	s.pendingMu.Lock()
+	if s.closed != nil {
+		err := s.closed
+		s.pendingMu.Unlock()
+		return nil, err
+	}
+	if s.pending == nil {
+		s.pending = map[string]chan *JSONRPCRequest{}
+	}
+	s.pending[id] = ch
+	s.pendingMu.Unlock()
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, id)
+		s.pendingMu.Unlock()
+	}()
+
+	b, err := json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  any             `json:"params,omitempty"`
+	}{"2.0", json.RawMessage(id), method, params})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.writeFrame(b); err != nil {
+		return nil, err
+	}
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, s.closedErr()
+		}
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Deliver hands a response to the Request waiting for it and reports
+// whether one was; responses nobody waits for, e.g. after a timeout, are
+// dropped.
+func (s *StdioRPC) Deliver(resp *JSONRPCRequest) bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	// Ids are sent as numbers; some clients echo them as strings
+	id := strings.Trim(string(bytes.TrimSpace(resp.ID)), `"`)
+	ch, ok := s.pending[id]
+	if !ok {
+		return false
+	}
+	delete(s.pending, id)
+	ch <- resp
+	return true
+}
+
+// Abort fails the pending requests and any later ones with err, once the
+// client is gone.
+func (s *StdioRPC) Abort(err error) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	s.closed = err
+	for id, ch := range s.pending {
+		close(ch)
+		delete(s.pending, id)
+	}
+}
+
+func (s *StdioRPC) closedErr() error {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	return s.closed
+}
@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadSkipsMalformedLine(t *testing.T) {
+	in := "{\"jsonrpc\":\"2.0\",\n" +
+		"{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"ping\"}\n"
+	rpc := NewRPC(strings.NewReader(in), &bytes.Buffer{})
+
+	_, err := rpc.Read()
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("first Read: got %v, want a *ParseError", err)
+	}
+	req, err := rpc.Read()
+	if err != nil {
+		t.Fatalf("second Read: %v", err)
+	}
+	if req.Method != "ping" || string(req.ID) != "1" {
+		t.Errorf("second Read: got method %q id %s, want ping 1", req.Method, req.ID)
+	}
+	if _, err := rpc.Read(); err != io.EOF {
+		t.Errorf("third Read: got %v, want io.EOF", err)
+	}
+}
+
+func TestReadSkipsMalformedFrame(t *testing.T) {
+	in := "Content-Length: 5\r\n\r\n{bad}" +
+		"Content-Length: 40\r\n\r\n{\"jsonrpc\":\"2.0\",\"id\":2,\"method\":\"ping\"}"
+	rpc := NewRPC(strings.NewReader(in), &bytes.Buffer{})
+
+	_, err := rpc.Read()
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("first Read: got %v, want a *ParseError", err)
+	}
+	req, err := rpc.Read()
+	if err != nil {
+		t.Fatalf("second Read: %v", err)
+	}
+	if req.Method != "ping" || string(req.ID) != "2" {
+		t.Errorf("second Read: got method %q id %s, want ping 2", req.Method, req.ID)
+	}
+}
+
+func TestReadStrictRefusesHTTP(t *testing.T) {
+	frame := "Content-Length: 40\r\n\r\n{\"jsonrpc\":\"2.0\",\"id\":2,\"method\":\"ping\"}"
+	for _, tt := range []struct {
+		in   string
+		want error
+	}{
+		{"POST /rag/search HTTP/1.1\r\nHost: 127.0.0.1:7070\r\nContent-Length: 40\r\n\r\n{}", ErrNotMCP},
+		{"Content-Type: application/json\r\n" + frame, ErrNotMCP},
+		{frame, nil},
+		{"content-length: 40\r\n\r\n{\"jsonrpc\":\"2.0\",\"id\":2,\"method\":\"ping\"}", nil},
+	} {
+		rpc := NewRPC(strings.NewReader(tt.in), &bytes.Buffer{})
+		rpc.SetStrict(true)
+		if _, err := rpc.Read(); !errors.Is(err, tt.want) {
+			t.Errorf("Read(%q): got %v, want %v", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestReplyEchoesRawID(t *testing.T) {
+	for _, id := range []string{`"abc"`, `7`, `null`, `12345678901234567890`} {
+		in := `{"jsonrpc":"2.0","id":` + id + `,"method":"ping"}` + "\n" +
+			`{"jsonrpc":"2.0","id":` + id + `,"method":"nope"}` + "\n"
+		var out bytes.Buffer
+		rpc := NewRPC(strings.NewReader(in), &out)
+
+		req, err := rpc.Read()
+		if err != nil {
+			t.Fatalf("id %s: Read: %v", id, err)
+		}
+		if err := rpc.Reply(req.ID, map[string]any{}); err != nil {
+			t.Fatalf("id %s: Reply: %v", id, err)
+		}
+		if req, err = rpc.Read(); err != nil {
+			t.Fatalf("id %s: second Read: %v", id, err)
+		}
+		if err := rpc.ReplyError(req.ID, -32601, "method not found", nil); err != nil {
+			t.Fatalf("id %s: ReplyError: %v", id, err)
+		}
+
+		dec := json.NewDecoder(&out)
+		for _, kind := range []string{"Reply", "ReplyError"} {
+			var resp struct {
+				ID json.RawMessage `json:"id"`
+			}
+			if err := dec.Decode(&resp); err != nil {
+				t.Fatalf("id %s: decode %s: %v", id, kind, err)
+			}
+			if string(resp.ID) != id {
+				t.Errorf("%s: got id %s, want %s", kind, resp.ID, id)
+			}
+		}
+	}
+}
+
+func TestValidID(t *testing.T) {
+	for _, id := range []string{`"abc"`, `7`, `null`, `12345678901234567890`, `-1.5`} {
+		if !ValidID(json.RawMessage(id)) {
+			t.Errorf("ValidID(%s) = false, want true", id)
+		}
+	}
+	if !ValidID(nil) {
+		t.Errorf("ValidID(nil) = false, want true")
+	}
+	for _, id := range []string{`{}`, `{"a":1}`, `[]`, `[1]`, `true`, `false`} {
+		if ValidID(json.RawMessage(id)) {
+			t.Errorf("ValidID(%s) = true, want false", id)
+		}
+	}
+}
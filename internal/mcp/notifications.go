@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// NotificationHandler handles an incoming JSON-RPC notification.
+// Notifications never receive a reply, so handlers only observe params.
+type NotificationHandler func(params json.RawMessage)
+
+// NotificationRouter dispatches client notifications by method name.
+// Unknown notifications are ignored, as required by JSON-RPC.
+type NotificationRouter struct {
+	mu       sync.RWMutex
+	handlers map[string]NotificationHandler
+	// Fallback is invoked for notifications without a registered handler
+	Fallback NotificationHandler
+}
+
+func NewNotificationRouter() *NotificationRouter {
+	return &NotificationRouter{handlers: map[string]NotificationHandler{}}
+}
+
+// Handle registers h for method, replacing any previous handler.
+func (r *NotificationRouter) Handle(method string, h NotificationHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[method] = h
+}
+
+// Dispatch routes req if it is a notification and reports whether it did.
+// Callers must not reply when Dispatch returns true.
+func (r *NotificationRouter) Dispatch(req *JSONRPCRequest) bool {
+	if !IsNotification(req) {
+		return false
+	}
+	r.mu.RLock()
+	h, ok := r.handlers[req.Method]
+	r.mu.RUnlock()
+	if ok && h != nil {
+		h(req.Params)
+	} else if r.Fallback != nil {
+		r.Fallback(req.Params)
+	}
+	return true
+}
+
+// IsNotification reports whether req is a notification: it carries no id,
+// or it uses the MCP "notifications/" namespace (some clients send an id anyway).
+// A request with "id": null is not a notification; its reply has a null id.
+func IsNotification(req *JSONRPCRequest) bool {
+	return req.ID == nil || strings.HasPrefix(req.Method, "notifications/")
+}
+
+// ValidID reports whether id is absent, a string, a number or null, the
+// id types JSON-RPC allows.
+func ValidID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var v any
+	if err := json.Unmarshal(id, &v); err != nil {
+		return false
+	}
+	switch v.(type) {
+	case nil, string, float64:
+		return true
+	}
+	return false
+}
+
+// ping → result (empty object per MCP spec)
+type PingResult struct{}
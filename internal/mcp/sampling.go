@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrSamplingUnsupported is returned by CreateMessage when the client did
+// not advertise the sampling capability.
+var ErrSamplingUnsupported = errors.New("client does not support sampling")
+
+// sampling/createMessage → params
+type CreateMessageParams struct {
+	Messages         []SamplingMessage `json:"messages"`
+	SystemPrompt     string            `json:"systemPrompt,omitempty"`
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+	// IncludeContext asks the client to add context of its own: "none",
+	// "thisServer" or "allServers"
+	IncludeContext string  `json:"includeContext,omitempty"`
+	Temperature    float64 `json:"temperature,omitempty"`
+	MaxTokens      int     `json:"maxTokens"`
+}
+
+type SamplingMessage struct {
+	Role    string          `json:"role"`
+	Content SamplingContent `json:"content"`
+}
+
+// SamplingContent is a text part of a sampling message; image and audio
+// parts are not used here.
+type SamplingContent struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// ModelPreferences guides the client's choice of model; it is free to
+// ignore them.
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         float64     `json:"costPriority,omitempty"`
+	SpeedPriority        float64     `json:"speedPriority,omitempty"`
+	IntelligencePriority float64     `json:"intelligencePriority,omitempty"`
+}
+
+type ModelHint struct {
+	Name string `json:"name"`
+}
+
+// sampling/createMessage → result
+type CreateMessageResult struct {
+	Role       string          `json:"role"`
+	Content    SamplingContent `json:"content"`
+	Model      string          `json:"model"`
+	StopReason string          `json:"stopReason,omitempty"`
+}
+
+// CreateMessage asks the model of session's client for a completion over
+// rpc. The client may show the request to its user first, so ctx should
+// allow for that.
+func CreateMessage(ctx context.Context, rpc *StdioRPC, session *Session, p CreateMessageParams) (*CreateMessageResult, error) {
+	if !session.CanSample() {
+		return nil, ErrSamplingUnsupported
+	}
+	raw, err := rpc.Request(ctx, "sampling/createMessage", p)
+	if err != nil {
+		return nil, err
+	}
+	var res CreateMessageResult
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
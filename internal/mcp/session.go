@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+)
+
+// ErrNotInitialized is the JSON-RPC error code of requests sent before the
+// initialize handshake completed.
+const ErrNotInitialized = -32002
+
+// ProtocolVersions are the MCP revisions the server speaks, newest first.
+var ProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// ElicitationVersion is the first revision with elicitation.
+const ElicitationVersion = "2025-06-18"
+
+// StructuredContentVersion is the first revision with structured tool
+// results (outputSchema and structuredContent).
+const StructuredContentVersion = "2025-06-18"
+
+// negotiateVersion returns the revision requested when the server speaks
+// it, else the newest one, for the client to accept or disconnect.
+func negotiateVersion(requested string) string {
+	for _, v := range ProtocolVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return ProtocolVersions[0]
+}
+
+// SessionState is a step of the MCP lifecycle.
+type SessionState int
+
+const (
+	// StateNew waits for initialize
+	StateNew SessionState = iota
+	// StateInitializing answered initialize and waits for
+	// notifications/initialized
+	StateInitializing
+	// StateReady serves every request
+	StateReady
+	// StateFailed had an initialize rejected; the client may retry it
+	StateFailed
+)
+
+func (s SessionState) String() string {
+	switch s {
+	case StateNew:
+		return "new"
+	case StateInitializing:
+		return "initializing"
+	case StateReady:
+		return "ready"
+	case StateFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// initialize → params
+type InitializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ClientInfo      MCPServerInfo  `json:"clientInfo"`
+}
+
+// Session tracks the lifecycle of one client connection: initialize, then
+// notifications/initialized, then normal operation. Until the handshake
+// completes only initialize and ping are served. A rejected initialize, or
+// one whose reply the client did not acknowledge, may be sent again.
+type Session struct {
+	mu     sync.Mutex
+	state  SessionState
+	client InitializeParams
+	// version is the negotiated protocol revision
+	version string
+	// lenient serves requests before the handshake completed
+	lenient bool
+}
+
+// NewSession returns a session waiting for initialize. A lenient session
+// serves requests before the handshake, for clients that skip it.
+func NewSession(lenient bool) *Session {
+	return &Session{lenient: lenient}
+}
+
+// State returns the current lifecycle step.
+func (s *Session) State() SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Client returns what the client sent with its last accepted initialize.
+func (s *Session) Client() InitializeParams {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+// Version returns the protocol revision negotiated by the last accepted
+// initialize, "" before one.
+func (s *Session) Version() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// Structured reports whether tool results may carry structuredContent
+// under the negotiated protocol revision.
+func (s *Session) Structured() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version >= StructuredContentVersion
+}
+
+// CanElicit reports whether the client advertised the elicitation
+// capability under a protocol revision that has it.
+func (s *Session) CanElicit() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.client.Capabilities["elicitation"]
+	return ok && s.version >= ElicitationVersion
+}
+
+// CanSample reports whether the client advertised the sampling capability
+// in its initialize.
+func (s *Session) CanSample() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.client.Capabilities["sampling"]
+	return ok
+}
+
+// Admit returns the error to reply to req with when the session cannot
+// serve it yet, or nil. Notifications are always admitted.
+func (s *Session) Admit(req *JSONRPCRequest) *JSONRPCErrorObj {
+	if IsNotification(req) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case req.Method == "ping":
+		return nil
+	case req.Method == "initialize":
+		if s.state == StateReady {
+			return &JSONRPCErrorObj{Code: -32600, Message: "invalid request", Data: "session already initialized"}
+		}
+		return nil
+	case s.state == StateReady || s.lenient:
+		return nil
+	case s.state == StateInitializing:
+		return &JSONRPCErrorObj{Code: ErrNotInitialized, Message: "server not initialized",
+			Data: "send notifications/initialized before " + req.Method}
+	}
+	return &JSONRPCErrorObj{Code: ErrNotInitialized, Message: "server not initialized",
+		Data: "send initialize before " + req.Method}
+}
+
+// Initialize handles the params of an admitted initialize request. On
+// success the session waits for notifications/initialized; on error it
+// accepts another initialize.
+func (s *Session) Initialize(params json.RawMessage) (InitializeParams, *JSONRPCErrorObj) {
+	var p InitializeParams
+	var err error
+	if len(params) > 0 {
+		err = json.Unmarshal(params, &p)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.state = StateFailed
+		return p, &JSONRPCErrorObj{Code: -32602, Message: "invalid params", Data: apierr.Invalid(err.Error())}
+	}
+	if p.ProtocolVersion == "" && !s.lenient {
+		s.state = StateFailed
+		return p, &JSONRPCErrorObj{Code: -32602, Message: "invalid params", Data: apierr.Invalid("protocolVersion is required")}
+	}
+	s.client = p
+	s.version = negotiateVersion(p.ProtocolVersion)
+	s.state = StateInitializing
+	return p, nil
+}
+
+// Initialized handles notifications/initialized and reports whether it
+// completed the handshake; sent at any other step it is ignored.
+func (s *Session) Initialized() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != StateInitializing {
+		return false
+	}
+	s.state = StateReady
+	return true
+}
@@ -0,0 +1,141 @@
+// Package pathglob matches slash-separated relative paths against exclude
+// patterns: globs with ** (any number of directories) or regular expressions
+// prefixed with "re:".
+//
+// A glob without a slash matches the name of any file or directory at any
+// depth ("*.min.js", "testdata"). A glob with a slash is anchored at the
+// walked root ("docs/drafts/*", "**/testdata/**"); a leading slash is
+// ignored and a trailing slash restricts it to directories. A regular
+// expression is matched against the whole relative path, unanchored.
+package pathglob
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type pattern struct {
+	segments []string       // glob split on "/"; nil for regexps and basename globs
+	name     string         // glob matched against a single path element
+	re       *regexp.Regexp // set for "re:" patterns
+	dirOnly  bool
+}
+
+// Set is a compiled list of patterns; a path is excluded when any matches.
+// The zero Set matches nothing.
+type Set struct {
+	patterns []pattern
+}
+
+// Compile parses patterns, ignoring blank ones.
+func Compile(patterns []string) (*Set, error) {
+	s := &Set{}
+	for _, raw := range patterns {
+		p := strings.TrimSpace(raw)
+		if p == "" {
+			continue
+		}
+		if expr, ok := strings.CutPrefix(p, "re:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("pattern %q: %v", raw, err)
+			}
+			s.patterns = append(s.patterns, pattern{re: re})
+			continue
+		}
+		var c pattern
+		if strings.HasSuffix(p, "/") {
+			c.dirOnly = true
+			p = strings.TrimRight(p, "/")
+		}
+		p = strings.TrimLeft(p, "/")
+		if p == "" {
+			return nil, fmt.Errorf("pattern %q matches nothing", raw)
+		}
+		if strings.Contains(p, "/") {
+			c.segments = strings.Split(p, "/")
+		} else {
+			c.name = p
+		}
+		// Surface malformed globs such as "[a-" now rather than never matching
+		for _, seg := range append([]string{c.name}, c.segments...) {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, fmt.Errorf("pattern %q: %v", raw, err)
+			}
+		}
+		s.patterns = append(s.patterns, c)
+	}
+	return s, nil
+}
+
+// Empty reports whether the set has no patterns.
+func (s *Set) Empty() bool {
+	return s == nil || len(s.patterns) == 0
+}
+
+// Match reports whether rel, a slash-separated path relative to the walked
+// root, is excluded. isDir tells whether rel names a directory.
+func (s *Set) Match(rel string, isDir bool) bool {
+	if s == nil {
+		return false
+	}
+	rel = strings.Trim(rel, "/")
+	if rel == "" || rel == "." {
+		return false
+	}
+	var parts []string
+	for _, p := range s.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		switch {
+		case p.re != nil:
+			if p.re.MatchString(rel) {
+				return true
+			}
+		case p.segments == nil:
+			// The walker skips excluded directories, so only the last
+			// element of rel is new here
+			if ok, _ := path.Match(p.name, path.Base(rel)); ok {
+				return true
+			}
+		default:
+			if parts == nil {
+				parts = strings.Split(rel, "/")
+			}
+			if matchSegments(p.segments, parts) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSegments matches path elements against glob segments, where "**"
+// stands for zero or more elements.
+func matchSegments(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			pat = pat[1:]
+			if len(pat) == 0 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pat, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,135 @@
+// Package pii finds personal data in text (email addresses, phone numbers,
+// payment card and national ID numbers) so it can be redacted before the
+// text is embedded and stored.
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds are the detectors in the order they are tried; where matches
+// overlap the earlier, longer one wins.
+var Kinds = []string{"email", "credit_card", "national_id", "phone"}
+
+var detectors = map[string]*regexp.Regexp{
+	"email": regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+	// 13-19 digits, optionally grouped by spaces or dashes; Luhn-checked
+	"credit_card": regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+	// US SSN (123-45-6789) and 16-digit national IDs such as the Indonesian NIK
+	"national_id": regexp.MustCompile(`\b(?:\d{3}-\d{2}-\d{4}|\d{16})\b`),
+	// International (+62 812-3456-7890), area-coded ((021) 555-0100) or
+	// three-group local numbers (0812-3456-7890)
+	"phone": regexp.MustCompile(`\+\d{1,3}[ .-]?\d{2,4}[ .-]?\d{3,4}[ .-]?\d{3,5}\b|\(\d{1,4}\)[ .-]?\d{3,4}[ .-]?\d{3,4}\b|\b\d{2,4}[ .-]\d{3,4}[ .-]\d{3,4}\b`),
+}
+
+// Match is the byte range of one finding in the scanned text.
+type Match struct {
+	Kind       string
+	Start, End int
+}
+
+// Detector finds the enabled kinds of personal data.
+type Detector struct {
+	kinds []string
+}
+
+// New returns a detector for kinds, or for every kind when none is given.
+func New(kinds []string) (*Detector, error) {
+	if len(kinds) == 0 {
+		return &Detector{kinds: Kinds}, nil
+	}
+	d := &Detector{}
+	for _, k := range Kinds {
+		for _, want := range kinds {
+			if want == k {
+				d.kinds = append(d.kinds, k)
+			}
+		}
+	}
+	for _, want := range kinds {
+		if _, ok := detectors[want]; !ok {
+			return nil, fmt.Errorf("unknown kind %q (known: %s)", want, strings.Join(Kinds, ", "))
+		}
+	}
+	return d, nil
+}
+
+// Find returns the findings of text in order, without overlaps.
+func (d *Detector) Find(text string) []Match {
+	var found []Match
+	for _, kind := range d.kinds {
+		for _, loc := range detectors[kind].FindAllStringIndex(text, -1) {
+			if kind == "credit_card" && !luhn(text[loc[0]:loc[1]]) {
+				continue
+			}
+			if kind == "phone" && partOfNumber(text, loc[0], loc[1]) {
+				continue
+			}
+			found = append(found, Match{Kind: kind, Start: loc[0], End: loc[1]})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Start != found[j].Start {
+			return found[i].Start < found[j].Start
+		}
+		return found[i].End > found[j].End
+	})
+	var out []Match
+	for _, m := range found {
+		if len(out) == 0 || m.Start >= out[len(out)-1].End {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Redact replaces each match in text with its kind in brackets, e.g.
+// [EMAIL].
+func Redact(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString("[" + strings.ToUpper(m.Kind) + "]")
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// partOfNumber reports whether a phone-like match is a piece of a longer
+// number, such as an IP address, a version or a card number.
+func partOfNumber(text string, start, end int) bool {
+	isDigit := func(i int) bool { return i >= 0 && i < len(text) && text[i] >= '0' && text[i] <= '9' }
+	isSep := func(i int) bool { return i >= 0 && i < len(text) && strings.IndexByte(" .-", text[i]) >= 0 }
+	if text[start] == '+' || text[start] == '(' {
+		return isSep(end) && isDigit(end+1)
+	}
+	return isSep(start-1) && isDigit(start-2) || isSep(end) && isDigit(end+1)
+}
+
+// luhn validates the check digit of a card number, ignoring separators.
+func luhn(number string) bool {
+	sum, n := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		v := int(c - '0')
+		if n%2 == 1 {
+			if v *= 2; v > 9 {
+				v -= 9
+			}
+		}
+		sum += v
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,319 @@
+// Package querylog records search queries in a local JSONL file, with the
+// query text hashed unless configured otherwise, and aggregates them for
+// rag_analytics: the most frequent queries and the ones the index could not
+// answer.
+package querylog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+// Entry is one logged search.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Query is the normalized query text, or "sha256:<hex>" of it in hash
+	// mode
+	Query  string `json:"query"`
+	Source string `json:"source"` // "mcp" or "http"
+	// Caller names the MCP session that searched
+	Caller  string `json:"caller,omitempty"`
+	Project string `json:"project,omitempty"`
+	Hits    int    `json:"hits"`
+	// BestScore is the score of the top hit, 0 without hits
+	BestScore float32 `json:"best_score"`
+	// Results are the paths of the top hits, best first
+	Results []string `json:"results,omitempty"`
+}
+
+// Log appends entries to the query log file. A nil Log records nothing.
+type Log struct {
+	conf cfg.QueryLogConfig
+	// weak is the best score below which a search counts as unanswered
+	weak float64
+
+	mu sync.Mutex
+}
+
+// New returns the query log of conf, or nil when query_log is disabled.
+// Entries older than the retention are dropped from the file first.
+func New(conf *cfg.Config) *Log {
+	if !conf.QueryLog.Enabled {
+		return nil
+	}
+	l := &Log{conf: conf.QueryLog, weak: conf.Search.Suggestions.MinScore}
+	if err := l.compact(); err != nil {
+		log.Printf("Query log: compact %s: %v", l.conf.Path, err)
+	}
+	return l
+}
+
+// Normalize folds case and whitespace so variants of a query count as one.
+func Normalize(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// key renders the query as stored: normalized, and hashed in hash mode.
+func (l *Log) key(query string) string {
+	q := Normalize(query)
+	if l.conf.Mode == "raw" {
+		return q
+	}
+	mac := hmac.New(sha256.New, []byte(l.conf.Salt))
+	mac.Write([]byte(q))
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// Record appends a search of query by caller, filtered to project, that
+// returned hits.
+func (l *Log) Record(source, caller, query, project string, hits []ragvec.Hit) {
+	if l == nil || strings.TrimSpace(query) == "" {
+		return
+	}
+	e := Entry{
+		Time:    time.Now().UTC(),
+		Query:   l.key(query),
+		Source:  source,
+		Caller:  caller,
+		Project: project,
+		Hits:    len(hits),
+	}
+	for i, h := range hits {
+		e.BestScore = max(e.BestScore, h.Score)
+		if i < l.conf.MaxResults {
+			e.Results = append(e.Results, h.Path)
+		}
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.conf.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("Query log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		log.Printf("Query log: %v", err)
+	}
+}
+
+// read calls fn for every entry of the file at or after since, skipping
+// lines that do not parse.
+func (l *Log) read(since time.Time, fn func(Entry)) error {
+	f, err := os.Open(l.conf.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) != nil || e.Time.Before(since) {
+			continue
+		}
+		fn(e)
+	}
+	return sc.Err()
+}
+
+// compact rewrites the file without the entries past the retention.
+func (l *Log) compact() error {
+	if l.conf.RetentionDays <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var buf bytes.Buffer
+	dropped := 0
+	cutoff := time.Now().AddDate(0, 0, -l.conf.RetentionDays)
+	err := l.read(time.Time{}, func(e Entry) {
+		if e.Time.Before(cutoff) {
+			dropped++
+			return
+		}
+		b, _ := json.Marshal(e)
+		buf.Write(append(b, '\n'))
+	})
+	if err != nil || dropped == 0 {
+		return err
+	}
+	tmp := l.conf.Path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.conf.Path)
+}
+
+// QueryStat aggregates the searches of one query.
+type QueryStat struct {
+	Query    string    `json:"query"`
+	Count    int       `json:"count"`
+	AvgHits  float64   `json:"avg_hits"`
+	AvgBest  float64   `json:"avg_best_score"`
+	LastSeen time.Time `json:"last_seen"`
+	// Projects are the project filters the query was searched with
+	Projects []string `json:"projects,omitempty"`
+	// Results are the paths it returned most often, at most three
+	Results []string `json:"results,omitempty"`
+
+	hits    int
+	best    float64
+	results map[string]int
+}
+
+// PathStat counts how often a file was among the top hits.
+type PathStat struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+// Report is what rag_analytics returns.
+type Report struct {
+	Since    time.Time `json:"since"`
+	Mode     string    `json:"mode"`
+	Searches int       `json:"searches"`
+	Queries  int       `json:"unique_queries"`
+	// ZeroResults counts searches without hits, Weak those whose best hit
+	// scored below search.suggestions.min_score
+	ZeroResults int `json:"zero_result_searches"`
+	Weak        int `json:"weak_searches"`
+	// Top are the most frequent queries; Unanswered the most frequent ones
+	// without a hit scoring at least search.suggestions.min_score
+	Top        []*QueryStat `json:"top_queries"`
+	Unanswered []*QueryStat `json:"unanswered_queries"`
+	// TopResults are the files returned most often
+	TopResults []PathStat `json:"top_results"`
+}
+
+// Analyze aggregates the entries logged since, keeping limit items per list.
+func (l *Log) Analyze(since time.Time, limit int) (*Report, error) {
+	if l == nil {
+		return nil, fmt.Errorf("query logging is disabled (query_log.enabled)")
+	}
+	rep := &Report{Since: since.UTC(), Mode: l.conf.Mode, Top: []*QueryStat{}, Unanswered: []*QueryStat{}, TopResults: []PathStat{}}
+	all := map[string]*QueryStat{}
+	unanswered := map[string]*QueryStat{}
+	paths := map[string]int{}
+	add := func(m map[string]*QueryStat, e Entry) {
+		s := m[e.Query]
+		if s == nil {
+			s = &QueryStat{Query: e.Query, results: map[string]int{}}
+			m[e.Query] = s
+		}
+		s.Count++
+		s.hits += e.Hits
+		s.best += float64(e.BestScore)
+		if e.Time.After(s.LastSeen) {
+			s.LastSeen = e.Time
+		}
+		if e.Project != "" && !contains(s.Projects, e.Project) {
+			s.Projects = append(s.Projects, e.Project)
+		}
+		for _, p := range e.Results {
+			s.results[p]++
+		}
+	}
+	l.mu.Lock()
+	err := l.read(since, func(e Entry) {
+		rep.Searches++
+		add(all, e)
+		if e.Hits == 0 {
+			rep.ZeroResults++
+		} else if float64(e.BestScore) < l.weak {
+			rep.Weak++
+		}
+		if e.Hits == 0 || float64(e.BestScore) < l.weak {
+			add(unanswered, e)
+		}
+		for _, p := range e.Results {
+			paths[p]++
+		}
+	})
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	rep.Queries = len(all)
+	rep.Top = ranked(all, limit)
+	rep.Unanswered = ranked(unanswered, limit)
+	for p, n := range paths {
+		rep.TopResults = append(rep.TopResults, PathStat{Path: p, Count: n})
+	}
+	sort.Slice(rep.TopResults, func(i, j int) bool {
+		a, b := rep.TopResults[i], rep.TopResults[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Path < b.Path
+	})
+	rep.TopResults = rep.TopResults[:min(limit, len(rep.TopResults))]
+	return rep, nil
+}
+
+// ranked returns the limit most frequent queries of m, most recent first on
+// ties, with their averages filled in.
+func ranked(m map[string]*QueryStat, limit int) []*QueryStat {
+	out := make([]*QueryStat, 0, len(m))
+	for _, s := range m {
+		s.AvgHits = float64(s.hits) / float64(s.Count)
+		s.AvgBest = s.best / float64(s.Count)
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].LastSeen.After(out[j].LastSeen)
+	})
+	out = out[:min(limit, len(out))]
+	for _, s := range out {
+		s.Results = topKeys(s.results, 3)
+	}
+	return out
+}
+
+// topKeys returns the n keys of counts with the highest counts.
+func topKeys(counts map[string]int, n int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys[:min(n, len(keys))]
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+// Package quota meters what each caller retrieves through searches: the
+// hits and the bytes of their text within a window, as set by search.quota
+// or a tenant's own quota. A search that would go past a cap returns the
+// hits that still fit, best first, and reports what it left out.
+package quota
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+// Report tells a caller how its quota shaped a search. It is added to
+// search responses as "quota" whenever a quota applies.
+type Report struct {
+	// Truncated is set when hits were left out to stay within the quota
+	Truncated bool `json:"truncated"`
+	// Omitted counts the hits left out
+	Omitted int `json:"omitted,omitempty"`
+	// Limit names the cap that cut the hits: "max_results" or "max_kb"
+	Limit string `json:"limit,omitempty"`
+	// RemainingResults and RemainingBytes are left in the window; a nil
+	// one is not capped
+	RemainingResults *int `json:"remaining_results,omitempty"`
+	RemainingBytes   *int `json:"remaining_bytes,omitempty"`
+	// ResetsAt is when the window ends (RFC3339)
+	ResetsAt string `json:"resets_at"`
+
+	end time.Time
+}
+
+// RetryAfter is the number of seconds until the window ends, at least 1.
+func (r *Report) RetryAfter() int {
+	return max(1, int(math.Ceil(time.Until(r.end).Seconds())))
+}
+
+type window struct {
+	end     time.Time
+	results int
+	bytes   int
+}
+
+// Meter holds the windows of the callers seen. A nil Meter meters
+// nothing.
+type Meter struct {
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+// New returns an empty Meter.
+func New() *Meter {
+	return &Meter{windows: map[string]*window{}}
+}
+
+// Size is what a hit counts toward max_kb: the bytes of its text and
+// snippet.
+func Size(h ragvec.Hit) int {
+	return len(h.Text) + len(h.Snippet)
+}
+
+// Take charges caller for as many of hits as fit its quota q, in order,
+// and returns them with a report; a nil report means q caps nothing.
+func (m *Meter) Take(caller string, q cfg.QuotaConfig, hits []ragvec.Hit) ([]ragvec.Hit, *Report) {
+	if m == nil || !q.Enabled() {
+		return hits, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	w := m.windows[caller]
+	if w == nil || !now.Before(w.end) {
+		m.sweep(now)
+		w = &window{end: now.Add(time.Duration(q.WindowSec) * time.Second)}
+		m.windows[caller] = w
+	}
+
+	rep := &Report{ResetsAt: w.end.UTC().Format(time.RFC3339), end: w.end}
+	n := 0
+	for _, h := range hits {
+		if q.MaxResults > 0 && w.results+1 > q.MaxResults {
+			rep.Limit = "max_results"
+			break
+		}
+		size := Size(h)
+		if q.MaxKB > 0 && w.bytes+size > q.MaxKB*1024 {
+			rep.Limit = "max_kb"
+			break
+		}
+		w.results++
+		w.bytes += size
+		n++
+	}
+	if n < len(hits) {
+		rep.Truncated = true
+		rep.Omitted = len(hits) - n
+	}
+	if q.MaxResults > 0 {
+		left := q.MaxResults - w.results
+		rep.RemainingResults = &left
+	}
+	if q.MaxKB > 0 {
+		left := q.MaxKB*1024 - w.bytes
+		rep.RemainingBytes = &left
+	}
+	return hits[:n], rep
+}
+
+// sweep drops windows that ended, so callers that went away are
+// forgotten; m.mu is held.
+func (m *Meter) sweep(now time.Time) {
+	for k, w := range m.windows {
+		if !now.Before(w.end) {
+			delete(m.windows, k)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/Rhyanz46/mcp-service/internal/chunker"
 	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/stopwords"
 )
 
 type Doc struct {
@@ -29,14 +30,26 @@ type Inverted struct {
 	VocabSize int
 	DocByID   map[string]Doc
 	config    *cfg.Config
+	stop      stopwords.Set
 }
 
 var wordRE = regexp.MustCompile(`[A-Za-z0-9_\p{L}]+`)
 
-// Tokenize sederhana (lowercase + word char)
-func tokenize(s string) []string {
+// Tokenize sederhana (lowercase + word char), tanpa stopword
+func tokenize(s string, stop stopwords.Set) []string {
 	low := strings.ToLower(s)
-	return wordRE.FindAllString(low, -1)
+	words := wordRE.FindAllString(low, -1)
+	out := words[:0]
+	for _, w := range words {
+		if !stop.Contains(w) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func stopwordsFor(config *cfg.Config) stopwords.Set {
+	return stopwords.Load(config.Language.Stopwords, config.Language.ExtraStopwords, config.Language.DisableStopwords)
 }
 
 func loadDocsWithConfig(dir string, config *cfg.Config) ([]Doc, error) {
@@ -45,9 +58,10 @@ func loadDocsWithConfig(dir string, config *cfg.Config) ([]Doc, error) {
 	if err != nil {
 		return nil, err
 	}
+	stop := stopwordsFor(config)
 	var docs []Doc
 	for _, chunk := range chunks {
-		terms := tokenize(chunk.Text)
+		terms := tokenize(chunk.Text, stop)
 		docs = append(docs, Doc{ID: chunk.ID, Text: chunk.Text, Terms: terms})
 	}
 	return docs, nil
@@ -61,6 +75,7 @@ func buildIndex(docs []Doc, config *cfg.Config) *Inverted {
 		DocLen:  make(map[string]int),
 		DocByID: make(map[string]Doc),
 		config:  config,
+		stop:    stopwordsFor(config),
 	}
 	totalLen := 0
 	vocab := map[string]struct{}{}
@@ -148,7 +163,7 @@ type Hit struct {
 }
 
 func (idx *Inverted) Search(query string, k int) []Hit {
-	q := tokenize(query)
+	q := tokenize(query, idx.stop)
 	type pair struct {
 		id string
 		s  float64
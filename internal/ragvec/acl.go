@@ -0,0 +1,79 @@
+package ragvec
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/pathglob"
+)
+
+// aclRule is a compiled access.rules entry.
+type aclRule struct {
+	match  *pathglob.Set
+	labels []string
+}
+
+type aclRules []aclRule
+
+func compileACLRules(rules []cfg.ACLRule) (aclRules, error) {
+	out := make(aclRules, 0, len(rules))
+	for _, r := range rules {
+		set, err := pathglob.Compile([]string{r.Pattern})
+		if err != nil {
+			return nil, fmt.Errorf("access rule: %w", err)
+		}
+		out = append(out, aclRule{match: set, labels: NormalizeTags(r.Labels)})
+	}
+	return out, nil
+}
+
+// labels returns the labels of the rules matching path, a file under dir,
+// or one of the directories between them.
+func (rs aclRules) labels(dir, path string) []string {
+	if len(rs) == 0 {
+		return nil
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	var out []string
+	for _, r := range rs {
+		hit := r.match.Match(rel, false)
+		for d := rel; !hit; {
+			i := strings.LastIndexByte(d, '/')
+			if i < 0 {
+				break
+			}
+			d = d[:i]
+			hit = r.match.Match(d, true)
+		}
+		if hit {
+			out = append(out, r.labels...)
+		}
+	}
+	return NormalizeTags(out)
+}
+
+// chunkACL is the acl payload of a chunk: the labels of its run and its
+// file, or PublicLabel when there are none, so a tenant filter can match
+// unlabeled chunks with the same "any" condition as its own labels.
+func chunkACL(run, file []string) []string {
+	if acl := NormalizeTags(append(append([]string{}, run...), file...)); len(acl) > 0 {
+		return acl
+	}
+	return []string{cfg.PublicLabel}
+}
+
+// NormalizeACL is NormalizeTags for access labels given by a caller,
+// which may not name PublicLabel.
+func NormalizeACL(labels []string) ([]string, error) {
+	labels = NormalizeTags(labels)
+	if err := cfg.CheckLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
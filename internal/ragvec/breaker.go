@@ -0,0 +1,117 @@
+package ragvec
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+)
+
+// ErrVectorStoreUnavailable is returned without contacting Qdrant while the
+// circuit is open.
+var ErrVectorStoreUnavailable error = &apierr.Error{Category: apierr.Store, Component: "vector_store", Transient: true,
+	Err: errors.New("vector store unavailable (circuit open, retrying shortly)")}
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 10 * time.Second
+)
+
+// Circuit breaker states.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// CircuitBreaker opens after threshold consecutive failures and fails fast
+// until cooldown elapses. It then lets a single trial call through
+// (half-open): success closes the circuit, failure re-opens it.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     string
+	openedAt  time.Time
+	trial     bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, state: BreakerClosed}
+}
+
+// breakers shares one breaker per Qdrant base URL, since short-lived clients
+// (status probes) and the long-lived RAG client talk to the same server.
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*CircuitBreaker{}
+)
+
+func breakerFor(baseURL string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	if b, ok := breakers[baseURL]; ok {
+		return b
+	}
+	b := NewCircuitBreaker(threshold, cooldown)
+	breakers[baseURL] = b
+	return b
+}
+
+// Allow reports whether a call may proceed.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrVectorStoreUnavailable
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return nil
+	case BreakerHalfOpen:
+		if b.trial {
+			// A trial call is already in flight.
+			return ErrVectorStoreUnavailable
+		}
+		b.trial = true
+		return nil
+	}
+	return nil
+}
+
+// Record feeds the outcome of a call into the breaker.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if err == nil {
+		b.failures = 0
+		b.state = BreakerClosed
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// State returns the current state and consecutive failure count.
+func (b *CircuitBreaker) State() (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen, b.failures
+	}
+	return b.state, b.failures
+}
@@ -0,0 +1,144 @@
+package ragvec
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// Check is the outcome of one CheckConfig probe.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "ok", "warn" or "fail"
+	Detail string `json:"detail"`
+	// Fix suggests what to change when the check did not pass
+	Fix string `json:"fix,omitempty"`
+}
+
+func (c Check) withFix(fix string) Check {
+	c.Fix = fix
+	return c
+}
+
+func checkOK(name, format string, a ...any) Check {
+	return Check{Name: name, Status: "ok", Detail: fmt.Sprintf(format, a...)}
+}
+
+func checkWarn(name, format string, a ...any) Check {
+	return Check{Name: name, Status: "warn", Detail: fmt.Sprintf(format, a...)}
+}
+
+func checkFail(name, format string, a ...any) Check {
+	return Check{Name: name, Status: "fail", Detail: fmt.Sprintf(format, a...)}
+}
+
+// CheckConfig probes what a validated configuration depends on at runtime:
+// every embedding provider in the chain embeds a test string with its
+// credentials, the vector store is pinged, and the dimension the providers
+// produce is compared with the existing collection and the manifest. Nothing
+// is created or written.
+func CheckConfig(config *cfg.Config) []Check {
+	var out []Check
+	names := append([]string{config.Embedding.Provider}, config.Embedding.Fallback...)
+	dim := 0
+	for _, name := range names {
+		check := "embedding " + name
+		prov, err := newProvider(name, config)
+		if err != nil {
+			out = append(out, checkFail(check, "%v", err).withFix(providerFix(name, config)))
+			continue
+		}
+		start := time.Now()
+		vecs, err := prov.Embed([]string{"ping"})
+		if err != nil {
+			out = append(out, checkFail(check, "%s: %v", modelFor(name, config), err).withFix(providerFix(name, config)))
+			continue
+		}
+		got := 0
+		if len(vecs) > 0 {
+			got = len(vecs[0])
+		}
+		switch {
+		case got != prov.Dim():
+			out = append(out, checkFail(check, "%s returned %d dimensions but dim is set to %d", modelFor(name, config), got, prov.Dim()).
+				withFix(fmt.Sprintf("set embedding.%s.dim to %d", name, got)))
+		case dim != 0 && got != dim:
+			out = append(out, checkFail(check, "produces %d dimensions, %s produces %d; a failover chain needs one dimension", got, names[0], dim).
+				withFix("remove it from embedding.fallback or configure it for the same dimension"))
+		default:
+			out = append(out, checkOK(check, "%s, %d dimensions, %d ms", modelFor(name, config), got, time.Since(start).Milliseconds()))
+		}
+		if dim == 0 {
+			dim = got
+		}
+	}
+
+	store := NewStore(config, dim)
+	check := config.Store.Backend
+	if err := store.HealthCheck(); err != nil {
+		return append(out, checkFail(check, "%s is not reachable: %v", config.StoreURL(), err).withFix(storeFix(config)))
+	}
+	exists, err := store.CollectionExists()
+	switch {
+	case err != nil:
+		return append(out, checkFail(check, "%s: %v", config.StoreURL(), err).withFix(storeFix(config)))
+	case !exists:
+		out = append(out, checkOK(check, "%s reachable; collection %s will be created on first start", config.StoreURL(), config.CollectionName()))
+	default:
+		out = append(out, checkOK(check, "%s reachable; collection %s exists", config.StoreURL(), config.CollectionName()))
+		if dr, ok := store.(DimReporter); ok && dim > 0 {
+			have, err := dr.CollectionDim()
+			switch {
+			case err != nil:
+				out = append(out, checkWarn("dimension", "could not read the vector size of %s: %v", config.CollectionName(), err))
+			case have == 0:
+				out = append(out, checkWarn("dimension", "%s does not report the vector size of %s", config.Store.Backend, config.CollectionName()))
+			case have != dim:
+				out = append(out, checkFail("dimension", "collection %s stores %d-dimensional vectors but the embedding provider produces %d", config.CollectionName(), have, dim).
+					withFix("point qdrant.collection at a new collection and re-index, or switch back to the provider the collection was built with"))
+			default:
+				out = append(out, checkOK("dimension", "collection %s and provider agree on %d dimensions", config.CollectionName(), dim))
+			}
+		}
+	}
+
+	m := newManifestStore(config.Indexing.ManifestPath, config.CollectionName()).snapshot()
+	roots := make([]string, 0, len(m.Roots))
+	for k, e := range m.Roots {
+		if dim > 0 && e.Dim > 0 && e.Dim != dim {
+			roots = append(roots, fmt.Sprintf("%s (%d)", k, e.Dim))
+		}
+	}
+	sort.Strings(roots)
+	if len(roots) > 0 {
+		out = append(out, checkWarn("manifest", "indexed with another dimension than the provider's %d: %v", dim, roots).
+			withFix("re-index these roots with rag_index"))
+	}
+	return out
+}
+
+// providerFix suggests how to get an embedding provider working.
+func providerFix(name string, config *cfg.Config) string {
+	switch {
+	case name == "openai":
+		return "set OPENAI_API_KEY (or embedding.openai.api_key) to a valid key and check access to api.openai.com, or use the local provider"
+	case config.Embedding.Local.Engine == "onnx":
+		return "check embedding.local.onnx.model_path, vocab_path and runtime_lib, and that the binary was built with -tags onnx"
+	}
+	return "check the embedding.local section"
+}
+
+// storeFix suggests how to reach the configured vector store.
+func storeFix(config *cfg.Config) string {
+	switch config.Store.Backend {
+	case "redis":
+		return "start Redis Stack (docker run -p 6379:6379 redis/redis-stack-server) or fix store.redis.url"
+	case "opensearch":
+		return "start OpenSearch 2.x with the k-NN plugin or fix store.opensearch.url and credentials"
+	case "memory":
+		return "check that store.memory.path is readable"
+	}
+	return "start Qdrant (make start-qdrant, or docker run -p 6333:6333 qdrant/qdrant) or fix qdrant.url; -no-qdrant starts the server without it"
+}
@@ -0,0 +1,51 @@
+package ragvec
+
+// ChunkRef names an indexed chunk by its file and position in it, which
+// is what its point ID is derived from (see chunkID).
+type ChunkRef struct {
+	Path     string `json:"path"`
+	Position int    `json:"position"`
+}
+
+// GetChunks returns the chunks refs name, in the order of refs. Chunks
+// that are gone, soft-deleted or expired, or that carry none of the labels
+// of acl (when it is not nil) are left out, as a search would leave them
+// out.
+func (r *VecRAG) GetChunks(refs []ChunkRef, acl []string) ([]Hit, error) {
+	base, err := filterConditions(SearchFilter{ACL: acl}.storeFilter()["must"])
+	if err != nil {
+		return nil, err
+	}
+	found := map[ChunkRef]Hit{}
+	done := map[string]bool{}
+	for _, ref := range refs {
+		if done[ref.Path] {
+			continue
+		}
+		done[ref.Path] = true
+		must := append(append([]map[string]any{}, base...), map[string]any{"key": "path", "match": map[string]any{"value": ref.Path}})
+		filter := searchable(liveFilter(map[string]any{"must": must}))
+		var offset any
+		for {
+			pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offset, filter)
+			if err != nil {
+				return nil, err
+			}
+			for _, pt := range pts {
+				h := hitFromPoint(SearchHit{ID: pt.ID, Payload: pt.Payload})
+				found[ChunkRef{Path: h.Path, Position: h.Position}] = h
+			}
+			if next == nil {
+				break
+			}
+			offset = next
+		}
+	}
+	out := make([]Hit, 0, len(refs))
+	for _, ref := range refs {
+		if h, ok := found[ref]; ok {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,295 @@
+package ragvec
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// chunkCacheVersion changes whenever cached chunks would no longer match
+// what chunking produces, invalidating every cache.
+const chunkCacheVersion = 4
+
+// fileStamp tells cheaply whether a file changed.
+type fileStamp struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+func fileStampOf(info os.FileInfo) fileStamp {
+	return fileStamp{Size: info.Size(), ModTime: info.ModTime().UTC()}
+}
+
+func (s fileStamp) matches(info os.FileInfo) bool {
+	return s.Size == info.Size() && s.ModTime.Equal(info.ModTime())
+}
+
+// cachedFile is what the chunk cache knows of one file on disk.
+type cachedFile struct {
+	fileStamp
+	Hash string `json:"hash"`
+	// Images stamps the images the file references; their chunks are
+	// cached with the file's
+	Images map[string]fileStamp `json:"images,omitempty"`
+}
+
+// cachedChunks are the chunks of one file content, as stored per hash.
+// They were through the secrets check and content filters already.
+type cachedChunks struct {
+	Chunks []cachedChunk `json:"chunks"`
+	Images []cachedChunk `json:"images,omitempty"`
+}
+
+// cachedChunk is a checked chunk with the kinds of secrets and redactions
+// found in it.
+type cachedChunk struct {
+	chunker.Chunk
+	Secrets  []string `json:"secrets,omitempty"`
+	Redacted []string `json:"redacted,omitempty"`
+}
+
+func cacheChunks(batch []preparedChunk) []cachedChunk {
+	out := make([]cachedChunk, len(batch))
+	for i, c := range batch {
+		out[i] = cachedChunk{Chunk: c.Chunk, Secrets: c.secrets, Redacted: c.redacted}
+	}
+	return out
+}
+
+// prepared returns the chunks and image chunks ready to write, without
+// checking them again.
+func (cs cachedChunks) prepared() (chunks, images []preparedChunk) {
+	for _, c := range cs.Chunks {
+		chunks = append(chunks, c.prepared())
+	}
+	for _, c := range cs.Images {
+		images = append(images, c.prepared())
+	}
+	return chunks, images
+}
+
+func (c cachedChunk) prepared() preparedChunk {
+	return preparedChunk{Chunk: c.Chunk, secrets: c.Secrets, redacted: c.Redacted, checked: true}
+}
+
+// chunkCache keeps the chunks of indexed files under indexing.chunk_cache.dir
+// so that indexing an unchanged file again skips reading, converting,
+// splitting, summarizing, OCR, the secrets check and the content filters.
+// Chunks are stored once per content hash in a directory per chunking and
+// filter settings, so changing them starts afresh; an index maps file paths
+// to the hash and stamp they had. Only the owner may read the cache, and
+// with store.encryption the chunk files are sealed with its key.
+type chunkCache struct {
+	mu    sync.Mutex
+	dir   string
+	aead  cipher.AEAD // nil without store.encryption
+	files map[string]cachedFile
+	// refs counts the files per hash, to drop chunks no file has anymore
+	refs  map[string]int
+	dirty bool
+}
+
+// newChunkCache opens the cache of the current chunking settings and
+// content filters, or returns nil when the cache is disabled.
+func newChunkCache(config *cfg.Config, filters []ContentFilter) *chunkCache {
+	cc := config.Indexing.ChunkCache
+	if !cc.Enabled {
+		return nil
+	}
+	c := &chunkCache{dir: filepath.Join(cc.Dir, chunkSettings(config, filters)), files: map[string]cachedFile{}, refs: map[string]int{}}
+	if key := config.Store.Encryption.Key; key != "" {
+		var err error
+		if c.aead, err = newAEAD(key); err != nil {
+			// Never fall back to storing clear text
+			log.Printf("Warning: chunk cache disabled: %v", err)
+			return nil
+		}
+	}
+	if b, err := os.ReadFile(c.indexPath()); err == nil {
+		if err := json.Unmarshal(b, &c.files); err != nil {
+			log.Printf("Warning: ignoring unreadable chunk cache index %s: %v", c.indexPath(), err)
+			c.files = map[string]cachedFile{}
+		}
+	}
+	for _, f := range c.files {
+		c.refs[f.Hash]++
+	}
+	return c
+}
+
+// chunkSettings fingerprints the settings that shape chunks, including
+// the secrets check and content filters they passed and whether they are
+// encrypted.
+func chunkSettings(config *cfg.Config, filters []ContentFilter) string {
+	ix := config.Indexing
+	names := make([]string, len(filters))
+	for i, f := range filters {
+		names[i] = f.Name()
+	}
+	b, _ := json.Marshal(struct {
+		Version                 int
+		ChunkSize, ChunkOverlap int
+		Chunking                cfg.ChunkingConfig
+		FileTypes               any
+		Email                   any
+		Images                  any
+		Summaries               any
+		TOC                     any
+		Secrets                 any
+		Filters                 any
+		FilterNames             []string
+		Encrypted               bool
+	}{chunkCacheVersion, ix.ChunkSize, ix.ChunkOverlap, config.Chunking, ix.FileTypes, ix.Email, ix.Images, ix.Summaries, ix.TOC,
+		ix.Secrets, ix.Filters, names, config.Store.Encryption.Key != ""})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (c *chunkCache) indexPath() string { return filepath.Join(c.dir, "index.json") }
+
+func (c *chunkCache) chunksPath(hash string) string { return filepath.Join(c.dir, hash+".json") }
+
+// get returns the cached chunks of path when the file and the images it
+// references are as they were cached.
+func (c *chunkCache) get(path string, info os.FileInfo) (cachedChunks, bool) {
+	c.mu.Lock()
+	f, ok := c.files[path]
+	c.mu.Unlock()
+	if !ok || !f.matches(info) {
+		return cachedChunks{}, false
+	}
+	return c.load(f)
+}
+
+// match returns the cached chunks of path when its content, of hash, is
+// the one cached although the file was touched, e.g. by a checkout.
+func (c *chunkCache) match(path, hash string) (cachedChunks, bool) {
+	c.mu.Lock()
+	f, ok := c.files[path]
+	c.mu.Unlock()
+	if !ok || f.Hash != hash {
+		return cachedChunks{}, false
+	}
+	return c.load(f)
+}
+
+func (c *chunkCache) load(f cachedFile) (cachedChunks, bool) {
+	for img, stamp := range f.Images {
+		info, err := os.Stat(img)
+		if err != nil || !stamp.matches(info) {
+			return cachedChunks{}, false
+		}
+	}
+	var out cachedChunks
+	b, err := os.ReadFile(c.chunksPath(f.Hash))
+	if err == nil {
+		b, err = c.open(b)
+	}
+	if err != nil || json.Unmarshal(b, &out) != nil || len(out.Chunks) == 0 {
+		return cachedChunks{}, false
+	}
+	return out, true
+}
+
+// seal encrypts the contents of a chunk file when the cache has a key.
+func (c *chunkCache) seal(b []byte) ([]byte, error) {
+	if c.aead == nil {
+		return b, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, b, []byte("chunk_cache")), nil
+}
+
+func (c *chunkCache) open(b []byte) ([]byte, error) {
+	if c.aead == nil {
+		return b, nil
+	}
+	n := c.aead.NonceSize()
+	if len(b) < n {
+		return nil, errors.New("malformed chunk cache file")
+	}
+	return c.aead.Open(nil, b[:n], b[n:], []byte("chunk_cache"))
+}
+
+// put caches the chunks of the file at path. Files without chunks are not
+// cached.
+func (c *chunkCache) put(path string, cs cachedChunks) {
+	if len(cs.Chunks) == 0 {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	f := cachedFile{fileStamp: fileStampOf(info), Hash: cs.Chunks[0].FileHash}
+	for _, img := range cs.Images {
+		if info, err := os.Stat(img.Path); err == nil {
+			if f.Images == nil {
+				f.Images = map[string]fileStamp{}
+			}
+			f.Images[img.Path] = fileStampOf(info)
+		}
+	}
+	b, err := json.Marshal(cs)
+	if err == nil {
+		b, err = c.seal(b)
+	}
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		log.Printf("Warning: chunk cache: %v", err)
+		return
+	}
+	if err := os.WriteFile(c.chunksPath(f.Hash), b, 0600); err != nil {
+		log.Printf("Warning: chunk cache: %v", err)
+		return
+	}
+	if old, ok := c.files[path]; ok {
+		if c.refs[old.Hash]--; c.refs[old.Hash] <= 0 && old.Hash != f.Hash {
+			delete(c.refs, old.Hash)
+			_ = os.Remove(c.chunksPath(old.Hash))
+		}
+	}
+	c.files[path] = f
+	c.refs[f.Hash]++
+	c.dirty = true
+}
+
+// save writes the index when it changed.
+func (c *chunkCache) save() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return
+	}
+	b, err := json.Marshal(c.files)
+	if err == nil {
+		tmp := c.indexPath() + ".tmp"
+		if err = os.WriteFile(tmp, b, 0600); err == nil {
+			err = os.Rename(tmp, c.indexPath())
+		}
+	}
+	if err != nil {
+		log.Printf("Warning: failed to save the chunk cache index: %v", err)
+		return
+	}
+	c.dirty = false
+}
@@ -0,0 +1,129 @@
+package ragvec
+
+import (
+	"math"
+	"strconv"
+)
+
+// Describe identifies the index behind r: collection and embedding stamp.
+func (r *VecRAG) Describe() map[string]any {
+	provider := r.config.Embedding.Provider
+	return map[string]any{
+		"collection": r.config.CollectionName(),
+		"provider":   provider,
+		"model":      modelFor(provider, r.config),
+		"dim":        r.embed.Dim(),
+	}
+}
+
+// rboPersistence weights the top of the rankings in rank-biased overlap; at
+// 0.9 the first 10 ranks carry about 86% of the weight.
+const rboPersistence = 0.9
+
+// CompareResults measures how much two ranked hit lists agree. Hits are
+// identified by file path and chunk position rather than point ID, since IDs
+// differ between collections indexed separately.
+func CompareResults(a, b []Hit) map[string]any {
+	ka, kb := hitKeys(a), hitKeys(b)
+	pa, pb := rankedPaths(a, len(a)), rankedPaths(b, len(b))
+	depth := max(len(ka), len(kb))
+	var top1 bool
+	if len(ka) > 0 && len(kb) > 0 {
+		top1 = ka[0] == kb[0]
+	}
+	common := intersect(ka, kb)
+	var overlapAtK float64
+	if depth > 0 {
+		overlapAtK = float64(len(common)) / float64(depth)
+	}
+	return map[string]any{
+		"common_chunks": len(common),
+		"overlap_at_k":  round4(overlapAtK),
+		"jaccard":       round4(jaccard(ka, kb)),
+		"path_jaccard":  round4(jaccard(pa, pb)),
+		"rbo":           round4(rankBiasedOverlap(ka, kb, rboPersistence)),
+		"top1_match":    top1,
+		"only_a_paths":  difference(pa, pb),
+		"only_b_paths":  difference(pb, pa),
+	}
+}
+
+func hitKeys(hits []Hit) []string {
+	out := make([]string, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, h.Path+"#"+strconv.Itoa(h.Position))
+	}
+	return out
+}
+
+func intersect(a, b []string) []string {
+	in := map[string]bool{}
+	for _, s := range b {
+		in[s] = true
+	}
+	var out []string
+	for _, s := range a {
+		if in[s] {
+			out = append(out, s)
+			delete(in, s)
+		}
+	}
+	return out
+}
+
+func difference(a, b []string) []string {
+	in := map[string]bool{}
+	for _, s := range b {
+		in[s] = true
+	}
+	out := []string{}
+	for _, s := range a {
+		if !in[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func jaccard(a, b []string) float64 {
+	union := map[string]bool{}
+	for _, s := range a {
+		union[s] = true
+	}
+	for _, s := range b {
+		union[s] = true
+	}
+	if len(union) == 0 {
+		return 1
+	}
+	return float64(len(intersect(a, b))) / float64(len(union))
+}
+
+// rankBiasedOverlap is the extrapolated RBO of Webber et al. (2010) for two
+// rankings truncated at the same or different depths; 1 means identical.
+func rankBiasedOverlap(a, b []string, p float64) float64 {
+	depth := max(len(a), len(b))
+	if depth == 0 {
+		return 1
+	}
+	seenA, seenB := map[string]bool{}, map[string]bool{}
+	var overlap int
+	var sum, agree float64
+	for d := 1; d <= depth; d++ {
+		if d <= len(a) {
+			if seenB[a[d-1]] {
+				overlap++
+			}
+			seenA[a[d-1]] = true
+		}
+		if d <= len(b) {
+			if seenA[b[d-1]] {
+				overlap++
+			}
+			seenB[b[d-1]] = true
+		}
+		agree = float64(overlap) / float64(d)
+		sum += math.Pow(p, float64(d-1)) * agree
+	}
+	return (1-p)*sum + math.Pow(p, float64(depth))*agree
+}
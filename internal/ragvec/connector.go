@@ -0,0 +1,157 @@
+package ragvec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/connectors"
+)
+
+// SyncResult reports what SyncConnector did.
+type SyncResult struct {
+	Connector string `json:"connector"`
+	Full      bool   `json:"full"`
+	Listed    int    `json:"listed"`
+	Indexed   int    `json:"indexed"`   // documents new or changed since the last sync
+	Unchanged int    `json:"unchanged"` // documents whose version was already indexed
+	Removed   int    `json:"removed"`   // documents gone from the source (full syncs only)
+	Chunks    int    `json:"chunks"`    // chunks written
+	// Errors lists documents that could not be fetched or indexed; their
+	// earlier chunks are kept
+	Errors []string `json:"errors,omitempty"`
+}
+
+// connectorRoot is the manifest key of a connector's sync state.
+func connectorRoot(name string) string { return "connector:" + name }
+
+// SyncConnector indexes the documents of a configured connector. Each
+// document is stored under its URL with url and title payloads plus any the
+// connector adds, and its version is recorded in the manifest so unchanged
+// documents are skipped next time. An incremental sync asks the source for
+// documents changed since the previous sync; a full one lists everything and
+// also deletes the chunks of documents that disappeared. The first sync is
+// always full.
+func (r *VecRAG) SyncConnector(conf cfg.ConnectorConfig, full bool, opts IngestOptions) (SyncResult, error) {
+	res := SyncResult{Connector: conf.Name}
+	conn, err := connectors.New(conf, r.config)
+	if err != nil {
+		return res, err
+	}
+	opts.ACL = append(append([]string{}, opts.ACL...), conf.ACL...)
+	opts.prepare()
+	if opts.Project == "" {
+		opts.Project = conf.Project
+	}
+	if opts.Project == "" {
+		opts.Project = conf.Name
+	}
+	defer r.projects.invalidate()
+
+	root := connectorRoot(conf.Name)
+	prev := r.manifest.snapshot().Roots[root]
+	if prev == nil {
+		full = true
+	}
+	res.Full = full
+
+	// The manifest maps each document path to "id:version"
+	type indexed struct{ path, version string }
+	known := map[string]indexed{}
+	chunks := 0
+	if prev != nil {
+		chunks = prev.Chunks
+		for p, v := range prev.FileHashes {
+			if id, version, ok := strings.Cut(v, ":"); ok {
+				known[id] = indexed{p, version}
+			}
+		}
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	var refs []connectors.Ref
+	if full {
+		refs, err = conn.List(ctx)
+	} else {
+		refs, err = conn.Incremental(ctx, prev.IndexedAt)
+	}
+	if err != nil {
+		return res, err
+	}
+	res.Listed = len(refs)
+
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		seen[ref.ID] = true
+		old, ok := known[ref.ID]
+		if ok && old.version == ref.Version {
+			res.Unchanged++
+			continue
+		}
+		doc, err := conn.Fetch(ctx, ref)
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", ref.ID, err))
+			continue
+		}
+		if ok && old.path != doc.URL {
+			// Renamed pages move to a new URL
+			n, err := r.deleteWhere(matchFilter(map[string]string{"path": old.path}))
+			chunks -= n
+			if err != nil {
+				return res, err
+			}
+		}
+		page := &fetchedPage{
+			file: chunker.File{Path: doc.URL, Text: doc.Text, ModTime: doc.Modified},
+			meta: map[string]any{"url": doc.URL},
+		}
+		if doc.Title != "" {
+			page.meta["title"] = doc.Title
+		}
+		for k, v := range doc.Meta {
+			page.meta[k] = v
+		}
+		var pr URLResult
+		replaced, err := r.countWhere(matchFilter(map[string]string{"path": doc.URL}))
+		if err == nil {
+			err = r.indexPage(page, &pr, opts)
+		}
+		chunks += pr.Chunks - replaced
+		res.Chunks += pr.Chunks
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", ref.ID, err))
+			continue
+		}
+		known[ref.ID] = indexed{doc.URL, doc.Version}
+		res.Indexed++
+	}
+
+	if full {
+		for id, old := range known {
+			if seen[id] {
+				continue
+			}
+			n, err := r.deleteWhere(matchFilter(map[string]string{"path": old.path}))
+			chunks -= n
+			if err != nil {
+				return res, err
+			}
+			delete(known, id)
+			res.Removed++
+		}
+	}
+
+	entry := r.rootEntry(root, start, opts)
+	entry.FileHashes = make(map[string]string, len(known))
+	for id, d := range known {
+		entry.FileHashes[d.path] = id + ":" + d.version
+	}
+	entry.Files = len(entry.FileHashes)
+	entry.Chunks = max(chunks, 0)
+	r.saveRoot(entry)
+	return res, nil
+}
@@ -0,0 +1,54 @@
+package ragvec
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// newContextHeader compiles indexing.context_headers.template, nil when
+// context headers are off.
+func newContextHeader(config *cfg.Config) (*template.Template, error) {
+	ch := config.Indexing.ContextHeaders
+	if !ch.Enabled {
+		return nil, nil
+	}
+	t, err := ch.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("context_headers template: %w", err)
+	}
+	return t, nil
+}
+
+// embedText is the text embedded for c: its context header and a blank line
+// before the chunk text. Summaries and tables of contents already name
+// their file and are embedded as they are.
+func (r *VecRAG) embedText(c preparedChunk, project string, meta map[string]any) string {
+	if r.header == nil || c.Kind != "" {
+		return c.Text
+	}
+	dir := filepath.Base(filepath.Dir(c.Path))
+	base := filepath.Base(c.Path)
+	file := base
+	if dir != "." && dir != "/" && dir != "" {
+		file = dir + "/" + base
+	}
+	var b strings.Builder
+	err := r.header.Execute(&b, cfg.ContextHeader{
+		File:     file,
+		Path:     c.Path,
+		Basename: base,
+		Project:  project,
+		Section:  c.Section,
+		Chapter:  c.Chapter,
+		Title:    toStr(meta["title"]),
+	})
+	header := strings.TrimSpace(b.String())
+	if err != nil || header == "" {
+		return c.Text
+	}
+	return header + "\n\n" + c.Text
+}
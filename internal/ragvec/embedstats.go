@@ -0,0 +1,122 @@
+package ragvec
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statsWindow is the number of recent embedding calls kept for error rates.
+const statsWindow = 100
+
+type embedOutcome struct {
+	took time.Duration
+	err  bool
+}
+
+// embedStats tracks embedding call outcomes so operators can tell a slow or
+// throttled provider apart from a slow vector store.
+type embedStats struct {
+	mu          sync.Mutex
+	calls       int
+	errors      int
+	recent      []embedOutcome // ring buffer of the last statsWindow calls
+	next        int
+	lastError   string
+	lastErrorAt time.Time
+}
+
+func (s *embedStats) record(took time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	o := embedOutcome{took: took, err: err != nil}
+	if err != nil {
+		s.errors++
+		s.lastError = err.Error()
+		s.lastErrorAt = time.Now()
+	}
+	if len(s.recent) < statsWindow {
+		s.recent = append(s.recent, o)
+		return
+	}
+	s.recent[s.next] = o
+	s.next = (s.next + 1) % statsWindow
+}
+
+func (s *embedStats) snapshot() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := map[string]any{
+		"calls":  s.calls,
+		"errors": s.errors,
+	}
+	if n := len(s.recent); n > 0 {
+		var failed int
+		var total time.Duration
+		for _, o := range s.recent {
+			if o.err {
+				failed++
+			}
+			total += o.took
+		}
+		out["recent_calls"] = n
+		out["recent_error_rate"] = float64(failed) / float64(n)
+		out["recent_avg_latency_ms"] = (total / time.Duration(n)).Milliseconds()
+	}
+	if s.lastError != "" {
+		out["last_error"] = s.lastError
+		out["last_error_at"] = s.lastErrorAt
+	}
+	return out
+}
+
+// RateLimitReporter is implemented by providers that expose the quota
+// reported on their most recent API response.
+type RateLimitReporter interface {
+	RateLimits() map[string]any
+}
+
+// rateLimitInfo keeps the x-ratelimit-* headers of the last response.
+type rateLimitInfo struct {
+	mu      sync.Mutex
+	headers map[string]string
+	status  int
+	at      time.Time
+}
+
+func (r *rateLimitInfo) capture(res *http.Response) {
+	h := map[string]string{}
+	for k, v := range res.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-ratelimit-") && len(v) > 0 {
+			h[strings.TrimPrefix(lk, "x-ratelimit-")] = v[0]
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.headers = h
+	r.status = res.StatusCode
+	r.at = time.Now()
+}
+
+func (r *rateLimitInfo) snapshot() map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.at.IsZero() {
+		return nil
+	}
+	out := map[string]any{
+		"last_status": r.status,
+		"throttled":   r.status == http.StatusTooManyRequests,
+		"observed_at": r.at,
+	}
+	for k, v := range r.headers {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,255 @@
+package ragvec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// encryptedPrefix marks a payload value sealed by encryptedStore. Values
+// without it are read as they are, so a collection indexed before
+// encryption was enabled stays searchable until it is re-indexed.
+const encryptedPrefix = "enc:v1:"
+
+// encryptedFields are the payload keys holding chunk text, and the subject
+// and sender of emails. Everything else stays in clear so the store can
+// still filter on it.
+var encryptedFields = []string{"preview", "text", "title", "from"}
+
+// encryptedStore seals the chunk text of payloads with AES-GCM before they
+// reach the wrapped store and opens it again in search and scroll results.
+// The field name is authenticated too, so a sealed preview cannot be passed
+// off as a text.
+type encryptedStore struct {
+	VectorStore
+	aead cipher.AEAD
+	err  error // a bad key fails every write and read instead of storing clear text
+}
+
+// encryptStore wraps s when conf has a key, or returns s.
+func encryptStore(s VectorStore, conf cfg.EncryptionConfig) VectorStore {
+	if conf.Key == "" {
+		return s
+	}
+	e := &encryptedStore{VectorStore: s}
+	e.aead, e.err = newAEAD(conf.Key)
+	return e
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("store encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("store encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func (e *encryptedStore) seal(field, value string) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := e.aead.Seal(nonce, nonce, []byte(value), []byte(field))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (e *encryptedStore) open(field, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value[len(encryptedPrefix):])
+	if err != nil || len(sealed) < e.aead.NonceSize() {
+		return "", fmt.Errorf("decrypt payload %s: malformed value", field)
+	}
+	n := e.aead.NonceSize()
+	plain, err := e.aead.Open(nil, sealed[:n], sealed[n:], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("decrypt payload %s: %w (was it written with another key?)", field, err)
+	}
+	return string(plain), nil
+}
+
+// sealPayload returns a copy of p with its text fields sealed.
+func (e *encryptedStore) sealPayload(p map[string]any) (map[string]any, error) {
+	out := make(map[string]any, len(p))
+	for k, v := range p {
+		out[k] = v
+	}
+	for _, field := range encryptedFields {
+		s, ok := out[field].(string)
+		if !ok || strings.HasPrefix(s, encryptedPrefix) {
+			continue
+		}
+		sealed, err := e.seal(field, s)
+		if err != nil {
+			return nil, err
+		}
+		out[field] = sealed
+	}
+	return out, nil
+}
+
+// openPayload returns p with its text fields decrypted. p itself is left
+// alone since stores such as Memory return their own maps.
+func (e *encryptedStore) openPayload(p map[string]any) (map[string]any, error) {
+	out, copied := p, false
+	for _, field := range encryptedFields {
+		s, ok := p[field].(string)
+		if !ok || !strings.HasPrefix(s, encryptedPrefix) {
+			continue
+		}
+		plain, err := e.open(field, s)
+		if err != nil {
+			return nil, err
+		}
+		if !copied {
+			out, copied = make(map[string]any, len(p)), true
+			for k, v := range p {
+				out[k] = v
+			}
+		}
+		out[field] = plain
+	}
+	return out, nil
+}
+
+func (e *encryptedStore) UpsertPoints(ids []string, vecs [][]float32, payloads []map[string]any) error {
+	if e.err != nil {
+		return e.err
+	}
+	sealed := make([]map[string]any, len(payloads))
+	for i, p := range payloads {
+		var err error
+		if sealed[i], err = e.sealPayload(p); err != nil {
+			return err
+		}
+	}
+	return e.VectorStore.UpsertPoints(ids, vecs, sealed)
+}
+
+func (e *encryptedStore) SetPayload(filter map[string]any, patch map[string]any) error {
+	if e.err != nil {
+		return e.err
+	}
+	sealed, err := e.sealPayload(patch)
+	if err != nil {
+		return err
+	}
+	return e.VectorStore.SetPayload(filter, sealed)
+}
+
+func (e *encryptedStore) SetPayloadByIDs(ids []any, patch map[string]any) error {
+	if e.err != nil {
+		return e.err
+	}
+	sealed, err := e.sealPayload(patch)
+	if err != nil {
+		return err
+	}
+	return e.VectorStore.SetPayloadByIDs(ids, sealed)
+}
+
+func (e *encryptedStore) Search(vec []float32, k int, filter map[string]any, params SearchParams) ([]SearchHit, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	hits, err := e.VectorStore.Search(vec, k, filter, params)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		if hits[i].Payload, err = e.openPayload(hits[i].Payload); err != nil {
+			return nil, err
+		}
+	}
+	return hits, nil
+}
+
+func (e *encryptedStore) ScrollPoints(limit int, offset any) ([]ScrollPoint, any, error) {
+	return e.ScrollPointsWithFilter(limit, offset, nil)
+}
+
+func (e *encryptedStore) ScrollPointsWithFilter(limit int, offset any, filter map[string]any) ([]ScrollPoint, any, error) {
+	if e.err != nil {
+		return nil, nil, e.err
+	}
+	pts, next, err := e.VectorStore.ScrollPointsWithFilter(limit, offset, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range pts {
+		if pts[i].Payload, err = e.openPayload(pts[i].Payload); err != nil {
+			return nil, nil, err
+		}
+	}
+	return pts, next, nil
+}
+
+// The wrapper does not implement TextSearcher: ranking by the stored text
+// would only see ciphertext. The other optional interfaces pass through.
+
+func (e *encryptedStore) CollectionDim() (int, error) {
+	if dr, ok := e.VectorStore.(DimReporter); ok {
+		return dr.CollectionDim()
+	}
+	return 0, nil
+}
+
+func (e *encryptedStore) withRequestID(id string) VectorStore {
+	c := *e
+	c.VectorStore = StoreWithRequestID(e.VectorStore, id)
+	return &c
+}
+
+// Snapshots are taken by the store and keep the payloads sealed.
+
+func (e *encryptedStore) createSnapshot(timeout time.Duration) (Snapshot, error) {
+	ss, ok := e.VectorStore.(snapshotStore)
+	if !ok {
+		return Snapshot{}, ErrSnapshotsUnsupported
+	}
+	return ss.createSnapshot(timeout)
+}
+
+func (e *encryptedStore) listSnapshots() ([]Snapshot, error) {
+	ss, ok := e.VectorStore.(snapshotStore)
+	if !ok {
+		return nil, ErrSnapshotsUnsupported
+	}
+	return ss.listSnapshots()
+}
+
+func (e *encryptedStore) downloadSnapshot(name string, timeout time.Duration) (io.ReadCloser, int64, error) {
+	ss, ok := e.VectorStore.(snapshotStore)
+	if !ok {
+		return nil, 0, ErrSnapshotsUnsupported
+	}
+	return ss.downloadSnapshot(name, timeout)
+}
+
+func (e *encryptedStore) deleteSnapshot(name string) error {
+	ss, ok := e.VectorStore.(snapshotStore)
+	if !ok {
+		return ErrSnapshotsUnsupported
+	}
+	return ss.deleteSnapshot(name)
+}
+
+func (e *encryptedStore) uploadSnapshot(name string, r io.Reader, timeout time.Duration) error {
+	ss, ok := e.VectorStore.(snapshotStore)
+	if !ok {
+		return ErrSnapshotsUnsupported
+	}
+	return ss.uploadSnapshot(name, r, timeout)
+}
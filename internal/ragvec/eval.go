@@ -0,0 +1,206 @@
+package ragvec
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EvalCase is one line of an evaluation file: a query and the paths that a
+// good retrieval should return. Expected paths match a hit when they are
+// equal to its path or a trailing path segment of it, so both absolute and
+// repository-relative paths work.
+type EvalCase struct {
+	Query         string   `json:"query"`
+	ExpectedPaths []string `json:"expected_paths"`
+	Project       string   `json:"project,omitempty"`
+	ProjectPrefix string   `json:"project_prefix,omitempty"`
+}
+
+// EvalConfig is a named set of search parameters to evaluate.
+type EvalConfig struct {
+	Name   string       `json:"name"`
+	Params SearchParams `json:"params"`
+}
+
+// EvalQuery reports how one case fared under one configuration.
+type EvalQuery struct {
+	Query     string   `json:"query"`
+	Recall    float64  `json:"recall"`
+	RR        float64  `json:"reciprocal_rank"`
+	NDCG      float64  `json:"ndcg"`
+	Retrieved []string `json:"retrieved"`
+	Missed    []string `json:"missed,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// EvalReport aggregates metrics for one configuration. Metrics are computed
+// over distinct file paths in rank order, averaged across cases; failed
+// searches count as zero.
+type EvalReport struct {
+	Config    string       `json:"config"`
+	Params    SearchParams `json:"params"`
+	K         int          `json:"k"`
+	Cases     int          `json:"cases"`
+	Errors    int          `json:"errors"`
+	RecallAtK float64      `json:"recall_at_k"`
+	MRR       float64      `json:"mrr"`
+	NDCG      float64      `json:"ndcg_at_k"`
+	AvgMs     float64      `json:"avg_latency_ms"`
+	Queries   []EvalQuery  `json:"queries,omitempty"`
+}
+
+// LoadEvalCases reads a JSONL evaluation file. Blank lines and lines
+// starting with # are skipped.
+func LoadEvalCases(path string) ([]EvalCase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cases []EvalCase
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		t := strings.TrimSpace(sc.Text())
+		if t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+		var c EvalCase
+		if err := json.Unmarshal([]byte(t), &c); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if strings.TrimSpace(c.Query) == "" || len(c.ExpectedPaths) == 0 {
+			return nil, fmt.Errorf("%s:%d: query and expected_paths are required", path, line)
+		}
+		cases = append(cases, c)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("%s: no evaluation cases", path)
+	}
+	return cases, nil
+}
+
+// Evaluate runs every case under each configuration and reports recall@k,
+// MRR and nDCG@k. With no configurations the configured search defaults are
+// evaluated. details keeps the per-query breakdown in the reports. acl
+// limits the searches as SearchFilter.ACL does.
+func (r *VecRAG) Evaluate(cases []EvalCase, k int, configs []EvalConfig, details bool, acl []string) []EvalReport {
+	if k <= 0 {
+		k = 10
+	}
+	if len(configs) == 0 {
+		configs = []EvalConfig{{Name: "default", Params: r.DefaultSearchParams()}}
+	}
+	reports := make([]EvalReport, 0, len(configs))
+	for i, ec := range configs {
+		name := ec.Name
+		if name == "" {
+			name = fmt.Sprintf("config-%d", i+1)
+		}
+		rep := EvalReport{Config: name, Params: ec.Params, K: k, Cases: len(cases)}
+		var elapsed time.Duration
+		for _, c := range cases {
+			start := time.Now()
+			hits, err := r.SearchFiltered(c.Query, k, SearchFilter{Project: c.Project, ProjectPrefix: c.ProjectPrefix, ACL: acl}, ec.Params)
+			elapsed += time.Since(start)
+			q := EvalQuery{Query: c.Query}
+			if err != nil {
+				rep.Errors++
+				q.Error = err.Error()
+				q.Missed = c.ExpectedPaths
+			} else {
+				q.Retrieved = rankedPaths(hits, k)
+				scoreQuery(&q, c.ExpectedPaths, k)
+			}
+			rep.RecallAtK += q.Recall
+			rep.MRR += q.RR
+			rep.NDCG += q.NDCG
+			if details {
+				rep.Queries = append(rep.Queries, q)
+			}
+		}
+		n := float64(len(cases))
+		if n > 0 {
+			rep.RecallAtK = round4(rep.RecallAtK / n)
+			rep.MRR = round4(rep.MRR / n)
+			rep.NDCG = round4(rep.NDCG / n)
+			rep.AvgMs = round4(float64(elapsed.Microseconds()) / 1000 / n)
+		}
+		reports = append(reports, rep)
+	}
+	return reports
+}
+
+// rankedPaths returns the distinct hit paths in rank order, at most k.
+func rankedPaths(hits []Hit, k int) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, h := range hits {
+		p := h.Path
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		out = append(out, p)
+		if len(out) == k {
+			break
+		}
+	}
+	return out
+}
+
+// scoreQuery fills recall, reciprocal rank and binary-relevance nDCG.
+func scoreQuery(q *EvalQuery, expected []string, k int) {
+	found := make([]bool, len(expected))
+	var dcg float64
+	for rank, p := range q.Retrieved {
+		for i, e := range expected {
+			if !found[i] && pathMatches(p, e) {
+				found[i] = true
+				dcg += 1 / math.Log2(float64(rank)+2)
+				if q.RR == 0 {
+					q.RR = 1 / float64(rank+1)
+				}
+				break
+			}
+		}
+	}
+	var ideal float64
+	for i := 0; i < len(expected) && i < k; i++ {
+		ideal += 1 / math.Log2(float64(i)+2)
+	}
+	hits := 0
+	for i, ok := range found {
+		if ok {
+			hits++
+		} else {
+			q.Missed = append(q.Missed, expected[i])
+		}
+	}
+	q.Recall = float64(hits) / float64(len(expected))
+	if ideal > 0 {
+		q.NDCG = dcg / ideal
+	}
+	q.Recall, q.RR, q.NDCG = round4(q.Recall), round4(q.RR), round4(q.NDCG)
+}
+
+func pathMatches(hit, expected string) bool {
+	hit = filepath.ToSlash(filepath.Clean(hit))
+	expected = filepath.ToSlash(filepath.Clean(strings.TrimSpace(expected)))
+	return hit == expected || strings.HasSuffix(hit, "/"+strings.TrimPrefix(expected, "/"))
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
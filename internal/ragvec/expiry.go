@@ -0,0 +1,96 @@
+package ragvec
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTTL reads an index-run TTL: a Go duration ("36h", "90m") or a whole
+// number of days ("7d"). An empty string means no expiry.
+func ParseTTL(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid ttl %q: use a positive duration such as 36h or 7d", s)
+	}
+	return d, nil
+}
+
+// PurgeExpired deletes the chunks whose ttl ran out by now and drops the
+// manifest entries of the expired runs.
+func (r *VecRAG) PurgeExpired(now time.Time) (int, error) {
+	filter := map[string]any{"must": []map[string]any{
+		{"key": "expires_at", "range": map[string]any{"lte": now.Unix()}},
+	}}
+	deleted, err := r.deleteWhere(filter)
+	if deleted > 0 {
+		r.projects.invalidate()
+	}
+	if err != nil {
+		return deleted, err
+	}
+	r.forgetExpired(now)
+	return deleted, nil
+}
+
+// startExpirySweeper runs PurgeExpired and PurgeDeleted every interval for
+// the life of the process.
+func (r *VecRAG) startExpirySweeper(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for range t.C {
+			n, err := r.PurgeExpired(time.Now())
+			switch {
+			case err != nil:
+				log.Printf("TTL sweep failed: %v", err)
+			case n > 0:
+				log.Printf("TTL sweep deleted %d expired chunks", n)
+			}
+			n, err = r.PurgeDeleted(time.Now())
+			switch {
+			case err != nil:
+				log.Printf("Soft-delete purge failed: %v", err)
+			case n > 0:
+				log.Printf("Purged %d chunks deleted more than %s ago", n, r.SoftDeleteRetention())
+			}
+		}
+	}()
+}
+
+// forgetExpired drops the manifest entries of runs that expired by now.
+func (r *VecRAG) forgetExpired(now time.Time) {
+	r.manifest.mu.Lock()
+	defer r.manifest.mu.Unlock()
+	changed := false
+	for key, e := range r.manifest.m.Roots {
+		if e.ExpiresAt != nil && !e.ExpiresAt.After(now) {
+			delete(r.manifest.m.Roots, key)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := r.manifest.saveLocked(); err != nil {
+		fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: failed to save index manifest: %v\n", err)
+	}
+}
@@ -0,0 +1,159 @@
+package ragvec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+)
+
+// SearchExplanation tells how a search ran: what was asked of the store and
+// which steps removed candidates afterwards.
+type SearchExplanation struct {
+	Backend string `json:"backend"`
+	// Hybrid is set when the store ranked by BM25 and vectors together;
+	// TextWeight is the BM25 share of the fused score
+	Hybrid     bool    `json:"hybrid"`
+	TextWeight float64 `json:"text_weight,omitempty"`
+	// Embedding is the provider/model/dim of the query vector
+	Embedding string `json:"embedding"`
+	// StoreFilter is the filter sent to the store, including the implicit
+	// exclusion of soft-deleted and table-of-contents points
+	StoreFilter map[string]any `json:"store_filter"`
+	Params      SearchParams   `json:"params"`
+	// Requested is how many candidates were asked of the store, Returned how
+	// many it gave
+	Requested int `json:"requested"`
+	Returned  int `json:"returned"`
+	// DroppedByModel, DroppedByPrefix, Cut and Merged count the candidates
+	// removed by search.model_mismatch filter, project_prefix, the k limit
+	// and search.merge_adjacent, in that order
+	DroppedByModel  int `json:"dropped_by_model"`
+	DroppedByPrefix int `json:"dropped_by_prefix"`
+	Cut             int `json:"cut"`
+	Merged          int `json:"merged"`
+	// Reranker names the reranker that rescored the candidates left after
+	// the filters, before the cut
+	Reranker string `json:"reranker,omitempty"`
+}
+
+// HitExplanation breaks down the score of one hit.
+type HitExplanation struct {
+	// Rank is the hit's place in the store's ranking (1-based)
+	Rank int `json:"rank"`
+	// StoreScore is the score the store returned: the vector similarity,
+	// or the fused score under hybrid search
+	StoreScore float32 `json:"store_score"`
+	// VectorScore and TextScore are the components of the score; TextScore
+	// (BM25) is set under hybrid search only. They are left out when the
+	// store cannot compute them apart.
+	VectorScore *float32 `json:"vector_score,omitempty"`
+	TextScore   *float32 `json:"text_score,omitempty"`
+	// Adjustments are the changes made to the hit after the store ranked it
+	Adjustments []string `json:"adjustments,omitempty"`
+	// Filters are the conditions of the search filter the hit met, with its
+	// values
+	Filters []string `json:"filters_matched,omitempty"`
+}
+
+func (r *VecRAG) startExplanation(ex *SearchExplanation, stamp Stamp, filter map[string]any, params SearchParams, requested, returned int) {
+	ex.Backend = r.config.Store.Backend
+	if ex.Backend == "" {
+		ex.Backend = "qdrant"
+	}
+	if _, ok := r.vdb.(TextSearcher); ok && r.config.Store.OpenSearch.Hybrid && !params.Exact {
+		ex.Hybrid = true
+		ex.TextWeight = r.config.Store.OpenSearch.TextWeight
+	}
+	ex.Embedding = stamp.String()
+	ex.StoreFilter = filter
+	ex.Params = params
+	ex.Requested = requested
+	ex.Returned = returned
+}
+
+// explainHits fills the score components, adjustments and matched filters
+// of the final hits.
+func (r *VecRAG) explainHits(ex *SearchExplanation, query string, vec []float32, hits []Hit, f SearchFilter, stamp Stamp) error {
+	var textScores, vectorScores map[string]float32
+	if se, ok := r.vdb.(ScoreExplainer); ok && ex.Hybrid && len(hits) > 0 {
+		ids := make([]any, len(hits))
+		for i, h := range hits {
+			ids[i] = h.ID
+		}
+		var err error
+		if textScores, vectorScores, err = se.ExplainScores(query, vec, ids); err != nil {
+			return fmt.Errorf("explain scores: %w", err)
+		}
+	}
+	for i := range hits {
+		h := &hits[i]
+		// Merging copies the explanation of the first part; give each hit
+		// its own
+		e := *h.Explain
+		h.Explain = &e
+		if ex.Hybrid {
+			if v, ok := vectorScores[h.ID]; ok {
+				e.VectorScore = &v
+			}
+			// A chunk without the query terms has no BM25 score at all
+			t := textScores[h.ID]
+			e.TextScore = &t
+		} else {
+			v := e.StoreScore
+			e.VectorScore = &v
+		}
+		if len(h.MergedPositions) > 0 {
+			e.Adjustments = append(e.Adjustments, fmt.Sprintf("merged positions %v by search.merge_adjacent; the score is the best of the parts (%.4f)", h.MergedPositions, h.Score))
+		}
+		if h.ModelMismatch {
+			e.Adjustments = append(e.Adjustments, fmt.Sprintf("embedded with %s, the query with %s; the score is not comparable", h.Embedding, stamp))
+		}
+		e.Filters = f.matchedBy(*h)
+	}
+	return nil
+}
+
+// matchedBy lists the conditions of f that h meets, with h's values.
+func (f SearchFilter) matchedBy(h Hit) []string {
+	var out []string
+	if p := strings.TrimSpace(f.Project); p != "" {
+		out = append(out, "project = "+h.Project)
+	} else if p := strings.TrimSpace(f.ProjectPrefix); p != "" {
+		out = append(out, fmt.Sprintf("project %s starts with %s", h.Project, p))
+	}
+	has := map[string]bool{}
+	for _, t := range h.Tags {
+		has[t] = true
+	}
+	if tags := NormalizeTags(f.TagsAny); len(tags) > 0 {
+		var matched []string
+		for _, t := range tags {
+			if has[t] {
+				matched = append(matched, t)
+			}
+		}
+		out = append(out, "tags_any matched "+strings.Join(matched, ", "))
+	}
+	if tags := NormalizeTags(f.TagsAll); len(tags) > 0 {
+		out = append(out, "tags_all "+strings.Join(tags, ", "))
+	}
+	if v := chunker.NormalizeVersion(f.Version); v != "" {
+		out = append(out, fmt.Sprintf("version %s matches %s", h.Version, v))
+	}
+	if f.ACL != nil {
+		if len(h.ACL) == 0 {
+			out = append(out, "acl: unlabeled")
+		} else {
+			out = append(out, "acl "+strings.Join(h.ACL, ", "))
+		}
+	}
+	if !f.ModifiedAfter.IsZero() {
+		out = append(out, fmt.Sprintf("modified_at %s after %s", h.ModifiedAt, f.ModifiedAfter.UTC().Format(time.RFC3339)))
+	}
+	if !f.IndexedBefore.IsZero() {
+		out = append(out, fmt.Sprintf("indexed_at %s before %s", h.IndexedAt, f.IndexedBefore.UTC().Format(time.RFC3339)))
+	}
+	return out
+}
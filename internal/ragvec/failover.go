@@ -0,0 +1,187 @@
+package ragvec
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// HealthChecker is implemented by providers that can be probed cheaply.
+// Providers without it (e.g. local embeddings) are always considered healthy.
+type HealthChecker interface {
+	HealthCheck() error
+}
+
+// ProviderStatus is the health snapshot of one provider in a failover chain.
+type ProviderStatus struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	LastError string    `json:"last_error,omitempty"`
+	LastCheck time.Time `json:"last_check,omitempty"`
+	LatencyMS int64     `json:"latency_ms,omitempty"`
+}
+
+type chainEntry struct {
+	name   string
+	prov   EmbeddingProvider
+	status ProviderStatus
+}
+
+// FailoverProvider embeds with the first healthy provider of a chain,
+// e.g. openai → local, so search keeps working (in degraded quality) while
+// the primary provider is down. All providers must share one dimension.
+type FailoverProvider struct {
+	mu      sync.Mutex
+	entries []*chainEntry
+	stop    chan struct{}
+}
+
+func NewFailoverProvider(names []string, provs []EmbeddingProvider) (*FailoverProvider, error) {
+	if len(provs) == 0 || len(names) != len(provs) {
+		return nil, fmt.Errorf("failover chain needs at least one provider")
+	}
+	f := &FailoverProvider{}
+	dim := provs[0].Dim()
+	for i, p := range provs {
+		if p.Dim() != dim {
+			return nil, fmt.Errorf("fallback provider %s has dimension %d, primary has %d", names[i], p.Dim(), dim)
+		}
+		f.entries = append(f.entries, &chainEntry{name: names[i], prov: p, status: ProviderStatus{Name: names[i], Healthy: true}})
+	}
+	return f, nil
+}
+
+func (f *FailoverProvider) Dim() int { return f.entries[0].prov.Dim() }
+
+// Embed tries each healthy provider in order; a failing provider is marked
+// unhealthy until the next successful probe.
+func (f *FailoverProvider) Embed(texts []string) ([][]float32, error) {
+	vecs, _, err := f.EmbedNamed(texts)
+	return vecs, err
+}
+
+// EmbedNamed is Embed that also returns the name of the provider that
+// produced the vectors.
+func (f *FailoverProvider) EmbedNamed(texts []string) ([][]float32, string, error) {
+	return f.embedNamed(texts, "")
+}
+
+// embedNamed is EmbedNamed tagging provider requests with a correlation ID.
+func (f *FailoverProvider) embedNamed(texts []string, id string) ([][]float32, string, error) {
+	var lastErr error
+	for _, e := range f.candidates() {
+		start := time.Now()
+		vecs, err := embedWithID(e.prov, texts, id)
+		f.record(e, err, time.Since(start))
+		if err == nil {
+			return vecs, e.name, nil
+		}
+		log.Printf("Embedding provider %s failed: %v", e.name, err)
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no embedding provider available")
+	}
+	return nil, "", lastErr
+}
+
+// candidates returns healthy providers first (in chain order), followed by
+// unhealthy ones as a last resort.
+func (f *FailoverProvider) candidates() []*chainEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]*chainEntry, 0, len(f.entries))
+	for _, e := range f.entries {
+		if e.status.Healthy {
+			out = append(out, e)
+		}
+	}
+	for _, e := range f.entries {
+		if !e.status.Healthy {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (f *FailoverProvider) record(e *chainEntry, err error, took time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e.status.LastCheck = time.Now()
+	e.status.LatencyMS = took.Milliseconds()
+	e.status.Healthy = err == nil
+	e.status.LastError = ""
+	if err != nil {
+		e.status.LastError = err.Error()
+	}
+}
+
+// Probe health-checks every provider that supports it.
+func (f *FailoverProvider) Probe() {
+	for _, e := range f.entries {
+		hc, ok := e.prov.(HealthChecker)
+		if !ok {
+			continue
+		}
+		start := time.Now()
+		err := hc.HealthCheck()
+		f.record(e, err, time.Since(start))
+		if err != nil {
+			log.Printf("Embedding provider %s health check failed: %v", e.name, err)
+		}
+	}
+}
+
+// StartProbing probes the chain every interval until Close is called.
+func (f *FailoverProvider) StartProbing(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	f.mu.Lock()
+	if f.stop != nil {
+		f.mu.Unlock()
+		return
+	}
+	f.stop = make(chan struct{})
+	stop := f.stop
+	f.mu.Unlock()
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				f.Probe()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops background probing.
+func (f *FailoverProvider) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stop != nil {
+		close(f.stop)
+		f.stop = nil
+	}
+}
+
+// Active returns the name of the provider that will serve the next request.
+func (f *FailoverProvider) Active() string {
+	return f.candidates()[0].name
+}
+
+// Status returns a snapshot of every provider in the chain.
+func (f *FailoverProvider) Status() []ProviderStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]ProviderStatus, len(f.entries))
+	for i, e := range f.entries {
+		out[i] = e.status
+	}
+	return out
+}
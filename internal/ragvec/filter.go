@@ -0,0 +1,250 @@
+package ragvec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/pii"
+)
+
+// ContentFilter inspects the chunks of a batch before they are embedded and
+// may rewrite or drop them, e.g. to redact personal data. The built-in
+// filters are configured under indexing.filters; AddFilter plugs in others.
+type ContentFilter interface {
+	// Name prefixes the filter's redaction kinds in run reports
+	Name() string
+	// Filter returns one result per chunk, in order
+	Filter(chunks []FilterChunk) ([]FilterResult, error)
+}
+
+// FilterChunk is a chunk as seen by a ContentFilter.
+type FilterChunk struct {
+	Path string `json:"path"`
+	Text string `json:"text"`
+}
+
+// FilterResult is a ContentFilter's verdict on one chunk: its new text, or
+// Drop to leave it out, and how many redactions of each kind were made.
+type FilterResult struct {
+	Text       string
+	Drop       bool
+	Redactions map[string]int
+}
+
+// text returns the text a result leaves: none once dropped.
+func (res FilterResult) text() string {
+	if res.Drop {
+		return ""
+	}
+	return res.Text
+}
+
+// AddFilter appends f to the content filters run on every indexed chunk.
+// Call it before indexing starts; it is not safe for concurrent use.
+func (r *VecRAG) AddFilter(f ContentFilter) {
+	r.filters = append(r.filters, f)
+	// Cached chunks did not pass f
+	if r.chunks != nil {
+		r.chunks = newChunkCache(r.config, r.filters)
+	}
+}
+
+// newContentFilters builds the filters enabled in indexing.filters.
+func newContentFilters(config *cfg.Config) ([]ContentFilter, error) {
+	var out []ContentFilter
+	fc := config.Indexing.Filters
+	if fc.PII.Enabled {
+		d, err := pii.New(fc.PII.Kinds)
+		if err != nil {
+			return nil, fmt.Errorf("pii filter: %w", err)
+		}
+		out = append(out, piiFilter{d})
+	}
+	if fc.HTTP.URL != "" {
+		out = append(out, newHTTPFilter(fc.HTTP, userAgent(config)))
+	}
+	return out, nil
+}
+
+// applyFilters runs the content filters over a batch in order. Chunks a
+// filter drops are not passed to the next one. The subject and sender of
+// an email chunk are stored in its payload too, so they are passed to the
+// filters after the chunks, as texts of their own; dropping one empties it.
+func (r *VecRAG) applyFilters(batch []preparedChunk, rep *IngestReport) ([]preparedChunk, error) {
+	for _, f := range r.filters {
+		if len(batch) == 0 {
+			break
+		}
+		in := make([]FilterChunk, len(batch))
+		var emails []int // indexes in batch of the chunks with a Message
+		for i, c := range batch {
+			in[i] = FilterChunk{Path: c.Path, Text: c.Text}
+			if c.Message != nil {
+				emails = append(emails, i)
+			}
+		}
+		for _, i := range emails {
+			m := batch[i].Message
+			in = append(in, FilterChunk{Path: batch[i].Path, Text: m.Subject}, FilterChunk{Path: batch[i].Path, Text: m.From})
+		}
+		results, err := f.Filter(in)
+		if err != nil {
+			return nil, fmt.Errorf("content filter %s: %w", f.Name(), err)
+		}
+		if len(results) != len(in) {
+			return nil, fmt.Errorf("content filter %s returned %d results for %d chunks", f.Name(), len(results), len(in))
+		}
+		for j, i := range emails {
+			subject, from := results[len(batch)+2*j], results[len(batch)+2*j+1]
+			// Chunks of one message share it, so it is copied
+			m := *batch[i].Message
+			m.Subject, m.From = subject.text(), from.text()
+			if m != *batch[i].Message {
+				batch[i].Message = &m
+				batch[i].redacted = appendKinds(batch[i].redacted, f.Name(), subject.Redactions)
+				batch[i].redacted = appendKinds(batch[i].redacted, f.Name(), from.Redactions)
+			}
+		}
+		results = results[:len(batch)]
+		kept := batch[:0]
+		for i, res := range results {
+			c := batch[i]
+			changed := !res.Drop && res.Text != c.Text
+			// A chunk counts as redacted once, however many filters change it
+			rep.addFiltered(f.Name(), res, changed && !c.filtered)
+			if res.Drop {
+				continue
+			}
+			if changed {
+				c.Text = res.Text
+				c.filtered = true
+				c.redacted = appendKinds(c.redacted, f.Name(), res.Redactions)
+			}
+			kept = append(kept, c)
+		}
+		batch = kept
+	}
+	return batch, nil
+}
+
+// appendKinds adds the kinds of redactions, as name.kind, to kinds unless
+// they are listed already.
+func appendKinds(kinds []string, name string, redactions map[string]int) []string {
+	var add []string
+	for kind, n := range redactions {
+		if k := name + "." + kind; n > 0 && !slices.Contains(kinds, k) {
+			add = append(add, k)
+		}
+	}
+	sort.Strings(add)
+	return append(kinds, add...)
+}
+
+// piiFilter redacts personal data found by the pii package.
+type piiFilter struct {
+	detector *pii.Detector
+}
+
+func (piiFilter) Name() string { return "pii" }
+
+func (f piiFilter) Filter(chunks []FilterChunk) ([]FilterResult, error) {
+	out := make([]FilterResult, len(chunks))
+	for i, c := range chunks {
+		found := f.detector.Find(c.Text)
+		out[i].Text = pii.Redact(c.Text, found)
+		if len(found) > 0 {
+			out[i].Redactions = map[string]int{}
+			for _, m := range found {
+				out[i].Redactions[m.Kind]++
+			}
+		}
+	}
+	return out, nil
+}
+
+// httpFilter sends each batch to an external service: POST
+// {"chunks": [{"path", "text"}]}, answered by {"chunks": [{"text", "drop",
+// "redactions"}]} in the same order. A result without text keeps the chunk
+// unchanged.
+type httpFilter struct {
+	conf      cfg.HTTPFilterConfig
+	userAgent string
+	client    *http.Client
+}
+
+func newHTTPFilter(conf cfg.HTTPFilterConfig, userAgent string) *httpFilter {
+	timeout := time.Duration(conf.TimeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &httpFilter{conf: conf, userAgent: userAgent, client: &http.Client{Timeout: timeout}}
+}
+
+func (*httpFilter) Name() string { return "http" }
+
+func (f *httpFilter) Filter(chunks []FilterChunk) ([]FilterResult, error) {
+	out, err := f.call(chunks)
+	if err != nil && f.conf.FailOpen {
+		log.Printf("Warning: content filter http failed, indexing %d chunks unfiltered: %v", len(chunks), err)
+		out = make([]FilterResult, len(chunks))
+		for i, c := range chunks {
+			out[i].Text = c.Text
+		}
+		return out, nil
+	}
+	return out, err
+}
+
+func (f *httpFilter) call(chunks []FilterChunk) ([]FilterResult, error) {
+	body, err := json.Marshal(map[string]any{"chunks": chunks})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", f.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", f.userAgent)
+	for k, v := range f.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	var decoded struct {
+		Chunks []struct {
+			Text       *string        `json:"text"`
+			Drop       bool           `json:"drop"`
+			Redactions map[string]int `json:"redactions"`
+		} `json:"chunks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(decoded.Chunks) != len(chunks) {
+		return nil, fmt.Errorf("got %d results for %d chunks", len(decoded.Chunks), len(chunks))
+	}
+	out := make([]FilterResult, len(chunks))
+	for i, d := range decoded.Chunks {
+		out[i] = FilterResult{Text: chunks[i].Text, Drop: d.Drop, Redactions: d.Redactions}
+		if d.Text != nil {
+			out[i].Text = *d.Text
+		}
+	}
+	return out, nil
+}
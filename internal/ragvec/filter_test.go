@@ -0,0 +1,45 @@
+package ragvec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	"github.com/Rhyanz46/mcp-service/internal/pii"
+)
+
+func TestApplyFiltersRedactsEmailFields(t *testing.T) {
+	d, err := pii.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &VecRAG{filters: []ContentFilter{piiFilter{d}}}
+	msg := &chunker.Message{Subject: "Call me at +1 415 555 0100", From: "Ana <ana@example.com>", ThreadID: "<t1@example.com>"}
+	batch := prepareChunks([]chunker.Chunk{
+		{Path: "/mail/a.eml", Position: 0, Text: "First part", Message: msg},
+		{Path: "/mail/a.eml", Position: 1, Text: "Second part", Message: msg},
+		{Path: "/docs/b.md", Position: 0, Text: "No email here"},
+	})
+	out, err := r.applyFilters(batch, &IngestReport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 3 {
+		t.Fatalf("kept %d chunks, want 3", len(out))
+	}
+	for _, c := range out[:2] {
+		m := c.Message
+		if strings.Contains(m.From, "ana@example.com") || strings.Contains(m.Subject, "555") {
+			t.Errorf("chunk %d keeps personal data: from %q, subject %q", c.Position, m.From, m.Subject)
+		}
+		if len(c.redacted) == 0 {
+			t.Errorf("chunk %d lists no redactions", c.Position)
+		}
+	}
+	if msg.From != "Ana <ana@example.com>" {
+		t.Errorf("shared message changed: %q", msg.From)
+	}
+	if out[2].Message != nil || out[2].Text != "No email here" {
+		t.Errorf("other chunk changed: %+v", out[2].Chunk)
+	}
+}
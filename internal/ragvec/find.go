@@ -0,0 +1,129 @@
+package ragvec
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FileQuery selects indexed files by payload metadata only; no embeddings
+// are computed. Empty fields match everything.
+type FileQuery struct {
+	Glob     string // path glob; "*" stays within a segment, "**" spans segments
+	Name     string // case-insensitive basename substring
+	Project  string // exact project name (server-side filter)
+	FileType string // exact file_type (server-side filter)
+	// ACL, when set, limits the files to those carrying one of these
+	// access labels, as SearchFilter.ACL does
+	ACL []string
+}
+
+// FindFiles scrolls the collection and returns one entry per matching file,
+// sorted by path, along with the total before offset/limit are applied.
+func (r *VecRAG) FindFiles(fq FileQuery, offset, limit int) ([]map[string]any, int, error) {
+	var must []map[string]any
+	if p := strings.TrimSpace(fq.Project); p != "" {
+		must = append(must, map[string]any{"key": "project", "match": map[string]any{"value": p}})
+	}
+	if ft := strings.TrimSpace(fq.FileType); ft != "" {
+		must = append(must, map[string]any{"key": "file_type", "match": map[string]any{"value": ft}})
+	}
+	must = append(must, aclMust(fq.ACL)...)
+	filter := liveFilter(nil)
+	if len(must) > 0 {
+		filter["must"] = must
+	}
+	var glob *regexp.Regexp
+	if g := strings.TrimSpace(fq.Glob); g != "" {
+		glob = globRegexp(g)
+	}
+	name := strings.ToLower(strings.TrimSpace(fq.Name))
+
+	files := map[string]map[string]any{}
+	var offsetID any
+	for {
+		pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offsetID, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, pt := range pts {
+			p := pt.Payload
+			path := toStr(p["path"])
+			if f, ok := files[path]; ok {
+				f["chunks"] = f["chunks"].(int) + 1
+				continue
+			}
+			base := toStr(p["basename"])
+			if name != "" && !strings.Contains(strings.ToLower(base), name) {
+				continue
+			}
+			if glob != nil && !glob.MatchString(filepath.ToSlash(path)) {
+				continue
+			}
+			files[path] = map[string]any{
+				"path":      path,
+				"basename":  base,
+				"project":   toStr(p["project"]),
+				"file_type": toStr(p["file_type"]),
+				"file_hash": toStr(p["file_hash"]),
+				"chunks":    1,
+			}
+		}
+		if next == nil {
+			break
+		}
+		offsetID = next
+	}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	total := len(paths)
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset > total {
+		return []map[string]any{}, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	out := make([]map[string]any, 0, end-offset)
+	for _, p := range paths[offset:end] {
+		out = append(out, files[p])
+	}
+	return out, total, nil
+}
+
+// globRegexp compiles a path glob. Patterns are unanchored at the start so
+// "payments/**" matches that directory anywhere in an absolute path.
+func globRegexp(glob string) *regexp.Regexp {
+	glob = filepath.ToSlash(glob)
+	var b strings.Builder
+	b.WriteString("(^|/)")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if strings.HasSuffix(glob, "/") {
+		b.WriteString(".*")
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
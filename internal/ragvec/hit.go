@@ -0,0 +1,148 @@
+package ragvec
+
+import (
+	"fmt"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// Hit is one search result as returned by MCP tools and the HTTP API.
+// Optional fields are left out of the JSON when the chunk does not carry
+// them.
+type Hit struct {
+	ID       string  `json:"id"`
+	Score    float32 `json:"score"`
+	Path     string  `json:"path"`
+	Basename string  `json:"basename"`
+	Project  string  `json:"project"`
+	FileType string  `json:"file_type"`
+	// Position is the chunk's index in its file, -1 for a summary chunk
+	Position int `json:"position"`
+	// Snippet is a preview of Text, at most 240 characters
+	Snippet string `json:"snippet"`
+	Text    string `json:"text,omitempty"`
+	// Kind is "summary" for generated per-file summaries, "image" for
+	// images referenced by documents, else empty
+	Kind string `json:"kind,omitempty"`
+	// Section is the heading the chunk falls under
+	Section string `json:"section,omitempty"`
+	// Chapter is the chapter of an EPUB or LaTeX document the chunk falls
+	// under
+	Chapter string `json:"chapter,omitempty"`
+	// Symbols names the schema definitions a .proto or .graphql chunk
+	// covers
+	Symbols []string `json:"symbols,omitempty"`
+	// Version and ReleaseDate name the changelog entry the chunk belongs
+	// to
+	Version     string `json:"version,omitempty"`
+	ReleaseDate string `json:"release_date,omitempty"`
+	// StartTime and EndTime are the HH:MM:SS span of a transcript chunk
+	StartTime string   `json:"start_time,omitempty"`
+	EndTime   string   `json:"end_time,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	// ACL lists the access labels of the chunk, none when anyone may read
+	// it
+	ACL []string `json:"acl,omitempty"`
+	// Metadata holds the fields of the chunk's source: url and title for
+	// pages and connector documents, repo, ref and commit for repositories,
+	// bucket, object_key and version_id for S3 objects, referenced_by for
+	// images indexed from the document that references them, title, from,
+	// sent_at and thread_id for email messages, channel, started_at,
+	// ended_at and thread_id for chat conversations
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// IndexedAt, ModifiedAt and ExpiresAt are RFC3339 timestamps
+	IndexedAt  string `json:"indexed_at,omitempty"`
+	ModifiedAt string `json:"modified_at,omitempty"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+	// SourceRange is set for chunks that know where they came from
+	*SourceRange
+	// MergedPositions lists the chunks joined into this hit by
+	// search.merge_adjacent
+	MergedPositions []int `json:"merged_positions,omitempty"`
+	// ModelMismatch marks hits embedded with another provider or model,
+	// named in Embedding
+	ModelMismatch bool   `json:"model_mismatch,omitempty"`
+	Embedding     string `json:"embedding,omitempty"`
+	// Tokens is the estimated size of the text under a token budget
+	Tokens int `json:"tokens,omitempty"`
+	// Source ("file" or "index") and Drifted are set by ReadThrough
+	Source  string `json:"source,omitempty"`
+	Drifted bool   `json:"drifted,omitempty"`
+	// Explain breaks down the score; set by SearchExplained
+	Explain *HitExplanation `json:"explain,omitempty"`
+}
+
+// SourceRange locates a chunk in its source file as it was when indexed.
+type SourceRange struct {
+	StartByte int    `json:"start_byte"`
+	EndByte   int    `json:"end_byte"`
+	FileHash  string `json:"file_hash"`
+}
+
+// metadataFields are the payload keys copied into Hit.Metadata.
+var metadataFields = []string{"url", "title", "repo", "ref", "commit", "bucket", "object_key", "version_id", "referenced_by", "from", "sent_at", "thread_id", "channel", "started_at", "ended_at"}
+
+// hitFromPoint maps a store hit to a Hit.
+func hitFromPoint(h SearchHit) Hit {
+	p := h.Payload
+	hit := Hit{
+		ID:       fmt.Sprint(h.ID),
+		Score:    h.Score,
+		Path:     toStr(p["path"]),
+		Basename: toStr(p["basename"]),
+		Project:  toStr(p["project"]),
+		FileType: toStr(p["file_type"]),
+		Snippet:  toStr(p["preview"]),
+	}
+	hit.Position, _ = intOf(p["position"])
+	hit.Text, _ = p["text"].(string)
+	hit.Kind, _ = p["kind"].(string)
+	hit.Section, _ = p["section"].(string)
+	hit.Chapter, _ = p["chapter"].(string)
+	hit.Symbols = stringsOf(p["symbols"])
+	hit.Version, _ = p["version"].(string)
+	hit.ReleaseDate, _ = p["release_date"].(string)
+	hit.StartTime, _ = p["start_time"].(string)
+	hit.EndTime, _ = p["end_time"].(string)
+	hit.Tags = stringsOf(p["tags"])
+	for _, l := range stringsOf(p["acl"]) {
+		if l != cfg.PublicLabel {
+			hit.ACL = append(hit.ACL, l)
+		}
+	}
+	for _, key := range metadataFields {
+		if v, ok := p[key].(string); ok && v != "" {
+			if hit.Metadata == nil {
+				hit.Metadata = map[string]string{}
+			}
+			hit.Metadata[key] = v
+		}
+	}
+	for key, dst := range map[string]*string{"indexed_at": &hit.IndexedAt, "modified_at": &hit.ModifiedAt, "expires_at": &hit.ExpiresAt} {
+		if sec, ok := floatOf(p[key]); ok {
+			*dst = time.Unix(int64(sec), 0).UTC().Format(time.RFC3339)
+		}
+	}
+	if end, ok := intOf(p["end_byte"]); ok {
+		start, _ := intOf(p["start_byte"])
+		hit.SourceRange = &SourceRange{StartByte: start, EndByte: end, FileHash: toStr(p["file_hash"])}
+	}
+	return hit
+}
+
+// stringsOf reads a string list from a payload, which holds []any after
+// a JSON round trip.
+func stringsOf(v any) []string {
+	switch l := v.(type) {
+	case []string:
+		return l
+	case []any:
+		out := make([]string, 0, len(l))
+		for _, s := range l {
+			out = append(out, toStr(s))
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package ragvec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// KindImage marks points holding the alt text and OCR text of an image
+// referenced by a document.
+const KindImage = "image"
+
+// imageChunks builds the chunks of the images doc references when
+// indexing.images is enabled: each image's alt text followed by the text
+// OCR reads from it, stored under the image's path. Only images inside root
+// are read; seen holds the images of the run so far, so an image shared by
+// several documents is read once. An image that cannot be read is skipped,
+// and one OCR fails on keeps its alt text, each with a log line.
+func (r *VecRAG) imageChunks(doc chunker.File, root string, seen map[string]bool, size, overlap int) []chunker.Chunk {
+	if !r.config.Indexing.Images.Enabled || r.config.GetFileType(doc.Path) == "code" || chunker.DiskPath(doc.Path) != doc.Path {
+		return nil
+	}
+	var out []chunker.Chunk
+	for _, ref := range chunker.ImageRefs(doc.Text) {
+		path, ok := resolveImage(doc.Path, root, ref.Src)
+		if !ok || seen[path] {
+			continue
+		}
+		seen[path] = true
+		text, modTime, ok := r.imageText(path, ref.Alt)
+		if !ok {
+			continue
+		}
+		cs := chunker.ChunkFiles([]chunker.File{{Path: path, Text: text, ModTime: modTime}}, size, overlap, r.config)
+		for i := range cs {
+			cs[i].Kind = KindImage
+			cs[i].ReferencedBy = doc.Path
+			// The text is not a range of the image file
+			cs[i].Start, cs[i].End = 0, 0
+			cs[i].Section, cs[i].Chapter = "", ""
+		}
+		out = append(out, cs...)
+	}
+	return out
+}
+
+// dedupeImages drops the image chunks of images already in seen and adds
+// the others to it.
+func dedupeImages(cs []preparedChunk, seen map[string]bool) []preparedChunk {
+	var out []preparedChunk
+	added := map[string]bool{}
+	for _, c := range cs {
+		if seen[c.Path] && !added[c.Path] {
+			continue
+		}
+		seen[c.Path], added[c.Path] = true, true
+		out = append(out, c)
+	}
+	return out
+}
+
+// resolveImage maps an image reference of the document at docPath to a
+// file under root, rejecting URLs, site-absolute paths, images outside
+// root and files that are not images.
+func resolveImage(docPath, root, src string) (string, bool) {
+	if i := strings.IndexAny(src, "?#"); i >= 0 {
+		src = src[:i]
+	}
+	if u, err := url.PathUnescape(src); err == nil {
+		src = u
+	}
+	if src == "" || strings.Contains(src, ":") || strings.HasPrefix(src, "/") || chunker.ImageMIME(src) == "" {
+		return "", false
+	}
+	path := filepath.Join(filepath.Dir(docPath), filepath.FromSlash(src))
+	absRoot, err1 := filepath.Abs(root)
+	absPath, err2 := filepath.Abs(path)
+	if err1 != nil || err2 != nil {
+		return "", false
+	}
+	if rel, err := filepath.Rel(absRoot, absPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		log.Printf("Skipping image %s referenced by %s: outside %s", src, docPath, root)
+		return "", false
+	}
+	return path, true
+}
+
+// imageText reads the image at path and returns the text to index for it:
+// alt, then the OCR text when an OCR endpoint is set. ok is false when
+// the image is missing or too large, or has no text at all.
+func (r *VecRAG) imageText(path, alt string) (string, time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		log.Printf("Skipping image %s: not a readable file", path)
+		return "", time.Time{}, false
+	}
+	if max := int64(r.config.Indexing.Images.MaxKB) * 1024; max > 0 && info.Size() > max {
+		log.Printf("Skipping image %s: larger than indexing.images.max_kb (%d)", path, r.config.Indexing.Images.MaxKB)
+		return "", time.Time{}, false
+	}
+	parts := []string{}
+	if alt != "" {
+		parts = append(parts, alt)
+	}
+	if r.ocr != nil {
+		b, err := os.ReadFile(path)
+		if err == nil {
+			var text string
+			if text, err = r.ocr.Read(path, chunker.ImageMIME(path), b); err == nil && strings.TrimSpace(text) != "" {
+				parts = append(parts, strings.TrimSpace(text))
+			}
+		}
+		if err != nil {
+			log.Printf("Warning: OCR failed for %s, indexing its alt text only: %v", path, err)
+		}
+	}
+	if len(parts) == 0 {
+		return "", time.Time{}, false
+	}
+	return strings.Join(parts, "\n\n"), info.ModTime(), true
+}
+
+// ocrClient calls the OCR endpoint of indexing.images.ocr.
+type ocrClient struct {
+	url       string
+	apiKey    string
+	userAgent string
+	client    *http.Client
+}
+
+func newOCRClient(config *cfg.Config) *ocrClient {
+	im := config.Indexing.Images
+	if !im.Enabled || im.OCR.URL == "" {
+		return nil
+	}
+	timeout := time.Duration(im.OCR.TimeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &ocrClient{url: im.OCR.URL, apiKey: im.OCR.APIKey, userAgent: userAgent(config), client: &http.Client{Timeout: timeout}}
+}
+
+// Read posts {"path", "mime_type", "image"} with the image base64-encoded
+// and returns the "text" of the answer.
+func (o *ocrClient) Read(path, mimeType string, image []byte) (string, error) {
+	body, err := json.Marshal(map[string]any{"path": path, "mime_type": mimeType, "image": base64.StdEncoding.EncodeToString(image)})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", o.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", o.userAgent)
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("http %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	var decoded struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	return decoded.Text, nil
+}
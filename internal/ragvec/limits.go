@@ -0,0 +1,70 @@
+package ragvec
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+)
+
+// LimitError reports an index run stopped by indexing.max_files or
+// indexing.max_total_chunks.
+type LimitError struct {
+	Limit string // config key, e.g. "max_files"
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("index run exceeds indexing.%s (more than %d); narrow it with include/exclude or raise the limit", e.Limit, e.Max)
+}
+
+// limitGuard applies the per-run caps of one ingestion.
+type limitGuard struct {
+	action    string
+	maxChunks int
+	warn      func(string)
+	warned    bool
+}
+
+func (r *VecRAG) newLimitGuard(opts IngestOptions) *limitGuard {
+	return &limitGuard{action: r.config.Indexing.LimitAction, maxChunks: r.config.Indexing.MaxTotalChunks, warn: opts.OnWarning}
+}
+
+// exceeded handles a run past a cap: in warn mode it reports the first time
+// and lets the run continue, otherwise it returns err.
+func (g *limitGuard) exceeded(err *LimitError) error {
+	if g.action != "warn" {
+		return err
+	}
+	if !g.warned {
+		g.warned = true
+		msg := fmt.Sprintf("indexing.%s of %d exceeded; continuing because indexing.limit_action is warn", err.Limit, err.Max)
+		log.Printf("Warning: %s", msg)
+		if g.warn != nil {
+			g.warn(msg)
+		}
+	}
+	return nil
+}
+
+// checkFiles counts the files of dir before anything is embedded.
+func (r *VecRAG) checkFiles(g *limitGuard, dir string, walk chunker.WalkOptions) error {
+	max := r.config.Indexing.MaxFiles
+	if max <= 0 {
+		return nil
+	}
+	n, err := chunker.CountFiles(dir, walk, r.config, max)
+	if err != nil || n <= max {
+		return err
+	}
+	return g.exceeded(&LimitError{Limit: "max_files", Max: max})
+}
+
+// checkChunks is called before upserting a batch that brings the run to
+// total chunks.
+func (g *limitGuard) checkChunks(total int) error {
+	if g.maxChunks <= 0 || total <= g.maxChunks {
+		return nil
+	}
+	return g.exceeded(&LimitError{Limit: "max_total_chunks", Max: g.maxChunks})
+}
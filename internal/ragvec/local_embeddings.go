@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/stopwords"
 )
 
 // Simple local embedding provider using TF-IDF
@@ -17,6 +18,7 @@ type LocalEmbeddingProvider struct {
 	idf       map[string]float64
 	vocabSize int
 	dim       int
+	stop      stopwords.Set
 }
 
 func NewLocalEmbeddingProviderWithConfig(config *cfg.LocalEmbedding) *LocalEmbeddingProvider {
@@ -24,6 +26,7 @@ func NewLocalEmbeddingProviderWithConfig(config *cfg.LocalEmbedding) *LocalEmbed
 		vocab: make(map[string]int),
 		idf:   make(map[string]float64),
 		dim:   config.Dim,
+		stop:  stopwords.New([]string{"en"}, nil),
 	}
 }
 
@@ -32,9 +35,13 @@ func NewLocalEmbeddingProvider() *LocalEmbeddingProvider {
 		vocab: make(map[string]int),
 		idf:   make(map[string]float64),
 		dim:   512, // Fixed dimension for consistency
+		stop:  stopwords.New([]string{"en"}, nil),
 	}
 }
 
+// SetStopwords replaces the stopword set used when tokenizing.
+func (p *LocalEmbeddingProvider) SetStopwords(s stopwords.Set) { p.stop = s }
+
 func (p *LocalEmbeddingProvider) Dim() int { return p.dim }
 
 // Build vocabulary and IDF from a corpus of texts
@@ -44,7 +51,7 @@ func (p *LocalEmbeddingProvider) BuildVocab(texts []string) {
 	docFreq := make(map[string]int)
 
 	for _, text := range texts {
-		terms := tokenizeText(text)
+		terms := tokenizeText(text, p.stop)
 		seen := make(map[string]bool)
 		for _, term := range terms {
 			vocabSet[term] = true
@@ -87,7 +94,7 @@ func (p *LocalEmbeddingProvider) Embed(texts []string) ([][]float32, error) {
 }
 
 func (p *LocalEmbeddingProvider) textToVector(text string) []float32 {
-	terms := tokenizeText(text)
+	terms := tokenizeText(text, p.stop)
 
 	// Calculate TF
 	tf := make(map[string]float64)
@@ -141,26 +148,18 @@ func (p *LocalEmbeddingProvider) textToVector(text string) []float32 {
 }
 
 // Simple tokenizer
-func tokenizeText(text string) []string {
+func tokenizeText(text string, stop stopwords.Set) []string {
 	// Convert to lowercase
 	text = strings.ToLower(text)
-	// Remove code-specific noise but keep meaningful terms
-	text = regexp.MustCompile(`[^\w\s]`).ReplaceAllString(text, " ")
+	// Remove code-specific noise but keep meaningful terms (letters of any script)
+	text = regexp.MustCompile(`[^\p{L}\p{N}_\s]`).ReplaceAllString(text, " ")
 	// Split on whitespace
 	terms := regexp.MustCompile(`\s+`).Split(text, -1)
-	// Filter out short terms and common stop words
-	stopWords := map[string]bool{
-		"the": true, "a": true, "an": true, "and": true, "or": true,
-		"but": true, "in": true, "on": true, "at": true, "to": true,
-		"for": true, "of": true, "with": true, "by": true, "is": true,
-		"are": true, "was": true, "were": true, "be": true, "been": true,
-		"have": true, "has": true, "had": true, "do": true, "does": true,
-		"did": true, "will": true, "would": true, "could": true, "should": true,
-	}
+	// Filter out short terms and configured stop words
 	var filtered []string
 	for _, term := range terms {
 		term = strings.TrimSpace(term)
-		if len(term) > 2 && !stopWords[term] {
+		if len(term) > 2 && !stop.Contains(term) {
 			filtered = append(filtered, term)
 		}
 	}
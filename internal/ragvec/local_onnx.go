@@ -0,0 +1,120 @@
+package ragvec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sync"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// onnxRunner executes a BERT-style encoder for a single sequence and returns
+// its last hidden state flattened as [len(ids) × dim].
+type onnxRunner interface {
+	Run(ids, mask, types []int64) ([]float32, error)
+}
+
+// newONNXRunner is provided by onnxruntime.go when built with -tags onnx.
+var newONNXRunner func(conf cfg.ONNXConfig, dim int) (onnxRunner, error)
+
+var errONNXNotBuilt = errors.New("local engine 'onnx' is not compiled in; rebuild with -tags onnx (see README)")
+
+// ONNXProvider embeds with a sentence-transformer (e.g. all-MiniLM-L6-v2):
+// WordPiece tokenization, one encoder pass, mean pooling over the attention
+// mask and L2 normalization.
+type ONNXProvider struct {
+	tok       *wordPiece
+	run       onnxRunner
+	dim       int
+	maxTokens int
+	model     string
+	mu        sync.Mutex // ONNX sessions are not safe for concurrent Run calls
+}
+
+func NewONNXProviderWithConfig(config *cfg.LocalEmbedding) (*ONNXProvider, error) {
+	if newONNXRunner == nil {
+		return nil, errONNXNotBuilt
+	}
+	tok, err := loadWordPiece(config.ONNX.VocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("load onnx vocab: %w", err)
+	}
+	run, err := newONNXRunner(config.ONNX, config.Dim)
+	if err != nil {
+		return nil, fmt.Errorf("load onnx model: %w", err)
+	}
+	return &ONNXProvider{
+		tok:       tok,
+		run:       run,
+		dim:       config.Dim,
+		maxTokens: config.ONNX.MaxTokens,
+		model:     onnxModelName(config),
+	}, nil
+}
+
+func (p *ONNXProvider) Dim() int { return p.dim }
+
+func (p *ONNXProvider) Embed(texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		ids := p.tok.encode(t, p.maxTokens)
+		mask := make([]int64, len(ids))
+		types := make([]int64, len(ids))
+		for j := range mask {
+			mask[j] = 1
+		}
+		p.mu.Lock()
+		hidden, err := p.run.Run(ids, mask, types)
+		p.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if len(hidden) != len(ids)*p.dim {
+			return nil, fmt.Errorf("onnx output has %d values, want %d×%d; check embedding.local.dim", len(hidden), len(ids), p.dim)
+		}
+		out[i] = meanPool(hidden, mask, p.dim)
+	}
+	return out, nil
+}
+
+// meanPool averages token vectors where mask is set and L2-normalizes.
+func meanPool(hidden []float32, mask []int64, dim int) []float32 {
+	vec := make([]float32, dim)
+	var n float32
+	for t, m := range mask {
+		if m == 0 {
+			continue
+		}
+		n++
+		row := hidden[t*dim : (t+1)*dim]
+		for d, v := range row {
+			vec[d] += v
+		}
+	}
+	var norm float64
+	for d := range vec {
+		if n > 0 {
+			vec[d] /= n
+		}
+		norm += float64(vec[d] * vec[d])
+	}
+	if norm > 0 {
+		inv := float32(1 / math.Sqrt(norm))
+		for d := range vec {
+			vec[d] *= inv
+		}
+	}
+	return vec
+}
+
+// onnxModelName identifies the model in embedding stamps, e.g.
+// "onnx:all-MiniLM-L6-v2" for all-MiniLM-L6-v2/model.onnx.
+func onnxModelName(config *cfg.LocalEmbedding) string {
+	dir := filepath.Base(filepath.Dir(config.ONNX.ModelPath))
+	if dir == "." || dir == string(filepath.Separator) {
+		dir = filepath.Base(config.ONNX.ModelPath)
+	}
+	return "onnx:" + dir
+}
@@ -0,0 +1,254 @@
+package ragvec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// Manifest describes what has been indexed into a collection and with which
+// settings. It is persisted as JSON next to the service (indexing.manifest_path).
+type Manifest struct {
+	Collection string                `json:"collection"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	Roots      map[string]*RootEntry `json:"roots"`
+}
+
+// RootEntry records one indexed directory and the settings used for it.
+type RootEntry struct {
+	Root         string            `json:"root"`
+	IndexedAt    time.Time         `json:"indexed_at"`
+	IncludeCode  bool              `json:"include_code"`
+	Files        int               `json:"files"`
+	Chunks       int               `json:"chunks"`
+	Provider     string            `json:"provider"`
+	Model        string            `json:"model"`
+	Dim          int               `json:"dim"`
+	ChunkSize    int               `json:"chunk_size"`
+	ChunkOverlap int               `json:"chunk_overlap"`
+	FileHashes   map[string]string `json:"file_hashes,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	ACL          []string          `json:"acl,omitempty"`
+	// ExpiresAt is set for runs indexed with a ttl
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// manifestStore guards loading and saving the manifest file.
+type manifestStore struct {
+	mu   sync.Mutex
+	path string
+	m    *Manifest
+}
+
+func newManifestStore(path, collection string) *manifestStore {
+	st := &manifestStore{path: path, m: &Manifest{Collection: collection, Roots: map[string]*RootEntry{}}}
+	if path == "" {
+		return st
+	}
+	if b, err := os.ReadFile(path); err == nil {
+		var m Manifest
+		if err := json.Unmarshal(b, &m); err == nil && m.Collection == collection {
+			if m.Roots == nil {
+				m.Roots = map[string]*RootEntry{}
+			}
+			st.m = &m
+		}
+	}
+	return st
+}
+
+func (s *manifestStore) saveLocked() error {
+	s.m.UpdatedAt = time.Now().UTC()
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(s.m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// snapshot returns a deep copy that callers may read without locking.
+func (s *manifestStore) snapshot() Manifest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := Manifest{Collection: s.m.Collection, UpdatedAt: s.m.UpdatedAt, Roots: map[string]*RootEntry{}}
+	for k, v := range s.m.Roots {
+		cp := *v
+		cp.FileHashes = make(map[string]string, len(v.FileHashes))
+		for p, h := range v.FileHashes {
+			cp.FileHashes[p] = h
+		}
+		out.Roots[k] = &cp
+	}
+	return out
+}
+
+// embeddingModel names the model behind the configured primary provider.
+func embeddingModel(config *cfg.Config) string {
+	return modelFor(config.Embedding.Provider, config)
+}
+
+// recordIngest stores the outcome of an IngestDocs run for dir, keyed by
+// opts.root when set.
+// hashes maps every indexed file path to its content hash.
+func (r *VecRAG) recordIngest(dir string, includeCode bool, opts IngestOptions, hashes map[string]string, indexed int) {
+	root := opts.root
+	if root == "" {
+		var err error
+		if root, err = filepath.Abs(dir); err != nil {
+			root = dir
+		}
+	}
+	entry := r.rootEntry(root, time.Now(), opts)
+	entry.IncludeCode = includeCode
+	entry.Files = len(hashes)
+	entry.Chunks = indexed
+	entry.FileHashes = hashes
+	r.saveRoot(entry)
+}
+
+// rootEntry returns a manifest entry for a run started at indexedAt with
+// the current embedding and chunking settings.
+func (r *VecRAG) rootEntry(root string, indexedAt time.Time, opts IngestOptions) *RootEntry {
+	entry := &RootEntry{
+		Root:         root,
+		IndexedAt:    indexedAt.UTC(),
+		Provider:     r.config.Embedding.Provider,
+		Model:        embeddingModel(r.config),
+		Dim:          r.embed.Dim(),
+		ChunkSize:    r.config.Indexing.ChunkSize,
+		ChunkOverlap: r.config.Indexing.ChunkOverlap,
+		Tags:         opts.Tags,
+		ACL:          opts.ACL,
+	}
+	if !opts.expiresAt.IsZero() {
+		t := opts.expiresAt.UTC()
+		entry.ExpiresAt = &t
+	}
+	return entry
+}
+
+// saveRoot stores entry under its root, replacing an earlier one.
+func (r *VecRAG) saveRoot(entry *RootEntry) {
+	r.manifest.mu.Lock()
+	defer r.manifest.mu.Unlock()
+	r.manifest.m.Roots[entry.Root] = entry
+	if err := r.manifest.saveLocked(); err != nil {
+		fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: failed to save index manifest: %v\n", err)
+	}
+}
+
+// forgetIndexed drops manifest entries after a delete. An empty project
+// clears the whole manifest.
+func (r *VecRAG) forgetIndexed(project string) {
+	r.manifest.mu.Lock()
+	defer r.manifest.mu.Unlock()
+	if project == "" {
+		r.manifest.m.Roots = map[string]*RootEntry{}
+	} else {
+		for key := range r.manifest.m.Roots {
+			r.manifest.dropProjectLocked(key, project)
+		}
+	}
+	if err := r.manifest.saveLocked(); err != nil {
+		fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: failed to save index manifest: %v\n", err)
+	}
+}
+
+// forgetFiles drops paths from the manifest entry of root.
+func (r *VecRAG) forgetFiles(root string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	r.manifest.mu.Lock()
+	defer r.manifest.mu.Unlock()
+	e := r.manifest.m.Roots[root]
+	if e == nil {
+		return
+	}
+	for _, p := range paths {
+		delete(e.FileHashes, p)
+	}
+	e.Files = len(e.FileHashes)
+	if err := r.manifest.saveLocked(); err != nil {
+		fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: failed to save index manifest: %v\n", err)
+	}
+}
+
+// forgetIndexedBefore drops the manifest entries of runs before t after a
+// purge, limited to one project's files when project is set.
+func (r *VecRAG) forgetIndexedBefore(t time.Time, project string) {
+	r.manifest.mu.Lock()
+	defer r.manifest.mu.Unlock()
+	for key, e := range r.manifest.m.Roots {
+		if !e.IndexedAt.Before(t) {
+			continue
+		}
+		if project == "" {
+			delete(r.manifest.m.Roots, key)
+		} else {
+			r.manifest.dropProjectLocked(key, project)
+		}
+	}
+	if err := r.manifest.saveLocked(); err != nil {
+		fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: failed to save index manifest: %v\n", err)
+	}
+}
+
+// dropProjectLocked removes project's files from the root entry at key,
+// dropping the entry once it is empty. The caller holds the lock.
+func (s *manifestStore) dropProjectLocked(key, project string) {
+	e := s.m.Roots[key]
+	for p := range e.FileHashes {
+		if projectFromPath(p) == project {
+			delete(e.FileHashes, p)
+		}
+	}
+	e.Files = len(e.FileHashes)
+	if e.Files == 0 {
+		delete(s.m.Roots, key)
+	}
+}
+
+// Manifest returns a copy of the current index manifest.
+func (r *VecRAG) Manifest() Manifest {
+	return r.manifest.snapshot()
+}
+
+// ConfigDrift lists differences between the settings used at index time and
+// the current configuration, e.g. a changed chunk size or embedding model.
+func (r *VecRAG) ConfigDrift() []string {
+	m := r.manifest.snapshot()
+	roots := make([]string, 0, len(m.Roots))
+	for k := range m.Roots {
+		roots = append(roots, k)
+	}
+	sort.Strings(roots)
+	var out []string
+	model := embeddingModel(r.config)
+	for _, k := range roots {
+		e := m.Roots[k]
+		if e.Provider != r.config.Embedding.Provider || e.Model != model {
+			out = append(out, fmt.Sprintf("%s was indexed with %s/%s but the current provider is %s/%s; similarity scores are not comparable, re-index it", k, e.Provider, e.Model, r.config.Embedding.Provider, model))
+		}
+		if e.Dim != r.embed.Dim() {
+			out = append(out, fmt.Sprintf("%s was indexed with dimension %d but the current provider produces %d", k, e.Dim, r.embed.Dim()))
+		}
+		if e.ChunkSize != r.config.Indexing.ChunkSize || e.ChunkOverlap != r.config.Indexing.ChunkOverlap {
+			out = append(out, fmt.Sprintf("%s was chunked with size=%d overlap=%d but current config is size=%d overlap=%d", k, e.ChunkSize, e.ChunkOverlap, r.config.Indexing.ChunkSize, r.config.Indexing.ChunkOverlap))
+		}
+	}
+	return out
+}
@@ -0,0 +1,312 @@
+package ragvec
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// ---------- In-memory store ----------
+
+// Memory is an in-process store with exact cosine search, meant for tests
+// and demos. Collections are shared per name within the process, so status
+// probes see the same data as the RAG system; with a path they are saved
+// with gob after every change and reloaded on start.
+type Memory struct {
+	coll    *memCollection
+	dim     int
+	breaker *CircuitBreaker
+}
+
+type memCollection struct {
+	mu      sync.RWMutex
+	name    string
+	path    string
+	created bool
+	dim     int
+	points  map[string]memPoint
+}
+
+type memPoint struct {
+	Vec     []float32
+	Payload map[string]any
+}
+
+// memSnapshot is the gob file layout.
+type memSnapshot struct {
+	Dim    int
+	Points map[string]memPoint
+}
+
+func init() {
+	// Concrete types that can appear inside payload values
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+	gob.Register([]string{})
+}
+
+var (
+	memMu          sync.Mutex
+	memCollections = map[string]*memCollection{}
+)
+
+func NewMemoryWithConfig(config *cfg.Config, dim int) *Memory {
+	name := config.CollectionName()
+	path := config.Store.Memory.Path
+	memMu.Lock()
+	defer memMu.Unlock()
+	key := name + "\x00" + path
+	c, ok := memCollections[key]
+	if !ok {
+		c = &memCollection{name: name, path: path, points: map[string]memPoint{}}
+		if err := c.load(); err != nil {
+			fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: memory store %s not loaded: %v\n", path, err)
+		}
+		memCollections[key] = c
+	}
+	return &Memory{coll: c, dim: dim, breaker: breakerFor("memory://"+key, 0, 0)}
+}
+
+// Breaker exposes a breaker for interface parity; it never opens.
+func (m *Memory) Breaker() *CircuitBreaker { return m.breaker }
+
+func (c *memCollection) load() error {
+	if c.path == "" {
+		return nil
+	}
+	f, err := os.Open(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var snap memSnapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return err
+	}
+	c.dim, c.created = snap.Dim, true
+	if snap.Points != nil {
+		c.points = snap.Points
+	}
+	return nil
+}
+
+// save writes the snapshot atomically; the caller holds the write lock.
+func (c *memCollection) save() error {
+	if c.path == "" {
+		return nil
+	}
+	if dir := filepath.Dir(c.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(memSnapshot{Dim: c.dim, Points: c.points}); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+func (m *Memory) HealthCheck() error { return nil }
+
+func (m *Memory) CollectionExists() (bool, error) {
+	m.coll.mu.RLock()
+	defer m.coll.mu.RUnlock()
+	return m.coll.created, nil
+}
+
+func (m *Memory) CollectionDim() (int, error) {
+	m.coll.mu.RLock()
+	defer m.coll.mu.RUnlock()
+	if !m.coll.created {
+		return 0, nil
+	}
+	return m.coll.dim, nil
+}
+
+func (m *Memory) EnsureCollection() error {
+	c := m.coll
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.created {
+		return nil
+	}
+	c.created, c.dim = true, m.dim
+	return c.save()
+}
+
+func (m *Memory) CountPoints() (int, error) {
+	m.coll.mu.RLock()
+	defer m.coll.mu.RUnlock()
+	return len(m.coll.points), nil
+}
+
+func (m *Memory) UpsertPoints(ids []string, vecs [][]float32, payloads []map[string]any) error {
+	c := m.coll
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, id := range ids {
+		if c.dim > 0 && len(vecs[i]) != c.dim {
+			return storeErrorf("memory", "upsert: vector has %d dimensions, collection %s has %d", len(vecs[i]), c.name, c.dim)
+		}
+		c.points[id] = memPoint{Vec: vecs[i], Payload: payloads[i]}
+	}
+	return c.save()
+}
+
+// Search scores every matching point; params are ignored since the search
+// is always exact.
+func (m *Memory) Search(vec []float32, k int, filter map[string]any, params SearchParams) ([]SearchHit, error) {
+	matches, err := filterMatches(filter)
+	if err != nil {
+		return nil, err
+	}
+	c := m.coll
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	hits := make([]SearchHit, 0, len(c.points))
+	for id, p := range c.points {
+		if !payloadMatches(p.Payload, matches) {
+			continue
+		}
+		hits = append(hits, SearchHit{ID: id, Score: cosine(vec, p.Vec), Payload: p.Payload})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID.(string) < hits[j].ID.(string)
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+func cosine(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
+
+func (m *Memory) DeleteByIDs(ids []any) error {
+	c := m.coll
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		delete(c.points, fmt.Sprint(id))
+	}
+	return c.save()
+}
+
+func (m *Memory) SetPayload(filter map[string]any, patch map[string]any) error {
+	matches, err := filterMatches(filter)
+	if err != nil {
+		return err
+	}
+	c := m.coll
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, p := range c.points {
+		if payloadMatches(p.Payload, matches) {
+			c.patch(id, patch)
+		}
+	}
+	return c.save()
+}
+
+func (m *Memory) SetPayloadByIDs(ids []any, patch map[string]any) error {
+	c := m.coll
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		c.patch(fmt.Sprint(id), patch)
+	}
+	return c.save()
+}
+
+// patch merges into a copy of the payload, since earlier search results may
+// still hold the old map. The caller holds the write lock.
+func (c *memCollection) patch(id string, patch map[string]any) {
+	p, ok := c.points[id]
+	if !ok {
+		return
+	}
+	payload := make(map[string]any, len(p.Payload)+len(patch))
+	for k, v := range p.Payload {
+		payload[k] = v
+	}
+	for k, v := range patch {
+		payload[k] = v
+	}
+	c.points[id] = memPoint{Vec: p.Vec, Payload: payload}
+}
+
+func (m *Memory) ScrollPoints(limit int, offset any) ([]ScrollPoint, any, error) {
+	return m.ScrollPointsWithFilter(limit, offset, nil)
+}
+
+// ScrollPointsWithFilter pages in ID order; offset is the first ID of the
+// next page, so deleting points during the walk skips nothing.
+func (m *Memory) ScrollPointsWithFilter(limit int, offset any, filter map[string]any) ([]ScrollPoint, any, error) {
+	if limit <= 0 || limit > 10000 {
+		limit = 1000
+	}
+	matches, err := filterMatches(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := m.coll
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ids := make([]string, 0, len(c.points))
+	for id, p := range c.points {
+		if payloadMatches(p.Payload, matches) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	start := 0
+	if offset != nil {
+		start = sort.SearchStrings(ids, fmt.Sprint(offset))
+	}
+	end := min(start+limit, len(ids))
+	pts := make([]ScrollPoint, 0, end-start)
+	for _, id := range ids[start:end] {
+		pts = append(pts, ScrollPoint{ID: id, Payload: c.points[id].Payload})
+	}
+	var next any
+	if end < len(ids) {
+		next = ids[end]
+	}
+	return pts, next, nil
+}
@@ -0,0 +1,104 @@
+package ragvec
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// mergeAdjacent collapses hits that are consecutive chunks of the same file
+// into a single passage. Overlapping windows repeat up to overlap runes at
+// the chunk boundary; the repeated part is emitted once. The merged hit keeps
+// the best score of its parts and lists the merged positions. Entries of
+// different changelog versions and different email messages stay apart.
+func mergeAdjacent(items []Hit, overlap int) []Hit {
+	if len(items) < 2 {
+		return items
+	}
+	byPath := map[string][]Hit{}
+	var paths []string
+	for _, it := range items {
+		p := it.Path
+		if it.Kind == KindSummary {
+			// Summaries sit outside the window sequence; never merge them
+			p += "#" + KindSummary
+		}
+		if _, ok := byPath[p]; !ok {
+			paths = append(paths, p)
+		}
+		byPath[p] = append(byPath[p], it)
+	}
+	out := make([]Hit, 0, len(items))
+	for _, p := range paths {
+		group := byPath[p]
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Position < group[j].Position })
+		cur := group[0]
+		for _, next := range group[1:] {
+			if next.Position == lastPosition(cur)+1 && sameEntry(cur, next) {
+				cur = joinHits(cur, next, overlap)
+				continue
+			}
+			out = append(out, cur)
+			cur = next
+		}
+		out = append(out, cur)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// sameEntry reports whether two chunks of a file belong to the same
+// changelog version and email message.
+func sameEntry(a, b Hit) bool {
+	return a.Version == b.Version && a.Metadata["sent_at"] == b.Metadata["sent_at"] && a.Metadata["from"] == b.Metadata["from"] && a.Metadata["title"] == b.Metadata["title"]
+}
+
+func joinHits(a, b Hit, overlap int) Hit {
+	merged := a
+	positions := a.MergedPositions
+	if positions == nil {
+		positions = []int{a.Position}
+	}
+	merged.MergedPositions = append(slices.Clip(positions), b.Position)
+	merged.Score = max(a.Score, b.Score)
+	if a.SourceRange != nil {
+		if b.SourceRange != nil {
+			r := *a.SourceRange
+			r.EndByte = b.EndByte
+			merged.SourceRange = &r
+		} else {
+			merged.SourceRange = nil
+		}
+	}
+	if b.EndTime != "" {
+		merged.EndTime = b.EndTime
+	}
+	if a.Text != "" || b.Text != "" {
+		merged.Text = a.Text + b.Text[sharedBoundary(a.Text, b.Text, overlap):]
+		merged.Snippet = preview(merged.Text, 240)
+	}
+	return merged
+}
+
+// sharedBoundary returns the byte length of the longest suffix of a (at most
+// max runes) that is also a prefix of b.
+func sharedBoundary(a, b string, max int) int {
+	ra := []rune(a)
+	if max > len(ra) {
+		max = len(ra)
+	}
+	for n := max; n > 0; n-- {
+		tail := string(ra[len(ra)-n:])
+		if strings.HasPrefix(b, tail) {
+			return len(tail)
+		}
+	}
+	return 0
+}
+
+func lastPosition(it Hit) int {
+	if ps := it.MergedPositions; len(ps) > 0 {
+		return ps[len(ps)-1]
+	}
+	return it.Position
+}
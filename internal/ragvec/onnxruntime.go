@@ -0,0 +1,67 @@
+//go:build onnx
+
+// Build with: go get github.com/yalue/onnxruntime_go && go build -tags onnx
+// The ONNX Runtime shared library must be installed separately.
+
+package ragvec
+
+import (
+	ort "github.com/yalue/onnxruntime_go"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+func init() {
+	newONNXRunner = newORTRunner
+}
+
+type ortRunner struct {
+	session *ort.DynamicAdvancedSession
+	dim     int
+}
+
+func newORTRunner(conf cfg.ONNXConfig, dim int) (onnxRunner, error) {
+	if !ort.IsInitialized() {
+		if conf.RuntimeLib != "" {
+			ort.SetSharedLibraryPath(conf.RuntimeLib)
+		}
+		if err := ort.InitializeEnvironment(); err != nil {
+			return nil, err
+		}
+	}
+	s, err := ort.NewDynamicAdvancedSession(conf.ModelPath,
+		[]string{"input_ids", "attention_mask", "token_type_ids"},
+		[]string{"last_hidden_state"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ortRunner{session: s, dim: dim}, nil
+}
+
+func (r *ortRunner) Run(ids, mask, types []int64) ([]float32, error) {
+	shape := ort.NewShape(1, int64(len(ids)))
+	idsT, err := ort.NewTensor(shape, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer idsT.Destroy()
+	maskT, err := ort.NewTensor(shape, mask)
+	if err != nil {
+		return nil, err
+	}
+	defer maskT.Destroy()
+	typesT, err := ort.NewTensor(shape, types)
+	if err != nil {
+		return nil, err
+	}
+	defer typesT.Destroy()
+	out, err := ort.NewEmptyTensor[float32](ort.NewShape(1, int64(len(ids)), int64(r.dim)))
+	if err != nil {
+		return nil, err
+	}
+	defer out.Destroy()
+	if err := r.session.Run([]ort.Value{idsT, maskT, typesT}, []ort.Value{out}); err != nil {
+		return nil, err
+	}
+	return append([]float32(nil), out.GetData()...), nil
+}
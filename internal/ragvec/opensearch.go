@@ -0,0 +1,591 @@
+package ragvec
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// ---------- OpenSearch client ----------
+
+// OpenSearch stores each point as a document holding the vector (knn_vector),
+// the chunk text (BM25) and the payload. With hybrid enabled, searches run a
+// native hybrid query through a normalization search pipeline so lexical
+// and semantic scores are combined server-side.
+type OpenSearch struct {
+	baseURL    string
+	user, pass string
+	index      string
+	dim        int
+	hybrid     bool
+	textWeight float64
+	client     *http.Client
+	breaker    *CircuitBreaker
+	requestID  string
+}
+
+func NewOpenSearchWithConfig(config *cfg.Config, dim int) *OpenSearch {
+	oc := config.Store.OpenSearch
+	base := strings.TrimRight(oc.URL, "/")
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if oc.InsecureSkipVerify {
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &OpenSearch{
+		baseURL:    base,
+		user:       oc.Username,
+		pass:       oc.Password,
+		index:      config.CollectionName(),
+		dim:        dim,
+		hybrid:     oc.Hybrid,
+		textWeight: oc.TextWeight,
+		client:     &http.Client{Transport: tr},
+		breaker:    breakerFor(base, config.Qdrant.BreakerThreshold, time.Duration(config.Qdrant.BreakerCooldownSec)*time.Second),
+	}
+}
+
+// Breaker exposes the circuit breaker guarding this cluster.
+func (o *OpenSearch) Breaker() *CircuitBreaker { return o.breaker }
+
+func (o *OpenSearch) withRequestID(id string) VectorStore {
+	c := *o
+	c.requestID = id
+	return &c
+}
+
+// do sends a request through the circuit breaker. body is JSON-encoded
+// unless it is already a []byte (NDJSON for _bulk). The caller closes the
+// response body.
+func (o *OpenSearch) do(method, path string, body any, timeout time.Duration) (*http.Response, error) {
+	if err := o.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	return o.send(method, path, body, timeout)
+}
+
+// send performs the request without consulting the breaker, but still
+// records its outcome so probes can close an open circuit.
+func (o *OpenSearch) send(method, path string, body any, timeout time.Duration) (*http.Response, error) {
+	var rd io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		rd = bytes.NewReader(b)
+		contentType = "application/x-ndjson"
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, o.baseURL+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	if rd != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if o.user != "" || o.pass != "" {
+		req.SetBasicAuth(o.user, o.pass)
+	}
+	if o.requestID != "" {
+		req.Header.Set(RequestIDHeader, o.requestID)
+	}
+	client := *o.client
+	client.Timeout = timeout
+	res, err := client.Do(req)
+	if err != nil {
+		o.breaker.Record(err)
+		return nil, apierr.Wrap(apierr.Store, "opensearch", err)
+	}
+	if res.StatusCode >= 500 {
+		o.breaker.Record(fmt.Errorf("http %d", res.StatusCode))
+	} else {
+		o.breaker.Record(nil)
+	}
+	return res, nil
+}
+
+// decodeOS reads a JSON response into out, turning statuses >= 300 into an
+// error that names op and includes OpenSearch's error reason.
+func decodeOS(res *http.Response, op string, out any) error {
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&e)
+		if e.Error.Reason != "" {
+			return storeErrorf("opensearch", "%s http %d: %s", op, res.StatusCode, e.Error.Reason)
+		}
+		return storeErrorf("opensearch", "%s http %d", op, res.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func (o *OpenSearch) pipeline() string { return o.index + "-hybrid" }
+
+// HealthCheck queries the cluster root, bypassing an open circuit so that it
+// can serve as the recovery probe.
+func (o *OpenSearch) HealthCheck() error {
+	res, err := o.send("GET", "/", nil, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	return decodeOS(res, "health", nil)
+}
+
+func (o *OpenSearch) CollectionExists() (bool, error) {
+	res, err := o.do("HEAD", "/"+o.index, nil, 5*time.Second)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	switch {
+	case res.StatusCode == 404:
+		return false, nil
+	case res.StatusCode >= 300:
+		return false, storeErrorf("opensearch", "get collection http %d", res.StatusCode)
+	}
+	return true, nil
+}
+
+// CollectionDim reads the dimension of the vector field mapping.
+func (o *OpenSearch) CollectionDim() (int, error) {
+	res, err := o.do("GET", "/"+o.index+"/_mapping", nil, 5*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	if res.StatusCode == 404 {
+		res.Body.Close()
+		return 0, nil
+	}
+	var rr map[string]struct {
+		Mappings struct {
+			Properties struct {
+				Vector struct {
+					Dimension int `json:"dimension"`
+				} `json:"vector"`
+			} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := decodeOS(res, "get mapping", &rr); err != nil {
+		return 0, err
+	}
+	for _, ix := range rr {
+		return ix.Mappings.Properties.Vector.Dimension, nil
+	}
+	return 0, nil
+}
+
+// EnsureCollection creates the index (k-NN enabled, HNSW/cosine) when
+// missing and, with hybrid enabled, (re)writes the normalization pipeline so
+// text_weight changes apply without re-indexing.
+func (o *OpenSearch) EnsureCollection() error {
+	ok, err := o.CollectionExists()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		keyword := map[string]any{"type": "keyword"}
+		body := map[string]any{
+			"settings": map[string]any{"index": map[string]any{"knn": true}},
+			"mappings": map[string]any{
+				"properties": map[string]any{
+					"vector": map[string]any{
+						"type":      "knn_vector",
+						"dimension": o.dim,
+						"method":    map[string]any{"name": "hnsw", "space_type": "cosinesimil", "engine": "lucene"},
+					},
+					"text":             map[string]any{"type": "text"},
+					"id":               keyword,
+					"project":          keyword,
+					"path":             keyword,
+					"file_type":        keyword,
+					"kind":             keyword,
+					"tags":             keyword,
+					"version_prefixes": keyword,
+					"acl":              keyword,
+					"indexed_at":       map[string]any{"type": "long"},
+					"modified_at":      map[string]any{"type": "long"},
+					"expires_at":       map[string]any{"type": "long"},
+					"deleted_at":       map[string]any{"type": "long"},
+					"payload":          map[string]any{"type": "object", "enabled": false},
+				},
+			},
+		}
+		res, err := o.do("PUT", "/"+o.index, body, 30*time.Second)
+		if err != nil {
+			return err
+		}
+		if err := decodeOS(res, "create collection", nil); err != nil {
+			return err
+		}
+	} else {
+		// Indexes created before these fields existed would map them
+		// dynamically (tags as text)
+		body := map[string]any{"properties": map[string]any{
+			"tags":             map[string]any{"type": "keyword"},
+			"version_prefixes": map[string]any{"type": "keyword"},
+			"acl":              map[string]any{"type": "keyword"},
+			"indexed_at":       map[string]any{"type": "long"},
+			"modified_at":      map[string]any{"type": "long"},
+			"expires_at":       map[string]any{"type": "long"},
+			"deleted_at":       map[string]any{"type": "long"},
+		}}
+		res, err := o.do("PUT", "/"+o.index+"/_mapping", body, 10*time.Second)
+		if err != nil {
+			return err
+		}
+		if err := decodeOS(res, "update mapping", nil); err != nil {
+			return err
+		}
+	}
+	if !o.hybrid {
+		return nil
+	}
+	pipe := map[string]any{
+		"description": "mcp-rag hybrid BM25 + k-NN score combination",
+		"phase_results_processors": []any{map[string]any{
+			"normalization-processor": map[string]any{
+				"normalization": map[string]any{"technique": "min_max"},
+				"combination": map[string]any{
+					"technique":  "arithmetic_mean",
+					"parameters": map[string]any{"weights": []float64{o.textWeight, 1 - o.textWeight}},
+				},
+			},
+		}},
+	}
+	res, err := o.do("PUT", "/_search/pipeline/"+o.pipeline(), pipe, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	return decodeOS(res, "create search pipeline", nil)
+}
+
+func (o *OpenSearch) CountPoints() (int, error) {
+	res, err := o.do("GET", "/"+o.index+"/_count", nil, 10*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	var rr struct {
+		Count int `json:"count"`
+	}
+	if err := decodeOS(res, "count", &rr); err != nil {
+		return 0, err
+	}
+	return rr.Count, nil
+}
+
+// bulk sends NDJSON to _bulk, waiting for a refresh so the change is
+// visible to the next search, and reports the first item failure.
+func (o *OpenSearch) bulk(op string, ndjson []byte) error {
+	res, err := o.do("POST", "/_bulk?refresh=wait_for", ndjson, 60*time.Second)
+	if err != nil {
+		return err
+	}
+	type item struct {
+		Status int `json:"status"`
+		Error  any `json:"error"`
+	}
+	var rr struct {
+		Errors bool              `json:"errors"`
+		Items  []map[string]item `json:"items"`
+	}
+	if err := decodeOS(res, op, &rr); err != nil {
+		return err
+	}
+	if rr.Errors {
+		for _, it := range rr.Items {
+			for _, r := range it {
+				if r.Error != nil && r.Status != 404 {
+					return storeErrorf("opensearch", "%s http %d: %v", op, r.Status, r.Error)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (o *OpenSearch) UpsertPoints(ids []string, vecs [][]float32, payloads []map[string]any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i, id := range ids {
+		doc := map[string]any{"id": id, "vector": vecs[i], "payload": payloads[i], "text": payloads[i]["text"]}
+		for _, f := range append(slices.Clone(filterFields), rangeFields...) {
+			if v, ok := payloads[i][f]; ok {
+				doc[f] = v
+			}
+		}
+		if err := enc.Encode(map[string]any{"index": map[string]any{"_index": o.index, "_id": id}}); err != nil {
+			return err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return o.bulk("upsert", buf.Bytes())
+}
+
+func (o *OpenSearch) DeleteByIDs(ids []any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, id := range ids {
+		if err := enc.Encode(map[string]any{"delete": map[string]any{"_index": o.index, "_id": fmt.Sprint(id)}}); err != nil {
+			return err
+		}
+	}
+	return o.bulk("delete", buf.Bytes())
+}
+
+// SetPayload patches the matching documents with _update_by_query: the
+// stored payload and, for filter and range fields, the top-level copies.
+func (o *OpenSearch) SetPayload(filter map[string]any, patch map[string]any) error {
+	filters, err := termFilters(filter)
+	if err != nil {
+		return err
+	}
+	return o.updateByQuery(map[string]any{"bool": map[string]any{"filter": filters}}, patch)
+}
+
+func (o *OpenSearch) SetPayloadByIDs(ids []any, patch map[string]any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = fmt.Sprint(id)
+	}
+	return o.updateByQuery(map[string]any{"ids": map[string]any{"values": values}}, patch)
+}
+
+func (o *OpenSearch) updateByQuery(query map[string]any, patch map[string]any) error {
+	body := map[string]any{
+		"query": query,
+		"script": map[string]any{
+			"lang": "painless",
+			"source": "for (e in params.patch.entrySet()) { ctx._source.payload[e.getKey()] = e.getValue(); " +
+				"if (params.fields.contains(e.getKey())) { ctx._source[e.getKey()] = e.getValue(); } }",
+			"params": map[string]any{"patch": patch, "fields": append(slices.Clone(filterFields), rangeFields...)},
+		},
+	}
+	res, err := o.do("POST", "/"+o.index+"/_update_by_query?refresh=true&conflicts=proceed", body, 120*time.Second)
+	if err != nil {
+		return err
+	}
+	var rr struct {
+		Failures []any `json:"failures"`
+	}
+	if err := decodeOS(res, "set payload", &rr); err != nil {
+		return err
+	}
+	if len(rr.Failures) > 0 {
+		return fmt.Errorf("set payload: %v", rr.Failures[0])
+	}
+	return nil
+}
+
+// termFilters renders filter as a list of term (or, for any-of matches,
+// terms, and for range conditions, range) queries. must_not conditions are
+// wrapped in a bool query.
+func termFilters(filter map[string]any) ([]any, error) {
+	matches, err := filterMatches(filter)
+	if err != nil {
+		return nil, err
+	}
+	out := []any{}
+	for _, m := range matches {
+		var q map[string]any
+		if m.Range != nil {
+			q = map[string]any{"range": map[string]any{m.Key: m.Range}}
+		} else if len(m.Any) == 1 {
+			q = map[string]any{"term": map[string]any{m.Key: m.Any[0]}}
+		} else {
+			q = map[string]any{"terms": map[string]any{m.Key: m.Any}}
+		}
+		if m.Not {
+			q = map[string]any{"bool": map[string]any{"must_not": []any{q}}}
+		}
+		out = append(out, q)
+	}
+	return out, nil
+}
+
+type osHits struct {
+	Hits struct {
+		Hits []struct {
+			ID     string         `json:"_id"`
+			Score  float32        `json:"_score"`
+			Source map[string]any `json:"_source"`
+			Sort   []any          `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (o *OpenSearch) search(path string, body map[string]any, op string) (*osHits, error) {
+	res, err := o.do("POST", path, body, 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	var rr osHits
+	if err := decodeOS(res, op, &rr); err != nil {
+		return nil, err
+	}
+	return &rr, nil
+}
+
+// knnQuery builds the vector clause. Exact uses the k-NN plugin's scoring
+// script, a brute-force scan over the filtered documents.
+func (o *OpenSearch) knnQuery(vec []float32, k int, filters []any, params SearchParams) map[string]any {
+	if params.Exact {
+		base := map[string]any{"match_all": map[string]any{}}
+		if len(filters) > 0 {
+			base = map[string]any{"bool": map[string]any{"filter": filters}}
+		}
+		return map[string]any{"script_score": map[string]any{
+			"query": base,
+			"script": map[string]any{
+				"source": "knn_score",
+				"lang":   "knn",
+				"params": map[string]any{"field": "vector", "query_value": vec, "space_type": "cosinesimil"},
+			},
+		}}
+	}
+	knn := map[string]any{"vector": vec, "k": k}
+	if len(filters) > 0 {
+		knn["filter"] = map[string]any{"bool": map[string]any{"filter": filters}}
+	}
+	if params.HNSWEf > 0 {
+		knn["method_parameters"] = map[string]any{"ef_search": params.HNSWEf}
+	}
+	return map[string]any{"knn": map[string]any{"vector": knn}}
+}
+
+func (o *OpenSearch) Search(vec []float32, k int, filter map[string]any, params SearchParams) ([]SearchHit, error) {
+	return o.SearchText("", vec, k, filter, params)
+}
+
+// SearchText runs a hybrid query when hybrid is enabled and text is given;
+// exact searches and empty text fall back to vectors only.
+func (o *OpenSearch) SearchText(text string, vec []float32, k int, filter map[string]any, params SearchParams) ([]SearchHit, error) {
+	filters, err := termFilters(filter)
+	if err != nil {
+		return nil, err
+	}
+	path := "/" + o.index + "/_search"
+	query := o.knnQuery(vec, k, filters, params)
+	if o.hybrid && strings.TrimSpace(text) != "" && !params.Exact {
+		lexical := map[string]any{"bool": map[string]any{
+			"must":   map[string]any{"match": map[string]any{"text": text}},
+			"filter": filters,
+		}}
+		query = map[string]any{"hybrid": map[string]any{"queries": []any{lexical, query}}}
+		path += "?search_pipeline=" + o.pipeline()
+	}
+	rr, err := o.search(path, map[string]any{"size": k, "query": query, "_source": []string{"payload"}}, "search")
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]SearchHit, 0, len(rr.Hits.Hits))
+	for _, h := range rr.Hits.Hits {
+		p, _ := h.Source["payload"].(map[string]any)
+		hits = append(hits, SearchHit{ID: h.ID, Score: h.Score, Payload: p})
+	}
+	return hits, nil
+}
+
+// ExplainScores scores the documents ids by BM25 on text and by exact
+// vector similarity to vec, apart, to break down a hybrid ranking. Documents
+// without the query terms have no text score.
+func (o *OpenSearch) ExplainScores(text string, vec []float32, ids []any) (map[string]float32, map[string]float32, error) {
+	byIDs := []any{map[string]any{"ids": map[string]any{"values": ids}}}
+	path := "/" + o.index + "/_search"
+	scores := func(query map[string]any) (map[string]float32, error) {
+		rr, err := o.search(path, map[string]any{"size": len(ids), "query": query, "_source": false}, "explain")
+		if err != nil {
+			return nil, err
+		}
+		out := map[string]float32{}
+		for _, h := range rr.Hits.Hits {
+			out[h.ID] = h.Score
+		}
+		return out, nil
+	}
+	textScores, err := scores(map[string]any{"bool": map[string]any{
+		"must":   map[string]any{"match": map[string]any{"text": text}},
+		"filter": byIDs,
+	}})
+	if err != nil {
+		return nil, nil, err
+	}
+	vectorScores, err := scores(o.knnQuery(vec, len(ids), byIDs, SearchParams{Exact: true}))
+	if err != nil {
+		return nil, nil, err
+	}
+	return textScores, vectorScores, nil
+}
+
+func (o *OpenSearch) ScrollPoints(limit int, offset any) ([]ScrollPoint, any, error) {
+	return o.ScrollPointsWithFilter(limit, offset, nil)
+}
+
+// ScrollPointsWithFilter pages with search_after on the id field, which stays
+// consistent while points are deleted during the walk.
+func (o *OpenSearch) ScrollPointsWithFilter(limit int, offset any, filter map[string]any) ([]ScrollPoint, any, error) {
+	if limit <= 0 || limit > 10000 {
+		limit = 1000
+	}
+	filters, err := termFilters(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := map[string]any{"match_all": map[string]any{}}
+	if len(filters) > 0 {
+		query = map[string]any{"bool": map[string]any{"filter": filters}}
+	}
+	body := map[string]any{
+		"size":    limit,
+		"query":   query,
+		"sort":    []any{map[string]any{"id": "asc"}},
+		"_source": []string{"payload"},
+	}
+	if offset != nil {
+		body["search_after"] = []any{offset}
+	}
+	rr, err := o.search("/"+o.index+"/_search", body, "scroll")
+	if err != nil {
+		return nil, nil, err
+	}
+	pts := make([]ScrollPoint, 0, len(rr.Hits.Hits))
+	var last any
+	for _, h := range rr.Hits.Hits {
+		p, _ := h.Source["payload"].(map[string]any)
+		pts = append(pts, ScrollPoint{ID: h.ID, Payload: p})
+		if len(h.Sort) > 0 {
+			last = h.Sort[0]
+		}
+	}
+	if len(pts) < limit {
+		last = nil
+	}
+	return pts, last, nil
+}
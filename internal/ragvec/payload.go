@@ -0,0 +1,105 @@
+package ragvec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// reservedPayloadKeys are written by the indexer; patching them would
+// desynchronize the payload from the file and vector it describes. project
+// has its own rename operation.
+var reservedPayloadKeys = map[string]bool{
+	"path": true, "basename": true, "position": true, "preview": true, "text": true,
+	"file_type": true, "project": true, "file_hash": true, "kind": true, "summary_of": true, "section": true, "chapter": true, "symbols": true,
+	"version": true, "version_prefixes": true, "release_date": true, "referenced_by": true,
+	"start_time": true, "end_time": true, "from": true, "sent_at": true, "thread_id": true,
+	"channel": true, "started_at": true, "ended_at": true,
+	"start_byte": true, "end_byte": true, "indexed_at": true, "modified_at": true, "expires_at": true, "url": true, "title": true,
+	"repo": true, "ref": true, "commit": true, "bucket": true, "object_key": true, "version_id": true,
+	"embed_provider": true, "embed_model": true, "embed_dim": true,
+}
+
+// PayloadSelector picks the points a payload patch applies to: exact
+// matches on payload fields and an optional path prefix. An empty selector
+// matches every point.
+type PayloadSelector struct {
+	Match      map[string]string `json:"match"`
+	PathPrefix string            `json:"path_prefix"`
+}
+
+// Empty reports whether the selector matches every point.
+func (s PayloadSelector) Empty() bool {
+	return len(s.Match) == 0 && s.PathPrefix == ""
+}
+
+// matchFilter builds a must-list of exact matches, or nil for no matches.
+func matchFilter(match map[string]string) map[string]any {
+	if len(match) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(match))
+	for k := range match {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	must := make([]map[string]any, 0, len(keys))
+	for _, k := range keys {
+		must = append(must, map[string]any{"key": k, "match": map[string]any{"value": match[k]}})
+	}
+	return map[string]any{"must": must}
+}
+
+// PatchPayload merges patch into the payload of the selected points and
+// returns how many were matched. Without a path prefix the store applies
+// the patch server-side by filter; with one, matching IDs are collected by
+// scrolling and patched in batches. dryRun only counts.
+func (r *VecRAG) PatchPayload(sel PayloadSelector, patch map[string]any, dryRun bool) (int, error) {
+	if len(patch) == 0 {
+		return 0, fmt.Errorf("empty patch")
+	}
+	for k := range patch {
+		if strings.TrimSpace(k) == "" || reservedPayloadKeys[k] {
+			return 0, fmt.Errorf("payload key %q cannot be patched", k)
+		}
+	}
+	filter := matchFilter(sel.Match)
+	if !dryRun {
+		defer r.results.invalidate(sel.Match["project"])
+	}
+	if sel.PathPrefix == "" {
+		n, err := r.countWhere(filter)
+		if err != nil || n == 0 || dryRun {
+			return n, err
+		}
+		return n, r.vdb.SetPayload(filter, patch)
+	}
+
+	var ids []any
+	var offset any
+	for {
+		pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offset, filter)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range pts {
+			if strings.HasPrefix(toStr(p.Payload["path"]), sel.PathPrefix) {
+				ids = append(ids, p.ID)
+			}
+		}
+		if next == nil {
+			break
+		}
+		offset = next
+	}
+	if dryRun {
+		return len(ids), nil
+	}
+	for start := 0; start < len(ids); start += 1000 {
+		end := min(start+1000, len(ids))
+		if err := r.vdb.SetPayloadByIDs(ids[start:end], patch); err != nil {
+			return start, err
+		}
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,90 @@
+package ragvec
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// projectCache memoizes the project aggregation. Within the TTL the cached
+// list is served as-is; once stale it is still served while a single
+// background refresh runs. Index and delete operations invalidate it so the
+// next read reloads synchronously.
+type projectCache struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	list       []map[string]any
+	at         time.Time
+	refreshing bool
+	gen        uint64 // bumped by invalidate; refreshes started earlier are discarded
+}
+
+func newProjectCache(ttl time.Duration) *projectCache {
+	return &projectCache{ttl: ttl}
+}
+
+func (c *projectCache) get(load func() ([]map[string]any, error)) ([]map[string]any, error) {
+	if c == nil || c.ttl <= 0 {
+		return load()
+	}
+	c.mu.Lock()
+	if c.list != nil {
+		list := c.list
+		if time.Since(c.at) >= c.ttl && !c.refreshing {
+			c.refreshing = true
+			go c.refresh(c.gen, load)
+		}
+		c.mu.Unlock()
+		return copyList(list), nil
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	list, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.store(gen, list)
+	return copyList(list), nil
+}
+
+func (c *projectCache) refresh(gen uint64, load func() ([]map[string]any, error)) {
+	list, err := load()
+	c.mu.Lock()
+	c.refreshing = false
+	c.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: project cache refresh failed: %v\n", err)
+		return
+	}
+	c.store(gen, list)
+}
+
+func (c *projectCache) store(gen uint64, list []map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	c.list = list
+	c.at = time.Now()
+}
+
+func (c *projectCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.list = nil
+	c.gen++
+}
+
+// copyList lets callers filter the slice in place without touching the cache.
+func copyList(list []map[string]any) []map[string]any {
+	out := make([]map[string]any, len(list))
+	copy(out, list)
+	return out
+}
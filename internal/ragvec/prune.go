@@ -0,0 +1,70 @@
+package ragvec
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+)
+
+// PruneResult reports the files PruneMissing found gone from disk.
+type PruneResult struct {
+	Chunks int `json:"chunks"`
+	// Files lists the first maxPreviewFiles paths; FileCount counts all
+	Files     []string `json:"files"`
+	FileCount int      `json:"file_count"`
+}
+
+// PruneMissing deletes the chunks of files that the previous rag_index of
+// dir recorded in the manifest but that no longer exist on disk, and drops
+// them from the manifest. Files that merely became excluded or too large
+// are kept. A directory that was never indexed has nothing to prune. A root
+// that cannot be read, e.g. an unmounted volume, fails the call instead of
+// counting every file as gone.
+func (r *VecRAG) PruneMissing(dir string) (PruneResult, error) {
+	res := PruneResult{Files: []string{}}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		root = dir
+	}
+	if info, err := os.Stat(root); err != nil {
+		return res, apierr.Wrap(apierr.Validation, "indexing", fmt.Errorf("not pruning %s: %w", dir, err))
+	} else if !info.IsDir() {
+		return res, apierr.Wrap(apierr.Validation, "indexing", fmt.Errorf("not pruning %s: not a directory", dir))
+	}
+	prev := r.manifest.snapshot().Roots[root]
+	if prev == nil {
+		return res, nil
+	}
+	var missing []string
+	for path := range prev.FileHashes {
+		if _, err := os.Lstat(chunker.DiskPath(path)); errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) == 0 {
+		return res, nil
+	}
+	sort.Strings(missing)
+	defer r.projects.invalidate()
+	var delErr error
+	for i, path := range missing {
+		var n int
+		n, delErr = r.deleteWhere(matchFilter(map[string]string{"path": path}))
+		res.Chunks += n
+		if delErr != nil {
+			// Keep the failed file in the manifest so the next run retries it
+			missing = missing[:i]
+			break
+		}
+	}
+	r.forgetFiles(root, missing)
+	res.FileCount = len(missing)
+	res.Files = missing[:min(len(missing), maxPreviewFiles)]
+	return res, delErr
+}
@@ -0,0 +1,28 @@
+package ragvec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPruneMissingNeedsRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "docs")
+	if err := os.Mkdir(root, 0700); err != nil {
+		t.Fatal(err)
+	}
+	r := &VecRAG{manifest: newManifestStore("", "test")}
+	r.manifest.m.Roots[root] = &RootEntry{Root: root, FileHashes: map[string]string{filepath.Join(root, "a.md"): "h1"}}
+
+	// An unmounted root looks like every file was deleted
+	if err := os.Remove(root); err != nil {
+		t.Fatal(err)
+	}
+	res, err := r.PruneMissing(root)
+	if err == nil {
+		t.Fatalf("pruned %d files of a missing root, want an error", res.FileCount)
+	}
+	if got := len(r.manifest.snapshot().Roots[root].FileHashes); got != 1 {
+		t.Errorf("manifest keeps %d files, want 1", got)
+	}
+}
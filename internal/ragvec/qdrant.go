@@ -0,0 +1,577 @@
+package ragvec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// ---------- Qdrant minimal client ----------
+type Qdrant struct {
+	baseURL    string
+	collection string
+	dim        int
+	breaker    *CircuitBreaker
+	tuning     cfg.CollectionConfig
+	retry      retryPolicy
+	requestID  string
+}
+
+func NewQdrantWithConfig(config *cfg.QdrantConfig, dim int) *Qdrant {
+	base := strings.TrimRight(config.URL, "/")
+	return &Qdrant{
+		baseURL:    base,
+		collection: config.Collection,
+		dim:        dim,
+		breaker:    breakerFor(base, config.BreakerThreshold, time.Duration(config.BreakerCooldownSec)*time.Second),
+		tuning:     config.CollectionConfig,
+		retry:      newRetryPolicy(config.Retry),
+	}
+}
+
+func NewQdrant(dim int) *Qdrant {
+	u := os.Getenv("QDRANT_URL")
+	if u == "" {
+		u = "http://localhost:6333"
+	}
+	coll := os.Getenv("QDRANT_COLLECTION")
+	if coll == "" {
+		coll = DefaultCollection
+	}
+	base := strings.TrimRight(u, "/")
+	return &Qdrant{baseURL: base, collection: coll, dim: dim, breaker: breakerFor(base, 0, 0), retry: newRetryPolicy(cfg.RetryConfig{Attempts: 3})}
+}
+
+// Breaker exposes the circuit breaker guarding this Qdrant instance.
+func (q *Qdrant) Breaker() *CircuitBreaker { return q.breaker }
+
+func (q *Qdrant) withRequestID(id string) VectorStore {
+	c := *q
+	c.requestID = id
+	return &c
+}
+
+// do sends a JSON request to Qdrant through the circuit breaker. A nil body
+// sends no payload. Transport errors and 5xx responses count as failures;
+// the caller owns (and must close) the returned response body.
+func (q *Qdrant) do(method, path string, body any, timeout time.Duration) (*http.Response, error) {
+	if err := q.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	return q.send(method, path, body, timeout)
+}
+
+// doRetry is do under the retry policy, for idempotent requests such as
+// upserts with fixed IDs, searches and scrolls.
+func (q *Qdrant) doRetry(method, path string, body any, timeout time.Duration) (*http.Response, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return q.retry.do("Qdrant "+method+" "+path, func() (*http.Response, error) {
+		if err := q.breaker.Allow(); err != nil {
+			return nil, err
+		}
+		return q.sendRaw(method, path, bytes.NewReader(b), "application/json", timeout)
+	})
+}
+
+// send performs the request without consulting the breaker, but still
+// records its outcome so probes can close an open circuit.
+func (q *Qdrant) send(method, path string, body any, timeout time.Duration) (*http.Response, error) {
+	if body == nil {
+		return q.sendRaw(method, path, nil, "", timeout)
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return q.sendRaw(method, path, bytes.NewReader(b), "application/json", timeout)
+}
+
+// sendRaw is send for a body that is already encoded as contentType, e.g. a
+// streamed snapshot upload.
+func (q *Qdrant) sendRaw(method, path string, body io.Reader, contentType string, timeout time.Duration) (*http.Response, error) {
+	req, err := http.NewRequest(method, q.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if q.requestID != "" {
+		req.Header.Set(RequestIDHeader, q.requestID)
+	}
+	client := &http.Client{Timeout: timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		q.breaker.Record(err)
+		return nil, apierr.Wrap(apierr.Store, "qdrant", err)
+	}
+	if res.StatusCode >= 500 {
+		q.breaker.Record(fmt.Errorf("http %d", res.StatusCode))
+	} else {
+		q.breaker.Record(nil)
+	}
+	return res, nil
+}
+
+func (q *Qdrant) collectionPath(suffix string) string {
+	return fmt.Sprintf("/collections/%s%s", q.collection, suffix)
+}
+
+func (q *Qdrant) EnsureCollection() error {
+	// PUT /collections/{name}
+	vectors := map[string]any{
+		"size":     q.dim,
+		"distance": "Cosine",
+	}
+	if q.tuning.OnDiskVectors {
+		vectors["on_disk"] = true
+	}
+	body := map[string]any{"vectors": vectors}
+	if q.tuning.ShardNumber > 0 {
+		body["shard_number"] = q.tuning.ShardNumber
+	}
+	for k, v := range q.tuningParams() {
+		body[k] = v
+	}
+	res, err := q.do("PUT", q.collectionPath(""), body, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 && res.StatusCode != 409 { // 409 = already exists (ok)
+		return storeErrorf("qdrant", "ensure collection http %d", res.StatusCode)
+	}
+	if res.StatusCode == 409 {
+		if err := q.updateTuning(); err != nil {
+			return err
+		}
+	}
+	return q.EnsurePayloadIndexes()
+}
+
+// CollectionExists reports whether the collection has been created.
+func (q *Qdrant) CollectionExists() (bool, error) {
+	res, err := q.do("GET", q.collectionPath(""), nil, 5*time.Second)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == 404:
+		return false, nil
+	case res.StatusCode >= 300:
+		return false, storeErrorf("qdrant", "get collection http %d", res.StatusCode)
+	}
+	return true, nil
+}
+
+// CollectionDim reads the vector size from the collection config. Named
+// vectors are not used by this service; the first one found is reported.
+func (q *Qdrant) CollectionDim() (int, error) {
+	res, err := q.do("GET", q.collectionPath(""), nil, 5*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == 404:
+		return 0, nil
+	case res.StatusCode >= 300:
+		return 0, storeErrorf("qdrant", "get collection http %d", res.StatusCode)
+	}
+	var rr struct {
+		Result struct {
+			Config struct {
+				Params struct {
+					Vectors json.RawMessage `json:"vectors"`
+				} `json:"params"`
+			} `json:"config"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
+		return 0, err
+	}
+	var single struct {
+		Size int `json:"size"`
+	}
+	if json.Unmarshal(rr.Result.Config.Params.Vectors, &single) == nil && single.Size > 0 {
+		return single.Size, nil
+	}
+	var named map[string]struct {
+		Size int `json:"size"`
+	}
+	if json.Unmarshal(rr.Result.Config.Params.Vectors, &named) == nil {
+		for _, v := range named {
+			return v.Size, nil
+		}
+	}
+	return 0, nil
+}
+
+// collectionParams are the tuningParams keys that belong in "params" when
+// patching an existing collection.
+var collectionParams = []string{"on_disk_payload", "replication_factor", "write_consistency_factor"}
+
+// tuningParams renders the configured HNSW, quantization, payload storage
+// and replication settings; it is empty when everything is left at
+// defaults.
+func (q *Qdrant) tuningParams() map[string]any {
+	out := map[string]any{}
+	t := q.tuning
+	if t.OnDiskPayload {
+		out["on_disk_payload"] = true
+	}
+	if t.ReplicationFactor > 0 {
+		out["replication_factor"] = t.ReplicationFactor
+	}
+	if t.WriteConsistencyFactor > 0 {
+		out["write_consistency_factor"] = t.WriteConsistencyFactor
+	}
+	hnsw := map[string]any{}
+	if t.HNSW.M > 0 {
+		hnsw["m"] = t.HNSW.M
+	}
+	if t.HNSW.EfConstruct > 0 {
+		hnsw["ef_construct"] = t.HNSW.EfConstruct
+	}
+	if t.HNSW.OnDisk {
+		hnsw["on_disk"] = true
+	}
+	if len(hnsw) > 0 {
+		out["hnsw_config"] = hnsw
+	}
+	qc := t.Quantization
+	switch qc.Type {
+	case "scalar":
+		sc := map[string]any{"type": "int8", "always_ram": qc.AlwaysRAM}
+		if qc.Quantile > 0 {
+			sc["quantile"] = qc.Quantile
+		}
+		out["quantization_config"] = map[string]any{"scalar": sc}
+	case "product":
+		out["quantization_config"] = map[string]any{"product": map[string]any{"compression": qc.Compression, "always_ram": qc.AlwaysRAM}}
+	case "binary":
+		out["quantization_config"] = map[string]any{"binary": map[string]any{"always_ram": qc.AlwaysRAM}}
+	}
+	return out
+}
+
+// updateTuning patches the tunable settings onto an existing collection so
+// config changes take effect without recreating it. on_disk for the original
+// vectors and shard_number can only be set at creation time.
+func (q *Qdrant) updateTuning() error {
+	params := q.tuningParams()
+	if len(params) == 0 {
+		return nil
+	}
+	body := map[string]any{}
+	cp := map[string]any{}
+	for _, k := range collectionParams {
+		if v, ok := params[k]; ok {
+			cp[k] = v
+		}
+	}
+	if len(cp) > 0 {
+		body["params"] = cp
+	}
+	for _, k := range []string{"hnsw_config", "quantization_config"} {
+		if v, ok := params[k]; ok {
+			body[k] = v
+		}
+	}
+	res, err := q.do("PATCH", q.collectionPath(""), body, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return storeErrorf("qdrant", "update collection config http %d", res.StatusCode)
+	}
+	return nil
+}
+
+// facetFields are indexed as keywords so Facet can aggregate them and
+// filters on them stay fast.
+var facetFields = []string{"path", "project", "tags", "acl"}
+
+// EnsurePayloadIndexes creates keyword payload indexes used for faceting
+// and filtering, and integer ones for the timestamp range filters. Creating
+// an index that already exists is a no-op in Qdrant.
+func (q *Qdrant) EnsurePayloadIndexes() error {
+	for _, field := range facetFields {
+		if err := q.ensurePayloadIndex(field, "keyword"); err != nil {
+			return err
+		}
+	}
+	for _, field := range rangeFields {
+		if err := q.ensurePayloadIndex(field, "integer"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *Qdrant) ensurePayloadIndex(field, schema string) error {
+	body := map[string]any{"field_name": field, "field_schema": schema}
+	res, err := q.do("PUT", q.collectionPath("/index?wait=true"), body, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return storeErrorf("qdrant", "create payload index %s http %d", field, res.StatusCode)
+	}
+	return nil
+}
+
+// FacetHit is one distinct payload value and the number of points holding it.
+type FacetHit struct {
+	Value any `json:"value"`
+	Count int `json:"count"`
+}
+
+// ErrFacetUnsupported means the store has no facet API (Qdrant < 1.12 or
+// another backend).
+var ErrFacetUnsupported = errors.New("facet API not supported by the vector store")
+
+// Facet counts points per distinct value of an indexed keyword field.
+func (q *Qdrant) Facet(key string, limit int, filter map[string]any) ([]FacetHit, error) {
+	body := map[string]any{"key": key, "limit": limit, "exact": true}
+	if filter != nil {
+		body["filter"] = filter
+	}
+	res, err := q.do("POST", q.collectionPath("/facet"), body, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 404 {
+		return nil, ErrFacetUnsupported
+	}
+	if res.StatusCode >= 300 {
+		return nil, storeErrorf("qdrant", "facet http %d", res.StatusCode)
+	}
+	var rr struct {
+		Result struct {
+			Hits []FacetHit `json:"hits"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
+		return nil, err
+	}
+	return rr.Result.Hits, nil
+}
+
+// HealthCheck verifies Qdrant is reachable by querying /collections.
+// It bypasses an open circuit so that it can serve as the recovery probe.
+func (q *Qdrant) HealthCheck() error {
+	res, err := q.send("GET", "/collections", nil, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return storeErrorf("qdrant", "health http %d", res.StatusCode)
+	}
+	return nil
+}
+
+// CountPoints returns the number of points in the current collection
+func (q *Qdrant) CountPoints() (int, error) {
+	res, err := q.do("POST", q.collectionPath("/points/count"), map[string]any{"exact": true}, 10*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return 0, storeErrorf("qdrant", "count http %d", res.StatusCode)
+	}
+	var rr struct {
+		Result struct {
+			Count int `json:"count"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
+		return 0, err
+	}
+	return rr.Result.Count, nil
+}
+
+func (q *Qdrant) UpsertPoints(ids []string, vecs [][]float32, payloads []map[string]any) error {
+	if len(ids) != len(vecs) || len(ids) != len(payloads) {
+		return errors.New("mismatch len")
+	}
+	points := make([]map[string]any, 0, len(ids))
+	for i := range ids {
+		points = append(points, map[string]any{
+			"id":      ids[i],
+			"vector":  vecs[i],
+			"payload": payloads[i],
+		})
+	}
+	res, err := q.doRetry("PUT", q.collectionPath("/points?wait=true"), map[string]any{"points": points}, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return storeErrorf("qdrant", "upsert http %d", res.StatusCode)
+	}
+	return nil
+}
+
+type SearchHit struct {
+	ID      any            `json:"id"`
+	Score   float32        `json:"score"`
+	Payload map[string]any `json:"payload"`
+}
+
+// SearchParams tunes a single search: HNSWEf widens the HNSW beam for better
+// recall at higher latency (0 = Qdrant default) and Exact skips the index
+// for a brute-force scan. Consistency and TimeoutSec only apply to Qdrant:
+// how many replicas of a clustered collection must answer, and how long the
+// server may search.
+type SearchParams struct {
+	HNSWEf      int    `json:"hnsw_ef,omitempty"`
+	Exact       bool   `json:"exact,omitempty"`
+	Consistency string `json:"consistency,omitempty"`
+	TimeoutSec  int    `json:"timeout_sec,omitempty"`
+}
+
+func (q *Qdrant) Search(vec []float32, k int, filter map[string]any, params SearchParams) ([]SearchHit, error) {
+	body := map[string]any{
+		"vector":       vec,
+		"limit":        k,
+		"with_payload": true,
+	}
+	if filter != nil {
+		body["filter"] = filter
+	}
+	if params.HNSWEf > 0 || params.Exact {
+		body["params"] = SearchParams{HNSWEf: params.HNSWEf, Exact: params.Exact}
+	}
+	query := url.Values{}
+	if params.Consistency != "" {
+		query.Set("consistency", params.Consistency)
+	}
+	timeout := 15 * time.Second
+	if params.TimeoutSec > 0 {
+		query.Set("timeout", strconv.Itoa(params.TimeoutSec))
+		// Leave the server room to report its own timeout
+		timeout = max(timeout, time.Duration(params.TimeoutSec)*time.Second+5*time.Second)
+	}
+	path := "/points/search"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	res, err := q.doRetry("POST", q.collectionPath(path), body, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return nil, storeErrorf("qdrant", "search http %d", res.StatusCode)
+	}
+
+	var rr struct {
+		Result []SearchHit `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
+		return nil, err
+	}
+	return rr.Result, nil
+}
+
+// DeleteByIDs deletes points by explicit list of IDs (UUIDs or integers)
+func (q *Qdrant) DeleteByIDs(ids []any) error {
+	res, err := q.do("POST", q.collectionPath("/points/delete?wait=true"), map[string]any{"points": ids}, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return storeErrorf("qdrant", "delete http %d", res.StatusCode)
+	}
+	return nil
+}
+
+// SetPayload merges patch into the payload of the points matching filter
+// in one server-side call.
+func (q *Qdrant) SetPayload(filter map[string]any, patch map[string]any) error {
+	return q.setPayload(map[string]any{"payload": patch, "filter": filter})
+}
+
+// SetPayloadByIDs merges patch into the payload of the listed points.
+func (q *Qdrant) SetPayloadByIDs(ids []any, patch map[string]any) error {
+	return q.setPayload(map[string]any{"payload": patch, "points": ids})
+}
+
+func (q *Qdrant) setPayload(body map[string]any) error {
+	res, err := q.do("POST", q.collectionPath("/points/payload?wait=true"), body, 60*time.Second)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return storeErrorf("qdrant", "set payload http %d", res.StatusCode)
+	}
+	return nil
+}
+
+type ScrollPoint struct {
+	ID      any            `json:"id"`
+	Payload map[string]any `json:"payload"`
+}
+
+func (q *Qdrant) ScrollPoints(limit int, offset any) ([]ScrollPoint, any, error) {
+	return q.ScrollPointsWithFilter(limit, offset, nil)
+}
+
+// ScrollPointsWithFilter supports server-side filtering when scrolling
+func (q *Qdrant) ScrollPointsWithFilter(limit int, offset any, filter map[string]any) ([]ScrollPoint, any, error) {
+	if limit <= 0 || limit > 10000 {
+		limit = 1000
+	}
+	body := map[string]any{
+		"limit":        limit,
+		"with_payload": true,
+	}
+	if offset != nil {
+		body["offset"] = offset
+	}
+	if filter != nil {
+		body["filter"] = filter
+	}
+	res, err := q.doRetry("POST", q.collectionPath("/points/scroll"), body, 15*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return nil, nil, storeErrorf("qdrant", "scroll http %d", res.StatusCode)
+	}
+	var rr struct {
+		Result struct {
+			Points         []ScrollPoint `json:"points"`
+			NextPageOffset any           `json:"next_page_offset"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
+		return nil, nil, err
+	}
+	return rr.Result.Points, rr.Result.NextPageOffset, nil
+}
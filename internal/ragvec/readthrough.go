@@ -0,0 +1,101 @@
+package ragvec
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	"github.com/Rhyanz46/mcp-service/internal/secrets"
+)
+
+// ReadThrough replaces the stored text of each hit with its byte range read
+// from the source file, so callers see current content even when the index
+// is stale. Hits get Source "file" when the text came from disk, or "index"
+// when the file is not readable here or the hit has no byte range; Drifted
+// marks files whose content changed since they were indexed. Files are
+// converted as when they were indexed, and the text read passes the secrets
+// check and content filters as indexed chunks do: a hit the check or a
+// filter would leave out, or a filter that fails, keeps the stored text.
+func (r *VecRAG) ReadThrough(items []Hit) {
+	files := map[string]*readFile{}
+	var read []preparedChunk
+	for i := range items {
+		it := &items[i]
+		it.Source = "index"
+		sr := it.SourceRange
+		if sr == nil || sr.EndByte <= sr.StartByte {
+			continue
+		}
+		f, ok := files[it.Path]
+		if !ok {
+			f = r.loadReadFile(it.Path)
+			files[it.Path] = f
+		}
+		if f == nil {
+			continue
+		}
+		if f.hash != sr.FileHash {
+			it.Drifted = true
+		}
+		if sr.StartByte >= len(f.text) {
+			continue
+		}
+		start, end := sr.StartByte, min(sr.EndByte, len(f.text))
+		// Position carries the item through the checks, which may leave
+		// chunks out
+		c := preparedChunk{Chunk: chunker.Chunk{Path: it.Path, Text: f.text[start:end], Position: i}}
+		c.fileSecrets = secrets.Within(f.secrets, start, end)
+		read = append(read, c)
+	}
+	if len(read) == 0 {
+		return
+	}
+	checked, err := r.applyFilters(r.scrubSecrets(read, nil), nil)
+	if err != nil {
+		log.Printf("Read-through: %v; returning the indexed text", err)
+		return
+	}
+	for _, c := range checked {
+		it := &items[c.Position]
+		text := strings.ToValidUTF8(c.Text, "")
+		it.Text = text
+		it.Snippet = preview(text, 240)
+		it.Source = "file"
+	}
+}
+
+type readFile struct {
+	text string
+	hash string
+	// secrets are those found in the whole text, see prepareFile
+	secrets []secrets.Match
+}
+
+func (r *VecRAG) loadReadFile(path string) *readFile {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	text, err := chunker.DocumentText(path, b, r.config)
+	if err != nil {
+		return nil
+	}
+	f := &readFile{text: text, hash: chunker.FileHash(text)}
+	if r.secretAction() != "" {
+		f.secrets = r.secrets.Find(text)
+	}
+	return f
+}
+
+func intOf(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,40 @@
+package ragvec
+
+import (
+	"log"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// ReconnectLoop polls the vector store every interval until it is healthy and the RAG
+// system initializes, then hands the result to onReady and returns. Closing
+// stop aborts the loop.
+func ReconnectLoop(config *cfg.Config, interval time.Duration, stop <-chan struct{}, onReady func(*VecRAG)) {
+	if interval <= 0 {
+		return
+	}
+	q := NewStore(config, 1)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		if err := q.HealthCheck(); err != nil {
+			if config.Logging.Level == "debug" {
+				log.Printf("Vector store still unreachable: %v", err)
+			}
+			continue
+		}
+		rag, err := NewVecRAGWithConfig(config)
+		if err != nil {
+			log.Printf("Vector store is reachable but RAG initialization failed: %v", err)
+			continue
+		}
+		onReady(rag)
+		return
+	}
+}
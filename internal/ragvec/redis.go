@@ -0,0 +1,727 @@
+package ragvec
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// ---------- Redis Stack (RediSearch) client ----------
+
+// Redis stores each point as a hash "<index>:<id>" holding the vector, the
+// JSON payload and the filterable fields as TAGs, searched with a RediSearch
+// HNSW index. It speaks RESP2 directly, so no client library is needed.
+type Redis struct {
+	addr     string
+	user     string
+	pass     string
+	db       int
+	useTLS   bool
+	index    string
+	dim      int
+	timeout  time.Duration
+	idle     chan *redisConn
+	breaker  *CircuitBreaker
+	dialHost string
+}
+
+// redisTagSep separates TAG values; paths may contain commas, the default.
+const redisTagSep = "\x1f"
+
+// tagValue renders a payload value for a TAG field; lists become one
+// separated value per element.
+func tagValue(v any) string {
+	switch t := v.(type) {
+	case []string:
+		return strings.Join(t, redisTagSep)
+	case []any:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			parts[i] = toStr(e)
+		}
+		return strings.Join(parts, redisTagSep)
+	}
+	return toStr(v)
+}
+
+func NewRedisWithConfig(config *cfg.Config, dim int) *Redis {
+	rc := config.Store.Redis
+	r := &Redis{
+		index:   config.CollectionName(),
+		dim:     dim,
+		timeout: 15 * time.Second,
+		idle:    make(chan *redisConn, max(rc.PoolSize, 1)),
+		breaker: breakerFor(rc.URL, config.Qdrant.BreakerThreshold, time.Duration(config.Qdrant.BreakerCooldownSec)*time.Second),
+	}
+	if u, err := url.Parse(rc.URL); err == nil {
+		r.addr = u.Host
+		r.dialHost = u.Hostname()
+		r.useTLS = u.Scheme == "rediss"
+		if u.User != nil {
+			r.user = u.User.Username()
+			r.pass, _ = u.User.Password()
+			if r.pass == "" {
+				// redis://:pass@host and redis://pass@host both mean a password
+				r.pass, r.user = r.user, ""
+			}
+		}
+		if db, err := strconv.Atoi(strings.Trim(u.Path, "/")); err == nil {
+			r.db = db
+		}
+	}
+	if r.addr != "" && !strings.Contains(r.addr, ":") {
+		r.addr += ":6379"
+	}
+	return r
+}
+
+// Breaker exposes the circuit breaker guarding this Redis server.
+func (r *Redis) Breaker() *CircuitBreaker { return r.breaker }
+
+// redisError is an error reply ("-ERR ...") from the server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+type redisConn struct {
+	c  net.Conn
+	br *bufio.Reader
+	bw *bufio.Writer
+}
+
+func (r *Redis) dial() (*redisConn, error) {
+	d := &net.Dialer{Timeout: 5 * time.Second}
+	var c net.Conn
+	var err error
+	if r.useTLS {
+		c, err = tls.DialWithDialer(d, "tcp", r.addr, &tls.Config{ServerName: r.dialHost})
+	} else {
+		c, err = d.Dial("tcp", r.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{c: c, br: bufio.NewReader(c), bw: bufio.NewWriter(c)}
+	var setup [][]any
+	if r.pass != "" {
+		if r.user != "" {
+			setup = append(setup, []any{"AUTH", r.user, r.pass})
+		} else {
+			setup = append(setup, []any{"AUTH", r.pass})
+		}
+	}
+	if r.db != 0 {
+		setup = append(setup, []any{"SELECT", r.db})
+	}
+	if len(setup) > 0 {
+		replies, err := rc.pipeline(setup, r.timeout)
+		if err == nil {
+			err = firstError(replies)
+		}
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// do runs commands in one round trip through the circuit breaker. Error
+// replies are returned in place; err reports transport failures only.
+func (r *Redis) do(cmds ...[]any) ([]any, error) {
+	if err := r.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	replies, err := r.send(cmds...)
+	return replies, apierr.Wrap(apierr.Store, "redis", err)
+}
+
+// send is do without consulting the breaker; it still records the outcome.
+func (r *Redis) send(cmds ...[]any) ([]any, error) {
+	var conn *redisConn
+	select {
+	case conn = <-r.idle:
+	default:
+		var err error
+		if conn, err = r.dial(); err != nil {
+			r.breaker.Record(err)
+			return nil, err
+		}
+	}
+	replies, err := conn.pipeline(cmds, r.timeout)
+	r.breaker.Record(err)
+	if err != nil {
+		conn.c.Close()
+		return nil, err
+	}
+	select {
+	case r.idle <- conn:
+	default:
+		conn.c.Close()
+	}
+	return replies, nil
+}
+
+func (rc *redisConn) pipeline(cmds [][]any, timeout time.Duration) ([]any, error) {
+	_ = rc.c.SetDeadline(time.Now().Add(timeout))
+	for _, cmd := range cmds {
+		fmt.Fprintf(rc.bw, "*%d\r\n", len(cmd))
+		for _, arg := range cmd {
+			var b []byte
+			switch v := arg.(type) {
+			case []byte:
+				b = v
+			case string:
+				b = []byte(v)
+			default:
+				b = []byte(fmt.Sprint(v))
+			}
+			fmt.Fprintf(rc.bw, "$%d\r\n", len(b))
+			rc.bw.Write(b)
+			rc.bw.WriteString("\r\n")
+		}
+	}
+	if err := rc.bw.Flush(); err != nil {
+		return nil, err
+	}
+	out := make([]any, len(cmds))
+	for i := range cmds {
+		v, err := rc.read()
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// read parses one RESP2 reply: string, redisError, int64, nil or []any.
+func (rc *redisConn) read() (any, error) {
+	line, err := rc.br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	body := line[1 : len(line)-2]
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return redisError(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rc.br, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			if arr[i], err = rc.read(); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+}
+
+func firstError(replies []any) error {
+	for _, v := range replies {
+		if e, ok := v.(redisError); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// call runs a single command and turns an error reply into an error.
+func (r *Redis) call(cmd ...any) (any, error) {
+	replies, err := r.do(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := replies[0].(redisError); ok {
+		return nil, apierr.Wrap(apierr.Store, "redis", e)
+	}
+	return replies[0], nil
+}
+
+func (r *Redis) key(id string) string { return r.index + ":" + id }
+
+// HealthCheck sends PING, bypassing an open circuit so it can serve as the
+// recovery probe.
+func (r *Redis) HealthCheck() error {
+	replies, err := r.send([]any{"PING"})
+	if err != nil {
+		return err
+	}
+	return firstError(replies)
+}
+
+func isUnknownIndex(err error) bool {
+	var re redisError
+	if !errors.As(err, &re) {
+		return false
+	}
+	msg := strings.ToLower(string(re))
+	return strings.Contains(msg, "unknown index") || strings.Contains(msg, "no such index")
+}
+
+// info returns FT.INFO as a field map.
+func (r *Redis) info() (map[string]any, error) {
+	v, err := r.call("FT.INFO", r.index)
+	if err != nil {
+		return nil, err
+	}
+	arr, _ := v.([]any)
+	out := map[string]any{}
+	for i := 0; i+1 < len(arr); i += 2 {
+		out[toStr(arr[i])] = arr[i+1]
+	}
+	return out, nil
+}
+
+func (r *Redis) CollectionExists() (bool, error) {
+	_, err := r.info()
+	if isUnknownIndex(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CollectionDim finds the DIM of the vector field in the FT.INFO
+// attributes.
+func (r *Redis) CollectionDim() (int, error) {
+	info, err := r.info()
+	if isUnknownIndex(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	attrs, _ := info["attributes"].([]any)
+	for _, a := range attrs {
+		fields, _ := a.([]any)
+		attr := map[string]any{}
+		for i := 0; i+1 < len(fields); i++ {
+			attr[strings.ToLower(toStr(fields[i]))] = fields[i+1]
+		}
+		if toStr(attr["identifier"]) != "vector" {
+			continue
+		}
+		// Redis 7.2 lists the vector parameters flat among the attribute
+		// fields; earlier versions did not report DIM at all
+		if n, err := strconv.Atoi(toStr(attr["dim"])); err == nil {
+			return n, nil
+		}
+	}
+	return 0, nil
+}
+
+// redisField returns the schema arguments of a filter or range field.
+func redisField(name string) []any {
+	if slices.Contains(rangeFields, name) {
+		return []any{name, "NUMERIC"}
+	}
+	return []any{name, "TAG", "SEPARATOR", redisTagSep, "CASESENSITIVE"}
+}
+
+// redisLateFields were added to the schema after the first release; they
+// are added to existing indexes on startup.
+var redisLateFields = []string{"tags", "version_prefixes", "acl", "indexed_at", "modified_at", "expires_at", "deleted_at"}
+
+// EnsureCollection creates the search index when it does not exist yet and
+// adds fields introduced after an existing index was created.
+func (r *Redis) EnsureCollection() error {
+	ok, err := r.CollectionExists()
+	if err != nil {
+		return err
+	}
+	if ok {
+		for _, f := range redisLateFields {
+			cmd := append([]any{"FT.ALTER", r.index, "SCHEMA", "ADD"}, redisField(f)...)
+			_, err := r.call(cmd...)
+			if err != nil && !strings.Contains(strings.ToLower(err.Error()), "duplicate") {
+				return fmt.Errorf("add %s field: %w", f, err)
+			}
+		}
+		return nil
+	}
+	cmd := []any{"FT.CREATE", r.index, "ON", "HASH", "PREFIX", 1, r.index + ":", "SCHEMA",
+		"vector", "VECTOR", "HNSW", 6, "TYPE", "FLOAT32", "DIM", r.dim, "DISTANCE_METRIC", "COSINE"}
+	for _, f := range append(slices.Clone(filterFields), rangeFields...) {
+		cmd = append(cmd, redisField(f)...)
+	}
+	if _, err := r.call(cmd...); err != nil {
+		return fmt.Errorf("create index: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) CountPoints() (int, error) {
+	info, err := r.info()
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+	n, err := strconv.Atoi(toStr(info["num_docs"]))
+	if err != nil {
+		return 0, fmt.Errorf("count: num_docs %v", info["num_docs"])
+	}
+	return n, nil
+}
+
+func (r *Redis) UpsertPoints(ids []string, vecs [][]float32, payloads []map[string]any) error {
+	cmds := make([][]any, 0, len(ids))
+	for i, id := range ids {
+		pj, err := json.Marshal(payloads[i])
+		if err != nil {
+			return err
+		}
+		cmd := []any{"HSET", r.key(id), "vector", vectorBlob(vecs[i]), "payload", pj}
+		for _, f := range filterFields {
+			if v, ok := payloads[i][f]; ok {
+				cmd = append(cmd, f, tagValue(v))
+			}
+		}
+		for _, f := range rangeFields {
+			if v, ok := payloads[i][f]; ok {
+				cmd = append(cmd, f, toStr(v))
+			}
+		}
+		cmds = append(cmds, cmd)
+	}
+	replies, err := r.do(cmds...)
+	if err != nil {
+		return err
+	}
+	if err := firstError(replies); err != nil {
+		return fmt.Errorf("upsert: %w", err)
+	}
+	return nil
+}
+
+// Search runs a KNN query pre-filtered by TAG matches. HNSWEf maps to
+// EF_RUNTIME; exact search is not available on an HNSW index, so Exact only
+// widens the beam to the whole result window.
+func (r *Redis) Search(vec []float32, k int, filter map[string]any, params SearchParams) ([]SearchHit, error) {
+	pre, err := r.filterQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	knn := "KNN $k @vector $vec"
+	args := []any{"k", k, "vec", vectorBlob(vec)}
+	ef := params.HNSWEf
+	if params.Exact && ef < k*10 {
+		ef = k * 10
+	}
+	if ef > 0 {
+		knn += " EF_RUNTIME $ef"
+		args = append(args, "ef", ef)
+	}
+	cmd := []any{"FT.SEARCH", r.index, pre + "=>[" + knn + " AS __dist]", "PARAMS", len(args)}
+	cmd = append(cmd, args...)
+	cmd = append(cmd, "SORTBY", "__dist", "ASC", "RETURN", 2, "payload", "__dist", "LIMIT", 0, k, "DIALECT", 2)
+	v, err := r.call(cmd...)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	docs := r.parseDocs(v)
+	hits := make([]SearchHit, 0, len(docs))
+	for _, d := range docs {
+		dist, _ := strconv.ParseFloat(d.fields["__dist"], 64)
+		hits = append(hits, SearchHit{ID: d.id, Score: float32(1 - dist), Payload: d.payload()})
+	}
+	return hits, nil
+}
+
+type redisDoc struct {
+	id     string
+	fields map[string]string
+}
+
+func (d redisDoc) payload() map[string]any {
+	var p map[string]any
+	_ = json.Unmarshal([]byte(d.fields["payload"]), &p)
+	return p
+}
+
+// parseDocs decodes an FT.SEARCH reply: [total, key, [field, value, ...], ...].
+func (r *Redis) parseDocs(v any) []redisDoc {
+	arr, _ := v.([]any)
+	var out []redisDoc
+	for i := 1; i+1 < len(arr); i += 2 {
+		d := redisDoc{id: strings.TrimPrefix(toStr(arr[i]), r.index+":"), fields: map[string]string{}}
+		fv, _ := arr[i+1].([]any)
+		for j := 0; j+1 < len(fv); j += 2 {
+			d.fields[toStr(fv[j])] = toStr(fv[j+1])
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// filterQuery renders filter as a RediSearch pre-filter ("*" when empty).
+func (r *Redis) filterQuery(filter map[string]any) (string, error) {
+	matches, err := filterMatches(filter)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "*", nil
+	}
+	var parts []string
+	for _, m := range matches {
+		neg := ""
+		if m.Not {
+			neg = "-"
+		}
+		if m.Range != nil && slices.Contains(rangeFields, m.Key) {
+			parts = append(parts, neg+"@"+m.Key+":"+numericRange(m.Range))
+			continue
+		}
+		if m.Range != nil || !slices.Contains(filterFields, m.Key) {
+			return "", fmt.Errorf("redis backend cannot filter on %q", m.Key)
+		}
+		vals := make([]string, len(m.Any))
+		for i, v := range m.Any {
+			vals[i] = escapeTag(v)
+		}
+		parts = append(parts, neg+"@"+m.Key+":{"+strings.Join(vals, " | ")+"}")
+	}
+	return "(" + strings.Join(parts, " ") + ")", nil
+}
+
+// numericRange renders a range condition as "[min max]", with "(" marking
+// exclusive bounds.
+func numericRange(rng map[string]float64) string {
+	lo, hi := "-inf", "+inf"
+	for op, v := range rng {
+		n := strconv.FormatFloat(v, 'f', -1, 64)
+		switch op {
+		case "gt":
+			lo = "(" + n
+		case "gte":
+			lo = n
+		case "lt":
+			hi = "(" + n
+		case "lte":
+			hi = n
+		}
+	}
+	return "[" + lo + " " + hi + "]"
+}
+
+// escapeTag backslash-escapes everything but letters, digits and '_'.
+func escapeTag(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (r *Redis) DeleteByIDs(ids []any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	cmd := []any{"DEL"}
+	for _, id := range ids {
+		cmd = append(cmd, r.key(fmt.Sprint(id)))
+	}
+	if _, err := r.call(cmd...); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+// SetPayload rewrites the matching hashes page by page: the JSON payload and
+// any TAG fields the patch touches.
+func (r *Redis) SetPayload(filter map[string]any, patch map[string]any) error {
+	var offset any
+	for {
+		pts, next, err := r.ScrollPointsWithFilter(1000, offset, filter)
+		if err != nil {
+			return err
+		}
+		if err := r.patchPoints(pts, patch); err != nil {
+			return err
+		}
+		if next == nil {
+			return nil
+		}
+		offset = next
+	}
+}
+
+func (r *Redis) SetPayloadByIDs(ids []any, patch map[string]any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	cmds := make([][]any, len(ids))
+	for i, id := range ids {
+		cmds[i] = []any{"HGET", r.key(fmt.Sprint(id)), "payload"}
+	}
+	replies, err := r.do(cmds...)
+	if err != nil {
+		return err
+	}
+	pts := make([]ScrollPoint, 0, len(ids))
+	for i, rep := range replies {
+		s, ok := rep.(string)
+		if !ok {
+			continue // no such point
+		}
+		var p map[string]any
+		if json.Unmarshal([]byte(s), &p) != nil {
+			continue
+		}
+		pts = append(pts, ScrollPoint{ID: fmt.Sprint(ids[i]), Payload: p})
+	}
+	return r.patchPoints(pts, patch)
+}
+
+// patchPoints merges patch into the fetched payloads and writes them back
+// with any TAG and NUMERIC fields the patch touches.
+func (r *Redis) patchPoints(pts []ScrollPoint, patch map[string]any) error {
+	if len(pts) == 0 {
+		return nil
+	}
+	cmds := make([][]any, 0, len(pts))
+	for _, p := range pts {
+		for k, v := range patch {
+			p.Payload[k] = v
+		}
+		pj, err := json.Marshal(p.Payload)
+		if err != nil {
+			return err
+		}
+		cmd := []any{"HSET", r.key(toStr(p.ID)), "payload", pj}
+		for _, f := range filterFields {
+			if v, ok := patch[f]; ok {
+				cmd = append(cmd, f, tagValue(v))
+			}
+		}
+		for _, f := range rangeFields {
+			v, ok := patch[f]
+			switch {
+			case ok && v == nil:
+				// a nil patch value clears the field, e.g. deleted_at on undelete
+				cmds = append(cmds, []any{"HDEL", r.key(toStr(p.ID)), f})
+			case ok:
+				cmd = append(cmd, f, toStr(v))
+			}
+		}
+		cmds = append(cmds, cmd)
+	}
+	replies, err := r.do(cmds...)
+	if err != nil {
+		return err
+	}
+	if err := firstError(replies); err != nil {
+		return fmt.Errorf("set payload: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) ScrollPoints(limit int, offset any) ([]ScrollPoint, any, error) {
+	return r.ScrollPointsWithFilter(limit, offset, nil)
+}
+
+// ScrollPointsWithFilter walks the keyspace with SCAN, which stays
+// consistent while points are deleted during the walk, and applies the
+// filter client-side. A page may hold fewer than limit points; the returned
+// offset is nil once the walk is complete.
+func (r *Redis) ScrollPointsWithFilter(limit int, offset any, filter map[string]any) ([]ScrollPoint, any, error) {
+	if limit <= 0 || limit > 10000 {
+		limit = 1000
+	}
+	matches, err := filterMatches(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	cursor := "0"
+	if offset != nil {
+		cursor = toStr(offset)
+	}
+	v, err := r.call("SCAN", cursor, "MATCH", escapeGlob(r.index)+":*", "COUNT", limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scroll: %w", err)
+	}
+	arr, _ := v.([]any)
+	if len(arr) != 2 {
+		return nil, nil, fmt.Errorf("scroll: malformed SCAN reply")
+	}
+	keys, _ := arr[1].([]any)
+	var pts []ScrollPoint
+	if len(keys) > 0 {
+		cmds := make([][]any, len(keys))
+		for i, k := range keys {
+			cmds[i] = []any{"HGET", k, "payload"}
+		}
+		replies, err := r.do(cmds...)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, rep := range replies {
+			s, ok := rep.(string)
+			if !ok {
+				continue // deleted meanwhile
+			}
+			var p map[string]any
+			if json.Unmarshal([]byte(s), &p) != nil || !payloadMatches(p, matches) {
+				continue
+			}
+			pts = append(pts, ScrollPoint{ID: strings.TrimPrefix(toStr(keys[i]), r.index+":"), Payload: p})
+		}
+	}
+	var next any
+	if c := toStr(arr[0]); c != "0" {
+		next = c
+	}
+	return pts, next, nil
+}
+
+func escapeGlob(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+	return r.Replace(s)
+}
+
+// vectorBlob encodes vec as little-endian FLOAT32 bytes.
+func vectorBlob(vec []float32) []byte {
+	b := make([]byte, 4*len(vec))
+	for i, f := range vec {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
@@ -0,0 +1,38 @@
+package ragvec
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestCheckRepoHost(t *testing.T) {
+	for _, repo := range []string{
+		"https://127.0.0.1/team/app.git",
+		"https://[::1]:8443/team/app",
+		"git@10.0.0.7:team/app.git",
+		"ssh://git@169.254.169.254/team/app",
+	} {
+		name, err := repoName(repo, []string{"https", "ssh"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := checkRepoHost(context.Background(), repo, name); err == nil {
+			t.Errorf("checkRepoHost(%q): got nil error", repo)
+		}
+	}
+
+	repo := "https://203.0.113.9:8443/team/app.git"
+	name, err := repoName(repo, []string{"https"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pin, err := checkRepoHost(context.Background(), repo, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-c", "http.followRedirects=false", "-c", "http.curloptResolve=203.0.113.9:8443:203.0.113.9"}
+	if !reflect.DeepEqual(pin, want) {
+		t.Errorf("pin = %v, want %v", pin, want)
+	}
+}
@@ -0,0 +1,199 @@
+package ragvec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// Reranker scores search candidates against the query with a model that
+// reads both together, which ranks better than vector similarity alone.
+// The built-in rerankers are chosen by search.reranker; SetReranker plugs
+// in others.
+type Reranker interface {
+	// Name identifies the reranker in explanations and logs
+	Name() string
+	// Rerank returns one relevance score per candidate, in order; higher is
+	// more relevant
+	Rerank(query string, candidates []RerankCandidate) ([]float32, error)
+}
+
+// RerankCandidate is a search candidate as seen by a Reranker. Score is its
+// score from the store.
+type RerankCandidate struct {
+	Path  string
+	Text  string
+	Score float32
+}
+
+// SetReranker replaces the reranker configured in search.reranker; nil
+// turns reranking off. Call it before searches start; it is not safe for
+// concurrent use.
+func (r *VecRAG) SetReranker(rr Reranker) {
+	r.reranker = rr
+}
+
+// Default models of the hosted rerankers.
+var rerankDefaults = map[string]struct{ url, model string }{
+	"cohere": {"https://api.cohere.com/v2/rerank", "rerank-v3.5"},
+	"jina":   {"https://api.jina.ai/v1/rerank", "jina-reranker-v2-base-multilingual"},
+}
+
+// newReranker builds the reranker named in search.reranker.provider.
+func newReranker(config *cfg.Config) Reranker {
+	rc := config.Search.Reranker
+	switch rc.Provider {
+	case "passthrough":
+		return passthroughReranker{}
+	case "cohere", "jina":
+		d := rerankDefaults[rc.Provider]
+		rr := &httpReranker{name: rc.Provider, url: rc.URL, model: rc.Model, apiKey: rc.APIKey, userAgent: userAgent(config)}
+		if rr.url == "" {
+			rr.url = d.url
+		}
+		if rr.model == "" {
+			rr.model = d.model
+		}
+		timeout := time.Duration(rc.TimeoutSec) * time.Second
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		rr.client = &http.Client{Timeout: timeout}
+		return rr
+	}
+	return nil
+}
+
+// rerankCandidates is how many candidates to fetch for a search of k hits
+// when a reranker is set: search.reranker.candidates, but at least k.
+func (r *VecRAG) rerankCandidates(k int) int {
+	if r.reranker == nil {
+		return 0
+	}
+	return max(r.config.Search.Reranker.Candidates, k)
+}
+
+// rerank reorders items by the reranker's scores, which replace the store
+// scores. When the reranker fails and search.reranker.fail_open is set,
+// items keep the store's order.
+func (r *VecRAG) rerank(query string, items []Hit, ex *SearchExplanation) ([]Hit, error) {
+	if r.reranker == nil || len(items) == 0 {
+		return items, nil
+	}
+	in := make([]RerankCandidate, len(items))
+	for i, h := range items {
+		text := h.Text
+		if text == "" {
+			text = h.Snippet
+		}
+		in[i] = RerankCandidate{Path: h.Path, Text: text, Score: h.Score}
+	}
+	scores, err := r.reranker.Rerank(query, in)
+	if err == nil && len(scores) != len(items) {
+		err = fmt.Errorf("got %d scores for %d candidates", len(scores), len(items))
+	}
+	if err != nil {
+		if r.config.Search.Reranker.FailOpen {
+			log.Printf("Warning: reranker %s failed, keeping the store ranking: %v", r.reranker.Name(), err)
+			return items, nil
+		}
+		return nil, apierr.Wrap(apierr.Provider, "reranker", fmt.Errorf("reranker %s: %w", r.reranker.Name(), err))
+	}
+	if ex != nil {
+		ex.Reranker = r.reranker.Name()
+	}
+	for i := range items {
+		if e := items[i].Explain; e != nil {
+			e.Adjustments = append(e.Adjustments, fmt.Sprintf("rescored by reranker %s: %.4f -> %.4f", r.reranker.Name(), items[i].Score, scores[i]))
+		}
+		items[i].Score = scores[i]
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Score > items[j].Score })
+	return items, nil
+}
+
+// passthroughReranker keeps the store's scores and order. It exercises the
+// reranking path without a model, e.g. to compare against a real one.
+type passthroughReranker struct{}
+
+func (passthroughReranker) Name() string { return "passthrough" }
+
+func (passthroughReranker) Rerank(query string, candidates []RerankCandidate) ([]float32, error) {
+	out := make([]float32, len(candidates))
+	for i, c := range candidates {
+		out[i] = c.Score
+	}
+	return out, nil
+}
+
+// httpReranker calls a hosted rerank API. Cohere and Jina share the request
+// {"model", "query", "documents", "top_n"} and answer {"results": [{"index",
+// "relevance_score"}]}.
+type httpReranker struct {
+	name      string
+	url       string
+	model     string
+	apiKey    string
+	userAgent string
+	client    *http.Client
+}
+
+func (h *httpReranker) Name() string { return h.name }
+
+func (h *httpReranker) Rerank(query string, candidates []RerankCandidate) ([]float32, error) {
+	docs := make([]string, len(candidates))
+	for i, c := range candidates {
+		docs[i] = c.Text
+	}
+	body, err := json.Marshal(map[string]any{"model": h.model, "query": query, "documents": docs, "top_n": len(docs)})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", h.userAgent)
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	var decoded struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float32 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(decoded.Results) != len(docs) {
+		return nil, fmt.Errorf("got %d results for %d documents", len(decoded.Results), len(docs))
+	}
+	out := make([]float32, len(docs))
+	for _, res := range decoded.Results {
+		if res.Index < 0 || res.Index >= len(docs) {
+			return nil, fmt.Errorf("result index %d out of range", res.Index)
+		}
+		out[res.Index] = res.RelevanceScore
+	}
+	return out, nil
+}
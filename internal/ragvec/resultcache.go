@@ -0,0 +1,189 @@
+package ragvec
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+)
+
+// resultCache memoizes search results by query, k, filter and parameters
+// for search.cache.ttl_sec. Writes drop the entries of the projects they
+// touch: an entry filtered to one project survives changes to the others,
+// while an unfiltered or prefix-filtered one goes whenever a project it may
+// cover changes. A search that started before an invalidation is not
+// cached, so a result racing a write cannot outlive it.
+type resultCache struct {
+	ttl time.Duration
+	max int
+
+	mu           sync.Mutex
+	entries      map[string]*cachedResult
+	gen          uint64 // bumped by invalidate
+	hits, misses int
+}
+
+type cachedResult struct {
+	hits    []Hit
+	project string
+	prefix  string
+	at      time.Time
+}
+
+func newResultCache(ttl time.Duration, max int) *resultCache {
+	return &resultCache{ttl: ttl, max: max, entries: map[string]*cachedResult{}}
+}
+
+func (c *resultCache) enabled() bool {
+	return c != nil && c.ttl > 0 && c.max > 0
+}
+
+// snapshot reports the size and hit rate of the cache, nil when it is off.
+func (c *resultCache) snapshot() map[string]any {
+	if !c.enabled() {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return map[string]any{"entries": len(c.entries), "hits": c.hits, "misses": c.misses, "ttl_sec": int(c.ttl.Seconds())}
+}
+
+// resultKey identifies a search; f's tags are normalized so equivalent
+// filters share an entry.
+func resultKey(query string, k int, f SearchFilter, params SearchParams) string {
+	f.Project = strings.TrimSpace(f.Project)
+	f.ProjectPrefix = strings.TrimSpace(f.ProjectPrefix)
+	f.TagsAny = NormalizeTags(f.TagsAny)
+	f.TagsAll = NormalizeTags(f.TagsAll)
+	f.Version = chunker.NormalizeVersion(f.Version)
+	if f.ACL != nil {
+		f.ACL = NormalizeTags(f.ACL)
+		slices.Sort(f.ACL)
+	}
+	b, _ := json.Marshal(struct {
+		Query  string
+		K      int
+		Filter SearchFilter
+		Params SearchParams
+	}{query, k, f, params})
+	return string(b)
+}
+
+// get returns a copy of the cached hits of key, and the generation to pass
+// to put after a miss.
+func (c *resultCache) get(key string) ([]Hit, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && time.Since(e.at) >= c.ttl {
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, c.gen, false
+	}
+	c.hits++
+	return append([]Hit(nil), e.hits...), c.gen, true
+}
+
+// put caches hits under key unless the cache was invalidated since gen.
+// When full, expired entries go first, then the oldest.
+func (c *resultCache) put(key string, gen uint64, f SearchFilter, hits []Hit) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.max {
+		var oldest string
+		for k, e := range c.entries {
+			if time.Since(e.at) >= c.ttl {
+				delete(c.entries, k)
+			} else if oldest == "" || e.at.Before(c.entries[oldest].at) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= c.max {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = &cachedResult{
+		hits:    append([]Hit(nil), hits...),
+		project: strings.TrimSpace(f.Project),
+		prefix:  strings.ToLower(strings.TrimSpace(f.ProjectPrefix)),
+		at:      time.Now(),
+	}
+}
+
+// invalidate drops the entries projects may affect; no projects, or an
+// empty one, means any project changed.
+func (c *resultCache) invalidate(projects ...string) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	all := len(projects) == 0
+	for _, p := range projects {
+		all = all || p == ""
+	}
+	for k, e := range c.entries {
+		if all || e.covers(projects) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// covers reports whether a change to one of projects may change e.
+func (e *cachedResult) covers(projects []string) bool {
+	for _, p := range projects {
+		switch {
+		case e.project != "":
+			if e.project == p {
+				return true
+			}
+		case e.prefix != "":
+			if strings.HasPrefix(strings.ToLower(p), e.prefix) {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// SearchCacheStatus reports the entries and hit counts of the search result
+// cache since startup, nil when search.cache is off.
+func (r *VecRAG) SearchCacheStatus() map[string]any {
+	return r.results.snapshot()
+}
+
+// indexChanged drops the cached project list and the cached results that a
+// change to projects may affect; without projects, all of them.
+func (r *VecRAG) indexChanged(projects ...string) {
+	r.projects.invalidate()
+	r.results.invalidate(projects...)
+}
+
+// filterProject returns the project a store filter is limited to, or ""
+// when it is not limited to one.
+func filterProject(filter map[string]any) string {
+	must, _ := filterConditions(filter["must"])
+	for _, c := range must {
+		if c["key"] != "project" {
+			continue
+		}
+		if m, ok := c["match"].(map[string]any); ok {
+			if v, ok := m["value"].(string); ok {
+				return v
+			}
+		}
+	}
+	return ""
+}
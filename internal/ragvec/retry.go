@@ -0,0 +1,97 @@
+package ragvec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// retryPolicy repeats requests that failed transiently: network errors,
+// timeouts and 5xx replies. 4xx replies and an open circuit are final.
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func newRetryPolicy(conf cfg.RetryConfig) retryPolicy {
+	p := retryPolicy{attempts: max(conf.Attempts, 1)}
+	var err error
+	if p.backoff, p.maxBackoff, err = conf.Durations(); err != nil {
+		// Validate rejects this; fall back to single attempts
+		p.attempts = 1
+	}
+	return p
+}
+
+// transient reports whether a request that ended in res or err may succeed
+// when sent again.
+func transient(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrVectorStoreUnavailable)
+	}
+	return res.StatusCode >= 500
+}
+
+// wait returns the delay before retry n (1-based): the backoff doubled n-1
+// times, capped, with up to half of it taken off at random so clients
+// retrying together spread out.
+func (p retryPolicy) wait(n int) time.Duration {
+	d := p.backoff
+	for i := 1; i < n && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.maxBackoff)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// do calls send until it succeeds, fails permanently or runs out of
+// attempts, and returns the last outcome. what names the operation in logs.
+func (p retryPolicy) do(what string, send func() (*http.Response, error)) (*http.Response, error) {
+	for n := 1; ; n++ {
+		res, err := send()
+		if n >= p.attempts || !transient(res, err) {
+			return res, err
+		}
+		reason := err
+		if res != nil {
+			reason = fmt.Errorf("http %d", res.StatusCode)
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+			res.Body.Close()
+		}
+		d := p.wait(n)
+		log.Printf("%s failed (attempt %d/%d), retrying in %v: %v", what, n, p.attempts, d.Round(time.Millisecond), reason)
+		time.Sleep(d)
+	}
+}
+
+// retryable reports whether an operation that failed with err may succeed
+// when run again: a network error, a timeout, or a 429 or 5xx reply of a
+// provider or store. An open circuit is final.
+func retryable(err error) bool {
+	return !errors.Is(err, ErrVectorStoreUnavailable) && apierr.Of(err, "").Retryable
+}
+
+// run calls fn until it succeeds, fails permanently (see retryable) or the
+// attempts run out, and returns how many attempts were made.
+func (p retryPolicy) run(what string, fn func() error) (int, error) {
+	for n := 1; ; n++ {
+		err := fn()
+		if err == nil || n >= p.attempts || !retryable(err) {
+			return n, err
+		}
+		d := p.wait(n)
+		log.Printf("%s failed (attempt %d/%d), retrying in %v: %v", what, n, p.attempts, d.Round(time.Millisecond), err)
+		time.Sleep(d)
+	}
+}
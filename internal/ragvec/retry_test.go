@@ -0,0 +1,133 @@
+package ragvec
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// countingEmbedder returns fixed vectors and counts the texts embedded.
+type countingEmbedder struct {
+	texts int
+}
+
+func (e *countingEmbedder) Embed(texts []string) ([][]float32, error) {
+	e.texts += len(texts)
+	out := make([][]float32, len(texts))
+	for i := range out {
+		out[i] = []float32{1, 0, 0, 0}
+	}
+	return out, nil
+}
+
+func (e *countingEmbedder) Dim() int { return 4 }
+
+// failingStore fails its first upserts with errs, in order.
+type failingStore struct {
+	VectorStore
+	errs    []error
+	upserts int
+}
+
+func (s *failingStore) UpsertPoints(ids []string, vecs [][]float32, payloads []map[string]any) error {
+	s.upserts++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return err
+	}
+	return nil
+}
+
+func TestUpsertChunksRetries(t *testing.T) {
+	unavailable := storeErrorf("qdrant", "upsert http 503")
+	tests := []struct {
+		name        string
+		errs        []error
+		wantErr     bool
+		wantUpserts int
+	}{
+		{"transient failure is retried", []error{unavailable}, false, 2},
+		{"retries run out", []error{unavailable, unavailable, unavailable}, true, 3},
+		{"bad request is final", []error{storeErrorf("qdrant", "upsert http 400")}, true, 1},
+		{"open circuit is final", []error{ErrVectorStoreUnavailable}, true, 1},
+		{"unmarked error is final", []error{errors.New("dimension mismatch")}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := cfg.DefaultConfig()
+			config.Indexing.BatchRetry = cfg.RetryConfig{Attempts: 3, Backoff: "1ms", MaxBackoff: "1ms"}
+			embed := &countingEmbedder{}
+			store := &failingStore{errs: tt.errs}
+			r := &VecRAG{config: config, embed: embed, vdb: store}
+			chunks := prepareChunks([]chunker.Chunk{{Path: "/docs/a.md", Text: "one"}, {Path: "/docs/a.md", Position: 1, Text: "two"}})
+			for i := range chunks {
+				chunks[i].checked = true
+			}
+			n, err := r.upsertChunks(chunks, IngestOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && n != 2 {
+				t.Errorf("wrote %d chunks, want 2", n)
+			}
+			if store.upserts != tt.wantUpserts {
+				t.Errorf("%d upserts, want %d", store.upserts, tt.wantUpserts)
+			}
+			// Retries repeat the write only
+			if embed.texts != 2 {
+				t.Errorf("embedded %d texts, want 2", embed.texts)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{storeErrorf("qdrant", "upsert http 503"), true},
+		{apierr.Wrap(apierr.Provider, "embedding", fmt.Errorf("openai embeddings http 429")), true},
+		{apierr.Wrap(apierr.Provider, "embedding", fmt.Errorf("openai embeddings http 401")), false},
+		{ErrVectorStoreUnavailable, false},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// downEmbedder fails every call.
+type downEmbedder struct{}
+
+func (downEmbedder) Embed([]string) ([][]float32, error) {
+	return nil, errors.New("connection refused")
+}
+func (downEmbedder) Dim() int { return 4 }
+
+func TestUpsertChunksRefusesFallbackModel(t *testing.T) {
+	config := cfg.DefaultConfig()
+	config.Embedding.Provider = "openai"
+	config.Indexing.BatchRetry = cfg.RetryConfig{Attempts: 2, Backoff: "1ms", MaxBackoff: "1ms"}
+	chain, err := NewFailoverProvider([]string{"openai", "local"}, []EmbeddingProvider{downEmbedder{}, &countingEmbedder{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &failingStore{}
+	r := &VecRAG{config: config, embed: chain, vdb: store}
+	chunks := prepareChunks([]chunker.Chunk{{Path: "/docs/a.md", Text: "one"}})
+	chunks[0].checked = true
+	if _, err := r.upsertChunks(chunks, IngestOptions{}); err == nil {
+		t.Fatal("vectors of the fallback model were accepted")
+	}
+	if store.upserts != 0 {
+		t.Errorf("%d upserts, want none", store.upserts)
+	}
+}
@@ -0,0 +1,114 @@
+package ragvec
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsRules is the robots.txt group that applies to the crawler.
+type robotsRules struct {
+	rules []robotsRule
+	delay time.Duration // Crawl-delay
+}
+
+type robotsRule struct {
+	allow bool
+	len   int // pattern length; the longest match wins
+	re    *regexp.Regexp
+}
+
+// parseRobots picks the group for agent from a robots.txt body (falling back
+// to "*") and compiles its Allow/Disallow patterns, which may use * and a
+// trailing $.
+func parseRobots(body, agent string) *robotsRules {
+	type group struct {
+		agents []string
+		lines  [][2]string
+	}
+	var groups []*group
+	var cur *group
+	inAgents := false
+	sc := bufio.NewScanner(strings.NewReader(body))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, val = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(val)
+		if key == "user-agent" {
+			if !inAgents {
+				cur = &group{}
+				groups = append(groups, cur)
+			}
+			cur.agents = append(cur.agents, strings.ToLower(val))
+			inAgents = true
+			continue
+		}
+		inAgents = false
+		if cur != nil {
+			cur.lines = append(cur.lines, [2]string{key, val})
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	var chosen, wildcard *group
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" && wildcard == nil {
+				wildcard = g
+			} else if a != "*" && a != "" && strings.Contains(agent, a) && chosen == nil {
+				chosen = g
+			}
+		}
+	}
+	if chosen == nil {
+		chosen = wildcard
+	}
+	out := &robotsRules{}
+	if chosen == nil {
+		return out
+	}
+	for _, kv := range chosen.lines {
+		switch kv[0] {
+		case "allow", "disallow":
+			if kv[1] == "" {
+				continue // an empty Disallow allows everything
+			}
+			pattern := regexp.QuoteMeta(strings.TrimSuffix(kv[1], "$"))
+			pattern = "^" + strings.ReplaceAll(pattern, `\*`, ".*")
+			if strings.HasSuffix(kv[1], "$") {
+				pattern += "$"
+			}
+			if re, err := regexp.Compile(pattern); err == nil {
+				out.rules = append(out.rules, robotsRule{allow: kv[0] == "allow", len: len(kv[1]), re: re})
+			}
+		case "crawl-delay":
+			if secs, err := strconv.ParseFloat(kv[1], 64); err == nil && secs > 0 {
+				out.delay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+	return out
+}
+
+// allowed reports whether path (with query) may be fetched. Among matching
+// rules the longest wins, and Allow wins a tie.
+func (r *robotsRules) allowed(path string) bool {
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		if rule.len > best || (rule.len == best && rule.allow) {
+			best, allow = rule.len, rule.allow
+		}
+	}
+	return allow
+}
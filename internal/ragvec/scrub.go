@@ -0,0 +1,277 @@
+package ragvec
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	"github.com/Rhyanz46/mcp-service/internal/secrets"
+)
+
+// IngestReport collects what the content checks changed during one run and
+// how its batches were written. Pass a fresh one in IngestOptions.Report and
+// read it once the run returns.
+type IngestReport struct {
+	mu sync.Mutex
+	// SecretChunks counts chunks in which secrets were found
+	SecretChunks int `json:"secret_chunks"`
+	// SkippedChunks counts chunks left out because of their secrets
+	SkippedChunks int `json:"skipped_chunks"`
+	// SecretKinds counts the secrets found per kind, e.g. aws_access_key
+	SecretKinds map[string]int `json:"secret_kinds,omitempty"`
+	// SecretFiles lists the first files with secrets
+	SecretFiles []string `json:"secret_files,omitempty"`
+	// SecretAction is indexing.secrets.action during the run
+	SecretAction string `json:"secret_action,omitempty"`
+	// RedactedChunks counts chunks a content filter changed
+	RedactedChunks int `json:"redacted_chunks"`
+	// DroppedChunks counts chunks a content filter left out
+	DroppedChunks int `json:"dropped_chunks"`
+	// Redactions counts redactions per filter and kind, e.g. pii.email
+	Redactions map[string]int `json:"redactions,omitempty"`
+	// Batches tracks the upserted batches; it is reported on its own
+	Batches BatchReport `json:"-"`
+	// ReusedFiles counts files whose chunks came from the chunk cache; it
+	// is reported on its own
+	ReusedFiles int `json:"-"`
+}
+
+// BatchReport counts the batches of a run. Point IDs derive from the chunk,
+// so re-running after a failed batch overwrites what was already written.
+type BatchReport struct {
+	// Written counts batches stored, Retried those that needed more than
+	// one attempt
+	Written int `json:"written"`
+	Retried int `json:"retried"`
+	// Failed lists the first batches that could not be written. Directory
+	// runs stop at the first; URL and connector runs go on with the next
+	// document.
+	Failed []FailedBatch `json:"failed,omitempty"`
+	failed int
+}
+
+// LastFailed returns the most recent failed batch, or nil.
+func (b BatchReport) LastFailed() *FailedBatch {
+	if len(b.Failed) == 0 {
+		return nil
+	}
+	return &b.Failed[len(b.Failed)-1]
+}
+
+// FailedBatch describes a batch that could not be written.
+type FailedBatch struct {
+	// Batch is its 1-based number in the run
+	Batch    int `json:"batch"`
+	Chunks   int `json:"chunks"`
+	Attempts int `json:"attempts"`
+	// Files lists the first files with chunks in the batch
+	Files []string `json:"files"`
+}
+
+// Empty reports whether the run found nothing worth reporting.
+func (rep *IngestReport) Empty() bool {
+	return rep == nil || rep.SecretChunks == 0 && rep.RedactedChunks == 0 && rep.DroppedChunks == 0
+}
+
+func (rep *IngestReport) addReused() {
+	if rep == nil {
+		return
+	}
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.ReusedFiles++
+}
+
+func (rep *IngestReport) addSecrets(c chunker.Chunk, found []secrets.Match, action string, skipped bool) {
+	if rep == nil {
+		return
+	}
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.SecretAction = action
+	rep.SecretChunks++
+	if skipped {
+		rep.SkippedChunks++
+	}
+	if rep.SecretKinds == nil {
+		rep.SecretKinds = map[string]int{}
+	}
+	for _, m := range found {
+		rep.SecretKinds[m.Rule]++
+	}
+	for _, p := range rep.SecretFiles {
+		if p == c.Path {
+			return
+		}
+	}
+	if len(rep.SecretFiles) < maxPreviewFiles {
+		rep.SecretFiles = append(rep.SecretFiles, c.Path)
+	}
+}
+
+func (rep *IngestReport) addFiltered(name string, res FilterResult, firstChange bool) {
+	if rep == nil {
+		return
+	}
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	switch {
+	case res.Drop:
+		rep.DroppedChunks++
+	case firstChange:
+		rep.RedactedChunks++
+	}
+	for kind, n := range res.Redactions {
+		if rep.Redactions == nil {
+			rep.Redactions = map[string]int{}
+		}
+		rep.Redactions[name+"."+kind] += n
+	}
+}
+
+func (rep *IngestReport) addBatch(chunks []preparedChunk, attempts int, err error) {
+	if rep == nil {
+		return
+	}
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	b := &rep.Batches
+	if err == nil {
+		b.Written++
+		if attempts > 1 {
+			b.Retried++
+		}
+		return
+	}
+	b.failed++
+	if len(b.Failed) >= maxPreviewFiles {
+		return
+	}
+	f := FailedBatch{Batch: b.Written + b.failed, Chunks: len(chunks), Attempts: attempts}
+	for _, c := range chunks {
+		if len(f.Files) < maxPreviewFiles && !slices.Contains(f.Files, c.Path) {
+			f.Files = append(f.Files, c.Path)
+		}
+	}
+	b.Failed = append(b.Failed, f)
+}
+
+// secretAction is indexing.secrets.action, "" when scanning is off.
+func (r *VecRAG) secretAction() string {
+	if r.secrets == nil {
+		return ""
+	}
+	switch a := r.config.Indexing.Secrets.Action; a {
+	case "off":
+		return ""
+	case "":
+		return "mask"
+	default:
+		return a
+	}
+}
+
+// preparedChunk is a chunk on its way to the store with what the content
+// checks found in it.
+type preparedChunk struct {
+	chunker.Chunk
+	secrets  []string // kinds of secrets found, stored as payload secrets
+	redacted []string // filter redactions, stored as payload redacted
+	filtered bool     // a content filter changed the text
+	// fileSecrets are the parts of the secrets found in the whole file
+	// that fall in this chunk, in offsets of its text
+	fileSecrets []secrets.Match
+	// checked chunks went through the secrets check and content filters,
+	// e.g. those from the chunk cache
+	checked bool
+}
+
+func prepareChunks(chunks []chunker.Chunk) []preparedChunk {
+	out := make([]preparedChunk, len(chunks))
+	for i, c := range chunks {
+		out[i] = preparedChunk{Chunk: c}
+	}
+	return out
+}
+
+// prepareFile prepares the chunks of one file whose whole text is text.
+// Secrets are looked for in the whole text as well, so that one split
+// across chunks, such as a PEM private key, is caught in every chunk that
+// holds a part of it and not only in the chunk with its BEGIN line.
+func (r *VecRAG) prepareFile(text string, chunks []chunker.Chunk) []preparedChunk {
+	out := prepareChunks(chunks)
+	if r.secretAction() == "" {
+		return out
+	}
+	found := r.secrets.Find(text)
+	if len(found) == 0 {
+		return out
+	}
+	for i := range out {
+		c := &out[i]
+		if c.End > c.Start && c.End <= len(text) && text[c.Start:c.End] == c.Text {
+			c.fileSecrets = secrets.Within(found, c.Start, c.End)
+		}
+	}
+	return out
+}
+
+// checkChunks runs the secrets check and the content filters over the
+// chunks of batch not checked yet and returns the chunks to store.
+func (r *VecRAG) checkChunks(batch []preparedChunk, rep *IngestReport) ([]preparedChunk, error) {
+	var done, fresh []preparedChunk
+	for _, c := range batch {
+		if c.checked {
+			done = append(done, c)
+		} else {
+			fresh = append(fresh, c)
+		}
+	}
+	if len(fresh) == 0 {
+		return done, nil
+	}
+	fresh, err := r.applyFilters(r.scrubSecrets(fresh, rep), rep)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fresh {
+		fresh[i].checked = true
+	}
+	return append(done, fresh...), nil
+}
+
+// scrubSecrets applies indexing.secrets to a batch before it is embedded
+// and returns the chunks to store.
+func (r *VecRAG) scrubSecrets(batch []preparedChunk, rep *IngestReport) []preparedChunk {
+	action := r.secretAction()
+	if action == "" {
+		return batch
+	}
+	var out []preparedChunk
+	for _, c := range batch {
+		found := secrets.Merge(append(r.secrets.Find(c.Text), c.fileSecrets...))
+		if len(found) == 0 {
+			out = append(out, c)
+			continue
+		}
+		rep.addSecrets(c.Chunk, found, action, action == "skip")
+		if action == "skip" {
+			continue
+		}
+		if action == "mask" {
+			c.Text = secrets.Mask(c.Text, found)
+		}
+		c.secrets = secrets.Rules(found)
+		out = append(out, c)
+	}
+	return out
+}
+
+// maskSecrets masks the secrets of text unless scanning is off, e.g. before
+// a file is sent to a summarizer.
+func (r *VecRAG) maskSecrets(text string) string {
+	if r.secretAction() == "" {
+		return text
+	}
+	return secrets.Mask(text, r.secrets.Find(text))
+}
@@ -0,0 +1,421 @@
+package ragvec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/connectors"
+)
+
+// ErrSnapshotsUnsupported means the vector store has no snapshot API.
+var ErrSnapshotsUnsupported error = &apierr.Error{Category: apierr.Config, Component: "config", Err: errors.New("snapshots need the qdrant backend")}
+
+// Snapshot is a full copy of the collection taken by the vector store.
+type Snapshot struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Created  string `json:"creation_time,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// snapshotStore is implemented by stores that can snapshot and recover
+// their collection.
+type snapshotStore interface {
+	createSnapshot(timeout time.Duration) (Snapshot, error)
+	listSnapshots() ([]Snapshot, error)
+	downloadSnapshot(name string, timeout time.Duration) (io.ReadCloser, int64, error)
+	deleteSnapshot(name string) error
+	// uploadSnapshot replaces the collection with the snapshot read from r
+	uploadSnapshot(name string, r io.Reader, timeout time.Duration) error
+}
+
+// doRaw is do for a body that is already encoded as contentType.
+func (q *Qdrant) doRaw(method, path string, body io.Reader, contentType string, timeout time.Duration) (*http.Response, error) {
+	if err := q.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	return q.sendRaw(method, path, body, contentType, timeout)
+}
+
+// snapshotError reads the error Qdrant reports for a failed snapshot call.
+func snapshotError(op string, res *http.Response) error {
+	var rr struct {
+		Status struct {
+			Error string `json:"error"`
+		} `json:"status"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	if json.Unmarshal(b, &rr) == nil && rr.Status.Error != "" {
+		return fmt.Errorf("%s http %d: %s", op, res.StatusCode, rr.Status.Error)
+	}
+	return fmt.Errorf("%s http %d", op, res.StatusCode)
+}
+
+func (q *Qdrant) createSnapshot(timeout time.Duration) (Snapshot, error) {
+	res, err := q.do("POST", q.collectionPath("/snapshots?wait=true"), nil, timeout)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return Snapshot{}, snapshotError("create snapshot", res)
+	}
+	var rr struct {
+		Result Snapshot `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
+		return Snapshot{}, err
+	}
+	return rr.Result, nil
+}
+
+func (q *Qdrant) listSnapshots() ([]Snapshot, error) {
+	res, err := q.do("GET", q.collectionPath("/snapshots"), nil, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return nil, snapshotError("list snapshots", res)
+	}
+	var rr struct {
+		Result []Snapshot `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
+		return nil, err
+	}
+	return rr.Result, nil
+}
+
+// downloadSnapshot streams a snapshot file; the size is -1 when Qdrant does
+// not report it.
+func (q *Qdrant) downloadSnapshot(name string, timeout time.Duration) (io.ReadCloser, int64, error) {
+	res, err := q.doRaw("GET", q.collectionPath("/snapshots/"+url.PathEscape(name)), nil, "", timeout)
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.StatusCode >= 300 {
+		defer res.Body.Close()
+		return nil, 0, snapshotError("download snapshot", res)
+	}
+	return res.Body, res.ContentLength, nil
+}
+
+func (q *Qdrant) deleteSnapshot(name string) error {
+	res, err := q.do("DELETE", q.collectionPath("/snapshots/"+url.PathEscape(name)+"?wait=true"), nil, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return snapshotError("delete snapshot", res)
+	}
+	return nil
+}
+
+// uploadSnapshot streams r to Qdrant as a multipart upload, so large
+// snapshots are never held in memory. Priority "snapshot" makes the
+// snapshot's points replace the collection's.
+func (q *Qdrant) uploadSnapshot(name string, r io.Reader, timeout time.Duration) error {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile("snapshot", name)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	res, err := q.doRaw("POST", q.collectionPath("/snapshots/upload?priority=snapshot&wait=true"), pr, mw.FormDataContentType(), timeout)
+	// Unblock the writer if the request ended before reading everything
+	pr.Close()
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return snapshotError("upload snapshot", res)
+	}
+	return nil
+}
+
+// BackupResult reports where rag_backup stored a snapshot.
+type BackupResult struct {
+	Snapshot
+	// Path is the copy in backup.dir
+	Path string `json:"path,omitempty"`
+	// S3URL is the copy in backup.s3
+	S3URL string `json:"s3_url,omitempty"`
+	// OnServer is false once the server copy was deleted after copying
+	OnServer bool `json:"on_server"`
+}
+
+// Backup snapshots the collection through the vector store and copies the
+// snapshot to backup.dir and backup.s3 when they are configured. The
+// server copy is deleted afterwards unless backup.keep_on_server is set or
+// there is no other copy.
+func (r *VecRAG) Backup() (BackupResult, error) {
+	ss, ok := r.vdb.(snapshotStore)
+	if !ok {
+		return BackupResult{}, ErrSnapshotsUnsupported
+	}
+	conf := r.config.Backup
+	timeout := r.backupTimeout()
+	snap, err := ss.createSnapshot(timeout)
+	if err != nil {
+		return BackupResult{}, err
+	}
+	res := BackupResult{Snapshot: snap, OnServer: true}
+	if conf.Dir == "" && conf.S3.Bucket == "" {
+		return res, nil
+	}
+
+	body, size, err := ss.downloadSnapshot(snap.Name, timeout)
+	if err != nil {
+		return res, err
+	}
+	defer body.Close()
+	if size < 0 {
+		size = snap.Size
+	}
+	src := io.Reader(body)
+	if conf.Dir != "" {
+		res.Path = filepath.Join(conf.Dir, snap.Name)
+		if err := writeFileAtomic(res.Path, body); err != nil {
+			res.Path = ""
+			return res, fmt.Errorf("save snapshot: %w", err)
+		}
+		if conf.S3.Bucket != "" {
+			f, err := os.Open(res.Path)
+			if err != nil {
+				return res, err
+			}
+			defer f.Close()
+			if st, err := f.Stat(); err == nil {
+				size = st.Size()
+			}
+			src = f
+		}
+	}
+	if conf.S3.Bucket != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		bucket := connectors.NewS3Bucket(conf.S3, 0)
+		key := conf.S3.Prefix + snap.Name
+		if err := bucket.Put(ctx, key, src, size); err != nil {
+			return res, err
+		}
+		res.S3URL = bucket.URL(key)
+	}
+
+	if !conf.KeepOnServer {
+		if err := ss.deleteSnapshot(snap.Name); err != nil {
+			fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: failed to delete snapshot %s from the server: %v\n", snap.Name, err)
+		} else {
+			res.OnServer = false
+		}
+	}
+	return res, nil
+}
+
+// writeFileAtomic writes r to path through a temporary file, so an
+// interrupted download never leaves a truncated snapshot behind.
+func writeFileAtomic(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (r *VecRAG) backupTimeout() time.Duration {
+	if s := r.config.Backup.TimeoutSec; s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 10 * time.Minute
+}
+
+// BackupEntry is a snapshot available to Restore.
+type BackupEntry struct {
+	Name    string    `json:"name"`
+	Source  string    `json:"source"` // "server", "dir" or "s3"
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created,omitempty"`
+}
+
+// ListBackups lists the snapshots on the server, in backup.dir and in
+// backup.s3, newest first. A source that cannot be listed is reported in
+// the returned warnings.
+func (r *VecRAG) ListBackups() ([]BackupEntry, []string, error) {
+	ss, ok := r.vdb.(snapshotStore)
+	if !ok {
+		return nil, nil, ErrSnapshotsUnsupported
+	}
+	var out []BackupEntry
+	var warnings []string
+	snaps, err := ss.listSnapshots()
+	if err != nil {
+		warnings = append(warnings, "server: "+err.Error())
+	}
+	for _, s := range snaps {
+		e := BackupEntry{Name: s.Name, Source: "server", Size: s.Size}
+		e.Created, _ = time.Parse("2006-01-02T15:04:05.999999", s.Created)
+		out = append(out, e)
+	}
+	conf := r.config.Backup
+	if conf.Dir != "" {
+		entries, err := os.ReadDir(conf.Dir)
+		if err != nil && !os.IsNotExist(err) {
+			warnings = append(warnings, "dir: "+err.Error())
+		}
+		for _, de := range entries {
+			info, err := de.Info()
+			if err != nil || !info.Mode().IsRegular() || !strings.HasSuffix(de.Name(), ".snapshot") {
+				continue
+			}
+			out = append(out, BackupEntry{Name: de.Name(), Source: "dir", Size: info.Size(), Created: info.ModTime().UTC()})
+		}
+	}
+	if conf.S3.Bucket != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		objects, err := connectors.NewS3Bucket(conf.S3, 0).List(ctx, conf.S3.Prefix)
+		if err != nil {
+			warnings = append(warnings, "s3: "+err.Error())
+		}
+		for _, o := range objects {
+			name := strings.TrimPrefix(o.Key, conf.S3.Prefix)
+			if !strings.HasSuffix(name, ".snapshot") || strings.Contains(name, "/") {
+				continue
+			}
+			out = append(out, BackupEntry{Name: name, Source: "s3", Size: o.Size, Created: o.LastModified.UTC()})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Created.After(out[j].Created) })
+	return out, warnings, nil
+}
+
+// RestoreResult reports what Restore did.
+type RestoreResult struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	// Warning is set when the restored collection does not match the
+	// embedding provider
+	Warning string `json:"warning,omitempty"`
+}
+
+// Restore replaces the collection with snapshot name, read from source:
+// "dir", "s3", "server" or "" for the first of those holding it. The index
+// manifest is cleared, since it describes the replaced contents; the next
+// rag_index re-embeds every file.
+func (r *VecRAG) Restore(name, source string) (RestoreResult, error) {
+	res := RestoreResult{Name: name, Source: source}
+	ss, ok := r.vdb.(snapshotStore)
+	if !ok {
+		return res, ErrSnapshotsUnsupported
+	}
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return res, fmt.Errorf("invalid snapshot name %q", name)
+	}
+	conf := r.config.Backup
+	timeout := r.backupTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if source == "" {
+		source = r.locateBackup(ctx, name)
+		res.Source = source
+	}
+	var body io.ReadCloser
+	switch source {
+	case "dir":
+		if conf.Dir == "" {
+			return res, fmt.Errorf("backup dir is not configured")
+		}
+		f, err := os.Open(filepath.Join(conf.Dir, name))
+		if err != nil {
+			return res, err
+		}
+		body = f
+	case "s3":
+		if conf.S3.Bucket == "" {
+			return res, fmt.Errorf("backup s3 bucket is not configured")
+		}
+		resp, err := connectors.NewS3Bucket(conf.S3, 0).Get(ctx, conf.S3.Prefix+name)
+		if err != nil {
+			return res, err
+		}
+		body = resp.Body
+	case "server":
+		rc, _, err := ss.downloadSnapshot(name, timeout)
+		if err != nil {
+			return res, err
+		}
+		body = rc
+	default:
+		return res, fmt.Errorf("unknown snapshot source %q (use dir, s3 or server)", source)
+	}
+	defer body.Close()
+
+	defer r.indexChanged()
+	if err := ss.uploadSnapshot(name, body, timeout); err != nil {
+		return res, err
+	}
+	r.forgetIndexed("")
+	if dr, ok := r.vdb.(DimReporter); ok {
+		if have, err := dr.CollectionDim(); err == nil && have > 0 && have != r.embed.Dim() {
+			res.Warning = fmt.Sprintf("the restored collection stores %d-dimensional vectors but the embedding provider produces %d", have, r.embed.Dim())
+		}
+	}
+	return res, nil
+}
+
+// locateBackup picks the source of snapshot name for Restore: backup.dir,
+// then backup.s3, then the server.
+func (r *VecRAG) locateBackup(ctx context.Context, name string) string {
+	conf := r.config.Backup
+	if conf.Dir != "" {
+		if _, err := os.Stat(filepath.Join(conf.Dir, name)); err == nil {
+			return "dir"
+		}
+	}
+	if conf.S3.Bucket != "" {
+		objects, err := connectors.NewS3Bucket(conf.S3, 0).List(ctx, conf.S3.Prefix+name)
+		if err == nil {
+			for _, o := range objects {
+				if o.Key == conf.S3.Prefix+name {
+					return "s3"
+				}
+			}
+		}
+	}
+	return "server"
+}
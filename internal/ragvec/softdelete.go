@@ -0,0 +1,93 @@
+package ragvec
+
+import (
+	"time"
+)
+
+// deletedCond matches points soft-deleted by DeleteAll or DeleteProject,
+// which carry deleted_at (unix seconds) until they are purged.
+var deletedCond = map[string]any{"key": "deleted_at", "range": map[string]any{"gte": 0}}
+
+// liveFilter adds the exclusion of soft-deleted points to filter.
+func liveFilter(filter map[string]any) map[string]any {
+	out := map[string]any{}
+	for k, v := range filter {
+		out[k] = v
+	}
+	mustNot, _ := filterConditions(filter["must_not"])
+	out["must_not"] = append(append([]map[string]any{}, mustNot...), deletedCond)
+	return out
+}
+
+// deletedFilter matches the soft-deleted points of project, or of every
+// project when it is empty.
+func deletedFilter(project string) map[string]any {
+	must := []map[string]any{deletedCond}
+	if project != "" {
+		must = append(must, map[string]any{"key": "project", "match": map[string]any{"value": project}})
+	}
+	return map[string]any{"must": must}
+}
+
+// projectFilter matches project, or everything when it is empty.
+func projectFilter(project string) map[string]any {
+	if project == "" {
+		return nil
+	}
+	return matchFilter(map[string]string{"project": project})
+}
+
+// SoftDeleteRetention is how long deleted chunks stay restorable; 0 means
+// deletes are immediate.
+func (r *VecRAG) SoftDeleteRetention() time.Duration {
+	return time.Duration(r.config.Indexing.SoftDeleteRetentionHours) * time.Hour
+}
+
+// softDelete marks the live points of project (every project when empty)
+// with deleted_at, hiding them from search and listings until they are
+// restored or purged.
+func (r *VecRAG) softDelete(project string) (int, error) {
+	filter := liveFilter(projectFilter(project))
+	n, err := r.countWhere(filter)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	defer r.indexChanged(project)
+	if err := r.vdb.SetPayload(filter, map[string]any{"deleted_at": time.Now().Unix()}); err != nil {
+		return 0, err
+	}
+	// The manifest must not vouch for files whose chunks are hidden, or
+	// re-indexing them would skip them as unchanged
+	r.forgetIndexed(project)
+	return n, nil
+}
+
+// Undelete restores the soft-deleted points of project (every project when
+// empty) that have not been purged yet. Their files are re-embedded on the
+// next rag_index, as the delete dropped them from the manifest.
+func (r *VecRAG) Undelete(project string) (int, error) {
+	filter := deletedFilter(project)
+	n, err := r.countWhere(filter)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	defer r.indexChanged(project)
+	if err := r.vdb.SetPayload(filter, map[string]any{"deleted_at": nil}); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// PreviewUndelete reports what Undelete(project) would restore.
+func (r *VecRAG) PreviewUndelete(project string) (DeletePreview, error) {
+	return r.previewWhere(deletedFilter(project))
+}
+
+// PurgeDeleted permanently deletes the points soft-deleted longer than the
+// retention ago.
+func (r *VecRAG) PurgeDeleted(now time.Time) (int, error) {
+	filter := map[string]any{"must": []map[string]any{
+		{"key": "deleted_at", "range": map[string]any{"lte": now.Add(-r.SoftDeleteRetention()).Unix()}},
+	}}
+	return r.deleteWhere(filter)
+}
@@ -0,0 +1,96 @@
+package ragvec
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// Stamp identifies the embedding space a vector belongs to. It is written to
+// every point payload (embed_provider, embed_model, embed_dim) so vectors from
+// different models are never silently compared.
+type Stamp struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Dim      int    `json:"dim"`
+}
+
+func (s Stamp) String() string {
+	return fmt.Sprintf("%s/%s/%d", s.Provider, s.Model, s.Dim)
+}
+
+// modelFor names the model behind a provider name.
+func modelFor(provider string, config *cfg.Config) string {
+	if provider == "openai" {
+		return config.Embedding.OpenAI.Model
+	}
+	if config.Embedding.Local.Engine == "onnx" {
+		return onnxModelName(&config.Embedding.Local)
+	}
+	return "local-tfidf"
+}
+
+// embedStamped embeds texts, records the call in the embedding statistics and
+// reports which provider produced the vectors; with a failover chain this may
+// be a fallback rather than the primary.
+func (r *VecRAG) embedStamped(texts []string) ([][]float32, Stamp, error) {
+	start := time.Now()
+	vecs, stamp, err := r.embedRaw(texts)
+	r.stats.record(time.Since(start), err)
+	return vecs, stamp, err
+}
+
+// embedRaw is embedStamped without recording statistics.
+func (r *VecRAG) embedRaw(texts []string) ([][]float32, Stamp, error) {
+	name := r.config.Embedding.Provider
+	var vecs [][]float32
+	var err error
+	if chain, ok := r.embed.(*FailoverProvider); ok {
+		vecs, name, err = chain.embedNamed(texts, r.requestID)
+	} else {
+		vecs, err = embedWithID(r.embed, texts, r.requestID)
+	}
+	return vecs, Stamp{Provider: name, Model: modelFor(name, r.config), Dim: r.embed.Dim()}, apierr.Wrap(apierr.Provider, "embedding", err)
+}
+
+// checkWrite refuses vectors for the store that a failover provider made
+// with another model than the primary one, which the collection is indexed
+// with: they would not be comparable with the rest. The error is transient
+// since the primary provider may answer the next attempt.
+func (r *VecRAG) checkWrite(s Stamp) error {
+	want := modelFor(r.config.Embedding.Provider, r.config)
+	if s.Model == want {
+		return nil
+	}
+	return &apierr.Error{Category: apierr.Provider, Component: "embedding", Transient: true,
+		Err: fmt.Errorf("fallback provider %s embeds with model %s, not %s like the collection; not writing its vectors", s.Provider, s.Model, want)}
+}
+
+func (s Stamp) payload(p map[string]any) {
+	p["embed_provider"] = s.Provider
+	p["embed_model"] = s.Model
+	p["embed_dim"] = s.Dim
+}
+
+// stampOf reads the stamp from a point payload; ok is false for points
+// indexed before stamping was introduced.
+func stampOf(p map[string]any) (Stamp, bool) {
+	model, ok := p["embed_model"].(string)
+	if !ok || model == "" {
+		return Stamp{}, false
+	}
+	s := Stamp{Provider: toStr(p["embed_provider"]), Model: model}
+	if d, ok := floatOf(p["embed_dim"]); ok {
+		s.Dim = int(d)
+	}
+	return s, true
+}
+
+// Model mismatch policies for search (search.model_mismatch).
+const (
+	MismatchWarn   = "warn"
+	MismatchFilter = "filter"
+	MismatchIgnore = "ignore"
+)
@@ -0,0 +1,251 @@
+package ragvec
+
+import (
+	"fmt"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// VectorStore is the vector database behind a VecRAG. Filters use Qdrant's
+// JSON filter syntax; other backends accept the subset this package builds:
+// a "must" list of exact keyword matches.
+type VectorStore interface {
+	EnsureCollection() error
+	CollectionExists() (bool, error)
+	HealthCheck() error
+	CountPoints() (int, error)
+	UpsertPoints(ids []string, vecs [][]float32, payloads []map[string]any) error
+	Search(vec []float32, k int, filter map[string]any, params SearchParams) ([]SearchHit, error)
+	DeleteByIDs(ids []any) error
+	// SetPayload merges patch into the payload of every point matching filter
+	SetPayload(filter map[string]any, patch map[string]any) error
+	SetPayloadByIDs(ids []any, patch map[string]any) error
+	ScrollPoints(limit int, offset any) ([]ScrollPoint, any, error)
+	ScrollPointsWithFilter(limit int, offset any, filter map[string]any) ([]ScrollPoint, any, error)
+	Breaker() *CircuitBreaker
+}
+
+// filterFields are the payload keys stores other than Qdrant index
+// separately so filters can use them. tags, version_prefixes and acl hold
+// lists.
+var filterFields = []string{"project", "path", "file_type", "kind", "tags", "version_prefixes", "acl"}
+
+// rangeFields are the numeric payload keys (unix seconds) those stores index
+// for range filters.
+var rangeFields = []string{"indexed_at", "modified_at", "expires_at", "deleted_at"}
+
+// DimReporter is implemented by stores that can read the vector size of an
+// existing collection, so a provider producing another size is caught
+// before the first upsert fails.
+type DimReporter interface {
+	// CollectionDim returns the vector size, or 0 when the collection does
+	// not exist or the size is not reported.
+	CollectionDim() (int, error)
+}
+
+// TextSearcher is implemented by stores that can use the query text as well
+// as its vector, e.g. for hybrid BM25 + k-NN ranking.
+type TextSearcher interface {
+	SearchText(text string, vec []float32, k int, filter map[string]any, params SearchParams) ([]SearchHit, error)
+}
+
+// ScoreExplainer is implemented by hybrid stores that can score given
+// points by text and by vector apart, to break down a fused ranking.
+type ScoreExplainer interface {
+	ExplainScores(text string, vec []float32, ids []any) (textScores, vectorScores map[string]float32, err error)
+}
+
+// NewStore creates the client for the configured backend. dim is the vector
+// size used when the collection has to be created. Chunk text is encrypted
+// when store.encryption has a key.
+func NewStore(config *cfg.Config, dim int) VectorStore {
+	return encryptStore(newStore(config, dim), config.Store.Encryption)
+}
+
+// storeErrorf formats an error of backend, marked as a vector store
+// failure for clients.
+func storeErrorf(backend, format string, a ...any) error {
+	return apierr.Wrap(apierr.Store, backend, fmt.Errorf(format, a...))
+}
+
+func newStore(config *cfg.Config, dim int) VectorStore {
+	switch config.Store.Backend {
+	case "redis":
+		return NewRedisWithConfig(config, dim)
+	case "opensearch":
+		return NewOpenSearchWithConfig(config, dim)
+	case "memory":
+		return NewMemoryWithConfig(config, dim)
+	}
+	return NewQdrantWithConfig(&config.Qdrant, dim)
+}
+
+// fieldMatch requires payload[Key] to equal one of Any (when the payload
+// value is a list, one of its elements must) or, for a range condition, to
+// be a number within Range. Not inverts the condition.
+type fieldMatch struct {
+	Key   string
+	Any   []string
+	Range map[string]float64 // "gt", "gte", "lt", "lte"
+	Not   bool
+}
+
+// filterMatches flattens a filter into the field matches it requires.
+// It fails on anything other than must and must_not lists of exact
+// ("value"), any-of ("any") or numeric range conditions.
+func filterMatches(filter map[string]any) ([]fieldMatch, error) {
+	out := []fieldMatch{}
+	if filter == nil {
+		return out, nil
+	}
+	for key := range filter {
+		if key != "must" && key != "must_not" {
+			return nil, fmt.Errorf("unsupported filter: only must and must_not lists of exact matches are supported")
+		}
+	}
+	for _, key := range []string{"must", "must_not"} {
+		conds, err := filterConditions(filter[key])
+		if err != nil {
+			return nil, err
+		}
+		matches, err := conditionMatches(conds)
+		if err != nil {
+			return nil, err
+		}
+		for i := range matches {
+			matches[i].Not = key == "must_not"
+		}
+		out = append(out, matches...)
+	}
+	return out, nil
+}
+
+// filterConditions reads a must or must_not list.
+func filterConditions(list any) ([]map[string]any, error) {
+	switch l := list.(type) {
+	case nil:
+		return nil, nil
+	case []map[string]any:
+		return l, nil
+	case []any:
+		conds := make([]map[string]any, 0, len(l))
+		for _, c := range l {
+			m, ok := c.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("unsupported filter condition %v", c)
+			}
+			conds = append(conds, m)
+		}
+		return conds, nil
+	}
+	return nil, fmt.Errorf("unsupported filter: only must and must_not lists of exact matches are supported")
+}
+
+func conditionMatches(conds []map[string]any) ([]fieldMatch, error) {
+	out := []fieldMatch{}
+	for _, c := range conds {
+		key, _ := c["key"].(string)
+		if rng, ok := c["range"].(map[string]any); ok {
+			fm := fieldMatch{Key: key, Range: map[string]float64{}}
+			for op, v := range rng {
+				f, ok := floatOf(v)
+				if !ok || (op != "gt" && op != "gte" && op != "lt" && op != "lte") {
+					return nil, fmt.Errorf("unsupported filter condition %v", c)
+				}
+				fm.Range[op] = f
+			}
+			if key == "" || len(fm.Range) == 0 {
+				return nil, fmt.Errorf("unsupported filter condition %v", c)
+			}
+			out = append(out, fm)
+			continue
+		}
+		match, _ := c["match"].(map[string]any)
+		fm := fieldMatch{Key: key}
+		if val, ok := match["value"]; ok {
+			fm.Any = []string{toStr(val)}
+		} else {
+			switch vals := match["any"].(type) {
+			case []string:
+				fm.Any = vals
+			case []any:
+				for _, v := range vals {
+					fm.Any = append(fm.Any, toStr(v))
+				}
+			}
+		}
+		if key == "" || len(fm.Any) == 0 {
+			return nil, fmt.Errorf("unsupported filter condition %v", c)
+		}
+		out = append(out, fm)
+	}
+	return out, nil
+}
+
+// payloadMatches reports whether payload satisfies every match.
+func payloadMatches(payload map[string]any, matches []fieldMatch) bool {
+	for _, m := range matches {
+		var ok bool
+		if m.Range != nil {
+			ok = inRange(payload[m.Key], m.Range)
+		} else {
+			ok = valueMatches(payload[m.Key], m.Any)
+		}
+		if ok == m.Not {
+			return false
+		}
+	}
+	return true
+}
+
+func inRange(v any, rng map[string]float64) bool {
+	f, ok := floatOf(v)
+	if !ok {
+		return false
+	}
+	for op, bound := range rng {
+		switch {
+		case op == "gt" && !(f > bound), op == "gte" && !(f >= bound),
+			op == "lt" && !(f < bound), op == "lte" && !(f <= bound):
+			return false
+		}
+	}
+	return true
+}
+
+func floatOf(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func valueMatches(v any, want []string) bool {
+	var have []string
+	switch t := v.(type) {
+	case []string:
+		have = t
+	case []any:
+		for _, e := range t {
+			have = append(have, toStr(e))
+		}
+	default:
+		have = []string{toStr(v)}
+	}
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,320 @@
+package ragvec
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	"github.com/Rhyanz46/mcp-service/internal/stopwords"
+)
+
+// Suggestions guide a caller whose search found nothing useful, so an agent
+// can retry or tell the user instead of answering from empty context.
+type Suggestions struct {
+	// Reason is "no_results" or "low_scores"
+	Reason string `json:"reason"`
+	// BestScore is the top hit's score, 0 without hits
+	BestScore float32 `json:"best_score"`
+	// Projects are indexed projects that may hold the answer
+	Projects []ProjectSuggestion `json:"closest_projects,omitempty"`
+	// DidYouMean lists query terms absent from the index with indexed terms
+	// spelled alike; Query applies the first alternative of each
+	DidYouMean []TermSuggestion `json:"did_you_mean,omitempty"`
+	Query      string           `json:"suggested_query,omitempty"`
+	// Hints are the next steps in plain words
+	Hints []string `json:"hints"`
+}
+
+// ProjectSuggestion is a project worth searching instead.
+type ProjectSuggestion struct {
+	Project string `json:"project"`
+	// Score is the best hit of the project for the query when it was
+	// searched without the filter
+	Score float32 `json:"score,omitempty"`
+	// Why is "outside_filter" (it has hits the filter excluded) or
+	// "similar_name" (its name resembles the query or the project filter)
+	Why string `json:"why"`
+}
+
+// TermSuggestion is a misspelling candidate.
+type TermSuggestion struct {
+	Term         string   `json:"term"`
+	Alternatives []string `json:"alternatives"`
+}
+
+// maxTermAlternatives bounds the alternatives offered per term.
+const maxTermAlternatives = 3
+
+// Suggest returns guidance for a search of query under f that returned
+// hits, or nil when the hits look useful or search.suggestions is off. A hit
+// is useful when it scores at least search.suggestions.min_score.
+func (r *VecRAG) Suggest(query string, hits []Hit, f SearchFilter, params SearchParams) (*Suggestions, error) {
+	sc := r.config.Search.Suggestions
+	if !sc.Enabled {
+		return nil, nil
+	}
+	s := &Suggestions{Reason: "no_results"}
+	if len(hits) > 0 {
+		s.Reason = "low_scores"
+		for _, h := range hits {
+			s.BestScore = max(s.BestScore, h.Score)
+		}
+		if float64(s.BestScore) >= sc.MinScore {
+			return nil, nil
+		}
+	}
+
+	// Only what the caller may read is suggested
+	projects, err := r.ListProjectsFor(f.ACL)
+	if err != nil {
+		return nil, err
+	}
+	if len(projects) == 0 {
+		s.Hints = append(s.Hints, "The index is empty. Index the documents with rag_index (or rag_index_url for web pages) before searching.")
+		return s, nil
+	}
+	names := make([]string, 0, len(projects))
+	for _, p := range projects {
+		names = append(names, toStr(p["project"]))
+	}
+
+	filtered := f.narrowed()
+	seen := map[string]bool{}
+	if filtered {
+		// Look for what the filter hid, within what the caller may read
+		wide, err := r.SearchFiltered(query, 10, SearchFilter{ACL: f.ACL}, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range wide {
+			if seen[h.Project] || float64(h.Score) < sc.MinScore {
+				continue
+			}
+			seen[h.Project] = true
+			s.Projects = append(s.Projects, ProjectSuggestion{Project: h.Project, Score: h.Score, Why: "outside_filter"})
+		}
+	}
+	stop := stopwords.Load(r.config.Language.Stopwords, r.config.Language.ExtraStopwords, r.config.Language.DisableStopwords)
+	terms := tokenizeText(query, stop)
+	wanted := append(append([]string{}, terms...), strings.ToLower(strings.TrimSpace(f.Project)))
+	for _, name := range names {
+		if !seen[name] && similarName(name, wanted) {
+			seen[name] = true
+			s.Projects = append(s.Projects, ProjectSuggestion{Project: name, Why: "similar_name"})
+		}
+	}
+	if len(s.Projects) > sc.MaxProjects {
+		s.Projects = s.Projects[:sc.MaxProjects]
+	}
+
+	vocab, err := r.vocabulary(f.ACL)
+	if err != nil {
+		return nil, err
+	}
+	s.Query = query
+	for _, t := range terms {
+		if vocab[t] > 0 {
+			continue
+		}
+		alts := closestTerms(t, vocab, maxTermAlternatives)
+		if len(alts) == 0 {
+			continue
+		}
+		s.DidYouMean = append(s.DidYouMean, TermSuggestion{Term: t, Alternatives: alts})
+		s.Query = regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(t)+`\b`).ReplaceAllString(s.Query, alts[0])
+	}
+	if len(s.DidYouMean) == 0 {
+		s.Query = ""
+	}
+
+	if p := strings.TrimSpace(f.Project); p != "" && !contains(names, p) {
+		s.Hints = append(s.Hints, fmt.Sprintf("Project %q is not indexed; call rag_projects for the indexed ones.", p))
+	}
+	if filtered {
+		s.Hints = append(s.Hints, "The filter (project, tags or dates) may be too narrow; retry without it or in one of closest_projects.")
+	}
+	if s.Query != "" {
+		s.Hints = append(s.Hints, "Some query terms do not occur in the index; retry with suggested_query.")
+	}
+	if s.Reason == "low_scores" {
+		s.Hints = append(s.Hints, fmt.Sprintf("The best hit scores %.2f, below search.suggestions.min_score (%.2f); treat the hits as weak evidence.", s.BestScore, sc.MinScore))
+	}
+	s.Hints = append(s.Hints,
+		"If the answer lives in files that are not indexed yet, index their directory with rag_index.",
+		"Do not answer from these results alone; if nothing above helps, say the index holds nothing relevant.")
+	return s, nil
+}
+
+// narrowed reports whether f restricts a search beyond the query.
+func (f SearchFilter) narrowed() bool {
+	return strings.TrimSpace(f.Project) != "" || strings.TrimSpace(f.ProjectPrefix) != "" ||
+		len(NormalizeTags(f.TagsAny)) > 0 || len(NormalizeTags(f.TagsAll)) > 0 ||
+		chunker.NormalizeVersion(f.Version) != "" || !f.ModifiedAfter.IsZero() || !f.IndexedBefore.IsZero()
+}
+
+// similarName reports whether a project name contains one of words, or is
+// a small edit away from one.
+func similarName(name string, words []string) bool {
+	lower := strings.ToLower(name)
+	for _, w := range words {
+		if len(w) < 3 {
+			continue
+		}
+		if strings.Contains(lower, w) || editDistance(lower, w, maxEdits(w)) <= maxEdits(w) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// maxEdits is the number of typos tolerated in a word of w's length.
+func maxEdits(w string) int {
+	if len([]rune(w)) <= 5 {
+		return 1
+	}
+	return 2
+}
+
+// closestTerms returns up to n vocabulary terms within maxEdits of term,
+// nearest first and then most common.
+func closestTerms(term string, vocab map[string]int, n int) []string {
+	limit := maxEdits(term)
+	type cand struct {
+		term string
+		dist int
+		df   int
+	}
+	var cands []cand
+	tl := len([]rune(term))
+	for v, df := range vocab {
+		if d := len([]rune(v)) - tl; d > limit || -d > limit {
+			continue
+		}
+		if dist := editDistance(term, v, limit); dist <= limit {
+			cands = append(cands, cand{v, dist, df})
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		a, b := cands[i], cands[j]
+		if a.dist != b.dist {
+			return a.dist < b.dist
+		}
+		if a.df != b.df {
+			return a.df > b.df
+		}
+		return a.term < b.term
+	})
+	out := make([]string, 0, n)
+	for _, c := range cands[:min(n, len(cands))] {
+		out = append(out, c.term)
+	}
+	return out
+}
+
+// editDistance is the Levenshtein distance of a and b, or limit+1 once it
+// is certain to exceed limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// vocabMaxChunks bounds the chunks read to build the vocabulary.
+const vocabMaxChunks = 20000
+
+// vocabCache holds the document frequency of the indexed terms for
+// did-you-mean suggestions. It is rebuilt once older than
+// search.suggestions.vocabulary_ttl_sec, so terms indexed since may be
+// missed until then. There is one vocabulary per set of access labels.
+type vocabCache struct {
+	mu    sync.Mutex
+	byACL map[string]vocabEntry
+}
+
+type vocabEntry struct {
+	df map[string]int
+	at time.Time
+}
+
+// vocabulary returns the document frequency of each term indexed in the
+// chunks carrying one of the access labels acl (all chunks for nil), read
+// from at most vocabMaxChunks chunks.
+func (r *VecRAG) vocabulary(acl []string) (map[string]int, error) {
+	ttl := time.Duration(r.config.Search.Suggestions.VocabularyTTLSec) * time.Second
+	key := "*"
+	if acl != nil {
+		key = "acl:" + strings.Join(NormalizeTags(acl), "\x00")
+	}
+	c := r.vocab
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.byACL[key]; ok && time.Since(e.at) < ttl {
+		return e.df, nil
+	}
+	stop := stopwords.Load(r.config.Language.Stopwords, r.config.Language.ExtraStopwords, r.config.Language.DisableStopwords)
+	df := map[string]int{}
+	filter := searchable(liveFilter(nil))
+	if acl != nil {
+		filter = searchable(liveFilter(map[string]any{"must": aclMust(acl)}))
+	}
+	read := 0
+	var offset any
+	for read < vocabMaxChunks {
+		pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offset, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, pt := range pts {
+			seen := map[string]bool{}
+			for _, t := range tokenizeText(toStr(pt.Payload["text"]), stop) {
+				if !seen[t] {
+					seen[t] = true
+					df[t]++
+				}
+			}
+		}
+		read += len(pts)
+		if next == nil {
+			break
+		}
+		offset = next
+	}
+	if c.byACL == nil {
+		c.byACL = map[string]vocabEntry{}
+	}
+	c.byACL[key] = vocabEntry{df: df, at: time.Now()}
+	return df, nil
+}
@@ -0,0 +1,204 @@
+package ragvec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// KindSummary marks points holding a generated per-file summary.
+const KindSummary = "summary"
+
+// Summarizer condenses a whole file into a short description.
+type Summarizer interface {
+	Summarize(path, text string) (string, error)
+}
+
+func newSummarizer(config *cfg.Config) Summarizer {
+	sc := config.Indexing.Summaries
+	if !sc.Enabled {
+		return nil
+	}
+	if sc.Provider == "openai" {
+		return &openAISummarizer{apiKey: config.Embedding.OpenAI.APIKey, model: sc.Model, maxChars: sc.MaxChars}
+	}
+	return &extractiveSummarizer{maxChars: sc.MaxChars}
+}
+
+// summaryChunks builds one extra chunk per sufficiently long file. Files that
+// fail to summarize are skipped with a warning rather than failing the index run.
+func (r *VecRAG) summaryChunks(files []chunker.File) []chunker.Chunk {
+	if r.summarizer == nil {
+		return nil
+	}
+	var out []chunker.Chunk
+	for _, f := range files {
+		if len(f.Text) < r.config.Indexing.Summaries.MinFileChars {
+			continue
+		}
+		// Summarizers may send the text to an API; keep secrets and what
+		// the content filters redact out of it
+		text, err := r.filterText(f.Path, r.maskSecrets(f.Text))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: summary skipped for %s: %v\n", f.Path, err)
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		s, err := r.summarizer.Summarize(f.Path, text)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: summary failed for %s: %v\n", f.Path, err)
+			continue
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		out = append(out, chunker.Chunk{
+			ID:       filepath.Base(f.Path) + ":summary",
+			Path:     f.Path,
+			Text:     "Summary of " + filepath.Base(f.Path) + ": " + s,
+			Position: -1,
+			FileHash: chunker.FileHash(f.Text),
+			Kind:     KindSummary,
+			Modified: f.ModTime,
+		})
+	}
+	return out
+}
+
+// filterText runs the content filters over the text of the file at path
+// as one chunk. It returns "" when a filter drops it.
+func (r *VecRAG) filterText(path, text string) (string, error) {
+	out, err := r.applyFilters([]preparedChunk{{Chunk: chunker.Chunk{Path: path, Text: text}}}, nil)
+	if err != nil || len(out) == 0 {
+		return "", err
+	}
+	return out[0].Text, nil
+}
+
+// ---------- Extractive (offline) ----------
+
+// extractiveSummarizer needs no network: it keeps the title, the first
+// paragraph and the section headings of a document.
+type extractiveSummarizer struct {
+	maxChars int
+}
+
+func (e *extractiveSummarizer) Summarize(path, text string) (string, error) {
+	var title, intro string
+	var headings []string
+	var para []string
+	for _, line := range strings.Split(text, "\n") {
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "#") {
+			h := strings.TrimSpace(strings.TrimLeft(t, "#"))
+			if h == "" {
+				continue
+			}
+			if title == "" {
+				title = h
+			} else {
+				headings = append(headings, h)
+			}
+			continue
+		}
+		if intro != "" {
+			continue
+		}
+		if t == "" {
+			if len(para) > 0 {
+				intro = strings.Join(para, " ")
+			}
+			continue
+		}
+		para = append(para, t)
+	}
+	if intro == "" && len(para) > 0 {
+		intro = strings.Join(para, " ")
+	}
+	var b strings.Builder
+	if title != "" {
+		b.WriteString(title)
+		b.WriteString(". ")
+	}
+	b.WriteString(intro)
+	if len(headings) > 0 {
+		if len(headings) > 12 {
+			headings = headings[:12]
+		}
+		b.WriteString(" Sections: ")
+		b.WriteString(strings.Join(headings, "; "))
+		b.WriteString(".")
+	}
+	return preview(b.String(), e.maxChars), nil
+}
+
+// ---------- OpenAI chat completions ----------
+
+type openAISummarizer struct {
+	apiKey   string
+	model    string
+	maxChars int
+}
+
+// summaryInputChars bounds how much of a file is sent to the model.
+const summaryInputChars = 12000
+
+func (o *openAISummarizer) Summarize(path, text string) (string, error) {
+	if len(text) > summaryInputChars {
+		text = text[:summaryInputChars]
+	}
+	type msg struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	type reqT struct {
+		Model     string `json:"model"`
+		Messages  []msg  `json:"messages"`
+		MaxTokens int    `json:"max_tokens"`
+	}
+	prompt := fmt.Sprintf("Summarize the file %s in at most %d characters. Describe what it is about and what it covers; no preamble.\n\n%s",
+		filepath.Base(path), o.maxChars, text)
+	body, _ := json.Marshal(reqT{
+		Model:     o.model,
+		Messages:  []msg{{Role: "user", Content: prompt}},
+		MaxTokens: o.maxChars/3 + 16,
+	})
+	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return "", fmt.Errorf("openai chat http %d", res.StatusCode)
+	}
+	var rr struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
+		return "", err
+	}
+	if len(rr.Choices) == 0 {
+		return "", fmt.Errorf("openai chat returned no choices")
+	}
+	return preview(rr.Choices[0].Message.Content, o.maxChars), nil
+}
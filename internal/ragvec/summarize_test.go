@@ -0,0 +1,39 @@
+package ragvec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/pii"
+)
+
+// recordingSummarizer keeps the texts it is asked to summarize.
+type recordingSummarizer struct {
+	texts []string
+}
+
+func (s *recordingSummarizer) Summarize(path, text string) (string, error) {
+	s.texts = append(s.texts, text)
+	return "A summary.", nil
+}
+
+func TestSummaryChunksFilterText(t *testing.T) {
+	d, err := pii.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := cfg.DefaultConfig()
+	config.Indexing.Summaries.MinFileChars = 10
+	sum := &recordingSummarizer{}
+	r := &VecRAG{config: config, summarizer: sum, filters: []ContentFilter{piiFilter{d}}}
+	text := "# Team\n\nWrite to ana@example.com for access.\n" + strings.Repeat("More text. ", 20)
+	out := r.summaryChunks([]chunker.File{{Path: "/docs/team.md", Text: text}})
+	if len(out) != 1 || len(sum.texts) != 1 {
+		t.Fatalf("got %d summaries from %d calls, want 1", len(out), len(sum.texts))
+	}
+	if strings.Contains(sum.texts[0], "ana@example.com") {
+		t.Errorf("summarizer received personal data: %q", sum.texts[0])
+	}
+}
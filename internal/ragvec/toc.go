@@ -0,0 +1,181 @@
+package ragvec
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+)
+
+// KindTOC marks points holding a file's table of contents.
+const KindTOC = "toc"
+
+// tocPosition is the position of table-of-contents points, apart from the
+// windows (0…) and the summary (-1) of the same file.
+const tocPosition = -2
+
+// tocCond matches table-of-contents points, which searches leave out.
+var tocCond = map[string]any{"key": "kind", "match": map[string]any{"value": KindTOC}}
+
+// searchable adds the exclusion of table-of-contents points to a filter
+// built by liveFilter.
+func searchable(filter map[string]any) map[string]any {
+	filter["must_not"] = append(filter["must_not"].([]map[string]any), tocCond)
+	return filter
+}
+
+// tocChunks builds one table-of-contents chunk per file with headings. Its
+// text, the outline itself, is what gets embedded.
+func (r *VecRAG) tocChunks(files []chunker.File) []chunker.Chunk {
+	tc := r.config.Indexing.TOC
+	if !tc.Enabled {
+		return nil
+	}
+	var out []chunker.Chunk
+	for _, f := range files {
+		hs := capHeadings(chunker.Headings(f.Text), tc.MaxHeadings)
+		if len(hs) == 0 {
+			continue
+		}
+		var b strings.Builder
+		b.WriteString("Contents of " + filepath.Base(f.Path) + ":")
+		for _, h := range hs {
+			b.WriteString("\n" + strings.Repeat("  ", h.Level-1) + "- " + h.Title)
+		}
+		out = append(out, chunker.Chunk{
+			ID:       filepath.Base(f.Path) + ":toc",
+			Path:     f.Path,
+			Text:     b.String(),
+			Position: tocPosition,
+			FileHash: chunker.FileHash(f.Text),
+			Kind:     KindTOC,
+			Modified: f.ModTime,
+			Headings: hs,
+		})
+	}
+	return out
+}
+
+// capHeadings keeps at most n headings (0 keeps all), dropping the deepest
+// levels first and then cutting the tail.
+func capHeadings(hs []chunker.Heading, n int) []chunker.Heading {
+	if n <= 0 {
+		return hs
+	}
+	for depth := 6; len(hs) > n && depth > 1; depth-- {
+		kept := hs[:0:0]
+		for _, h := range hs {
+			if h.Level < depth {
+				kept = append(kept, h)
+			}
+		}
+		if len(kept) == 0 {
+			break
+		}
+		hs = kept
+	}
+	if len(hs) > n {
+		hs = hs[:n]
+	}
+	return hs
+}
+
+// headingsPayload stores headings in a form every backend round-trips.
+func headingsPayload(hs []chunker.Heading) []any {
+	out := make([]any, len(hs))
+	for i, h := range hs {
+		out[i] = map[string]any{"level": h.Level, "title": h.Title, "start_byte": h.Start}
+	}
+	return out
+}
+
+// headingsOf reads the headings of a table-of-contents payload.
+func headingsOf(v any) []chunker.Heading {
+	l, _ := v.([]any)
+	out := make([]chunker.Heading, 0, len(l))
+	for _, e := range l {
+		m, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		h := chunker.Heading{Title: toStr(m["title"])}
+		h.Level, _ = intOf(m["level"])
+		h.Start, _ = intOf(m["start_byte"])
+		out = append(out, h)
+	}
+	return out
+}
+
+// TOCEntry is the outline of one indexed file.
+type TOCEntry struct {
+	Path     string            `json:"path"`
+	Project  string            `json:"project"`
+	Headings []chunker.Heading `json:"headings"`
+}
+
+// TOCQuery selects the outlines rag_toc returns. Empty fields match
+// everything.
+type TOCQuery struct {
+	Project string // exact project name (server-side filter)
+	Glob    string // path glob, as for FindFiles
+	// MaxLevel drops headings deeper than it; 0 keeps all
+	MaxLevel int
+	// ACL, when set, limits the outlines to files carrying one of these
+	// access labels, as SearchFilter.ACL does
+	ACL []string
+}
+
+// TOC returns the outlines of the indexed files matching q, sorted by
+// path, along with the total before offset/limit are applied. Files indexed
+// before table-of-contents points existed, or with indexing.toc disabled,
+// have none.
+func (r *VecRAG) TOC(q TOCQuery, offset, limit int) ([]TOCEntry, int, error) {
+	must := []map[string]any{tocCond}
+	if p := strings.TrimSpace(q.Project); p != "" {
+		must = append(must, map[string]any{"key": "project", "match": map[string]any{"value": p}})
+	}
+	must = append(must, aclMust(q.ACL)...)
+	filter := liveFilter(map[string]any{"must": must})
+	var glob *regexp.Regexp
+	if g := strings.TrimSpace(q.Glob); g != "" {
+		glob = globRegexp(g)
+	}
+
+	entries := []TOCEntry{}
+	var offsetID any
+	for {
+		pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offsetID, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, pt := range pts {
+			p := pt.Payload
+			path := toStr(p["path"])
+			if glob != nil && !glob.MatchString(filepath.ToSlash(path)) {
+				continue
+			}
+			e := TOCEntry{Path: path, Project: toStr(p["project"])}
+			for _, h := range headingsOf(p["headings"]) {
+				if q.MaxLevel <= 0 || h.Level <= q.MaxLevel {
+					e.Headings = append(e.Headings, h)
+				}
+			}
+			if len(e.Headings) > 0 {
+				entries = append(entries, e)
+			}
+		}
+		if next == nil {
+			break
+		}
+		offsetID = next
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	total := len(entries)
+	if limit <= 0 {
+		limit = 50
+	}
+	offset = min(max(offset, 0), total)
+	return entries[offset:min(offset+limit, total)], total, nil
+}
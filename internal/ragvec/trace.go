@@ -0,0 +1,48 @@
+package ragvec
+
+// RequestIDHeader carries the correlation ID of an HTTP API request to the
+// vector store and embedding API, so their logs can be matched with ours.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDStore is implemented by HTTP-based stores that can tag their
+// requests with a correlation ID.
+type requestIDStore interface {
+	withRequestID(id string) VectorStore
+}
+
+// requestIDEmbedder is implemented by HTTP-based embedding providers that
+// can tag their requests with a correlation ID.
+type requestIDEmbedder interface {
+	embedWithID(texts []string, id string) ([][]float32, error)
+}
+
+// embedWithID embeds with p, tagging the provider request with id when the
+// provider supports it.
+func embedWithID(p EmbeddingProvider, texts []string, id string) ([][]float32, error) {
+	if e, ok := p.(requestIDEmbedder); ok && id != "" {
+		return e.embedWithID(texts, id)
+	}
+	return p.Embed(texts)
+}
+
+// WithRequestID returns a view of r whose vector store and embedding calls
+// send id in the X-Request-ID header. The view shares the index, manifest
+// and caches with r; stores without HTTP requests ignore the ID.
+func (r *VecRAG) WithRequestID(id string) *VecRAG {
+	if r == nil || id == "" {
+		return r
+	}
+	c := *r
+	c.requestID = id
+	c.vdb = StoreWithRequestID(r.vdb, id)
+	return &c
+}
+
+// StoreWithRequestID returns a view of s that sends id in the X-Request-ID
+// header, or s itself when it makes no HTTP requests.
+func StoreWithRequestID(s VectorStore, id string) VectorStore {
+	if t, ok := s.(requestIDStore); ok && id != "" {
+		return t.withRequestID(id)
+	}
+	return s
+}
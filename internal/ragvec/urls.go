@@ -0,0 +1,432 @@
+package ragvec
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// URLResult reports the outcome for one URL of IngestURLs.
+type URLResult struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Chunks      int    `json:"chunks"`
+	Error       string `json:"error,omitempty"`
+	// Skipped says why a fetched page was not indexed
+	Skipped string `json:"skipped,omitempty"`
+}
+
+var errPrivateAddress = errors.New("address is loopback, private or link-local (set indexing.urls.allow_private to allow)")
+
+// urlClient returns the HTTP client used for page fetches. Unless private
+// addresses are allowed, the dialer refuses them after DNS resolution, which
+// also covers redirects.
+func urlClient(conf cfg.URLFetchConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if !conf.AllowPrivate {
+		// A proxy would be dialed instead of the page's host
+		tr.Proxy = nil
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || privateIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	tr.DialContext = dialer.DialContext
+	return &http.Client{Transport: tr, Timeout: time.Duration(conf.TimeoutSec) * time.Second}
+}
+
+// privateIP reports whether ip is loopback, private, link-local or
+// unspecified, i.e. an address of the server's own network.
+func privateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// IngestURLs fetches each URL (the page itself, no links are followed) and
+// indexes its text. HTML is reduced to readable text; other text types are
+// indexed as they are. Without opts.Project, a page's project is its host.
+// Failures of single URLs are reported in the results; the error is
+// reserved for the vector store or embedding failing.
+func (r *VecRAG) IngestURLs(urls []string, opts IngestOptions) ([]URLResult, int, error) {
+	opts.prepare()
+	defer r.projects.invalidate()
+	client := urlClient(r.config.Indexing.URLs)
+	results := make([]URLResult, 0, len(urls))
+	total := 0
+	for _, raw := range urls {
+		res := URLResult{URL: strings.TrimSpace(raw)}
+		page, err := r.fetchURL(client, &res, nil)
+		if err == nil && page.sitemap {
+			err = fmt.Errorf("sitemap: index it with crawl enabled")
+		}
+		if err == nil {
+			err = r.indexPage(page, &res, opts)
+			total += res.Chunks
+			if err != nil {
+				return append(results, res), total, err
+			}
+		} else {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, total, nil
+}
+
+// CrawlResult summarizes a CrawlSite run.
+type CrawlResult struct {
+	Pages   []URLResult `json:"pages"`
+	Indexed int         `json:"indexed"` // chunks
+	// Blocked counts URLs skipped because robots.txt disallows them
+	Blocked int `json:"blocked"`
+	// Unvisited counts discovered URLs left over when max pages was reached
+	Unvisited int `json:"unvisited"`
+}
+
+// CrawlSite indexes a site breadth-first from seeds, which may be pages or
+// XML sitemaps. Only links on the seeds' origins are followed, robots.txt
+// (including Crawl-delay) and robots meta tags are respected, and at most
+// maxPages URLs are fetched. Pages reached under several URLs are indexed
+// once.
+func (r *VecRAG) CrawlSite(seeds []string, maxPages int, opts IngestOptions) (CrawlResult, error) {
+	opts.prepare()
+	defer r.projects.invalidate()
+	conf := r.config.Indexing.URLs
+	if maxPages <= 0 || maxPages > conf.MaxPages {
+		maxPages = conf.MaxPages
+	}
+	client := urlClient(conf)
+	var out CrawlResult
+	origins := map[string]bool{}
+	seen := map[string]bool{}
+	var queue []string
+	for _, s := range seeds {
+		if u, err := url.Parse(strings.TrimSpace(s)); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			origins[originOf(u)] = true
+		}
+		if key := crawlKey(s); !seen[key] {
+			seen[key] = true
+			queue = append(queue, strings.TrimSpace(s))
+		}
+	}
+	robots := map[string]*robotsRules{}
+	indexed := map[string]bool{} // canonical URLs
+	fetched := 0
+	for len(queue) > 0 && fetched < maxPages {
+		raw := queue[0]
+		queue = queue[1:]
+		res := URLResult{URL: raw}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			res.Error = "not an http(s) URL"
+			out.Pages = append(out.Pages, res)
+			continue
+		}
+		origin := originOf(u)
+		rules, ok := robots[origin]
+		if !ok {
+			rules = r.fetchRobots(client, origin)
+			robots[origin] = rules
+		}
+		if !rules.allowed(u.RequestURI()) {
+			out.Blocked++
+			continue
+		}
+		if fetched > 0 {
+			time.Sleep(max(time.Duration(conf.CrawlDelayMS)*time.Millisecond, min(rules.delay, 30*time.Second)))
+		}
+		fetched++
+		page, err := r.fetchURL(client, &res, origins)
+		if err != nil {
+			res.Error = err.Error()
+			out.Pages = append(out.Pages, res)
+			continue
+		}
+		if !page.nofollow {
+			for _, l := range page.links {
+				lu, err := url.Parse(l)
+				if err != nil || !origins[originOf(lu)] || skipCrawlExt(lu.Path) {
+					continue
+				}
+				if key := crawlKey(l); !seen[key] {
+					seen[key] = true
+					queue = append(queue, l)
+				}
+			}
+		}
+		canonical := page.meta["url"].(string)
+		switch {
+		case page.sitemap:
+			res.Skipped = "sitemap"
+		case page.noindex:
+			res.Skipped = "noindex"
+		case indexed[canonical]:
+			res.Skipped = "duplicate of " + canonical
+		default:
+			indexed[canonical] = true
+			err = r.indexPage(page, &res, opts)
+			out.Indexed += res.Chunks
+		}
+		out.Pages = append(out.Pages, res)
+		if err != nil {
+			return out, err
+		}
+	}
+	out.Unvisited = len(queue)
+	return out, nil
+}
+
+// fetchRobots loads the robots.txt rules of origin. A missing file allows
+// everything; a server error disallows everything, as crawlers conventionally
+// do.
+func (r *VecRAG) fetchRobots(client *http.Client, origin string) *robotsRules {
+	req, err := http.NewRequest("GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return &robotsRules{}
+	}
+	req.Header.Set("User-Agent", userAgent(r.config))
+	resp, err := client.Do(req)
+	if err != nil {
+		return &robotsRules{}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return parseRobots("User-agent: *\nDisallow: /", crawlerAgent)
+	case resp.StatusCode >= 300:
+		return &robotsRules{}
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512*1024))
+	return parseRobots(string(body), crawlerAgent)
+}
+
+// crawlerAgent is the product token matched against robots.txt User-agent
+// lines.
+const crawlerAgent = "mcp-rag-service"
+
+func userAgent(config *cfg.Config) string {
+	return crawlerAgent + "/" + config.Server.Version
+}
+
+func originOf(u *url.URL) string {
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host)
+}
+
+// canonicalInScope reports whether a page fetched from final may be
+// indexed under its canonical URL c.
+func canonicalInScope(c string, final *url.URL, origins map[string]bool) bool {
+	cu, err := url.Parse(c)
+	if err != nil || originOf(cu) != originOf(final) {
+		return false
+	}
+	return origins == nil || origins[originOf(cu)]
+}
+
+// crawlKey identifies a URL for de-duplication: without fragment, with
+// lowercased scheme and host.
+func crawlKey(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+	u.Fragment, u.RawFragment = "", ""
+	u.Scheme, u.Host = strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+	return u.String()
+}
+
+// skipCrawlExt reports links to assets that are never text pages.
+func skipCrawlExt(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".ico", ".css", ".js", ".mjs", ".map",
+		".pdf", ".zip", ".gz", ".tgz", ".tar", ".mp3", ".mp4", ".webm", ".woff", ".woff2", ".ttf", ".eot":
+		return true
+	}
+	return false
+}
+
+// fetchedPage is a downloaded page ready for indexing.
+type fetchedPage struct {
+	file  chunker.File
+	meta  map[string]any // url (canonical) and title payload
+	links []string       // absolute, for crawling
+	// sitemap pages only contribute links; noindex/nofollow come from the
+	// robots meta tag
+	sitemap, noindex, nofollow bool
+}
+
+// indexPage replaces the chunks of the page's URL with fresh ones.
+func (r *VecRAG) indexPage(page *fetchedPage, res *URLResult, opts IngestOptions) error {
+	opts.meta = page.meta
+	if opts.Project == "" {
+		u, _ := url.Parse(page.file.Path)
+		opts.Project = u.Hostname()
+	}
+	n, err := r.replaceFile(page.file, opts)
+	res.Chunks += n
+	return err
+}
+
+// replaceFile chunks one document and replaces the chunks stored under its
+// path, returning how many were written.
+func (r *VecRAG) replaceFile(f chunker.File, opts IngestOptions) (int, error) {
+	one := []chunker.File{f}
+	raw := append(chunker.ChunkFiles(one, r.config.Indexing.ChunkSize, r.config.Indexing.ChunkOverlap, r.config), r.summaryChunks(one)...)
+	cs := r.prepareFile(f.Text, append(raw, r.tocChunks(one)...))
+	if _, err := r.deleteWhere(matchFilter(map[string]string{"path": f.Path})); err != nil {
+		return 0, err
+	}
+	written := 0
+	batchSize := r.config.Indexing.BatchSize
+	for start := 0; start < len(cs); start += batchSize {
+		if err := opts.canceled(); err != nil {
+			return written, err
+		}
+		batch := cs[start:min(start+batchSize, len(cs))]
+		n, err := r.upsertChunks(batch, opts)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// IngestText indexes one document given as text under path, which need not
+// exist on disk (e.g. virtual://notes.md); its extension picks the file
+// type and chunking strategy. Earlier chunks stored under path are
+// replaced. Without opts.Project the project derives from path like for
+// files on disk.
+func (r *VecRAG) IngestText(path, text string, opts IngestOptions) (int, error) {
+	opts.prepare()
+	defer r.projects.invalidate()
+	return r.replaceFile(chunker.File{Path: path, Text: text, ModTime: time.Now()}, opts)
+}
+
+var sitemapLocRE = regexp.MustCompile(`(?is)<loc>\s*(.*?)\s*</loc>`)
+
+// fetchURL downloads res.URL and converts it to a document, filling in the
+// title and content type of res. The document's path is the page's
+// canonical URL, or else its URL after redirects. A canonical URL is only
+// trusted on the page's own origin and, when crawling, on one of the
+// crawled origins; a page must not replace the chunks of another site's.
+func (r *VecRAG) fetchURL(client *http.Client, res *URLResult, origins map[string]bool) (*fetchedPage, error) {
+	u, err := url.Parse(res.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("not an http(s) URL")
+	}
+	u.Fragment, u.RawFragment = "", ""
+	res.URL = u.String()
+	req, err := http.NewRequest("GET", res.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent(r.config))
+	req.Header.Set("Accept", "text/html, text/markdown;q=0.9, text/plain;q=0.8, application/xml;q=0.5")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("http %d", resp.StatusCode)
+	}
+	var rd io.Reader = resp.Body
+	maxBytes := int64(r.config.Indexing.MaxFileKB) * 1024
+	if maxBytes > 0 {
+		rd = io.LimitReader(resp.Body, maxBytes+1)
+	}
+	body, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("page larger than indexing.max_file_kb (%d KB)", r.config.Indexing.MaxFileKB)
+	}
+
+	final := resp.Request.URL
+	final.Fragment, final.RawFragment = "", ""
+	page := &fetchedPage{file: chunker.File{Path: final.String()}, meta: map[string]any{"url": final.String()}}
+	resolve := func(href string) string {
+		ref, err := url.Parse(href)
+		if err != nil {
+			return ""
+		}
+		abs := final.ResolveReference(ref)
+		if abs.Scheme != "http" && abs.Scheme != "https" {
+			return ""
+		}
+		abs.Fragment, abs.RawFragment = "", ""
+		return abs.String()
+	}
+
+	// Trust the declared type unless it is missing or generic
+	ctype, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if ctype == "" || ctype == "application/octet-stream" {
+		ctype, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	res.ContentType = ctype
+	text := string(body)
+	isXML := ctype == "application/xml" || ctype == "text/xml"
+	switch {
+	case ctype == "text/html" || ctype == "application/xhtml+xml":
+		p := chunker.ParseHTML(text)
+		res.Title, text = p.Title, p.Text
+		if res.Title != "" && !strings.HasPrefix(text, "# ") {
+			text = "# " + res.Title + "\n\n" + text
+		}
+		// Index under the canonical URL so aliases of a page replace
+		// each other
+		if c := resolve(p.Canonical); c != "" && canonicalInScope(c, final, origins) {
+			page.file.Path, page.meta["url"] = c, c
+		}
+		if res.Title != "" {
+			page.meta["title"] = res.Title
+		}
+		for _, l := range p.Links {
+			if abs := resolve(l); abs != "" {
+				page.links = append(page.links, abs)
+			}
+		}
+		page.noindex, page.nofollow = p.NoIndex, p.NoFollow
+	case isXML && (strings.Contains(text, "<urlset") || strings.Contains(text, "<sitemapindex")):
+		for _, m := range sitemapLocRE.FindAllStringSubmatch(text, -1) {
+			if abs := resolve(html.UnescapeString(m[1])); abs != "" {
+				page.links = append(page.links, abs)
+			}
+		}
+		page.sitemap = true
+		return page, nil
+	case strings.HasPrefix(ctype, "text/"):
+	default:
+		return nil, fmt.Errorf("unsupported content type %s", ctype)
+	}
+	if strings.TrimSpace(text) == "" && !page.noindex {
+		return nil, fmt.Errorf("no text content")
+	}
+	page.file.Text = text
+	if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		page.file.ModTime = lm
+	}
+	return page, nil
+}
@@ -1,20 +1,27 @@
 package ragvec
 
 import (
-	"bytes"
+	"bufio"
+	"context"
+	"crypto/sha1"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"text/template"
 	"time"
-	"crypto/rand"
+	"unicode/utf8"
 
 	"github.com/Rhyanz46/mcp-service/internal/chunker"
 	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/secrets"
+	"github.com/Rhyanz46/mcp-service/internal/stopwords"
 )
 
 const (
@@ -32,13 +39,17 @@ type OpenAIProvider struct {
 	apiKey string
 	model  string
 	dim    int
+	limits rateLimitInfo
+	// maxRequestBytes bounds the JSON body of one request; 0 = no bound
+	maxRequestBytes int
 }
 
 func NewOpenAIProviderWithConfig(config *cfg.OpenAIConfig) *OpenAIProvider {
 	return &OpenAIProvider{
-		apiKey: config.APIKey,
-		model:  config.Model,
-		dim:    config.Dim,
+		apiKey:          config.APIKey,
+		model:           config.Model,
+		dim:             config.Dim,
+		maxRequestBytes: config.MaxRequestKB * 1024,
 	}
 }
 
@@ -56,15 +67,66 @@ func NewOpenAIProvider() *OpenAIProvider {
 
 func (p *OpenAIProvider) Dim() int { return p.dim }
 
+// RateLimits returns the x-ratelimit-* headers of the last API response.
+func (p *OpenAIProvider) RateLimits() map[string]any { return p.limits.snapshot() }
+
+// HealthCheck performs a tiny embedding call to verify the API is usable.
+func (p *OpenAIProvider) HealthCheck() error {
+	_, err := p.Embed([]string{"ping"})
+	return err
+}
+
 func (p *OpenAIProvider) Embed(texts []string) ([][]float32, error) {
-	type reqT struct {
-		Model string   `json:"model"`
-		Input []string `json:"input"`
+	return p.embedWithID(texts, "")
+}
+
+// openaiMaxInputs is the most inputs the embeddings API accepts at once.
+const openaiMaxInputs = 2048
+
+// embedWithID splits texts into requests that stay under maxRequestBytes
+// and the API's input limit; a single text larger than the bound is sent
+// on its own.
+func (p *OpenAIProvider) embedWithID(texts []string, id string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); {
+		end, size := start, 0
+		for end < len(texts) && end-start < openaiMaxInputs {
+			n := jsonStringLen(texts[end]) + 2 // separator and newline
+			if end > start && p.maxRequestBytes > 0 && size+n > p.maxRequestBytes {
+				break
+			}
+			size += n
+			end++
+		}
+		vecs, err := p.embedRequest(texts[start:end], id)
+		if err != nil {
+			return nil, err
+		}
+		if len(vecs) != end-start {
+			return nil, fmt.Errorf("openai embeddings returned %d vectors for %d inputs", len(vecs), end-start)
+		}
+		out = append(out, vecs...)
+		start = end
 	}
-	body, _ := json.Marshal(reqT{Model: p.model, Input: texts})
-	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/embeddings", bytes.NewReader(body))
+	return out, nil
+}
+
+// embedRequest embeds texts in one API call. The request body is encoded
+// into the connection as it is sent, one input at a time, instead of being
+// built in memory first.
+func (p *OpenAIProvider) embedRequest(texts []string, id string) ([][]float32, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeEmbedRequest(pw, p.model, texts))
+	}()
+	// Closing the reader stops the writer if the request fails early
+	defer pr.Close()
+	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/embeddings", pr)
 	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	req.Header.Set("Content-Type", "application/json")
+	if id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
 
 	client := &http.Client{Timeout: 30 * time.Second}
 	res, err := client.Do(req)
@@ -72,11 +134,13 @@ func (p *OpenAIProvider) Embed(texts []string) ([][]float32, error) {
 		return nil, err
 	}
 	defer res.Body.Close()
+	p.limits.capture(res)
 	if res.StatusCode >= 300 {
 		return nil, fmt.Errorf("openai embeddings http %d", res.StatusCode)
 	}
 	var rr struct {
 		Data []struct {
+			Index     int       `json:"index"`
 			Embedding []float32 `json:"embedding"`
 		} `json:"data"`
 	}
@@ -85,308 +149,193 @@ func (p *OpenAIProvider) Embed(texts []string) ([][]float32, error) {
 	}
 	out := make([][]float32, len(rr.Data))
 	for i, d := range rr.Data {
-		out[i] = d.Embedding
+		k := i
+		if d.Index >= 0 && d.Index < len(out) {
+			k = d.Index
+		}
+		out[k] = d.Embedding
 	}
 	return out, nil
 }
 
-// ---------- Qdrant minimal client ----------
-type Qdrant struct {
-	baseURL    string
-	collection string
-	dim        int
-}
-
-func NewQdrantWithConfig(config *cfg.QdrantConfig, dim int) *Qdrant {
-	return &Qdrant{
-		baseURL:    strings.TrimRight(config.URL, "/"),
-		collection: config.Collection,
-		dim:        dim,
+// writeEmbedRequest encodes {"model": model, "input": texts} to w.
+func writeEmbedRequest(w io.Writer, model string, texts []string) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	bw.WriteString(`{"model":`)
+	if err := enc.Encode(model); err != nil {
+		return err
 	}
+	bw.WriteString(`,"input":[`)
+	for i, t := range texts {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		if err := enc.Encode(t); err != nil {
+			return err
+		}
+	}
+	bw.WriteString("]}")
+	return bw.Flush()
 }
 
-func NewQdrant(dim int) *Qdrant {
-	u := os.Getenv("QDRANT_URL")
-	if u == "" {
-		u = "http://localhost:6333"
-	}
-	coll := os.Getenv("QDRANT_COLLECTION")
-	if coll == "" {
-		coll = DefaultCollection
+// jsonStringLen is the length of s encoded as a JSON string by
+// encoding/json, which escapes control characters, <, >, &, U+2028, U+2029
+// and invalid UTF-8.
+func jsonStringLen(s string) int {
+	n := 2
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\' || c == '\n' || c == '\r' || c == '\t':
+				n += 2
+			case c < 0x20 || c == '<' || c == '>' || c == '&':
+				n += 6
+			default:
+				n++
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if (r == utf8.RuneError && size == 1) || r == '\u2028' || r == '\u2029' {
+			n += 6
+		} else {
+			n += size
+		}
+		i += size
 	}
-	return &Qdrant{baseURL: strings.TrimRight(u, "/"), collection: coll, dim: dim}
+	return n
 }
 
-func (q *Qdrant) EnsureCollection() error {
-	// PUT /collections/{name}
-	url := fmt.Sprintf("%s/collections/%s", q.baseURL, q.collection)
-	body := map[string]any{
-		"vectors": map[string]any{
-			"size":     q.dim,
-			"distance": "Cosine",
-		},
-	}
-	b, _ := json.Marshal(body)
-	req, _ := http.NewRequest("PUT", url, bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 10 * time.Second}
-	res, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	if res.StatusCode >= 300 && res.StatusCode != 409 { // 409 = already exists (ok)
-		return fmt.Errorf("ensure collection http %d", res.StatusCode)
-	}
-	return nil
+// ---------- Scrolling and project listing ----------
+
+// ListProjects aggregates indexed chunks by project (directory name of each file).
+// It uses Qdrant's facet API when available and falls back to a full scroll.
+func (r *VecRAG) ListProjects() ([]map[string]any, error) {
+	return r.projects.get(func() ([]map[string]any, error) { return r.aggregateProjects(nil) })
 }
 
-// HealthCheck verifies Qdrant is reachable by querying /collections
-func (q *Qdrant) HealthCheck() error {
-	url := fmt.Sprintf("%s/collections", q.baseURL)
-	client := &http.Client{Timeout: 5 * time.Second}
-	res, err := client.Get(url)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	if res.StatusCode >= 300 {
-		return fmt.Errorf("health http %d", res.StatusCode)
+// ListProjectsFor is ListProjects counting only the chunks carrying one of
+// the access labels acl, e.g. a tenant's; nil acl counts every chunk.
+// Listings for labels are not cached.
+func (r *VecRAG) ListProjectsFor(acl []string) ([]map[string]any, error) {
+	if acl == nil {
+		return r.ListProjects()
 	}
-	return nil
+	return r.aggregateProjects(aclMust(acl))
 }
 
-// CountPoints returns the number of points in the current collection
-func (q *Qdrant) CountPoints() (int, error) {
-	url := fmt.Sprintf("%s/collections/%s/points/count", q.baseURL, q.collection)
-	body := map[string]any{"exact": true}
-	b, _ := json.Marshal(body)
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 10 * time.Second}
-	res, err := client.Do(req)
-	if err != nil {
-		return 0, err
+// aggregateProjects counts the chunks and files of each project among the
+// points matching must.
+func (r *VecRAG) aggregateProjects(must []map[string]any) ([]map[string]any, error) {
+	list, err := facetProjects(r.vdb, must)
+	if err == nil {
+		return list, nil
 	}
-	defer res.Body.Close()
-	if res.StatusCode >= 300 {
-		return 0, fmt.Errorf("count http %d", res.StatusCode)
+	if r.config.Logging.Level == "debug" {
+		fmt.Fprintf(os.Stderr, "[MCP-RAG] Facet aggregation unavailable, scrolling: %v\n", err)
 	}
-	var rr struct {
-		Result struct {
-			Count int `json:"count"`
-		} `json:"result"`
-	}
-	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
-		return 0, err
-	}
-	return rr.Result.Count, nil
-}
-
-func (q *Qdrant) UpsertPoints(ids []string, vecs [][]float32, payloads []map[string]any) error {
-    if len(ids) != len(vecs) || len(ids) != len(payloads) {
-        return errors.New("mismatch len")
-    }
-    points := make([]map[string]any, 0, len(ids))
-    for i := range ids {
-        points = append(points, map[string]any{
-            "id":      ids[i],
-            "vector":  vecs[i],
-            "payload": payloads[i],
-        })
-    }
-    body := map[string]any{"points": points}
-	b, _ := json.Marshal(body)
-	url := fmt.Sprintf("%s/collections/%s/points?wait=true", q.baseURL, q.collection)
-	req, _ := http.NewRequest("PUT", url, bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 30 * time.Second}
-	res, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	if res.StatusCode >= 300 {
-		return fmt.Errorf("upsert http %d", res.StatusCode)
-	}
-	return nil
+	return r.scrollProjects(must)
 }
 
-type SearchHit struct {
-	ID      any            `json:"id"`
-	Score   float32        `json:"score"`
-	Payload map[string]any `json:"payload"`
+// facetLimit bounds the distinct paths requested from a single facet call;
+// a full page means the answer may be incomplete.
+const facetLimit = 100000
+
+// FacetProjects aggregates per-project chunk and file counts from facets
+// over payload.project and payload.path, costing one request per distinct
+// file instead of a scan over every point. Projects whose chunk count
+// differs from what their paths imply (renamed or merged ones) get their
+// file count from an extra facet filtered by project. Only Qdrant has a
+// facet API; other stores return ErrFacetUnsupported.
+func FacetProjects(s VectorStore) ([]map[string]any, error) {
+	return facetProjects(s, nil)
 }
 
-func (q *Qdrant) Search(vec []float32, k int, filter map[string]any) ([]SearchHit, error) {
-	body := map[string]any{
-		"vector": vec,
-		"limit":  k,
+func facetProjects(s VectorStore, must []map[string]any) ([]map[string]any, error) {
+	if e, ok := s.(*encryptedStore); ok {
+		// project and path are stored in clear
+		s = e.VectorStore
 	}
-	if filter != nil {
-		body["filter"] = filter
+	q, ok := s.(*Qdrant)
+	if !ok {
+		return nil, ErrFacetUnsupported
 	}
-	b, _ := json.Marshal(body)
-	url := fmt.Sprintf("%s/collections/%s/points/search", q.baseURL, q.collection)
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 15 * time.Second}
-	res, err := client.Do(req)
+	base := liveFilter(nil)
+	if len(must) > 0 {
+		base = liveFilter(map[string]any{"must": must})
+	}
+	projects, err := q.Facet("project", facetLimit, base)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
-	if res.StatusCode >= 300 {
-		return nil, fmt.Errorf("search http %d", res.StatusCode)
+	hits, err := q.Facet("path", facetLimit, base)
+	if err != nil {
+		return nil, err
 	}
-
-	var rr struct {
-		Result []struct {
-			ID      any            `json:"id"`
-			Score   float32        `json:"score"`
-			Payload map[string]any `json:"payload"`
-		} `json:"result"`
+	if len(hits) >= facetLimit || len(projects) >= facetLimit {
+		return nil, fmt.Errorf("facet truncated at %d values", facetLimit)
 	}
-	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
-		return nil, err
+	byPath := map[string]int{}
+	files := map[string]int{}
+	for _, h := range hits {
+		project := projectFromPath(toStr(h.Value))
+		byPath[project] += h.Count
+		files[project]++
 	}
-	out := make([]SearchHit, len(rr.Result))
-	for i, v := range rr.Result {
-		out[i] = SearchHit{ID: v.ID, Score: v.Score, Payload: v.Payload}
+	counts := map[string]int{}
+	for _, h := range projects {
+		proj := toStr(h.Value)
+		counts[proj] = h.Count
+		if byPath[proj] == h.Count {
+			continue
+		}
+		filter := map[string]any{
+			"must": append([]map[string]any{
+				{"key": "project", "match": map[string]any{"value": proj}},
+			}, must...),
+		}
+		ph, err := q.Facet("path", facetLimit, liveFilter(filter))
+		if err != nil {
+			return nil, err
+		}
+		files[proj] = len(ph)
 	}
+	out := make([]map[string]any, 0, len(counts))
+	for proj, n := range counts {
+		out = append(out, map[string]any{
+			"project":      proj,
+			"total_chunks": n,
+			"files":        files[proj],
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return fmt.Sprint(out[i]["project"]) < fmt.Sprint(out[j]["project"]) })
 	return out, nil
 }
 
-// DeleteByIDs deletes points by explicit list of IDs (UUIDs or integers)
-func (q *Qdrant) DeleteByIDs(ids []any) error {
-    body := map[string]any{"points": ids}
-    b, _ := json.Marshal(body)
-    url := fmt.Sprintf("%s/collections/%s/points/delete?wait=true", q.baseURL, q.collection)
-    req, _ := http.NewRequest("POST", url, bytes.NewReader(b))
-    req.Header.Set("Content-Type", "application/json")
-    client := &http.Client{Timeout: 30 * time.Second}
-    res, err := client.Do(req)
-    if err != nil {
-        return err
-    }
-    defer res.Body.Close()
-    if res.StatusCode >= 300 {
-        return fmt.Errorf("delete http %d", res.StatusCode)
-    }
-    return nil
-}
-
-// ---------- Scrolling and project listing ----------
-type ScrollPoint struct {
-	ID      any            `json:"id"`
-	Payload map[string]any `json:"payload"`
-}
-
-func (q *Qdrant) ScrollPoints(limit int, offset any) ([]ScrollPoint, any, error) {
-	if limit <= 0 || limit > 10000 {
-		limit = 1000
-	}
-	body := map[string]any{
-		"limit":        limit,
-		"with_payload": true,
-	}
-	if offset != nil {
-		body["offset"] = offset
-	}
-	b, _ := json.Marshal(body)
-	url := fmt.Sprintf("%s/collections/%s/points/scroll", q.baseURL, q.collection)
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 15 * time.Second}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer res.Body.Close()
-	if res.StatusCode >= 300 {
-		return nil, nil, fmt.Errorf("scroll http %d", res.StatusCode)
-	}
-	var rr struct {
-		Result struct {
-			Points []struct {
-				ID      any            `json:"id"`
-				Payload map[string]any `json:"payload"`
-			} `json:"points"`
-			NextPageOffset any `json:"next_page_offset"`
-		} `json:"result"`
-	}
-	if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
-		return nil, nil, err
-	}
-	pts := make([]ScrollPoint, len(rr.Result.Points))
-	for i, p := range rr.Result.Points {
-		pts[i] = ScrollPoint{ID: p.ID, Payload: p.Payload}
-	}
-	return pts, rr.Result.NextPageOffset, nil
-}
-
-// ScrollPointsWithFilter supports server-side filtering when scrolling
-func (q *Qdrant) ScrollPointsWithFilter(limit int, offset any, filter map[string]any) ([]ScrollPoint, any, error) {
-    if limit <= 0 || limit > 10000 {
-        limit = 1000
-    }
-    body := map[string]any{
-        "limit":        limit,
-        "with_payload": true,
-    }
-    if offset != nil {
-        body["offset"] = offset
-    }
-    if filter != nil {
-        body["filter"] = filter
-    }
-    b, _ := json.Marshal(body)
-    url := fmt.Sprintf("%s/collections/%s/points/scroll", q.baseURL, q.collection)
-    req, _ := http.NewRequest("POST", url, bytes.NewReader(b))
-    req.Header.Set("Content-Type", "application/json")
-    client := &http.Client{Timeout: 15 * time.Second}
-    res, err := client.Do(req)
-    if err != nil {
-        return nil, nil, err
-    }
-    defer res.Body.Close()
-    if res.StatusCode >= 300 {
-        return nil, nil, fmt.Errorf("scroll http %d", res.StatusCode)
-    }
-    var rr struct {
-        Result struct {
-            Points         []struct {
-                ID      any            `json:"id"`
-                Payload map[string]any `json:"payload"`
-            } `json:"points"`
-            NextPageOffset any `json:"next_page_offset"`
-        } `json:"result"`
-    }
-    if err := json.NewDecoder(res.Body).Decode(&rr); err != nil {
-        return nil, nil, err
-    }
-    pts := make([]ScrollPoint, len(rr.Result.Points))
-    for i, p := range rr.Result.Points {
-        pts[i] = ScrollPoint{ID: p.ID, Payload: p.Payload}
-    }
-    return pts, rr.Result.NextPageOffset, nil
-}
-
-// ListProjects aggregates indexed chunks by project (directory name of each file)
-func (r *VecRAG) ListProjects() ([]map[string]any, error) {
-	// Scroll through all points and group by project name derived from payload.path
+func (r *VecRAG) scrollProjects(must []map[string]any) ([]map[string]any, error) {
+	// Scroll through all points and group by payload.project (derived from
+	// payload.path for points that lack it)
 	counts := map[string]int{}
 	files := map[string]map[string]struct{}{}
+	filter := liveFilter(nil)
+	if len(must) > 0 {
+		filter = liveFilter(map[string]any{"must": must})
+	}
 	var offset any
 	for {
-		pts, next, err := r.vdb.ScrollPoints(1000, offset)
+		pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offset, filter)
 		if err != nil {
 			return nil, err
 		}
 		for _, pt := range pts {
 			p := pt.Payload
-			pathVal := toStr(p["path"])
-			project := projectFromPath(pathVal)
+			project, _ := p["project"].(string)
+			if project == "" {
+				project = projectFromPath(toStr(p["path"]))
+			}
 			counts[project]++
 			if files[project] == nil {
 				files[project] = map[string]struct{}{}
@@ -415,7 +364,8 @@ func projectFromPath(p string) string {
 	if p == "" {
 		return "unknown"
 	}
-	dir := filepath.Dir(p)
+	// Files inside an archive belong to the archive's directory
+	dir := filepath.Dir(chunker.DiskPath(p))
 	if dir == "." || dir == "/" {
 		return "root"
 	}
@@ -423,15 +373,17 @@ func projectFromPath(p string) string {
 }
 
 // ListProjectsFiltered filters by name prefix and paginates results after aggregation.
+// acl limits the listing as in ListProjectsFor.
 // Note: This scans the whole collection to aggregate per-project counts.
-func (r *VecRAG) ListProjectsFiltered(prefix string, offset, limit int) ([]map[string]any, int, error) {
-	list, err := r.ListProjects()
+func (r *VecRAG) ListProjectsFiltered(prefix string, offset, limit int, acl []string) ([]map[string]any, int, error) {
+	list, err := r.ListProjectsFor(acl)
 	if err != nil {
 		return nil, 0, err
 	}
 	// filter by prefix (case-insensitive)
 	fprefix := strings.ToLower(strings.TrimSpace(prefix))
-	filtered := list[:0]
+	// list may be the cached one, so it is not filtered in place
+	var filtered []map[string]any
 	if fprefix == "" {
 		filtered = list
 	} else {
@@ -463,35 +415,191 @@ func (r *VecRAG) ListProjectsFiltered(prefix string, offset, limit int) ([]map[s
 
 // ---------- RAG ops ----------
 type VecRAG struct {
-	embed  EmbeddingProvider
-	vdb    *Qdrant
-	config *cfg.Config
+	embed    EmbeddingProvider
+	vdb      VectorStore
+	config   *cfg.Config
+	manifest *manifestStore
+	// chunks is nil unless indexing.chunk_cache is enabled
+	chunks *chunkCache
+	// summarizer is nil unless indexing.summaries is enabled
+	summarizer Summarizer
+	projects   *projectCache
+	// vocab backs the did-you-mean terms of Suggest
+	vocab *vocabCache
+	// results caches search results; see search.cache
+	results *resultCache
+	// reranker rescores search candidates; see search.reranker
+	reranker Reranker
+	// ocr reads the text of images; nil unless indexing.images.ocr.url
+	// is set
+	ocr *ocrClient
+	// header renders the context line embedded with each chunk; nil when
+	// indexing.context_headers is off
+	header *template.Template
+	stats  *embedStats
+	// secrets finds credentials in chunks; see indexing.secrets
+	secrets *secrets.Scanner
+	// filters run on every chunk before it is embedded
+	filters []ContentFilter
+	// requestID tags store and provider calls; see WithRequestID
+	requestID string
 }
 
 func NewVecRAGWithConfig(config *cfg.Config) (*VecRAG, error) {
+	return newVecRAG(config, true)
+}
+
+// OpenVecRAG attaches to an existing collection without creating or tuning
+// it, e.g. an alternative index searched for comparison.
+func OpenVecRAG(config *cfg.Config) (*VecRAG, error) {
+	return newVecRAG(config, false)
+}
+
+func newVecRAG(config *cfg.Config, create bool) (*VecRAG, error) {
 	// Create embedding provider based on config
-	var prov EmbeddingProvider
+	prov, err := newProvider(config.Embedding.Provider, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Embedding.Fallback) > 0 {
+		names := []string{config.Embedding.Provider}
+		provs := []EmbeddingProvider{prov}
+		for _, name := range config.Embedding.Fallback {
+			fb, err := newProvider(name, config)
+			if err != nil {
+				return nil, fmt.Errorf("fallback provider %s: %w", name, err)
+			}
+			names = append(names, name)
+			provs = append(provs, fb)
+		}
+		chain, err := NewFailoverProvider(names, provs)
+		if err != nil {
+			return nil, err
+		}
+		chain.Probe()
+		fmt.Fprintf(os.Stderr, "[MCP-RAG] Embedding failover chain: %s (active: %s)\n", strings.Join(names, " -> "), chain.Active())
+		prov = chain
+	} else if hc, ok := prov.(HealthChecker); ok {
+		if err := hc.HealthCheck(); err != nil {
+			fmt.Fprintf(os.Stderr, "[MCP-RAG] Warning: embedding provider %s health check failed: %v\n", config.Embedding.Provider, err)
+		}
+	}
+
+	q := NewStore(config, prov.Dim())
+	if create {
+		if err := q.EnsureCollection(); err != nil {
+			return nil, fmt.Errorf("failed to connect to the vector store or create collection: %w (ensure %s is running on %s)", err, config.Store.Backend, config.StoreURL())
+		}
+	} else if ok, err := q.CollectionExists(); err != nil {
+		return nil, fmt.Errorf("failed to connect to the vector store: %w (ensure %s is running on %s)", err, config.Store.Backend, config.StoreURL())
+	} else if !ok {
+		return nil, fmt.Errorf("collection %s does not exist", config.CollectionName())
+	}
 
-	switch config.Embedding.Provider {
+	scanner, err := secrets.New(config.Indexing.Secrets.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("indexing secrets: %w", err)
+	}
+	filters, err := newContentFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	header, err := newContextHeader(config)
+	if err != nil {
+		return nil, err
+	}
+	manifest := newManifestStore(config.Indexing.ManifestPath, config.CollectionName())
+	r := &VecRAG{embed: prov, vdb: q, config: config, manifest: manifest, chunks: newChunkCache(config, filters), summarizer: newSummarizer(config),
+		projects: newProjectCache(time.Duration(config.Qdrant.ProjectsCacheSec) * time.Second),
+		vocab:    &vocabCache{},
+		results:  newResultCache(time.Duration(config.Search.Cache.TTLSec)*time.Second, config.Search.Cache.MaxEntries),
+		reranker: newReranker(config),
+		ocr:      newOCRClient(config),
+		header:   header,
+		stats:    &embedStats{}, secrets: scanner, filters: filters}
+	if create {
+		r.startExpirySweeper(time.Duration(config.Indexing.TTLSweepIntervalSec) * time.Second)
+	}
+	// Probing starts last so that a failed constructor leaves no goroutine
+	if chain, ok := prov.(*FailoverProvider); ok {
+		chain.StartProbing(time.Duration(config.Embedding.HealthCheckIntervalSec) * time.Second)
+	}
+	return r, nil
+}
+
+// newProvider creates the embedding provider registered under name.
+func newProvider(name string, config *cfg.Config) (EmbeddingProvider, error) {
+	switch name {
 	case "openai":
 		if config.Embedding.OpenAI.APIKey == "" {
 			return nil, fmt.Errorf("OpenAI API key is required when using OpenAI provider")
 		}
-		prov = NewOpenAIProviderWithConfig(&config.Embedding.OpenAI)
 		fmt.Fprintf(os.Stderr, "[MCP-RAG] Using OpenAI embeddings\n")
+		return NewOpenAIProviderWithConfig(&config.Embedding.OpenAI), nil
 	case "local":
-		prov = NewLocalEmbeddingProviderWithConfig(&config.Embedding.Local)
+		if config.Embedding.Local.Engine == "onnx" {
+			fmt.Fprintf(os.Stderr, "[MCP-RAG] Using local ONNX embeddings (%s)\n", config.Embedding.Local.ONNX.ModelPath)
+			p, err := NewONNXProviderWithConfig(&config.Embedding.Local)
+			if err != nil {
+				return nil, err
+			}
+			return p, nil
+		}
 		fmt.Fprintf(os.Stderr, "[MCP-RAG] Using local TF-IDF embeddings (no external API required)\n")
+		p := NewLocalEmbeddingProviderWithConfig(&config.Embedding.Local)
+		p.SetStopwords(stopwords.Load(config.Language.Stopwords, config.Language.ExtraStopwords, config.Language.DisableStopwords))
+		return p, nil
 	default:
-		return nil, fmt.Errorf("unsupported embedding provider: %s", config.Embedding.Provider)
+		return nil, fmt.Errorf("unsupported embedding provider: %s", name)
 	}
+}
 
-	q := NewQdrantWithConfig(&config.Qdrant, prov.Dim())
-	if err := q.EnsureCollection(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Qdrant or create collection: %w (ensure Qdrant is running on %s)", err, q.baseURL)
+// EmbeddingStatus reports the active embedding provider and model, recent
+// call statistics, the last rate-limit headers seen from OpenAI and, when a
+// failover chain is configured, the health of every provider in it.
+func (r *VecRAG) EmbeddingStatus() map[string]any {
+	stats := r.stats.snapshot()
+	if _, ok := stats["last_error"]; ok && r.config.Logging.RedactErrors {
+		stats["last_error"] = "redacted"
 	}
+	out := map[string]any{
+		"active": r.config.Embedding.Provider,
+		"stats":  stats,
+	}
+	limits := map[string]any{}
+	if chain, ok := r.embed.(*FailoverProvider); ok {
+		out["active"] = chain.Active()
+		out["providers"] = chain.Status()
+		for _, e := range chain.entries {
+			if rl, ok := e.prov.(RateLimitReporter); ok {
+				if snap := rl.RateLimits(); snap != nil {
+					limits[e.name] = snap
+				}
+			}
+		}
+	} else if rl, ok := r.embed.(RateLimitReporter); ok {
+		if snap := rl.RateLimits(); snap != nil {
+			limits[r.config.Embedding.Provider] = snap
+		}
+	}
+	out["model"] = modelFor(toStr(out["active"]), r.config)
+	if len(limits) > 0 {
+		out["rate_limits"] = limits
+	}
+	return out
+}
 
-	return &VecRAG{embed: prov, vdb: q, config: config}, nil
+// ProbeEmbedding embeds a tiny input through the live provider chain and
+// reports its latency. Probes are not counted in the call statistics.
+func (r *VecRAG) ProbeEmbedding() (map[string]any, error) {
+	start := time.Now()
+	_, stamp, err := r.embedRaw([]string{"ping"})
+	return map[string]any{
+		"provider":   stamp.Provider,
+		"model":      stamp.Model,
+		"latency_ms": time.Since(start).Milliseconds(),
+		"ok":         err == nil,
+	}, err
 }
 
 func NewVecRAG() (*VecRAG, error) {
@@ -499,137 +607,637 @@ func NewVecRAG() (*VecRAG, error) {
 	return NewVecRAGWithConfig(cfg.DefaultConfig())
 }
 
+// ingestQueueBatches bounds how many batches of chunks may wait for
+// embedding while the walker reads ahead.
+const ingestQueueBatches = 2
+
+var errIngestAborted = errors.New("ingestion aborted")
+
+// IngestDocs streams files → chunks → batches: a walker goroutine reads and
+// chunks one file at a time into a bounded channel while batches are embedded
+// and upserted, so memory stays flat regardless of corpus size. Batches
+// already upserted stay indexed if a later file or batch fails.
 func (r *VecRAG) IngestDocs(dir string, includeCode bool) (int, error) {
-	chunks, err := chunker.MakeChunks(dir, r.config.Indexing.ChunkSize, r.config.Indexing.ChunkOverlap, includeCode, r.config)
+	return r.IngestDocsWith(dir, includeCode, IngestOptions{})
+}
+
+// IngestOptions adds labels to every chunk of one ingestion run.
+type IngestOptions struct {
+	Tags    []string      // stored as payload.tags; see NormalizeTags
+	ACL     []string      // access labels added to those of access.rules
+	TTL     time.Duration // chunks expire this long after the run; 0 keeps them
+	Project string        // overrides the project derived from each path
+	Include []string      // pathglob patterns; when set, only matching files are read
+	Exclude []string      // pathglob patterns skipped in addition to indexing.exclude
+	// Report, when set, receives what the content checks changed and how
+	// the batches were written
+	Report *IngestReport
+	// OnWarning receives non-fatal problems of the run, such as a cap
+	// exceeded with indexing.limit_action warn
+	OnWarning func(string)
+	// Context stops the run before its next batch once it is done; nil
+	// never does. Batches already written stay indexed.
+	Context context.Context
+
+	expiresAt time.Time      // derived from TTL when the run starts
+	meta      map[string]any // extra payload of one source, e.g. a page's url and title
+	// root replaces the walked directory in chunk paths and the manifest,
+	// e.g. for a temporary clone of a repository
+	root string
+}
+
+// prepare normalizes opts at the start of a run.
+func (opts *IngestOptions) prepare() {
+	opts.Tags = NormalizeTags(opts.Tags)
+	opts.ACL = NormalizeTags(opts.ACL)
+	opts.Project = strings.TrimSpace(opts.Project)
+	if opts.TTL > 0 {
+		opts.expiresAt = time.Now().Add(opts.TTL)
+	}
+}
+
+// canceled returns the error of a done Context, or nil.
+func (opts IngestOptions) canceled() error {
+	if opts.Context == nil {
+		return nil
+	}
+	return opts.Context.Err()
+}
+
+// NormalizeTags trims tags and drops empty and duplicate ones, keeping order.
+func NormalizeTags(tags []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// IngestDocsWith is IngestDocs with per-run options.
+func (r *VecRAG) IngestDocsWith(dir string, includeCode bool, opts IngestOptions) (int, error) {
+	opts.prepare()
+	// Even a partially failed run may have written points
+	defer r.projects.invalidate()
+	batchSize := r.config.Indexing.BatchSize
+	size, overlap := r.config.Indexing.ChunkSize, r.config.Indexing.ChunkOverlap
+	walk := chunker.WalkOptions{IncludeCode: includeCode, Include: opts.Include, Exclude: opts.Exclude}
+	rules, err := compileACLRules(r.config.Access.Rules)
 	if err != nil {
 		return 0, err
 	}
-	if len(chunks) == 0 {
-		return 0, nil
+	limits := r.newLimitGuard(opts)
+	if err := r.checkFiles(limits, dir, walk); err != nil {
+		return 0, err
+	}
+	if err := opts.canceled(); err != nil {
+		return 0, err
 	}
 
-	// Use batch size from config
-	batchSize := r.config.Indexing.BatchSize
-	total := 0
-	for i := 0; i < len(chunks); i += batchSize {
-		j := i + batchSize
-		if j > len(chunks) {
-			j = len(chunks)
+	chunks := make(chan preparedChunk, batchSize*ingestQueueBatches)
+	done := make(chan struct{})
+	defer close(done)
+	walkErr := make(chan error, 1)
+	hashes := map[string]string{} // owned by the walker until walkErr is received
+	images := map[string]bool{}   // likewise
+	rooted := func(path string) (string, error) {
+		if opts.root == "" {
+			return path, nil
 		}
-		batch := chunks[i:j]
-		texts := make([]string, len(batch))
-		for k, c := range batch {
-			texts[k] = c.Text
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return "", err
 		}
+		return opts.root + "/" + filepath.ToSlash(rel), nil
+	}
+	// Clones of a repository are new files on every run, so the chunk
+	// cache only serves directories
+	cache := r.chunks
+	if opts.root != "" {
+		cache = nil
+	}
+	reused := map[string]cachedChunks{} // owned by the walker
+	if cache != nil {
+		defer cache.save()
+		walk.Reuse = func(path string, info os.FileInfo) bool {
+			cc, ok := cache.get(path, info)
+			if ok {
+				reused[path] = cc
+			}
+			return ok
+		}
+	}
+	go func() {
+		defer close(chunks)
+		walkErr <- chunker.WalkFiles(dir, walk, r.config, func(f chunker.File) error {
+			cc, hit := reused[f.Path]
+			delete(reused, f.Path)
+			if !hit && cache != nil {
+				// A touched file whose content is unchanged
+				if cc, hit = cache.match(f.Path, chunker.FileHash(f.Text)); hit {
+					for i := range cc.Chunks {
+						cc.Chunks[i].Modified = f.ModTime
+					}
+					cache.put(f.Path, cc)
+				}
+			}
+			var cs, imgs []preparedChunk
+			switch {
+			case hit:
+				opts.Report.addReused()
+				cs, imgs = cc.prepared()
+				imgs = dedupeImages(imgs, images)
+			case cache != nil:
+				// Cached images are the file's own, whichever file
+				// references them first in a run
+				imgs = prepareChunks(r.imageChunks(f, dir, map[string]bool{}, size, overlap))
+			default:
+				// Images are found relative to the file on disk
+				imgs = prepareChunks(r.imageChunks(f, dir, images, size, overlap))
+			}
+			acl := rules.labels(dir, f.Path)
+			var err error
+			if f.Path, err = rooted(f.Path); err != nil {
+				return err
+			}
+			if !hit {
+				one := []chunker.File{f}
+				raw := append(chunker.ChunkFiles(one, size, overlap, r.config), r.summaryChunks(one)...)
+				cs = r.prepareFile(f.Text, append(raw, r.tocChunks(one)...))
+			}
+			if len(cs) > 0 {
+				hashes[f.Path] = cs[0].FileHash
+			}
+			if !hit && cache != nil {
+				// The cache only ever holds what passed the checks
+				if cs, err = r.checkChunks(cs, opts.Report); err != nil {
+					return err
+				}
+				if imgs, err = r.checkChunks(imgs, opts.Report); err != nil {
+					return err
+				}
+				cache.put(f.Path, cachedChunks{Chunks: cacheChunks(cs), Images: cacheChunks(imgs)})
+				imgs = dedupeImages(imgs, images)
+			}
+			for i := range cs {
+				cs[i].ACL = acl
+			}
+			for _, c := range imgs {
+				c.ACL = rules.labels(dir, c.Path)
+				if c.Path, err = rooted(c.Path); err != nil {
+					return err
+				}
+				c.ReferencedBy = f.Path
+				hashes[c.Path] = c.FileHash
+				cs = append(cs, c)
+			}
+			for _, c := range cs {
+				select {
+				case chunks <- c:
+				case <-done:
+					return errIngestAborted
+				}
+			}
+			return nil
+		})
+	}()
 
-		vecs, err := r.embed.Embed(texts)
+	total := 0
+	batch := make([]preparedChunk, 0, batchSize)
+	for c := range chunks {
+		batch = append(batch, c)
+		if len(batch) < batchSize {
+			continue
+		}
+		if err := limits.checkChunks(total + len(batch)); err != nil {
+			return total, err
+		}
+		if err := opts.canceled(); err != nil {
+			return total, err
+		}
+		n, err := r.upsertChunks(batch, opts)
+		total += n
 		if err != nil {
 			return total, err
 		}
-		ids := make([]string, len(batch))
-		payloads := make([]map[string]any, len(batch))
-		for k, c := range batch {
-			ids[k] = uuidV4()
-			payloads[k] = map[string]any{
-				"path":      c.Path,
-				"position":  c.Position,
-				"basename":  filepath.Base(c.Path),
-				"preview":   preview(c.Text, 240),
-				"file_type": r.config.GetFileType(c.Path),
-				"project":   projectFromPath(c.Path),
-			}
+		batch = batch[:0]
+	}
+	if len(batch) > 0 {
+		if err := limits.checkChunks(total + len(batch)); err != nil {
+			return total, err
 		}
-		if err := r.vdb.UpsertPoints(ids, vecs, payloads); err != nil {
+		if err := opts.canceled(); err != nil {
+			return total, err
+		}
+		n, err := r.upsertChunks(batch, opts)
+		total += n
+		if err != nil {
 			return total, err
 		}
-		total += len(batch)
 	}
+	if err := <-walkErr; err != nil {
+		return total, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	r.recordIngest(dir, includeCode, opts, hashes, total)
 	return total, nil
 }
 
-// DeleteAll deletes all points by scrolling and deleting in batches
+// upsertChunks embeds one batch and writes it with its payloads, returning
+// how many chunks were written after the secrets check and content filters;
+// chunks checked already are not checked again. Transient failures are
+// retried under indexing.batch_retry; once the batch is embedded only the
+// write is repeated. The outcome goes to opts.Report.
+func (r *VecRAG) upsertChunks(chunks []preparedChunk, opts IngestOptions) (int, error) {
+	batch, err := r.checkChunks(chunks, opts.Report)
+	if err != nil {
+		opts.Report.addBatch(chunks, 1, err)
+		return 0, err
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	policy := newRetryPolicy(r.config.Indexing.BatchRetry)
+	var points *chunkPoints
+	attempts, err := policy.run(fmt.Sprintf("batch of %d chunks", len(batch)), func() error {
+		if points == nil {
+			p, err := r.embedChunks(batch, opts)
+			if err != nil {
+				return err
+			}
+			points = p
+		}
+		return r.storePoints(points)
+	})
+	opts.Report.addBatch(chunks, attempts, err)
+	if err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
+
+// chunkPoints are the points of an embedded batch, ready to be upserted.
+type chunkPoints struct {
+	ids      []string
+	vecs     [][]float32
+	payloads []map[string]any
+}
+
+// embedChunks embeds prepared chunks and builds their points.
+func (r *VecRAG) embedChunks(batch []preparedChunk, opts IngestOptions) (*chunkPoints, error) {
+	texts := make([]string, len(batch))
+	for k, c := range batch {
+		texts[k] = r.embedText(c, r.chunkProject(c, opts), opts.meta)
+	}
+	vecs, stamp, err := r.embedStamped(texts)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkWrite(stamp); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(batch))
+	payloads := make([]map[string]any, len(batch))
+	indexedAt := time.Now().Unix()
+	for k, c := range batch {
+		project := r.chunkProject(c, opts)
+		ids[k] = chunkID(project, c.Path, c.Position)
+		payloads[k] = map[string]any{
+			"path":       c.Path,
+			"position":   c.Position,
+			"basename":   filepath.Base(c.Path),
+			"preview":    preview(c.Text, 240),
+			"text":       c.Text,
+			"file_type":  r.config.GetFileType(c.Path),
+			"project":    project,
+			"file_hash":  c.FileHash,
+			"indexed_at": indexedAt,
+		}
+		if !c.Modified.IsZero() {
+			payloads[k]["modified_at"] = c.Modified.Unix()
+		}
+		if c.Kind == KindSummary {
+			payloads[k]["kind"] = KindSummary
+			payloads[k]["summary_of"] = c.Path
+		}
+		if c.Kind == KindImage {
+			payloads[k]["kind"] = KindImage
+			payloads[k]["referenced_by"] = c.ReferencedBy
+		}
+		if c.Kind == KindTOC {
+			payloads[k]["kind"] = KindTOC
+			payloads[k]["headings"] = headingsPayload(c.Headings)
+		}
+		if c.Section != "" {
+			payloads[k]["section"] = c.Section
+		}
+		if c.Chapter != "" {
+			payloads[k]["chapter"] = c.Chapter
+		}
+		if len(c.Symbols) > 0 {
+			payloads[k]["symbols"] = c.Symbols
+		}
+		if c.Version != "" {
+			payloads[k]["version"] = c.Version
+			payloads[k]["version_prefixes"] = chunker.VersionPrefixes(c.Version)
+			if c.ReleaseDate != "" {
+				payloads[k]["release_date"] = c.ReleaseDate
+			}
+		}
+		if m := c.Message; m != nil {
+			payloads[k]["title"] = m.Subject
+			payloads[k]["from"] = m.From
+			payloads[k]["thread_id"] = m.ThreadID
+			if m.Date != "" {
+				payloads[k]["sent_at"] = m.Date
+			}
+		}
+		if c.StartTime != "" {
+			payloads[k]["start_time"] = c.StartTime
+			payloads[k]["end_time"] = c.EndTime
+		}
+		if c.End > 0 {
+			payloads[k]["start_byte"] = c.Start
+			payloads[k]["end_byte"] = c.End
+		}
+		if len(opts.Tags) > 0 {
+			payloads[k]["tags"] = opts.Tags
+		}
+		payloads[k]["acl"] = chunkACL(opts.ACL, c.ACL)
+		if !opts.expiresAt.IsZero() {
+			payloads[k]["expires_at"] = opts.expiresAt.Unix()
+		}
+		if len(c.secrets) > 0 {
+			payloads[k]["secrets"] = c.secrets
+		}
+		if len(c.redacted) > 0 {
+			payloads[k]["redacted"] = c.redacted
+		}
+		for key, v := range opts.meta {
+			payloads[k][key] = v
+		}
+		stamp.payload(payloads[k])
+	}
+	return &chunkPoints{ids: ids, vecs: vecs, payloads: payloads}, nil
+}
+
+// storePoints upserts the points of a batch.
+func (r *VecRAG) storePoints(pts *chunkPoints) error {
+	if err := r.vdb.UpsertPoints(pts.ids, pts.vecs, pts.payloads); err != nil {
+		return err
+	}
+	projects := make([]string, 0, 1)
+	for _, p := range pts.payloads {
+		if project := toStr(p["project"]); !slices.Contains(projects, project) {
+			projects = append(projects, project)
+		}
+	}
+	r.results.invalidate(projects...)
+	return nil
+}
+
+// chunkProject is the project c is stored under.
+func (r *VecRAG) chunkProject(c preparedChunk, opts IngestOptions) string {
+	if opts.Project != "" {
+		return opts.Project
+	}
+	return projectFromPath(c.Path)
+}
+
+// DeleteAll deletes all points by scrolling and deleting in batches, or
+// soft-deletes them while indexing.soft_delete_retention_hours is set.
 func (r *VecRAG) DeleteAll() (int, error) {
-    deleted := 0
-    batch := make([]any, 0, 1000)
-    var offset any
-    for {
-        pts, next, err := r.vdb.ScrollPoints(1000, offset)
-        if err != nil {
-            return deleted, err
-        }
-        for _, p := range pts {
-            batch = append(batch, p.ID)
-            if len(batch) >= 1000 {
-                if err := r.vdb.DeleteByIDs(batch); err != nil { return deleted, err }
-                deleted += len(batch)
-                batch = batch[:0]
-            }
-        }
-        if next == nil { break }
-        offset = next
-    }
-    if len(batch) > 0 {
-        if err := r.vdb.DeleteByIDs(batch); err != nil { return deleted, err }
-        deleted += len(batch)
-    }
-    return deleted, nil
-}
-
-// DeleteProject deletes all points for a project via filtered scroll+delete
+	if r.SoftDeleteRetention() > 0 {
+		return r.softDelete("")
+	}
+	defer r.indexChanged()
+	deleted := 0
+	batch := make([]any, 0, 1000)
+	var offset any
+	for {
+		pts, next, err := r.vdb.ScrollPoints(1000, offset)
+		if err != nil {
+			return deleted, err
+		}
+		for _, p := range pts {
+			batch = append(batch, p.ID)
+			if len(batch) >= 1000 {
+				if err := r.vdb.DeleteByIDs(batch); err != nil {
+					return deleted, err
+				}
+				deleted += len(batch)
+				batch = batch[:0]
+			}
+		}
+		if next == nil {
+			break
+		}
+		offset = next
+	}
+	if len(batch) > 0 {
+		if err := r.vdb.DeleteByIDs(batch); err != nil {
+			return deleted, err
+		}
+		deleted += len(batch)
+	}
+	r.forgetIndexed("")
+	return deleted, nil
+}
+
+// DeleteProject deletes all points for a project via filtered scroll+delete,
+// or soft-deletes them while indexing.soft_delete_retention_hours is set.
 func (r *VecRAG) DeleteProject(project string) (int, error) {
-    filter := map[string]any{
-        "must": []map[string]any{
-            {"key": "project", "match": map[string]any{"value": project}},
-        },
-    }
-    deleted := 0
-    ids := make([]any, 0, 1000)
-    var offset any
-    for {
-        pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offset, filter)
-        if err != nil {
-            return deleted, err
-        }
-        for _, p := range pts {
-            ids = append(ids, p.ID)
-            if len(ids) >= 1000 {
-                if err := r.vdb.DeleteByIDs(ids); err != nil { return deleted, err }
-                deleted += len(ids)
-                ids = ids[:0]
-            }
-        }
-        if next == nil { break }
-        offset = next
-    }
-    if len(ids) > 0 {
-        if err := r.vdb.DeleteByIDs(ids); err != nil { return deleted, err }
-        deleted += len(ids)
-    }
-    return deleted, nil
-}
-
-func (r *VecRAG) Search(query string, k int) ([]map[string]any, error) {
+	if r.SoftDeleteRetention() > 0 {
+		return r.softDelete(project)
+	}
+	defer r.projects.invalidate()
+	deleted, err := r.deleteWhere(matchFilter(map[string]string{"project": project}))
+	if err != nil {
+		return deleted, err
+	}
+	r.forgetIndexed(project)
+	return deleted, nil
+}
+
+// maxPreviewFiles bounds the file list of a DeletePreview.
+const maxPreviewFiles = 100
+
+// DeletePreview describes what a delete would remove.
+type DeletePreview struct {
+	Points int `json:"points"`
+	// Projects counts the points per project
+	Projects map[string]int `json:"projects"`
+	// Files lists the affected paths, sorted and capped at maxPreviewFiles;
+	// FileCount is the full number
+	Files     []string `json:"files"`
+	FileCount int      `json:"file_count"`
+}
+
+// PreviewDelete reports what DeleteProject(project), or DeleteAll when
+// project is empty, would remove. Nothing is deleted.
+func (r *VecRAG) PreviewDelete(project string) (DeletePreview, error) {
+	return r.previewWhere(liveFilter(projectFilter(project)))
+}
+
+// previewWhere summarizes the points matching filter.
+func (r *VecRAG) previewWhere(filter map[string]any) (DeletePreview, error) {
+	out := DeletePreview{Projects: map[string]int{}, Files: []string{}}
+	files := map[string]struct{}{}
+	var offset any
+	for {
+		pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offset, filter)
+		if err != nil {
+			return out, err
+		}
+		for _, pt := range pts {
+			path := toStr(pt.Payload["path"])
+			proj, _ := pt.Payload["project"].(string)
+			if proj == "" {
+				proj = projectFromPath(path)
+			}
+			out.Points++
+			out.Projects[proj]++
+			files[path] = struct{}{}
+		}
+		if next == nil {
+			break
+		}
+		offset = next
+	}
+	for f := range files {
+		out.Files = append(out.Files, f)
+	}
+	sort.Strings(out.Files)
+	out.FileCount = len(out.Files)
+	if len(out.Files) > maxPreviewFiles {
+		out.Files = out.Files[:maxPreviewFiles]
+	}
+	return out, nil
+}
+
+// PurgeIndexedBefore deletes the chunks indexed before t, optionally only
+// in one project. Chunks indexed before timestamps were recorded carry no
+// indexed_at and are never matched. dryRun only counts.
+func (r *VecRAG) PurgeIndexedBefore(t time.Time, project string, dryRun bool) (int, error) {
+	filter := SearchFilter{Project: project, IndexedBefore: t}.storeFilter()
+	if dryRun {
+		return r.countWhere(filter)
+	}
+	defer r.projects.invalidate()
+	deleted, err := r.deleteWhere(filter)
+	if err != nil {
+		return deleted, err
+	}
+	r.forgetIndexedBefore(t, project)
+	return deleted, nil
+}
+
+// deleteWhere deletes the points matching filter via scroll+delete.
+func (r *VecRAG) deleteWhere(filter map[string]any) (int, error) {
+	defer r.results.invalidate(filterProject(filter))
+	deleted := 0
+	ids := make([]any, 0, 1000)
+	var offset any
+	for {
+		pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offset, filter)
+		if err != nil {
+			return deleted, err
+		}
+		for _, p := range pts {
+			ids = append(ids, p.ID)
+			if len(ids) >= 1000 {
+				if err := r.vdb.DeleteByIDs(ids); err != nil {
+					return deleted, err
+				}
+				deleted += len(ids)
+				ids = ids[:0]
+			}
+		}
+		if next == nil {
+			break
+		}
+		offset = next
+	}
+	if len(ids) > 0 {
+		if err := r.vdb.DeleteByIDs(ids); err != nil {
+			return deleted, err
+		}
+		deleted += len(ids)
+	}
+	return deleted, nil
+}
+
+// RenameProject rewrites the project of every point in from to to, merging
+// into to if it already has points. Vectors are untouched, so nothing is
+// re-embedded. It returns the number of points moved and whether to
+// already existed.
+func (r *VecRAG) RenameProject(from, to string) (int, bool, error) {
+	defer r.indexChanged(from, to)
+	filter := matchFilter(map[string]string{"project": from})
+	moved, err := r.countWhere(filter)
+	if err != nil || moved == 0 {
+		return 0, false, err
+	}
+	existing, err := r.countWhere(matchFilter(map[string]string{"project": to}))
+	if err != nil {
+		return 0, false, err
+	}
+	if err := r.vdb.SetPayload(filter, map[string]any{"project": to}); err != nil {
+		return 0, false, err
+	}
+	return moved, existing > 0, nil
+}
+
+// countWhere counts the points matching filter by scrolling them.
+func (r *VecRAG) countWhere(filter map[string]any) (int, error) {
+	n := 0
+	var offset any
+	for {
+		pts, next, err := r.vdb.ScrollPointsWithFilter(1000, offset, filter)
+		if err != nil {
+			return n, err
+		}
+		n += len(pts)
+		if next == nil {
+			return n, nil
+		}
+		offset = next
+	}
+}
+
+func (r *VecRAG) Search(query string, k int) ([]Hit, error) {
 	return r.SearchWithFilter(query, k, "", "")
 }
 
 func preview(s string, n int) string {
-    rs := []rune(strings.TrimSpace(s))
-    if len(rs) <= n {
-        return string(rs)
-    }
-    return string(rs[:n]) + "…"
+	rs := []rune(strings.TrimSpace(s))
+	if len(rs) <= n {
+		return string(rs)
+	}
+	return string(rs[:n]) + "…"
 }
 
-// uuidV4 generates random UUID v4 string (xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx)
-func uuidV4() string {
-    b := make([]byte, 16)
-    if _, err := rand.Read(b); err != nil {
-        // Fallback to timestamp if rand fails
-        return fmt.Sprintf("%d", time.Now().UnixNano())
-    }
-    b[6] = (b[6] & 0x0f) | 0x40
-    b[8] = (b[8] & 0x3f) | 0x80
-    return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+// chunkIDSpace is the UUID namespace of chunk point IDs.
+var chunkIDSpace = [16]byte{0x6d, 0x63, 0x70, 0x2d, 0x72, 0x61, 0x67, 0x2d, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x2d, 0x69, 0x64}
+
+// chunkID is the point ID of a chunk: a name-based UUID (version 5) of its
+// project, path and position. Indexing the same chunk again overwrites its
+// point, so a run repeated after a failure does not leave duplicates.
+func chunkID(project, path string, position int) string {
+	h := sha1.New()
+	h.Write(chunkIDSpace[:])
+	fmt.Fprintf(h, "%s\x00%s\x00%d", project, path, position)
+	b := h.Sum(nil)[:16]
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
 
 func toStr(v any) string {
@@ -644,29 +1252,137 @@ func toStr(v any) string {
 // SearchWithFilter supports optional project or projectPrefix filtering.
 // If project is set, it uses a server-side Qdrant filter for exact match.
 // If projectPrefix is set (and project empty), it fetches a larger set then filters client-side.
-func (r *VecRAG) SearchWithFilter(query string, k int, project string, projectPrefix string) ([]map[string]any, error) {
+func (r *VecRAG) SearchWithFilter(query string, k int, project string, projectPrefix string) ([]Hit, error) {
+	return r.SearchWithParams(query, k, project, projectPrefix, r.DefaultSearchParams())
+}
+
+// DefaultSearchParams returns the configured search-time parameters.
+func (r *VecRAG) DefaultSearchParams() SearchParams {
+	s := r.config.Search
+	return SearchParams{HNSWEf: s.HNSWEf, Exact: s.Exact, Consistency: s.Consistency, TimeoutSec: s.TimeoutSec}
+}
+
+// SearchWithParams is SearchWithFilter with explicit search-time parameters.
+func (r *VecRAG) SearchWithParams(query string, k int, project string, projectPrefix string, params SearchParams) ([]Hit, error) {
+	return r.SearchFiltered(query, k, SearchFilter{Project: project, ProjectPrefix: projectPrefix}, params)
+}
+
+// SearchFilter restricts a search. Empty fields match everything.
+type SearchFilter struct {
+	Project       string    // exact project name (server-side filter)
+	ProjectPrefix string    // project name prefix (client-side, over-fetching)
+	TagsAny       []string  // chunk has at least one of these tags
+	TagsAll       []string  // chunk has every one of these tags
+	Version       string    // changelog version or a prefix of it ("2.3" matches 2.3.0)
+	ModifiedAfter time.Time // source file modified after this time
+	IndexedBefore time.Time // chunk indexed before this time
+	// ACL, when set, limits hits to chunks carrying one of these access
+	// labels; a tenant's are its config TenantLabels
+	ACL []string
+}
+
+// ParseTime reads a time filter bound: RFC3339 or a YYYY-MM-DD date (UTC
+// midnight). An empty string gives the zero time, i.e. no bound.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// storeFilter renders the server-side part of f, or nil when it has none.
+func (f SearchFilter) storeFilter() map[string]any {
+	var must []map[string]any
+	if p := strings.TrimSpace(f.Project); p != "" {
+		must = append(must, map[string]any{"key": "project", "match": map[string]any{"value": p}})
+	}
+	if tags := NormalizeTags(f.TagsAny); len(tags) > 0 {
+		must = append(must, map[string]any{"key": "tags", "match": map[string]any{"any": tags}})
+	}
+	for _, t := range NormalizeTags(f.TagsAll) {
+		must = append(must, map[string]any{"key": "tags", "match": map[string]any{"value": t}})
+	}
+	if v := chunker.NormalizeVersion(f.Version); v != "" {
+		must = append(must, map[string]any{"key": "version_prefixes", "match": map[string]any{"value": v}})
+	}
+	must = append(must, aclMust(f.ACL)...)
+	if !f.ModifiedAfter.IsZero() {
+		must = append(must, map[string]any{"key": "modified_at", "range": map[string]any{"gt": f.ModifiedAfter.Unix()}})
+	}
+	if !f.IndexedBefore.IsZero() {
+		must = append(must, map[string]any{"key": "indexed_at", "range": map[string]any{"lt": f.IndexedBefore.Unix()}})
+	}
+	if len(must) == 0 {
+		return nil
+	}
+	return map[string]any{"must": must}
+}
+
+// aclMust returns the condition limiting points to those carrying one of
+// the access labels acl, or none for nil.
+func aclMust(acl []string) []map[string]any {
+	if acl == nil {
+		return nil
+	}
+	return []map[string]any{{"key": "acl", "match": map[string]any{"any": NormalizeTags(acl)}}}
+}
+
+// SearchFiltered is the general search entry point.
+func (r *VecRAG) SearchFiltered(query string, k int, f SearchFilter, params SearchParams) ([]Hit, error) {
+	return r.search(query, k, f, params, nil)
+}
+
+// SearchExplained is SearchFiltered that also tells how the search ran and
+// how each hit got its score.
+func (r *VecRAG) SearchExplained(query string, k int, f SearchFilter, params SearchParams) ([]Hit, *SearchExplanation, error) {
+	ex := &SearchExplanation{}
+	hits, err := r.search(query, k, f, params, ex)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hits, ex, nil
+}
+
+// search runs a search, filling ex and each hit's Explain when ex is set.
+func (r *VecRAG) search(query string, k int, f SearchFilter, params SearchParams, ex *SearchExplanation) ([]Hit, error) {
 	if k <= 0 {
 		k = 5
 	}
-	vecs, err := r.embed.Embed([]string{query})
+	// Explanations always run the search; they are about how it ranks
+	var key string
+	var gen uint64
+	if ex == nil && r.results.enabled() {
+		key = resultKey(query, k, f, params)
+		hits, g, ok := r.results.get(key)
+		if ok {
+			return hits, nil
+		}
+		gen = g
+	}
+	vecs, stamp, err := r.embedStamped([]string{query})
 	if err != nil {
 		return nil, err
 	}
-	// Build filter for exact project match
-	var filter map[string]any
-	if strings.TrimSpace(project) != "" {
-		filter = map[string]any{
-			"must": []map[string]any{
-				{
-					"key":   "project",
-					"match": map[string]any{"value": project},
-				},
-			},
-		}
-	}
+	policy := r.config.Search.ModelMismatch
+	// Tables of contents are for rag_toc, not answers
+	filter := searchable(liveFilter(f.storeFilter()))
 	// If prefix provided without exact project, pull a larger page and filter client-side
+	projectPrefix := f.ProjectPrefix
+	byPrefix := strings.TrimSpace(f.Project) == "" && strings.TrimSpace(projectPrefix) != ""
 	limit := k
-	if filter == nil && strings.TrimSpace(projectPrefix) != "" {
+	if policy == MismatchFilter {
+		// Mismatching hits are dropped client-side; over-fetch to still fill k
+		limit = k * 2
+	}
+	if byPrefix {
 		if k < 20 {
 			limit = 20
 		}
@@ -677,40 +1393,81 @@ func (r *VecRAG) SearchWithFilter(query string, k int, project string, projectPr
 			limit = 100
 		}
 	}
-	res, err := r.vdb.Search(vecs[0], limit, filter)
+	// The reranker picks from a wider pool than k
+	limit = max(limit, r.rerankCandidates(k))
+	var res []SearchHit
+	if ts, ok := r.vdb.(TextSearcher); ok {
+		res, err = ts.SearchText(query, vecs[0], limit, filter, params)
+	} else {
+		res, err = r.vdb.Search(vecs[0], limit, filter, params)
+	}
 	if err != nil {
 		return nil, err
 	}
-	// Map hits
-	items := make([]map[string]any, 0, len(res))
-	for _, h := range res {
-		p := h.Payload
-		it := map[string]any{
-			"id":        fmt.Sprint(h.ID),
-			"score":     h.Score,
-			"path":      toStr(p["path"]),
-			"basename":  toStr(p["basename"]),
-			"position":  p["position"],
-			"snippet":   toStr(p["preview"]),
-			"file_type": toStr(p["file_type"]),
-			"project":   toStr(p["project"]),
+	if ex != nil {
+		r.startExplanation(ex, stamp, filter, params, limit, len(res))
+	}
+	items := make([]Hit, 0, len(res))
+	for rank, h := range res {
+		it := hitFromPoint(h)
+		if ex != nil {
+			it.Explain = &HitExplanation{Rank: rank + 1, StoreScore: h.Score}
+		}
+		if hs, ok := stampOf(h.Payload); ok && policy != MismatchIgnore {
+			if hs != stamp {
+				if policy == MismatchFilter {
+					if ex != nil {
+						ex.DroppedByModel++
+					}
+					continue
+				}
+				it.ModelMismatch = true
+				it.Embedding = hs.String()
+			}
 		}
 		items = append(items, it)
 	}
 	// Client-side prefix filter if needed
-	if filter == nil && strings.TrimSpace(projectPrefix) != "" {
+	if byPrefix {
 		pref := strings.ToLower(strings.TrimSpace(projectPrefix))
 		filtered := items[:0]
 		for _, it := range items {
-			if strings.HasPrefix(strings.ToLower(fmt.Sprint(it["project"])), pref) {
+			if strings.HasPrefix(strings.ToLower(it.Project), pref) {
 				filtered = append(filtered, it)
 			}
 		}
+		if ex != nil {
+			ex.DroppedByPrefix = len(items) - len(filtered)
+		}
 		items = filtered
 	}
+	if items, err = r.rerank(query, items, ex); err != nil {
+		return nil, err
+	}
+	if ex != nil {
+		ex.Cut = max(len(items)-k, 0)
+	}
+	if len(items) > k {
+		items = items[:k]
+	}
+	if r.config.Search.MergeAdjacent {
+		before := len(items)
+		items = mergeAdjacent(items, r.config.MaxChunkOverlap())
+		if ex != nil {
+			ex.Merged = before - len(items)
+		}
+	}
 	// Trim to k
 	if len(items) > k {
 		items = items[:k]
 	}
+	if ex != nil {
+		if err := r.explainHits(ex, query, vecs[0], items, f, stamp); err != nil {
+			return nil, err
+		}
+	}
+	if key != "" {
+		r.results.put(key, gen, f, items)
+	}
 	return items, nil
 }
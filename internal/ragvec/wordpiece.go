@@ -0,0 +1,115 @@
+package ragvec
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// wordPiece is a BERT-style (uncased) WordPiece tokenizer driven by the
+// vocab.txt that ships with sentence-transformer models.
+type wordPiece struct {
+	vocab               map[string]int64
+	cls, sep, unk       int64
+	maxInputCharsPerTok int
+}
+
+func loadWordPiece(path string) (*wordPiece, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	wp := &wordPiece{vocab: map[string]int64{}, maxInputCharsPerTok: 100}
+	sc := bufio.NewScanner(f)
+	var id int64
+	for sc.Scan() {
+		wp.vocab[strings.TrimRight(sc.Text(), "\r")] = id
+		id++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for tok, dst := range map[string]*int64{"[CLS]": &wp.cls, "[SEP]": &wp.sep, "[UNK]": &wp.unk} {
+		v, ok := wp.vocab[tok]
+		if !ok {
+			return nil, fmt.Errorf("vocab %s has no %s token", path, tok)
+		}
+		*dst = v
+	}
+	return wp, nil
+}
+
+// encode returns input ids wrapped in [CLS] … [SEP], truncated to maxLen.
+func (wp *wordPiece) encode(text string, maxLen int) []int64 {
+	ids := []int64{wp.cls}
+	for _, word := range basicTokens(text) {
+		for _, id := range wp.pieces(word) {
+			if len(ids) >= maxLen-1 {
+				return append(ids, wp.sep)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return append(ids, wp.sep)
+}
+
+// pieces splits one word greedily into the longest vocabulary entries,
+// marking continuations with "##".
+func (wp *wordPiece) pieces(word string) []int64 {
+	rs := []rune(word)
+	if len(rs) > wp.maxInputCharsPerTok {
+		return []int64{wp.unk}
+	}
+	var out []int64
+	for start := 0; start < len(rs); {
+		end := len(rs)
+		var id int64 = -1
+		for ; end > start; end-- {
+			sub := string(rs[start:end])
+			if start > 0 {
+				sub = "##" + sub
+			}
+			if v, ok := wp.vocab[sub]; ok {
+				id = v
+				break
+			}
+		}
+		if id < 0 {
+			return []int64{wp.unk}
+		}
+		out = append(out, id)
+		start = end
+	}
+	return out
+}
+
+// basicTokens lowercases, drops control characters and splits on whitespace
+// and punctuation, keeping each punctuation mark and CJK character as its
+// own token. Accents are not stripped.
+func basicTokens(text string) []string {
+	var out []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			out = append(out, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case unicode.IsControl(r) || r == 0xFFFD:
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.Is(unicode.Han, r):
+			flush()
+			out = append(out, string(r))
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return out
+}
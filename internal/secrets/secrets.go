@@ -0,0 +1,179 @@
+// Package secrets finds likely credentials in text (API keys, tokens,
+// private keys, passwords in .env style assignments and URLs) so they can
+// be masked or skipped before the text is embedded and stored.
+package secrets
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// rule is one kind of secret. When the expression has a capture group only
+// that group is the secret, e.g. the value of an assignment.
+type rule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+var rules = []rule{
+	{"private_key", regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----(?s:.*?)(?:-----END (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----|\z)`)},
+	{"aws_access_key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github_token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"openai_key", regexp.MustCompile(`\bsk-(?:proj-|svcacct-)?[A-Za-z0-9_-]{20,}`)},
+	{"slack_token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"google_api_key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{"stripe_key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{20,}`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"url_password", regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:([^/\s@]+)@`)},
+	{"assignment", regexp.MustCompile(`(?im)^[ \t]*(?:export[ \t]+)?["']?[A-Za-z0-9_.-]*(?:secret|token|passw(?:or)?d|pwd|api_?key|access_?key|private_?key|credentials?)[A-Za-z0-9_.-]*["']?[ \t]*[=:][ \t]*["']?([^\s"'#,;]{6,})`)},
+}
+
+// placeholderRE matches assignment values that are documentation, not
+// secrets: references, templates, numbers and the usual fillers.
+var placeholderRE = regexp.MustCompile(`(?i)^(?:[$<{%(\[].*|[0-9.]+|x+|\*+|\.+|changeme|change_me|example.*|your[_-].*|placeholder.*|redacted.*)$`)
+
+// plainAssignment reports whether an assignment value looks like code or a
+// word rather than a credential: a call, or a short value of letters only
+// such as a type name ("token: string").
+func plainAssignment(value string) bool {
+	if placeholderRE.MatchString(value) || strings.ContainsAny(value, "()") {
+		return true
+	}
+	if len(value) >= 12 {
+		return false
+	}
+	for _, c := range value {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// Match is the byte range of one secret in the scanned text.
+type Match struct {
+	Rule       string
+	Start, End int
+}
+
+// Scanner finds secrets, ignoring matches of its allow list.
+type Scanner struct {
+	allow []*regexp.Regexp
+}
+
+// New returns a scanner that ignores secrets matching any of the allow
+// regular expressions, e.g. the example keys of a documentation page.
+func New(allow []string) (*Scanner, error) {
+	s := &Scanner{}
+	for _, expr := range allow {
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("allow %q: %v", expr, err)
+		}
+		s.allow = append(s.allow, re)
+	}
+	return s, nil
+}
+
+// Find returns the secrets of text in order, without overlaps; where two
+// rules overlap the earlier, longer match wins.
+func (s *Scanner) Find(text string) []Match {
+	var found []Match
+	for _, r := range rules {
+		for _, loc := range r.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			value := text[start:end]
+			if r.name == "assignment" && plainAssignment(value) {
+				continue
+			}
+			if s.allowed(value) {
+				continue
+			}
+			found = append(found, Match{Rule: r.name, Start: start, End: end})
+		}
+	}
+	return Merge(found)
+}
+
+// Merge sorts matches and drops overlapping ones; where two overlap the
+// earlier, longer match wins.
+func Merge(found []Match) []Match {
+	if len(found) < 2 {
+		return found
+	}
+	found = slices.Clone(found)
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Start != found[j].Start {
+			return found[i].Start < found[j].Start
+		}
+		return found[i].End > found[j].End
+	})
+	out := found[:1]
+	for _, m := range found[1:] {
+		if m.Start >= out[len(out)-1].End {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (s *Scanner) allowed(value string) bool {
+	for _, re := range s.allow {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask replaces each match found in text with [REDACTED:<rule>].
+func Mask(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString("[REDACTED:" + m.Rule + "]")
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Within returns the parts of matches that fall in text[start:end], with
+// offsets relative to start, e.g. the piece of a private key found in the
+// whole file that one chunk of it holds.
+func Within(matches []Match, start, end int) []Match {
+	var out []Match
+	for _, m := range matches {
+		if m.End <= start || m.Start >= end {
+			continue
+		}
+		out = append(out, Match{Rule: m.Rule, Start: max(m.Start, start) - start, End: min(m.End, end) - start})
+	}
+	return out
+}
+
+// Rules returns the distinct rule names of matches, in order of appearance.
+func Rules(matches []Match) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, m := range matches {
+		if !seen[m.Rule] {
+			seen[m.Rule] = true
+			out = append(out, m.Rule)
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+// Package stopwords provides per-language stopword lists shared by the local
+// TF-IDF embeddings and the classic BM25 index.
+package stopwords
+
+import (
+	"sort"
+	"strings"
+)
+
+// Set is a lookup of lowercase words excluded from term statistics.
+// A nil Set contains nothing.
+type Set map[string]struct{}
+
+// Contains reports whether word (already lowercased) is a stopword.
+func (s Set) Contains(word string) bool {
+	_, ok := s[word]
+	return ok
+}
+
+// builtin lists, keyed by language code. "en" is kept identical to the
+// original hard-coded list so existing local-mode indexes stay comparable.
+var builtin = map[string][]string{
+	"en": {
+		"the", "a", "an", "and", "or",
+		"but", "in", "on", "at", "to",
+		"for", "of", "with", "by", "is",
+		"are", "was", "were", "be", "been",
+		"have", "has", "had", "do", "does",
+		"did", "will", "would", "could", "should",
+	},
+	"id": {
+		"yang", "dan", "di", "ke", "dari", "ini", "itu", "untuk", "dengan", "pada",
+		"adalah", "dalam", "tidak", "akan", "juga", "atau", "ada", "oleh", "sudah", "telah",
+		"saya", "kami", "kita", "mereka", "dia", "ia", "anda", "bisa", "dapat", "karena",
+		"sebagai", "seperti", "jika", "bila", "apabila", "maka", "agar", "supaya", "bahwa", "masih",
+		"lebih", "hanya", "harus", "serta", "tersebut", "namun", "tetapi", "antara", "setelah", "sebelum",
+		"saat", "ketika", "para", "lagi", "pun", "yaitu", "yakni", "hal", "secara", "sangat",
+		"belum", "bagi", "hingga", "sampai", "tanpa", "kepada", "terhadap", "sehingga", "sedangkan", "sebuah",
+		"suatu", "setiap", "semua", "banyak", "beberapa", "nya", "kah", "lah", "pula", "pernah",
+	},
+}
+
+// Languages returns the codes of the built-in lists.
+func Languages() []string {
+	out := make([]string, 0, len(builtin))
+	for k := range builtin {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Known reports whether lang has a built-in list.
+func Known(lang string) bool {
+	_, ok := builtin[normalize(lang)]
+	return ok
+}
+
+// New builds a set from built-in languages plus extra words.
+func New(langs []string, extra []string) Set {
+	s := Set{}
+	for _, l := range langs {
+		for _, w := range builtin[normalize(l)] {
+			s[w] = struct{}{}
+		}
+	}
+	for _, w := range extra {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			s[w] = struct{}{}
+		}
+	}
+	return s
+}
+
+// Load builds the configured set: the built-in lists for langs plus extra
+// words, or an empty set when disabled.
+func Load(langs, extra []string, disabled bool) Set {
+	if disabled {
+		return Set{}
+	}
+	return New(langs, extra)
+}
+
+func normalize(lang string) string {
+	return strings.ToLower(strings.TrimSpace(lang))
+}
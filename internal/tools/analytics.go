@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+)
+
+func ragAnalyticsTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_analytics",
+		Description: "Report what is being searched: the most frequent queries, the queries the index could not answer (no hits, or only weak ones) and the files returned most often. Use it to decide what documentation to index next. Requires query_log.enabled.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"days": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"maximum":     365,
+					"default":     7,
+					"description": "Look back this many days",
+				},
+				"limit": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"maximum":     100,
+					"default":     10,
+					"description": "Max number of entries per list",
+				},
+			},
+		},
+		OutputSchema: object(map[string]any{
+			"since":                field("string", "RFC3339 start of the window"),
+			"mode":                 field("string", "query_log.mode"),
+			"searches":             field("integer", ""),
+			"unique_queries":       field("integer", ""),
+			"zero_result_searches": field("integer", ""),
+			"weak_searches":        field("integer", "Searches whose best hit scored below search.suggestions.min_score"),
+			"top_queries":          arrayOf(queryStatSchema),
+			"unanswered_queries":   arrayOf(queryStatSchema),
+			"top_results": arrayOf(object(map[string]any{
+				"path":  field("string", ""),
+				"count": field("integer", ""),
+			}, "path", "count")),
+		}, "since", "searches", "unique_queries", "top_queries", "unanswered_queries", "top_results"),
+		ReadOnly: true,
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			if env.Queries == nil {
+				return failure("query logging is disabled", apierr.New(apierr.Config, "config", "Set query_log.enabled to record searches for rag_analytics")), nil
+			}
+			days, limit := 7, 10
+			if v, ok := args.Number("days"); ok && v >= 1 && v <= 365 {
+				days = int(v)
+			}
+			if v, ok := args.Number("limit"); ok && v >= 1 && v <= 100 {
+				limit = int(v)
+			}
+			rep, err := env.Queries.Analyze(time.Now().AddDate(0, 0, -days), limit)
+			if err != nil {
+				log.Printf("Query analytics error: %v", err)
+				return failure("analytics error", env.describe(err)), nil
+			}
+			msg := fmt.Sprintf("%d searches (%d unique queries) in the last %d days; %d without hits, %d with weak hits",
+				rep.Searches, rep.Queries, days, rep.ZeroResults, rep.Weak)
+			return result(msg, rep), nil
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+// answerPrompt is the system prompt of sampled answers unless
+// search.sampling.system_prompt replaces it.
+const answerPrompt = "You answer a question using only the numbered excerpts given with it. " +
+	"Be brief and precise. Cite the excerpts you rely on as [1], [2]. " +
+	"If the excerpts do not answer the question, say so instead of guessing."
+
+// answer is a sampled answer as rag_search returns it.
+type answer struct {
+	Text       string `json:"text"`
+	Model      string `json:"model,omitempty"`
+	StopReason string `json:"stop_reason,omitempty"`
+}
+
+// sampleAnswer asks the client's model to answer query from hits.
+func sampleAnswer(client Client, sc cfg.SamplingConfig, query string, hits []ragvec.Hit) (*answer, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Question: %s\n\nExcerpts:\n", query)
+	for i, h := range hits {
+		text := h.Text
+		if text == "" {
+			text = h.Snippet
+		}
+		where := h.Path
+		if h.Section != "" {
+			where += " (" + h.Section + ")"
+		}
+		fmt.Fprintf(&b, "\n[%d] %s\n%s\n", i+1, where, strings.TrimSpace(text))
+	}
+	p := mcp.CreateMessageParams{
+		Messages:       []mcp.SamplingMessage{{Role: "user", Content: mcp.SamplingContent{Type: "text", Text: b.String()}}},
+		SystemPrompt:   answerPrompt,
+		IncludeContext: "none",
+		MaxTokens:      sc.MaxTokens,
+	}
+	if sc.SystemPrompt != "" {
+		p.SystemPrompt = sc.SystemPrompt
+	}
+	if sc.ModelHint != "" {
+		p.ModelPreferences = &mcp.ModelPreferences{Hints: []mcp.ModelHint{{Name: sc.ModelHint}}}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(sc.TimeoutSec)*time.Second)
+	defer cancel()
+	res, err := client.Sample(ctx, p)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("the client did not answer within %d seconds", sc.TimeoutSec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.Content.Type != "text" || strings.TrimSpace(res.Content.Text) == "" {
+		return nil, fmt.Errorf("client returned no text (content type %q)", res.Content.Type)
+	}
+	return &answer{Text: strings.TrimSpace(res.Content.Text), Model: res.Model, StopReason: res.StopReason}, nil
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+)
+
+func ragBackupTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_backup",
+		Description: "Snapshot the whole collection with the Qdrant snapshot API and copy it to the configured backup directory and/or S3 bucket. Use list to see the snapshots rag_restore can recover.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"list": map[string]any{
+					"type":        "boolean",
+					"description": "List the available snapshots instead of taking one",
+					"default":     false,
+				},
+			},
+		},
+		OutputSchema: object(map[string]any{
+			"snapshot": object(map[string]any{
+				"name":          field("string", ""),
+				"size":          field("integer", "Bytes"),
+				"creation_time": field("string", ""),
+				"checksum":      field("string", ""),
+				"path":          field("string", "Copy in backup.dir"),
+				"s3_url":        field("string", "Copy in backup.s3"),
+				"on_server":     field("boolean", ""),
+			}, "name", "size", "on_server"),
+			"snapshots": arrayOf(object(map[string]any{
+				"name":    field("string", ""),
+				"source":  map[string]any{"type": "string", "enum": []string{"server", "dir", "s3"}},
+				"size":    field("integer", "Bytes"),
+				"created": field("string", "RFC3339"),
+			}, "name", "source", "size")),
+			"warnings": stringList,
+			"status":   statusField,
+		}, "status"),
+		// Not ReadOnly: snapshots are written to backup.dir and backup.s3.
+		// Listing them only serves rag_restore, which read-only sessions
+		// lack as well.
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			if args.Bool("list", false) {
+				entries, warnings, err := rag.ListBackups()
+				if err != nil {
+					log.Printf("List backups error: %v", err)
+					return failure("backup error", env.describe(err)), nil
+				}
+				if warnings == nil {
+					warnings = []string{}
+				}
+				msg := fmt.Sprintf("Found %d snapshots", len(entries))
+				if len(warnings) > 0 {
+					msg += fmt.Sprintf(" (%d sources could not be listed)", len(warnings))
+				}
+				payload := map[string]any{
+					"snapshots": entries,
+					"warnings":  warnings,
+					"status":    "success",
+				}
+				return result(msg, payload), nil
+			}
+			res, err := rag.Backup()
+			if err != nil {
+				log.Printf("Backup error: %v", err)
+				return failure("backup error", env.describe(err)), nil
+			}
+			var where []string
+			if res.Path != "" {
+				where = append(where, res.Path)
+			}
+			if res.S3URL != "" {
+				where = append(where, res.S3URL)
+			}
+			if res.OnServer {
+				where = append(where, "the Qdrant server")
+			}
+			msg := fmt.Sprintf("Created snapshot %s (%d bytes), stored on %s", res.Name, res.Size, strings.Join(where, " and "))
+			payload := map[string]any{
+				"snapshot": res,
+				"status":   "success",
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
+
+func ragRestoreTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_restore",
+		Description: "Replace the whole collection with a snapshot taken by rag_backup. The index manifest is cleared, so the next rag_index re-embeds every file.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name": map[string]any{
+					"type":        "string",
+					"description": "Snapshot file name, as listed by rag_backup with list=true",
+				},
+				"source": map[string]any{
+					"type":        "string",
+					"description": "Where to read the snapshot: dir, s3 or server (default: the first of those holding it)",
+					"enum":        []string{"dir", "s3", "server"},
+				},
+			},
+			"required": []string{"name"},
+		},
+		OutputSchema: object(map[string]any{
+			"restore": object(map[string]any{
+				"name":    field("string", ""),
+				"source":  field("string", ""),
+				"warning": field("string", "Set when the restored vectors do not fit the embedding provider"),
+			}, "name", "source"),
+			"status": statusField,
+		}, "restore", "status"),
+		SessionHandler: func(args Args, client Client) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			name := strings.TrimSpace(args.String("name"))
+			if name == "" {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("Provide the snapshot name")}
+			}
+			source := args.String("source")
+			switch source {
+			case "", "dir", "s3", "server":
+			default:
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("source must be 'dir', 's3' or 'server'")}
+			}
+			describe := func() string {
+				return fmt.Sprintf("An agent asks to replace the whole collection with snapshot %s; everything indexed since is lost. Proceed?", name)
+			}
+			if res := env.confirm(client, "restore", describe); res != nil {
+				return res, nil
+			}
+			res, err := rag.Restore(name, source)
+			if err != nil {
+				log.Printf("Restore error: %v", err)
+				return failure("restore error", env.describe(err)), nil
+			}
+			msg := fmt.Sprintf("Restored the collection from snapshot %s (%s); run rag_index to track files in the manifest again", res.Name, res.Source)
+			if res.Warning != "" {
+				msg += "; warning: " + res.Warning
+			}
+			payload := map[string]any{
+				"restore": res,
+				"status":  "success",
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
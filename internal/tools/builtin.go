@@ -0,0 +1,25 @@
+package tools
+
+// RegisterBuiltin registers the core RAG tools backed by env.
+func RegisterBuiltin(r *Registry, env *Env) {
+	env.Stats = r.Stats()
+	r.Register(ragIndexTool(env))
+	r.Register(ragIndexURLTool(env))
+	r.Register(ragIndexRepoTool(env))
+	r.Register(ragConnectorSyncTool(env))
+	r.Register(ragDeleteTool(env))
+	r.Register(ragUndeleteTool(env))
+	r.Register(ragPurgeTool(env))
+	r.Register(ragProjectRenameTool(env))
+	r.Register(ragSearchTool(env))
+	r.Register(ragGetChunkTool(env))
+	r.Register(ragProjectsTool(env))
+	r.Register(ragFindFilesTool(env))
+	r.Register(ragTOCTool(env))
+	r.Register(ragEvalTool(env))
+	r.Register(ragAnalyticsTool(env))
+	r.Register(statusGetTool(env))
+	r.Register(ragManifestTool(env))
+	r.Register(ragBackupTool(env))
+	r.Register(ragRestoreTool(env))
+}
@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+// chunkSummary is a hit of a summarized rag_search result: where the chunk
+// is and how it scored, without its text.
+type chunkSummary struct {
+	ID       string `json:"id"`
+	Path     string `json:"path"`
+	Project  string `json:"project"`
+	Position int    `json:"position"`
+	// MergedPositions lists the chunks joined into the hit
+	MergedPositions []int   `json:"merged_positions,omitempty"`
+	Score           float32 `json:"score"`
+	Section         string  `json:"section,omitempty"`
+}
+
+// chunkCursor lists the chunks still to fetch with rag_get_chunk and how
+// much text one call returns.
+type chunkCursor struct {
+	Refs        []ragvec.ChunkRef `json:"refs"`
+	MaxKB       int               `json:"max_kb,omitempty"`
+	ReadThrough bool              `json:"read_through,omitempty"`
+}
+
+func (c chunkCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeChunkCursor(s string) (chunkCursor, error) {
+	var c chunkCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	if len(c.Refs) == 0 || c.MaxKB < 0 {
+		return c, fmt.Errorf("malformed cursor")
+	}
+	return c, nil
+}
+
+// summarize returns hits without their text and the cursor that fetches
+// their text maxKB at a time.
+func summarize(hits []ragvec.Hit, maxKB int, readThrough bool) ([]chunkSummary, string) {
+	out := make([]chunkSummary, 0, len(hits))
+	cur := chunkCursor{MaxKB: maxKB, ReadThrough: readThrough}
+	for _, h := range hits {
+		out = append(out, chunkSummary{ID: h.ID, Path: h.Path, Project: h.Project, Position: h.Position,
+			MergedPositions: h.MergedPositions, Score: h.Score, Section: h.Section})
+		positions := h.MergedPositions
+		if len(positions) == 0 {
+			positions = []int{h.Position}
+		}
+		// A merged hit is fetched as the chunks it joined
+		for _, pos := range positions {
+			cur.Refs = append(cur.Refs, ragvec.ChunkRef{Path: h.Path, Position: pos})
+		}
+	}
+	return out, cur.encode()
+}
+
+// fitKB returns how many of hits, at least one, fit maxKB once encoded
+// (0 = all of them).
+func fitKB(hits []ragvec.Hit, maxKB int) int {
+	if maxKB <= 0 {
+		return len(hits)
+	}
+	size := 0
+	for i, h := range hits {
+		b, _ := json.Marshal(h)
+		size += len(b)
+		if size > maxKB*1024 && i > 0 {
+			return i
+		}
+	}
+	return len(hits)
+}
+
+func ragGetChunkTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_get_chunk",
+		Description: "Fetch the full text of indexed chunks: pass the cursor of a summarized rag_search result to page through its hits, or a path and position to read one chunk.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"cursor": map[string]any{
+					"type":        "string",
+					"description": "Cursor from a summarized rag_search result (summarized.cursor) or from a previous call (next_cursor)",
+				},
+				"path": map[string]any{
+					"type":        "string",
+					"description": "Path of the chunk's file, as search results show it; ignored with cursor",
+				},
+				"position": map[string]any{
+					"type":        "integer",
+					"minimum":     -1,
+					"default":     0,
+					"description": "Position of the chunk in its file (-1 = the file's summary chunk)",
+				},
+			},
+		},
+		OutputSchema: object(map[string]any{
+			"chunks":      arrayOf(hitSchema),
+			"count":       field("integer", ""),
+			"next_cursor": field("string", "Cursor of the chunks left"),
+			"remaining":   field("integer", ""),
+			"missing":     field("integer", "Chunks no longer indexed or not readable"),
+			"quota":       quotaSchema,
+		}, "chunks", "count"),
+		ReadOnly: true,
+		SessionHandler: func(args Args, client Client) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				log.Println("RAG chunk requested but RAG system not initialized")
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			cur := chunkCursor{MaxKB: env.Config.Search.MaxOutputKB, ReadThrough: env.Config.Search.ReadThrough}
+			if c := args.String("cursor"); c != "" {
+				var err error
+				if cur, err = decodeChunkCursor(c); err != nil {
+					return nil, &Error{Code: -32602, Message: "invalid cursor", Data: apierr.Invalid(c)}
+				}
+			} else {
+				path := args.String("path")
+				if strings.TrimSpace(path) == "" {
+					return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("cursor or path is required")}
+				}
+				pos := 0
+				if v, ok := args.Number("position"); ok {
+					pos = int(v)
+				}
+				cur.Refs = []ragvec.ChunkRef{{Path: path, Position: pos}}
+			}
+			// A tenant session only reads the documents of its labels
+			acl, err := env.tenantACL()
+			if err != nil {
+				return failure("get chunk error", apierr.New(apierr.Config, "config", err.Error())), nil
+			}
+			hits, err := rag.GetChunks(cur.Refs, acl)
+			if err != nil {
+				log.Printf("Get chunk error: %v", err)
+				return failure("get chunk error", env.describe(err)), nil
+			}
+			missing := len(cur.Refs) - len(hits)
+			n := fitKB(hits, cur.MaxKB)
+			page := hits[:n]
+			if cur.ReadThrough {
+				rag.ReadThrough(page)
+			}
+			found := len(page)
+			page, usage := env.Quota.Take(quotaCaller(client), env.Config.TenantQuota(env.Config.Access.Tenant), page)
+			if usage != nil && found > 0 && len(page) == 0 {
+				return failure("quota exceeded", apierr.QuotaExceeded("The search quota of this session is used up until "+usage.ResetsAt)), nil
+			}
+
+			msg := fmt.Sprintf("Returning %d chunks", len(page))
+			payload := map[string]any{
+				"chunks": page,
+				"count":  len(page),
+			}
+			// Chunks the quota held back stay in the cursor
+			if rest := hits[len(page):]; len(rest) > 0 {
+				next := chunkCursor{MaxKB: cur.MaxKB, ReadThrough: cur.ReadThrough}
+				for _, h := range rest {
+					next.Refs = append(next.Refs, ragvec.ChunkRef{Path: h.Path, Position: h.Position})
+				}
+				payload["next_cursor"] = next.encode()
+				payload["remaining"] = len(rest)
+				msg += fmt.Sprintf(" (%d more, pass next_cursor)", len(rest))
+			}
+			if missing > 0 {
+				payload["missing"] = missing
+				msg += fmt.Sprintf(" (%d no longer indexed or not readable)", missing)
+			}
+			if usage != nil {
+				payload["quota"] = usage
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+)
+
+// confirmSchema is the form of a confirmation: one checkbox.
+var confirmSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"confirm": map[string]any{
+			"type":        "boolean",
+			"title":       "Proceed",
+			"description": "Check to carry out the operation",
+		},
+	},
+	"required": []string{"confirm"},
+}
+
+// confirm asks the user of client whether to carry out op, described by
+// the message describe returns, when tools.confirm asks for it. It returns
+// nil to proceed and the failed result to return otherwise. Calls without
+// a client (the CLI) are not asked about.
+func (e *Env) confirm(client Client, op string, describe func() string) *mcp.ToolsCallResult {
+	cc := e.confirmConfig()
+	if client == nil || !cc.Confirms(op) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cc.TimeoutSec)*time.Second)
+	defer cancel()
+	res, err := client.Elicit(ctx, mcp.ElicitParams{Message: describe(), RequestedSchema: confirmSchema})
+	switch {
+	case errors.Is(err, mcp.ErrElicitationUnsupported):
+		if cc.Mode != "require" {
+			return nil
+		}
+		d := apierr.New(apierr.Config, "confirmation", "tools.confirm requires confirming "+op+" but the client does not support elicitation")
+		d.Hint = "Run the operation from a client that supports MCP elicitation (protocol 2025-06-18), or from the CLI"
+		return failure("not confirmed", d)
+	case errors.Is(err, context.DeadlineExceeded):
+		return refused(fmt.Sprintf("the user did not answer within %d seconds", cc.TimeoutSec))
+	case err != nil:
+		log.Printf("Confirmation of %s failed: %v", op, err)
+		return refused("asking the user failed: " + err.Error())
+	}
+	if res.Action != "accept" {
+		return refused("the user chose " + res.Action)
+	}
+	if ok, _ := res.Content["confirm"].(bool); !ok {
+		return refused("the user did not check confirm")
+	}
+	return nil
+}
+
+// refused is the result of an operation the user did not confirm.
+func refused(details string) *mcp.ToolsCallResult {
+	d := apierr.New(apierr.Limit, "confirmation", details)
+	d.Hint = "The operation was not carried out; only retry when the user asks for it again"
+	return failure("not confirmed", d)
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/connectors"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/webhook"
+)
+
+func ragConnectorSyncTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_connector_sync",
+		Description: "Index the documents of a configured connector (e.g. a Confluence space or a Slack export). Only documents changed since the last sync are fetched; a full sync also removes documents deleted at the source.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"connector": map[string]any{
+					"type":        "string",
+					"description": "Name of the connector in the connectors config",
+				},
+				"full": map[string]any{
+					"type":        "boolean",
+					"description": "List every document instead of asking for recent changes, and drop deleted ones",
+					"default":     false,
+				},
+				"tags": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Labels stored on every chunk written; filter on them with rag_search tags_any/tags_all",
+				},
+				"acl": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Access labels (teams, groups) allowed to read the documents; none = everyone",
+				},
+				"ttl": map[string]any{
+					"type":        "string",
+					"description": "Delete the written chunks after this long, e.g. 36h or 7d; default: keep",
+				},
+			},
+			"required": []string{"connector"},
+		},
+		OutputSchema: object(map[string]any{
+			"connector": field("string", ""),
+			"full":      field("boolean", ""),
+			"listed":    field("integer", "Documents listed by the source"),
+			"indexed":   field("integer", "Documents new or changed since the last sync"),
+			"unchanged": field("integer", ""),
+			"removed":   field("integer", "Documents gone from the source (full syncs)"),
+			"chunks":    field("integer", "Chunks written"),
+			"errors":    stringList,
+			"status":    statusField,
+			"message":   field("string", ""),
+		}, "connector", "indexed", "chunks", "status", "message"),
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Please ensure Qdrant vector database is running"), nil
+			}
+			name := args.String("connector")
+			conf, ok := connectors.Find(env.Config, name)
+			if !ok {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid(fmt.Sprintf("Unknown connector %q; configure it under connectors", name))}
+			}
+			ttl, err := ragvec.ParseTTL(args.String("ttl"))
+			if err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid(err.Error())}
+			}
+			acl, err := ragvec.NormalizeACL(args.Strings("acl"))
+			if err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid(err.Error())}
+			}
+			full := args.Bool("full", false)
+
+			log.Printf("Syncing connector %s (type: %s, full: %v)", name, conf.Type, full)
+			start := time.Now()
+			report := &ragvec.IngestReport{}
+			res, err := rag.SyncConnector(conf, full, ragvec.IngestOptions{Tags: args.Strings("tags"), ACL: acl, TTL: ttl, Report: report})
+			errText := env.errText(err)
+			env.Hooks.Notify(webhook.Event{Event: "index", Source: "mcp", Count: res.Chunks,
+				Details: map[string]any{"connector": name, "full": res.Full}}, start, errText)
+			if err != nil {
+				log.Printf("Connector sync error: %v", err)
+				return indexFailure("sync error", apierr.Of(err, errText), res.Chunks, report), nil
+			}
+
+			kind := "Incremental"
+			if res.Full {
+				kind = "Full"
+			}
+			msg := fmt.Sprintf("%s sync of %s: %d documents indexed (%d chunks), %d unchanged, %d removed",
+				kind, name, res.Indexed, res.Chunks, res.Unchanged, res.Removed)
+			if len(res.Errors) > 0 {
+				msg += fmt.Sprintf(", %d failed", len(res.Errors))
+			}
+			payload := map[string]any{
+				"connector": res.Connector,
+				"full":      res.Full,
+				"listed":    res.Listed,
+				"indexed":   res.Indexed,
+				"unchanged": res.Unchanged,
+				"removed":   res.Removed,
+				"chunks":    res.Chunks,
+				"errors":    res.Errors,
+				"status":    "success",
+				"message":   msg,
+			}
+			msg += reportContentChecks(payload, report)
+			msg += reportBatches(payload, report)
+			payload["message"] = msg
+			return result(msg, payload), nil
+		},
+	}
+}
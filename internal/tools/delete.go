@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/webhook"
+)
+
+func ragDeleteTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_delete",
+		Description: "Delete indexed chunks. Use either 'all' or 'project'; dry_run previews what would be removed. Deleted chunks stay restorable with rag_undelete during the retention window.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"all": map[string]any{
+					"type":        "boolean",
+					"description": "Delete all chunks in the collection",
+					"default":     false,
+				},
+				"project": map[string]any{
+					"type":        "string",
+					"description": "Delete chunks for a specific project (parent directory)",
+					"default":     "",
+				},
+				"dry_run": map[string]any{
+					"type":        "boolean",
+					"description": "Only report how many chunks and which files and projects would be deleted",
+					"default":     false,
+				},
+			},
+		},
+		OutputSchema: object(deleteProps("deleted", "Chunks deleted, or that would be with dry_run"), "deleted", "all", "project", "dry_run", "status"),
+		SessionHandler: func(args Args, client Client) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			all := args.Bool("all", false)
+			proj := args.String("project")
+			if !all && strings.TrimSpace(proj) == "" {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("Provide either all=true or a non-empty project")}
+			}
+			if args.Bool("dry_run", false) {
+				if all {
+					proj = ""
+				}
+				preview, err := rag.PreviewDelete(proj)
+				if err != nil {
+					log.Printf("Delete preview error: %v", err)
+					return failure("delete error", env.describe(err)), nil
+				}
+				msg := fmt.Sprintf("Would delete %d chunks from %d files in %d projects", preview.Points, preview.FileCount, len(preview.Projects))
+				payload := map[string]any{
+					"deleted":    preview.Points,
+					"all":        all,
+					"project":    proj,
+					"projects":   preview.Projects,
+					"files":      preview.Files,
+					"file_count": preview.FileCount,
+					"dry_run":    true,
+					"status":     "success",
+				}
+				return result(msg, payload), nil
+			}
+			op := "delete_project"
+			if all {
+				op = "delete_all"
+			}
+			describe := func() string {
+				what, scope := "every chunk in the collection", ""
+				if !all {
+					what, scope = fmt.Sprintf("the project '%s'", proj), proj
+				}
+				if p, err := rag.PreviewDelete(scope); err == nil {
+					what += fmt.Sprintf(" (%d chunks from %d files in %d projects)", p.Points, p.FileCount, len(p.Projects))
+				}
+				return "An agent asks to delete " + what + ". Proceed?"
+			}
+			if res := env.confirm(client, op, describe); res != nil {
+				return res, nil
+			}
+			var del int
+			var err error
+			start := time.Now()
+			if all {
+				del, err = rag.DeleteAll()
+			} else {
+				del, err = rag.DeleteProject(proj)
+			}
+			event := webhook.Event{Event: "delete", Source: "mcp", Count: del,
+				Details: map[string]any{"all": all, "project": proj}}
+			errText := env.errText(err)
+			env.Hooks.Notify(event, start, errText)
+			if err != nil {
+				log.Printf("Delete error: %v", err)
+				return failure("delete error", apierr.Of(err, errText)), nil
+			}
+			msg := fmt.Sprintf("Deleted %d chunks", del)
+			if !all {
+				msg += fmt.Sprintf(" in project '%s'", proj)
+			}
+			payload := map[string]any{
+				"deleted": del,
+				"all":     all,
+				"project": proj,
+				"dry_run": false,
+				"status":  "success",
+			}
+			if keep := rag.SoftDeleteRetention(); keep > 0 && del > 0 {
+				until := time.Now().Add(keep).UTC().Format(time.RFC3339)
+				msg += fmt.Sprintf("; restorable with rag_undelete until %s", until)
+				payload["restorable_until"] = until
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
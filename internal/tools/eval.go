@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+func ragEvalTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_eval",
+		Description: "Measure retrieval quality: run the queries of a JSONL file of {query, expected_paths} cases and report recall@k, MRR and nDCG@k for each search configuration.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"file": map[string]any{
+					"type":        "string",
+					"description": "Path of the JSONL evaluation file, relative to the server's search.eval_dir; one {\"query\", \"expected_paths\": [...]} object per line, optional project/project_prefix",
+				},
+				"k": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"maximum":     50,
+					"default":     10,
+					"description": "Cut-off rank for the metrics",
+				},
+				"configs": map[string]any{
+					"type":        "array",
+					"description": "Search configurations to compare; omitted fields use the server defaults. Default: a single configuration with the server defaults",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"name":        map[string]any{"type": "string"},
+							"hnsw_ef":     map[string]any{"type": "integer", "minimum": 0},
+							"exact":       map[string]any{"type": "boolean"},
+							"consistency": map[string]any{"type": "string"},
+							"timeout_sec": map[string]any{"type": "integer", "minimum": 0},
+						},
+					},
+				},
+				"details": map[string]any{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Include per-query results (retrieved and missed paths)",
+				},
+			},
+			"required": []string{"file"},
+		},
+		OutputSchema: object(map[string]any{
+			"file":  field("string", ""),
+			"cases": field("integer", ""),
+			"k":     field("integer", ""),
+			"reports": arrayOf(object(map[string]any{
+				"config":         field("string", ""),
+				"params":         field("object", "Search parameters of the configuration"),
+				"k":              field("integer", ""),
+				"cases":          field("integer", ""),
+				"errors":         field("integer", ""),
+				"recall_at_k":    field("number", ""),
+				"mrr":            field("number", ""),
+				"ndcg_at_k":      field("number", ""),
+				"avg_latency_ms": field("number", ""),
+				"queries": arrayOf(object(map[string]any{
+					"query":           field("string", ""),
+					"recall":          field("number", ""),
+					"reciprocal_rank": field("number", ""),
+					"ndcg":            field("number", ""),
+					"retrieved":       stringList,
+					"missed":          stringList,
+					"error":           field("string", ""),
+				}, "query")),
+			}, "config", "recall_at_k", "mrr", "ndcg_at_k")),
+			"index": field("object", "Index settings the queries ran against"),
+		}, "file", "cases", "k", "reports"),
+		ReadOnly: true,
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				log.Println("RAG evaluation requested but RAG system not initialized")
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			file := strings.TrimSpace(args.String("file"))
+			if file == "" {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("file is required")}
+			}
+			path, err := env.evalFile(file)
+			if err != nil {
+				return nil, err
+			}
+			k := 10
+			if v, ok := args.Number("k"); ok && v >= 1 && v <= 50 {
+				k = int(v)
+			}
+			cases, err := ragvec.LoadEvalCases(path)
+			if err != nil {
+				return failure("invalid evaluation file", apierr.Invalid(err.Error())), nil
+			}
+			var configs []ragvec.EvalConfig
+			if list, ok := args["configs"].([]any); ok {
+				for _, item := range list {
+					m, ok := item.(map[string]any)
+					if !ok {
+						return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("configs must be an array of objects")}
+					}
+					c := Args(m)
+					params, err := overrideParams(rag.DefaultSearchParams(), c)
+					if err != nil {
+						return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("configs: " + err.Error())}
+					}
+					configs = append(configs, ragvec.EvalConfig{Name: c.String("name"), Params: params})
+				}
+			}
+			log.Printf("Evaluating %d queries from %s (k=%d, %d configs)", len(cases), file, k, max(len(configs), 1))
+			// A tenant session only searches its own documents
+			acl, err := env.tenantACL()
+			if err != nil {
+				return failure("evaluation error", apierr.New(apierr.Config, "config", err.Error())), nil
+			}
+			reports := rag.Evaluate(cases, k, configs, args.Bool("details", false), acl)
+			payload := map[string]any{
+				"file":    file,
+				"cases":   len(cases),
+				"k":       k,
+				"reports": reports,
+				"index": map[string]any{
+					"provider":       env.Config.Embedding.Provider,
+					"chunk_size":     env.Config.Indexing.ChunkSize,
+					"chunk_overlap":  env.Config.Indexing.ChunkOverlap,
+					"merge_adjacent": env.Config.Search.MergeAdjacent,
+				},
+			}
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "Evaluated %d queries at k=%d", len(cases), k)
+			for _, r := range reports {
+				fmt.Fprintf(&sb, "\n%s: recall@%d=%.3f mrr=%.3f ndcg@%d=%.3f", r.Config, k, r.RecallAtK, r.MRR, k, r.NDCG)
+				if r.Errors > 0 {
+					fmt.Fprintf(&sb, " (%d errors)", r.Errors)
+				}
+			}
+			return result(sb.String(), payload), nil
+		},
+	}
+}
+
+// evalFile resolves the file argument of rag_eval under search.eval_dir.
+// Paths leaving the directory, also through symlinks, are refused, so the
+// tool cannot read other files of the server.
+func (e *Env) evalFile(file string) (string, error) {
+	dir := strings.TrimSpace(e.Config.Search.EvalDir)
+	if dir == "" {
+		d := apierr.New(apierr.Config, "config", "search.eval_dir is not set, so rag_eval reads no files")
+		return "", &Error{Code: d.Code, Message: "evaluation disabled", Data: d}
+	}
+	abs, err := filepath.Abs(dir)
+	root := abs
+	if err == nil {
+		root, err = filepath.EvalSymlinks(abs)
+	}
+	if err != nil {
+		d := apierr.New(apierr.Config, "config", "search.eval_dir is not readable: "+e.errText(err))
+		return "", &Error{Code: d.Code, Message: "evaluation disabled", Data: d}
+	}
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	// Checked before and after resolving symlinks, so that errors say
+	// nothing about files outside the directory
+	outside := &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("file must name a file under search.eval_dir")}
+	if clean := filepath.Clean(path); !within(abs, clean) && !within(root, clean) {
+		return "", outside
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("no such evaluation file: " + file)}
+	}
+	if err != nil || !within(root, path) {
+		return "", outside
+	}
+	return path, nil
+}
+
+// within reports whether path is root or below it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
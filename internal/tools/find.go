@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+func ragFindFilesTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_find_files",
+		Description: "Find indexed files by path glob, filename substring, project, and file type using metadata only (no embeddings). Returns one entry per file with its chunk count.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"glob": map[string]any{
+					"type":        "string",
+					"description": "Path glob, e.g. \"payments/**\" or \"**/*.md\" (* stays within a directory, ** spans directories)",
+					"default":     "",
+				},
+				"name": map[string]any{
+					"type":        "string",
+					"description": "Case-insensitive substring of the file name",
+					"default":     "",
+				},
+				"project": map[string]any{
+					"type":        "string",
+					"description": "Exact project name (parent folder)",
+					"default":     "",
+				},
+				"file_type": map[string]any{
+					"type":        "string",
+					"enum":        []string{"", "documentation", "code", "config", "database", "web", "other"},
+					"description": "Restrict to a file type category",
+					"default":     "",
+				},
+				"offset": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"default":     0,
+					"description": "Pagination offset",
+				},
+				"limit": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"maximum":     1000,
+					"default":     100,
+					"description": "Max number of files to return",
+				},
+			},
+		},
+		OutputSchema: object(withFilter(pageProps("files", object(map[string]any{
+			"path":      field("string", ""),
+			"basename":  field("string", ""),
+			"project":   field("string", ""),
+			"file_type": field("string", ""),
+			"file_hash": field("string", ""),
+			"chunks":    field("integer", ""),
+		}, "path", "project"))), "files", "count", "total"),
+		ReadOnly: true,
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				log.Println("RAG file search requested but RAG system not initialized")
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			fq := ragvec.FileQuery{
+				Glob:     args.String("glob"),
+				Name:     args.String("name"),
+				Project:  args.String("project"),
+				FileType: args.String("file_type"),
+			}
+			// A tenant session only finds its own documents
+			var err error
+			if fq.ACL, err = env.tenantACL(); err != nil {
+				return failure("find files error", apierr.New(apierr.Config, "config", err.Error())), nil
+			}
+			var offset int
+			limit := 100
+			if v, ok := args.Number("offset"); ok && v >= 0 {
+				offset = int(v)
+			}
+			if v, ok := args.Number("limit"); ok && v >= 1 && v <= 1000 {
+				limit = int(v)
+			}
+			files, total, err := rag.FindFiles(fq, offset, limit)
+			if err != nil {
+				log.Printf("File search error: %v", err)
+				return failure("find files error", env.describe(err)), nil
+			}
+			payload := map[string]any{
+				"files":  files,
+				"count":  len(files),
+				"total":  total,
+				"offset": offset,
+				"limit":  limit,
+				"filter": map[string]any{
+					"glob":      fq.Glob,
+					"name":      fq.Name,
+					"project":   fq.Project,
+					"file_type": fq.FileType,
+				},
+			}
+			return result(fmt.Sprintf("Found %d matching files (showing %d)", total, len(files)), payload), nil
+		},
+	}
+}
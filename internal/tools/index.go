@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/pathglob"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/webhook"
+)
+
+func ragIndexTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_index",
+		Description: fmt.Sprintf("Index documents from a directory into Qdrant vector database. Supports documentation (%v) and code files (%v).", env.Config.Indexing.FileTypes.Documentation, env.Config.Indexing.FileTypes.Code),
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"dir": map[string]any{
+					"type":        "string",
+					"description": "Directory path containing documents to index",
+					"default":     "./docs",
+				},
+				"include_code": map[string]any{
+					"type":        "boolean",
+					"description": "Whether to include code files in indexing",
+					"default":     false,
+				},
+				"tags": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Labels stored on every chunk of this run; filter on them with rag_search tags_any/tags_all",
+				},
+				"acl": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Access labels (teams, groups) allowed to read the indexed files, added to those of access.rules; none = everyone",
+				},
+				"ttl": map[string]any{
+					"type":        "string",
+					"description": "Delete this run's chunks after this long, e.g. 36h or 7d (for meeting notes, scraped pages); default: keep",
+				},
+				"prune_missing": map[string]any{
+					"type":        "boolean",
+					"description": "Delete the chunks of files the previous run of this directory indexed that no longer exist on disk",
+					"default":     env.Config.Indexing.PruneMissing,
+				},
+				"include": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Only index files matching these patterns relative to dir, whatever their extension, e.g. \"docs/**/*.md\" or \"api/**/*.yaml\"; same syntax as exclude",
+				},
+				"exclude": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Extra patterns to skip on top of indexing.exclude, relative to dir: globs with ** (\"**/testdata/**\", \"*.min.js\", \"*_generated.go\") or \"re:<regexp>\"",
+				},
+			},
+		},
+		OutputSchema: object(indexProps(map[string]any{
+			"directory":    field("string", ""),
+			"include_code": field("boolean", ""),
+			"tags":         map[string]any{"type": []string{"array", "null"}, "items": field("string", "")},
+			"include":      stringList,
+			"exclude":      stringList,
+			"ttl":          field("string", "Time to live of the chunks, as a Go duration"),
+			"pruned": object(map[string]any{
+				"chunks":     field("integer", ""),
+				"files":      stringList,
+				"file_count": field("integer", ""),
+			}),
+			"config":       field("object", "Chunking and embedding settings of the run"),
+			"reused_files": field("integer", "Unchanged files whose chunks came from indexing.chunk_cache"),
+		}), "indexed", "directory", "status", "message"),
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				log.Println("RAG index requested but RAG system not initialized")
+				return errRAGNotInitialized("Please ensure Qdrant vector database is running"), nil
+			}
+
+			dir := "./docs"
+			if v := args.String("dir"); strings.TrimSpace(v) != "" {
+				dir = v
+			}
+			includeCode := args.Bool("include_code", false)
+			tags := ragvec.NormalizeTags(args.Strings("tags"))
+			ttl, err := ragvec.ParseTTL(args.String("ttl"))
+			if err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid(err.Error())}
+			}
+			acl, err := ragvec.NormalizeACL(args.Strings("acl"))
+			if err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid(err.Error())}
+			}
+			prune := args.Bool("prune_missing", env.Config.Indexing.PruneMissing)
+			include := args.Strings("include")
+			if _, err := pathglob.Compile(include); err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("include: " + err.Error())}
+			}
+			exclude := args.Strings("exclude")
+			if _, err := pathglob.Compile(exclude); err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("exclude: " + err.Error())}
+			}
+
+			log.Printf("Starting document indexing from directory: %s (include_code: %v, tags: %v, ttl: %v)", dir, includeCode, tags, ttl)
+			start := time.Now()
+			var pruned ragvec.PruneResult
+			if prune {
+				pruned, err = rag.PruneMissing(dir)
+				errText := env.errText(err)
+				if pruned.FileCount > 0 || err != nil {
+					env.Hooks.Notify(webhook.Event{Event: "delete", Source: "mcp", Count: pruned.Chunks,
+						Details: map[string]any{"directory": dir, "missing_files": pruned.FileCount}}, start, errText)
+				}
+				if err != nil {
+					log.Printf("Prune error: %v", err)
+					return failure("prune error", apierr.Of(err, errText)), nil
+				}
+			}
+			var warnings []string
+			report := &ragvec.IngestReport{}
+			n, err := rag.IngestDocsWith(dir, includeCode, ragvec.IngestOptions{Tags: tags, ACL: acl, TTL: ttl, Include: include, Exclude: exclude,
+				Report: report, OnWarning: func(msg string) { warnings = append(warnings, msg) }})
+			event := webhook.Event{Event: "index", Source: "mcp", Count: n,
+				Details: map[string]any{"directory": dir, "include_code": includeCode, "tags": tags}}
+			errText := env.errText(err)
+			env.Hooks.Notify(event, start, errText)
+			var limitErr *ragvec.LimitError
+			if errors.As(err, &limitErr) {
+				log.Printf("Index stopped: %v", err)
+				return failure("index limit exceeded", apierr.New(apierr.Limit, "indexing", fmt.Sprintf("%s (%d chunks were indexed before stopping)", errText, n))), nil
+			}
+			if err != nil {
+				log.Printf("Index error: %v", err)
+				return indexFailure("index error", apierr.Of(err, errText), n, report), nil
+			}
+
+			log.Printf("Successfully indexed %d document chunks", n)
+			msg := fmt.Sprintf("Successfully indexed %d document chunks from %s", n, dir)
+			payload := map[string]any{
+				"indexed":      n,
+				"directory":    dir,
+				"include_code": includeCode,
+				"tags":         tags,
+				"status":       "success",
+				"message":      msg,
+				"config": map[string]any{
+					"chunk_size":    env.Config.Indexing.ChunkSize,
+					"chunk_overlap": env.Config.Indexing.ChunkOverlap,
+					"batch_size":    env.Config.Indexing.BatchSize,
+					"provider":      env.Config.Embedding.Provider,
+				},
+			}
+			if len(include) > 0 {
+				payload["include"] = include
+			}
+			if len(exclude) > 0 {
+				payload["exclude"] = exclude
+			}
+			if len(warnings) > 0 {
+				payload["warnings"] = warnings
+			}
+			if report.ReusedFiles > 0 {
+				payload["reused_files"] = report.ReusedFiles
+				msg += fmt.Sprintf(" (%d unchanged files taken from the chunk cache)", report.ReusedFiles)
+			}
+			msg += reportContentChecks(payload, report)
+			msg += reportBatches(payload, report)
+			payload["message"] = msg
+			if ttl > 0 {
+				payload["ttl"] = ttl.String()
+				msg += fmt.Sprintf(" (expire in %s)", ttl)
+				payload["message"] = msg
+			}
+			if prune {
+				payload["pruned"] = pruned
+				if pruned.FileCount > 0 {
+					msg += fmt.Sprintf("; removed %d chunks of %d files missing from disk", pruned.Chunks, pruned.FileCount)
+					payload["message"] = msg
+				}
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
+
+// reportContentChecks adds what the secrets check and content filters of an
+// index run changed to its result payload and returns a note for the result
+// message.
+func reportContentChecks(payload map[string]any, rep *ragvec.IngestReport) string {
+	if rep.Empty() {
+		return ""
+	}
+	payload["content_checks"] = rep
+	var notes []string
+	if rep.SecretChunks > 0 {
+		verb := map[string]string{"mask": "masked", "skip": "skipped", "flag": "flagged"}[rep.SecretAction]
+		notes = append(notes, fmt.Sprintf("secrets %s in %d chunks", verb, rep.SecretChunks))
+	}
+	if rep.RedactedChunks > 0 {
+		notes = append(notes, fmt.Sprintf("%d chunks redacted", rep.RedactedChunks))
+	}
+	if rep.DroppedChunks > 0 {
+		notes = append(notes, fmt.Sprintf("%d chunks dropped by filters", rep.DroppedChunks))
+	}
+	return " (" + strings.Join(notes, "; ") + ")"
+}
+
+// reportBatches adds how the batches of a run were written to payload and
+// returns a note for the message when some were retried or failed.
+func reportBatches(payload map[string]any, rep *ragvec.IngestReport) string {
+	b := rep.Batches
+	payload["batches"] = b
+	var notes []string
+	if b.Retried > 0 {
+		notes = append(notes, fmt.Sprintf("%d of %d batches retried", b.Retried, b.Written))
+	}
+	if len(b.Failed) > 0 {
+		notes = append(notes, fmt.Sprintf("%d batches failed", len(b.Failed)))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(notes, "; ") + ")"
+}
+
+// indexFailure reports a run stopped by an error with what it wrote before.
+// The failed batch is named so the caller can tell what is missing; running
+// again is safe since chunks already written are overwritten, not
+// duplicated.
+func indexFailure(message string, d apierr.Data, indexed int, rep *ragvec.IngestReport) *mcp.ToolsCallResult {
+	payload := struct {
+		failurePayload
+		Indexed int                `json:"indexed"`
+		Batches ragvec.BatchReport `json:"batches"`
+	}{failurePayload{Status: "error", Error: message, Data: d}, indexed, rep.Batches}
+	text := fmt.Sprintf("%s: %s", message, d.Details)
+	if f := rep.Batches.LastFailed(); f != nil {
+		text += fmt.Sprintf(" (batch %d of %d chunks failed after %d attempts; %d chunks in %d batches were indexed before it, re-run to complete)",
+			f.Batch, f.Chunks, f.Attempts, indexed, rep.Batches.Written)
+	}
+	res := result(text, payload)
+	res.IsError = true
+	return res
+}
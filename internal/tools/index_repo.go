@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/webhook"
+)
+
+func ragIndexRepoTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_index_repo",
+		Description: "Shallow-clone a git repository at a branch or tag, index it like rag_index and remove the clone. Chunks carry repo, ref and commit; re-indexing the same repo and ref replaces its chunks.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"url": map[string]any{
+					"type":        "string",
+					"description": "Git URL, e.g. https://github.com/spf13/cobra.git or git@github.com:spf13/cobra.git",
+				},
+				"ref": map[string]any{
+					"type":        "string",
+					"description": "Branch or tag to index (default: the repository's default branch)",
+					"default":     "",
+				},
+				"include_code": map[string]any{
+					"type":        "boolean",
+					"description": "Whether to include code files in indexing",
+					"default":     false,
+				},
+				"project": map[string]any{
+					"type":        "string",
+					"description": "Project for the indexed files (default: the repository name)",
+					"default":     "",
+				},
+				"tags": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Labels stored on every chunk; filter on them with rag_search tags_any/tags_all",
+				},
+				"acl": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Access labels (teams, groups) allowed to read the repository, added to those of access.rules; none = everyone",
+				},
+				"ttl": map[string]any{
+					"type":        "string",
+					"description": "Delete the repository's chunks after this long, e.g. 36h or 7d; default: keep",
+				},
+			},
+			"required": []string{"url"},
+		},
+		OutputSchema: object(indexProps(map[string]any{
+			"repo":         field("string", ""),
+			"ref":          field("string", "Branch or tag indexed"),
+			"commit":       field("string", ""),
+			"root":         field("string", "Prefix of the chunk paths: host/path@ref"),
+			"project":      field("string", ""),
+			"removed":      field("integer", "Chunks of an earlier run whose files are gone or changed"),
+			"include_code": field("boolean", ""),
+			"ttl":          field("string", ""),
+		}), "indexed", "repo", "commit", "status", "message"),
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Please ensure Qdrant vector database is running"), nil
+			}
+			repo := args.String("url")
+			if repo == "" {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("Provide the repository 'url'")}
+			}
+			ttl, err := ragvec.ParseTTL(args.String("ttl"))
+			if err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid(err.Error())}
+			}
+			acl, err := ragvec.NormalizeACL(args.Strings("acl"))
+			if err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid(err.Error())}
+			}
+			includeCode := args.Bool("include_code", false)
+			report := &ragvec.IngestReport{}
+			opts := ragvec.IngestOptions{Tags: args.Strings("tags"), ACL: acl, TTL: ttl, Project: args.String("project"), Report: report}
+
+			log.Printf("Indexing repository %s (ref: %q, include_code: %v)", ragvec.RedactRepoURL(repo), args.String("ref"), includeCode)
+			start := time.Now()
+			res, err := rag.IngestRepo(repo, args.String("ref"), includeCode, opts)
+			errText := env.errText(err)
+			env.Hooks.Notify(webhook.Event{Event: "index", Source: "mcp", Count: res.Chunks,
+				Details: map[string]any{"repo": res.Repo, "ref": res.Ref, "commit": res.Commit}}, start, errText)
+			if err != nil {
+				log.Printf("Repository index error: %v", err)
+				return indexFailure("index error", apierr.Of(err, errText), res.Chunks, report), nil
+			}
+
+			msg := fmt.Sprintf("Indexed %d chunks from %s at %s (%.12s)", res.Chunks, res.Repo, res.Ref, res.Commit)
+			if res.Removed > 0 {
+				msg += fmt.Sprintf(", removed %d stale chunks", res.Removed)
+			}
+			payload := map[string]any{
+				"indexed":      res.Chunks,
+				"repo":         res.Repo,
+				"ref":          res.Ref,
+				"commit":       res.Commit,
+				"root":         res.Root,
+				"project":      res.Project,
+				"removed":      res.Removed,
+				"include_code": includeCode,
+				"status":       "success",
+				"message":      msg,
+			}
+			if ttl > 0 {
+				payload["ttl"] = ttl.String()
+			}
+			msg += reportContentChecks(payload, report)
+			msg += reportBatches(payload, report)
+			payload["message"] = msg
+			return result(msg, payload), nil
+		},
+	}
+}
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/webhook"
+)
+
+func ragIndexURLTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_index_url",
+		Description: "Fetch HTTP(S) pages and index their text. By default only the given pages are fetched; with crawl, a docs site is indexed from seed pages or sitemaps. HTML is cleaned to readable text and re-indexing a URL replaces its chunks.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"urls": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"minItems":    1,
+					"description": "Page URLs to fetch and index; with crawl, seed pages or XML sitemaps",
+				},
+				"crawl": map[string]any{
+					"type":        "boolean",
+					"description": "Follow links on the seeds' origins breadth-first, respecting robots.txt",
+					"default":     false,
+				},
+				"max_pages": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Pages to fetch at most when crawling (default and upper bound: indexing.urls.max_pages)",
+				},
+				"project": map[string]any{
+					"type":        "string",
+					"description": "Project for the indexed pages (default: each page's host)",
+					"default":     "",
+				},
+				"tags": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Labels stored on every chunk; filter on them with rag_search tags_any/tags_all",
+				},
+				"acl": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Access labels (teams, groups) allowed to read the pages; none = everyone",
+				},
+				"ttl": map[string]any{
+					"type":        "string",
+					"description": "Delete the pages' chunks after this long, e.g. 36h or 7d; default: keep",
+				},
+			},
+			"required": []string{"urls"},
+		},
+		OutputSchema: object(indexProps(map[string]any{
+			"pages": arrayOf(object(map[string]any{
+				"url":          field("string", ""),
+				"title":        field("string", ""),
+				"content_type": field("string", ""),
+				"chunks":       field("integer", ""),
+				"error":        field("string", "Why the page could not be fetched"),
+				"skipped":      field("string", "Why the page was not indexed"),
+			}, "url", "chunks")),
+			"failed":            field("integer", "Pages that could not be fetched"),
+			"skipped":           field("integer", "Pages fetched but not indexed"),
+			"blocked_by_robots": field("integer", "URLs robots.txt disallows (crawl)"),
+			"unvisited":         field("integer", "URLs left when max_pages was reached (crawl)"),
+		}), "indexed", "pages", "status", "message"),
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Please ensure Qdrant vector database is running"), nil
+			}
+			var urls []string
+			for _, u := range args.Strings("urls") {
+				if u = strings.TrimSpace(u); u != "" {
+					urls = append(urls, u)
+				}
+			}
+			if len(urls) == 0 {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("Provide at least one URL in 'urls'")}
+			}
+			ttl, err := ragvec.ParseTTL(args.String("ttl"))
+			if err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid(err.Error())}
+			}
+			acl, err := ragvec.NormalizeACL(args.Strings("acl"))
+			if err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid(err.Error())}
+			}
+			report := &ragvec.IngestReport{}
+			opts := ragvec.IngestOptions{Tags: args.Strings("tags"), ACL: acl, TTL: ttl, Project: args.String("project"), Report: report}
+
+			crawl := args.Bool("crawl", false)
+			start := time.Now()
+			var pages []ragvec.URLResult
+			var n int
+			var crawled ragvec.CrawlResult
+			if crawl {
+				maxPages := 0
+				if f, ok := args.Number("max_pages"); ok {
+					maxPages = int(f)
+				}
+				log.Printf("Crawling from %d seed URLs (max_pages: %d)", len(urls), maxPages)
+				crawled, err = rag.CrawlSite(urls, maxPages, opts)
+				pages, n = crawled.Pages, crawled.Indexed
+			} else {
+				log.Printf("Indexing %d URLs", len(urls))
+				pages, n, err = rag.IngestURLs(urls, opts)
+			}
+			errText := env.errText(err)
+			env.Hooks.Notify(webhook.Event{Event: "index", Source: "mcp", Count: n,
+				Details: map[string]any{"urls": urls, "crawl": crawl}}, start, errText)
+			if err != nil {
+				log.Printf("URL index error: %v", err)
+				return indexFailure("index error", apierr.Of(err, errText), n, report), nil
+			}
+			failed, skipped := 0, 0
+			for _, p := range pages {
+				switch {
+				case p.Error != "":
+					failed++
+				case p.Skipped != "":
+					skipped++
+				}
+			}
+			msg := fmt.Sprintf("Indexed %d chunks from %d of %d URLs", n, len(pages)-failed-skipped, len(urls))
+			if crawl {
+				msg = fmt.Sprintf("Crawled %d pages, indexed %d chunks from %d", len(pages), n, len(pages)-failed-skipped)
+			}
+			payload := map[string]any{
+				"indexed": n,
+				"pages":   pages,
+				"failed":  failed,
+				"skipped": skipped,
+				"status":  "success",
+				"message": msg,
+			}
+			if crawl {
+				payload["blocked_by_robots"] = crawled.Blocked
+				payload["unvisited"] = crawled.Unvisited
+			}
+			msg += reportContentChecks(payload, report)
+			msg += reportBatches(payload, report)
+			payload["message"] = msg
+			if len(pages) > 0 && failed == len(pages) {
+				payload["status"] = "error"
+				res := result(msg, payload)
+				res.IsError = true
+				return res, nil
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
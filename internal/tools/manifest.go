@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+)
+
+func ragManifestTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_manifest",
+		Description: "Describe what is indexed: roots, file and chunk counts, provider/model and chunking parameters used, plus warnings when the current config diverges from index time.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"include_hashes": map[string]any{
+					"type":        "boolean",
+					"description": "Include per-file content hashes for each root",
+					"default":     false,
+				},
+			},
+		},
+		OutputSchema: object(map[string]any{
+			"collection": field("string", ""),
+			"updated_at": field("string", "RFC3339"),
+			"roots": arrayOf(object(map[string]any{
+				"root":          field("string", ""),
+				"indexed_at":    field("string", "RFC3339"),
+				"include_code":  field("boolean", ""),
+				"files":         field("integer", ""),
+				"chunks":        field("integer", ""),
+				"provider":      field("string", ""),
+				"model":         field("string", ""),
+				"dim":           field("integer", ""),
+				"chunk_size":    field("integer", ""),
+				"chunk_overlap": field("integer", ""),
+				"file_hashes":   map[string]any{"type": "object", "additionalProperties": field("string", "")},
+				"tags":          stringList,
+				"acl":           stringList,
+				"expires_at":    field("string", "RFC3339 expiry of a run indexed with a ttl"),
+			}, "root", "files", "chunks")),
+			"totals": object(map[string]any{
+				"roots":  field("integer", ""),
+				"files":  field("integer", ""),
+				"chunks": field("integer", ""),
+			}, "roots", "files", "chunks"),
+			"warnings": stringList,
+		}, "collection", "roots", "totals", "warnings"),
+		ReadOnly: true,
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			m := rag.Manifest()
+			withHashes := args.Bool("include_hashes", false)
+			roots := make([]string, 0, len(m.Roots))
+			for k := range m.Roots {
+				roots = append(roots, k)
+			}
+			sort.Strings(roots)
+			entries := make([]any, 0, len(roots))
+			files, chunks := 0, 0
+			for _, k := range roots {
+				e := m.Roots[k]
+				files += e.Files
+				chunks += e.Chunks
+				if !withHashes {
+					e.FileHashes = nil
+				}
+				entries = append(entries, e)
+			}
+			drift := rag.ConfigDrift()
+			if drift == nil {
+				drift = []string{}
+			}
+			payload := map[string]any{
+				"collection": m.Collection,
+				"updated_at": m.UpdatedAt,
+				"roots":      entries,
+				"totals":     map[string]any{"roots": len(roots), "files": files, "chunks": chunks},
+				"warnings":   drift,
+			}
+			msg := fmt.Sprintf("Manifest: %d indexed roots, %d files, %d chunks", len(roots), files, chunks)
+			if len(drift) > 0 {
+				msg += fmt.Sprintf(" (%d config mismatches)", len(drift))
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
@@ -0,0 +1,153 @@
+package tools
+
+// Output schemas describe the structured results of the tools (MCP
+// outputSchema). They list the fields clients can rely on; results may
+// carry more, so none of them forbids additional properties.
+
+// object is an object schema with props, of which required are always
+// present.
+func object(props map[string]any, required ...string) map[string]any {
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// arrayOf is an array schema of items.
+func arrayOf(items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "items": items}
+}
+
+// field is a schema of JSON type typ ("string", "integer", ...).
+func field(typ, description string) map[string]any {
+	s := map[string]any{"type": typ}
+	if description != "" {
+		s["description"] = description
+	}
+	return s
+}
+
+// nullable is a schema of JSON type typ or null.
+func nullable(typ, description string) map[string]any {
+	s := field(typ, description)
+	s["type"] = []string{typ, "null"}
+	return s
+}
+
+var (
+	stringList = arrayOf(field("string", ""))
+	intList    = arrayOf(field("integer", ""))
+)
+
+// statusField is the "status" of write tools: "success", or "error" for a
+// failed result.
+var statusField = map[string]any{"type": "string", "enum": []string{"success", "error"}}
+
+// hitSchema is a search hit. Summarized results (see rag_search) carry
+// only the required fields, score and section.
+var hitSchema = object(map[string]any{
+	"id":               field("string", "Point ID of the chunk"),
+	"score":            field("number", ""),
+	"path":             field("string", "Path of the chunk's file; virtual:// for text indexed without a file"),
+	"basename":         field("string", ""),
+	"project":          field("string", ""),
+	"file_type":        field("string", ""),
+	"position":         field("integer", "Index of the chunk in its file, -1 for a summary chunk"),
+	"snippet":          field("string", "Preview of text, at most 240 characters"),
+	"text":             field("string", "Text of the chunk"),
+	"kind":             field("string", `"summary", "image", or absent for document text`),
+	"section":          field("string", "Heading the chunk falls under"),
+	"chapter":          field("string", ""),
+	"symbols":          stringList,
+	"version":          field("string", "Changelog version the chunk belongs to"),
+	"release_date":     field("string", ""),
+	"start_time":       field("string", "HH:MM:SS start of a transcript chunk"),
+	"end_time":         field("string", ""),
+	"tags":             stringList,
+	"acl":              stringList,
+	"metadata":         map[string]any{"type": "object", "additionalProperties": field("string", "")},
+	"indexed_at":       field("string", "RFC3339"),
+	"modified_at":      field("string", "RFC3339"),
+	"expires_at":       field("string", "RFC3339"),
+	"start_byte":       field("integer", "Where the chunk starts in its file as indexed"),
+	"end_byte":         field("integer", ""),
+	"file_hash":        field("string", ""),
+	"merged_positions": intList,
+	"model_mismatch":   field("boolean", "Embedded with another provider or model than the query"),
+	"embedding":        field("string", "Provider and model of a mismatched hit"),
+	"tokens":           field("integer", "Estimated size under a token budget"),
+	"source":           field("string", `"file" or "index" under read_through`),
+	"drifted":          field("boolean", "The file changed since indexing"),
+	"explain":          field("object", "Breakdown of the score (explain)"),
+}, "id", "path", "project", "position", "score")
+
+// quotaSchema is the usage of search.quota after a call.
+var quotaSchema = object(map[string]any{
+	"truncated":         field("boolean", "Hits were left out to stay within the quota"),
+	"omitted":           field("integer", ""),
+	"limit":             field("string", `The cap that cut the hits: "max_results" or "max_kb"`),
+	"remaining_results": field("integer", ""),
+	"remaining_bytes":   field("integer", ""),
+	"resets_at":         field("string", "RFC3339 end of the quota window"),
+}, "truncated", "resets_at")
+
+// indexProps are the fields every indexing tool returns.
+func indexProps(props map[string]any) map[string]any {
+	props["indexed"] = field("integer", "Chunks written")
+	props["status"] = statusField
+	props["message"] = field("string", "")
+	props["content_checks"] = field("object", "What the secrets check and content filters changed")
+	props["batches"] = field("object", "Upsert batches written, retried and failed")
+	props["warnings"] = stringList
+	return props
+}
+
+// deleteProps are the fields of rag_delete and rag_undelete results.
+func deleteProps(count, description string) map[string]any {
+	return map[string]any{
+		count:              field("integer", description),
+		"all":              field("boolean", ""),
+		"project":          field("string", ""),
+		"dry_run":          field("boolean", ""),
+		"projects":         map[string]any{"type": "object", "description": "Chunks per project (dry_run)", "additionalProperties": field("integer", "")},
+		"files":            arrayOf(field("string", "")),
+		"file_count":       field("integer", "Number of files (dry_run); files lists the first 100"),
+		"restorable_until": field("string", "RFC3339 end of the soft-delete retention"),
+		"status":           statusField,
+	}
+}
+
+// queryStatSchema is a query of rag_analytics.
+var queryStatSchema = object(map[string]any{
+	"query":          field("string", ""),
+	"count":          field("integer", ""),
+	"avg_hits":       field("number", ""),
+	"avg_best_score": field("number", ""),
+	"last_seen":      field("string", "RFC3339"),
+	"projects":       stringList,
+	"results":        stringList,
+}, "query", "count")
+
+// pageProps are the fields of paged listings.
+func pageProps(key string, items map[string]any) map[string]any {
+	return map[string]any{
+		key:      arrayOf(items),
+		"count":  field("integer", "Items on this page"),
+		"total":  field("integer", "Items matching"),
+		"offset": field("integer", ""),
+		"limit":  field("integer", ""),
+	}
+}
+
+// withFilter adds the filters a listing applied to props.
+func withFilter(props map[string]any) map[string]any {
+	props["filter"] = field("object", "Filters of the listing")
+	return props
+}
+
+// withHint adds a hint on an empty listing to props.
+func withHint(props map[string]any) map[string]any {
+	props["hint"] = field("string", "Why the listing is empty")
+	return props
+}
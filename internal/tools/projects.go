@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+)
+
+func ragProjectsTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_projects",
+		Description: "List detected projects (by parent directory) with total indexed chunks and file count. Supports prefix filter and pagination.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"prefix": map[string]any{
+					"type":        "string",
+					"description": "Filter project names by prefix (case-insensitive)",
+					"default":     "",
+				},
+				"offset": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"default":     0,
+					"description": "Pagination offset",
+				},
+				"limit": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"maximum":     1000,
+					"default":     50,
+					"description": "Max number of projects to return",
+				},
+			},
+		},
+		OutputSchema: object(withFilter(pageProps("projects", object(map[string]any{
+			"project":      field("string", ""),
+			"total_chunks": field("integer", ""),
+			"files":        field("integer", ""),
+		}, "project", "total_chunks"))), "projects", "count", "total"),
+		ReadOnly: true,
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				log.Println("RAG projects requested but RAG system not initialized")
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			prefix := args.String("prefix")
+			var offset, limit int
+			if v, ok := args.Number("offset"); ok && v >= 0 {
+				offset = int(v)
+			}
+			if v, ok := args.Number("limit"); ok && v >= 1 && v <= 1000 {
+				limit = int(v)
+			}
+			// A tenant session only counts the chunks it may read
+			acl, err := env.tenantACL()
+			if err != nil {
+				return failure("projects error", apierr.New(apierr.Config, "config", err.Error())), nil
+			}
+			list, total, err := rag.ListProjectsFiltered(prefix, offset, limit, acl)
+			if err != nil {
+				log.Printf("Projects listing error: %v", err)
+				return failure("projects error", env.describe(err)), nil
+			}
+			payload := map[string]any{
+				"projects": list,
+				"count":    len(list),
+				"total":    total,
+				"offset":   offset,
+				"limit":    limit,
+				"filter":   map[string]any{"prefix": prefix},
+			}
+			return result(fmt.Sprintf("Found %d projects (showing %d)", total, len(list)), payload), nil
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/webhook"
+)
+
+func ragPurgeTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_purge",
+		Description: "Delete chunks indexed before a given time, e.g. leftovers of files no longer re-indexed. Use dry_run to count first.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"indexed_before": map[string]any{
+					"type":        "string",
+					"description": "Delete chunks indexed before this time (RFC3339 or YYYY-MM-DD)",
+				},
+				"project": map[string]any{
+					"type":        "string",
+					"description": "Only purge chunks of this project",
+					"default":     "",
+				},
+				"dry_run": map[string]any{
+					"type":        "boolean",
+					"description": "Only count the chunks that would be deleted",
+					"default":     false,
+				},
+			},
+			"required": []string{"indexed_before"},
+		},
+		OutputSchema: object(map[string]any{
+			"deleted":        field("integer", "Chunks deleted, or that would be with dry_run"),
+			"indexed_before": field("string", "RFC3339"),
+			"project":        field("string", ""),
+			"dry_run":        field("boolean", ""),
+			"status":         statusField,
+		}, "deleted", "indexed_before", "dry_run", "status"),
+		SessionHandler: func(args Args, client Client) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			before, err := ragvec.ParseTime(args.String("indexed_before"))
+			if err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("indexed_before: " + err.Error())}
+			}
+			if before.IsZero() {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("Provide indexed_before")}
+			}
+			proj := args.String("project")
+			dryRun := args.Bool("dry_run", false)
+			if !dryRun {
+				describe := func() string {
+					what := fmt.Sprintf("every chunk indexed before %s", before.UTC().Format(time.RFC3339))
+					if proj != "" {
+						what += fmt.Sprintf(" in project '%s'", proj)
+					}
+					if n, err := rag.PurgeIndexedBefore(before, proj, true); err == nil {
+						what += fmt.Sprintf(" (%d chunks)", n)
+					}
+					return "An agent asks to delete " + what + ". Proceed?"
+				}
+				if res := env.confirm(client, "purge", describe); res != nil {
+					return res, nil
+				}
+			}
+			start := time.Now()
+			n, err := rag.PurgeIndexedBefore(before, proj, dryRun)
+			errText := env.errText(err)
+			if !dryRun {
+				event := webhook.Event{Event: "delete", Source: "mcp", Count: n,
+					Details: map[string]any{"project": proj, "indexed_before": before.UTC().Format(time.RFC3339)}}
+				env.Hooks.Notify(event, start, errText)
+			}
+			if err != nil {
+				log.Printf("Purge error: %v", err)
+				return failure("purge error", apierr.Of(err, errText)), nil
+			}
+			verb := "Deleted"
+			if dryRun {
+				verb = "Would delete"
+			}
+			msg := fmt.Sprintf("%s %d chunks indexed before %s", verb, n, before.UTC().Format(time.RFC3339))
+			if proj != "" {
+				msg += fmt.Sprintf(" in project '%s'", proj)
+			}
+			payload := map[string]any{
+				"deleted":        n,
+				"indexed_before": before.UTC().Format(time.RFC3339),
+				"project":        proj,
+				"dry_run":        dryRun,
+				"status":         "success",
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
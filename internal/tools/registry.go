@@ -0,0 +1,507 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/querylog"
+	"github.com/Rhyanz46/mcp-service/internal/quota"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+	"github.com/Rhyanz46/mcp-service/internal/redact"
+	"github.com/Rhyanz46/mcp-service/internal/webhook"
+)
+
+// Handler executes a tool call. Domain failures (Qdrant or provider errors)
+// are reported as results with IsError set; a returned *Error is reserved for
+// protocol-level problems such as invalid params and maps to a JSON-RPC error.
+type Handler func(args Args) (*mcp.ToolsCallResult, error)
+
+// SessionHandler is a Handler that may talk back to the client of the
+// session the call came from. client is nil for calls from outside an MCP
+// session, such as the CLI.
+type SessionHandler func(args Args, client Client) (*mcp.ToolsCallResult, error)
+
+// Client is what tools may ask of the client that called them.
+type Client interface {
+	// Sample asks the client's model for a completion (MCP sampling). It
+	// returns mcp.ErrSamplingUnsupported when the client did not advertise
+	// sampling.
+	Sample(ctx context.Context, p mcp.CreateMessageParams) (*mcp.CreateMessageResult, error)
+	// Elicit asks the client's user for input (MCP elicitation). It
+	// returns mcp.ErrElicitationUnsupported when the client cannot be
+	// asked.
+	Elicit(ctx context.Context, p mcp.ElicitParams) (*mcp.ElicitResult, error)
+	// Name identifies the session, e.g. "session 2 (127.0.0.1:51234)"
+	Name() string
+}
+
+// clientName is the name of client; "" for calls without a session, such
+// as the CLI.
+func clientName(client Client) string {
+	if client == nil {
+		return ""
+	}
+	return client.Name()
+}
+
+// quotaCaller is the search.quota key of client: each MCP session has its
+// own quota, like each API key or address over HTTP.
+func quotaCaller(client Client) string {
+	if name := clientName(client); name != "" {
+		return "mcp:" + name
+	}
+	return "mcp"
+}
+
+// Tool describes a single MCP tool: its advertised schema and its handler.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema map[string]any
+	// OutputSchema describes the structured result (see output.go)
+	OutputSchema map[string]any
+	Handler      Handler
+	// SessionHandler replaces Handler for tools that talk back to the client
+	SessionHandler SessionHandler
+	// ReadOnly marks tools that never modify the index; only these remain
+	// visible when tools.read_only is set.
+	ReadOnly bool
+}
+
+// maxCompletions is the MCP limit on values returned by completion/complete.
+const maxCompletions = 100
+
+// completion is the completion/complete result of values out of total
+// matches.
+func completion(values []string, total int) mcp.Completion {
+	out := mcp.Completion{Values: values, Total: max(total, len(values))}
+	if out.Values == nil {
+		out.Values = []string{}
+	}
+	if len(out.Values) > maxCompletions {
+		out.Values = out.Values[:maxCompletions]
+	}
+	out.HasMore = out.Total > len(out.Values)
+	return out
+}
+
+// Error is a tool failure carrying a JSON-RPC error code.
+type Error struct {
+	Code    int
+	Message string
+	Data    any
+}
+
+func (e *Error) Error() string {
+	if e.Data != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Data)
+	}
+	return e.Message
+}
+
+// Registry holds tools in registration order together with the set of
+// tools that are currently enabled.
+type Registry struct {
+	mu       sync.RWMutex
+	order    []string
+	byName   map[string]Tool
+	disabled map[string]bool
+	readOnly bool
+	allowed  map[string]bool // tools of the session role; nil = all
+	pageSize int             // tools.page_size
+	onChange func()
+	stats    *CallStats
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byName: map[string]Tool{}, disabled: map[string]bool{}, stats: newCallStats()}
+}
+
+// OnChange sets a callback invoked whenever the visible tool set changes.
+func (r *Registry) OnChange(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = fn
+}
+
+// NotifyChanged fires the change callback explicitly, e.g. when tool
+// behaviour changes because the server left degraded mode.
+func (r *Registry) NotifyChanged() {
+	r.mu.RLock()
+	cb := r.onChange
+	r.mu.RUnlock()
+	if cb != nil {
+		cb()
+	}
+}
+
+// Register adds t, replacing an existing tool with the same name in place.
+func (r *Registry) Register(t Tool) {
+	r.update(func() {
+		if _, ok := r.byName[t.Name]; !ok {
+			r.order = append(r.order, t.Name)
+		}
+		r.byName[t.Name] = t
+	})
+}
+
+// SetEnabled enables or disables a single tool at runtime.
+func (r *Registry) SetEnabled(name string, enabled bool) {
+	r.update(func() {
+		if enabled {
+			delete(r.disabled, name)
+		} else {
+			r.disabled[name] = true
+		}
+	})
+}
+
+// Configure applies the enablement rules from the tools config section,
+// including the session role. An unknown role hides every tool rather than
+// falling back to no limit.
+func (r *Registry) Configure(tc cfg.ToolsConfig) {
+	role, err := tc.SessionRole()
+	if err != nil {
+		log.Printf("Warning: %v; no tools are available", err)
+	}
+	r.update(func() {
+		r.disabled = map[string]bool{}
+		for _, name := range tc.Disabled {
+			r.disabled[name] = true
+		}
+		r.readOnly = tc.ReadOnly || role.ReadOnly
+		r.pageSize = tc.PageSize
+		r.allowed = nil
+		if err != nil || len(role.Tools) > 0 {
+			r.allowed = map[string]bool{}
+			for _, name := range role.Tools {
+				r.allowed[name] = true
+			}
+		}
+	})
+}
+
+// update applies fn under the write lock and fires the change callback when
+// the visible tool set differs afterwards.
+func (r *Registry) update(fn func()) {
+	r.mu.Lock()
+	before := strings.Join(r.visibleLocked(), ",")
+	fn()
+	after := strings.Join(r.visibleLocked(), ",")
+	cb := r.onChange
+	r.mu.Unlock()
+	if before != after && cb != nil {
+		cb()
+	}
+}
+
+func (r *Registry) enabledLocked(name string) bool {
+	t, ok := r.byName[name]
+	if !ok || r.disabled[name] || r.allowed != nil && !r.allowed[name] {
+		return false
+	}
+	return !r.readOnly || t.ReadOnly
+}
+
+func (r *Registry) visibleLocked() []string {
+	out := make([]string, 0, len(r.order))
+	for _, name := range r.order {
+		if r.enabledLocked(name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// Get returns the tool registered under name if it is enabled.
+func (r *Registry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.enabledLocked(name) {
+		return Tool{}, false
+	}
+	return r.byName[name], true
+}
+
+// List returns the MCP descriptors of all enabled tools.
+func (r *Registry) List() []mcp.Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := r.visibleLocked()
+	out := make([]mcp.Tool, 0, len(names))
+	for _, name := range names {
+		t := r.byName[name]
+		out = append(out, mcp.Tool{Name: t.Name, Description: t.Description, InputSchema: t.InputSchema, OutputSchema: t.OutputSchema})
+	}
+	return out
+}
+
+// ListPage returns one page of enabled tools starting at cursor, plus the
+// cursor of the next page ("" when this is the last one). Pages hold
+// tools.page_size tools; 0 returns everything.
+func (r *Registry) ListPage(cursor string) ([]mcp.Tool, string, error) {
+	all := r.List()
+	r.mu.RLock()
+	pageSize := r.pageSize
+	r.mu.RUnlock()
+	start := 0
+	if cursor != "" {
+		n, err := decodeCursor(cursor)
+		if err != nil || n > len(all) {
+			return nil, "", &Error{Code: -32602, Message: "invalid cursor", Data: apierr.Invalid(cursor)}
+		}
+		start = n
+	}
+	if pageSize <= 0 || start+pageSize >= len(all) {
+		return all[start:], "", nil
+	}
+	end := start + pageSize
+	return all[start:end], encodeCursor(end), nil
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(c string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := strings.CutPrefix(string(b), "offset:")
+	if !ok {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+	return n, nil
+}
+
+// Call runs the named tool with args.
+func (r *Registry) Call(name string, args map[string]any) (*mcp.ToolsCallResult, error) {
+	return r.CallFrom(nil, name, args)
+}
+
+// CallFrom runs the named tool with args for client.
+func (r *Registry) CallFrom(client Client, name string, args map[string]any) (*mcp.ToolsCallResult, error) {
+	t, ok := r.Get(name)
+	if !ok {
+		return nil, &Error{Code: -32601, Message: "tool not found", Data: name}
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	start := time.Now()
+	var res *mcp.ToolsCallResult
+	var err error
+	if t.SessionHandler != nil {
+		res, err = t.SessionHandler(Args(args), client)
+	} else {
+		res, err = t.Handler(Args(args))
+	}
+	r.stats.record(name, time.Since(start), err != nil || (res != nil && res.IsError))
+	return res, err
+}
+
+// Stats returns the per-tool call counters collected by Call.
+func (r *Registry) Stats() *CallStats {
+	return r.stats
+}
+
+// Env holds the dependencies shared by tool handlers. The RAG system may be
+// nil when running in degraded mode.
+type Env struct {
+	Config *cfg.Config
+	// Hooks receives index/delete events for the configured webhooks
+	Hooks *webhook.Notifier
+	// Stats is the registry's call counters, reported by status_get
+	Stats *CallStats
+	// Queries logs searches for rag_analytics; nil when query_log is off
+	Queries *querylog.Log
+	// Quota meters the session's search results under search.quota
+	Quota *quota.Meter
+
+	mu  sync.RWMutex
+	rag *ragvec.VecRAG
+	// confirmCfg is tools.confirm, which SetTools replaces on reload
+	confirmCfg cfg.ConfirmConfig
+}
+
+func NewEnv(config *cfg.Config, rag *ragvec.VecRAG) *Env {
+	return &Env{Config: config, Hooks: webhook.New(config), Queries: querylog.New(config), Quota: quota.New(), rag: rag,
+		confirmCfg: config.Tools.Confirm}
+}
+
+// SetTools applies a reloaded tools section to the tools. Config keeps the
+// section it was started with.
+func (e *Env) SetTools(tc cfg.ToolsConfig) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.confirmCfg = tc.Confirm
+}
+
+func (e *Env) confirmConfig() cfg.ConfirmConfig {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.confirmCfg
+}
+
+func (e *Env) RAG() *ragvec.VecRAG {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rag
+}
+
+func (e *Env) SetRAG(rag *ragvec.VecRAG) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rag = rag
+}
+
+func (e *Env) debug() bool { return e.Config.Logging.Level == "debug" }
+
+// errText renders err for clients, redacting internal details when
+// logging.redact_errors is enabled.
+func (e *Env) errText(err error) string {
+	return redact.Error(err, e.Config.Logging.RedactErrors)
+}
+
+// qdrantURL returns the vector store URL as shown to clients.
+func (e *Env) qdrantURL() string {
+	if e.Config.Logging.RedactErrors {
+		return redact.URL(e.Config.StoreURL())
+	}
+	return e.Config.StoreURL()
+}
+
+// Args wraps decoded tool arguments with typed accessors.
+type Args map[string]any
+
+// String returns the string argument key, or "" when absent.
+func (a Args) String(key string) string {
+	v, _ := a[key].(string)
+	return v
+}
+
+// Bool returns the boolean argument key, or def when absent.
+func (a Args) Bool(key string, def bool) bool {
+	if v, ok := a[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// Strings returns the string elements of the array argument key; non-string
+// elements are skipped.
+func (a Args) Strings(key string) []string {
+	arr, _ := a[key].([]any)
+	out := make([]string, 0, len(arr))
+	for _, v := range arr {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Number returns the numeric argument key (JSON numbers decode to float64).
+func (a Args) Number(key string) (float64, bool) {
+	v, ok := a[key].(float64)
+	return v, ok
+}
+
+// tenantACL returns the access labels of access.tenant, which limit what
+// the session may read; nil without a tenant.
+func (e *Env) tenantACL() ([]string, error) {
+	return e.Config.Access.TenantLabels(e.Config.Access.Tenant)
+}
+
+// completeProject completes project names from the indexed collection.
+// It yields no values while the RAG system is unavailable.
+func (e *Env) completeProject(prefix string) (mcp.Completion, error) {
+	rag := e.RAG()
+	if rag == nil {
+		return completion(nil, 0), nil
+	}
+	acl, err := e.tenantACL()
+	if err != nil {
+		return mcp.Completion{}, err
+	}
+	list, total, err := rag.ListProjectsFiltered(prefix, 0, maxCompletions, acl)
+	if err != nil {
+		return mcp.Completion{}, err
+	}
+	out := make([]string, 0, len(list))
+	for _, it := range list {
+		out = append(out, fmt.Sprint(it["project"]))
+	}
+	return completion(out, total), nil
+}
+
+// describe classifies err for clients, its text redacted as errText does.
+func (e *Env) describe(err error) apierr.Data {
+	return apierr.Of(err, e.errText(err))
+}
+
+// errRAGNotInitialized is returned by tools that need Qdrant in degraded mode.
+func errRAGNotInitialized(details string) *mcp.ToolsCallResult {
+	return failure("RAG not initialized", apierr.Unavailable(details))
+}
+
+// failurePayload is the JSON payload of failed tool results: the error with
+// the code, category, component, retryable flag and hint of its data.
+type failurePayload struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	apierr.Data
+}
+
+// failure builds an isError result so the model can see and react to a
+// failed operation instead of receiving a JSON-RPC protocol error.
+func failure(message string, d apierr.Data) *mcp.ToolsCallResult {
+	res := result(fmt.Sprintf("%s: %s", message, d.Details), failurePayload{Status: "error", Error: message, Data: d})
+	res.IsError = true
+	return res
+}
+
+// result builds a tool result with a text summary and a JSON payload, the
+// payload also being its structured content.
+func result(text string, payload any) *mcp.ToolsCallResult {
+	return &mcp.ToolsCallResult{Content: []mcp.ContentItem{{Type: "text", Text: text}, jsonResource(payload)}, StructuredContent: payload}
+}
+
+// helper: wrap any value as an MCP embedded JSON resource
+func jsonResource(v any) mcp.ContentItem {
+	b, _ := json.Marshal(v)
+	data := base64.StdEncoding.EncodeToString(b)
+	return mcp.ContentItem{Type: "resource_link", Name: "data.json", URI: "data:application/json;base64," + data}
+}
+
+// ResultPayload returns the JSON payload attached to a tool result, or nil
+// when it has none.
+func ResultPayload(res *mcp.ToolsCallResult) (json.RawMessage, error) {
+	for _, c := range res.Content {
+		data, ok := strings.CutPrefix(c.URI, "data:application/json;base64,")
+		if c.Type != "resource_link" || !ok {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", c.Name, err)
+		}
+		return b, nil
+	}
+	return nil, nil
+}
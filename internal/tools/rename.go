@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+)
+
+func ragProjectRenameTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_project_rename",
+		Description: "Rename a project, or merge it into another, by rewriting the project of its indexed chunks. Nothing is re-embedded.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"from": map[string]any{
+					"type":        "string",
+					"description": "Current project name",
+				},
+				"to": map[string]any{
+					"type":        "string",
+					"description": "New project name; an existing project is merged into",
+				},
+			},
+			"required": []string{"from", "to"},
+		},
+		OutputSchema: object(map[string]any{
+			"from":   field("string", ""),
+			"to":     field("string", ""),
+			"moved":  field("integer", "Chunks moved"),
+			"merged": field("boolean", "to existed already"),
+			"status": statusField,
+		}, "from", "to", "moved", "merged", "status"),
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			from := strings.TrimSpace(args.String("from"))
+			to := strings.TrimSpace(args.String("to"))
+			if from == "" || to == "" || from == to {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("Provide non-empty, different 'from' and 'to' project names")}
+			}
+			moved, merged, err := rag.RenameProject(from, to)
+			if err != nil {
+				log.Printf("Project rename error: %v", err)
+				return failure("rename error", env.describe(err)), nil
+			}
+			var msg string
+			switch {
+			case moved == 0:
+				msg = fmt.Sprintf("Project '%s' has no indexed chunks", from)
+			case merged:
+				msg = fmt.Sprintf("Merged %d chunks from project '%s' into '%s'", moved, from, to)
+			default:
+				msg = fmt.Sprintf("Renamed project '%s' to '%s' (%d chunks)", from, to, moved)
+			}
+			payload := map[string]any{
+				"from":   from,
+				"to":     to,
+				"moved":  moved,
+				"merged": merged,
+				"status": "success",
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
@@ -0,0 +1,320 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+)
+
+// InstructionsURI is the retrieval instructions resource.
+const InstructionsURI = "rag://instructions"
+
+// ProjectURITemplate describes one indexed project; completion/complete
+// fills in its project argument.
+const ProjectURITemplate = "rag://projects/{project}"
+
+const projectURIPrefix = "rag://projects/"
+
+// Resources serves the MCP resources of a session. Their content is
+// generated on every read, so it follows the index and the visible tools.
+type Resources struct {
+	env      *Env
+	registry *Registry
+}
+
+func NewResources(env *Env, registry *Registry) *Resources {
+	return &Resources{env: env, registry: registry}
+}
+
+// List returns the resources enabled in the config.
+func (r *Resources) List() []mcp.Resource {
+	out := []mcp.Resource{}
+	if r.env.Config.Resources.Instructions.Enabled {
+		out = append(out, mcp.Resource{
+			URI:         InstructionsURI,
+			Name:        "retrieval-instructions",
+			Title:       "Retrieval instructions",
+			Description: "How to search this server's index: the tools available to this session, the indexed projects and tips. Generated from the live index; suited for a system prompt.",
+			MimeType:    "text/markdown",
+		})
+	}
+	return out
+}
+
+// Templates returns the resource templates of the session. Projects are
+// offered while rag_search or rag_projects is visible, since they name
+// the same projects.
+func (r *Resources) Templates() []mcp.ResourceTemplate {
+	out := []mcp.ResourceTemplate{}
+	if r.projects() {
+		out = append(out, mcp.ResourceTemplate{
+			URITemplate: ProjectURITemplate,
+			Name:        "project",
+			Title:       "Indexed project",
+			Description: "File and chunk counts of one indexed project, and how to search it. The project argument completes from the index.",
+			MimeType:    "text/markdown",
+		})
+	}
+	return out
+}
+
+func (r *Resources) projects() bool {
+	_, search := r.registry.Get("rag_search")
+	_, projects := r.registry.Get("rag_projects")
+	return search || projects
+}
+
+// HasCompletions reports whether completion/complete has values to offer.
+func (r *Resources) HasCompletions() bool {
+	return r.projects()
+}
+
+// Complete returns completion values for argument arg of what ref names.
+// No prompts are offered, and only resource templates take arguments.
+func (r *Resources) Complete(ref mcp.CompleteRef, arg, value string) (mcp.Completion, error) {
+	switch ref.Type {
+	case mcp.RefPrompt:
+		return mcp.Completion{}, &Error{Code: -32602, Message: "prompt not found", Data: apierr.Invalid(ref.Name)}
+	case mcp.RefResource:
+	default:
+		return mcp.Completion{}, &Error{Code: -32602, Message: "invalid params",
+			Data: apierr.Invalid(fmt.Sprintf("unsupported reference type %q; use %q or %q", ref.Type, mcp.RefPrompt, mcp.RefResource))}
+	}
+	switch {
+	case ref.URI == ProjectURITemplate && r.projects():
+		if arg != "project" {
+			return completion(nil, 0), nil
+		}
+		return r.env.completeProject(value)
+	case ref.URI == InstructionsURI && r.env.Config.Resources.Instructions.Enabled:
+		return completion(nil, 0), nil
+	}
+	return mcp.Completion{}, &Error{Code: -32602, Message: "resource not found", Data: apierr.Invalid(ref.URI)}
+}
+
+// Read renders the resource at uri.
+func (r *Resources) Read(uri string) (*mcp.ResourcesReadResult, error) {
+	if name, ok := strings.CutPrefix(uri, projectURIPrefix); ok && r.projects() {
+		return r.readProject(uri, name)
+	}
+	if uri != InstructionsURI || !r.env.Config.Resources.Instructions.Enabled {
+		return nil, &Error{Code: -32002, Message: "resource not found", Data: uri}
+	}
+	text, err := r.instructions()
+	if err != nil {
+		d := r.env.describe(err)
+		return nil, &Error{Code: d.Code, Message: "instructions error", Data: d}
+	}
+	return &mcp.ResourcesReadResult{Contents: []mcp.ResourceContents{{URI: uri, MimeType: "text/markdown", Text: text}}}, nil
+}
+
+// readProject renders the project resource of the project named in uri.
+func (r *Resources) readProject(uri, name string) (*mcp.ResourcesReadResult, error) {
+	if n, err := url.PathUnescape(name); err == nil {
+		name = n
+	}
+	rag := r.env.RAG()
+	if rag == nil {
+		d := apierr.Unavailable("RAG not initialized; ensure Qdrant is running")
+		return nil, &Error{Code: d.Code, Message: "project error", Data: d}
+	}
+	acl, err := r.env.tenantACL()
+	if err != nil {
+		d := apierr.New(apierr.Config, "config", err.Error())
+		return nil, &Error{Code: d.Code, Message: "project error", Data: d}
+	}
+	list, err := rag.ListProjectsFor(acl)
+	if err != nil {
+		d := r.env.describe(err)
+		return nil, &Error{Code: d.Code, Message: "project error", Data: d}
+	}
+	for _, p := range list {
+		if fmt.Sprint(p["project"]) != name {
+			continue
+		}
+		files, _ := p["files"].(int)
+		chunks, _ := p["total_chunks"].(int)
+		var b strings.Builder
+		fmt.Fprintf(&b, "# Project %s\n\n", name)
+		fmt.Fprintf(&b, "- Files: %d\n- Chunks: %d\n\n", files, chunks)
+		fmt.Fprintf(&b, "Search it with `rag_search` and `\"project\": %q`.\n", name)
+		return &mcp.ResourcesReadResult{Contents: []mcp.ResourceContents{{URI: uri, MimeType: "text/markdown", Text: b.String()}}}, nil
+	}
+	return nil, &Error{Code: -32002, Message: "resource not found", Data: uri}
+}
+
+// instructionsData is what instruction templates can use.
+type instructionsData struct {
+	Server     string
+	Collection string
+	Provider   string
+	// Degraded is set while the vector store is unreachable
+	Degraded bool
+	// Projects are the largest projects, at most max_projects; MoreProjects
+	// counts the rest
+	Projects      []projectInfo
+	MoreProjects  int
+	TotalProjects int
+	TotalChunks   int
+	// Tools are the tools visible to the session; Has tells by name
+	Tools []toolInfo
+	Has   map[string]bool
+	// Example is a project name to show in examples, "" when none is indexed
+	Example string
+	Tips    []string
+	// Generated is when the guide was rendered (RFC3339)
+	Generated string
+}
+
+type projectInfo struct {
+	Name   string
+	Files  int
+	Chunks int
+}
+
+type toolInfo struct {
+	Name string
+	// Summary is the first sentence of the description
+	Summary string
+}
+
+func (r *Resources) instructions() (string, error) {
+	conf := r.env.Config
+	ic := conf.Resources.Instructions
+	data := instructionsData{
+		Server:     conf.Server.Name,
+		Collection: conf.CollectionName(),
+		Provider:   conf.Embedding.Provider,
+		Has:        map[string]bool{},
+		Tips:       ic.Tips,
+		Generated:  time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, t := range r.registry.List() {
+		data.Tools = append(data.Tools, toolInfo{Name: t.Name, Summary: firstSentence(t.Description)})
+		data.Has[t.Name] = true
+	}
+	rag := r.env.RAG()
+	data.Degraded = rag == nil
+	if rag != nil {
+		acl, err := r.env.tenantACL()
+		if err != nil {
+			return "", err
+		}
+		list, err := rag.ListProjectsFor(acl)
+		if err != nil {
+			return "", err
+		}
+		for _, p := range list {
+			info := projectInfo{Name: fmt.Sprint(p["project"])}
+			info.Files, _ = p["files"].(int)
+			info.Chunks, _ = p["total_chunks"].(int)
+			data.Projects = append(data.Projects, info)
+			data.TotalChunks += info.Chunks
+		}
+		sort.SliceStable(data.Projects, func(i, j int) bool { return data.Projects[i].Chunks > data.Projects[j].Chunks })
+		data.TotalProjects = len(data.Projects)
+		if len(data.Projects) > 0 {
+			data.Example = data.Projects[0].Name
+		}
+		if len(data.Projects) > ic.MaxProjects {
+			data.MoreProjects = len(data.Projects) - ic.MaxProjects
+			data.Projects = data.Projects[:ic.MaxProjects]
+		}
+	}
+
+	tmpl := template.New("instructions")
+	var err error
+	if ic.Template != "" {
+		src, rerr := os.ReadFile(ic.Template)
+		if rerr != nil {
+			return "", rerr
+		}
+		tmpl, err = tmpl.Parse(string(src))
+	} else {
+		tmpl, err = tmpl.Parse(defaultInstructions)
+	}
+	if err != nil {
+		return "", fmt.Errorf("instructions template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("instructions template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// firstSentence returns s up to its first full stop, skipping those of
+// abbreviations such as "e.g.".
+func firstSentence(s string) string {
+	for i := 0; ; {
+		j := strings.Index(s[i:], ". ")
+		if j < 0 {
+			return s
+		}
+		end := i + j + 1
+		if !strings.HasSuffix(s[:end], "e.g.") && !strings.HasSuffix(s[:end], "i.e.") {
+			return s[:end]
+		}
+		i = end
+	}
+}
+
+// defaultInstructions is the built-in retrieval guide. A template set in
+// resources.instructions.template replaces it and receives the same data.
+const defaultInstructions = `# Retrieval instructions
+
+{{.Server}} searches a vector index of documents and code{{if not .Degraded}}: {{.TotalChunks}} chunks in {{.TotalProjects}} project{{if ne .TotalProjects 1}}s{{end}} (collection ` + "`{{.Collection}}`" + `, {{.Provider}} embeddings){{end}}. Search it before answering questions about these projects, and base your answer on what it returns.
+{{- if .Degraded}}
+
+The vector store is unreachable right now. Searches fail until it is back; say so instead of guessing.
+{{- end}}
+
+## Searching
+{{if .Has.rag_search}}
+Call ` + "`rag_search`" + ` with a ` + "`query`" + ` phrased as a question or description of what you need, e.g. ` + "`{\"query\": \"how are API keys rotated?\"{{if .Example}}, \"project\": \"{{.Example}}\"{{end}}}`" + `.
+
+- ` + "`k`" + ` sets how many chunks come back (default 5). Prefer ` + "`max_tokens`" + ` to fill a token budget instead of guessing ` + "`k`" + `.
+- ` + "`project`" + ` limits the search to one project, ` + "`project_prefix`" + ` to a family of them. Leave both out when unsure which project holds the answer.
+- ` + "`tags_any`" + `, ` + "`tags_all`" + `, ` + "`modified_after`" + ` and ` + "`indexed_before`" + ` narrow the search further. For questions about a release, ` + "`version`" + ` (e.g. ` + "`\"2.3\"`" + `) limits the search to that version's changelog entries.
+- Cite hits by ` + "`path`" + `, or by ` + "`metadata.url`" + ` when present. Hits are ranked by ` + "`score`" + `; if the best ones do not answer the question, rephrase the query rather than asking for more of the same.
+- A result with ` + "`suggestions`" + ` found nothing confident. Follow its ` + "`suggested_query`" + `, ` + "`closest_projects`" + ` and ` + "`hints`" + `; if they do not help, say the index holds nothing relevant instead of answering from memory.
+{{- if .Has.rag_get_chunk}}
+- A result with ` + "`summarized`" + ` lists hits without their text to keep it small. Read the text of the hits you need with ` + "`rag_get_chunk`" + ` and ` + "`summarized.cursor`" + `.
+{{- end}}
+{{- if .Has.rag_toc}}
+- Use ` + "`rag_toc`" + ` to see the headings of a project's files before searching it.
+{{- end}}
+{{- if .Has.rag_find_files}}
+- Use ` + "`rag_find_files`" + ` to look up files by name or path instead of by content.
+{{- end}}
+{{else}}
+` + "`rag_search`" + ` is not available in this session.
+{{end}}
+{{- if .Projects}}
+## Projects
+{{range .Projects}}
+- ` + "`{{.Name}}`" + `: {{.Files}} files, {{.Chunks}} chunks
+{{- end}}
+{{- if .MoreProjects}}
+- … and {{.MoreProjects}} more{{if .Has.rag_projects}}; call ` + "`rag_projects`" + ` to list them{{end}}
+{{- end}}
+{{end}}
+## Tools in this session
+{{range .Tools}}
+- ` + "`{{.Name}}`" + `: {{.Summary}}
+{{- end}}
+{{- if .Tips}}
+
+## Tips
+{{range .Tips}}
+- {{.}}
+{{- end}}
+{{- end}}
+`
@@ -0,0 +1,359 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+func ragSearchTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_search",
+		Description: "Search for relevant document chunks using semantic similarity. Supports optional project filter. When nothing scores well the result carries suggestions (closest projects, did-you-mean terms, what to index) instead of an answer.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{
+					"type":        "string",
+					"description": "Search query for finding relevant document chunks",
+				},
+				"k": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"maximum":     20,
+					"default":     5,
+					"description": "Number of most relevant document chunks to return",
+				},
+				"project": map[string]any{
+					"type":        "string",
+					"description": "Filter results to an exact project name (parent folder)",
+					"default":     "",
+				},
+				"project_prefix": map[string]any{
+					"type":        "string",
+					"description": "Filter results to projects starting with this prefix (client-side)",
+					"default":     "",
+				},
+				"tags_any": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Only return chunks carrying at least one of these tags",
+				},
+				"tags_all": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Only return chunks carrying all of these tags",
+				},
+				"version": map[string]any{
+					"type":        "string",
+					"description": "Only return changelog entries of this version; a prefix selects every release under it (\"2.3\" matches 2.3.0 and 2.3.1, a leading v is ignored)",
+				},
+				"modified_after": map[string]any{
+					"type":        "string",
+					"description": "Only return chunks whose source file was modified after this time (RFC3339 or YYYY-MM-DD)",
+				},
+				"indexed_before": map[string]any{
+					"type":        "string",
+					"description": "Only return chunks indexed before this time (RFC3339 or YYYY-MM-DD)",
+				},
+				"params": map[string]any{
+					"type":        "object",
+					"description": "Search-time index parameters; omitted fields use the server defaults",
+					"properties": map[string]any{
+						"hnsw_ef": map[string]any{
+							"type":        "integer",
+							"minimum":     0,
+							"description": "HNSW beam width: higher improves recall at the cost of latency",
+						},
+						"exact": map[string]any{
+							"type":        "boolean",
+							"description": "Skip the index and scan exhaustively (slow; for evaluation runs)",
+						},
+						"consistency": map[string]any{
+							"type":        "string",
+							"description": "Qdrant cluster read consistency: majority, quorum, all or a replica count; use all right after indexing to avoid stale replicas",
+						},
+						"timeout_sec": map[string]any{
+							"type":        "integer",
+							"minimum":     0,
+							"description": "Server-side search timeout in seconds (Qdrant)",
+						},
+					},
+				},
+				"max_tokens": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Context budget: return ranked chunks until their estimated token count would exceed this; k (default 20 here) still caps the count",
+				},
+				"explain": map[string]any{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Break down each hit's score (vector and BM25 components, rank, merges, model mismatch, filters matched) and report how the search ran, to diagnose relevance problems",
+				},
+				"read_through": map[string]any{
+					"type":        "boolean",
+					"description": "Re-read each hit from its source file instead of the indexed text; hits whose file changed since indexing are marked drifted. Only when search.read_through is on; false turns it off for this call",
+				},
+				"answer": map[string]any{
+					"type":        "boolean",
+					"description": "Have the client's model answer the query from the hits (MCP sampling); the hits are then listed without their text. Needs a client with the sampling capability (default: search.sampling.enabled)",
+				},
+				"max_output_kb": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"description": "Summarize the result when it would be larger: hits are listed with path and score only, and rag_get_chunk fetches their text with the returned cursor (0 = never; default: search.max_output_kb)",
+				},
+			},
+			"required": []string{"query"},
+		},
+		OutputSchema: object(map[string]any{
+			"query":        field("string", ""),
+			"chunks":       arrayOf(hitSchema),
+			"total_chunks": field("integer", ""),
+			"message":      field("string", ""),
+			"config":       field("object", "Provider and filters the search ran with"),
+			"budget":       field("object", "Token budget usage (token_budget)"),
+			"explain":      field("object", "How the query was run (explain)"),
+			"quota":        quotaSchema,
+			"suggestions":  field("object", "Did-you-mean terms and projects for weak results"),
+			"warnings":     stringList,
+			"summarized": object(map[string]any{
+				"max_output_kb": field("integer", ""),
+				"full_bytes":    field("integer", "Size of the hits with their text"),
+				"cursor":        field("string", "Pass to rag_get_chunk for the text"),
+			}, "cursor"),
+			"answer": object(map[string]any{
+				"text":        field("string", ""),
+				"model":       field("string", ""),
+				"stop_reason": field("string", ""),
+			}, "text"),
+			"answer_error": field("string", "Why no answer was sampled"),
+		}, "query", "chunks", "total_chunks", "message"),
+		ReadOnly: true,
+		SessionHandler: func(args Args, client Client) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				log.Println("RAG search requested but RAG system not initialized")
+				return errRAGNotInitialized("Please ensure Qdrant vector database is running"), nil
+			}
+
+			q := args.String("query")
+			if strings.TrimSpace(q) == "" {
+				log.Println("Empty search query provided")
+				return nil, &Error{Code: -32602, Message: "query required", Data: apierr.Invalid("Search query cannot be empty")}
+			}
+
+			maxOutputKB := env.Config.Search.MaxOutputKB
+			if f, ok := args.Number("max_output_kb"); ok {
+				if f < 0 {
+					return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("max_output_kb cannot be negative")}
+				}
+				maxOutputKB = int(f)
+			}
+			maxTokens := 0
+			if f, ok := args.Number("max_tokens"); ok {
+				if f < 1 {
+					return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("max_tokens must be at least 1")}
+				}
+				maxTokens = int(f)
+			}
+			k := 5
+			if maxTokens > 0 {
+				// The budget decides how many chunks fit
+				k = 20
+			}
+			if f, ok := args.Number("k"); ok && f >= 1 && f <= 20 {
+				k = int(f)
+			}
+
+			proj := args.String("project")
+			projPref := args.String("project_prefix")
+			if env.debug() {
+				log.Printf("Performing semantic search: query='%s', k=%d, project='%s', project_prefix='%s'", q, k, proj, projPref)
+			}
+			var err error
+			params := rag.DefaultSearchParams()
+			if p, ok := args["params"].(map[string]any); ok {
+				if params, err = overrideParams(params, p); err != nil {
+					return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("params: " + err.Error())}
+				}
+			}
+			filter := ragvec.SearchFilter{
+				Project:       proj,
+				ProjectPrefix: projPref,
+				TagsAny:       args.Strings("tags_any"),
+				TagsAll:       args.Strings("tags_all"),
+				Version:       args.String("version"),
+			}
+			// A tenant session only finds the documents of its labels
+			if filter.ACL, err = env.tenantACL(); err != nil {
+				return failure("search error", apierr.New(apierr.Config, "config", err.Error())), nil
+			}
+			if filter.ModifiedAfter, err = ragvec.ParseTime(args.String("modified_after")); err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("modified_after: " + err.Error())}
+			}
+			if filter.IndexedBefore, err = ragvec.ParseTime(args.String("indexed_before")); err != nil {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("indexed_before: " + err.Error())}
+			}
+			explain := args.Bool("explain", false)
+			var hits []ragvec.Hit
+			var explanation *ragvec.SearchExplanation
+			if explain {
+				hits, explanation, err = rag.SearchExplained(q, k, filter, params)
+			} else {
+				hits, err = rag.SearchFiltered(q, k, filter, params)
+			}
+			if err != nil {
+				log.Printf("Search error: %v", err)
+				return failure("search error", env.describe(err)), nil
+			}
+			env.Queries.Record("mcp", clientName(client), q, proj, hits)
+			suggestions, err := rag.Suggest(q, hits, filter, params)
+			if err != nil {
+				// Guidance is best effort; the hits stand on their own
+				log.Printf("Search suggestions error: %v", err)
+			}
+			// The argument can turn read-through off, not on
+			readThrough := env.Config.Search.ReadThrough && args.Bool("read_through", true)
+			if readThrough {
+				rag.ReadThrough(hits)
+			}
+			var budget map[string]any
+			if maxTokens > 0 {
+				found := len(hits)
+				var used int
+				hits, used = ragvec.FitTokens(hits, maxTokens)
+				budget = map[string]any{"max_tokens": maxTokens, "total_tokens": used, "omitted": found - len(hits)}
+			}
+			found := len(hits)
+			hits, usage := env.Quota.Take(quotaCaller(client), env.Config.TenantQuota(env.Config.Access.Tenant), hits)
+			if usage != nil && found > 0 && len(hits) == 0 {
+				return failure("quota exceeded", apierr.QuotaExceeded("The search quota of this session is used up until "+usage.ResetsAt)), nil
+			}
+
+			log.Printf("Search completed, returning %d document chunks for LLM context", len(hits))
+			msg := fmt.Sprintf("Found %d relevant document chunks", len(hits))
+			payload := map[string]any{
+				"query":        q,
+				"chunks":       hits,
+				"total_chunks": len(hits),
+				"message":      msg,
+				"config": map[string]any{
+					"provider":       env.Config.Embedding.Provider,
+					"project":        proj,
+					"project_prefix": projPref,
+					"tags_any":       ragvec.NormalizeTags(filter.TagsAny),
+					"tags_all":       ragvec.NormalizeTags(filter.TagsAll),
+					"version":        args.String("version"),
+					"modified_after": args.String("modified_after"),
+					"indexed_before": args.String("indexed_before"),
+					"params":         params,
+					"read_through":   readThrough,
+				},
+			}
+			warnings := rag.ConfigDrift()
+			mismatched, drifted := 0, 0
+			for _, h := range hits {
+				if h.ModelMismatch {
+					mismatched++
+				}
+				if h.Drifted {
+					drifted++
+				}
+			}
+			if drifted > 0 {
+				warnings = append(warnings, fmt.Sprintf("%d hits come from files changed since indexing; their text is current but the ranges may have shifted (re-run rag_index)", drifted))
+			}
+			if mismatched > 0 {
+				warnings = append(warnings, fmt.Sprintf("%d hits were embedded with a different provider/model than the query; their scores are not meaningful (set search.model_mismatch=filter to drop them)", mismatched))
+			}
+			if budget != nil {
+				payload["budget"] = budget
+				msg += fmt.Sprintf(" (~%d of %d tokens)", budget["total_tokens"], maxTokens)
+			}
+			if explanation != nil {
+				payload["explain"] = explanation
+			}
+			if usage != nil {
+				payload["quota"] = usage
+				if usage.Truncated {
+					msg += fmt.Sprintf(" (%d more left out by the search quota, see quota)", usage.Omitted)
+				}
+			}
+			if suggestions != nil {
+				payload["suggestions"] = suggestions
+				msg += " (no confident match, see suggestions)"
+			}
+			if len(warnings) > 0 {
+				payload["warnings"] = warnings
+				msg += fmt.Sprintf(" (warning: %d index/config mismatches, see rag_manifest)", len(warnings))
+			}
+			// The client's model answers from the hits, which are then
+			// listed without their text
+			var ans *answer
+			if _, asked := args["answer"]; args.Bool("answer", env.Config.Search.Sampling.Enabled) && len(hits) > 0 {
+				err := mcp.ErrSamplingUnsupported
+				if client != nil {
+					ans, err = sampleAnswer(client, env.Config.Search.Sampling, q, hits)
+				}
+				if err != nil && (asked || !errors.Is(err, mcp.ErrSamplingUnsupported)) {
+					// Clients without sampling get the hits quietly unless
+					// they asked for an answer
+					log.Printf("Sampling error: %v", err)
+					text := env.errText(err)
+					payload["answer_error"] = text
+					msg += " (no answer: " + text + ")"
+				}
+			}
+			if ans != nil {
+				summaries, cursor := summarize(hits, maxOutputKB, readThrough)
+				payload["answer"] = ans
+				payload["chunks"] = summaries
+				payload["summarized"] = map[string]any{"cursor": cursor}
+				msg += "\n\n" + ans.Text
+			} else if b, _ := json.Marshal(payload); maxOutputKB > 0 && len(hits) > 0 && len(b) > maxOutputKB*1024 {
+				// Past max_output_kb the hits are listed without their text
+				summaries, cursor := summarize(hits, maxOutputKB, readThrough)
+				payload["chunks"] = summaries
+				payload["summarized"] = map[string]any{"max_output_kb": maxOutputKB, "full_bytes": len(b), "cursor": cursor}
+				msg += fmt.Sprintf(" (summarized: the full result is %d KB, over max_output_kb; fetch the text with rag_get_chunk and summarized.cursor)", (len(b)+1023)/1024)
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
+
+// overrideParams applies the hnsw_ef, exact, consistency and timeout_sec
+// keys present in p on top of base.
+func overrideParams(base ragvec.SearchParams, p Args) (ragvec.SearchParams, error) {
+	if v, ok := p.Number("hnsw_ef"); ok && v >= 0 {
+		base.HNSWEf = int(v)
+	}
+	base.Exact = p.Bool("exact", base.Exact)
+	v, ok := p["consistency"].(string)
+	if n, isNum := p.Number("consistency"); isNum {
+		// A replica count may come as a JSON number
+		v, ok = strconv.Itoa(int(n)), true
+	}
+	if ok {
+		if !cfg.ValidReadConsistency(v) {
+			return base, fmt.Errorf("consistency must be majority, quorum, all or a positive number of replicas")
+		}
+		base.Consistency = v
+	}
+	if v, ok := p.Number("timeout_sec"); ok {
+		if v < 0 {
+			return base, fmt.Errorf("timeout_sec cannot be negative")
+		}
+		base.TimeoutSec = int(v)
+	}
+	return base, nil
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow is the number of recent calls per tool kept for latency
+// percentiles.
+const latencyWindow = 1000
+
+// ToolStats summarizes the calls of one tool since startup. Percentiles
+// cover the last latencyWindow calls.
+type ToolStats struct {
+	Name       string        `json:"name"`
+	Calls      int           `json:"calls"`
+	Errors     int           `json:"errors"`
+	ErrorRate  float64       `json:"error_rate"`
+	Total      time.Duration `json:"-"`
+	AvgMS      float64       `json:"avg_ms"`
+	P50MS      float64       `json:"p50_ms"`
+	P90MS      float64       `json:"p90_ms"`
+	P99MS      float64       `json:"p99_ms"`
+	MaxMS      float64       `json:"max_ms"`
+	LastCallAt time.Time     `json:"last_call_at"`
+}
+
+type toolCounters struct {
+	calls  int
+	errors int
+	total  time.Duration
+	max    time.Duration
+	recent []time.Duration // ring buffer of the last latencyWindow calls
+	next   int
+	last   time.Time
+}
+
+// CallStats counts invocations, failures and latency per tool.
+type CallStats struct {
+	mu     sync.Mutex
+	since  time.Time
+	byTool map[string]*toolCounters
+}
+
+func newCallStats() *CallStats {
+	return &CallStats{since: time.Now(), byTool: map[string]*toolCounters{}}
+}
+
+// record adds one call of name; failed covers both protocol errors and
+// results flagged IsError.
+func (s *CallStats) record(name string, took time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.byTool[name]
+	if c == nil {
+		c = &toolCounters{}
+		s.byTool[name] = c
+	}
+	c.calls++
+	if failed {
+		c.errors++
+	}
+	c.total += took
+	if took > c.max {
+		c.max = took
+	}
+	c.last = time.Now()
+	if len(c.recent) < latencyWindow {
+		c.recent = append(c.recent, took)
+		return
+	}
+	c.recent[c.next] = took
+	c.next = (c.next + 1) % latencyWindow
+}
+
+// Since is when counting started.
+func (s *CallStats) Since() time.Time {
+	return s.since
+}
+
+// Snapshot returns the stats of every tool called so far, sorted by name.
+func (s *CallStats) Snapshot() []ToolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ToolStats, 0, len(s.byTool))
+	for name, c := range s.byTool {
+		sorted := append([]time.Duration(nil), c.recent...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		out = append(out, ToolStats{
+			Name:       name,
+			Calls:      c.calls,
+			Errors:     c.errors,
+			ErrorRate:  float64(c.errors) / float64(c.calls),
+			Total:      c.total,
+			AvgMS:      ms(c.total / time.Duration(c.calls)),
+			P50MS:      ms(percentile(sorted, 0.5)),
+			P90MS:      ms(percentile(sorted, 0.9)),
+			P99MS:      ms(percentile(sorted, 0.99)),
+			MaxMS:      ms(c.max),
+			LastCallAt: c.last,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// percentile picks the nearest-rank value at q from ascending durations.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/chunker"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+func statusGetTool(env *Env) Tool {
+	return Tool{
+		Name:        "status_get",
+		Description: "Get server status: provider, Qdrant health, counts, and config summary.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"fast_only": map[string]any{
+					"type":        "boolean",
+					"description": "If true, skip project aggregation (one facet request, or a full scan on Qdrant without the facet API)",
+					"default":     true,
+				},
+				"probe_embedding": map[string]any{
+					"type":        "boolean",
+					"description": "Embed a tiny probe input to measure live provider latency (costs one API call with OpenAI)",
+					"default":     true,
+				},
+			},
+		},
+		OutputSchema: object(map[string]any{
+			"provider":  field("string", "Configured embedding provider"),
+			"embedding": field("object", "Active provider, fallback chain and probe"),
+			"qdrant": object(map[string]any{
+				"backend":    field("string", ""),
+				"url":        field("string", ""),
+				"collection": field("string", ""),
+				"health":     field("string", `"ok" or the error of the health check`),
+				"latency_ms": field("integer", ""),
+				"circuit": object(map[string]any{
+					"state":                field("string", `"closed", "open" or "half_open"`),
+					"consecutive_failures": field("integer", ""),
+				}),
+				"encrypted": field("boolean", ""),
+			}, "backend", "collection", "health"),
+			"counts": object(map[string]any{
+				"chunks":   nullable("integer", "null when the store is unreachable"),
+				"projects": nullable("integer", "null with fast_only"),
+			}),
+			"config":        field("object", "Indexing settings"),
+			"search_cache":  nullable("object", ""),
+			"tools":         nullable("object", "Per-tool call counters and latencies"),
+			"degraded_mode": field("boolean", "Running without the vector store"),
+			"fast_only":     field("boolean", ""),
+			"elapsed_ms":    field("integer", ""),
+			"note":          field("string", "Why counts are missing"),
+		}, "provider", "qdrant", "counts", "degraded_mode"),
+		ReadOnly: true,
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			conf := env.Config
+			start := time.Now()
+			fastOnly := args.Bool("fast_only", true)
+			// Always probe the vector store using current config (even if rag is nil)
+			q := ragvec.NewStore(conf, 1)
+			healthErr := q.HealthCheck()
+			healthLatency := time.Since(start).Milliseconds()
+			var chunks *int
+			if healthErr == nil {
+				if c, err := q.CountPoints(); err == nil {
+					chunks = &c
+				}
+			}
+			var projectsCount *int
+			var skippedReason string
+			if healthErr == nil && !fastOnly {
+				// Prefer the RAG system's cached aggregation
+				var list []map[string]any
+				var err error
+				if rag := env.RAG(); rag != nil {
+					var acl []string
+					if acl, err = env.tenantACL(); err == nil {
+						list, err = rag.ListProjectsFor(acl)
+					}
+				} else {
+					list, err = ragvec.FacetProjects(q)
+				}
+				if err == nil {
+					v := len(list)
+					projectsCount = &v
+				}
+			}
+			if healthErr == nil && !fastOnly && projectsCount == nil {
+				// No facet API: aggregate projects via scroll (cheap per page, expensive overall)
+				seen := map[string]struct{}{}
+				var offset any
+				for {
+					pts, next, err := q.ScrollPoints(1000, offset)
+					if err != nil {
+						skippedReason = fmt.Sprintf("aggregation error: %v", err)
+						break
+					}
+					for _, pt := range pts {
+						if proj, ok := pt.Payload["project"].(string); ok && proj != "" {
+							seen[proj] = struct{}{}
+						} else if pth, ok := pt.Payload["path"].(string); ok {
+							seen[projectFromPath(pth)] = struct{}{}
+						}
+					}
+					if next == nil {
+						break
+					}
+					offset = next
+					// Soft guard: prevent very long scans
+					if time.Since(start) > 5*time.Second {
+						skippedReason = "timeout: partial scan exceeded 5s"
+						break
+					}
+				}
+				if skippedReason == "" {
+					v := len(seen)
+					projectsCount = &v
+				}
+			} else if fastOnly {
+				skippedReason = "fast_only=true"
+			}
+			elapsed := time.Since(start).Milliseconds()
+			healthStr := "ok"
+			if healthErr != nil {
+				healthStr = env.errText(healthErr)
+			}
+			embedding := map[string]any{"active": nil}
+			var searchCache map[string]any
+			if rag := env.RAG(); rag != nil {
+				searchCache = rag.SearchCacheStatus()
+				embedding = rag.EmbeddingStatus()
+				if args.Bool("probe_embedding", true) {
+					probe, err := rag.ProbeEmbedding()
+					if err != nil {
+						probe["error"] = env.errText(err)
+					}
+					embedding["probe"] = probe
+				}
+			}
+			status := map[string]any{
+				"provider":  conf.Embedding.Provider,
+				"embedding": embedding,
+				"qdrant": map[string]any{
+					"backend":    conf.Store.Backend,
+					"url":        env.qdrantURL(),
+					"collection": conf.CollectionName(),
+					"health":     healthStr,
+					"latency_ms": healthLatency,
+					"circuit":    circuitState(q),
+					"encrypted":  conf.Store.Encryption.Key != "",
+				},
+				"counts": map[string]any{
+					"chunks":   chunks,
+					"projects": projectsCount,
+				},
+				"config": map[string]any{
+					"chunk_size":    conf.Indexing.ChunkSize,
+					"chunk_overlap": conf.Indexing.ChunkOverlap,
+					"batch_size":    conf.Indexing.BatchSize,
+					"max_file_kb":   conf.Indexing.MaxFileKB,
+					"exclude_dirs":  conf.Indexing.ExcludeDirs,
+					"exclude":       conf.Indexing.Exclude,
+				},
+				"search_cache":  searchCache,
+				"tools":         toolStatus(env.Stats),
+				"degraded_mode": env.RAG() == nil,
+				"fast_only":     fastOnly,
+				"elapsed_ms":    elapsed,
+				"note":          skippedReason,
+			}
+			txt := fmt.Sprintf("status: provider=%s, %s=%s/%s, health=%v, chunks=%v, projects=%v",
+				conf.Embedding.Provider,
+				conf.Store.Backend, env.qdrantURL(), conf.CollectionName(),
+				healthErr == nil,
+				nilOrInt(chunks), nilOrInt(projectsCount),
+			)
+			return result(txt, status), nil
+		},
+	}
+}
+
+// toolStatus reports per-tool call counters since startup, sorted by name.
+func toolStatus(stats *CallStats) map[string]any {
+	if stats == nil {
+		return nil
+	}
+	return map[string]any{
+		"since":      stats.Since().UTC(),
+		"uptime_sec": int64(time.Since(stats.Since()).Seconds()),
+		"calls":      stats.Snapshot(),
+	}
+}
+
+func nilOrInt(p *int) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// project derivation copied for status aggregation without full VecRAG
+func projectFromPath(p string) string {
+	if p == "" {
+		return "unknown"
+	}
+	// Files inside an archive belong to the archive's directory
+	dir := filepath.Dir(chunker.DiskPath(p))
+	if dir == "." || dir == "/" {
+		return "root"
+	}
+	return filepath.Base(dir)
+}
+
+// circuitState reports the vector store circuit breaker state and failure streak.
+func circuitState(q ragvec.VectorStore) map[string]any {
+	state, failures := q.Breaker().State()
+	return map[string]any{"state": state, "consecutive_failures": failures}
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+func ragTOCTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_toc",
+		Description: "Browse the structure of indexed documents: the headings of each file, per project, without running a search. Use it to see what a project covers before searching it, or to pick a section to search for.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"project": map[string]any{
+					"type":        "string",
+					"description": "Exact project name (parent folder)",
+					"default":     "",
+				},
+				"glob": map[string]any{
+					"type":        "string",
+					"description": "Path glob, e.g. \"guides/**\" or \"**/*.md\" (* stays within a directory, ** spans directories)",
+					"default":     "",
+				},
+				"max_level": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"maximum":     6,
+					"default":     6,
+					"description": "Deepest heading level to return (1 = titles only)",
+				},
+				"offset": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"default":     0,
+					"description": "Pagination offset",
+				},
+				"limit": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"maximum":     500,
+					"default":     50,
+					"description": "Max number of files to return",
+				},
+			},
+		},
+		OutputSchema: object(withHint(pageProps("files", object(map[string]any{
+			"path":    field("string", ""),
+			"project": field("string", ""),
+			"headings": arrayOf(object(map[string]any{
+				"level":      field("integer", ""),
+				"title":      field("string", ""),
+				"start_byte": field("integer", "Offset of the heading line in the file"),
+			}, "level", "title")),
+		}, "path", "headings"))), "files", "count", "total"),
+		ReadOnly: true,
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				log.Println("RAG table of contents requested but RAG system not initialized")
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			q := ragvec.TOCQuery{
+				Project: args.String("project"),
+				Glob:    args.String("glob"),
+			}
+			if v, ok := args.Number("max_level"); ok && v >= 1 && v <= 6 {
+				q.MaxLevel = int(v)
+			}
+			// A tenant session only sees the outlines of its documents
+			var err error
+			if q.ACL, err = env.tenantACL(); err != nil {
+				return failure("table of contents error", apierr.New(apierr.Config, "config", err.Error())), nil
+			}
+			var offset int
+			limit := 50
+			if v, ok := args.Number("offset"); ok && v >= 0 {
+				offset = int(v)
+			}
+			if v, ok := args.Number("limit"); ok && v >= 1 && v <= 500 {
+				limit = int(v)
+			}
+			files, total, err := rag.TOC(q, offset, limit)
+			if err != nil {
+				log.Printf("Table of contents error: %v", err)
+				return failure("table of contents error", env.describe(err)), nil
+			}
+			payload := map[string]any{
+				"files":  files,
+				"count":  len(files),
+				"total":  total,
+				"offset": offset,
+				"limit":  limit,
+			}
+			if total == 0 && !env.Config.Indexing.TOC.Enabled {
+				payload["hint"] = "indexing.toc is disabled; enable it and re-index to record headings"
+			}
+			return result(fmt.Sprintf("Found %d files with headings (showing %d)", total, len(files)), payload), nil
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+)
+
+func ragUndeleteTool(env *Env) Tool {
+	return Tool{
+		Name:        "rag_undelete",
+		Description: "Restore chunks removed by rag_delete that are still within the soft-delete retention window. Use either 'all' or 'project'.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"all": map[string]any{
+					"type":        "boolean",
+					"description": "Restore every deleted chunk",
+					"default":     false,
+				},
+				"project": map[string]any{
+					"type":        "string",
+					"description": "Restore the deleted chunks of a specific project",
+					"default":     "",
+				},
+				"dry_run": map[string]any{
+					"type":        "boolean",
+					"description": "Only report how many chunks and which files and projects would be restored",
+					"default":     false,
+				},
+			},
+		},
+		OutputSchema: object(deleteProps("restored", "Chunks restored, or that would be with dry_run"), "restored", "all", "project", "dry_run", "status"),
+		Handler: func(args Args) (*mcp.ToolsCallResult, error) {
+			rag := env.RAG()
+			if rag == nil {
+				return errRAGNotInitialized("Ensure Qdrant is running"), nil
+			}
+			all := args.Bool("all", false)
+			proj := args.String("project")
+			if !all && strings.TrimSpace(proj) == "" {
+				return nil, &Error{Code: -32602, Message: "invalid params", Data: apierr.Invalid("Provide either all=true or a non-empty project")}
+			}
+			if all {
+				proj = ""
+			}
+			if args.Bool("dry_run", false) {
+				preview, err := rag.PreviewUndelete(proj)
+				if err != nil {
+					log.Printf("Undelete preview error: %v", err)
+					return failure("undelete error", env.describe(err)), nil
+				}
+				msg := fmt.Sprintf("Would restore %d chunks from %d files in %d projects", preview.Points, preview.FileCount, len(preview.Projects))
+				payload := map[string]any{
+					"restored":   preview.Points,
+					"all":        all,
+					"project":    proj,
+					"projects":   preview.Projects,
+					"files":      preview.Files,
+					"file_count": preview.FileCount,
+					"dry_run":    true,
+					"status":     "success",
+				}
+				return result(msg, payload), nil
+			}
+			n, err := rag.Undelete(proj)
+			if err != nil {
+				log.Printf("Undelete error: %v", err)
+				return failure("undelete error", env.describe(err)), nil
+			}
+			msg := fmt.Sprintf("Restored %d chunks", n)
+			if !all {
+				msg += fmt.Sprintf(" in project '%s'", proj)
+			}
+			if n > 0 {
+				msg += "; run rag_index on their directories to track them in the manifest again"
+			}
+			payload := map[string]any{
+				"restored": n,
+				"all":      all,
+				"project":  proj,
+				"dry_run":  false,
+				"status":   "success",
+			}
+			return result(msg, payload), nil
+		},
+	}
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+)
+
+// Event is the JSON body posted to webhooks.
+type Event struct {
+	Event      string         `json:"event"`  // "index" or "delete"
+	Source     string         `json:"source"` // "mcp" (tool call), "http" or "cli"
+	Status     string         `json:"status"` // "success" or "error"
+	Count      int            `json:"count"`  // chunks indexed or deleted, also on partial failure
+	DurationMS int64          `json:"duration_ms"`
+	Error      string         `json:"error,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	Time       time.Time      `json:"time"`
+}
+
+// Notifier posts events to the configured webhooks in the background. A nil
+// Notifier or one without webhooks does nothing.
+type Notifier struct {
+	hooks  []cfg.WebhookConfig
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+func New(conf *cfg.Config) *Notifier {
+	return &Notifier{hooks: conf.Webhooks, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Notify fills in the status, duration and error of ev from start and err
+// (already rendered for clients, "" on success) and delivers it.
+func (n *Notifier) Notify(ev Event, start time.Time, errText string) {
+	if n == nil || len(n.hooks) == 0 {
+		return
+	}
+	ev.Status, ev.Error = "success", errText
+	if errText != "" {
+		ev.Status = "error"
+	}
+	ev.DurationMS = time.Since(start).Milliseconds()
+	ev.Time = time.Now().UTC()
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("Webhook: encode %s event: %v", ev.Event, err)
+		return
+	}
+	for _, h := range n.hooks {
+		if !h.Wants(ev.Event) {
+			continue
+		}
+		n.wg.Add(1)
+		go func(h cfg.WebhookConfig) {
+			defer n.wg.Done()
+			if err := n.post(h, ev.Event, body); err != nil {
+				log.Printf("Webhook %s event to %s failed: %v", ev.Event, h.URL, err)
+			}
+		}(h)
+	}
+}
+
+// post delivers body, retrying once on network errors and 5xx replies.
+func (n *Notifier) post(h cfg.WebhookConfig, event string, body []byte) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Second)
+		}
+		var req *http.Request
+		req, err = http.NewRequest("POST", h.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-MCP-Event", event)
+		if h.Secret != "" {
+			req.Header.Set("X-MCP-Signature", "sha256="+Sign(h.Secret, body))
+		}
+		var res *http.Response
+		res, err = n.client.Do(req)
+		if err != nil {
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("http %d", res.StatusCode)
+		if res.StatusCode < 500 {
+			return err
+		}
+	}
+	return err
+}
+
+// Sign returns the hex HMAC-SHA256 of body under secret, as sent in
+// X-MCP-Signature; receivers recompute it over the raw body to verify.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Wait blocks until pending deliveries finish or timeout passes, so events
+// are not lost on shutdown.
+func (n *Notifier) Wait(timeout time.Duration) {
+	if n == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Rhyanz46/mcp-service/internal/apierr"
+	cfg "github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/mcp"
+	"github.com/Rhyanz46/mcp-service/internal/redact"
+	"github.com/Rhyanz46/mcp-service/internal/tools"
+)
+
+// mcpServer answers MCP requests for every session of the process: the
+// stdio one and each connection accepted on -mcp-listen. Sessions share
+// the tool registry and the RAG backend; each has its own handshake state
+// and framing.
+type mcpServer struct {
+	conf      *cfg.Config
+	registry  *tools.Registry
+	resources *tools.Resources
+	// listChanged is set when the tool set may change while sessions
+	// are open
+	listChanged bool
+
+	mu       sync.Mutex
+	sessions map[*mcp.StdioRPC]string
+	seq      int
+}
+
+// newMCPServer returns a server for registry and resources. With
+// listChanged, sessions are told when the visible tool set changes.
+func newMCPServer(conf *cfg.Config, registry *tools.Registry, resources *tools.Resources, listChanged bool) *mcpServer {
+	s := &mcpServer{conf: conf, registry: registry, resources: resources, listChanged: listChanged, sessions: map[*mcp.StdioRPC]string{}}
+	if listChanged {
+		registry.OnChange(s.broadcast)
+	}
+	return s
+}
+
+// capabilities returns what initialize advertises: only what the config
+// and the state of the server make available.
+func (s *mcpServer) capabilities() mcp.Capabilities {
+	var c mcp.Capabilities
+	if s.listChanged || len(s.registry.List()) > 0 {
+		c.Tools = map[string]any{"listChanged": s.listChanged}
+	}
+	if s.resources.HasCompletions() {
+		c.Completions = map[string]any{}
+	}
+	if len(s.resources.List()) > 0 || len(s.resources.Templates()) > 0 {
+		c.Resources = map[string]any{"listChanged": false}
+	}
+	return c
+}
+
+// broadcast sends notifications/tools/list_changed to every open session.
+func (s *mcpServer) broadcast() {
+	s.mu.Lock()
+	sessions := make(map[*mcp.StdioRPC]string, len(s.sessions))
+	for rpc, name := range s.sessions {
+		sessions[rpc] = name
+	}
+	s.mu.Unlock()
+	for rpc, name := range sessions {
+		if err := rpc.Notify("notifications/tools/list_changed", nil); err != nil {
+			log.Printf("Failed to send tools/list_changed to %s: %v", name, err)
+		}
+	}
+}
+
+// open registers a session and returns its name for logs, or "" when
+// max sessions (0 = unlimited) are open already.
+func (s *mcpServer) open(rpc *mcp.StdioRPC, label string, max int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if max > 0 && len(s.sessions) >= max {
+		return ""
+	}
+	s.seq++
+	name := fmt.Sprintf("session %d (%s)", s.seq, label)
+	s.sessions[rpc] = name
+	return name
+}
+
+func (s *mcpServer) close(rpc *mcp.StdioRPC) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, rpc)
+}
+
+// serveStdio answers the stdio session until the client disconnects; it
+// returns the exit code of the process.
+func (s *mcpServer) serveStdio(rpc *mcp.StdioRPC) int {
+	name := s.open(rpc, "stdio", 0)
+	defer s.close(rpc)
+	if err := s.serve(name, rpc, s.conf.Server.LenientInitialize); err != nil {
+		log.Printf("Parse error: %v", err)
+		_ = rpc.ReplyError(nil, -32700, "parse error", err.Error())
+		return 1
+	}
+	log.Println("Client disconnected, shutting down...")
+	return 0
+}
+
+// checkListener refuses an MCP listener reachable from other hosts. MCP
+// sessions are not authenticated, unlike the HTTP API, so TCP listeners
+// must be bound to loopback; unix sockets rely on their permissions.
+func checkListener(ln net.Listener) error {
+	addr, ok := ln.Addr().(*net.TCPAddr)
+	if !ok || addr.IP.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("%s is not a loopback address; MCP sessions are unauthenticated, so listen on 127.0.0.1, [::1] or a unix socket", addr)
+}
+
+// listen accepts MCP connections on ln until it is closed. Each connection
+// is a session of its own, spoken in either framing stdio accepts; beyond
+// server.max_sessions open ones a connection gets an error and is closed.
+// Header framing must start with Content-Length, so an HTTP request, such
+// as a browser's POST to the port, is closed unanswered, and sessions always
+// require the initialize handshake.
+func (s *mcpServer) listen(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("MCP listener error: %v", err)
+			}
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *mcpServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	rpc := mcp.NewRPC(conn, conn)
+	rpc.SetMaxResponseBytes(s.conf.Server.MaxResponseKB * 1024)
+	rpc.SetStrict(true)
+	label := conn.RemoteAddr().String()
+	if label == "" || label == "@" {
+		label = "unix socket"
+	}
+	name := s.open(rpc, label, s.conf.Server.MaxSessions)
+	if name == "" {
+		log.Printf("Refusing MCP connection from %s: %d sessions are open", label, s.conf.Server.MaxSessions)
+		_ = rpc.ReplyError(nil, -32000, "too many sessions", map[string]any{"max_sessions": s.conf.Server.MaxSessions})
+		return
+	}
+	defer s.close(rpc)
+	log.Printf("MCP %s opened", name)
+	if err := s.serve(name, rpc, false); errors.Is(err, mcp.ErrNotMCP) {
+		log.Printf("MCP %s: not an MCP client (%v), closing", name, err)
+	} else if err != nil {
+		log.Printf("MCP %s: parse error: %v", name, err)
+		_ = rpc.ReplyError(nil, -32700, "parse error", err.Error())
+	}
+	log.Printf("MCP %s closed", name)
+}
+
+// serve answers the requests of one session until its client disconnects.
+// It returns nil at the end of input and the read error otherwise. lenient
+// serves requests before the initialize handshake completed.
+func (s *mcpServer) serve(name string, rpc *mcp.StdioRPC, lenient bool) error {
+	debug := s.conf.Logging.Level == "debug"
+	session := mcp.NewSession(lenient)
+	notifications := mcp.NewNotificationRouter()
+	notifications.Handle("notifications/initialized", func(json.RawMessage) {
+		if !session.Initialized() {
+			log.Printf("Ignoring notifications/initialized in %s state %s", name, session.State())
+			return
+		}
+		if debug {
+			log.Printf("Client initialization notification received (%s)", name)
+		}
+	})
+	notifications.Handle("notifications/cancelled", func(params json.RawMessage) {
+		if debug {
+			log.Printf("Client cancelled request: %s", string(params))
+		}
+	})
+
+	// Requests are handled one at a time, in order. Reading goes on
+	// meanwhile, so a tool waiting for the client's response to a request
+	// of the server (sampling, elicitation) gets it even when the client
+	// sends other messages first: those wait in the inbox, which has no
+	// bound.
+	in := newInbox()
+	go func() {
+		for {
+			req, err := rpc.Read()
+			if err == nil && mcp.IsResponse(req) {
+				if !rpc.Deliver(req) && debug {
+					log.Printf("Dropping response %s nobody waits for (%s)", string(req.ID), name)
+				}
+				continue
+			}
+			var parseErr *mcp.ParseError
+			if errors.As(err, &parseErr) {
+				// The bad message is skipped; the session goes on
+				in.push(message{nil, err})
+				continue
+			}
+			if err != nil {
+				rpc.Abort(fmt.Errorf("client disconnected: %w", err))
+			}
+			in.push(message{req, err})
+			if err != nil {
+				return
+			}
+		}
+	}()
+	client := sessionClient{rpc: rpc, session: session, name: name}
+
+	for {
+		m := in.pop()
+		req, err := m.req, m.err
+		var parseErr *mcp.ParseError
+		if errors.As(err, &parseErr) {
+			log.Printf("Parse error in %s: %v", name, err)
+			_ = rpc.ReplyError(nil, -32700, "parse error", err.Error())
+			continue
+		}
+		if err != nil {
+			if strings.Contains(err.Error(), "EOF") || errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.ECONNRESET) {
+				return nil
+			}
+			return err
+		}
+
+		if debug {
+			log.Printf("Received request: %s", req.Method)
+		}
+
+		if !mcp.ValidID(req.ID) {
+			_ = rpc.ReplyError(nil, -32600, "invalid request", "id must be a string, a number or null")
+			continue
+		}
+
+		// Notifications have no id and must not be replied to.
+		if notifications.Dispatch(req) {
+			continue
+		}
+
+		// Until initialize and notifications/initialized, only initialize
+		// and ping are served.
+		if e := session.Admit(req); e != nil {
+			_ = rpc.ReplyError(req.ID, e.Code, e.Message, e.Data)
+			continue
+		}
+		s.handle(rpc, session, client, req)
+	}
+}
+
+// message is a request read from a session, or the error reading it.
+type message struct {
+	req *mcp.JSONRPCRequest
+	err error
+}
+
+// inbox queues the messages of a session between its reader and the loop
+// answering them. push never blocks.
+type inbox struct {
+	mu    sync.Mutex
+	added *sync.Cond
+	items []message
+}
+
+func newInbox() *inbox {
+	q := &inbox{}
+	q.added = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *inbox) push(m message) {
+	q.mu.Lock()
+	q.items = append(q.items, m)
+	q.mu.Unlock()
+	q.added.Signal()
+}
+
+// pop waits for the oldest message and removes it.
+func (q *inbox) pop() message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 {
+		q.added.Wait()
+	}
+	m := q.items[0]
+	q.items[0] = message{}
+	q.items = q.items[1:]
+	return m
+}
+
+// sessionClient lets tools talk back to the client of a session.
+type sessionClient struct {
+	rpc     *mcp.StdioRPC
+	session *mcp.Session
+	name    string
+}
+
+func (c sessionClient) Name() string {
+	return c.name
+}
+
+func (c sessionClient) Sample(ctx context.Context, p mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	return mcp.CreateMessage(ctx, c.rpc, c.session, p)
+}
+
+func (c sessionClient) Elicit(ctx context.Context, p mcp.ElicitParams) (*mcp.ElicitResult, error) {
+	return mcp.Elicit(ctx, c.rpc, c.session, p)
+}
+
+// errText is the text of an internal error as replied to clients, redacted
+// when logging.redact_errors is set.
+func (s *mcpServer) errText(err error) string {
+	return redact.Error(err, s.conf.Logging.RedactErrors)
+}
+
+// handle answers one admitted request.
+func (s *mcpServer) handle(rpc *mcp.StdioRPC, session *mcp.Session, client tools.Client, req *mcp.JSONRPCRequest) {
+	switch req.Method {
+	case "initialize":
+		if _, e := session.Initialize(req.Params); e != nil {
+			log.Printf("Initialization rejected: %v", e.Data)
+			_ = rpc.ReplyError(req.ID, e.Code, e.Message, e.Data)
+			return
+		}
+		res := mcp.InitializeResult{
+			ProtocolVersion: session.Version(),
+			Capabilities:    s.capabilities(),
+			ServerInfo:      mcp.MCPServerInfo{Name: s.conf.Server.Name, Version: s.conf.Server.Version},
+		}
+		log.Println("Initialization completed")
+		_ = rpc.Reply(req.ID, res)
+
+	case "tools/list":
+		var lp mcp.ToolsListParams
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &lp); err != nil {
+				_ = rpc.ReplyError(req.ID, -32602, "invalid params", apierr.Invalid(err.Error()))
+				return
+			}
+		}
+		list, next, err := s.registry.ListPage(lp.Cursor)
+		if err != nil {
+			var terr *tools.Error
+			if errors.As(err, &terr) {
+				_ = rpc.ReplyError(req.ID, terr.Code, terr.Message, terr.Data)
+			} else {
+				_ = rpc.ReplyError(req.ID, -32603, "internal error", s.errText(err))
+			}
+			return
+		}
+		if !session.Structured() {
+			for i := range list {
+				list[i].OutputSchema = nil
+			}
+		}
+		if s.conf.Logging.Level == "debug" {
+			log.Printf("Returning %d available tools", len(list))
+		}
+		_ = rpc.Reply(req.ID, mcp.ToolsListResult{Tools: list, NextCursor: next})
+
+	case "tools/call":
+		var p mcp.ToolsCallParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			log.Printf("Invalid tool call params: %v", err)
+			_ = rpc.ReplyError(req.ID, -32602, "invalid params", apierr.Invalid(err.Error()))
+			return
+		}
+
+		if s.conf.Logging.Level == "debug" {
+			log.Printf("Calling tool: %s", p.Name)
+		}
+
+		res, err := s.registry.CallFrom(client, p.Name, p.Args)
+		if err != nil {
+			var terr *tools.Error
+			if errors.As(err, &terr) {
+				if terr.Code == -32601 {
+					log.Printf("Unknown tool requested: %s", p.Name)
+				}
+				_ = rpc.ReplyError(req.ID, terr.Code, terr.Message, terr.Data)
+			} else {
+				log.Printf("Tool %s failed: %v", p.Name, err)
+				d := apierr.Of(err, s.errText(err))
+				_ = rpc.ReplyError(req.ID, d.Code, "internal error", d)
+			}
+			return
+		}
+		// Older revisions have no structured results; the JSON content
+		// item carries the same payload
+		if !session.Structured() {
+			res.StructuredContent = nil
+		}
+		_ = rpc.Reply(req.ID, res)
+
+	case "resources/list":
+		_ = rpc.Reply(req.ID, mcp.ResourcesListResult{Resources: s.resources.List()})
+
+	case "resources/templates/list":
+		_ = rpc.Reply(req.ID, mcp.ResourceTemplatesListResult{ResourceTemplates: s.resources.Templates()})
+
+	case "resources/read":
+		var rp mcp.ResourcesReadParams
+		if err := json.Unmarshal(req.Params, &rp); err != nil {
+			_ = rpc.ReplyError(req.ID, -32602, "invalid params", apierr.Invalid(err.Error()))
+			return
+		}
+		res, err := s.resources.Read(rp.URI)
+		if err != nil {
+			var terr *tools.Error
+			if errors.As(err, &terr) {
+				_ = rpc.ReplyError(req.ID, terr.Code, terr.Message, terr.Data)
+			} else {
+				_ = rpc.ReplyError(req.ID, -32603, "internal error", s.errText(err))
+			}
+			return
+		}
+		_ = rpc.Reply(req.ID, res)
+
+	case "completion/complete":
+		var cp mcp.CompleteParams
+		if err := json.Unmarshal(req.Params, &cp); err != nil {
+			_ = rpc.ReplyError(req.ID, -32602, "invalid params", apierr.Invalid(err.Error()))
+			return
+		}
+		comp, err := s.resources.Complete(cp.Ref, cp.Argument.Name, cp.Argument.Value)
+		if err != nil {
+			var terr *tools.Error
+			if errors.As(err, &terr) {
+				_ = rpc.ReplyError(req.ID, terr.Code, terr.Message, terr.Data)
+			} else {
+				log.Printf("Completion error: %v", err)
+				d := apierr.Of(err, s.errText(err))
+				_ = rpc.ReplyError(req.ID, d.Code, "completion error", d)
+			}
+			return
+		}
+		_ = rpc.Reply(req.ID, mcp.CompleteResult{Completion: comp})
+
+	case "ping":
+		_ = rpc.Reply(req.ID, mcp.PingResult{})
+
+	default:
+		log.Printf("Unknown method: %s", req.Method)
+		_ = rpc.ReplyError(req.ID, -32601, "method not found", req.Method)
+	}
+}
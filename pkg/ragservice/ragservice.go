@@ -0,0 +1,245 @@
+// Package ragservice embeds the RAG engine behind the MCP server in other Go
+// programs: index directories and documents into the configured vector
+// store and search them, without running the binary or its HTTP API.
+//
+//	conf, err := ragservice.LoadConfig("config.json")
+//	...
+//	svc, err := ragservice.New(conf)
+//	...
+//	res, err := svc.Index(ctx, "./docs", ragservice.IndexOptions{Tags: []string{"v2"}})
+//	hits, err := svc.Search(ctx, "how do I rotate keys?", ragservice.SearchOptions{K: 5})
+//
+// The configuration is the same as the server's, including the vector store
+// backend, embedding provider and indexing guardrails.
+package ragservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Rhyanz46/mcp-service/internal/config"
+	"github.com/Rhyanz46/mcp-service/internal/ragvec"
+)
+
+type (
+	// Config is the service configuration, as read from config.json.
+	Config = config.Config
+	// Hit is one search result.
+	Hit = ragvec.Hit
+	// SourceRange locates a hit in its source file.
+	SourceRange = ragvec.SourceRange
+	// Filter restricts a search by project, tags and time.
+	Filter = ragvec.SearchFilter
+	// SearchParams tunes a single search.
+	SearchParams = ragvec.SearchParams
+	// IndexReport tells what the content checks changed during an index
+	// run and how its batches were written.
+	IndexReport = ragvec.IngestReport
+	// Reranker rescores search candidates; see SetReranker.
+	Reranker = ragvec.Reranker
+	// RerankCandidate is a search candidate as seen by a Reranker.
+	RerankCandidate = ragvec.RerankCandidate
+)
+
+// DefaultConfig returns the built-in defaults.
+func DefaultConfig() *Config {
+	return config.DefaultConfig()
+}
+
+// LoadConfig reads a config file over the defaults ("" reads none), applies
+// the environment overrides the server honours and validates the result.
+func LoadConfig(path string) (*Config, error) {
+	conf := config.DefaultConfig()
+	if path != "" {
+		if err := conf.LoadFromFile(path); err != nil {
+			return nil, fmt.Errorf("load config file: %w", err)
+		}
+	}
+	conf.LoadFromEnv()
+	if err := conf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return conf, nil
+}
+
+// Service indexes and searches one collection. It is safe for concurrent
+// use.
+type Service struct {
+	conf *Config
+	rag  *ragvec.VecRAG
+}
+
+// New validates conf and connects to its vector store and embedding
+// provider, creating the collection if it does not exist.
+func New(conf *Config) (*Service, error) {
+	if conf == nil {
+		conf = DefaultConfig()
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	rag, err := ragvec.NewVecRAGWithConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{conf: conf, rag: rag}, nil
+}
+
+// Config returns the configuration the service was created with.
+func (s *Service) Config() *Config {
+	return s.conf
+}
+
+// SetReranker replaces the reranker of search.reranker with rr; nil turns
+// reranking off. Call it before the first search.
+func (s *Service) SetReranker(rr Reranker) {
+	s.rag.SetReranker(rr)
+}
+
+// IndexOptions adjusts one index run.
+type IndexOptions struct {
+	// IncludeCode indexes code files as well as documentation
+	IncludeCode bool
+	// Tags are stored on every chunk and can be filtered on
+	Tags []string
+	// TTL expires the chunks this long after the run; 0 keeps them
+	TTL time.Duration
+	// Project overrides the project derived from each path
+	Project string
+	// Include limits the run to matching files; Exclude skips files on top
+	// of indexing.exclude. Both take the patterns of rag_index.
+	Include []string
+	Exclude []string
+	// RequestID is sent to the vector store and embedding API in the
+	// X-Request-ID header
+	RequestID string
+}
+
+// IndexResult is the outcome of an index run.
+type IndexResult struct {
+	// Chunks counts the chunks written, including by a failed run
+	Chunks int
+	// Report tells what the content checks changed and how the batches
+	// were written
+	Report *IndexReport
+	// Warnings lists non-fatal problems, such as a cap exceeded with
+	// indexing.limit_action warn
+	Warnings []string
+}
+
+func (s *Service) ingestOptions(ctx context.Context, opts IndexOptions, res *IndexResult) ragvec.IngestOptions {
+	res.Report = &IndexReport{}
+	return ragvec.IngestOptions{
+		Tags:      opts.Tags,
+		TTL:       opts.TTL,
+		Project:   opts.Project,
+		Include:   opts.Include,
+		Exclude:   opts.Exclude,
+		Report:    res.Report,
+		OnWarning: func(msg string) { res.Warnings = append(res.Warnings, msg) },
+		Context:   ctx,
+	}
+}
+
+// Index indexes the files under dir like rag_index. Cancelling ctx stops
+// the run before its next batch; what was written stays indexed, and
+// running it again overwrites those chunks rather than duplicating them.
+func (s *Service) Index(ctx context.Context, dir string, opts IndexOptions) (IndexResult, error) {
+	var res IndexResult
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	iopts := s.ingestOptions(ctx, opts, &res)
+	var err error
+	res.Chunks, err = s.rag.WithRequestID(opts.RequestID).IngestDocsWith(dir, opts.IncludeCode, iopts)
+	return res, err
+}
+
+// IndexText indexes one document given as text under path, which need not
+// exist on disk (e.g. virtual://notes.md); its extension picks the
+// chunking strategy. Chunks stored earlier under path are replaced.
+// IncludeCode, Include and Exclude do not apply.
+func (s *Service) IndexText(ctx context.Context, path, text string, opts IndexOptions) (IndexResult, error) {
+	var res IndexResult
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	iopts := s.ingestOptions(ctx, opts, &res)
+	var err error
+	res.Chunks, err = s.rag.WithRequestID(opts.RequestID).IngestText(path, text, iopts)
+	return res, err
+}
+
+// SearchOptions adjusts one search.
+type SearchOptions struct {
+	// K is the number of hits to return (default 5)
+	K      int
+	Filter Filter
+	// Params overrides the search defaults of the configuration
+	Params *SearchParams
+	// ReadThrough replaces each hit's text with its range of the source
+	// file, as read now and passed through the secrets check and content
+	// filters
+	ReadThrough bool
+	// RequestID is sent to the vector store and embedding API in the
+	// X-Request-ID header
+	RequestID string
+}
+
+// Search returns the chunks most similar to query, best first. When ctx is
+// done first its error is returned and the search in flight is abandoned.
+func (s *Service) Search(ctx context.Context, query string, opts SearchOptions) ([]Hit, error) {
+	rag := s.rag.WithRequestID(opts.RequestID)
+	params := rag.DefaultSearchParams()
+	if opts.Params != nil {
+		params = *opts.Params
+	}
+	return await(ctx, func() ([]Hit, error) {
+		hits, err := rag.SearchFiltered(query, opts.K, opts.Filter, params)
+		if err == nil && opts.ReadThrough {
+			rag.ReadThrough(hits)
+		}
+		return hits, err
+	})
+}
+
+// Delete removes the chunks of project and returns how many there were.
+// With indexing.soft_delete_retention_hours set they are hidden and purged
+// later instead. Cancelling ctx returns early but does not stop a delete
+// that has started.
+func (s *Service) Delete(ctx context.Context, project string) (int, error) {
+	if project == "" {
+		return 0, fmt.Errorf("project is required; use DeleteAll to empty the collection")
+	}
+	return await(ctx, func() (int, error) { return s.rag.DeleteProject(project) })
+}
+
+// DeleteAll removes every chunk of the collection.
+func (s *Service) DeleteAll(ctx context.Context) (int, error) {
+	return await(ctx, s.rag.DeleteAll)
+}
+
+// await runs fn and returns its result, or ctx's error once ctx is done.
+// fn keeps running in the background after that.
+func await[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	type outcome struct {
+		v   T
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		v, err := fn()
+		done <- outcome{v, err}
+	}()
+	select {
+	case o := <-done:
+		return o.v, o.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
@@ -10,7 +10,7 @@ go build -o "$BIN" .
 run_rpc() {
   local payload="$1"
   (
-    printf '%s\n' '{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}'
+    printf '%s\n' '{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}' '{"jsonrpc":"2.0","method":"notifications/initialized"}'
     sleep 0.2
     printf '%s\n' "$payload"
     # keep stdin open briefly to avoid EOF before server reads